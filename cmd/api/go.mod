@@ -160,11 +160,14 @@ require (
 replace (
 	github.com/aidenlippert/zerostate/libs/api => ../../libs/api
 	github.com/aidenlippert/zerostate/libs/auth => ../../libs/auth
+	github.com/aidenlippert/zerostate/libs/billing => ../../libs/billing
 	github.com/aidenlippert/zerostate/libs/database => ../../libs/database
 	github.com/aidenlippert/zerostate/libs/execution => ../../libs/execution
 	github.com/aidenlippert/zerostate/libs/identity => ../../libs/identity
+	github.com/aidenlippert/zerostate/libs/ledger => ../../libs/ledger
 	github.com/aidenlippert/zerostate/libs/orchestration => ../../libs/orchestration
 	github.com/aidenlippert/zerostate/libs/p2p => ../../libs/p2p
+	github.com/aidenlippert/zerostate/libs/scoring => ../../libs/scoring
 	github.com/aidenlippert/zerostate/libs/search => ../../libs/search
 	github.com/aidenlippert/zerostate/libs/telemetry => ../../libs/telemetry
 )
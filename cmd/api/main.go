@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/analytics"
 	"github.com/aidenlippert/zerostate/libs/api"
 	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
 	"github.com/aidenlippert/zerostate/libs/execution"
 	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/aidenlippert/zerostate/libs/llm"
 	"github.com/aidenlippert/zerostate/libs/metrics"
 	"github.com/aidenlippert/zerostate/libs/orchestration"
@@ -22,6 +26,7 @@ import (
 	"github.com/aidenlippert/zerostate/libs/search"
 	"github.com/aidenlippert/zerostate/libs/storage"
 	"github.com/aidenlippert/zerostate/libs/substrate"
+	"github.com/aidenlippert/zerostate/libs/validation"
 	"github.com/aidenlippert/zerostate/libs/websocket"
 	_ "github.com/lib/pq" // PostgreSQL driver
 	"github.com/libp2p/go-libp2p"
@@ -148,6 +153,9 @@ func main() {
 	defer db.Close()
 	logger.Info("database connection established")
 
+	// One SQL journal shared by every service that moves funds
+	journal := ledger.New(ledger.NewSQLStore(db.Conn()), logger.With(zap.String("component", "ledger")))
+
 	// Initialize HNSW index for agent discovery
 	logger.Info("initializing HNSW index")
 	hnsw := search.NewIndex(logger)
@@ -165,6 +173,33 @@ func main() {
 	// Create WASM runner with 5-minute timeout
 	wasmRunner := execution.NewWASMRunner(logger, 5*time.Minute)
 
+	// Share compiled modules and downloaded binaries across executions.
+	// WASM_CACHE_DIR persists both across restarts and must be private to this node.
+	cacheDir := os.Getenv("WASM_CACHE_DIR")
+	moduleCacheConfig := execution.ModuleCacheConfig{}
+	moduleCacheConfig.MaxModules, _ = strconv.Atoi(getEnv("WASM_CACHE_MODULES", "64"))
+	binaryCacheConfig := execution.BinaryCacheConfig{}
+	if mb, err := strconv.Atoi(getEnv("WASM_BINARY_CACHE_MB", "256")); err == nil {
+		binaryCacheConfig.MaxBytes = int64(mb) << 20
+	}
+	if cacheDir != "" {
+		moduleCacheConfig.Dir = cacheDir + "/compiled"
+		binaryCacheConfig.Dir = cacheDir + "/binaries"
+	}
+	moduleCache, err := execution.NewModuleCache(ctx, moduleCacheConfig, logger)
+	if err != nil {
+		logger.Warn("failed to initialize WASM module cache, compiling on every run", zap.Error(err))
+		moduleCache = nil
+	} else {
+		defer moduleCache.Close(context.Background())
+		wasmRunner.SetModuleCache(moduleCache)
+	}
+	binaryCache, err := execution.NewBinaryCache(binaryCacheConfig, logger)
+	if err != nil {
+		logger.Warn("failed to initialize WASM binary cache, downloading on every run", zap.Error(err))
+		binaryCache = nil
+	}
+
 	// Create result store with database connection
 	resultStore := execution.NewPostgresResultStore(db.Conn(), logger)
 
@@ -216,7 +251,11 @@ func main() {
 			}, nil
 		}
 		dbAdapter := execution.NewDatabaseAdapter(getAgentFunc)
-		binaryStore = execution.NewS3BinaryStore(s3Storage, dbAdapter)
+		s3BinaryStore := execution.NewS3BinaryStore(s3Storage, dbAdapter)
+		if binaryCache != nil {
+			s3BinaryStore.SetBinaryCache(binaryCache)
+		}
+		binaryStore = s3BinaryStore
 		logger.Info("binary store initialized with S3 backend")
 	} else {
 		logger.Info("binary store not available (S3 storage not configured)")
@@ -262,6 +301,12 @@ func main() {
 	var wasmRunnerV2 *execution.WASMRunnerV2
 	if r2Storage != nil {
 		wasmRunnerV2 = execution.NewWASMRunnerV2(logger, r2Storage, 30*time.Second, 128)
+		if moduleCache != nil {
+			wasmRunnerV2.SetModuleCache(moduleCache)
+		}
+		if binaryCache != nil {
+			wasmRunnerV2.SetBinaryCache(binaryCache)
+		}
 		logger.Info("WASM runner V2 initialized with R2 backend")
 	}
 
@@ -423,7 +468,22 @@ func main() {
 		logger.Info("🧠 using intelligent task executor with Groq LLM + R2 WASM agents")
 	} else if binaryStore != nil {
 		// Fallback to basic WASM executor with S3
-		executor = orchestration.NewWASMTaskExecutor(wasmRunner, binaryStore, logger)
+		wasmExecutor := orchestration.NewWASMTaskExecutor(wasmRunner, binaryStore, logger)
+		// Fuel bought per unit of task budget (0 disables metering)
+		if fuelPerUnit, err := strconv.ParseUint(getEnv("WASM_FUEL_PER_UNIT", "10000000"), 10, 64); err == nil {
+			wasmExecutor.SetFuelPerUnit(fuelPerUnit)
+		}
+		// Host API backends; agents only reach them with capabilities granted in their card
+		wasmExecutor.SetContentStore(p2p.GetGlobalContentStore())
+		wasmExecutor.SetAgentCaller(orchestration.NewSubtaskCaller(selector, wasmExecutor, logger))
+		// Task filesystems: input artifacts come from and outputs go to S3
+		if s3Storage != nil {
+			wasmExecutor.SetArtifactStore(s3Storage)
+		}
+		if mb, err := strconv.Atoi(getEnv("WASM_SCRATCH_MB", "64")); err == nil {
+			wasmExecutor.SetScratchLimit(int64(mb) << 20)
+		}
+		executor = wasmExecutor
 		logger.Info("using basic WASM task executor with S3 backend")
 	} else {
 		// Fallback to mock executor
@@ -441,6 +501,84 @@ func main() {
 		auctioneer := orchestration.NewAuctioneer(gossip, logger.With(zap.String("component", "auctioneer")))
 		orch.SetAuctioneer(auctioneer)
 		logger.Info("auctioneer wired into orchestrator - market-based task selection enabled")
+
+		// Commit-reveal bidding keeps bids hidden until every bidder has committed
+		// and bonds posted against the agent's ledger balance punish bidders who
+		// commit and never reveal
+		if strings.EqualFold(os.Getenv("SEALED_BID_AUCTIONS"), "true") {
+			minBond, _ := strconv.ParseFloat(getEnv("SEALED_BID_MIN_BOND", "0"), 64)
+			auctioneer.EnableSealedBids(orchestration.SealedBidConfig{
+				MinBond:  minBond,
+				Bonds:    api.NewLedgerBondManager(journal, logger.With(zap.String("component", "sealed-bid-bonds"))),
+				Reporter: orch,
+			})
+			logger.Info("sealed-bid auctions enabled", zap.Float64("min_bond", minBond))
+		}
+
+		// Urgent tasks can be allocated by a falling price clock instead
+		if strings.EqualFold(os.Getenv("DUTCH_AUCTIONS"), "true") {
+			duration, err := time.ParseDuration(getEnv("DUTCH_AUCTION_DURATION", "2s"))
+			if err != nil || duration <= 0 {
+				duration = 2 * time.Second
+			}
+			if err := auctioneer.EnableDutchAuctions(orchestration.DutchSchedule{Duration: duration}); err != nil {
+				logger.Warn("dutch auctions not enabled", zap.Error(err))
+			} else {
+				logger.Info("dutch auctions enabled for urgent tasks", zap.Duration("duration", duration))
+			}
+		}
+	}
+
+	// Standing asks let commodity tasks skip the auction window entirely
+	if !strings.EqualFold(os.Getenv("SPOT_MARKET"), "false") {
+		orderBook := orchestration.NewOrderBook(logger.With(zap.String("component", "order-book")))
+		orch.SetOrderBook(orderBook)
+		if gossip != nil {
+			if err := orderBook.SubscribeAsks(gossip); err != nil {
+				logger.Warn("failed to subscribe to standing asks", zap.Error(err))
+			}
+		}
+		logger.Info("spot order book enabled")
+	}
+
+	// Share of each escrow release charged as a platform fee
+	platformFee := platformFeeFromEnv()
+
+	// Escrow-backed capacity reservations are allocated before the spot market
+	if db != nil {
+		reservationEscrow := api.NewReservationEscrow(db, logger.With(zap.String("component", "reservation-escrow")))
+		reservationEscrow.SetPlatformFee(platformFee)
+		reservations := orchestration.NewReservationBook(
+			reservationEscrow,
+			orch,
+			orchestration.ReservationPolicy{SettleGrace: 5 * time.Minute},
+			logger.With(zap.String("component", "reservations")),
+		)
+		if gossip != nil {
+			reservations.SetGossip(gossip)
+		}
+		orch.SetReservations(reservations)
+		go reservations.Run(ctx, time.Minute)
+		logger.Info("capacity reservations enabled")
+	}
+
+	// Evaluate escrow release conditions whenever a task produces a result,
+	// split replicated tasks' escrows between the agreeing agents, pro-rate
+	// canceled tasks' escrows, and sweep auto-releases and condition
+	// deadlines in the background
+	if db != nil {
+		conditionObserver := api.NewEscrowConditionObserver(db, logger.With(zap.String("component", "escrow-conditions")))
+		conditionObserver.SetPlatformFee(platformFee)
+		orch.AddResultObserver(conditionObserver)
+
+		interval, err := time.ParseDuration(getEnv("ESCROW_SWEEP_INTERVAL", "1m"))
+		if err != nil || interval <= 0 {
+			interval = time.Minute
+		}
+		sweeper := economic.NewEscrowService(db.Conn(), logger.With(zap.String("component", "escrow-sweeper")))
+		sweeper.SetPlatformFee(platformFee)
+		sweeper.SetAccountResolver(economic.UserDIDResolver)
+		go sweeper.Run(ctx, interval)
 	}
 
 	logger.Info("orchestrator components initialized with meta-agent")
@@ -460,6 +598,58 @@ func main() {
 	defer wsHub.Stop()
 	logger.Info("WebSocket hub started")
 
+	// Stream task progress to the WebSocket subscribers of each task
+	orch.AddProgressBroadcaster(api.NewTaskProgressBroadcaster(wsHub))
+
+	// Enforce budget policies on batch task creation and settle task budgets as results arrive
+	var budgetGuard *api.BudgetGuard
+	if db != nil {
+		budgetGuard = api.NewBudgetGuard(db, wsHub, logger.With(zap.String("component", "budget-guard")))
+		orch.SetSpendGuard(budgetGuard)
+		orch.AddResultObserver(budgetGuard)
+	}
+
+	// Aggregate cleared auction prices into rolling percentiles and publish them to the market
+	priceIndex := economic.NewPriceIndex(0)
+	if db != nil {
+		var publisher economic.PriceIndexPublisher
+		if gossip != nil {
+			publisher = api.NewGossipPriceIndexPublisher(gossip, p2pHost.ID().String())
+		}
+		refresh, err := time.ParseDuration(getEnv("PRICE_INDEX_REFRESH", "1m"))
+		if err != nil || refresh <= 0 {
+			refresh = time.Minute
+		}
+		priceIndexService := economic.NewPriceIndexService(db.Conn(), priceIndex, publisher, logger.With(zap.String("component", "price-index")))
+		go priceIndexService.Run(ctx, refresh)
+		logger.Info("price index service started", zap.Duration("refresh", refresh))
+	}
+
+	// Scan bid history for collusion; COLLUSION_EXCLUDE=true keeps flagged bidders out of auctions
+	var bidderFlags *analytics.BidderFlags
+	if db != nil {
+		bidderFlags = analytics.NewBidderFlags(analytics.SeverityHigh, strings.EqualFold(os.Getenv("COLLUSION_EXCLUDE"), "true"))
+		if auctioneer := orch.Auctioneer(); auctioneer != nil {
+			auctioneer.SetBidderScreen(bidderFlags)
+		}
+		interval, err := time.ParseDuration(getEnv("COLLUSION_SCAN_INTERVAL", "15m"))
+		if err != nil || interval <= 0 {
+			interval = 15 * time.Minute
+		}
+		scanner := analytics.NewCollusionScanner(
+			analytics.NewMetricsService(db.Conn(), logger.With(zap.String("component", "collusion"))),
+			analytics.NewCollusionDetector(analytics.CollusionConfig{}),
+			bidderFlags,
+			0,
+			logger.With(zap.String("component", "collusion")),
+		)
+		go scanner.Run(ctx, interval)
+		logger.Info("collusion scanner started",
+			zap.Duration("interval", interval),
+			zap.Bool("exclude_flagged", bidderFlags.Enforcing()),
+		)
+	}
+
 	// Initialize blockchain service (Sprint 2)
 	logger.Info("initializing blockchain service")
 	blockchainEndpoint := os.Getenv("BLOCKCHAIN_ENDPOINT")
@@ -503,6 +693,37 @@ func main() {
 		promMetrics,
 		promRegistry,
 	)
+	handlers.SetPriceIndex(priceIndex)
+	if bidderFlags != nil {
+		handlers.SetBidderFlags(bidderFlags)
+	}
+	if budgetGuard != nil {
+		handlers.SetBudgetGuard(budgetGuard)
+	}
+	handlers.SetAdmissionPolicy(admissionPolicyFromEnv())
+	handlers.SetPlatformFee(platformFee)
+
+	// Share the SQL journal with the handlers and reconcile it in the background,
+	// since reconciliation reads every escrow from the chain
+	if db != nil {
+		handlers.SetLedger(journal)
+
+		interval, err := time.ParseDuration(getEnv("LEDGER_RECONCILE_INTERVAL", "10m"))
+		if err != nil || interval <= 0 {
+			interval = 10 * time.Minute
+		}
+		reconciliation := ledger.NewReconciliationJob(
+			ledger.NewReconciler(
+				journal,
+				economic.NewEscrowService(db.Conn(), logger.With(zap.String("component", "ledger-reconciliation"))),
+				api.NewChainEscrowSource(blockchain),
+				logger.With(zap.String("component", "ledger-reconciliation")),
+			),
+			logger.With(zap.String("component", "ledger-reconciliation")),
+		)
+		handlers.SetReconciliationJob(reconciliation)
+		go reconciliation.Run(ctx, interval)
+	}
 
 	// Create API server
 	logger.Info("creating API server")
@@ -595,6 +816,39 @@ func main() {
 	logger.Info("shutdown complete")
 }
 
+// admissionPolicyFromEnv applies WASM_ALLOWED_IMPORTS (comma-separated
+// patterns), WASM_MAX_INITIAL_PAGES, WASM_ALLOW_SIMD and WASM_ALLOW_THREADS
+// to the default upload admission policy
+func admissionPolicyFromEnv() *validation.AdmissionPolicy {
+	policy := validation.DefaultAdmissionPolicy()
+	if imports := os.Getenv("WASM_ALLOWED_IMPORTS"); imports != "" {
+		policy.AllowedImportModules = nil
+		for _, module := range strings.Split(imports, ",") {
+			if module = strings.TrimSpace(module); module != "" {
+				policy.AllowedImportModules = append(policy.AllowedImportModules, module)
+			}
+		}
+	}
+	if pages, err := strconv.ParseUint(os.Getenv("WASM_MAX_INITIAL_PAGES"), 10, 32); err == nil {
+		policy.MaxInitialPages = uint32(pages)
+	}
+	if strings.EqualFold(os.Getenv("WASM_ALLOW_SIMD"), "false") {
+		policy.AllowSIMD = false
+	}
+	policy.AllowThreads = strings.EqualFold(os.Getenv("WASM_ALLOW_THREADS"), "true")
+	return policy
+}
+
+// platformFeeFromEnv reads PLATFORM_FEE_RATE, the share (0-1) of each escrow
+// release charged as a platform fee; no fee is charged by default
+func platformFeeFromEnv() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("PLATFORM_FEE_RATE"), 64)
+	if err != nil || rate < 0 || rate >= 1 {
+		return 0
+	}
+	return rate
+}
+
 // getEnv returns environment variable value or default
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,675 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CollusionType classifies a suspected manipulation pattern
+type CollusionType string
+
+const (
+	CollusionSharedCredential CollusionType = "shared_credential" // Several DIDs bid with the same account or key
+	CollusionSharedIP         CollusionType = "shared_ip"         // Several DIDs bid from the same address
+	CollusionSelfDealing      CollusionType = "self_dealing"      // A requester bids on its own auctions
+	CollusionWinRotation      CollusionType = "win_rotation"      // Co-bidders take turns winning
+	CollusionPriceClustering  CollusionType = "price_clustering"  // Co-bidders repeatedly bid within a hair of each other
+)
+
+// Finding severities, ordered
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// severityRank orders severities for threshold comparisons
+var severityRank = map[string]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3}
+
+// BidRecord is one bid in an auction's history
+type BidRecord struct {
+	AuctionID   string    `json:"auction_id"`
+	RequesterID string    `json:"requester_id"`
+	BidderDID   string    `json:"bidder_did"`
+	Credential  string    `json:"credential,omitempty"` // Account or signing key that submitted the bid
+	SourceIP    string    `json:"source_ip,omitempty"`
+	Price       float64   `json:"price"`
+	Won         bool      `json:"won"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// CollusionFinding is a suspected manipulation pattern among bidders
+type CollusionFinding struct {
+	Type        CollusionType `json:"type"`
+	Severity    string        `json:"severity"`
+	DIDs        []string      `json:"dids"`
+	AuctionIDs  []string      `json:"auction_ids,omitempty"`
+	Score       float64       `json:"score"`     // Strength of the evidence
+	Threshold   float64       `json:"threshold"` // Score at which the pattern is reported
+	Description string        `json:"description"`
+}
+
+// CollusionConfig tunes the collusion detector
+type CollusionConfig struct {
+	// MinSharedAuctions is how many auctions two bidders must meet in before
+	// their win pattern is judged
+	MinSharedAuctions int
+
+	// RotationShare is the share of shared auctions a pair must win between them
+	RotationShare float64
+
+	// RotationRate is the share of consecutive pair wins that must switch winner.
+	// Independent competitors switch about half the time.
+	RotationRate float64
+
+	// ClusterSpread is the relative price spread, (max-min)/min, under which
+	// an auction's bids count as clustered
+	ClusterSpread float64
+
+	// MinClusteredAuctions is how many clustered auctions a pair must share
+	MinClusteredAuctions int
+}
+
+// DefaultCollusionConfig returns the default detector thresholds
+func DefaultCollusionConfig() CollusionConfig {
+	return CollusionConfig{
+		MinSharedAuctions:    6,
+		RotationShare:        0.8,
+		RotationRate:         0.8,
+		ClusterSpread:        0.01,
+		MinClusteredAuctions: 3,
+	}
+}
+
+// CollusionDetector scans bid histories for bid rotation, coordinated prices
+// and sock-puppet bidding
+type CollusionDetector struct {
+	config CollusionConfig
+}
+
+// NewCollusionDetector creates a detector. Zero config fields use the defaults.
+func NewCollusionDetector(config CollusionConfig) *CollusionDetector {
+	defaults := DefaultCollusionConfig()
+	if config.MinSharedAuctions <= 0 {
+		config.MinSharedAuctions = defaults.MinSharedAuctions
+	}
+	if config.RotationShare <= 0 {
+		config.RotationShare = defaults.RotationShare
+	}
+	if config.RotationRate <= 0 {
+		config.RotationRate = defaults.RotationRate
+	}
+	if config.ClusterSpread <= 0 {
+		config.ClusterSpread = defaults.ClusterSpread
+	}
+	if config.MinClusteredAuctions <= 0 {
+		config.MinClusteredAuctions = defaults.MinClusteredAuctions
+	}
+	return &CollusionDetector{config: config}
+}
+
+// auctionBids groups the bids of one auction
+type auctionBids struct {
+	id        string
+	requester string
+	opened    time.Time
+	bids      []BidRecord
+	winner    string
+}
+
+// Analyze returns the collusion findings in a bid history, most severe first
+func (d *CollusionDetector) Analyze(records []BidRecord) []CollusionFinding {
+	auctions := groupAuctions(records)
+
+	var findings []CollusionFinding
+	findings = append(findings, d.sharedIdentities(records, auctions)...)
+	findings = append(findings, d.selfDealing(records)...)
+	findings = append(findings, d.winRotation(auctions)...)
+	findings = append(findings, d.priceClustering(auctions)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+	return findings
+}
+
+// groupAuctions groups bids by auction in the order the auctions opened
+func groupAuctions(records []BidRecord) []*auctionBids {
+	byID := make(map[string]*auctionBids)
+	var auctions []*auctionBids
+	for _, r := range records {
+		a, ok := byID[r.AuctionID]
+		if !ok {
+			a = &auctionBids{id: r.AuctionID, requester: r.RequesterID, opened: r.SubmittedAt}
+			byID[r.AuctionID] = a
+			auctions = append(auctions, a)
+		}
+		if r.SubmittedAt.Before(a.opened) {
+			a.opened = r.SubmittedAt
+		}
+		if r.Won {
+			a.winner = r.BidderDID
+		}
+		a.bids = append(a.bids, r)
+	}
+
+	sort.Slice(auctions, func(i, j int) bool { return auctions[i].opened.Before(auctions[j].opened) })
+	return auctions
+}
+
+// sharedIdentities reports DIDs that bid with the same credential or address.
+// Sharing is only severe when the DIDs also meet in the same auction.
+func (d *CollusionDetector) sharedIdentities(records []BidRecord, auctions []*auctionBids) []CollusionFinding {
+	credentials := make(map[string]map[string]bool)
+	addresses := make(map[string]map[string]bool)
+	for _, r := range records {
+		if r.Credential != "" {
+			addToSet(credentials, r.Credential, r.BidderDID)
+		}
+		if r.SourceIP != "" {
+			addToSet(addresses, r.SourceIP, r.BidderDID)
+		}
+	}
+
+	var findings []CollusionFinding
+	for _, credential := range sortedKeys(credentials) {
+		if f := d.sharedFinding(CollusionSharedCredential, "credential", credential, credentials[credential], auctions, SeverityHigh); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	for _, address := range sortedKeys(addresses) {
+		// Addresses are shared legitimately behind NAT, so they rank lower
+		if f := d.sharedFinding(CollusionSharedIP, "address", address, addresses[address], auctions, SeverityMedium); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	return findings
+}
+
+// sharedFinding reports a set of DIDs behind one identity, if more than one
+func (d *CollusionDetector) sharedFinding(kind CollusionType, label, identity string, dids map[string]bool, auctions []*auctionBids, coBidSeverity string) *CollusionFinding {
+	if len(dids) < 2 {
+		return nil
+	}
+
+	// Auctions in which at least two of the DIDs bid against each other
+	var shared []string
+	for _, a := range auctions {
+		seen := 0
+		for _, did := range uniqueBidders(a) {
+			if dids[did] {
+				seen++
+			}
+		}
+		if seen >= 2 {
+			shared = append(shared, a.id)
+		}
+	}
+
+	severity := SeverityLow
+	if len(shared) > 0 {
+		severity = coBidSeverity
+	}
+
+	members := sortedSet(dids)
+	return &CollusionFinding{
+		Type:        kind,
+		Severity:    severity,
+		DIDs:        members,
+		AuctionIDs:  shared,
+		Score:       float64(len(members)),
+		Threshold:   2,
+		Description: fmt.Sprintf("%d bidders share %s %s and met in %d auctions", len(members), label, identity, len(shared)),
+	}
+}
+
+// selfDealing reports requesters whose own account bids on their auctions
+func (d *CollusionDetector) selfDealing(records []BidRecord) []CollusionFinding {
+	type key struct{ requester, did string }
+	hits := make(map[key][]string)
+	var order []key
+	for _, r := range records {
+		if r.RequesterID == "" {
+			continue
+		}
+		if r.BidderDID != r.RequesterID && r.Credential != r.RequesterID {
+			continue
+		}
+		k := key{r.RequesterID, r.BidderDID}
+		if _, ok := hits[k]; !ok {
+			order = append(order, k)
+		}
+		hits[k] = appendUnique(hits[k], r.AuctionID)
+	}
+
+	var findings []CollusionFinding
+	for _, k := range order {
+		findings = append(findings, CollusionFinding{
+			Type:        CollusionSelfDealing,
+			Severity:    SeverityHigh,
+			DIDs:        []string{k.did},
+			AuctionIDs:  hits[k],
+			Score:       float64(len(hits[k])),
+			Threshold:   1,
+			Description: fmt.Sprintf("bidder %s is controlled by requester %s and bid on %d of its auctions", k.did, k.requester, len(hits[k])),
+		})
+	}
+	return findings
+}
+
+// winRotation reports pairs of bidders who meet often, win nearly every
+// auction between them and hand the win back and forth
+func (d *CollusionDetector) winRotation(auctions []*auctionBids) []CollusionFinding {
+	shared := make(map[[2]string][]*auctionBids)
+	for _, a := range auctions {
+		bidders := uniqueBidders(a)
+		for i := range bidders {
+			for j := i + 1; j < len(bidders); j++ {
+				pair := [2]string{bidders[i], bidders[j]}
+				shared[pair] = append(shared[pair], a)
+			}
+		}
+	}
+
+	var findings []CollusionFinding
+	for _, pair := range sortedPairs(shared) {
+		met := shared[pair]
+		if len(met) < d.config.MinSharedAuctions {
+			continue
+		}
+
+		var winners []string
+		var ids []string
+		for _, a := range met {
+			if a.winner == pair[0] || a.winner == pair[1] {
+				winners = append(winners, a.winner)
+				ids = append(ids, a.id)
+			}
+		}
+		share := float64(len(winners)) / float64(len(met))
+		if share < d.config.RotationShare || len(winners) < 2 {
+			continue
+		}
+
+		switches := 0
+		for i := 1; i < len(winners); i++ {
+			if winners[i] != winners[i-1] {
+				switches++
+			}
+		}
+		rate := float64(switches) / float64(len(winners)-1)
+		if rate < d.config.RotationRate {
+			continue
+		}
+
+		findings = append(findings, CollusionFinding{
+			Type:        CollusionWinRotation,
+			Severity:    SeverityHigh,
+			DIDs:        []string{pair[0], pair[1]},
+			AuctionIDs:  ids,
+			Score:       rate,
+			Threshold:   d.config.RotationRate,
+			Description: fmt.Sprintf("bidders won %d of %d shared auctions and alternated winner %.0f%% of the time", len(winners), len(met), rate*100),
+		})
+	}
+	return findings
+}
+
+// priceClustering reports pairs of bidders who repeatedly submit near-identical
+// prices, a sign of an agreed price floor
+func (d *CollusionDetector) priceClustering(auctions []*auctionBids) []CollusionFinding {
+	clustered := make(map[[2]string][]string)
+	for _, a := range auctions {
+		prices := lowestPrices(a)
+		if len(prices) < 3 {
+			continue
+		}
+
+		lo, hi := prices[0].price, prices[0].price
+		for _, p := range prices[1:] {
+			if p.price < lo {
+				lo = p.price
+			}
+			if p.price > hi {
+				hi = p.price
+			}
+		}
+		if lo <= 0 || (hi-lo)/lo > d.config.ClusterSpread {
+			continue
+		}
+
+		for i := range prices {
+			for j := i + 1; j < len(prices); j++ {
+				pair := [2]string{prices[i].did, prices[j].did}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				clustered[pair] = append(clustered[pair], a.id)
+			}
+		}
+	}
+
+	var findings []CollusionFinding
+	for _, pair := range sortedPairs(clustered) {
+		ids := clustered[pair]
+		if len(ids) < d.config.MinClusteredAuctions {
+			continue
+		}
+		findings = append(findings, CollusionFinding{
+			Type:        CollusionPriceClustering,
+			Severity:    SeverityMedium,
+			DIDs:        []string{pair[0], pair[1]},
+			AuctionIDs:  ids,
+			Score:       float64(len(ids)),
+			Threshold:   float64(d.config.MinClusteredAuctions),
+			Description: fmt.Sprintf("bidders priced within %.1f%% of each other in %d auctions", d.config.ClusterSpread*100, len(ids)),
+		})
+	}
+	return findings
+}
+
+// FlaggedDIDs returns the DIDs named by findings at or above minSeverity
+func FlaggedDIDs(findings []CollusionFinding, minSeverity string) map[string]CollusionFinding {
+	flagged := make(map[string]CollusionFinding)
+	for _, f := range findings {
+		if severityRank[f.Severity] < severityRank[minSeverity] {
+			continue
+		}
+		for _, did := range f.DIDs {
+			if existing, ok := flagged[did]; !ok || severityRank[f.Severity] > severityRank[existing.Severity] {
+				flagged[did] = f
+			}
+		}
+	}
+	return flagged
+}
+
+// LoadBidRecords returns every bid submitted since the given time
+func (s *MetricsService) LoadBidRecords(ctx context.Context, since time.Time) ([]BidRecord, error) {
+	query := `
+		SELECT
+			b.auction_id::text,
+			a.user_id,
+			b.agent_did,
+			COALESCE(b.submitted_by, ''),
+			COALESCE(b.source_ip, ''),
+			b.price,
+			COALESCE(a.winning_bid_id = b.id, false),
+			b.created_at
+		FROM bids b
+		JOIN auctions a ON a.id = b.auction_id
+		WHERE b.created_at >= $1
+		ORDER BY b.created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bid history: %w", err)
+	}
+	defer rows.Close()
+
+	var records []BidRecord
+	for rows.Next() {
+		var r BidRecord
+		if err := rows.Scan(&r.AuctionID, &r.RequesterID, &r.BidderDID, &r.Credential, &r.SourceIP, &r.Price, &r.Won, &r.SubmittedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bid record: %w", err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bid history: %w", err)
+	}
+
+	return records, nil
+}
+
+// DetectCollusion scans the bid history of the lookback window for collusion
+func (s *MetricsService) DetectCollusion(ctx context.Context, lookbackHours int, detector *CollusionDetector) ([]CollusionFinding, error) {
+	if detector == nil {
+		detector = NewCollusionDetector(CollusionConfig{})
+	}
+
+	records, err := s.LoadBidRecords(ctx, time.Now().Add(-time.Duration(lookbackHours)*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	return detector.Analyze(records), nil
+}
+
+// collusionAnomalies converts collusion findings into anomalies
+func collusionAnomalies(findings []CollusionFinding, now time.Time) []Anomaly {
+	anomalies := make([]Anomaly, 0, len(findings))
+	for _, f := range findings {
+		anomalies = append(anomalies, Anomaly{
+			ID:          uuid.New(),
+			Type:        "collusion_" + string(f.Type),
+			Severity:    f.Severity,
+			Description: fmt.Sprintf("%s (%s)", f.Description, strings.Join(f.DIDs, ", ")),
+			Value:       f.Score,
+			Threshold:   f.Threshold,
+			DetectedAt:  now,
+		})
+	}
+	return anomalies
+}
+
+// BidderFlags holds the DIDs flagged by the last collusion scan. Auctions
+// consult it through IsFlagged; when not enforcing, flags are recorded for
+// review but nobody is excluded. It is safe for concurrent use.
+type BidderFlags struct {
+	mu          sync.RWMutex
+	enforce     bool
+	minSeverity string
+	flagged     map[string]CollusionFinding
+	allowed     map[string]bool
+}
+
+// NewBidderFlags creates an empty flag list. Findings at or above minSeverity
+// flag their DIDs; enforce excludes flagged DIDs from auctions.
+func NewBidderFlags(minSeverity string, enforce bool) *BidderFlags {
+	if _, ok := severityRank[minSeverity]; !ok {
+		minSeverity = SeverityHigh
+	}
+	return &BidderFlags{
+		enforce:     enforce,
+		minSeverity: minSeverity,
+		flagged:     make(map[string]CollusionFinding),
+		allowed:     make(map[string]bool),
+	}
+}
+
+// Update replaces the flagged DIDs with those named by findings
+func (f *BidderFlags) Update(findings []CollusionFinding) {
+	flagged := FlaggedDIDs(findings, f.minSeverity)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flagged = flagged
+}
+
+// IsFlagged reports whether a DID should be excluded from auctions
+func (f *BidderFlags) IsFlagged(did string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.enforce || f.allowed[did] {
+		return false
+	}
+	_, ok := f.flagged[did]
+	return ok
+}
+
+// Allow clears a DID after review so later scans no longer exclude it
+func (f *BidderFlags) Allow(did string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allowed[did] = true
+}
+
+// Flagged returns the flagged DIDs and the finding behind each, including
+// DIDs that were allowed after review
+func (f *BidderFlags) Flagged() map[string]CollusionFinding {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	flagged := make(map[string]CollusionFinding, len(f.flagged))
+	for did, finding := range f.flagged {
+		flagged[did] = finding
+	}
+	return flagged
+}
+
+// Enforcing reports whether flagged DIDs are excluded from auctions
+func (f *BidderFlags) Enforcing() bool {
+	return f.enforce
+}
+
+// CollusionScanner periodically scans bid history and refreshes bidder flags
+type CollusionScanner struct {
+	metrics       *MetricsService
+	detector      *CollusionDetector
+	flags         *BidderFlags
+	lookbackHours int
+	logger        *zap.Logger
+}
+
+// NewCollusionScanner creates a scanner over the given lookback window
+func NewCollusionScanner(metrics *MetricsService, detector *CollusionDetector, flags *BidderFlags, lookbackHours int, logger *zap.Logger) *CollusionScanner {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if lookbackHours <= 0 {
+		lookbackHours = 24 * 7
+	}
+	return &CollusionScanner{
+		metrics:       metrics,
+		detector:      detector,
+		flags:         flags,
+		lookbackHours: lookbackHours,
+		logger:        logger,
+	}
+}
+
+// Scan runs one detection pass and updates the flags
+func (s *CollusionScanner) Scan(ctx context.Context) ([]CollusionFinding, error) {
+	findings, err := s.metrics.DetectCollusion(ctx, s.lookbackHours, s.detector)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.flags != nil {
+		s.flags.Update(findings)
+	}
+	if len(findings) > 0 {
+		s.logger.Warn("collusion scan found suspicious bidding",
+			zap.Int("findings", len(findings)),
+			zap.Int("lookback_hours", s.lookbackHours),
+		)
+	}
+	return findings, nil
+}
+
+// Run scans every interval until ctx is done
+func (s *CollusionScanner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Scan(ctx); err != nil {
+			s.logger.Warn("collusion scan failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// bidPrice is a bidder's lowest price in an auction
+type bidPrice struct {
+	did   string
+	price float64
+}
+
+// lowestPrices returns each bidder's lowest price in an auction
+func lowestPrices(a *auctionBids) []bidPrice {
+	lowest := make(map[string]float64)
+	for _, b := range a.bids {
+		if p, ok := lowest[b.BidderDID]; !ok || b.Price < p {
+			lowest[b.BidderDID] = b.Price
+		}
+	}
+
+	prices := make([]bidPrice, 0, len(lowest))
+	for did, price := range lowest {
+		prices = append(prices, bidPrice{did: did, price: price})
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].did < prices[j].did })
+	return prices
+}
+
+// uniqueBidders returns the sorted distinct bidders of an auction
+func uniqueBidders(a *auctionBids) []string {
+	set := make(map[string]bool)
+	for _, b := range a.bids {
+		set[b.BidderDID] = true
+	}
+	return sortedSet(set)
+}
+
+func addToSet(sets map[string]map[string]bool, key, value string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	sets[key][value] = true
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+func sortedSet(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for v := range set {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs[V any](m map[[2]string]V) [][2]string {
+	pairs := make([][2]string, 0, len(m))
+	for p := range m {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
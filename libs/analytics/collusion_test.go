@@ -0,0 +1,140 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func bidAt(auction int, did string, price float64, won bool) BidRecord {
+	return BidRecord{
+		AuctionID:   fmt.Sprintf("auction-%d", auction),
+		RequesterID: "requester",
+		BidderDID:   did,
+		Price:       price,
+		Won:         won,
+		SubmittedAt: time.Unix(int64(auction*60), 0),
+	}
+}
+
+func findingsOf(findings []CollusionFinding, kind CollusionType) []CollusionFinding {
+	var matched []CollusionFinding
+	for _, f := range findings {
+		if f.Type == kind {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+func TestCollusionDetectsWinRotation(t *testing.T) {
+	var records []BidRecord
+	for i := 0; i < 8; i++ {
+		aWins := i%2 == 0
+		records = append(records,
+			bidAt(i, "did:key:a", 10, aWins),
+			bidAt(i, "did:key:b", 12, !aWins),
+		)
+	}
+
+	rotation := findingsOf(NewCollusionDetector(CollusionConfig{}).Analyze(records), CollusionWinRotation)
+	if len(rotation) != 1 {
+		t.Fatalf("expected one rotation finding, got %+v", rotation)
+	}
+	if rotation[0].Severity != SeverityHigh || rotation[0].Score != 1 {
+		t.Fatalf("expected a high-severity perfect rotation, got %+v", rotation[0])
+	}
+}
+
+func TestCollusionIgnoresCompetitiveWins(t *testing.T) {
+	var records []BidRecord
+	for i := 0; i < 8; i++ {
+		// The cheaper bidder keeps winning: competition, not rotation
+		records = append(records,
+			bidAt(i, "did:key:a", 10, i != 3),
+			bidAt(i, "did:key:b", 12, i == 3),
+		)
+	}
+
+	if rotation := findingsOf(NewCollusionDetector(CollusionConfig{}).Analyze(records), CollusionWinRotation); len(rotation) != 0 {
+		t.Fatalf("expected no rotation finding, got %+v", rotation)
+	}
+}
+
+func TestCollusionDetectsSharedCredentialsAndSelfDealing(t *testing.T) {
+	records := []BidRecord{
+		bidAt(1, "did:key:a", 10, true),
+		bidAt(1, "did:key:b", 11, false),
+		bidAt(2, "did:key:c", 10, true),
+	}
+	records[0].Credential = "account-1"
+	records[1].Credential = "account-1"
+	records[2].Credential = "requester"
+
+	findings := NewCollusionDetector(CollusionConfig{}).Analyze(records)
+
+	shared := findingsOf(findings, CollusionSharedCredential)
+	if len(shared) != 1 || len(shared[0].DIDs) != 2 || shared[0].Severity != SeverityHigh {
+		t.Fatalf("expected a high-severity shared credential between a and b, got %+v", shared)
+	}
+
+	self := findingsOf(findings, CollusionSelfDealing)
+	if len(self) != 1 || self[0].DIDs[0] != "did:key:c" {
+		t.Fatalf("expected c to be flagged for self-dealing, got %+v", self)
+	}
+}
+
+func TestCollusionDetectsPriceClustering(t *testing.T) {
+	var records []BidRecord
+	for i := 0; i < 3; i++ {
+		records = append(records,
+			bidAt(i, "did:key:a", 10.00, true),
+			bidAt(i, "did:key:b", 10.05, false),
+			bidAt(i, "did:key:c", 10.02, false),
+		)
+	}
+	// A spread-out auction does not count
+	records = append(records,
+		bidAt(9, "did:key:a", 10, true),
+		bidAt(9, "did:key:b", 15, false),
+		bidAt(9, "did:key:c", 20, false),
+	)
+
+	clustered := findingsOf(NewCollusionDetector(CollusionConfig{}).Analyze(records), CollusionPriceClustering)
+	if len(clustered) != 3 {
+		t.Fatalf("expected every pair of the three bidders to be flagged, got %+v", clustered)
+	}
+	for _, f := range clustered {
+		if f.Score != 3 {
+			t.Fatalf("expected three clustered auctions per pair, got %+v", f)
+		}
+	}
+}
+
+func TestBidderFlagsEnforcementAndAllow(t *testing.T) {
+	findings := []CollusionFinding{
+		{Type: CollusionWinRotation, Severity: SeverityHigh, DIDs: []string{"did:key:a", "did:key:b"}},
+		{Type: CollusionPriceClustering, Severity: SeverityMedium, DIDs: []string{"did:key:c"}},
+	}
+
+	monitor := NewBidderFlags(SeverityHigh, false)
+	monitor.Update(findings)
+	if monitor.IsFlagged("did:key:a") {
+		t.Fatal("expected a monitoring-only list not to exclude anyone")
+	}
+	if len(monitor.Flagged()) != 2 {
+		t.Fatalf("expected a and b to be recorded, got %v", monitor.Flagged())
+	}
+
+	enforcing := NewBidderFlags(SeverityHigh, true)
+	enforcing.Update(findings)
+	if !enforcing.IsFlagged("did:key:a") || enforcing.IsFlagged("did:key:c") {
+		t.Fatal("expected only high-severity DIDs to be excluded")
+	}
+
+	enforcing.Allow("did:key:a")
+	enforcing.Update(findings)
+	if enforcing.IsFlagged("did:key:a") {
+		t.Fatal("expected an allowed DID to stay cleared across scans")
+	}
+}
@@ -759,5 +759,12 @@ func (s *MetricsService) DetectAnomalies(ctx context.Context, lookbackHours int)
 		})
 	}
 
+	// Check bid history for collusion and shill bidding
+	records, err := s.LoadBidRecords(ctx, startTime)
+	if err == nil {
+		findings := NewCollusionDetector(CollusionConfig{}).Analyze(records)
+		anomalies = append(anomalies, collusionAnomalies(findings, now)...)
+	}
+
 	return anomalies, nil
 }
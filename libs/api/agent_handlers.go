@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/execution"
 	"github.com/aidenlippert/zerostate/libs/identity"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -181,6 +182,14 @@ func (h *Handlers) RegisterAgent(c *gin.Context) {
 		return
 	}
 
+	if err := validateFunctions(req.Capabilities, req.Functions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// Set default resources if not provided
 	if req.Resources == nil {
 		req.Resources = &AgentResources{
@@ -199,7 +208,7 @@ func (h *Handlers) RegisterAgent(c *gin.Context) {
 		Endpoints: &identity.Endpoints{
 			Libp2p: []string{h.host.ID().String()},
 		},
-		Capabilities: convertToCapabilities(req.Capabilities, req.Pricing),
+		Capabilities: convertToCapabilities(req.Capabilities, req.Pricing, req.Functions),
 		Proof: &identity.Proof{
 			Type:    "Ed25519Signature2020",
 			Created: time.Now().Format(time.RFC3339),
@@ -371,12 +380,13 @@ func (h *Handlers) RegisterAgent(c *gin.Context) {
 // Helper functions
 
 // convertToCapabilities converts capability names to identity.Capability structs
-func convertToCapabilities(names []string, pricing *AgentPricing) []identity.Capability {
+func convertToCapabilities(names []string, pricing *AgentPricing, functions map[string][]identity.Function) []identity.Capability {
 	capabilities := make([]identity.Capability, len(names))
 	for i, name := range names {
 		cap := identity.Capability{
-			Name:    name,
-			Version: "1.0",
+			Name:      name,
+			Version:   "1.0",
+			Functions: functions[name],
 		}
 
 		if pricing != nil {
@@ -391,6 +401,33 @@ func convertToCapabilities(names []string, pricing *AgentPricing) []identity.Cap
 	return capabilities
 }
 
+// validateFunctions checks typed function declarations against the declared
+// capabilities and the calling convention's encodings
+func validateFunctions(capabilities []string, functions map[string][]identity.Function) error {
+	declared := make(map[string]bool, len(capabilities))
+	for _, name := range capabilities {
+		declared[name] = true
+	}
+
+	for capability, fns := range functions {
+		if !declared[capability] {
+			return fmt.Errorf("functions declared for unknown capability %q", capability)
+		}
+		seen := make(map[string]bool, len(fns))
+		for _, fn := range fns {
+			spec := execution.FunctionSpec{Name: fn.Name, Encoding: fn.Encoding}
+			if err := spec.Validate(); err != nil {
+				return fmt.Errorf("capability %q: %w", capability, err)
+			}
+			if seen[fn.Name] {
+				return fmt.Errorf("capability %q: function %q declared twice", capability, fn.Name)
+			}
+			seen[fn.Name] = true
+		}
+	}
+	return nil
+}
+
 // GetAgent retrieves an agent by ID
 func (h *Handlers) GetAgent(c *gin.Context) {
 	agentID := c.Param("id")
@@ -854,6 +891,8 @@ type RegisterAgentRequest struct {
 	Pricing      *AgentPricing          `json:"pricing" binding:"required"`
 	Resources    *AgentResources        `json:"resources"`
 	Metadata     map[string]interface{} `json:"metadata"`
+	// Typed functions per capability name, with their payload schemas
+	Functions map[string][]identity.Function `json:"functions,omitempty"`
 	// WASM binary is uploaded as multipart file
 }
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/aidenlippert/zerostate/libs/database"
 	"github.com/aidenlippert/zerostate/libs/substrate"
+	"github.com/aidenlippert/zerostate/libs/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -43,6 +44,36 @@ func getUserIDString(c *gin.Context) (string, bool) {
 	}
 }
 
+// agentOwnerKey is the agent metadata field holding the ID of the user who uploaded it
+const agentOwnerKey = "owner_id"
+
+// callerOwnsAgent reports whether the caller may act for an agent: operators,
+// the agent itself, and the user who uploaded it
+func (h *Handlers) callerOwnsAgent(c *gin.Context, agentID string) (bool, error) {
+	if c.GetBool("is_system") || (agentID != "" && agentID == c.GetString("user_did")) {
+		return true, nil
+	}
+	userID, ok := getUserIDString(c)
+	if !ok || h.db == nil {
+		return false, nil
+	}
+
+	agent, err := h.db.GetAgentByID(agentID)
+	if err != nil {
+		return false, err
+	}
+	if agent == nil {
+		return false, nil
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(agent.Metadata, &metadata); err != nil {
+		return false, nil
+	}
+	owner, _ := metadata[agentOwnerKey].(string)
+	return owner != "" && owner == userID, nil
+}
+
 // UploadAgentRequest represents agent upload metadata
 type UploadAgentRequest struct {
 	Name         string   `form:"name" binding:"required"`
@@ -54,12 +85,18 @@ type UploadAgentRequest struct {
 
 // UploadAgentResponse represents the upload response
 type UploadAgentResponse struct {
-	AgentID    string `json:"agent_id"`
-	BinaryURL  string `json:"binary_url"`
-	BinaryHash string `json:"binary_hash"`
-	BinarySize int64  `json:"binary_size"`
-	Status     string `json:"status"` // "uploaded", "validating", "active"
-	Message    string `json:"message"`
+	AgentID      string                   `json:"agent_id"`
+	BinaryURL    string                   `json:"binary_url"`
+	BinaryHash   string                   `json:"binary_hash"`
+	BinarySize   int64                    `json:"binary_size"`
+	Status       string                   `json:"status"` // "uploaded", "validating", "active"
+	Message      string                   `json:"message"`
+	PolicyReport *validation.PolicyReport `json:"policy_report,omitempty"`
+}
+
+// SetAdmissionPolicy replaces the policy uploaded agent binaries must pass
+func (h *Handlers) SetAdmissionPolicy(policy *validation.AdmissionPolicy) {
+	h.admissionPolicy = policy
 }
 
 // UploadAgentSimple handles WASM agent binary upload without requiring agent ID in URL
@@ -154,14 +191,36 @@ func (h *Handlers) UploadAgent(c *gin.Context) {
 		return
 	}
 
-	// TODO: Add WASM validation here
-	// For now, we skip validation to get to production faster
-	// Validation will be added in Tier 2
 	logger.Info("WASM file received",
 		zap.String("filename", header.Filename),
 		zap.Int("size", len(fileContent)),
 	)
 
+	// Parse metadata from form
+	var metadata UploadAgentRequest
+	if err := c.ShouldBind(&metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid metadata",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Admit the binary before anything is stored
+	report := validation.NewWASMValidator(logger).Admit(fileContent, h.admissionPolicy, metadata.Capabilities)
+	if !report.Admitted {
+		logger.Warn("agent binary refused by admission policy",
+			zap.String("filename", header.Filename),
+			zap.Int("violations", len(report.Violations)),
+		)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "admission rejected",
+			"message":    "agent binary violates the admission policy",
+			"violations": report.Violations,
+		})
+		return
+	}
+
 	// Calculate file hash (SHA-256)
 	hash := sha256.Sum256(fileContent)
 	fileHash := hex.EncodeToString(hash[:])
@@ -200,16 +259,6 @@ func (h *Handlers) UploadAgent(c *gin.Context) {
 		logger.Warn("S3 storage not configured, using placeholder URL")
 	}
 
-	// Parse metadata from form
-	var metadata UploadAgentRequest
-	if err := c.ShouldBind(&metadata); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "invalid metadata",
-			"message": err.Error(),
-		})
-		return
-	}
-
 	// Store agent metadata in database (ensure JSON never null for JSONB column)
 	capabilitiesJSON, err := json.Marshal(metadata.Capabilities)
 	if err != nil {
@@ -226,10 +275,12 @@ func (h *Handlers) UploadAgent(c *gin.Context) {
 
 	// Build metadata blob including wasm hash, s3 key, version & price for future pricing model
 	metadataMap := map[string]interface{}{
-		"wasm_hash": fileHash,
-		"s3_key":    fmt.Sprintf("agents/%s/%s.wasm", agentID, fileHash),
-		"version":   metadata.Version,
-		"price":     metadata.Price,
+		agentOwnerKey:   userIDStr,
+		"wasm_hash":     fileHash,
+		"s3_key":        fmt.Sprintf("agents/%s/%s.wasm", agentID, fileHash),
+		"version":       metadata.Version,
+		"price":         metadata.Price,
+		"policy_report": report,
 	}
 	metadataJSON, err := json.Marshal(metadataMap)
 	if err != nil {
@@ -372,12 +423,13 @@ func (h *Handlers) UploadAgent(c *gin.Context) {
 	)
 
 	c.JSON(http.StatusCreated, UploadAgentResponse{
-		AgentID:    agentID,
-		BinaryURL:  binaryURL,
-		BinaryHash: fileHash,
-		BinarySize: header.Size,
-		Status:     "uploaded",
-		Message:    "agent WASM binary uploaded and validated successfully",
+		AgentID:      agentID,
+		BinaryURL:    binaryURL,
+		BinaryHash:   fileHash,
+		BinarySize:   header.Size,
+		Status:       "uploaded",
+		Message:      "agent WASM binary uploaded and validated successfully",
+		PolicyReport: report,
 	})
 }
 
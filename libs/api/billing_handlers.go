@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/billing"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Billing Handlers
+
+// maxStatementMonths caps how many monthly statements one request may generate
+const maxStatementMonths = 24
+
+// GetBillingStatements returns monthly usage statements for the caller or one of their agents.
+//
+// Query parameters:
+//   - month: a single "YYYY-MM" month (default: current month)
+//   - from, to: an inclusive "YYYY-MM" month range, instead of month
+//   - role: "user" (default) or "agent"
+//   - agent_id: agent DID for agent statements, which the caller must own (default: the caller's DID)
+//   - format: "json" (default) or "csv"
+func (h *Handlers) GetBillingStatements(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetBillingStatements"))
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	role, err := billing.ParseRole(c.Query("role"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid role",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Deposits, withdrawals, earnings and escrows all post to the DID account
+	subjectID := c.GetString("user_did")
+	if subjectID == "" {
+		subjectID = userID
+	}
+	if role == billing.RoleAgent && c.Query("agent_id") != "" {
+		subjectID = c.Query("agent_id")
+
+		owned, err := h.callerOwnsAgent(c, subjectID)
+		if err != nil {
+			logger.Error("failed to check agent ownership",
+				zap.Error(err),
+				zap.String("agent_id", subjectID),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to check agent ownership",
+				"message": err.Error(),
+			})
+			return
+		}
+		if !owned {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "you may only read statements for your own agents",
+			})
+			return
+		}
+	}
+
+	from, to, err := parseStatementRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid period",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", billing.FormatJSON)
+	if format != billing.FormatJSON && format != billing.FormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid format",
+			"message": "format must be 'json' or 'csv'",
+		})
+		return
+	}
+
+	billingSvc := billing.NewService(h.journal(), h.logger)
+
+	statements, err := billingSvc.GenerateMonthlyStatements(c.Request.Context(), subjectID, role, from, to)
+	if err != nil {
+		logger.Error("failed to generate statements",
+			zap.Error(err),
+			zap.String("subject_id", subjectID),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to generate statements",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if format == billing.FormatCSV {
+		filename := fmt.Sprintf("statements-%s-%s.csv", role, billing.MonthPeriod(from).Label())
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		if err := billing.WriteCSV(c.Writer, statements); err != nil {
+			logger.Error("failed to write CSV statements", zap.Error(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statements": statements,
+		"count":      len(statements),
+	})
+}
+
+// parseStatementRange resolves the month/from/to query parameters into a month range
+func parseStatementRange(c *gin.Context) (time.Time, time.Time, error) {
+	if month := c.Query("month"); month != "" {
+		period, err := billing.ParseMonth(month)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return period.Start, period.Start, nil
+	}
+
+	fromParam, toParam := c.Query("from"), c.Query("to")
+	if fromParam == "" && toParam == "" {
+		current := billing.MonthPeriod(time.Now())
+		return current.Start, current.Start, nil
+	}
+	if fromParam == "" || toParam == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from and to must be given together", billing.ErrInvalidPeriod)
+	}
+
+	from, err := billing.ParseMonth(fromParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := billing.ParseMonth(toParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if to.Start.Before(from.Start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to is before from", billing.ErrInvalidPeriod)
+	}
+	if to.Start.After(from.Start.AddDate(0, maxStatementMonths-1, 0)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d months may be requested at once", billing.ErrInvalidPeriod, maxStatementMonths)
+	}
+	return from.Start, to.Start, nil
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/billing"
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agentTestSchema is the SQLite equivalent of the agents table
+const agentTestSchema = `
+CREATE TABLE agents (
+	id TEXT PRIMARY KEY,
+	did TEXT NOT NULL,
+	name TEXT NOT NULL,
+	description TEXT,
+	capabilities BLOB,
+	pricing_model TEXT,
+	status TEXT,
+	max_capacity INTEGER DEFAULT 10,
+	current_load INTEGER DEFAULT 0,
+	region TEXT,
+	created_at TEXT,
+	updated_at TEXT,
+	last_seen_at TEXT,
+	metadata BLOB
+);
+`
+
+func TestBillingAgentStatementsRequireOwnership(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.Conn().SetMaxOpenConns(1)
+	_, err = db.Conn().Exec(agentTestSchema)
+	require.NoError(t, err)
+
+	ownerID := uuid.New()
+	agentID := uuid.NewString()
+	metadata, err := json.Marshal(map[string]string{agentOwnerKey: ownerID.String()})
+	require.NoError(t, err)
+	_, err = db.Conn().Exec(`INSERT INTO agents (id, did, name, capabilities, status, metadata) VALUES ($1, $1, 'adder', $2, 'online', $3)`,
+		agentID, []byte(`[]`), metadata)
+	require.NoError(t, err)
+
+	// The agent earns from an escrow, paying the platform fee, and from a channel
+	ctx := context.Background()
+	journal := ledger.New(ledger.NewMemoryStore(), nil)
+	agent := ledger.UserAccount(agentID)
+	for _, p := range []struct {
+		key, from, to string
+		amount        float64
+		description   string
+	}{
+		{"e1:release", ledger.EscrowAccount("e1"), agent, 10, ledger.DescEscrowRelease},
+		{"e1:fee", agent, ledger.AccountFees, 1, ledger.DescFee},
+		{"c1:settle", ledger.ChannelAccount("c1"), agent, 4, ledger.DescChannelRelease},
+	} {
+		_, err := journal.Transfer(ctx, p.key, p.from, p.to, ledger.FromFloat(p.amount), p.description, "ref")
+		require.NoError(t, err)
+	}
+
+	h := &Handlers{db: db}
+	h.SetLedger(journal)
+	s := newTestServer(h)
+	path := "/api/v1/billing/statements?role=agent&agent_id=" + agentID + "&month=" + time.Now().UTC().Format("2006-01")
+
+	w := serve(s, http.MethodGet, path, nil, testToken(t, uuid.New(), "did:user:stranger", false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	for _, authorization := range []string{
+		testToken(t, ownerID, "did:user:owner", false),
+		testToken(t, uuid.New(), agentID, false),
+		testToken(t, uuid.New(), "did:operator:1", true),
+	} {
+		w = serve(s, http.MethodGet, path, nil, authorization)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var resp struct {
+		Statements []*billing.Statement `json:"statements"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Statements, 1)
+	totals := resp.Statements[0].Totals
+	assert.Equal(t, 14.0, totals.Earnings)
+	assert.Equal(t, 1.0, totals.Fees)
+	assert.Equal(t, 13.0, totals.NetEarnings)
+}
+
+func TestBillingUserStatementsReadCallerDID(t *testing.T) {
+	ctx := context.Background()
+	journal := ledger.New(ledger.NewMemoryStore(), nil)
+	alice := ledger.UserAccount("did:user:alice")
+	_, err := journal.Transfer(ctx, "deposit", ledger.AccountCash, alice, ledger.FromFloat(50), ledger.DescDeposit, "did:user:alice")
+	require.NoError(t, err)
+	_, err = journal.Transfer(ctx, "e1:fund", alice, ledger.EscrowAccount("e1"), ledger.FromFloat(20), ledger.DescEscrowFund, "e1")
+	require.NoError(t, err)
+
+	h := &Handlers{}
+	h.SetLedger(journal)
+	s := newTestServer(h)
+	w := serve(s, http.MethodGet, "/api/v1/billing/statements", nil, testToken(t, uuid.New(), "did:user:alice", false))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Statements []*billing.Statement `json:"statements"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Statements, 1)
+	assert.Equal(t, alice, resp.Statements[0].AccountID)
+	assert.Equal(t, 50.0, resp.Statements[0].Totals.Deposits)
+	assert.Equal(t, 20.0, resp.Statements[0].Totals.TaskCharges)
+	assert.Equal(t, 30.0, resp.Statements[0].ClosingBalance)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"go.uber.org/zap"
+)
+
+// LedgerBondManager backs sealed-bid bonds with the journal: locking moves the
+// bond from the agent's balance into a per-auction bond account, releasing
+// moves it back and forfeiting pays it to the platform. A CFP auctioned again,
+// e.g. when its task is retried, locks a new bond in a new round.
+type LedgerBondManager struct {
+	ledger *ledger.Ledger
+	logger *zap.Logger
+
+	// Serializes reading a bond account and posting to it
+	mu sync.Mutex
+}
+
+// NewLedgerBondManager creates a bond manager posting to journal
+func NewLedgerBondManager(journal *ledger.Ledger, logger *zap.Logger) *LedgerBondManager {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &LedgerBondManager{
+		ledger: journal,
+		logger: logger,
+	}
+}
+
+// LockBond implements orchestration.BondManager
+func (m *LedgerBondManager) LockBond(ctx context.Context, agentDID, cfpID string, amount float64) error {
+	bond := ledger.FromFloat(amount)
+	if bond <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	locked, err := m.ledger.Balance(ctx, ledger.BondAccount(agentDID, cfpID))
+	if err != nil {
+		return fmt.Errorf("failed to read bond: %w", err)
+	}
+	if locked > 0 {
+		return fmt.Errorf("bond already locked for %s", cfpID)
+	}
+
+	round, err := m.rounds(ctx, agentDID, cfpID)
+	if err != nil {
+		return err
+	}
+
+	// The ledger refuses a lock that would overdraw the agent
+	err = m.post(ctx, "lock", round+1, agentDID, cfpID, ledger.UserAccount(agentDID), ledger.BondAccount(agentDID, cfpID), bond, ledger.DescBondLock)
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		return fmt.Errorf("%w: required %s", orchestration.ErrInsufficientBond, bond)
+	}
+	return err
+}
+
+// ReleaseBond implements orchestration.BondManager
+func (m *LedgerBondManager) ReleaseBond(ctx context.Context, agentDID, cfpID string) error {
+	return m.settle(ctx, "release", agentDID, cfpID, ledger.UserAccount(agentDID), ledger.DescBondRelease)
+}
+
+// ForfeitBond implements orchestration.BondManager
+func (m *LedgerBondManager) ForfeitBond(ctx context.Context, agentDID, cfpID string) error {
+	return m.settle(ctx, "forfeit", agentDID, cfpID, ledger.AccountFees, ledger.DescBondForfeit)
+}
+
+// settle empties an agent's bond account for cfpID into to
+func (m *LedgerBondManager) settle(ctx context.Context, action, agentDID, cfpID, to, description string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	locked, err := m.ledger.Balance(ctx, ledger.BondAccount(agentDID, cfpID))
+	if err != nil {
+		return fmt.Errorf("failed to read bond: %w", err)
+	}
+	if locked <= 0 {
+		return fmt.Errorf("no bond locked for %s", cfpID)
+	}
+
+	// The locked bond belongs to the latest round
+	round, err := m.rounds(ctx, agentDID, cfpID)
+	if err != nil {
+		return err
+	}
+	return m.post(ctx, action, round, agentDID, cfpID, ledger.BondAccount(agentDID, cfpID), to, locked, description)
+}
+
+// rounds counts the bonds an agent has locked for cfpID so far
+func (m *LedgerBondManager) rounds(ctx context.Context, agentDID, cfpID string) (int, error) {
+	entries, err := m.ledger.Entries(ctx, ledger.EntryFilter{AccountID: ledger.BondAccount(agentDID, cfpID)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bond history: %w", err)
+	}
+	n := 0
+	for _, entry := range entries {
+		if entry.Description == ledger.DescBondLock {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// post journals a bond movement. The key names the round, so a replay of the
+// same movement is deduplicated but a later auction of the CFP isn't.
+func (m *LedgerBondManager) post(ctx context.Context, action string, round int, agentDID, cfpID, from, to string, amount ledger.Amount, description string) error {
+	entry := ledger.NewTransfer(
+		fmt.Sprintf("bond:%s:%s:%d:%s", cfpID, agentDID, round, action),
+		from, to,
+		amount,
+		description,
+		cfpID,
+	)
+	entry.Metadata = map[string]string{ledger.MetadataTaskID: cfpID}
+	if action == "lock" {
+		entry.NoOverdraft = []string{from}
+	}
+	if _, err := m.ledger.Post(ctx, entry); err != nil {
+		return fmt.Errorf("failed to journal bond %s: %w", action, err)
+	}
+
+	m.logger.Debug("bond journaled",
+		zap.String("action", action),
+		zap.String("agent_did", agentDID),
+		zap.String("cfp_id", cfpID),
+		zap.Int("round", round),
+		zap.String("amount", amount.String()),
+	)
+	return nil
+}
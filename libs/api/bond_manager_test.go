@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerBondManagerLocksAgainstAgentBalance(t *testing.T) {
+	ctx := context.Background()
+	journal := ledger.New(ledger.NewMemoryStore(), nil)
+	agent := "did:agent:1"
+	_, err := journal.Transfer(ctx, "deposit", ledger.AccountCash, ledger.UserAccount(agent), ledger.FromFloat(5), ledger.DescDeposit, agent)
+	require.NoError(t, err)
+
+	bonds := NewLedgerBondManager(journal, nil)
+	balance := func(account string) float64 {
+		amount, err := journal.Balance(ctx, account)
+		require.NoError(t, err)
+		return amount.Float64()
+	}
+
+	require.ErrorIs(t, bonds.LockBond(ctx, agent, "cfp-1", 6), orchestration.ErrInsufficientBond)
+
+	// Honest bidders get their bond back
+	require.NoError(t, bonds.LockBond(ctx, agent, "cfp-1", 3))
+	assert.Error(t, bonds.LockBond(ctx, agent, "cfp-1", 1))
+	assert.Equal(t, 2.0, balance(ledger.UserAccount(agent)))
+	assert.Equal(t, 3.0, balance(ledger.BondAccount(agent, "cfp-1")))
+	require.NoError(t, bonds.ReleaseBond(ctx, agent, "cfp-1"))
+	assert.Equal(t, 5.0, balance(ledger.UserAccount(agent)))
+	assert.Error(t, bonds.ReleaseBond(ctx, agent, "cfp-1"))
+
+	// Violators forfeit it to the platform
+	require.NoError(t, bonds.LockBond(ctx, agent, "cfp-2", 4))
+	require.NoError(t, bonds.ForfeitBond(ctx, agent, "cfp-2"))
+	assert.Equal(t, 1.0, balance(ledger.UserAccount(agent)))
+	assert.Equal(t, 0.0, balance(ledger.BondAccount(agent, "cfp-2")))
+	assert.Equal(t, 4.0, balance(ledger.AccountFees))
+
+	// A CFP auctioned again locks a new bond
+	require.NoError(t, bonds.LockBond(ctx, agent, "cfp-1", 1))
+	assert.Equal(t, 0.0, balance(ledger.UserAccount(agent)))
+	assert.Equal(t, 1.0, balance(ledger.BondAccount(agent, "cfp-1")))
+	require.NoError(t, bonds.ForfeitBond(ctx, agent, "cfp-1"))
+	assert.Equal(t, 5.0, balance(ledger.AccountFees))
+}
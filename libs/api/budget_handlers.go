@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/aidenlippert/zerostate/libs/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// apiKeyIDKey is the gin context key holding the ID of the API key a request
+// was authenticated with, if any
+const apiKeyIDKey = "api_key_id"
+
+// apiKeyContextKey carries the API key ID in a request's context.Context, for
+// code that only sees the context, such as batch task authorization
+type apiKeyContextKey struct{}
+
+// withAPIKeyID returns ctx carrying the API key a request was authenticated with
+func withAPIKeyID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, id)
+}
+
+// apiKeyIDFromContext returns the API key carried by ctx, if any
+func apiKeyIDFromContext(ctx context.Context) *uuid.UUID {
+	if id, ok := ctx.Value(apiKeyContextKey{}).(uuid.UUID); ok {
+		return &id
+	}
+	return nil
+}
+
+// budgetSoftLimitMessage is the WebSocket message type for soft-limit warnings
+const budgetSoftLimitMessage = "budget_soft_limit"
+
+// BudgetGuard enforces budget policies on task submission and warns users
+// over the WebSocket hub when they approach a limit. It also settles task
+// reservations as results arrive.
+type BudgetGuard struct {
+	budgets budgetAuthorizer
+	hub     *websocket.Hub
+	logger  *zap.Logger
+}
+
+// budgetAuthorizer is the part of economic.BudgetService a BudgetGuard uses
+type budgetAuthorizer interface {
+	Authorize(ctx context.Context, req *economic.SpendRequest) (*economic.BudgetDecision, error)
+	SettleTask(ctx context.Context, taskID string, cost float64) error
+	ReleaseTask(ctx context.Context, taskID string) error
+}
+
+// NewBudgetGuard creates a guard backed by the budget tables in db. hub may be nil.
+func NewBudgetGuard(db *database.Database, hub *websocket.Hub, logger *zap.Logger) *BudgetGuard {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &BudgetGuard{
+		budgets: economic.NewBudgetService(db.Conn(), logger),
+		hub:     hub,
+		logger:  logger,
+	}
+}
+
+// Authorize checks a spend request against the user's policies and sends
+// any soft-limit warnings. A rejection is returned as *economic.BudgetViolation.
+func (g *BudgetGuard) Authorize(ctx context.Context, req *economic.SpendRequest) error {
+	decision, err := g.budgets.Authorize(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range decision.Warnings {
+		g.logger.Info("budget soft limit reached",
+			zap.String("user_id", req.UserID),
+			zap.String("kind", string(warning.Kind)),
+			zap.Float64("ratio", warning.Ratio),
+		)
+		if g.hub != nil {
+			g.hub.SendToUser(req.UserID, budgetSoftLimitMessage, map[string]interface{}{
+				"kind":       warning.Kind,
+				"scope":      warning.Scope,
+				"policy_id":  warning.PolicyID.String(),
+				"capability": warning.Capability,
+				"limit":      warning.Limit,
+				"projected":  warning.Projected,
+				"ratio":      warning.Ratio,
+				"source":     req.Source,
+			})
+		}
+	}
+	return nil
+}
+
+// Release cancels the reservation of a task that was authorized but never started
+func (g *BudgetGuard) Release(ctx context.Context, taskID string) {
+	if err := g.budgets.ReleaseTask(ctx, taskID); err != nil {
+		g.logger.Warn("failed to release task budget",
+			zap.String("task_id", taskID),
+			zap.Error(err),
+		)
+	}
+}
+
+// AuthorizeSpend implements orchestration.SpendGuard for batch task creation.
+// Batches submitted with an API key are checked against that key's policy
+// too, taking the key from ctx.
+func (g *BudgetGuard) AuthorizeSpend(ctx context.Context, userID string, tasks []*orchestration.Task) error {
+	req := &economic.SpendRequest{
+		UserID:   userID,
+		APIKeyID: apiKeyIDFromContext(ctx),
+		Source:   economic.SpendSourceBatch,
+		Items:    make([]economic.SpendItem, len(tasks)),
+	}
+	for i, task := range tasks {
+		req.Items[i] = economic.SpendItem{
+			TaskID:       task.ID,
+			Capabilities: task.Capabilities,
+			Amount:       task.Budget,
+		}
+	}
+	return g.Authorize(ctx, req)
+}
+
+// ReleaseSpend implements orchestration.SpendGuard
+func (g *BudgetGuard) ReleaseSpend(ctx context.Context, taskIDs []string) {
+	for _, taskID := range taskIDs {
+		g.Release(ctx, taskID)
+	}
+}
+
+// OnTaskResult settles a task's reservation. Completed tasks are charged their
+// reported cost, or their full budget if no cost was reported; canceled tasks
+// are charged what they earned before the cancel; failed tasks are charged
+// nothing.
+func (g *BudgetGuard) OnTaskResult(ctx context.Context, task *orchestration.Task, result *orchestration.TaskResult) {
+	if result == nil {
+		return
+	}
+
+	var cost float64
+	switch result.Status {
+	case orchestration.TaskStatusCompleted:
+		cost = result.Cost
+		if cost <= 0 {
+			cost = task.Budget
+		}
+	case orchestration.TaskStatusCanceled:
+		// Canceled tasks are charged for the work done before the cancel
+		cost = result.Cost
+	}
+
+	if err := g.budgets.SettleTask(ctx, task.ID, cost); err != nil {
+		g.logger.Warn("failed to settle task budget",
+			zap.String("task_id", task.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// SetBudgetGuard shares the orchestrator's budget guard with the handlers
+func (h *Handlers) SetBudgetGuard(guard *BudgetGuard) {
+	h.budgets = guard
+}
+
+// budgetGuard returns the shared guard, one for the handlers' database, or nil
+// without either
+func (h *Handlers) budgetGuard() *BudgetGuard {
+	if h.budgets != nil {
+		return h.budgets
+	}
+	if h.db == nil {
+		return nil
+	}
+	return NewBudgetGuard(h.db, h.wsHub, h.logger)
+}
+
+// getAPIKeyID returns the API key the request was authenticated with, if any
+func getAPIKeyID(c *gin.Context) *uuid.UUID {
+	value, exists := c.Get(apiKeyIDKey)
+	if !exists {
+		return apiKeyIDFromContext(c.Request.Context())
+	}
+
+	switch v := value.(type) {
+	case uuid.UUID:
+		return &v
+	case string:
+		if id, err := uuid.Parse(v); err == nil {
+			return &id
+		}
+	}
+	return nil
+}
+
+// respondBudgetError writes the response for a failed budget authorization
+func respondBudgetError(c *gin.Context, logger *zap.Logger, err error) {
+	var violation *economic.BudgetViolation
+	if errors.As(err, &violation) {
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":     "budget limit exceeded",
+			"code":      violation.Code,
+			"message":   violation.Error(),
+			"violation": violation,
+		})
+		return
+	}
+
+	logger.Error("failed to check budget", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "failed to check budget",
+		"message": err.Error(),
+	})
+}
+
+// Budget Policy Handlers
+
+// SetBudgetPolicyRequest creates or replaces a budget policy
+type SetBudgetPolicyRequest struct {
+	APIKeyID         *uuid.UUID         `json:"api_key_id"` // Omit for the user-wide policy
+	DailyLimit       float64            `json:"daily_limit"`
+	MonthlyLimit     float64            `json:"monthly_limit"`
+	MaxEscrowed      float64            `json:"max_escrowed"`
+	CapabilityLimits map[string]float64 `json:"capability_limits"`
+	SoftLimitRatio   float64            `json:"soft_limit_ratio"`
+}
+
+// SetBudgetPolicy creates or replaces the caller's user-wide or API key policy
+func (h *Handlers) SetBudgetPolicy(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "SetBudgetPolicy"))
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req SetBudgetPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	budgetSvc := economic.NewBudgetService(h.db.Conn(), h.logger)
+	policy, err := budgetSvc.SetPolicy(c.Request.Context(), &economic.BudgetPolicy{
+		UserID:           userID,
+		APIKeyID:         req.APIKeyID,
+		DailyLimit:       req.DailyLimit,
+		MonthlyLimit:     req.MonthlyLimit,
+		MaxEscrowed:      req.MaxEscrowed,
+		CapabilityLimits: req.CapabilityLimits,
+		SoftLimitRatio:   req.SoftLimitRatio,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, economic.ErrInvalidBudgetPolicy) || errors.Is(err, economic.ErrAPIKeyNotOwned) {
+			status = http.StatusBadRequest
+		} else {
+			logger.Error("failed to save budget policy", zap.Error(err))
+		}
+		c.JSON(status, gin.H{
+			"error":   "failed to save budget policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetBudgetPolicies returns the caller's policies with their current usage
+func (h *Handlers) GetBudgetPolicies(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetBudgetPolicies"))
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	budgetSvc := economic.NewBudgetService(h.db.Conn(), h.logger)
+
+	policies, err := budgetSvc.GetPolicies(c.Request.Context(), userID)
+	if err != nil {
+		logger.Error("failed to get budget policies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get budget policies",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	results := make([]gin.H, 0, len(policies))
+	for _, policy := range policies {
+		usage, err := budgetSvc.Usage(c.Request.Context(), policy)
+		if err != nil {
+			logger.Error("failed to get budget usage", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to get budget usage",
+				"message": err.Error(),
+			})
+			return
+		}
+		results = append(results, gin.H{
+			"policy": policy,
+			"usage":  usage,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": results,
+		"count":    len(results),
+	})
+}
+
+// DeleteBudgetPolicy removes one of the caller's policies
+func (h *Handlers) DeleteBudgetPolicy(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "DeleteBudgetPolicy"))
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid policy ID",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	budgetSvc := economic.NewBudgetService(h.db.Conn(), h.logger)
+	if err := budgetSvc.DeletePolicy(c.Request.Context(), userID, policyID); err != nil {
+		if errors.Is(err, economic.ErrBudgetPolicyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "budget policy not found",
+				"message": err.Error(),
+			})
+			return
+		}
+		logger.Error("failed to delete budget policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to delete budget policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": policyID.String()})
+}
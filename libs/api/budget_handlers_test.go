@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/auth"
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// authTestSchema is the SQLite equivalent of the users and api_keys tables
+const authTestSchema = `
+CREATE TABLE users (
+	id TEXT PRIMARY KEY,
+	did TEXT UNIQUE NOT NULL,
+	email TEXT,
+	password_hash TEXT,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP,
+	last_login_at TIMESTAMP,
+	is_active BOOLEAN DEFAULT true,
+	metadata BLOB DEFAULT X'7B7D'
+);
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	key_hash TEXT NOT NULL,
+	key_prefix TEXT NOT NULL,
+	name TEXT,
+	scopes BLOB DEFAULT X'5B5D',
+	created_at TIMESTAMP,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	is_active BOOLEAN DEFAULT true
+);
+`
+
+// fakeBudgets records spend requests and rejects any request made with the
+// limited API key
+type fakeBudgets struct {
+	mu       sync.Mutex
+	limited  uuid.UUID
+	requests []*economic.SpendRequest
+	settled  map[string]float64
+}
+
+func (f *fakeBudgets) Authorize(ctx context.Context, req *economic.SpendRequest) (*economic.BudgetDecision, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, req)
+	if req.APIKeyID != nil && *req.APIKeyID == f.limited {
+		return nil, &economic.BudgetViolation{
+			Kind:      economic.BudgetLimitDaily,
+			Code:      "daily_limit_exceeded",
+			Scope:     "api_key",
+			Limit:     1,
+			Requested: req.Total(),
+		}
+	}
+	return &economic.BudgetDecision{}, nil
+}
+
+func (f *fakeBudgets) SettleTask(ctx context.Context, taskID string, cost float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.settled == nil {
+		f.settled = make(map[string]float64)
+	}
+	f.settled[taskID] = cost
+	return nil
+}
+
+func (f *fakeBudgets) settlement(taskID string) (float64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cost, ok := f.settled[taskID]
+	return cost, ok
+}
+
+func (f *fakeBudgets) ReleaseTask(ctx context.Context, taskID string) error { return nil }
+
+func (f *fakeBudgets) last() *economic.SpendRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+// insertAPIKey creates a user owning a new API key and returns the user, the
+// key's ID and the full key
+func insertAPIKey(t *testing.T, db *database.Database, did string) (uuid.UUID, uuid.UUID, string) {
+	t.Helper()
+
+	key, hash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+
+	userID, keyID := uuid.New(), uuid.New()
+	now := time.Now()
+	_, err = db.Conn().Exec(`INSERT INTO users (id, did, created_at, updated_at) VALUES ($1, $2, $3, $3)`, userID, did, now)
+	require.NoError(t, err)
+	_, err = db.Conn().Exec(`
+		INSERT INTO api_keys (id, user_id, key_hash, key_prefix, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, keyID, userID, hash, auth.APIKeyLookupPrefix(key), now)
+	require.NoError(t, err)
+	return userID, keyID, key
+}
+
+func TestSubmitTaskEnforcesAPIKeyBudgets(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.Conn().SetMaxOpenConns(1)
+	_, err = db.Conn().Exec(authTestSchema)
+	require.NoError(t, err)
+
+	limitedUser, limitedKey, limited := insertAPIKey(t, db, "did:user:limited")
+	_, openKey, open := insertAPIKey(t, db, "did:user:open")
+
+	queue := orchestration.NewTaskQueue(context.Background(), 10, zap.NewNop())
+	t.Cleanup(func() { queue.Close() })
+
+	budgets := &fakeBudgets{limited: limitedKey}
+	s := newTestServer(&Handlers{
+		logger:    zap.NewNop(),
+		db:        db,
+		taskQueue: queue,
+		budgets:   &BudgetGuard{budgets: budgets, logger: zap.NewNop()},
+	})
+	submit := func(authorization string) int {
+		body := bytes.NewBufferString(`{"query":"add","capabilities":["math"],"budget":5}`)
+		return serve(s, http.MethodPost, "/api/v1/tasks/submit", body, authorization).Code
+	}
+
+	// The limited key's own policy rejects the task
+	assert.Equal(t, http.StatusPaymentRequired, submit("Bearer "+limited))
+	req := budgets.last()
+	require.NotNil(t, req.APIKeyID)
+	assert.Equal(t, limitedKey, *req.APIKeyID)
+	assert.Equal(t, limitedUser.String(), req.UserID)
+
+	assert.Equal(t, http.StatusAccepted, submit("Bearer "+open))
+	require.NotNil(t, budgets.last().APIKeyID)
+	assert.Equal(t, openKey, *budgets.last().APIKeyID)
+
+	// Requests authenticated with a token carry no API key
+	assert.Equal(t, http.StatusAccepted, submit(testToken(t, uuid.New(), "did:user:jwt", false)))
+	assert.Nil(t, budgets.last().APIKeyID)
+
+	n := len(budgets.requests)
+	assert.Equal(t, http.StatusUnauthorized, submit("Bearer "+auth.APIKeyPrefix+"not-a-real-key"))
+	assert.Len(t, budgets.requests, n)
+}
+
+func TestAuthorizeSpendUsesContextAPIKey(t *testing.T) {
+	keyID := uuid.New()
+	budgets := &fakeBudgets{limited: keyID}
+	guard := &BudgetGuard{budgets: budgets, logger: zap.NewNop()}
+	tasks := []*orchestration.Task{orchestration.NewTask("did:user:1", "test", []string{"math"}, nil)}
+
+	require.NoError(t, guard.AuthorizeSpend(context.Background(), "user-1", tasks))
+	assert.Nil(t, budgets.last().APIKeyID)
+
+	err := guard.AuthorizeSpend(withAPIKeyID(context.Background(), keyID), "user-1", tasks)
+	var violation *economic.BudgetViolation
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, economic.SpendSourceBatch, budgets.last().Source)
+}
+
+// failingSelector finds no agent for any task
+type failingSelector struct{}
+
+func (failingSelector) SelectAgent(ctx context.Context, task *orchestration.Task) (*identity.AgentCard, error) {
+	return nil, errors.New("no agents available")
+}
+
+func TestFailedTaskFreesBudget(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	budgets := &fakeBudgets{}
+	guard := &BudgetGuard{budgets: budgets, logger: logger}
+
+	queue := orchestration.NewTaskQueue(ctx, 10, logger)
+	o := orchestration.NewOrchestrator(ctx, queue, failingSelector{}, orchestration.NewMockTaskExecutor(logger),
+		&orchestration.OrchestratorConfig{NumWorkers: 1}, logger)
+	o.AddResultObserver(guard)
+	require.NoError(t, o.Start())
+	defer o.Stop()
+
+	task := orchestration.NewTask("did:user:1", "test", []string{"math"}, nil)
+	task.Budget = 10
+	task.MaxRetries = 0
+	require.NoError(t, queue.Enqueue(task))
+
+	// The reservation is settled without charging anything
+	require.Eventually(t, func() bool {
+		_, ok := budgets.settlement(task.ID)
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	cost, _ := budgets.settlement(task.ID)
+	assert.Zero(t, cost)
+
+	got, err := queue.Get(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, orchestration.TaskStatusFailed, got.Status)
+}
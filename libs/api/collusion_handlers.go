@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/analytics"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SetBidderFlags attaches the flag list refreshed by the collusion scanner.
+// When it enforces, flagged bidders are refused on auction endpoints.
+func (h *Handlers) SetBidderFlags(flags *analytics.BidderFlags) {
+	h.bidderFlags = flags
+}
+
+// GetCollusionFindings scans recent bid history for collusion and shill bidding
+func (h *Handlers) GetCollusionFindings(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetCollusionFindings"))
+
+	// Parse lookback hours (default: one week)
+	lookbackHours := 24 * 7
+	if lookbackStr := c.Query("lookback_hours"); lookbackStr != "" {
+		if parsed, err := strconv.Atoi(lookbackStr); err == nil && parsed > 0 {
+			lookbackHours = parsed
+		}
+	}
+
+	metricsSvc := analytics.NewMetricsService(h.db.Conn(), h.logger)
+	findings, err := metricsSvc.DetectCollusion(c.Request.Context(), lookbackHours, nil)
+	if err != nil {
+		logger.Error("failed to detect collusion", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to detect collusion", "message": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"lookback_hours": lookbackHours,
+		"findings":       findings,
+		"count":          len(findings),
+		"detected_at":    time.Now().Format(time.RFC3339),
+	}
+	if h.bidderFlags != nil {
+		response["flagged"] = h.bidderFlags.Flagged()
+		response["enforcing"] = h.bidderFlags.Enforcing()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AllowFlaggedBidder clears a flagged bidder after review. Only operators may
+// clear flags.
+func (h *Handlers) AllowFlaggedBidder(c *gin.Context) {
+	if h.bidderFlags == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "collusion screening unavailable",
+			"message": "bidder flags are not enabled on this node",
+		})
+		return
+	}
+
+	did := c.Param("did")
+	h.bidderFlags.Allow(did)
+
+	h.logger.Info("flagged bidder allowed after review", zap.String("agent_did", did))
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_did": did,
+		"status":    "allowed",
+	})
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/analytics"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowFlaggedBidderRequiresOperator(t *testing.T) {
+	flags := analytics.NewBidderFlags(analytics.SeverityHigh, true)
+	flags.Update([]analytics.CollusionFinding{{
+		Type:     analytics.CollusionSharedCredential,
+		Severity: analytics.SeverityHigh,
+		DIDs:     []string{"did:agent:shill"},
+	}})
+	s := newTestServer(&Handlers{bidderFlags: flags})
+	path := "/api/v1/analytics/collusion/flags/did:agent:shill"
+
+	// Neither an ordinary user nor the flagged agent itself may clear a flag
+	w := serve(s, http.MethodDelete, path, nil, testToken(t, uuid.New(), "did:user:1", false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serve(s, http.MethodDelete, path, nil, testToken(t, uuid.New(), "did:agent:shill", false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.True(t, flags.IsFlagged("did:agent:shill"))
+
+	w = serve(s, http.MethodDelete, path, nil, testToken(t, uuid.New(), "did:operator:1", true))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, flags.IsFlagged("did:agent:shill"))
+}
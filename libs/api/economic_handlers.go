@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -19,10 +20,15 @@ func (h *Handlers) CreateAuction(c *gin.Context) {
 	logger := h.logger.With(zap.String("handler", "CreateAuction"))
 
 	var req struct {
-		TaskID      string  `json:"task_id" binding:"required"`
-		MinBid      float64 `json:"min_bid" binding:"required,gt=0"`
-		Duration    int     `json:"duration" binding:"required,gt=0"` // seconds
-		Description string  `json:"description"`
+		TaskID       string   `json:"task_id" binding:"required"`
+		MinBid       float64  `json:"min_bid" binding:"omitempty,gt=0"`
+		Duration     int      `json:"duration" binding:"required,gt=0"` // seconds
+		Description  string   `json:"description"`
+		Capabilities []string `json:"capabilities"`
+
+		// ReservePriceRef sets the reserve from the price index, e.g. "p25"
+		// for the 25th percentile of recently cleared prices
+		ReservePriceRef string `json:"reserve_price_ref"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -34,9 +40,36 @@ func (h *Handlers) CreateAuction(c *gin.Context) {
 		return
 	}
 
+	if req.ReservePriceRef != "" {
+		reserve, err := h.resolveReservePrice(req.Capabilities, req.ReservePriceRef)
+		if err != nil {
+			logger.Warn("failed to resolve reserve price",
+				zap.String("reserve_price_ref", req.ReservePriceRef),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid reserve price reference",
+				"message": err.Error(),
+			})
+			return
+		}
+		req.MinBid = reserve
+	}
+
+	if req.MinBid <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": "min_bid or reserve_price_ref is required",
+		})
+		return
+	}
+
 	// Create auction using economic service
 	econSvc := economic.NewEconomicService(h.db)
-	capabilities, _ := json.Marshal([]string{})
+	if req.Capabilities == nil {
+		req.Capabilities = []string{}
+	}
+	capabilities, _ := json.Marshal(req.Capabilities)
 
 	auction, err := econSvc.CreateAuction(c.Request.Context(), req.TaskID, "user_id",
 		economic.AuctionTypeFirstPrice, req.Duration, &req.MinBid, nil, nil, capabilities)
@@ -53,17 +86,20 @@ func (h *Handlers) CreateAuction(c *gin.Context) {
 		zap.String("auction_id", auction.ID.String()),
 		zap.String("task_id", req.TaskID),
 		zap.Float64("min_bid", req.MinBid),
+		zap.String("reserve_price_ref", req.ReservePriceRef),
 	)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"auction_id":  auction.ID.String(),
-		"task_id":     req.TaskID,
-		"min_bid":     req.MinBid,
-		"status":      auction.Status,
-		"bids_count":  0,
-		"expires_at":  auction.ExpiresAt.Format(time.RFC3339),
-		"created_at":  auction.CreatedAt.Format(time.RFC3339),
-		"description": req.Description,
+		"auction_id":        auction.ID.String(),
+		"task_id":           req.TaskID,
+		"min_bid":           req.MinBid,
+		"status":            auction.Status,
+		"bids_count":        0,
+		"expires_at":        auction.ExpiresAt.Format(time.RFC3339),
+		"created_at":        auction.CreatedAt.Format(time.RFC3339),
+		"description":       req.Description,
+		"capabilities":      req.Capabilities,
+		"reserve_price_ref": req.ReservePriceRef,
 	})
 }
 
@@ -96,9 +132,24 @@ func (h *Handlers) SubmitBid(c *gin.Context) {
 		return
 	}
 
-	// Submit bid using economic service
+	// Submit bid using economic service, recording its origin for collusion analysis
 	econSvc := economic.NewEconomicService(h.db)
-	bid, err := econSvc.SubmitBid(c.Request.Context(), auctionID, req.AgentID, req.Amount, nil)
+	if h.bidderFlags != nil {
+		econSvc.SetBidderScreen(h.bidderFlags)
+	}
+	submittedBy, _ := getUserIDString(c)
+	bid, err := econSvc.SubmitBidFrom(c.Request.Context(), auctionID, req.AgentID, req.Amount, nil, economic.BidOrigin{
+		SubmittedBy: submittedBy,
+		SourceIP:    c.ClientIP(),
+	})
+	if errors.Is(err, economic.ErrBidderFlagged) {
+		logger.Warn("bid from flagged bidder rejected", zap.String("agent_id", req.AgentID))
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "bidder excluded",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		logger.Error("failed to submit bid", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -146,9 +197,16 @@ func (h *Handlers) OpenPaymentChannel(c *gin.Context) {
 		return
 	}
 
+	payerDID := c.GetString("user_did")
+	if payerDID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
 	// Open payment channel using economic service
 	econSvc := economic.NewEconomicService(h.db)
-	channel, err := econSvc.OpenPaymentChannel(c.Request.Context(), "payer_did", req.AgentID, req.InitialAmount, nil)
+	econSvc.SetLedger(h.journal())
+	channel, err := econSvc.OpenPaymentChannel(c.Request.Context(), payerDID, req.AgentID, req.InitialAmount, nil)
 	if err != nil {
 		logger.Error("failed to open payment channel", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -207,6 +265,7 @@ func (h *Handlers) SettlePaymentChannel(c *gin.Context) {
 
 	// Settle payment channel using economic service
 	econSvc := economic.NewEconomicService(h.db)
+	econSvc.SetLedger(h.journal())
 	err = econSvc.SettlePaymentChannel(c.Request.Context(), channelID, req.FinalAmount)
 	if err != nil {
 		logger.Error("failed to settle payment channel", zap.Error(err))
@@ -374,11 +433,9 @@ func (h *Handlers) DelegateToMetaOrchestrator(c *gin.Context) {
 	}
 
 	// Get user ID from JWT token (if available, otherwise use "anonymous")
-	userID := "anonymous"
-	if userIDValue, exists := c.Get("user_id"); exists {
-		if uid, ok := userIDValue.(string); ok {
-			userID = uid
-		}
+	userID, ok := getUserIDString(c)
+	if !ok {
+		userID = "anonymous"
 	}
 
 	// Set default priority if not provided
@@ -386,6 +443,25 @@ func (h *Handlers) DelegateToMetaOrchestrator(c *gin.Context) {
 		req.Priority = "normal"
 	}
 
+	// Enforce the user's budget policies on the delegated budget
+	guard := h.budgetGuard()
+	if guard != nil {
+		err := guard.Authorize(c.Request.Context(), &economic.SpendRequest{
+			UserID:   userID,
+			APIKeyID: getAPIKeyID(c),
+			Source:   economic.SpendSourceDelegation,
+			Items: []economic.SpendItem{{
+				TaskID:       req.TaskID,
+				Capabilities: req.Capabilities,
+				Amount:       req.Budget,
+			}},
+		})
+		if err != nil {
+			respondBudgetError(c, logger, err)
+			return
+		}
+	}
+
 	// Create delegation using real meta-orchestrator service
 	metaSvc := economic.NewMetaOrchestratorService(h.db.Conn(), h.logger)
 	delegation, subtasks, err := metaSvc.CreateDelegation(
@@ -402,6 +478,9 @@ func (h *Handlers) DelegateToMetaOrchestrator(c *gin.Context) {
 			zap.String("task_id", req.TaskID),
 			zap.Error(err),
 		)
+		if guard != nil {
+			guard.Release(c.Request.Context(), req.TaskID)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to create delegation",
 			"message": err.Error(),
@@ -514,6 +593,19 @@ func (h *Handlers) GetOrchestrationStatus(c *gin.Context) {
 
 // Escrow Handlers
 
+// SetPlatformFee sets the share of each escrow release charged as a platform fee
+func (h *Handlers) SetPlatformFee(rate float64) {
+	h.platformFee = rate
+}
+
+// escrowService returns an escrow service over the handlers' database
+func (h *Handlers) escrowService() *economic.EscrowService {
+	svc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	svc.SetPlatformFee(h.platformFee)
+	svc.SetAccountResolver(economic.UserDIDResolver)
+	return svc
+}
+
 // CreateEscrow handles escrow creation for task payments
 func (h *Handlers) CreateEscrow(c *gin.Context) {
 	logger := h.logger.With(zap.String("handler", "CreateEscrow"))
@@ -544,7 +636,7 @@ func (h *Handlers) CreateEscrow(c *gin.Context) {
 	payerID := userID.(string)
 
 	// Create escrow using economic service
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	escrow, err := escrowSvc.CreateEscrow(
 		c.Request.Context(),
@@ -556,6 +648,13 @@ func (h *Handlers) CreateEscrow(c *gin.Context) {
 		req.AutoReleaseMinutes,
 		req.Conditions,
 	)
+	if errors.Is(err, economic.ErrInvalidConditions) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid escrow conditions",
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		logger.Error("failed to create escrow", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -612,7 +711,7 @@ func (h *Handlers) FundEscrow(c *gin.Context) {
 	}
 
 	// Fund escrow
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	err = escrowSvc.FundEscrow(c.Request.Context(), escrowID, req.Signature)
 	if err != nil {
@@ -656,7 +755,7 @@ func (h *Handlers) ReleaseEscrow(c *gin.Context) {
 	releasedBy := userID.(string)
 
 	// Release escrow
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	err = escrowSvc.ReleaseEscrow(c.Request.Context(), escrowID, releasedBy)
 	if err != nil {
@@ -703,7 +802,7 @@ func (h *Handlers) RefundEscrow(c *gin.Context) {
 	refundedBy := userID.(string)
 
 	// Refund escrow
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	err = escrowSvc.RefundEscrow(c.Request.Context(), escrowID, refundedBy)
 	if err != nil {
@@ -743,7 +842,7 @@ func (h *Handlers) GetEscrow(c *gin.Context) {
 	}
 
 	// Get escrow
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	escrow, err := escrowSvc.GetEscrow(c.Request.Context(), escrowID)
 	if err != nil {
@@ -821,7 +920,7 @@ func (h *Handlers) OpenDispute(c *gin.Context) {
 	initiatorID := userID.(string)
 
 	// Open dispute
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	dispute, err := escrowSvc.OpenDispute(c.Request.Context(), escrowID, initiatorID, req.Reason)
 	if err != nil {
@@ -886,7 +985,7 @@ func (h *Handlers) SubmitEvidence(c *gin.Context) {
 	submitterID := userID.(string)
 
 	// Submit evidence
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	evidence, err := escrowSvc.SubmitEvidence(
 		c.Request.Context(),
@@ -963,7 +1062,7 @@ func (h *Handlers) ResolveDispute(c *gin.Context) {
 	reviewerID := userID.(string)
 
 	// Resolve dispute
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	err = escrowSvc.ResolveDispute(
 		c.Request.Context(),
@@ -1012,7 +1111,7 @@ func (h *Handlers) GetDispute(c *gin.Context) {
 	}
 
 	// Get dispute
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 
 	dispute, err := escrowSvc.GetDispute(c.Request.Context(), disputeID)
 	if err != nil {
@@ -1072,6 +1171,8 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 		Input   string  `json:"input" binding:"required"`
 		Budget  float64 `json:"budget" binding:"required,gt=0"`
 		Timeout int     `json:"timeout"` // Seconds, default: 30
+
+		InputArtifacts []execution.InputArtifact `json:"input_artifacts"` // Mounted read-only under /input
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1082,6 +1183,15 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 		})
 		return
 	}
+	for _, artifact := range req.InputArtifacts {
+		if err := artifact.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
 
 	// Set default timeout
 	timeout := time.Duration(req.Timeout) * time.Second
@@ -1128,7 +1238,7 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 
 	// Initialize economic executor if not already done
 	if h.execHandlers.economicExec == nil {
-		escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+		escrowSvc := h.escrowService()
 		economicMetrics := execution.NewEconomicTaskMetrics(nil) // Uses default registry
 		h.execHandlers.economicExec = execution.NewEconomicExecutor(
 			h.wasmRunner,
@@ -1138,12 +1248,15 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 			economicMetrics,
 			h.logger,
 		)
+		if h.s3Storage != nil {
+			h.execHandlers.economicExec.SetArtifactStore(h.s3Storage)
+		}
 	}
 
 	econExec := h.execHandlers.economicExec
 
 	// Step 1: Create escrow for payment
-	escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+	escrowSvc := h.escrowService()
 	escrow, err := escrowSvc.CreateEscrow(
 		c.Request.Context(),
 		req.TaskID,
@@ -1198,6 +1311,8 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 		Budget:   req.Budget,
 		EscrowID: escrowID,
 		Timeout:  timeout,
+
+		InputArtifacts: req.InputArtifacts,
 	}
 
 	result, err := econExec.ExecuteWithEconomics(c.Request.Context(), execReq)
@@ -1223,6 +1338,7 @@ func (h *Handlers) ExecuteEconomicTask(c *gin.Context) {
 		"error":            result.Error,
 		"execution_time":   result.ExecutionTime.String(),
 		"resource_usage":   result.ResourceUsage,
+		"artifacts":        result.Artifacts,
 		"escrow_id":        result.EscrowID.String(),
 		"escrow_status":    result.EscrowStatus,
 		"amount_paid":      result.AmountPaid,
@@ -1258,7 +1374,7 @@ func (h *Handlers) GetEconomicTaskResult(c *gin.Context) {
 
 	// Initialize economic executor if not already done
 	if h.execHandlers.economicExec == nil {
-		escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+		escrowSvc := h.escrowService()
 		economicMetrics := execution.NewEconomicTaskMetrics(nil) // Uses default registry
 		h.execHandlers.economicExec = execution.NewEconomicExecutor(
 			h.wasmRunner,
@@ -1293,7 +1409,7 @@ func (h *Handlers) EconomicHealthCheck(c *gin.Context) {
 
 	// Initialize economic executor if not already done
 	if h.execHandlers.economicExec == nil {
-		escrowSvc := economic.NewEscrowService(h.db.Conn(), h.logger)
+		escrowSvc := h.escrowService()
 		economicMetrics := execution.NewEconomicTaskMetrics(nil) // Uses default registry
 		h.execHandlers.economicExec = execution.NewEconomicExecutor(
 			h.wasmRunner,
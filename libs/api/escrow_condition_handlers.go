@@ -0,0 +1,402 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Escrow Condition Handlers
+
+// SubmitEscrowResult records a task result against an escrow and evaluates its release conditions
+func (h *Handlers) SubmitEscrowResult(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "SubmitEscrowResult"))
+
+	escrowID, ok := h.parseEscrowID(c, logger)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Result json.RawMessage `json:"result" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	escrowSvc := h.escrowService()
+
+	if !h.authorizeEscrowParty(c, logger, escrowSvc, escrowID) {
+		return
+	}
+
+	eval, err := escrowSvc.RecordTaskResult(c.Request.Context(), escrowID, req.Result)
+	if err != nil {
+		logger.Error("failed to record escrow result", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to record escrow result",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respondConditionEvaluation(c, escrowID, eval)
+}
+
+// SubmitEscrowApproval records a validator approval or rejection for an escrow
+func (h *Handlers) SubmitEscrowApproval(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "SubmitEscrowApproval"))
+
+	escrowID, ok := h.parseEscrowID(c, logger)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Approved *bool `json:"approved" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Extract user ID from JWT token
+	approverID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	escrowSvc := h.escrowService()
+
+	eval, err := escrowSvc.RecordApproval(c.Request.Context(), escrowID, approverID, *req.Approved)
+	if err != nil {
+		logger.Error("failed to record escrow approval", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to record escrow approval",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respondConditionEvaluation(c, escrowID, eval)
+}
+
+// SubmitEscrowSignature records a participant release signature for an escrow
+func (h *Handlers) SubmitEscrowSignature(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "SubmitEscrowSignature"))
+
+	escrowID, ok := h.parseEscrowID(c, logger)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Signature string `json:"signature" binding:"required"` // hex ed25519 signature over the release message
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Extract user ID from JWT token
+	signerID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	escrowSvc := h.escrowService()
+
+	eval, err := escrowSvc.RecordSignature(c.Request.Context(), escrowID, signerID, req.Signature)
+	if errors.Is(err, economic.ErrUnknownSigner) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "signer not allowed",
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("failed to record escrow signature", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to record escrow signature",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respondConditionEvaluation(c, escrowID, eval)
+}
+
+// SubmitEscrowMilestone records that a milestone was reached for an escrow
+func (h *Handlers) SubmitEscrowMilestone(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "SubmitEscrowMilestone"))
+
+	escrowID, ok := h.parseEscrowID(c, logger)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Milestone string `json:"milestone" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	escrowSvc := h.escrowService()
+
+	if !h.authorizeEscrowParty(c, logger, escrowSvc, escrowID) {
+		return
+	}
+
+	eval, err := escrowSvc.RecordMilestone(c.Request.Context(), escrowID, req.Milestone)
+	if err != nil {
+		logger.Error("failed to record escrow milestone", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to record escrow milestone",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.respondConditionEvaluation(c, escrowID, eval)
+}
+
+// GetEscrowEvaluations returns the condition evaluation audit log for an escrow
+func (h *Handlers) GetEscrowEvaluations(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetEscrowEvaluations"))
+
+	escrowID, ok := h.parseEscrowID(c, logger)
+	if !ok {
+		return
+	}
+
+	escrowSvc := h.escrowService()
+
+	evaluations, err := escrowSvc.GetConditionEvaluations(c.Request.Context(), escrowID)
+	if err != nil {
+		logger.Error("failed to get escrow evaluations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get escrow evaluations",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"escrow_id":   escrowID.String(),
+		"evaluations": evaluations,
+		"count":       len(evaluations),
+	})
+}
+
+// authorizeEscrowParty checks that the caller is the escrow's payer, its payee
+// or the agent assigned its task, writing a 401, 403 or 404 response otherwise
+func (h *Handlers) authorizeEscrowParty(c *gin.Context, logger *zap.Logger, escrowSvc *economic.EscrowService, escrowID uuid.UUID) bool {
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	userDID := c.GetString("user_did")
+
+	escrow, err := escrowSvc.GetEscrow(c.Request.Context(), escrowID)
+	if err != nil {
+		logger.Warn("failed to load escrow", zap.String("escrow_id", escrowID.String()), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "escrow not found",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	switch {
+	case escrow.PayerID == userID, escrow.PayeeID == userID:
+		return true
+	case userDID != "" && escrow.PayeeID == userDID:
+		return true
+	case userDID != "" && h.taskQueue != nil:
+		if task, err := h.taskQueue.Get(escrow.TaskID); err == nil && task.AssignedTo == userDID {
+			return true
+		}
+	}
+
+	logger.Warn("escrow access denied",
+		zap.String("escrow_id", escrowID.String()),
+		zap.String("user_id", userID),
+	)
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "forbidden",
+		"message": "only the escrow's payer, payee or assigned agent may do this",
+	})
+	return false
+}
+
+// parseEscrowID parses the :id path parameter, writing a 400 response on failure
+func (h *Handlers) parseEscrowID(c *gin.Context, logger *zap.Logger) (uuid.UUID, bool) {
+	escrowIDStr := c.Param("id")
+	escrowID, err := uuid.Parse(escrowIDStr)
+	if err != nil {
+		logger.Error("invalid escrow ID", zap.String("escrow_id", escrowIDStr), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid escrow ID",
+			"message": err.Error(),
+		})
+		return uuid.Nil, false
+	}
+	return escrowID, true
+}
+
+// respondConditionEvaluation writes the result of a condition evaluation
+func (h *Handlers) respondConditionEvaluation(c *gin.Context, escrowID uuid.UUID, eval *economic.ConditionEvaluation) {
+	if eval == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"escrow_id": escrowID.String(),
+			"evaluated": false,
+			"message":   "fact recorded; escrow has no conditions or is not funded",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"escrow_id":  escrowID.String(),
+		"evaluated":  true,
+		"evaluation": eval,
+	})
+}
+
+// EscrowConditionObserver feeds orchestrator task results into the task's
+// escrow: condition evaluation, a split between the agents that agreed on a
+// replicated result, and pro-rated settlement of canceled tasks
+type EscrowConditionObserver struct {
+	escrowSvc *economic.EscrowService
+	logger    *zap.Logger
+}
+
+// NewEscrowConditionObserver creates an observer backed by the escrow tables in db
+func NewEscrowConditionObserver(db *database.Database, logger *zap.Logger) *EscrowConditionObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	escrowSvc := economic.NewEscrowService(db.Conn(), logger)
+	escrowSvc.SetAccountResolver(economic.UserDIDResolver)
+	return &EscrowConditionObserver{
+		escrowSvc: escrowSvc,
+		logger:    logger,
+	}
+}
+
+// SetPlatformFee charges a platform fee on escrows released by their conditions
+func (o *EscrowConditionObserver) SetPlatformFee(rate float64) {
+	o.escrowSvc.SetPlatformFee(rate)
+}
+
+// OnTaskResult settles or evaluates the task's escrow, if any, by the result
+func (o *EscrowConditionObserver) OnTaskResult(ctx context.Context, task *orchestration.Task, result *orchestration.TaskResult) {
+	if result == nil {
+		return
+	}
+	if result.Status != orchestration.TaskStatusCompleted && result.Status != orchestration.TaskStatusCanceled {
+		return
+	}
+
+	escrow, err := o.escrowSvc.GetEscrowByTaskID(ctx, task.ID)
+	if err != nil {
+		return
+	}
+
+	if result.Status == orchestration.TaskStatusCanceled {
+		o.settleCanceled(ctx, task, escrow)
+		return
+	}
+
+	// Conditions decide escrows that have them
+	if shares := orchestration.ReplicaShares(task); len(shares) > 1 && escrow.Conditions == "" {
+		o.splitReplicated(ctx, task, escrow, shares)
+		return
+	}
+	if escrow.Conditions == "" {
+		return
+	}
+
+	payload, err := json.Marshal(result.Result)
+	if err != nil {
+		o.logger.Warn("failed to encode task result for escrow conditions",
+			zap.String("task_id", task.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if _, err := o.escrowSvc.RecordTaskResult(ctx, escrow.ID, payload); err != nil {
+		o.logger.Warn("failed to evaluate escrow conditions for task result",
+			zap.String("task_id", task.ID),
+			zap.String("escrow_id", escrow.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// splitReplicated pays each agent that agreed on a replicated task's result
+// its share of the escrow and refunds the rest
+func (o *EscrowConditionObserver) splitReplicated(ctx context.Context, task *orchestration.Task, escrow *economic.Escrow, shares map[string]float64) {
+	if escrow.Status != economic.EscrowStatusFunded {
+		return
+	}
+	if err := o.escrowSvc.SplitEscrow(ctx, escrow.ID, shares, "system"); err != nil {
+		o.logger.Error("failed to split escrow between replica agents",
+			zap.String("task_id", task.ID),
+			zap.String("escrow_id", escrow.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// settleCanceled pays the agent of a canceled task what it earned before it
+// stopped and refunds the rest of the escrow
+func (o *EscrowConditionObserver) settleCanceled(ctx context.Context, task *orchestration.Task, escrow *economic.Escrow) {
+	if escrow.Status != economic.EscrowStatusFunded {
+		return
+	}
+	earned := math.Min(math.Max(task.ActualCost, 0), escrow.Amount)
+	if err := o.escrowSvc.SettleEscrow(ctx, escrow.ID, earned, "system"); err != nil {
+		o.logger.Error("failed to settle escrow of canceled task",
+			zap.String("task_id", task.ID),
+			zap.String("escrow_id", escrow.ID.String()),
+			zap.Float64("earned", earned),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const escrowTestSchema = `
+CREATE TABLE escrows (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	payer_id TEXT NOT NULL,
+	payee_id TEXT NOT NULL,
+	amount REAL NOT NULL,
+	status TEXT,
+	funded_at TIMESTAMP,
+	released_at TIMESTAMP,
+	refunded_at TIMESTAMP,
+	dispute_id TEXT,
+	expires_at TIMESTAMP NOT NULL,
+	auto_release_at TIMESTAMP,
+	conditions TEXT,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP,
+	error TEXT
+);
+CREATE TABLE escrow_condition_facts (
+	id TEXT PRIMARY KEY,
+	escrow_id TEXT NOT NULL,
+	fact_type TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	value TEXT NOT NULL,
+	created_at TIMESTAMP
+);
+CREATE TABLE ledger_accounts (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE ledger_entries (
+	id TEXT PRIMARY KEY,
+	idempotency_key TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	reference TEXT NOT NULL DEFAULT '',
+	metadata BLOB,
+	reverses_id TEXT,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE ledger_postings (
+	entry_id TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	account_id TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (entry_id, line)
+);
+`
+
+// newEscrowTestHandlers returns handlers backed by an in-memory escrow table
+// holding one escrow from payer to payee for taskID
+func newEscrowTestHandlers(t *testing.T, taskID, payerID, payeeID string) (*Handlers, uuid.UUID) {
+	t.Helper()
+
+	db, err := database.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.Conn().SetMaxOpenConns(1)
+
+	_, err = db.Conn().Exec(escrowTestSchema)
+	require.NoError(t, err)
+
+	escrowID := uuid.New()
+	now := time.Now()
+	_, err = db.Conn().Exec(`
+		INSERT INTO escrows (id, task_id, payer_id, payee_id, amount, status, expires_at, conditions, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 10, 'funded', $5, '', $6, $6)
+	`, escrowID, taskID, payerID, payeeID, now.Add(time.Hour), now)
+	require.NoError(t, err)
+
+	queue := orchestration.NewTaskQueue(context.Background(), 10, zap.NewNop())
+	t.Cleanup(func() { queue.Close() })
+
+	return &Handlers{logger: zap.NewNop(), db: db, taskQueue: queue}, escrowID
+}
+
+// escrowTestRouter routes escrow condition requests as the user named by the
+// X-User-ID and X-User-DID headers; neither header means unauthenticated
+func escrowTestRouter(h *Handlers) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-User-ID"); id != "" {
+			c.Set("user_id", id)
+		}
+		if did := c.GetHeader("X-User-DID"); did != "" {
+			c.Set("user_did", did)
+		}
+	})
+	r.POST("/escrows/:id/result", h.SubmitEscrowResult)
+	r.POST("/escrows/:id/approvals", h.SubmitEscrowApproval)
+	r.POST("/escrows/:id/signatures", h.SubmitEscrowSignature)
+	r.POST("/escrows/:id/milestones", h.SubmitEscrowMilestone)
+	return r
+}
+
+func serveEscrowRequest(r *gin.Engine, path, body, userID, userDID string) int {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	if userDID != "" {
+		req.Header.Set("X-User-DID", userDID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestEscrowConditionHandlersRejectNonParties(t *testing.T) {
+	h, escrowID := newEscrowTestHandlers(t, "task-1", "payer-user", "did:agent:payee")
+	r := escrowTestRouter(h)
+	base := "/escrows/" + escrowID.String()
+
+	assert.Equal(t, http.StatusForbidden, serveEscrowRequest(r, base+"/result", `{"result":{"sum":42}}`, "stranger", "did:user:stranger"))
+	assert.Equal(t, http.StatusForbidden, serveEscrowRequest(r, base+"/milestones", `{"milestone":"final"}`, "stranger", "did:user:stranger"))
+
+	assert.Equal(t, http.StatusUnauthorized, serveEscrowRequest(r, base+"/result", `{"result":{"sum":42}}`, "", ""))
+	assert.Equal(t, http.StatusUnauthorized, serveEscrowRequest(r, base+"/milestones", `{"milestone":"final"}`, "", ""))
+	assert.Equal(t, http.StatusUnauthorized, serveEscrowRequest(r, base+"/approvals", `{"approved":true}`, "", ""))
+	assert.Equal(t, http.StatusUnauthorized, serveEscrowRequest(r, base+"/signatures", `{"signature":"00"}`, "", ""))
+
+	assert.Equal(t, http.StatusNotFound, serveEscrowRequest(r, "/escrows/"+uuid.NewString()+"/result", `{"result":{}}`, "payer-user", ""))
+}
+
+func TestEscrowConditionHandlersAllowParties(t *testing.T) {
+	h, escrowID := newEscrowTestHandlers(t, "task-1", "payer-user", "did:agent:payee")
+	r := escrowTestRouter(h)
+	base := "/escrows/" + escrowID.String()
+
+	task := orchestration.NewTask("did:user:payer", "test", []string{"test"}, nil)
+	task.ID = "task-1"
+	task.AssignedTo = "did:agent:worker"
+	require.NoError(t, h.taskQueue.Enqueue(task))
+
+	assert.Equal(t, http.StatusOK, serveEscrowRequest(r, base+"/result", `{"result":{"sum":42}}`, "payer-user", ""))
+	assert.Equal(t, http.StatusOK, serveEscrowRequest(r, base+"/milestones", `{"milestone":"draft"}`, "payee-user", "did:agent:payee"))
+	assert.Equal(t, http.StatusOK, serveEscrowRequest(r, base+"/result", `{"result":{"sum":42}}`, "agent-user", "did:agent:worker"))
+}
+
+func TestEscrowObserverSettlesCanceledAndReplicatedTasks(t *testing.T) {
+	ctx := context.Background()
+	h, canceledEscrow := newEscrowTestHandlers(t, "task-canceled", "payer-user", "did:agent:payee")
+	replicatedEscrow := uuid.New()
+	now := time.Now()
+	_, err := h.db.Conn().Exec(`
+		INSERT INTO escrows (id, task_id, payer_id, payee_id, amount, status, expires_at, conditions, created_at, updated_at)
+		VALUES ($1, 'task-replicated', 'payer-user', 'did:agent:a', 10, 'funded', $2, '', $3, $3)
+	`, replicatedEscrow, now.Add(time.Hour), now)
+	require.NoError(t, err)
+
+	observer := NewEscrowConditionObserver(h.db, zap.NewNop())
+
+	// A task canceled after earning 4 pays 4 and refunds the rest
+	canceled := orchestration.NewTask("did:user:payer", "test", []string{"test"}, nil)
+	canceled.ID = "task-canceled"
+	canceled.ActualCost = 4
+	observer.OnTaskResult(ctx, canceled, &orchestration.TaskResult{TaskID: canceled.ID, Status: orchestration.TaskStatusCanceled})
+
+	// A replicated task pays every agent that agreed
+	replicated := orchestration.NewTask("did:user:payer", "test", []string{"test"}, nil)
+	replicated.ID = "task-replicated"
+	replicated.Replicas = []orchestration.ReplicaResult{
+		{AgentDID: "did:agent:a", Agreed: true, Payout: 3},
+		{AgentDID: "did:agent:b", Agreed: true, Payout: 3},
+		{AgentDID: "did:agent:c", Agreed: false},
+	}
+	observer.OnTaskResult(ctx, replicated, &orchestration.TaskResult{TaskID: replicated.ID, Status: orchestration.TaskStatusCompleted})
+
+	escrows := economic.NewEscrowService(h.db.Conn(), nil)
+	for _, id := range []uuid.UUID{canceledEscrow, replicatedEscrow} {
+		escrow, err := escrows.GetEscrow(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, economic.EscrowStatusReleased, escrow.Status)
+	}
+	for account, expected := range map[string]float64{
+		ledger.UserAccount("did:agent:payee"): 4,
+		ledger.UserAccount("did:agent:a"):     3,
+		ledger.UserAccount("did:agent:b"):     3,
+		ledger.UserAccount("did:agent:c"):     0,
+		ledger.UserAccount("payer-user"):      10,
+	} {
+		balance, err := escrows.Ledger().Balance(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, ledger.FromFloat(expected), balance, account)
+	}
+}
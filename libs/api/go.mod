@@ -11,6 +11,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/libp2p/go-libp2p v0.39.1
 	github.com/prometheus/client_golang v1.23.2
+	github.com/stretchr/testify v1.11.1
 	go.opentelemetry.io/otel v1.38.0
 	go.opentelemetry.io/otel/trace v1.38.0
 	go.uber.org/zap v1.27.0
@@ -87,7 +88,6 @@ require (
 	github.com/quic-go/quic-go v0.49.0 // indirect
 	github.com/quic-go/webtransport-go v0.8.1-0.20241018022711-4ac2c9250e66 // indirect
 	github.com/raulk/go-watchdog v1.3.0 // indirect
-	github.com/stretchr/testify v1.11.1 // indirect
 	github.com/wlynxg/anet v0.0.5 // indirect
 	go.opentelemetry.io/otel/sdk/metric v1.38.0 // indirect
 	go.uber.org/dig v1.18.0 // indirect
@@ -104,8 +104,11 @@ require (
 
 require (
 	github.com/aidenlippert/zerostate/libs/auth v0.0.0
+	github.com/aidenlippert/zerostate/libs/billing v0.0.0
 	github.com/aidenlippert/zerostate/libs/database v0.0.0
 	github.com/aidenlippert/zerostate/libs/economic v0.0.0
+	github.com/aidenlippert/zerostate/libs/ledger v0.0.0
+	github.com/aidenlippert/zerostate/libs/scoring v0.0.0
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/bytedance/sonic v1.11.6 // indirect
 	github.com/bytedance/sonic/loader v0.1.1 // indirect
@@ -172,3 +175,9 @@ replace github.com/aidenlippert/zerostate/libs/auth => ../auth
 replace github.com/aidenlippert/zerostate/libs/database => ../database
 
 replace github.com/aidenlippert/zerostate/libs/economic => ../economic
+
+replace github.com/aidenlippert/zerostate/libs/ledger => ../ledger
+
+replace github.com/aidenlippert/zerostate/libs/billing => ../billing
+
+replace github.com/aidenlippert/zerostate/libs/scoring => ../scoring
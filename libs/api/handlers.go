@@ -5,14 +5,18 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/aidenlippert/zerostate/libs/analytics"
 	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
 	"github.com/aidenlippert/zerostate/libs/execution"
 	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/aidenlippert/zerostate/libs/metrics"
 	"github.com/aidenlippert/zerostate/libs/orchestration"
 	"github.com/aidenlippert/zerostate/libs/search"
 	"github.com/aidenlippert/zerostate/libs/storage"
 	"github.com/aidenlippert/zerostate/libs/substrate"
+	"github.com/aidenlippert/zerostate/libs/validation"
 	"github.com/aidenlippert/zerostate/libs/websocket"
 	"github.com/gin-gonic/gin"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -57,6 +61,27 @@ type Handlers struct {
 	runtimeRegistry *orchestration.RuntimeRegistry
 	promMetrics     *metrics.PrometheusMetrics
 	promRegistry    *prometheus.Registry
+
+	// Rolling cleared-price percentiles, when enabled
+	priceIndex *economic.PriceIndex
+
+	// Bidders flagged by collusion detection, when enabled
+	bidderFlags *analytics.BidderFlags
+
+	// Budget guard shared with the orchestrator; built from db when nil
+	budgets *BudgetGuard
+
+	// Admission policy for uploaded agent binaries; the default when nil
+	admissionPolicy *validation.AdmissionPolicy
+
+	// Shared SQL journal; built from db when nil
+	ledger *ledger.Ledger
+
+	// Share of each escrow release charged as a platform fee
+	platformFee float64
+
+	// Scheduled ledger reconciliation, when enabled
+	reconciliation *ledger.ReconciliationJob
 }
 
 // NewHandlers creates a new Handlers instance
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/aidenlippert/zerostate/libs/substrate"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Ledger Handlers
+
+// SetLedger shares the node's SQL journal with the handlers
+func (h *Handlers) SetLedger(l *ledger.Ledger) {
+	h.ledger = l
+}
+
+// SetReconciliationJob serves reconciliation reports from a scheduled job
+func (h *Handlers) SetReconciliationJob(job *ledger.ReconciliationJob) {
+	h.reconciliation = job
+}
+
+// journal returns the shared ledger, or one over the handlers' database
+func (h *Handlers) journal() *ledger.Ledger {
+	if h.ledger != nil {
+		return h.ledger
+	}
+	return ledger.New(ledger.NewSQLStore(h.db.Conn()), h.logger)
+}
+
+// NewChainEscrowSource returns the on-chain escrow view used for
+// reconciliation, or nil when the blockchain is disabled
+func NewChainEscrowSource(blockchain *substrate.BlockchainService) ledger.ChainEscrowSource {
+	if blockchain == nil || !blockchain.IsEnabled() {
+		return nil
+	}
+	escrowClient := blockchain.Escrow()
+	if escrowClient == nil {
+		return nil
+	}
+	return &chainEscrowSource{client: escrowClient}
+}
+
+// GetLedgerReconciliation returns the latest scheduled reconciliation of the
+// ledger against the escrow tables and on-chain escrows. Operators only.
+func (h *Handlers) GetLedgerReconciliation(c *gin.Context) {
+	if h.reconciliation == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "reconciliation not available",
+			"message": "ledger reconciliation is not scheduled on this node",
+		})
+		return
+	}
+
+	report, err := h.reconciliation.Latest()
+	if report == nil {
+		message := "no reconciliation report yet"
+		if err != nil {
+			message = err.Error()
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "reconciliation not available",
+			"message": message,
+		})
+		return
+	}
+
+	response := gin.H{
+		"clean":  report.Clean(),
+		"report": report,
+	}
+	if err != nil {
+		// The report is from an earlier run
+		response["last_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLedgerEntries returns journal entries filtered by account and/or
+// reference. Operators may read any account; other callers only their own.
+func (h *Handlers) GetLedgerEntries(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetLedgerEntries"))
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid limit",
+			"message": "limit must be a positive integer",
+		})
+		return
+	}
+
+	filter := ledger.EntryFilter{
+		AccountID: c.Query("account"),
+		Reference: c.Query("reference"),
+		Limit:     limit,
+	}
+	if !c.GetBool("is_system") {
+		own := ledger.UserAccount(c.GetString("user_did"))
+		if filter.AccountID != "" && filter.AccountID != own {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "you may only read your own ledger account",
+			})
+			return
+		}
+		filter.AccountID = own
+	}
+
+	entries, err := h.journal().Entries(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("failed to get ledger entries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get ledger entries",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// GetLedgerBalances returns balances of all ledger accounts matching a
+// prefix. Callers other than operators only see their own account.
+func (h *Handlers) GetLedgerBalances(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "GetLedgerBalances"))
+
+	prefix := c.Query("prefix")
+	own := ""
+	if !c.GetBool("is_system") {
+		own = ledger.UserAccount(c.GetString("user_did"))
+		prefix = own
+	}
+
+	balances, err := h.journal().Store().Balances(c.Request.Context(), prefix)
+	if err != nil {
+		logger.Error("failed to get ledger balances", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get ledger balances",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if own != "" {
+		// Another DID may start with the caller's
+		scoped := balances[:0]
+		for _, b := range balances {
+			if b.AccountID == own {
+				scoped = append(scoped, b)
+			}
+		}
+		balances = scoped
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balances": balances,
+		"count":    len(balances),
+	})
+}
+
+// chainEscrowSource adapts the substrate escrow client for ledger reconciliation
+type chainEscrowSource struct {
+	client *substrate.EscrowClient
+}
+
+// ChainEscrow implements ledger.ChainEscrowSource
+func (s *chainEscrowSource) ChainEscrow(ctx context.Context, taskID string) (*ledger.ChainEscrow, error) {
+	// Same task ID encoding as SubmitTask uses when creating the escrow
+	var taskIDBytes [32]byte
+	copy(taskIDBytes[:], []byte(taskID))
+
+	details, err := s.client.GetEscrow(ctx, taskIDBytes)
+	if err != nil {
+		if strings.Contains(err.Error(), "escrow not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// On-chain amounts are stored in hundredths (see SubmitTask)
+	units, ok := new(big.Float).SetString(string(details.Amount))
+	if !ok {
+		return nil, fmt.Errorf("invalid on-chain escrow amount %q", details.Amount)
+	}
+	amount, _ := new(big.Float).Quo(units, big.NewFloat(100)).Float64()
+
+	return &ledger.ChainEscrow{
+		State:  string(details.State),
+		Amount: amount,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noEscrows struct{}
+
+func (noEscrows) ListEscrowRecords(ctx context.Context) ([]ledger.EscrowRecord, error) {
+	return nil, nil
+}
+
+// newLedgerTestServer returns a server over an in-memory journal holding a
+// deposit for alice and one for bob
+func newLedgerTestServer(t *testing.T) (*Server, *Handlers) {
+	t.Helper()
+
+	ctx := context.Background()
+	journal := ledger.New(ledger.NewMemoryStore(), nil)
+	for _, did := range []string{"did:user:alice", "did:user:bob"} {
+		_, err := journal.Transfer(ctx, "deposit:"+did, ledger.AccountCash, ledger.UserAccount(did),
+			ledger.FromFloat(10), ledger.DescDeposit, did)
+		require.NoError(t, err)
+	}
+
+	h := &Handlers{}
+	h.SetLedger(journal)
+	return newTestServer(h), h
+}
+
+func TestLedgerEntriesScopedToCaller(t *testing.T) {
+	s, _ := newLedgerTestServer(t)
+	alice := testToken(t, uuid.New(), "did:user:alice", false)
+	bobAccount := ledger.UserAccount("did:user:bob")
+
+	w := serve(s, http.MethodGet, "/api/v1/economic/ledger/entries", nil, alice)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Entries []*ledger.Entry `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Entries, 1)
+	assert.Equal(t, "did:user:alice", resp.Entries[0].Reference)
+
+	w = serve(s, http.MethodGet, "/api/v1/economic/ledger/entries?account="+bobAccount, nil, alice)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	operator := testToken(t, uuid.New(), "did:operator:1", true)
+	w = serve(s, http.MethodGet, "/api/v1/economic/ledger/entries?account="+bobAccount, nil, operator)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLedgerBalancesScopedToCaller(t *testing.T) {
+	s, _ := newLedgerTestServer(t)
+
+	balances := func(authorization string) []ledger.AccountBalance {
+		w := serve(s, http.MethodGet, "/api/v1/economic/ledger/balances?prefix=user:", nil, authorization)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Balances []ledger.AccountBalance `json:"balances"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Balances
+	}
+
+	own := balances(testToken(t, uuid.New(), "did:user:alice", false))
+	require.Len(t, own, 1)
+	assert.Equal(t, ledger.UserAccount("did:user:alice"), own[0].AccountID)
+
+	assert.Len(t, balances(testToken(t, uuid.New(), "did:operator:1", true)), 2)
+}
+
+func TestLedgerReconciliationServesScheduledReport(t *testing.T) {
+	s, h := newLedgerTestServer(t)
+	operator := testToken(t, uuid.New(), "did:operator:1", true)
+	path := "/api/v1/economic/ledger/reconciliation"
+
+	w := serve(s, http.MethodGet, path, nil, testToken(t, uuid.New(), "did:user:alice", false))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Not scheduled on this node
+	w = serve(s, http.MethodGet, path, nil, operator)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	job := ledger.NewReconciliationJob(ledger.NewReconciler(h.ledger, noEscrows{}, nil, nil), nil)
+	h.SetReconciliationJob(job)
+	w = serve(s, http.MethodGet, path, nil, operator)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	_, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	w = serve(s, http.MethodGet, path, nil, operator)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"clean":true`)
+}
@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/auth"
+	"github.com/aidenlippert/zerostate/libs/database"
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -263,8 +265,9 @@ func rateLimitMiddleware(ratePerMinute int) gin.HandlerFunc {
 	}
 }
 
-// authMiddleware validates JWT authentication
-func authMiddleware() gin.HandlerFunc {
+// authMiddleware authenticates requests with a JWT access token, or with an
+// API key looked up in db
+func authMiddleware(db *database.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -290,6 +293,14 @@ func authMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
+		// API keys authenticate as the user who owns them
+		if strings.HasPrefix(tokenString, auth.APIKeyPrefix) {
+			if authenticateAPIKey(c, db, tokenString) {
+				c.Next()
+			}
+			return
+		}
+
 		// Validate token using auth library
 		jwtService := auth.NewJWTService(auth.DefaultJWTConfig())
 		claims, err := jwtService.ValidateAccessToken(tokenString)
@@ -306,6 +317,70 @@ func authMiddleware() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("user_did", claims.DID)
 		c.Set("user_email", claims.Email)
+		c.Set("is_system", claims.IsSystem)
+
+		c.Next()
+	}
+}
+
+// authenticateAPIKey resolves an API key to its owner and stores the owner
+// and the key ID in the request, writing a 401 response on failure
+func authenticateAPIKey(c *gin.Context, db *database.Database, key string) bool {
+	unauthorized := func(message string) bool {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": message,
+		})
+		c.Abort()
+		return false
+	}
+	if db == nil {
+		return unauthorized("API keys are not supported on this node")
+	}
+
+	ctx := c.Request.Context()
+	candidates, err := database.NewAPIKeyRepository(db).ListByPrefix(ctx, auth.APIKeyLookupPrefix(key))
+	if err != nil {
+		return unauthorized("invalid API key")
+	}
+
+	var apiKey *database.APIKey
+	for _, candidate := range candidates {
+		if candidate.IsValid() && auth.VerifyAPIKey(key, candidate.KeyHash) == nil {
+			apiKey = candidate
+			break
+		}
+	}
+	if apiKey == nil {
+		return unauthorized("invalid API key")
+	}
+
+	user, err := database.NewUserRepository(db).GetByID(ctx, apiKey.UserID)
+	if err != nil || !user.IsActive {
+		return unauthorized("invalid API key")
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("user_did", user.DID)
+	c.Set("user_email", user.Email.String)
+	c.Set("is_system", false)
+	c.Set(apiKeyIDKey, apiKey.ID)
+	c.Request = c.Request.WithContext(withAPIKeyID(ctx, apiKey.ID))
+	return true
+}
+
+// requireSystemUser restricts a route to system (operator) accounts. It must
+// run after authMiddleware.
+func requireSystemUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("is_system") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "operator access required",
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
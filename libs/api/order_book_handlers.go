@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// orderBook returns the orchestrator's spot order book, or nil when the spot market is off
+func (h *Handlers) orderBook() *orchestration.OrderBook {
+	if h.orchestrator == nil {
+		return nil
+	}
+	return h.orchestrator.OrderBook()
+}
+
+// PostStandingAsk posts or amends a standing ask on the spot market
+func (h *Handlers) PostStandingAsk(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "PostStandingAsk"))
+
+	orderBook := h.orderBook()
+	if orderBook == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "spot market unavailable",
+			"message": "order book is not enabled on this node",
+		})
+		return
+	}
+
+	var req struct {
+		AskID      string  `json:"ask_id"`
+		AgentID    string  `json:"agent_id" binding:"required"`
+		Capability string  `json:"capability" binding:"required"`
+		Price      float64 `json:"price" binding:"required,gt=0"`
+		Capacity   int     `json:"capacity" binding:"required,gt=0"`
+		ETAms      int64   `json:"eta_ms"`
+		ValidFor   int     `json:"valid_for" binding:"required,gt=0"` // seconds
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ask, err := orderBook.PostAsk(orchestration.StandingAsk{
+		ID:         req.AskID,
+		AgentDID:   agentcard.DID(req.AgentID),
+		Capability: req.Capability,
+		Price:      req.Price,
+		Capacity:   req.Capacity,
+		ETAms:      req.ETAms,
+		ValidUntil: time.Now().Add(time.Duration(req.ValidFor) * time.Second),
+	})
+	if err != nil {
+		logger.Warn("standing ask rejected", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid standing ask",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	logger.Info("standing ask posted",
+		zap.String("ask_id", ask.ID),
+		zap.String("agent_id", req.AgentID),
+		zap.String("capability", ask.Capability),
+		zap.Float64("price", ask.Price),
+	)
+
+	c.JSON(http.StatusCreated, ask)
+}
+
+// CancelStandingAsk withdraws a standing ask
+func (h *Handlers) CancelStandingAsk(c *gin.Context) {
+	orderBook := h.orderBook()
+	if orderBook == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "spot market unavailable",
+			"message": "order book is not enabled on this node",
+		})
+		return
+	}
+
+	agentID := c.Query("agent_id")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": "agent_id is required",
+		})
+		return
+	}
+
+	if err := orderBook.CancelAsk(c.Param("id"), agentcard.DID(agentID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, orchestration.ErrAskNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":   "failed to cancel standing ask",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ask_id": c.Param("id"),
+		"status": "cancelled",
+	})
+}
+
+// GetOrderBook returns the live standing asks of a capability in match order
+func (h *Handlers) GetOrderBook(c *gin.Context) {
+	orderBook := h.orderBook()
+	if orderBook == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "spot market unavailable",
+			"message": "order book is not enabled on this node",
+		})
+		return
+	}
+
+	capability := c.Param("capability")
+	asks := orderBook.Depth(capability)
+
+	c.JSON(http.StatusOK, gin.H{
+		"capability": capability,
+		"asks":       asks,
+		"count":      len(asks),
+	})
+}
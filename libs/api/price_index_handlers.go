@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// PriceIndexTopic is the gossip topic price index snapshots are published on
+	PriceIndexTopic = "ainur/v1/market/prices"
+
+	// PriceIndexMessageType is the gossip message type of a price index snapshot
+	PriceIndexMessageType = "AACL-Price-Index-v1"
+)
+
+// GossipPriceIndexPublisher publishes price index snapshots to the market
+// over gossip so runtimes can resolve floor references locally
+type GossipPriceIndexPublisher struct {
+	gossip *p2p.GossipService
+	peerID string
+}
+
+// NewGossipPriceIndexPublisher creates a publisher on the given gossip service
+func NewGossipPriceIndexPublisher(gossip *p2p.GossipService, peerID string) *GossipPriceIndexPublisher {
+	return &GossipPriceIndexPublisher{gossip: gossip, peerID: peerID}
+}
+
+// PublishPriceIndex implements economic.PriceIndexPublisher
+func (p *GossipPriceIndexPublisher) PublishPriceIndex(ctx context.Context, snapshot *economic.PriceIndexSnapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode price index: %w", err)
+	}
+
+	return p.gossip.Publish(PriceIndexTopic, &p2p.GossipMessage{
+		Type:      PriceIndexMessageType,
+		Payload:   payload,
+		Timestamp: time.Now().Unix(),
+		PeerID:    p.peerID,
+	})
+}
+
+// SetPriceIndex attaches the index used to serve price quotes and resolve
+// declarative reserve prices
+func (h *Handlers) SetPriceIndex(index *economic.PriceIndex) {
+	h.priceIndex = index
+}
+
+// GetPriceIndex returns rolling cleared-price percentiles for every capability
+func (h *Handlers) GetPriceIndex(c *gin.Context) {
+	if h.priceIndex == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "price index unavailable",
+			"message": "price index is not enabled on this node",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.priceIndex.Snapshot())
+}
+
+// GetCapabilityPrice returns the price quote of one capability
+func (h *Handlers) GetCapabilityPrice(c *gin.Context) {
+	capability := c.Param("capability")
+
+	if h.priceIndex == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "price index unavailable",
+			"message": "price index is not enabled on this node",
+		})
+		return
+	}
+
+	quote := h.priceIndex.Quote(capability)
+	if quote == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "no price data",
+			"message": fmt.Sprintf("no cleared prices for %s in the last %s", capability, h.priceIndex.Window()),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// resolveReservePrice turns a declarative reserve such as "p25" into a price
+func (h *Handlers) resolveReservePrice(capabilities []string, ref string) (float64, error) {
+	if h.priceIndex == nil {
+		return 0, fmt.Errorf("%w: price index is not enabled on this node", economic.ErrNoPriceData)
+	}
+	if len(capabilities) == 0 {
+		return 0, fmt.Errorf("%w: a capability is required to resolve %q", economic.ErrNoPriceData, ref)
+	}
+
+	return h.priceIndex.ResolveAny(capabilities, ref)
+}
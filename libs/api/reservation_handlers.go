@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// reservationEscrowGrace keeps a reservation escrow open past its window so
+// settlement can run after the last claimed task finishes
+const reservationEscrowGrace = 24 * time.Hour
+
+// ReservationEscrow backs capacity reservations with escrows: the requester
+// funds the full reservation when booking and the escrow is split between
+// agent and requester at settlement
+type ReservationEscrow struct {
+	escrowSvc *economic.EscrowService
+	logger    *zap.Logger
+}
+
+// NewReservationEscrow creates a reservation escrow backed by the escrow tables in db
+func NewReservationEscrow(db *database.Database, logger *zap.Logger) *ReservationEscrow {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	escrowSvc := economic.NewEscrowService(db.Conn(), logger)
+	escrowSvc.SetAccountResolver(economic.UserDIDResolver)
+	return &ReservationEscrow{
+		escrowSvc: escrowSvc,
+		logger:    logger,
+	}
+}
+
+// SetPlatformFee charges the agent's share of each settlement a platform fee
+func (e *ReservationEscrow) SetPlatformFee(rate float64) {
+	e.escrowSvc.SetPlatformFee(rate)
+}
+
+// LockReservation creates and funds an escrow for the reservation's total
+func (e *ReservationEscrow) LockReservation(ctx context.Context, r *orchestration.Reservation) (string, error) {
+	expiration := int(math.Ceil(time.Until(r.WindowEnd.Add(reservationEscrowGrace)).Minutes()))
+
+	escrow, err := e.escrowSvc.CreateEscrow(ctx,
+		"reservation:"+r.ID,
+		r.UserID,
+		string(r.AgentDID),
+		r.Total(),
+		expiration,
+		nil,
+		"",
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if err := e.escrowSvc.FundEscrow(ctx, escrow.ID, "reservation"); err != nil {
+		return "", err
+	}
+
+	return escrow.ID.String(), nil
+}
+
+// SettleReservation pays the agent its share and refunds the rest
+func (e *ReservationEscrow) SettleReservation(ctx context.Context, s *orchestration.ReservationSettlement) error {
+	escrowID, err := uuid.Parse(s.EscrowID)
+	if err != nil {
+		return fmt.Errorf("invalid reservation escrow ID: %w", err)
+	}
+	return e.escrowSvc.SettleEscrow(ctx, escrowID, s.AgentAmount, "system")
+}
+
+// reservations returns the orchestrator's reservation book, or nil when reservations are off
+func (h *Handlers) reservations() *orchestration.ReservationBook {
+	if h.orchestrator == nil {
+		return nil
+	}
+	return h.orchestrator.Reservations()
+}
+
+// reservationsUnavailable reports a node without a reservation book
+func reservationsUnavailable(c *gin.Context) {
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "reservations unavailable",
+		"message": "capacity reservations are not enabled on this node",
+	})
+}
+
+// reservationError maps reservation book errors to HTTP statuses
+func reservationError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, orchestration.ErrReservationNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, orchestration.ErrInvalidReservation):
+		status = http.StatusBadRequest
+	case errors.Is(err, orchestration.ErrReservationTransition):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":   message,
+		"message": err.Error(),
+	})
+}
+
+// CreateReservation books and escrows agent capacity in a future window
+func (h *Handlers) CreateReservation(c *gin.Context) {
+	logger := h.logger.With(zap.String("handler", "CreateReservation"))
+
+	reservations := h.reservations()
+	if reservations == nil {
+		reservationsUnavailable(c)
+		return
+	}
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req struct {
+		AgentID      string    `json:"agent_id" binding:"required"`
+		Capability   string    `json:"capability" binding:"required"`
+		Slots        int       `json:"slots" binding:"required,gt=0"`
+		Concurrency  int       `json:"concurrency"`
+		PricePerSlot float64   `json:"price_per_slot" binding:"required,gt=0"`
+		WindowStart  time.Time `json:"window_start" binding:"required"`
+		WindowEnd    time.Time `json:"window_end" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	reservation, err := reservations.Book(c.Request.Context(), orchestration.Reservation{
+		UserID:       userID,
+		AgentDID:     agentcard.DID(req.AgentID),
+		Capability:   req.Capability,
+		Slots:        req.Slots,
+		Concurrency:  req.Concurrency,
+		PricePerSlot: req.PricePerSlot,
+		WindowStart:  req.WindowStart,
+		WindowEnd:    req.WindowEnd,
+	})
+	if err != nil {
+		logger.Warn("reservation rejected", zap.Error(err))
+		reservationError(c, "failed to book reservation", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// ConfirmReservation records the agent's commitment to a pending reservation
+func (h *Handlers) ConfirmReservation(c *gin.Context) {
+	reservations := h.reservations()
+	if reservations == nil {
+		reservationsUnavailable(c)
+		return
+	}
+
+	var req struct {
+		AgentID string `json:"agent_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	reservation, err := reservations.Confirm(c.Param("id"), agentcard.DID(req.AgentID))
+	if err != nil {
+		reservationError(c, "failed to confirm reservation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// CancelReservation withdraws a reservation before its window and refunds it
+func (h *Handlers) CancelReservation(c *gin.Context) {
+	reservations := h.reservations()
+	if reservations == nil {
+		reservationsUnavailable(c)
+		return
+	}
+
+	userID, ok := getUserIDString(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	reservation, err := reservations.Cancel(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		reservationError(c, "failed to cancel reservation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ListReservations returns the caller's reservations, or an agent's with agent_id
+func (h *Handlers) ListReservations(c *gin.Context) {
+	reservations := h.reservations()
+	if reservations == nil {
+		reservationsUnavailable(c)
+		return
+	}
+
+	var list []*orchestration.Reservation
+	if agentID := c.Query("agent_id"); agentID != "" {
+		list = reservations.List("", agentcard.DID(agentID))
+	} else {
+		userID, ok := getUserIDString(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		list = reservations.List(userID, "")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reservations": list,
+		"count":        len(list),
+	})
+}
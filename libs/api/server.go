@@ -199,7 +199,7 @@ func (s *Server) setupRoutes() {
 
 			// Protected user routes
 			protected := users.Group("")
-			protected.Use(authMiddleware())
+			protected.Use(authMiddleware(s.handlers.db))
 			{
 				protected.POST("/logout", s.handlers.LogoutUser)
 				protected.GET("/me", s.handlers.GetCurrentUser)
@@ -209,7 +209,7 @@ func (s *Server) setupRoutes() {
 
 		// Protected routes - require authentication
 		protected := v1.Group("")
-		protected.Use(authMiddleware())
+		protected.Use(authMiddleware(s.handlers.db))
 		{
 			// Agent registration and task management now require auth
 			protected.POST("/agents/register", s.handlers.RegisterAgent)
@@ -217,6 +217,7 @@ func (s *Server) setupRoutes() {
 			protected.GET("/tasks/:id", s.handlers.GetTask)
 			protected.GET("/tasks/:id/status", s.handlers.GetTaskStatus)
 			protected.GET("/tasks/:id/result", s.handlers.GetTaskResult)
+			protected.GET("/tasks/:id/stream", s.handlers.StreamTaskProgress)
 			// Agent management
 			agents := protected.Group("/agents")
 			{
@@ -254,6 +255,20 @@ func (s *Server) setupRoutes() {
 				auctions.POST("/:id/bid", s.handlers.SubmitBid)
 			}
 
+			// Spot market - standing asks matched instantly against tasks
+			spot := protected.Group("/market")
+			{
+				spot.POST("/asks", s.handlers.PostStandingAsk)
+				spot.DELETE("/asks/:id", s.handlers.CancelStandingAsk)
+				spot.GET("/book/:capability", s.handlers.GetOrderBook)
+
+				// Capacity reservations - honored before the spot market
+				spot.POST("/reservations", s.handlers.CreateReservation)
+				spot.GET("/reservations", s.handlers.ListReservations)
+				spot.POST("/reservations/:id/confirm", s.handlers.ConfirmReservation)
+				spot.DELETE("/reservations/:id", s.handlers.CancelReservation)
+			}
+
 			// Payment channel management
 			payments := protected.Group("/payments")
 			{
@@ -302,6 +317,10 @@ func (s *Server) setupRoutes() {
 				economic.POST("/auctions", s.handlers.CreateAuction)
 				economic.POST("/auctions/:id/bids", s.handlers.SubmitBid)
 
+				// Cleared-price index
+				economic.GET("/prices", s.handlers.GetPriceIndex)
+				economic.GET("/prices/:capability", s.handlers.GetCapabilityPrice)
+
 				// Payment channel management
 				economic.POST("/payment-channels", s.handlers.OpenPaymentChannel)
 				economic.POST("/payment-channels/:id/settle", s.handlers.SettlePaymentChannel)
@@ -321,6 +340,18 @@ func (s *Server) setupRoutes() {
 				economic.POST("/escrows/:id/release", s.handlers.ReleaseEscrow)
 				economic.POST("/escrows/:id/refund", s.handlers.RefundEscrow)
 
+				// Escrow release conditions
+				economic.POST("/escrows/:id/result", s.handlers.SubmitEscrowResult)
+				economic.POST("/escrows/:id/approvals", s.handlers.SubmitEscrowApproval)
+				economic.POST("/escrows/:id/signatures", s.handlers.SubmitEscrowSignature)
+				economic.POST("/escrows/:id/milestones", s.handlers.SubmitEscrowMilestone)
+				economic.GET("/escrows/:id/evaluations", s.handlers.GetEscrowEvaluations)
+
+				// Ledger and reconciliation
+				economic.GET("/ledger/entries", s.handlers.GetLedgerEntries)
+				economic.GET("/ledger/balances", s.handlers.GetLedgerBalances)
+				economic.GET("/ledger/reconciliation", requireSystemUser(), s.handlers.GetLedgerReconciliation)
+
 				// Dispute resolution
 				economic.POST("/escrows/:id/dispute", s.handlers.OpenDispute)
 				economic.GET("/disputes/:id", s.handlers.GetDispute)
@@ -333,6 +364,20 @@ func (s *Server) setupRoutes() {
 				economic.GET("/health", s.handlers.EconomicHealthCheck)
 			}
 
+			// Budget policies and spend limits
+			budgets := protected.Group("/budgets")
+			{
+				budgets.GET("/policies", s.handlers.GetBudgetPolicies)
+				budgets.PUT("/policies", s.handlers.SetBudgetPolicy)
+				budgets.DELETE("/policies/:id", s.handlers.DeleteBudgetPolicy)
+			}
+
+			// Billing statements
+			billing := protected.Group("/billing")
+			{
+				billing.GET("/statements", s.handlers.GetBillingStatements)
+			}
+
 			// Analytics and monitoring
 			analytics := protected.Group("/analytics")
 			{
@@ -345,6 +390,8 @@ func (s *Server) setupRoutes() {
 				analytics.GET("/economic-health", s.handlers.GetEconomicHealthMetrics)
 				analytics.GET("/time-series", s.handlers.GetTimeSeriesData)
 				analytics.GET("/anomalies", s.handlers.DetectAnomalies)
+				analytics.GET("/collusion", s.handlers.GetCollusionFindings)
+				analytics.DELETE("/collusion/flags/:did", requireSystemUser(), s.handlers.AllowFlaggedBidder)
 				analytics.GET("/dashboard", s.handlers.GetAnalyticsDashboard)
 			}
 		}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestServer routes requests to h through the server's real routes and
+// middleware, without rate limiting or metrics
+func newTestServer(h *Handlers) *Server {
+	if h.logger == nil {
+		h.logger = zap.NewNop()
+	}
+	return NewServer(&Config{RequestTimeout: DefaultConfig().RequestTimeout}, h, zap.NewNop())
+}
+
+// testToken returns a bearer token the auth middleware accepts
+func testToken(t *testing.T, userID uuid.UUID, did string, isSystem bool) string {
+	t.Helper()
+
+	pair, err := auth.NewJWTService(auth.DefaultJWTConfig()).GenerateTokenPair(userID, did, "", isSystem)
+	require.NoError(t, err)
+	return "Bearer " + pair.AccessToken
+}
+
+// serve sends a request to the server with the given Authorization header
+func serve(s *Server, method, path string, body io.Reader, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireSystemUserRejectsUnauthenticated(t *testing.T) {
+	s := newTestServer(&Handlers{})
+
+	w := serve(s, http.MethodDelete, "/api/v1/analytics/collusion/flags/did:agent:1", nil, "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
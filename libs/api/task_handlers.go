@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/execution"
 	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/aidenlippert/zerostate/libs/scoring"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -23,8 +27,13 @@ type SubmitTaskRequest struct {
 	Constraints  map[string]interface{} `json:"constraints"`
 	Input        map[string]interface{} `json:"input"` // Direct task input (e.g., {"function":"add","args":[5,7]})
 	Budget       float64                `json:"budget" binding:"required,gt=0"`
+	ReservePrice float64                `json:"reserve_price"` // Lowest price an urgent task's Dutch auction falls to
 	Timeout      int                    `json:"timeout"`  // seconds
 	Priority     string                 `json:"priority"` // "low", "medium", "high"
+	ScoringRule  *scoring.Rule          `json:"scoring_rule"` // Optional multi-attribute auction scoring
+	Replication  *orchestration.ReplicationPolicy `json:"replication"` // Optional redundant execution with result voting
+
+	InputArtifacts []execution.InputArtifact `json:"input_artifacts"` // Files mounted read-only under /input
 }
 
 // SubmitTaskResponse represents the task submission response
@@ -99,6 +108,14 @@ func (h *Handlers) SubmitTask(c *gin.Context) {
 		return
 	}
 
+	if req.ReservePrice < 0 || req.ReservePrice > req.Budget {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request",
+			"message": "reserve_price must be between 0 and the budget",
+		})
+		return
+	}
+
 	// Set default timeout
 	if req.Timeout == 0 {
 		req.Timeout = 30 // 30 seconds default
@@ -112,6 +129,36 @@ func (h *Handlers) SubmitTask(c *gin.Context) {
 		return
 	}
 
+	for _, artifact := range req.InputArtifacts {
+		if err := artifact.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.ScoringRule != nil {
+		if err := req.ScoringRule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.Replication != nil {
+		if err := req.Replication.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
 	// Parse priority
 	priority := parsePriority(req.Priority)
 
@@ -172,7 +219,11 @@ func (h *Handlers) SubmitTask(c *gin.Context) {
 
 	task.Priority = priority
 	task.Budget = req.Budget
+	task.ReservePrice = req.ReservePrice
 	task.Timeout = time.Duration(req.Timeout) * time.Second
+	task.ScoringRule = req.ScoringRule
+	task.Replication = req.Replication
+	task.InputArtifacts = req.InputArtifacts
 
 	// Enqueue task
 	if h.taskQueue == nil {
@@ -184,11 +235,33 @@ func (h *Handlers) SubmitTask(c *gin.Context) {
 		return
 	}
 
+	// Enforce the user's budget policies before anything is queued or escrowed
+	guard := h.budgetGuard()
+	if guard != nil && userID != "" {
+		err := guard.Authorize(ctx, &economic.SpendRequest{
+			UserID:   userID,
+			APIKeyID: getAPIKeyID(c),
+			Source:   economic.SpendSourceTask,
+			Items: []economic.SpendItem{{
+				TaskID:       task.ID,
+				Capabilities: capabilities,
+				Amount:       task.Budget,
+			}},
+		})
+		if err != nil {
+			respondBudgetError(c, logger, err)
+			return
+		}
+	}
+
 	if err := h.taskQueue.Enqueue(task); err != nil {
 		logger.Error("failed to enqueue task",
 			zap.Error(err),
 			zap.String("task_id", task.ID),
 		)
+		if guard != nil {
+			guard.Release(ctx, task.ID)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal error",
 			"message": "failed to queue task",
@@ -419,8 +492,13 @@ func (h *Handlers) CancelTask(c *gin.Context) {
 		return
 	}
 
-	// Cancel the task
-	err := h.taskQueue.Cancel(taskID)
+	// Cancel the task; the orchestrator also stops it if it is running
+	var err error
+	if h.orchestrator != nil {
+		err = h.orchestrator.CancelTask(taskID)
+	} else {
+		err = h.taskQueue.Cancel(taskID)
+	}
 	if err != nil {
 		logger.Error("failed to cancel task", zap.Error(err))
 		if err == orchestration.ErrTaskNotFound {
@@ -429,6 +507,12 @@ func (h *Handlers) CancelTask(c *gin.Context) {
 				"message": "task not found",
 				"task_id": taskID,
 			})
+		} else if err == orchestration.ErrTaskFinished {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "conflict",
+				"message": "task already finished",
+				"task_id": taskID,
+			})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "internal error",
@@ -507,6 +591,107 @@ func (h *Handlers) GetTaskStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// taskStreamKeepalive is how often an idle progress stream is pinged and the
+// task rechecked, in case it ended without a final update
+const taskStreamKeepalive = 15 * time.Second
+
+// StreamTaskProgress streams a task's progress as server-sent events: a
+// "progress" event per update, then one "done" event carrying the outcome
+func (h *Handlers) StreamTaskProgress(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID := c.Param("id")
+	logger := h.logger.With(zap.String("handler", "StreamTaskProgress"), zap.String("task_id", taskID))
+
+	if h.taskQueue == nil || h.orchestrator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "service unavailable",
+			"message": "task orchestration not available",
+		})
+		return
+	}
+
+	userDIDVal, exists := c.Get("user_did")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "authentication required",
+		})
+		return
+	}
+	userDID := userDIDVal.(string)
+
+	task, err := h.getTaskByID(ctx, taskID)
+	if err != nil {
+		if err == orchestration.ErrTaskNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not found",
+				"message": "task not found",
+				"task_id": taskID,
+			})
+		} else {
+			logger.Error("failed to get task", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal error",
+				"message": "failed to retrieve task",
+			})
+		}
+		return
+	}
+	if task.UserID != userDID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "you can only stream your own tasks",
+		})
+		return
+	}
+
+	// Subscribe before checking the status so the final update can't slip
+	// between the two
+	updates, unsubscribe := h.orchestrator.SubscribeProgress(taskID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if task.IsTerminal() {
+		c.SSEvent("done", orchestration.FinalProgress(task))
+		return
+	}
+
+	keepalive := time.NewTicker(taskStreamKeepalive)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case p, ok := <-updates:
+			if !ok {
+				// Closed without the final update reaching us; report from the queue
+				if task, err := h.getTaskByID(ctx, taskID); err == nil && task.IsTerminal() {
+					c.SSEvent("done", orchestration.FinalProgress(task))
+				}
+				return false
+			}
+			if p.Final() {
+				c.SSEvent("done", p)
+				return false
+			}
+			c.SSEvent("progress", p)
+			return true
+		case <-keepalive.C:
+			if task, err := h.getTaskByID(ctx, taskID); err == nil && task.IsTerminal() {
+				c.SSEvent("done", orchestration.FinalProgress(task))
+				return false
+			}
+			fmt.Fprint(w, ": keepalive\n\n")
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
 // GetTaskResult retrieves the result of a completed task
 func (h *Handlers) GetTaskResult(c *gin.Context) {
 	taskID := c.Param("id")
@@ -567,6 +752,9 @@ func (h *Handlers) GetTaskResult(c *gin.Context) {
 			"assigned_to":  task.AssignedTo,
 		},
 	}
+	if len(task.Replicas) > 0 {
+		response.Metadata["replicas"] = task.Replicas
+	}
 
 	c.JSON(http.StatusOK, response)
 }
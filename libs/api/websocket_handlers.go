@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 
+	"github.com/aidenlippert/zerostate/libs/orchestration"
 	"github.com/aidenlippert/zerostate/libs/websocket"
 	"github.com/gin-gonic/gin"
 	gorillaws "github.com/gorilla/websocket"
@@ -49,8 +52,14 @@ func (h *Handlers) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Create new client
+	// Create new client; it may subscribe to the tasks its user owns
 	client := websocket.NewClient(conn, userID, h.wsHub, logger)
+	if userDID, ok := c.Get("user_did"); ok {
+		client.AuthorizeTask = func(taskID string) bool {
+			task, err := h.getTaskByID(context.Background(), taskID)
+			return err == nil && task.UserID == userDID
+		}
+	}
 
 	// Register client with hub
 	h.wsHub.RegisterClient(client)
@@ -160,3 +169,28 @@ func (h *Handlers) SendUserMessage(c *gin.Context) {
 		"message": "user message sent successfully",
 	})
 }
+
+// taskProgressBroadcaster forwards orchestrator progress to the WebSocket
+// subscribers of each task
+type taskProgressBroadcaster struct {
+	hub *websocket.Hub
+}
+
+// NewTaskProgressBroadcaster sends task progress to WebSocket clients as
+// "task_progress" messages
+func NewTaskProgressBroadcaster(hub *websocket.Hub) orchestration.ProgressBroadcaster {
+	return &taskProgressBroadcaster{hub: hub}
+}
+
+// BroadcastTaskProgress implements orchestration.ProgressBroadcaster
+func (b *taskProgressBroadcaster) BroadcastTaskProgress(userID string, progress orchestration.TaskProgress) {
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return
+	}
+	b.hub.SendToTask(progress.TaskID, "task_progress", data)
+}
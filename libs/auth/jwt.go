@@ -242,8 +242,19 @@ const (
 	APIKeyPrefix = "zs_"
 	// APIKeyLength is the length of the random part (32 bytes = 256 bits)
 	APIKeyLength = 32
+	// APIKeyLookupLength is how many leading characters of a key are stored
+	// unhashed as api_keys.key_prefix, to find the key's row
+	APIKeyLookupLength = 12
 )
 
+// APIKeyLookupPrefix returns the part of an API key stored unhashed for lookup
+func APIKeyLookupPrefix(key string) string {
+	if len(key) <= APIKeyLookupLength {
+		return key
+	}
+	return key[:APIKeyLookupLength]
+}
+
 // GenerateAPIKey generates a new secure API key
 func GenerateAPIKey() (string, string, error) {
 	// Generate random bytes
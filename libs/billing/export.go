@@ -0,0 +1,88 @@
+package billing
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvHeader is the column layout of CSV statement exports
+var csvHeader = []string{
+	"statement_id", "subject_id", "role", "period_start", "period_end",
+	"date", "type", "description", "task_id", "reference", "entry_id", "amount",
+}
+
+// WriteJSON writes statements as an indented JSON array
+func WriteJSON(w io.Writer, statements []*Statement) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(statements); err != nil {
+		return fmt.Errorf("failed to encode statements: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes one row per line item, followed by a summary row per
+// statement (type "total") holding the amount due or net earnings
+func WriteCSV(w io.Writer, statements []*Statement) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, stmt := range statements {
+		prefix := []string{
+			stmt.ID,
+			stmt.SubjectID,
+			string(stmt.Role),
+			stmt.Period.Start.Format(time.RFC3339),
+			stmt.Period.End.Format(time.RFC3339),
+		}
+
+		for _, item := range stmt.LineItems {
+			row := append(append([]string{}, prefix...),
+				item.Date.Format(time.RFC3339),
+				string(item.Type),
+				item.Description,
+				item.TaskID,
+				item.Reference,
+				item.EntryID,
+				formatAmount(item.Amount),
+			)
+			if err := cw.Write(row); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+
+		total, label := stmt.Totals.AmountDue, "amount due"
+		if stmt.Role == RoleAgent {
+			total, label = stmt.Totals.NetEarnings, "net earnings"
+		}
+		summary := append(append([]string{}, prefix...),
+			stmt.Period.End.Format(time.RFC3339), "total", label, "", "", "", formatAmount(total),
+		)
+		if err := cw.Write(summary); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// formatAmount prints amounts with the ledger's 8 decimal places
+func formatAmount(f float64) string {
+	return strconv.FormatFloat(f, 'f', 8, 64)
+}
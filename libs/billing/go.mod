@@ -0,0 +1,19 @@
+module github.com/aidenlippert/zerostate/libs/billing
+
+go 1.24.10
+
+require (
+	github.com/aidenlippert/zerostate/libs/ledger v0.0.0
+	github.com/stretchr/testify v1.11.1
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aidenlippert/zerostate/libs/ledger => ../ledger
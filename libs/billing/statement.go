@@ -0,0 +1,383 @@
+// Package billing produces periodic usage statements and invoices for users
+// and agents from the double-entry ledger.
+//
+// Statements are derived entirely from journal entries, so every line item
+// traces back to an immutable posting and a statement can be regenerated
+// for any past period with identical results.
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"go.uber.org/zap"
+)
+
+// Billing errors
+var (
+	// ErrInvalidPeriod indicates a malformed billing period
+	ErrInvalidPeriod = errors.New("invalid billing period")
+
+	// ErrInvalidRole indicates an unknown statement role
+	ErrInvalidRole = errors.New("role must be 'user' or 'agent'")
+)
+
+// Role selects which side of the marketplace a statement is for
+type Role string
+
+const (
+	RoleUser  Role = "user"  // Pays for tasks
+	RoleAgent Role = "agent" // Earns from tasks
+)
+
+// ParseRole parses a role, defaulting to RoleUser
+func ParseRole(s string) (Role, error) {
+	switch Role(s) {
+	case "", RoleUser:
+		return RoleUser, nil
+	case RoleAgent:
+		return RoleAgent, nil
+	}
+	return "", ErrInvalidRole
+}
+
+// LineItemType classifies a statement line
+type LineItemType string
+
+const (
+	LineTaskCharge     LineItemType = "task_charge"     // Escrow funded for a task
+	LineRefund         LineItemType = "refund"          // Escrow refunded to the payer
+	LineEarning        LineItemType = "earning"         // Escrow or channel payment received
+	LineChannelFunding LineItemType = "channel_funding" // Funds committed to a payment channel
+	LineChannelReturn  LineItemType = "channel_return"  // Unused channel funds returned on close
+	LineFee            LineItemType = "fee"             // Platform fee
+	LineDeposit        LineItemType = "deposit"         // Funds added to the account
+	LineWithdrawal     LineItemType = "withdrawal"      // Funds withdrawn from the account
+	LineAdjustment     LineItemType = "adjustment"      // Reversals and manual adjustments
+)
+
+// Period is a half-open billing interval [Start, End)
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// MonthPeriod returns the calendar month containing t, in UTC
+func MonthPeriod(t time.Time) Period {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Period{Start: start, End: start.AddDate(0, 1, 0)}
+}
+
+// ParseMonth parses a "YYYY-MM" month into a billing period
+func ParseMonth(s string) (Period, error) {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return Period{}, fmt.Errorf("%w: expected YYYY-MM, got %q", ErrInvalidPeriod, s)
+	}
+	return MonthPeriod(t), nil
+}
+
+// Label formats the period as "YYYY-MM" for monthly periods
+func (p Period) Label() string {
+	if p.Start.AddDate(0, 1, 0).Equal(p.End) && p.Start.Day() == 1 {
+		return p.Start.Format("2006-01")
+	}
+	return p.Start.Format("2006-01-02") + "_" + p.End.Format("2006-01-02")
+}
+
+// LineItem is one ledger movement on a statement.
+// Amount is signed from the account holder's perspective: negative amounts
+// leave the account, positive amounts arrive in it.
+type LineItem struct {
+	Date        time.Time    `json:"date"`
+	Type        LineItemType `json:"type"`
+	Description string       `json:"description"`
+	TaskID      string       `json:"task_id,omitempty"`
+	Reference   string       `json:"reference,omitempty"`
+	EntryID     string       `json:"entry_id"`
+	Amount      float64      `json:"amount"`
+}
+
+// Totals summarizes a statement
+type Totals struct {
+	TaskCharges     float64 `json:"task_charges"`
+	Refunds         float64 `json:"refunds"`
+	ChannelPayments float64 `json:"channel_payments"` // Channel funding net of returns
+	Fees            float64 `json:"fees"`
+	Earnings        float64 `json:"earnings"`
+	Deposits        float64 `json:"deposits"`
+	Withdrawals     float64 `json:"withdrawals"`
+	Adjustments     float64 `json:"adjustments"`
+	TaskCount       int     `json:"task_count"`
+
+	// AmountDue is what a user spent in the period (charges - refunds + channels + fees)
+	AmountDue float64 `json:"amount_due"`
+	// NetEarnings is what an agent earned in the period (earnings - fees)
+	NetEarnings float64 `json:"net_earnings"`
+}
+
+// Statement is a periodic account statement / invoice
+type Statement struct {
+	ID             string     `json:"id"`
+	AccountID      string     `json:"account_id"`
+	SubjectID      string     `json:"subject_id"`
+	Role           Role       `json:"role"`
+	Period         Period     `json:"period"`
+	OpeningBalance float64    `json:"opening_balance"`
+	ClosingBalance float64    `json:"closing_balance"`
+	Totals         Totals     `json:"totals"`
+	LineItems      []LineItem `json:"line_items"`
+	GeneratedAt    time.Time  `json:"generated_at"`
+}
+
+// Service generates statements from the ledger
+type Service struct {
+	ledger *ledger.Ledger
+	logger *zap.Logger
+}
+
+// NewService creates a billing service reading from l
+func NewService(l *ledger.Ledger, logger *zap.Logger) *Service {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Service{
+		ledger: l,
+		logger: logger,
+	}
+}
+
+// GenerateStatement builds the statement for subjectID (a user or agent DID) over period
+func (s *Service) GenerateStatement(ctx context.Context, subjectID string, role Role, period Period) (*Statement, error) {
+	if err := validateRequest(subjectID, role); err != nil {
+		return nil, err
+	}
+	if !period.End.After(period.Start) {
+		return nil, ErrInvalidPeriod
+	}
+
+	opening, err := s.balanceBefore(ctx, ledger.UserAccount(subjectID), period.Start)
+	if err != nil {
+		return nil, err
+	}
+	stmt, _, err := s.statement(ctx, subjectID, role, period, opening)
+	return stmt, err
+}
+
+// validateRequest checks the subject and role of a statement request
+func validateRequest(subjectID string, role Role) error {
+	if subjectID == "" {
+		return fmt.Errorf("subject ID is required")
+	}
+	if role != RoleUser && role != RoleAgent {
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// balanceBefore sums every posting to accountID before t
+func (s *Service) balanceBefore(ctx context.Context, accountID string, t time.Time) (ledger.Amount, error) {
+	before, err := s.ledger.Entries(ctx, ledger.EntryFilter{AccountID: accountID, Until: &t})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load prior entries: %w", err)
+	}
+	var balance ledger.Amount
+	for _, entry := range before {
+		balance += signedAmount(entry, accountID)
+	}
+	return balance, nil
+}
+
+// statement builds the statement for period starting from the opening
+// balance, and returns it with its closing balance
+func (s *Service) statement(ctx context.Context, subjectID string, role Role, period Period, opening ledger.Amount) (*Statement, ledger.Amount, error) {
+	accountID := ledger.UserAccount(subjectID)
+	entries, err := s.ledger.Entries(ctx, ledger.EntryFilter{
+		AccountID: accountID,
+		Since:     &period.Start,
+		Until:     &period.End,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load statement entries: %w", err)
+	}
+
+	stmt := &Statement{
+		ID:          fmt.Sprintf("stmt-%s-%s-%s", role, subjectID, period.Label()),
+		AccountID:   accountID,
+		SubjectID:   subjectID,
+		Role:        role,
+		Period:      period,
+		LineItems:   []LineItem{},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	closing := opening
+	tasks := make(map[string]bool)
+	var charges, refunds, channels, fees, earnings, deposits, withdrawals, adjustments ledger.Amount
+
+	for _, entry := range entries {
+		amount := signedAmount(entry, accountID)
+		if amount == 0 {
+			continue // entry only moves funds between the account's own sub-accounts
+		}
+		closing += amount
+
+		item := LineItem{
+			Date:        entry.CreatedAt,
+			Type:        classify(entry),
+			Description: entry.Description,
+			TaskID:      taskIDOf(entry),
+			Reference:   entry.Reference,
+			EntryID:     entry.ID.String(),
+			Amount:      amount.Float64(),
+		}
+
+		switch item.Type {
+		case LineTaskCharge:
+			charges -= amount
+			if item.TaskID != "" {
+				tasks[item.TaskID] = true
+			}
+		case LineRefund:
+			refunds += amount
+		case LineChannelFunding, LineChannelReturn:
+			channels -= amount
+		case LineFee:
+			fees -= amount
+		case LineEarning:
+			earnings += amount
+			if role == RoleAgent && item.TaskID != "" {
+				tasks[item.TaskID] = true
+			}
+		case LineDeposit:
+			deposits += amount
+		case LineWithdrawal:
+			withdrawals -= amount
+		default:
+			adjustments += amount
+		}
+
+		stmt.LineItems = append(stmt.LineItems, item)
+	}
+
+	sort.SliceStable(stmt.LineItems, func(i, j int) bool {
+		return stmt.LineItems[i].Date.Before(stmt.LineItems[j].Date)
+	})
+
+	stmt.OpeningBalance = opening.Float64()
+	stmt.ClosingBalance = closing.Float64()
+	stmt.Totals = Totals{
+		TaskCharges:     charges.Float64(),
+		Refunds:         refunds.Float64(),
+		ChannelPayments: channels.Float64(),
+		Fees:            fees.Float64(),
+		Earnings:        earnings.Float64(),
+		Deposits:        deposits.Float64(),
+		Withdrawals:     withdrawals.Float64(),
+		Adjustments:     adjustments.Float64(),
+		TaskCount:       len(tasks),
+		AmountDue:       (charges - refunds + channels + fees).Float64(),
+		NetEarnings:     (earnings - fees).Float64(),
+	}
+
+	s.logger.Debug("statement generated",
+		zap.String("statement_id", stmt.ID),
+		zap.Int("line_items", len(stmt.LineItems)),
+	)
+
+	return stmt, closing, nil
+}
+
+// GenerateMonthlyStatements builds one statement per calendar month from 'from' through 'to'
+func (s *Service) GenerateMonthlyStatements(ctx context.Context, subjectID string, role Role, from, to time.Time) ([]*Statement, error) {
+	if err := validateRequest(subjectID, role); err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+
+	// Each month opens at the previous month's closing balance, so the
+	// history before the range is scanned once
+	first := MonthPeriod(from)
+	balance, err := s.balanceBefore(ctx, ledger.UserAccount(subjectID), first.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	last := MonthPeriod(to).Start
+	var statements []*Statement
+	for period := first; !period.Start.After(last); period = MonthPeriod(period.End) {
+		var stmt *Statement
+		stmt, balance, err = s.statement(ctx, subjectID, role, period, balance)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+// signedAmount is the net effect of an entry on a liability account:
+// credits increase what the platform owes the holder, debits decrease it
+func signedAmount(entry *ledger.Entry, accountID string) ledger.Amount {
+	var net ledger.Amount
+	for _, p := range entry.Postings {
+		if p.AccountID != accountID {
+			continue
+		}
+		if p.Direction == ledger.Credit {
+			net += p.Amount
+		} else {
+			net -= p.Amount
+		}
+	}
+	return net
+}
+
+// classify maps a journal entry onto a statement line type
+func classify(entry *ledger.Entry) LineItemType {
+	if entry.ReversesID != nil {
+		return LineAdjustment
+	}
+	for _, p := range entry.Postings {
+		if p.AccountID == ledger.AccountFees {
+			return LineFee
+		}
+	}
+
+	switch entry.Description {
+	case ledger.DescEscrowFund:
+		return LineTaskCharge
+	case ledger.DescEscrowRefund:
+		return LineRefund
+	case ledger.DescEscrowRelease, ledger.DescChannelRelease:
+		return LineEarning
+	case ledger.DescChannelDeposit:
+		return LineChannelFunding
+	case ledger.DescChannelClose:
+		return LineChannelReturn
+	case ledger.DescDeposit:
+		return LineDeposit
+	case ledger.DescWithdrawal:
+		return LineWithdrawal
+	}
+	return LineAdjustment
+}
+
+// taskIDOf returns the task an entry pays for, if known
+func taskIDOf(entry *ledger.Entry) string {
+	if taskID := entry.Metadata[ledger.MetadataTaskID]; taskID != "" {
+		return taskID
+	}
+	// Channel escrow movements use the task ID as their reference
+	if entry.Description == ledger.DescChannelRelease || entry.Description == ledger.DescChannelRefund {
+		return entry.Reference
+	}
+	return ""
+}
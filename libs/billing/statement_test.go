@@ -0,0 +1,143 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, l *ledger.Ledger, key, from, to string, amount float64, desc, ref, taskID string, at time.Time) {
+	t.Helper()
+	entry := ledger.NewTransfer(key, from, to, ledger.FromFloat(amount), desc, ref)
+	entry.CreatedAt = at
+	if taskID != "" {
+		entry.Metadata = map[string]string{ledger.MetadataTaskID: taskID}
+	}
+	_, err := l.Post(context.Background(), entry)
+	require.NoError(t, err)
+}
+
+func seedLedger(t *testing.T) *ledger.Ledger {
+	l := ledger.New(ledger.NewMemoryStore(), nil)
+	jan := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 12, 0, 0, 0, time.UTC)
+	user, agent := ledger.UserAccount("alice"), ledger.UserAccount("did:agent")
+
+	post(t, l, "dep", ledger.AccountCash, user, 100, ledger.DescDeposit, "", "", jan)
+	post(t, l, "e1:fund", user, ledger.EscrowAccount("e1"), 30, ledger.DescEscrowFund, "e1", "task-1", jan)
+	post(t, l, "e1:release", ledger.EscrowAccount("e1"), agent, 30, ledger.DescEscrowRelease, "e1", "task-1", jan.Add(time.Hour))
+	post(t, l, "fee1", agent, ledger.AccountFees, 1.5, ledger.DescFee, "e1", "task-1", jan.Add(time.Hour))
+
+	post(t, l, "e2:fund", user, ledger.EscrowAccount("e2"), 20, ledger.DescEscrowFund, "e2", "task-2", feb)
+	post(t, l, "e2:refund", ledger.EscrowAccount("e2"), user, 20, ledger.DescEscrowRefund, "e2", "task-2", feb.Add(time.Hour))
+	post(t, l, "ch:dep", user, ledger.ChannelAccount("c1"), 10, ledger.DescChannelDeposit, "c1", "", feb)
+	post(t, l, "ch:close", ledger.ChannelAccount("c1"), user, 4, ledger.DescChannelClose, "c1", "", feb.Add(2*time.Hour))
+	return l
+}
+
+func TestGenerateStatementUser(t *testing.T) {
+	svc := NewService(seedLedger(t), nil)
+	ctx := context.Background()
+
+	period, err := ParseMonth("2026-01")
+	require.NoError(t, err)
+
+	jan, err := svc.GenerateStatement(ctx, "alice", RoleUser, period)
+	require.NoError(t, err)
+	assert.Equal(t, "stmt-user-alice-2026-01", jan.ID)
+	assert.Equal(t, 0.0, jan.OpeningBalance)
+	assert.Equal(t, 70.0, jan.ClosingBalance)
+	assert.Equal(t, 30.0, jan.Totals.TaskCharges)
+	assert.Equal(t, 100.0, jan.Totals.Deposits)
+	assert.Equal(t, 30.0, jan.Totals.AmountDue)
+	assert.Equal(t, 1, jan.Totals.TaskCount)
+	require.Len(t, jan.LineItems, 2)
+	assert.Equal(t, LineDeposit, jan.LineItems[0].Type)
+	assert.Equal(t, -30.0, jan.LineItems[1].Amount)
+	assert.Equal(t, "task-1", jan.LineItems[1].TaskID)
+
+	feb, err := svc.GenerateStatement(ctx, "alice", RoleUser, MonthPeriod(period.End))
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, feb.OpeningBalance)
+	assert.Equal(t, 64.0, feb.ClosingBalance)
+	assert.Equal(t, 20.0, feb.Totals.Refunds)
+	assert.Equal(t, 6.0, feb.Totals.ChannelPayments)
+	assert.Equal(t, 6.0, feb.Totals.AmountDue)
+}
+
+func TestGenerateStatementAgent(t *testing.T) {
+	svc := NewService(seedLedger(t), nil)
+
+	stmt, err := svc.GenerateStatement(context.Background(), "did:agent", RoleAgent, MonthPeriod(time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, stmt.Totals.Earnings)
+	assert.Equal(t, 1.5, stmt.Totals.Fees)
+	assert.Equal(t, 28.5, stmt.Totals.NetEarnings)
+	assert.Equal(t, 1, stmt.Totals.TaskCount)
+}
+
+func TestGenerateMonthlyStatements(t *testing.T) {
+	svc := NewService(seedLedger(t), nil)
+	from := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	statements, err := svc.GenerateMonthlyStatements(context.Background(), "alice", RoleUser, from, to)
+	require.NoError(t, err)
+	require.Len(t, statements, 3)
+	assert.Equal(t, "2026-03", statements[2].Period.Label())
+	assert.Empty(t, statements[2].LineItems)
+	assert.Equal(t, statements[1].ClosingBalance, statements[2].OpeningBalance)
+
+	// Carried-forward balances match a statement generated on its own
+	feb, err := svc.GenerateStatement(context.Background(), "alice", RoleUser, statements[1].Period)
+	require.NoError(t, err)
+	assert.Equal(t, feb.OpeningBalance, statements[1].OpeningBalance)
+	assert.Equal(t, feb.ClosingBalance, statements[1].ClosingBalance)
+
+	_, err = svc.GenerateMonthlyStatements(context.Background(), "alice", RoleUser, to, from)
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestParseInputs(t *testing.T) {
+	_, err := ParseMonth("2026-13")
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+
+	role, err := ParseRole("")
+	require.NoError(t, err)
+	assert.Equal(t, RoleUser, role)
+	_, err = ParseRole("admin")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestExport(t *testing.T) {
+	svc := NewService(seedLedger(t), nil)
+	period, err := ParseMonth("2026-02")
+	require.NoError(t, err)
+	stmt, err := svc.GenerateStatement(context.Background(), "alice", RoleUser, period)
+	require.NoError(t, err)
+
+	var jsonBuf bytes.Buffer
+	require.NoError(t, WriteJSON(&jsonBuf, []*Statement{stmt}))
+	var decoded []*Statement
+	require.NoError(t, json.Unmarshal(jsonBuf.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, stmt.Totals, decoded[0].Totals)
+
+	var csvBuf bytes.Buffer
+	require.NoError(t, WriteCSV(&csvBuf, []*Statement{stmt}))
+	rows, err := csv.NewReader(&csvBuf).ReadAll()
+	require.NoError(t, err)
+	// header + 4 line items + summary
+	require.Len(t, rows, 6)
+	assert.Equal(t, csvHeader, rows[0])
+	last := rows[len(rows)-1]
+	assert.Equal(t, "total", last[6])
+	assert.Equal(t, "6.00000000", last[11])
+}
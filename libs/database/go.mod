@@ -3,8 +3,11 @@ module github.com/aidenlippert/zerostate/libs/database
 go 1.24
 
 require (
+	github.com/aidenlippert/zerostate/libs/ledger v0.0.0
 	github.com/google/uuid v1.6.0
 	github.com/mattn/go-sqlite3 v1.14.32
 )
 
 require github.com/lib/pq v1.10.9
+
+replace github.com/aidenlippert/zerostate/libs/ledger => ../ledger
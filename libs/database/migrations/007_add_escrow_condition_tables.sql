@@ -0,0 +1,41 @@
+-- Migration 007: Add escrow release condition tables
+--
+-- Stores the facts (task results, approvals, signatures, milestones) that
+-- escrow release conditions are evaluated against, and an append-only log
+-- of every evaluation for auditing.
+
+-- Condition facts: append-only log of inputs to condition evaluation
+CREATE TABLE IF NOT EXISTS escrow_condition_facts (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	escrow_id UUID NOT NULL REFERENCES escrows(id) ON DELETE CASCADE,
+	fact_type TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	value TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for loading facts in order for an escrow
+CREATE INDEX IF NOT EXISTS idx_escrow_condition_facts_escrow ON escrow_condition_facts(escrow_id, created_at);
+
+-- Condition evaluations: audit log of every evaluation and its outcome
+CREATE TABLE IF NOT EXISTS escrow_condition_evaluations (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	escrow_id UUID NOT NULL REFERENCES escrows(id) ON DELETE CASCADE,
+	trigger TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	results JSONB NOT NULL DEFAULT '[]'::jsonb,
+	applied BOOLEAN NOT NULL DEFAULT false,
+	evaluated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for reading an escrow's evaluation history
+CREATE INDEX IF NOT EXISTS idx_escrow_condition_evaluations_escrow ON escrow_condition_evaluations(escrow_id, evaluated_at);
+
+-- Comments for documentation
+COMMENT ON TABLE escrow_condition_facts IS 'Inputs to escrow release condition evaluation';
+COMMENT ON TABLE escrow_condition_evaluations IS 'Audit log of escrow release condition evaluations';
+
+COMMENT ON COLUMN escrow_condition_facts.fact_type IS 'Type: result, approval, signature, milestone';
+COMMENT ON COLUMN escrow_condition_facts.subject IS 'Result hash, approver ID, signer ID or milestone name';
+COMMENT ON COLUMN escrow_condition_evaluations.trigger IS 'What caused the evaluation: task_result, approval, signature, milestone, scheduled';
+COMMENT ON COLUMN escrow_condition_evaluations.outcome IS 'Outcome: release, refund, pending';
@@ -0,0 +1,68 @@
+-- Migration 008: Add double-entry ledger tables
+--
+-- Every balance change (deposits, withdrawals, escrow funding, release and
+-- refund, payment channels) is journaled here as a balanced entry. Entries
+-- and postings are append-only; corrections are posted as reversing entries.
+
+-- Ledger accounts: chart of accounts, registered on first posting
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+	created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Journal entries: one row per balanced money movement
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id UUID PRIMARY KEY,
+	idempotency_key TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	reference TEXT NOT NULL DEFAULT '',
+	metadata JSONB,
+	reverses_id UUID REFERENCES ledger_entries(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for looking up entries by escrow, channel or task
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries(reference);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_created_at ON ledger_entries(created_at);
+
+-- Postings: immutable debit/credit lines, amounts in 1e-8 units
+CREATE TABLE IF NOT EXISTS ledger_postings (
+	entry_id UUID NOT NULL REFERENCES ledger_entries(id),
+	line INTEGER NOT NULL,
+	account_id TEXT NOT NULL REFERENCES ledger_accounts(id),
+	direction TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+	amount BIGINT NOT NULL CHECK (amount > 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (entry_id, line)
+);
+
+-- Index for account balances and statements
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account ON ledger_postings(account_id, created_at);
+
+-- Immutability: reject any UPDATE or DELETE on the journal
+CREATE OR REPLACE FUNCTION ledger_reject_mutation() RETURNS TRIGGER AS $$
+BEGIN
+	RAISE EXCEPTION 'ledger is append-only: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_immutable ON ledger_entries;
+CREATE TRIGGER ledger_entries_immutable
+	BEFORE UPDATE OR DELETE ON ledger_entries
+	FOR EACH ROW EXECUTE FUNCTION ledger_reject_mutation();
+
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+CREATE TRIGGER ledger_postings_immutable
+	BEFORE UPDATE OR DELETE ON ledger_postings
+	FOR EACH ROW EXECUTE FUNCTION ledger_reject_mutation();
+
+-- Comments for documentation
+COMMENT ON TABLE ledger_accounts IS 'Double-entry chart of accounts';
+COMMENT ON TABLE ledger_entries IS 'Append-only journal of balanced money movements';
+COMMENT ON TABLE ledger_postings IS 'Immutable debit/credit lines of journal entries';
+
+COMMENT ON COLUMN ledger_accounts.id IS 'Account ID: platform:cash, platform:fees, user:<did>, escrow:<id>, channel:<id>';
+COMMENT ON COLUMN ledger_entries.idempotency_key IS 'Caller-supplied key; replays return the original entry';
+COMMENT ON COLUMN ledger_entries.reverses_id IS 'Entry this entry reverses, for corrections';
+COMMENT ON COLUMN ledger_postings.amount IS 'Positive amount in 1e-8 currency units';
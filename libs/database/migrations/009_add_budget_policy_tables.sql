@@ -0,0 +1,52 @@
+-- Migration 009: Add budget policy tables
+--
+-- Budget policies cap what a user, or one of a user's API keys, may commit to
+-- tasks. Every authorized task budget is recorded in an append-mostly spend
+-- log that policies are evaluated against.
+
+-- Budget policies: one per user, plus at most one per API key
+CREATE TABLE IF NOT EXISTS budget_policies (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id TEXT NOT NULL,
+	api_key_id UUID REFERENCES api_keys(id) ON DELETE CASCADE,
+	daily_limit DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (daily_limit >= 0),
+	monthly_limit DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (monthly_limit >= 0),
+	max_escrowed DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (max_escrowed >= 0),
+	capability_limits JSONB NOT NULL DEFAULT '{}'::jsonb,
+	soft_limit_ratio DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (soft_limit_ratio >= 0 AND soft_limit_ratio <= 1),
+	created_at TIMESTAMPTZ DEFAULT NOW(),
+	updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Unique scope: NULL api_key_id (the user-wide policy) is treated as one value
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_policies_scope
+	ON budget_policies(user_id, (COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid)));
+
+-- Spend log: one row per authorized task budget
+CREATE TABLE IF NOT EXISTS budget_spend (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id TEXT NOT NULL,
+	api_key_id UUID,
+	task_id TEXT NOT NULL,
+	source TEXT NOT NULL,
+	capabilities JSONB NOT NULL DEFAULT '[]'::jsonb,
+	amount DOUBLE PRECISION NOT NULL CHECK (amount >= 0),
+	charged DOUBLE PRECISION,
+	status TEXT NOT NULL DEFAULT 'reserved' CHECK (status IN ('reserved', 'settled')),
+	created_at TIMESTAMPTZ DEFAULT NOW(),
+	settled_at TIMESTAMPTZ
+);
+
+-- Indexes for usage windows and settlement
+CREATE INDEX IF NOT EXISTS idx_budget_spend_user_created ON budget_spend(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_budget_spend_key_created ON budget_spend(api_key_id, created_at) WHERE api_key_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_budget_spend_task ON budget_spend(task_id) WHERE status = 'reserved';
+
+-- Comments for documentation
+COMMENT ON TABLE budget_policies IS 'Spending limits per user and per API key';
+COMMENT ON TABLE budget_spend IS 'Task budgets authorized against budget policies';
+
+COMMENT ON COLUMN budget_policies.capability_limits IS 'Monthly cap per capability, e.g. {"image-generation": 50}';
+COMMENT ON COLUMN budget_policies.soft_limit_ratio IS 'Fraction of a limit at which a warning is sent; 0 uses the default (0.8)';
+COMMENT ON COLUMN budget_spend.amount IS 'Task budget reserved at submission';
+COMMENT ON COLUMN budget_spend.charged IS 'Actual cost once the task settled';
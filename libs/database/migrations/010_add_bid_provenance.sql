@@ -0,0 +1,16 @@
+-- Migration 010: Record where bids come from
+--
+-- Collusion detection looks for several bidder DIDs driven by the same
+-- account or address, and for requesters bidding on their own auctions.
+
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS submitted_by TEXT;
+ALTER TABLE bids ADD COLUMN IF NOT EXISTS source_ip TEXT;
+
+-- Indexes for grouping bids by origin
+CREATE INDEX IF NOT EXISTS idx_bids_submitted_by ON bids(submitted_by) WHERE submitted_by IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bids_source_ip ON bids(source_ip) WHERE source_ip IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bids_created ON bids(created_at);
+
+-- Comments for documentation
+COMMENT ON COLUMN bids.submitted_by IS 'Account or API key that submitted the bid';
+COMMENT ON COLUMN bids.source_ip IS 'Client address the bid was submitted from';
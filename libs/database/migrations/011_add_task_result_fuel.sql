@@ -0,0 +1,23 @@
+-- Migration 011: Record metered fuel on task results
+--
+-- WASM tasks are metered by instruction count. Receipts report the fuel a
+-- task consumed so its cost can be checked independently of host speed.
+
+-- Results written by the execution result store
+CREATE TABLE IF NOT EXISTS task_results (
+	task_id TEXT PRIMARY KEY,
+	agent_id TEXT NOT NULL,
+	exit_code INTEGER NOT NULL DEFAULT 0,
+	stdout BYTEA,
+	stderr BYTEA,
+	duration_ms BIGINT NOT NULL DEFAULT 0,
+	error TEXT,
+	created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS fuel_used BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_task_results_agent ON task_results(agent_id, created_at DESC);
+
+-- Comments for documentation
+COMMENT ON COLUMN task_results.fuel_used IS 'WASM instructions charged to the task; 0 when unmetered';
@@ -0,0 +1,10 @@
+-- Migration 012: Record output artifacts on task results
+--
+-- WASM tasks may write files to /output in their task filesystem. Each file
+-- is hashed and uploaded after the run; receipts reference them by hash and
+-- storage key.
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS artifacts JSONB;
+
+-- Comments for documentation
+COMMENT ON COLUMN task_results.artifacts IS 'Output files as [{path, size, sha256, key, url}]; NULL when the task wrote none';
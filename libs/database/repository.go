@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
@@ -306,16 +307,64 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID) erro
 	return nil
 }
 
+// ============================================================================
+// API KEY REPOSITORY
+// ============================================================================
+
+type APIKeyRepository struct {
+	db *Database
+}
+
+func NewAPIKeyRepository(db *Database) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// ListByPrefix returns the active API keys stored under a lookup prefix.
+// Callers must still verify the full key against each key's hash.
+func (r *APIKeyRepository) ListByPrefix(ctx context.Context, prefix string) ([]*APIKey, error) {
+	query := `
+		SELECT id, user_id, key_hash, key_prefix, name, scopes, created_at,
+		       expires_at, last_used_at, revoked_at, is_active
+		FROM api_keys
+		WHERE key_prefix = $1 AND is_active = true
+	`
+	rows, err := r.db.db.QueryContext(ctx, query, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		var key APIKey
+		if err := rows.Scan(
+			&key.ID, &key.UserID, &key.KeyHash, &key.KeyPrefix, &key.Name, &key.Scopes, &key.CreatedAt,
+			&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.IsActive,
+		); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+		}
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
+	return keys, nil
+}
+
 // ============================================================================
 // ACCOUNT REPOSITORY
 // ============================================================================
 
 type AccountRepository struct {
-	db *Database
+	db     *Database
+	ledger *ledger.Ledger
 }
 
 func NewAccountRepository(db *Database) *AccountRepository {
-	return &AccountRepository{db: db}
+	return &AccountRepository{
+		db:     db,
+		ledger: ledger.New(ledger.NewSQLStore(db.db), nil),
+	}
 }
 
 // Create creates a new account
@@ -362,8 +411,25 @@ func (r *AccountRepository) GetByDID(ctx context.Context, did string) (*Account,
 	return &account, nil
 }
 
-// UpdateBalance updates account balance atomically
+// UpdateBalance updates account balance atomically and journals the change
+// against platform cash in the same transaction
 func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, did string, delta float64) error {
+	if tx == nil {
+		ownTx, err := r.db.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabase, err)
+		}
+		defer ownTx.Rollback()
+
+		if err := r.UpdateBalance(ctx, ownTx, did, delta); err != nil {
+			return err
+		}
+		if err := ownTx.Commit(); err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabase, err)
+		}
+		return nil
+	}
+
 	query := `
 		UPDATE accounts
 		SET balance = balance + $1,
@@ -371,15 +437,7 @@ func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, did s
 		    total_withdrawn = CASE WHEN $1 < 0 THEN total_withdrawn + ABS($1) ELSE total_withdrawn END
 		WHERE did = $2 AND balance + $1 >= 0
 	`
-	var result sql.Result
-	var err error
-
-	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, delta, did)
-	} else {
-		result, err = r.db.db.ExecContext(ctx, query, delta, did)
-	}
-
+	result, err := tx.ExecContext(ctx, query, delta, did)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrDatabase, err)
 	}
@@ -387,6 +445,27 @@ func (r *AccountRepository) UpdateBalance(ctx context.Context, tx *sql.Tx, did s
 	if rows == 0 {
 		return ErrInvalidInput // Balance would go negative
 	}
+
+	if delta == 0 {
+		return nil
+	}
+	entry := ledger.NewTransfer(
+		"balance:"+uuid.New().String(),
+		ledger.AccountCash, ledger.UserAccount(did),
+		ledger.FromFloat(delta),
+		ledger.DescDeposit, did,
+	)
+	if delta < 0 {
+		entry = ledger.NewTransfer(
+			"balance:"+uuid.New().String(),
+			ledger.UserAccount(did), ledger.AccountCash,
+			ledger.FromFloat(-delta),
+			ledger.DescWithdrawal, did,
+		)
+	}
+	if _, err := r.ledger.PostTx(ctx, tx, entry); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,590 @@
+package economic
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Budget policy defaults
+const (
+	// DefaultSoftLimitRatio is the fraction of a limit at which users are warned
+	DefaultSoftLimitRatio = 0.8
+
+	// DefaultReservationTTL bounds how long an unsettled task counts towards
+	// the concurrently escrowed amount
+	DefaultReservationTTL = 24 * time.Hour
+)
+
+// Budget errors
+var (
+	// ErrBudgetExceeded is wrapped by every BudgetViolation
+	ErrBudgetExceeded = errors.New("budget limit exceeded")
+
+	// ErrInvalidBudgetPolicy indicates a malformed budget policy
+	ErrInvalidBudgetPolicy = errors.New("invalid budget policy")
+
+	// ErrBudgetPolicyNotFound indicates the policy does not exist or belongs to another user
+	ErrBudgetPolicyNotFound = errors.New("budget policy not found")
+
+	// ErrAPIKeyNotOwned indicates a policy referenced an API key the user does not own
+	ErrAPIKeyNotOwned = errors.New("api key not found for user")
+)
+
+// BudgetLimitKind identifies one of the limits in a budget policy
+type BudgetLimitKind string
+
+const (
+	BudgetLimitDaily      BudgetLimitKind = "daily"      // Spend since midnight UTC
+	BudgetLimitMonthly    BudgetLimitKind = "monthly"    // Spend since the start of the UTC month
+	BudgetLimitCapability BudgetLimitKind = "capability" // Monthly spend on one capability
+	BudgetLimitEscrowed   BudgetLimitKind = "escrowed"   // Budget held by unfinished tasks
+)
+
+// ErrorCode returns the API error code reported when this limit rejects a request
+func (k BudgetLimitKind) ErrorCode() string {
+	return "BUDGET_" + strings.ToUpper(string(k)) + "_LIMIT_EXCEEDED"
+}
+
+// SpendSource records which entry point requested the spend
+type SpendSource string
+
+const (
+	SpendSourceTask       SpendSource = "task"
+	SpendSourceBatch      SpendSource = "batch"
+	SpendSourceDelegation SpendSource = "delegation"
+)
+
+// BudgetPolicy caps spending for a user, or for a single API key of a user.
+// Zero limits are unlimited. A request must satisfy every policy that applies
+// to it: the user-wide policy and the policy of the API key it was made with.
+type BudgetPolicy struct {
+	ID               uuid.UUID          `json:"id"`
+	UserID           string             `json:"user_id"`
+	APIKeyID         *uuid.UUID         `json:"api_key_id,omitempty"` // nil applies to all of the user's spending
+	DailyLimit       float64            `json:"daily_limit"`
+	MonthlyLimit     float64            `json:"monthly_limit"`
+	MaxEscrowed      float64            `json:"max_escrowed"`
+	CapabilityLimits map[string]float64 `json:"capability_limits,omitempty"` // Monthly cap per capability
+	SoftLimitRatio   float64            `json:"soft_limit_ratio"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// Scope describes what the policy applies to, for error messages
+func (p *BudgetPolicy) Scope() string {
+	if p.APIKeyID != nil {
+		return "api_key"
+	}
+	return "user"
+}
+
+// Validate checks the policy's limits
+func (p *BudgetPolicy) Validate() error {
+	if p.UserID == "" {
+		return fmt.Errorf("%w: user ID is required", ErrInvalidBudgetPolicy)
+	}
+	if p.DailyLimit < 0 || p.MonthlyLimit < 0 || p.MaxEscrowed < 0 {
+		return fmt.Errorf("%w: limits cannot be negative", ErrInvalidBudgetPolicy)
+	}
+	for capability, limit := range p.CapabilityLimits {
+		if capability == "" || limit < 0 {
+			return fmt.Errorf("%w: invalid limit for capability %q", ErrInvalidBudgetPolicy, capability)
+		}
+	}
+	if p.SoftLimitRatio < 0 || p.SoftLimitRatio > 1 {
+		return fmt.Errorf("%w: soft_limit_ratio must be between 0 and 1", ErrInvalidBudgetPolicy)
+	}
+	return nil
+}
+
+// SpendItem is the budget of one task in a spend request
+type SpendItem struct {
+	TaskID       string
+	Capabilities []string
+	Amount       float64
+}
+
+// SpendRequest is a set of tasks whose budgets must be authorized together
+type SpendRequest struct {
+	UserID   string
+	APIKeyID *uuid.UUID
+	Source   SpendSource
+	Items    []SpendItem
+}
+
+// Total returns the combined budget of all items
+func (r *SpendRequest) Total() float64 {
+	var total float64
+	for _, item := range r.Items {
+		total += item.Amount
+	}
+	return total
+}
+
+// capabilityTotals returns the requested amount per capability
+func (r *SpendRequest) capabilityTotals() map[string]float64 {
+	totals := make(map[string]float64)
+	for _, item := range r.Items {
+		for _, capability := range item.Capabilities {
+			totals[capability] += item.Amount
+		}
+	}
+	return totals
+}
+
+// BudgetUsage is the spending already counted against a policy
+type BudgetUsage struct {
+	Daily      float64            `json:"daily"`
+	Monthly    float64            `json:"monthly"`
+	Escrowed   float64            `json:"escrowed"`
+	Capability map[string]float64 `json:"capability,omitempty"`
+}
+
+// BudgetViolation is returned when a request would exceed a hard limit
+type BudgetViolation struct {
+	Kind       BudgetLimitKind `json:"kind"`
+	Code       string          `json:"code"`
+	PolicyID   uuid.UUID       `json:"policy_id"`
+	Scope      string          `json:"scope"`
+	Capability string          `json:"capability,omitempty"`
+	Limit      float64         `json:"limit"`
+	Current    float64         `json:"current"`
+	Requested  float64         `json:"requested"`
+}
+
+// Error implements error
+func (v *BudgetViolation) Error() string {
+	limit := string(v.Kind)
+	if v.Capability != "" {
+		limit = fmt.Sprintf("%s (%s)", limit, v.Capability)
+	}
+	return fmt.Sprintf("%s limit of %.2f would be exceeded: %.2f already committed, %.2f requested",
+		limit, v.Limit, v.Current, v.Requested)
+}
+
+// Unwrap allows errors.Is(err, ErrBudgetExceeded)
+func (v *BudgetViolation) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// BudgetWarning reports a soft limit crossed by an accepted request
+type BudgetWarning struct {
+	Kind       BudgetLimitKind `json:"kind"`
+	PolicyID   uuid.UUID       `json:"policy_id"`
+	Scope      string          `json:"scope"`
+	Capability string          `json:"capability,omitempty"`
+	Limit      float64         `json:"limit"`
+	Projected  float64         `json:"projected"`
+	Ratio      float64         `json:"ratio"`
+}
+
+// BudgetDecision is the outcome of an accepted spend request
+type BudgetDecision struct {
+	Warnings []BudgetWarning `json:"warnings,omitempty"`
+}
+
+// EvaluateBudget checks a request against one policy and its current usage.
+// It returns the first hard limit the request would exceed, or the soft
+// limits it crosses if it is within every hard limit.
+func EvaluateBudget(policy *BudgetPolicy, usage *BudgetUsage, req *SpendRequest) (*BudgetViolation, []BudgetWarning) {
+	type check struct {
+		kind       BudgetLimitKind
+		capability string
+		limit      float64
+		current    float64
+		requested  float64
+	}
+
+	total := req.Total()
+	checks := []check{
+		{kind: BudgetLimitDaily, limit: policy.DailyLimit, current: usage.Daily, requested: total},
+		{kind: BudgetLimitMonthly, limit: policy.MonthlyLimit, current: usage.Monthly, requested: total},
+		{kind: BudgetLimitEscrowed, limit: policy.MaxEscrowed, current: usage.Escrowed, requested: total},
+	}
+
+	requested := req.capabilityTotals()
+	capabilities := make([]string, 0, len(requested))
+	for capability := range requested {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	for _, capability := range capabilities {
+		checks = append(checks, check{
+			kind:       BudgetLimitCapability,
+			capability: capability,
+			limit:      policy.CapabilityLimits[capability],
+			current:    usage.Capability[capability],
+			requested:  requested[capability],
+		})
+	}
+
+	softRatio := policy.SoftLimitRatio
+	if softRatio == 0 {
+		softRatio = DefaultSoftLimitRatio
+	}
+
+	var warnings []BudgetWarning
+	for _, c := range checks {
+		if c.limit <= 0 {
+			continue
+		}
+
+		projected := c.current + c.requested
+		if projected > c.limit+1e-9 {
+			return &BudgetViolation{
+				Kind:       c.kind,
+				Code:       c.kind.ErrorCode(),
+				PolicyID:   policy.ID,
+				Scope:      policy.Scope(),
+				Capability: c.capability,
+				Limit:      c.limit,
+				Current:    c.current,
+				Requested:  c.requested,
+			}, nil
+		}
+
+		// Only warn when this request is the one that crosses the soft limit
+		threshold := c.limit * softRatio
+		if projected >= threshold && c.current < threshold {
+			warnings = append(warnings, BudgetWarning{
+				Kind:       c.kind,
+				PolicyID:   policy.ID,
+				Scope:      policy.Scope(),
+				Capability: c.capability,
+				Limit:      c.limit,
+				Projected:  projected,
+				Ratio:      projected / c.limit,
+			})
+		}
+	}
+
+	return nil, warnings
+}
+
+// BudgetService stores budget policies and enforces them against a spend log
+type BudgetService struct {
+	db             *sql.DB
+	logger         *zap.Logger
+	reservationTTL time.Duration
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(db *sql.DB, logger *zap.Logger) *BudgetService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &BudgetService{
+		db:             db,
+		logger:         logger,
+		reservationTTL: DefaultReservationTTL,
+	}
+}
+
+// SetPolicy creates or replaces the policy for the user or API key it names
+func (s *BudgetService) SetPolicy(ctx context.Context, policy *BudgetPolicy) (*BudgetPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	if policy.APIKeyID != nil {
+		var owner string
+		err := s.db.QueryRowContext(ctx,
+			"SELECT user_id::text FROM api_keys WHERE id = $1",
+			*policy.APIKeyID,
+		).Scan(&owner)
+		if err == sql.ErrNoRows || (err == nil && owner != policy.UserID) {
+			return nil, ErrAPIKeyNotOwned
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get api key: %w", err)
+		}
+	}
+
+	capabilityLimits, err := json.Marshal(policy.CapabilityLimits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode capability limits: %w", err)
+	}
+
+	now := time.Now()
+	if policy.ID == uuid.Nil {
+		policy.ID = uuid.New()
+	}
+
+	// One policy per (user, api key); the expression index treats NULL keys as equal
+	query := `
+		INSERT INTO budget_policies (
+			id, user_id, api_key_id, daily_limit, monthly_limit, max_escrowed,
+			capability_limits, soft_limit_ratio, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (user_id, (COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid)))
+		DO UPDATE SET
+			daily_limit = EXCLUDED.daily_limit,
+			monthly_limit = EXCLUDED.monthly_limit,
+			max_escrowed = EXCLUDED.max_escrowed,
+			capability_limits = EXCLUDED.capability_limits,
+			soft_limit_ratio = EXCLUDED.soft_limit_ratio,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at, updated_at
+	`
+
+	err = s.db.QueryRowContext(ctx, query,
+		policy.ID, policy.UserID, policy.APIKeyID, policy.DailyLimit, policy.MonthlyLimit,
+		policy.MaxEscrowed, capabilityLimits, policy.SoftLimitRatio, now,
+	).Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save budget policy: %w", err)
+	}
+
+	s.logger.Info("budget policy saved",
+		zap.String("policy_id", policy.ID.String()),
+		zap.String("user_id", policy.UserID),
+		zap.String("scope", policy.Scope()),
+	)
+
+	return policy, nil
+}
+
+// GetPolicies returns all policies belonging to a user
+func (s *BudgetService) GetPolicies(ctx context.Context, userID string) ([]*BudgetPolicy, error) {
+	return s.queryPolicies(ctx, s.db, `
+		SELECT id, user_id, api_key_id, daily_limit, monthly_limit, max_escrowed,
+		       capability_limits, soft_limit_ratio, created_at, updated_at
+		FROM budget_policies
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+}
+
+// DeletePolicy removes one of a user's policies
+func (s *BudgetService) DeletePolicy(ctx context.Context, userID string, policyID uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM budget_policies WHERE id = $1 AND user_id = $2",
+		policyID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget policy: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check deleted rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrBudgetPolicyNotFound
+	}
+	return nil
+}
+
+// Usage returns the spending currently counted against a policy
+func (s *BudgetService) Usage(ctx context.Context, policy *BudgetPolicy) (*BudgetUsage, error) {
+	return s.usage(ctx, s.db, policy, time.Now().UTC())
+}
+
+// Authorize checks a spend request against every applicable policy and, if
+// it is within all hard limits, records a reservation for each task. Policies
+// are locked for the duration so concurrent requests cannot both slip under a
+// limit. A rejected request returns a *BudgetViolation.
+func (s *BudgetService) Authorize(ctx context.Context, req *SpendRequest) (*BudgetDecision, error) {
+	if req.UserID == "" || len(req.Items) == 0 {
+		return &BudgetDecision{}, nil
+	}
+	for _, item := range req.Items {
+		if item.Amount < 0 {
+			return nil, fmt.Errorf("task %s has a negative budget", item.TaskID)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	policies, err := s.queryPolicies(ctx, tx, `
+		SELECT id, user_id, api_key_id, daily_limit, monthly_limit, max_escrowed,
+		       capability_limits, soft_limit_ratio, created_at, updated_at
+		FROM budget_policies
+		WHERE user_id = $1 AND (api_key_id IS NULL OR api_key_id = $2)
+		ORDER BY api_key_id NULLS FIRST
+		FOR UPDATE
+	`, req.UserID, req.APIKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	decision := &BudgetDecision{}
+	for _, policy := range policies {
+		usage, err := s.usage(ctx, tx, policy, now)
+		if err != nil {
+			return nil, err
+		}
+
+		violation, warnings := EvaluateBudget(policy, usage, req)
+		if violation != nil {
+			s.logger.Warn("spend request rejected by budget policy",
+				zap.String("user_id", req.UserID),
+				zap.String("policy_id", policy.ID.String()),
+				zap.String("code", violation.Code),
+				zap.Float64("requested", violation.Requested),
+			)
+			return nil, violation
+		}
+		decision.Warnings = append(decision.Warnings, warnings...)
+	}
+
+	for _, item := range req.Items {
+		capabilities, err := json.Marshal(item.Capabilities)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode capabilities: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO budget_spend (
+				id, user_id, api_key_id, task_id, source, capabilities, amount, status, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, 'reserved', $8)
+		`, uuid.New(), req.UserID, req.APIKeyID, item.TaskID, req.Source, capabilities, item.Amount, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record spend: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return decision, nil
+}
+
+// SettleTask finalizes a task's reservation with what it actually cost. The
+// task stops counting towards the escrowed amount; its cost keeps counting
+// towards the daily, monthly and capability limits.
+func (s *BudgetService) SettleTask(ctx context.Context, taskID string, cost float64) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE budget_spend
+		SET status = 'settled',
+		    charged = LEAST(GREATEST($2, 0), amount),
+		    settled_at = NOW()
+		WHERE task_id = $1 AND status = 'reserved'
+	`, taskID, cost)
+	if err != nil {
+		return fmt.Errorf("failed to settle spend: %w", err)
+	}
+	return nil
+}
+
+// ReleaseTask cancels a task's reservation, e.g. when it could not be queued
+func (s *BudgetService) ReleaseTask(ctx context.Context, taskID string) error {
+	return s.SettleTask(ctx, taskID, 0)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryPolicies runs a policy SELECT and scans the results
+func (s *BudgetService) queryPolicies(ctx context.Context, q queryer, query string, args ...interface{}) ([]*BudgetPolicy, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []*BudgetPolicy
+	for rows.Next() {
+		var policy BudgetPolicy
+		var apiKeyID uuid.NullUUID
+		var capabilityLimits []byte
+		if err := rows.Scan(
+			&policy.ID, &policy.UserID, &apiKeyID, &policy.DailyLimit, &policy.MonthlyLimit,
+			&policy.MaxEscrowed, &capabilityLimits, &policy.SoftLimitRatio,
+			&policy.CreatedAt, &policy.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan budget policy: %w", err)
+		}
+		if apiKeyID.Valid {
+			policy.APIKeyID = &apiKeyID.UUID
+		}
+		if len(capabilityLimits) > 0 {
+			if err := json.Unmarshal(capabilityLimits, &policy.CapabilityLimits); err != nil {
+				return nil, fmt.Errorf("failed to decode capability limits: %w", err)
+			}
+		}
+		policies = append(policies, &policy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read budget policies: %w", err)
+	}
+	return policies, nil
+}
+
+// usage sums the spend log for a policy's scope. Reserved tasks count at their
+// full budget until settled; settled tasks count at their actual cost.
+func (s *BudgetService) usage(ctx context.Context, q queryer, policy *BudgetPolicy, now time.Time) (*BudgetUsage, error) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	reservedSince := now.Add(-s.reservationTTL)
+
+	scope := "user_id = $1 AND ($2::uuid IS NULL OR api_key_id = $2)"
+	spent := "CASE WHEN status = 'reserved' THEN amount ELSE COALESCE(charged, 0) END"
+
+	windowStart := monthStart
+	if reservedSince.Before(windowStart) {
+		windowStart = reservedSince
+	}
+
+	usage := &BudgetUsage{Capability: make(map[string]float64)}
+	err := q.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(`+spent+`) FILTER (WHERE created_at >= $3), 0),
+			COALESCE(SUM(`+spent+`) FILTER (WHERE created_at >= $4), 0),
+			COALESCE(SUM(amount) FILTER (WHERE status = 'reserved' AND created_at >= $5), 0)
+		FROM budget_spend
+		WHERE `+scope+` AND created_at >= $6
+	`, policy.UserID, policy.APIKeyID, dayStart, monthStart, reservedSince, windowStart,
+	).Scan(&usage.Daily, &usage.Monthly, &usage.Escrowed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spend: %w", err)
+	}
+
+	if len(policy.CapabilityLimits) == 0 {
+		return usage, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.capability, COALESCE(SUM(`+spent+`), 0)
+		FROM budget_spend
+		CROSS JOIN LATERAL jsonb_array_elements_text(capabilities) AS c(capability)
+		WHERE `+scope+` AND created_at >= $3
+		GROUP BY c.capability
+	`, policy.UserID, policy.APIKeyID, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capability spend: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var capability string
+		var amount float64
+		if err := rows.Scan(&capability, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan capability spend: %w", err)
+		}
+		usage.Capability[capability] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capability spend: %w", err)
+	}
+
+	return usage, nil
+}
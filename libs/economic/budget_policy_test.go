@@ -0,0 +1,79 @@
+package economic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spendRequest(amounts ...float64) *SpendRequest {
+	req := &SpendRequest{UserID: "user-1", Source: SpendSourceBatch}
+	for _, amount := range amounts {
+		req.Items = append(req.Items, SpendItem{
+			TaskID:       uuid.New().String(),
+			Capabilities: []string{"image-generation"},
+			Amount:       amount,
+		})
+	}
+	return req
+}
+
+func TestEvaluateBudgetHardLimits(t *testing.T) {
+	policy := &BudgetPolicy{
+		ID:               uuid.New(),
+		UserID:           "user-1",
+		DailyLimit:       10,
+		MonthlyLimit:     100,
+		MaxEscrowed:      8,
+		CapabilityLimits: map[string]float64{"image-generation": 50},
+	}
+
+	violation, _ := EvaluateBudget(policy, &BudgetUsage{Daily: 9}, spendRequest(2))
+	require.NotNil(t, violation)
+	assert.Equal(t, BudgetLimitDaily, violation.Kind)
+	assert.Equal(t, "BUDGET_DAILY_LIMIT_EXCEEDED", violation.Code)
+	assert.True(t, errors.Is(violation, ErrBudgetExceeded))
+
+	// A batch is authorized as a whole
+	violation, _ = EvaluateBudget(policy, &BudgetUsage{}, spendRequest(5, 5))
+	require.NotNil(t, violation)
+	assert.Equal(t, BudgetLimitEscrowed, violation.Kind)
+	assert.Equal(t, 10.0, violation.Requested)
+
+	violation, _ = EvaluateBudget(policy, &BudgetUsage{Capability: map[string]float64{"image-generation": 49}}, spendRequest(2))
+	require.NotNil(t, violation)
+	assert.Equal(t, BudgetLimitCapability, violation.Kind)
+	assert.Equal(t, "image-generation", violation.Capability)
+
+	violation, _ = EvaluateBudget(policy, &BudgetUsage{Daily: 6, Monthly: 6}, spendRequest(4))
+	assert.Nil(t, violation, "spending exactly up to the limit is allowed")
+}
+
+func TestEvaluateBudgetSoftLimits(t *testing.T) {
+	policy := &BudgetPolicy{ID: uuid.New(), UserID: "user-1", DailyLimit: 10, SoftLimitRatio: 0.5}
+
+	violation, warnings := EvaluateBudget(policy, &BudgetUsage{Daily: 4}, spendRequest(2))
+	require.Nil(t, violation)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, BudgetLimitDaily, warnings[0].Kind)
+	assert.InDelta(t, 0.6, warnings[0].Ratio, 1e-9)
+
+	// Already past the soft limit: no repeated warning
+	_, warnings = EvaluateBudget(policy, &BudgetUsage{Daily: 6}, spendRequest(1))
+	assert.Empty(t, warnings)
+
+	// Unlimited policies never warn
+	_, warnings = EvaluateBudget(&BudgetPolicy{UserID: "user-1"}, &BudgetUsage{Daily: 1e6}, spendRequest(1e6))
+	assert.Empty(t, warnings)
+}
+
+func TestBudgetPolicyValidate(t *testing.T) {
+	assert.NoError(t, (&BudgetPolicy{UserID: "user-1", DailyLimit: 5}).Validate())
+	assert.ErrorIs(t, (&BudgetPolicy{DailyLimit: 5}).Validate(), ErrInvalidBudgetPolicy)
+	assert.ErrorIs(t, (&BudgetPolicy{UserID: "user-1", MonthlyLimit: -1}).Validate(), ErrInvalidBudgetPolicy)
+	assert.ErrorIs(t, (&BudgetPolicy{UserID: "user-1", SoftLimitRatio: 1.5}).Validate(), ErrInvalidBudgetPolicy)
+	assert.ErrorIs(t, (&BudgetPolicy{UserID: "user-1", CapabilityLimits: map[string]float64{"": 1}}).Validate(), ErrInvalidBudgetPolicy)
+}
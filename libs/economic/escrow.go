@@ -3,9 +3,15 @@ package economic
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -22,6 +28,10 @@ const (
 	EscrowStatusCancelled EscrowStatus = "cancelled" // Cancelled before funding
 )
 
+// ErrEscrowStateChanged is returned when a concurrent transition moved an
+// escrow out of the status a transition started from
+var ErrEscrowStateChanged = errors.New("escrow status changed concurrently")
+
 // DisputeStatus represents the state of a dispute
 type DisputeStatus string
 
@@ -77,10 +87,17 @@ type DisputeEvidence struct {
 	CreatedAt   time.Time
 }
 
+// AccountResolver maps an escrow party (payer or payee ID) to the identity
+// its ledger account is keyed by. It runs inside the journaling transaction.
+type AccountResolver func(ctx context.Context, tx *sql.Tx, partyID string) (string, error)
+
 // EscrowService handles escrow transactions and dispute resolution
 type EscrowService struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db       *sql.DB
+	ledger   *ledger.Ledger // Journals funding, release and refund in the same transaction
+	feeRate  float64        // Share of each release the payee pays the platform
+	accounts AccountResolver
+	logger   *zap.Logger
 }
 
 // NewEscrowService creates a new escrow service
@@ -90,10 +107,53 @@ func NewEscrowService(db *sql.DB, logger *zap.Logger) *EscrowService {
 	}
 	return &EscrowService{
 		db:     db,
+		ledger: ledger.New(ledger.NewSQLStore(db), logger),
 		logger: logger,
 	}
 }
 
+// SetAccountResolver sets how escrow parties map to ledger accounts. By
+// default the party ID is used as is.
+func (s *EscrowService) SetAccountResolver(r AccountResolver) {
+	s.accounts = r
+}
+
+// UserDIDResolver resolves user and agent IDs to their DIDs, so escrows
+// journal to the same ledger account as deposits, withdrawals and earnings.
+// DIDs and IDs without a user or agent are returned unchanged.
+func UserDIDResolver(ctx context.Context, tx *sql.Tx, partyID string) (string, error) {
+	if _, err := uuid.Parse(partyID); err != nil {
+		return partyID, nil
+	}
+
+	var did string
+	err := tx.QueryRowContext(ctx, `
+		SELECT did FROM users WHERE id = $1
+		UNION ALL
+		SELECT did FROM agents WHERE id = $1
+		LIMIT 1
+	`, partyID).Scan(&did)
+	if err == sql.ErrNoRows {
+		return partyID, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve DID of %s: %w", partyID, err)
+	}
+	return did, nil
+}
+
+// userAccount returns the ledger account of an escrow party
+func (s *EscrowService) userAccount(ctx context.Context, tx *sql.Tx, partyID string) (string, error) {
+	if s.accounts == nil {
+		return ledger.UserAccount(partyID), nil
+	}
+	id, err := s.accounts(ctx, tx, partyID)
+	if err != nil {
+		return "", err
+	}
+	return ledger.UserAccount(id), nil
+}
+
 // CreateEscrow creates a new escrow transaction
 func (s *EscrowService) CreateEscrow(
 	ctx context.Context,
@@ -105,6 +165,11 @@ func (s *EscrowService) CreateEscrow(
 	autoReleaseMinutes *int,
 	conditions string,
 ) (*Escrow, error) {
+	// Reject malformed release conditions before any funds are committed
+	if _, err := ParseReleaseConditions(conditions); err != nil {
+		return nil, err
+	}
+
 	escrowID := uuid.New()
 	now := time.Now()
 	expiresAt := now.Add(time.Duration(expirationMinutes) * time.Minute)
@@ -160,10 +225,12 @@ func (s *EscrowService) FundEscrow(ctx context.Context, escrowID uuid.UUID, sign
 
 	// Verify current status is created
 	var currentStatus EscrowStatus
+	var taskID, payerID string
+	var amount float64
 	err := s.db.QueryRowContext(ctx,
-		"SELECT status FROM escrows WHERE id = $1",
+		"SELECT status, task_id, payer_id, amount FROM escrows WHERE id = $1",
 		escrowID,
-	).Scan(&currentStatus)
+	).Scan(&currentStatus, &taskID, &payerID, &amount)
 
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("escrow not found")
@@ -176,19 +243,29 @@ func (s *EscrowService) FundEscrow(ctx context.Context, escrowID uuid.UUID, sign
 		return fmt.Errorf("invalid state transition: escrow status is %s, expected created", currentStatus)
 	}
 
-	query := `
-		UPDATE escrows
-		SET status = $1,
-		    funded_at = $2,
-		    updated_at = $2
-		WHERE id = $3
-	`
-
-	_, err = s.db.ExecContext(ctx, query, EscrowStatusFunded, now, escrowID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionEscrow(ctx, tx, escrowID, EscrowStatusFunded, "funded_at", now, EscrowStatusCreated); err != nil {
 		return fmt.Errorf("failed to fund escrow: %w", err)
 	}
 
+	// Lock the payer's funds in the escrow account
+	payerAccount, err := s.userAccount(ctx, tx, payerID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "fund", payerAccount, ledger.EscrowAccount(escrowID.String()), amount); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	s.logger.Info("escrow funded",
 		zap.String("escrow_id", escrowID.String()),
 		zap.Time("funded_at", now),
@@ -203,11 +280,12 @@ func (s *EscrowService) ReleaseEscrow(ctx context.Context, escrowID uuid.UUID, r
 
 	// Verify current status is funded
 	var currentStatus EscrowStatus
-	var payerID string
+	var taskID, payerID, payeeID string
+	var amount float64
 	err := s.db.QueryRowContext(ctx,
-		"SELECT status, payer_id FROM escrows WHERE id = $1",
+		"SELECT status, task_id, payer_id, payee_id, amount FROM escrows WHERE id = $1",
 		escrowID,
-	).Scan(&currentStatus, &payerID)
+	).Scan(&currentStatus, &taskID, &payerID, &payeeID, &amount)
 
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("escrow not found")
@@ -225,19 +303,28 @@ func (s *EscrowService) ReleaseEscrow(ctx context.Context, escrowID uuid.UUID, r
 		return fmt.Errorf("unauthorized: only payer or system can release escrow")
 	}
 
-	query := `
-		UPDATE escrows
-		SET status = $1,
-		    released_at = $2,
-		    updated_at = $2
-		WHERE id = $3
-	`
-
-	_, err = s.db.ExecContext(ctx, query, EscrowStatusReleased, now, escrowID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionEscrow(ctx, tx, escrowID, EscrowStatusReleased, "released_at", now, EscrowStatusFunded); err != nil {
 		return fmt.Errorf("failed to release escrow: %w", err)
 	}
 
+	payeeAccount, err := s.userAccount(ctx, tx, payeeID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "release", ledger.EscrowAccount(escrowID.String()), payeeAccount, amount); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	s.logger.Info("escrow released",
 		zap.String("escrow_id", escrowID.String()),
 		zap.String("released_by", releasedBy),
@@ -253,11 +340,12 @@ func (s *EscrowService) RefundEscrow(ctx context.Context, escrowID uuid.UUID, re
 
 	// Verify current status is funded or disputed
 	var currentStatus EscrowStatus
-	var payeeID string
+	var taskID, payerID, payeeID string
+	var amount float64
 	err := s.db.QueryRowContext(ctx,
-		"SELECT status, payee_id FROM escrows WHERE id = $1",
+		"SELECT status, task_id, payer_id, payee_id, amount FROM escrows WHERE id = $1",
 		escrowID,
-	).Scan(&currentStatus, &payeeID)
+	).Scan(&currentStatus, &taskID, &payerID, &payeeID, &amount)
 
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("escrow not found")
@@ -275,19 +363,28 @@ func (s *EscrowService) RefundEscrow(ctx context.Context, escrowID uuid.UUID, re
 		return fmt.Errorf("unauthorized: only payee or system can refund escrow")
 	}
 
-	query := `
-		UPDATE escrows
-		SET status = $1,
-		    refunded_at = $2,
-		    updated_at = $2
-		WHERE id = $3
-	`
-
-	_, err = s.db.ExecContext(ctx, query, EscrowStatusRefunded, now, escrowID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionEscrow(ctx, tx, escrowID, EscrowStatusRefunded, "refunded_at", now, EscrowStatusFunded, EscrowStatusDisputed); err != nil {
 		return fmt.Errorf("failed to refund escrow: %w", err)
 	}
 
+	payerAccount, err := s.userAccount(ctx, tx, payerID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "refund", ledger.EscrowAccount(escrowID.String()), payerAccount, amount); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	s.logger.Info("escrow refunded",
 		zap.String("escrow_id", escrowID.String()),
 		zap.String("refunded_by", refundedBy),
@@ -297,6 +394,181 @@ func (s *EscrowService) RefundEscrow(ctx context.Context, escrowID uuid.UUID, re
 	return nil
 }
 
+// SettleEscrow splits a funded escrow: payeeAmount goes to the payee and the
+// remainder back to the payer. The escrow ends released when the payee gets
+// anything and refunded otherwise. Only the system may split an escrow.
+func (s *EscrowService) SettleEscrow(ctx context.Context, escrowID uuid.UUID, payeeAmount float64, settledBy string) error {
+	now := time.Now()
+
+	if settledBy != "system" {
+		return fmt.Errorf("unauthorized: only system can split an escrow")
+	}
+
+	var currentStatus EscrowStatus
+	var taskID, payerID, payeeID string
+	var amount float64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT status, task_id, payer_id, payee_id, amount FROM escrows WHERE id = $1",
+		escrowID,
+	).Scan(&currentStatus, &taskID, &payerID, &payeeID, &amount)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("escrow not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get escrow: %w", err)
+	}
+
+	if currentStatus != EscrowStatusFunded {
+		return fmt.Errorf("invalid state transition: escrow status is %s, expected funded", currentStatus)
+	}
+	if payeeAmount < 0 || payeeAmount > amount {
+		return fmt.Errorf("invalid settlement: payee amount %.4f outside escrow amount %.4f", payeeAmount, amount)
+	}
+
+	// Split in ledger units so the escrow account ends exactly at zero
+	payee := ledger.FromFloat(payeeAmount)
+	refund := ledger.FromFloat(amount) - payee
+
+	status, column := EscrowStatusRefunded, "refunded_at"
+	if payee > 0 {
+		status, column = EscrowStatusReleased, "released_at"
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionEscrow(ctx, tx, escrowID, status, column, now, EscrowStatusFunded); err != nil {
+		return fmt.Errorf("failed to settle escrow: %w", err)
+	}
+
+	payeeAccount, err := s.userAccount(ctx, tx, payeeID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "release", ledger.EscrowAccount(escrowID.String()), payeeAccount, payee.Float64()); err != nil {
+		return err
+	}
+	payerAccount, err := s.userAccount(ctx, tx, payerID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "refund", ledger.EscrowAccount(escrowID.String()), payerAccount, refund.Float64()); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("escrow settled",
+		zap.String("escrow_id", escrowID.String()),
+		zap.Float64("payee_amount", payee.Float64()),
+		zap.Float64("refund_amount", refund.Float64()),
+		zap.Time("settled_at", now),
+	)
+
+	return nil
+}
+
+// SplitEscrow pays a funded escrow out to several payees, such as the agents
+// that agreed on a replicated task's result, and refunds the remainder to the
+// payer. shares maps each payee ID or DID to its amount. The escrow ends
+// released when any payee gets anything and refunded otherwise. Only the
+// system may split an escrow.
+func (s *EscrowService) SplitEscrow(ctx context.Context, escrowID uuid.UUID, shares map[string]float64, settledBy string) error {
+	now := time.Now()
+
+	if settledBy != "system" {
+		return fmt.Errorf("unauthorized: only system can split an escrow")
+	}
+
+	var currentStatus EscrowStatus
+	var taskID, payerID string
+	var amount float64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT status, task_id, payer_id, amount FROM escrows WHERE id = $1",
+		escrowID,
+	).Scan(&currentStatus, &taskID, &payerID, &amount)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("escrow not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get escrow: %w", err)
+	}
+
+	if currentStatus != EscrowStatusFunded {
+		return fmt.Errorf("invalid state transition: escrow status is %s, expected funded", currentStatus)
+	}
+
+	// Split in ledger units so the escrow account ends exactly at zero
+	payees := make([]string, 0, len(shares))
+	var paid ledger.Amount
+	for payee, share := range shares {
+		if share < 0 {
+			return fmt.Errorf("invalid settlement: negative share %.4f for %s", share, payee)
+		}
+		payees = append(payees, payee)
+		paid += ledger.FromFloat(share)
+	}
+	sort.Strings(payees)
+	refund := ledger.FromFloat(amount) - paid
+	if refund < 0 {
+		return fmt.Errorf("invalid settlement: shares %.4f exceed escrow amount %.4f", paid.Float64(), amount)
+	}
+
+	status, column := EscrowStatusRefunded, "refunded_at"
+	if paid > 0 {
+		status, column = EscrowStatusReleased, "released_at"
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionEscrow(ctx, tx, escrowID, status, column, now, EscrowStatusFunded); err != nil {
+		return fmt.Errorf("failed to split escrow: %w", err)
+	}
+
+	for _, payee := range payees {
+		payeeAccount, err := s.userAccount(ctx, tx, payee)
+		if err != nil {
+			return err
+		}
+		share := ledger.FromFloat(shares[payee]).Float64()
+		if err := s.journalSplit(ctx, tx, escrowID, taskID, "release", payee, ledger.EscrowAccount(escrowID.String()), payeeAccount, share); err != nil {
+			return err
+		}
+	}
+	payerAccount, err := s.userAccount(ctx, tx, payerID)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, "refund", ledger.EscrowAccount(escrowID.String()), payerAccount, refund.Float64()); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Info("escrow split",
+		zap.String("escrow_id", escrowID.String()),
+		zap.Int("payees", len(payees)),
+		zap.Float64("paid_amount", paid.Float64()),
+		zap.Float64("refund_amount", refund.Float64()),
+		zap.Time("settled_at", now),
+	)
+
+	return nil
+}
+
 // OpenDispute opens a dispute on an escrow (transition: funded → disputed)
 func (s *EscrowService) OpenDispute(
 	ctx context.Context,
@@ -360,15 +632,18 @@ func (s *EscrowService) OpenDispute(
 		SET status = $1,
 		    dispute_id = $2,
 		    updated_at = $3
-		WHERE id = $4
+		WHERE id = $4 AND status = $5
 	`
 
-	_, err = tx.ExecContext(ctx, escrowQuery,
-		EscrowStatusDisputed, disputeID, now, escrowID,
+	result, err := tx.ExecContext(ctx, escrowQuery,
+		EscrowStatusDisputed, disputeID, now, escrowID, EscrowStatusFunded,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update escrow: %w", err)
 	}
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return nil, ErrEscrowStateChanged
+	}
 
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
@@ -531,36 +806,44 @@ func (s *EscrowService) ResolveDispute(
 	}
 
 	// Apply outcome to escrow
-	var escrowQuery string
 	var newStatus EscrowStatus
+	var column string
 
 	if outcome == "release" {
-		newStatus = EscrowStatusReleased
-		escrowQuery = `
-			UPDATE escrows
-			SET status = $1,
-			    released_at = $2,
-			    updated_at = $2
-			WHERE id = $3
-		`
+		newStatus, column = EscrowStatusReleased, "released_at"
 	} else if outcome == "refund" {
-		newStatus = EscrowStatusRefunded
-		escrowQuery = `
-			UPDATE escrows
-			SET status = $1,
-			    refunded_at = $2,
-			    updated_at = $2
-			WHERE id = $3
-		`
+		newStatus, column = EscrowStatusRefunded, "refunded_at"
 	} else {
 		return fmt.Errorf("invalid outcome: must be 'release' or 'refund'")
 	}
 
-	_, err = tx.ExecContext(ctx, escrowQuery, newStatus, now, escrowID)
-	if err != nil {
+	if err := transitionEscrow(ctx, tx, escrowID, newStatus, column, now, EscrowStatusDisputed); err != nil {
 		return fmt.Errorf("failed to update escrow: %w", err)
 	}
 
+	// Journal the outcome in the same transaction
+	var taskID, payerID, payeeID string
+	var amount float64
+	err = tx.QueryRowContext(ctx,
+		"SELECT task_id, payer_id, payee_id, amount FROM escrows WHERE id = $1",
+		escrowID,
+	).Scan(&taskID, &payerID, &payeeID, &amount)
+	if err != nil {
+		return fmt.Errorf("failed to get escrow: %w", err)
+	}
+
+	recipient := payerID
+	if newStatus == EscrowStatusReleased {
+		recipient = payeeID
+	}
+	recipientAccount, err := s.userAccount(ctx, tx, recipient)
+	if err != nil {
+		return err
+	}
+	if err := s.journal(ctx, tx, escrowID, taskID, outcome, ledger.EscrowAccount(escrowID.String()), recipientAccount, amount); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -692,13 +975,15 @@ func (s *EscrowService) GetDisputeEvidence(ctx context.Context, disputeID uuid.U
 	return evidence, nil
 }
 
-// ProcessAutoReleases processes escrows that should be auto-released
+// ProcessAutoReleases processes escrows that should be auto-released.
+// Escrows with release conditions are only auto-released once their
+// conditions are met.
 func (s *EscrowService) ProcessAutoReleases(ctx context.Context) (int, error) {
 	now := time.Now()
 
 	// Find escrows that are funded and past auto-release time
 	query := `
-		SELECT id
+		SELECT id, conditions
 		FROM escrows
 		WHERE status = $1
 		  AND auto_release_at IS NOT NULL
@@ -709,19 +994,41 @@ func (s *EscrowService) ProcessAutoReleases(ctx context.Context) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to query auto-releases: %w", err)
 	}
-	defer rows.Close()
 
-	count := 0
+	type dueEscrow struct {
+		id         uuid.UUID
+		conditions string
+	}
+	var due []dueEscrow
 	for rows.Next() {
-		var escrowID uuid.UUID
-		if err := rows.Scan(&escrowID); err != nil {
+		var e dueEscrow
+		var conditions sql.NullString
+		if err := rows.Scan(&e.id, &conditions); err != nil {
 			s.logger.Error("failed to scan escrow ID", zap.Error(err))
 			continue
 		}
+		e.conditions = conditions.String
+		due = append(due, e)
+	}
+	rows.Close()
 
-		if err := s.ReleaseEscrow(ctx, escrowID, "system"); err != nil {
+	count := 0
+	for _, e := range due {
+		met, err := s.conditionsMet(ctx, e.id, e.conditions)
+		if err != nil {
+			s.logger.Error("failed to check escrow conditions before auto-release",
+				zap.String("escrow_id", e.id.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if !met {
+			continue
+		}
+
+		if err := s.ReleaseEscrow(ctx, e.id, "system"); err != nil {
 			s.logger.Error("failed to auto-release escrow",
-				zap.String("escrow_id", escrowID.String()),
+				zap.String("escrow_id", e.id.String()),
 				zap.Error(err),
 			)
 			continue
@@ -734,9 +1041,530 @@ func (s *EscrowService) ProcessAutoReleases(ctx context.Context) (int, error) {
 		s.logger.Info("processed auto-releases", zap.Int("count", count))
 	}
 
+	// Re-evaluate conditioned escrows so time-based clauses (deadlines) take effect
+	applied, err := s.ProcessConditionDeadlines(ctx)
+	if err != nil {
+		s.logger.Error("failed to process condition deadlines", zap.Error(err))
+	}
+
+	return count + applied, nil
+}
+
+// conditionsMet reports whether an escrow's release conditions currently
+// call for release. Escrows without conditions always do.
+func (s *EscrowService) conditionsMet(ctx context.Context, escrowID uuid.UUID, conditions string) (bool, error) {
+	rc, err := ParseReleaseConditions(conditions)
+	if err != nil || rc == nil {
+		return err == nil, err
+	}
+
+	facts, err := s.loadFacts(ctx, escrowID)
+	if err != nil {
+		return false, err
+	}
+	return EvaluateReleaseConditions(escrowID, rc, facts, time.Now()).Outcome == ConditionOutcomeRelease, nil
+}
+
+// ProcessConditionDeadlines re-evaluates every funded escrow whose release
+// conditions include a deadline, refunding those whose deadline has passed
+func (s *EscrowService) ProcessConditionDeadlines(ctx context.Context) (int, error) {
+	query := `
+		SELECT id, conditions
+		FROM escrows
+		WHERE status = $1
+		  AND conditions IS NOT NULL
+		  AND conditions <> ''
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, EscrowStatusFunded)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query conditioned escrows: %w", err)
+	}
+
+	var escrowIDs []uuid.UUID
+	for rows.Next() {
+		var escrowID uuid.UUID
+		var conditions string
+		if err := rows.Scan(&escrowID, &conditions); err != nil {
+			s.logger.Error("failed to scan escrow ID", zap.Error(err))
+			continue
+		}
+		if rc, err := ParseReleaseConditions(conditions); err == nil && rc.hasDeadline() {
+			escrowIDs = append(escrowIDs, escrowID)
+		}
+	}
+	rows.Close()
+
+	count := 0
+	for _, escrowID := range escrowIDs {
+		eval, err := s.EvaluateConditions(ctx, escrowID, "scheduled")
+		if err != nil {
+			s.logger.Error("failed to evaluate escrow conditions",
+				zap.String("escrow_id", escrowID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if eval != nil && eval.Applied {
+			count++
+		}
+	}
+
 	return count, nil
 }
 
+// Run processes auto-releases and condition deadlines every interval until
+// ctx is done
+func (s *EscrowService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ProcessAutoReleases(ctx); err != nil {
+				s.logger.Warn("escrow auto-release failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RecordTaskResult records a task result against an escrow and re-evaluates its conditions
+func (s *EscrowService) RecordTaskResult(ctx context.Context, escrowID uuid.UUID, result json.RawMessage) (*ConditionEvaluation, error) {
+	hash, err := HashTaskResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.recordFact(ctx, escrowID, FactTypeResult, hash, string(result)); err != nil {
+		return nil, err
+	}
+
+	return s.EvaluateConditions(ctx, escrowID, "task_result")
+}
+
+// RecordApproval records a validator approval or rejection and re-evaluates conditions
+func (s *EscrowService) RecordApproval(ctx context.Context, escrowID uuid.UUID, approverID string, approved bool) (*ConditionEvaluation, error) {
+	value := "rejected"
+	if approved {
+		value = "approved"
+	}
+
+	if err := s.recordFact(ctx, escrowID, FactTypeApproval, approverID, value); err != nil {
+		return nil, err
+	}
+
+	return s.EvaluateConditions(ctx, escrowID, "approval")
+}
+
+// RecordSignature records a participant signature over ReleaseSigningMessage and re-evaluates conditions
+func (s *EscrowService) RecordSignature(ctx context.Context, escrowID uuid.UUID, signerID string, signature string) (*ConditionEvaluation, error) {
+	escrow, err := s.GetEscrow(ctx, escrowID)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := ParseReleaseConditions(escrow.Conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only accept signatures from declared participants
+	known := false
+	if rc != nil {
+		for i := range rc.Conditions {
+			c := &rc.Conditions[i]
+			if c.Type != ConditionSignatures {
+				continue
+			}
+			if signer, ok := c.signer(signerID); ok {
+				if !verifyReleaseSignature(escrowID, signer.PublicKey, signature) {
+					return nil, fmt.Errorf("invalid signature from %s", signerID)
+				}
+				known = true
+			}
+		}
+	}
+	if !known {
+		return nil, ErrUnknownSigner
+	}
+
+	if err := s.recordFact(ctx, escrowID, FactTypeSignature, signerID, signature); err != nil {
+		return nil, err
+	}
+
+	return s.EvaluateConditions(ctx, escrowID, "signature")
+}
+
+// RecordMilestone records that a named milestone was reached and re-evaluates conditions
+func (s *EscrowService) RecordMilestone(ctx context.Context, escrowID uuid.UUID, milestone string) (*ConditionEvaluation, error) {
+	if err := s.recordFact(ctx, escrowID, FactTypeMilestone, milestone, "reached"); err != nil {
+		return nil, err
+	}
+
+	return s.EvaluateConditions(ctx, escrowID, "milestone")
+}
+
+// EvaluateConditions evaluates an escrow's release conditions, stores the evaluation
+// in the audit log, and releases or refunds the escrow when the outcome is final.
+// Escrows without conditions, or that are not funded, are not evaluated and yield nil.
+func (s *EscrowService) EvaluateConditions(ctx context.Context, escrowID uuid.UUID, trigger string) (*ConditionEvaluation, error) {
+	escrow, err := s.GetEscrow(ctx, escrowID)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := ParseReleaseConditions(escrow.Conditions)
+	if err != nil {
+		return nil, err
+	}
+	if rc == nil || escrow.Status != EscrowStatusFunded {
+		return nil, nil
+	}
+
+	facts, err := s.loadFacts(ctx, escrowID)
+	if err != nil {
+		return nil, err
+	}
+
+	eval := EvaluateReleaseConditions(escrowID, rc, facts, time.Now())
+	eval.Trigger = trigger
+
+	switch eval.Outcome {
+	case ConditionOutcomeRelease:
+		if err := s.ReleaseEscrow(ctx, escrowID, "system"); err != nil {
+			s.logger.Error("condition release failed",
+				zap.String("escrow_id", escrowID.String()),
+				zap.Error(err),
+			)
+		} else {
+			eval.Applied = true
+		}
+	case ConditionOutcomeRefund:
+		if err := s.RefundEscrow(ctx, escrowID, "system"); err != nil {
+			s.logger.Error("condition refund failed",
+				zap.String("escrow_id", escrowID.String()),
+				zap.Error(err),
+			)
+		} else {
+			eval.Applied = true
+		}
+	}
+
+	// Scheduled evaluations only make the audit log when something changed
+	if trigger == "scheduled" && !eval.Applied {
+		last, err := s.lastEvaluation(ctx, escrowID)
+		if err != nil {
+			return nil, err
+		}
+		if (last == nil && eval.Outcome == ConditionOutcomePending) || (last != nil && sameConditionState(last, eval)) {
+			return eval, nil
+		}
+	}
+
+	if err := s.saveEvaluation(ctx, eval); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("escrow conditions evaluated",
+		zap.String("escrow_id", escrowID.String()),
+		zap.String("trigger", trigger),
+		zap.String("outcome", string(eval.Outcome)),
+		zap.Bool("applied", eval.Applied),
+	)
+
+	return eval, nil
+}
+
+// GetConditionEvaluations returns the evaluation audit log for an escrow, oldest first
+func (s *EscrowService) GetConditionEvaluations(ctx context.Context, escrowID uuid.UUID) ([]ConditionEvaluation, error) {
+	query := `
+		SELECT id, escrow_id, trigger, outcome, results, applied, evaluated_at
+		FROM escrow_condition_evaluations
+		WHERE escrow_id = $1
+		ORDER BY evaluated_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, escrowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	evaluations := make([]ConditionEvaluation, 0)
+	for rows.Next() {
+		var e ConditionEvaluation
+		var results []byte
+		if err := rows.Scan(&e.ID, &e.EscrowID, &e.Trigger, &e.Outcome, &results, &e.Applied, &e.EvaluatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan evaluation: %w", err)
+		}
+		if err := json.Unmarshal(results, &e.Results); err != nil {
+			return nil, fmt.Errorf("failed to decode evaluation results: %w", err)
+		}
+		evaluations = append(evaluations, e)
+	}
+
+	return evaluations, nil
+}
+
+// lastEvaluation returns the most recent stored evaluation of an escrow, or
+// nil when it has none
+func (s *EscrowService) lastEvaluation(ctx context.Context, escrowID uuid.UUID) (*ConditionEvaluation, error) {
+	query := `
+		SELECT id, escrow_id, trigger, outcome, results, applied, evaluated_at
+		FROM escrow_condition_evaluations
+		WHERE escrow_id = $1
+		ORDER BY evaluated_at DESC
+		LIMIT 1
+	`
+
+	var e ConditionEvaluation
+	var results []byte
+	err := s.db.QueryRowContext(ctx, query, escrowID).Scan(&e.ID, &e.EscrowID, &e.Trigger, &e.Outcome, &results, &e.Applied, &e.EvaluatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last evaluation: %w", err)
+	}
+	if err := json.Unmarshal(results, &e.Results); err != nil {
+		return nil, fmt.Errorf("failed to decode evaluation results: %w", err)
+	}
+
+	return &e, nil
+}
+
+// recordFact appends a fact to the escrow's condition fact log
+func (s *EscrowService) recordFact(ctx context.Context, escrowID uuid.UUID, factType, subject, value string) error {
+	query := `
+		INSERT INTO escrow_condition_facts (
+			id, escrow_id, fact_type, subject, value, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, uuid.New(), escrowID, factType, subject, value, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record %s fact: %w", factType, err)
+	}
+
+	return nil
+}
+
+// loadFacts folds the fact log into the latest known state for evaluation
+func (s *EscrowService) loadFacts(ctx context.Context, escrowID uuid.UUID) (*ConditionFacts, error) {
+	query := `
+		SELECT fact_type, subject, value, created_at
+		FROM escrow_condition_facts
+		WHERE escrow_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, escrowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query condition facts: %w", err)
+	}
+	defer rows.Close()
+
+	facts := &ConditionFacts{
+		Approvals:  make(map[string]bool),
+		Signatures: make(map[string]string),
+		Milestones: make(map[string]bool),
+	}
+
+	for rows.Next() {
+		var factType, subject, value string
+		var createdAt time.Time
+		if err := rows.Scan(&factType, &subject, &value, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan condition fact: %w", err)
+		}
+
+		switch factType {
+		case FactTypeResult:
+			at := createdAt
+			facts.ResultHash = subject
+			facts.Result = json.RawMessage(value)
+			facts.ResultAt = &at
+		case FactTypeApproval:
+			facts.Approvals[subject] = value == "approved"
+		case FactTypeSignature:
+			facts.Signatures[subject] = value
+		case FactTypeMilestone:
+			facts.Milestones[subject] = true
+		}
+	}
+
+	return facts, nil
+}
+
+// saveEvaluation persists a condition evaluation to the audit log
+func (s *EscrowService) saveEvaluation(ctx context.Context, eval *ConditionEvaluation) error {
+	results, err := json.Marshal(eval.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode evaluation results: %w", err)
+	}
+
+	query := `
+		INSERT INTO escrow_condition_evaluations (
+			id, escrow_id, trigger, outcome, results, applied, evaluated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = s.db.ExecContext(ctx, query,
+		eval.ID, eval.EscrowID, eval.Trigger, eval.Outcome, results, eval.Applied, eval.EvaluatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save evaluation: %w", err)
+	}
+
+	return nil
+}
+
+// ListEscrowRecords lists all escrows for ledger reconciliation
+func (s *EscrowService) ListEscrowRecords(ctx context.Context) ([]ledger.EscrowRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, task_id, amount, status FROM escrows ORDER BY created_at",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escrows: %w", err)
+	}
+	defer rows.Close()
+
+	var records []ledger.EscrowRecord
+	for rows.Next() {
+		var rec ledger.EscrowRecord
+		if err := rows.Scan(&rec.ID, &rec.TaskID, &rec.Amount, &rec.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan escrow: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read escrows: %w", err)
+	}
+
+	return records, nil
+}
+
+// Ledger returns the ledger escrow movements are journaled to
+func (s *EscrowService) Ledger() *ledger.Ledger {
+	return s.ledger
+}
+
+// SetPlatformFee charges payees rate (0-1) of every escrow release as a
+// platform fee. Zero, the default, charges nothing.
+func (s *EscrowService) SetPlatformFee(rate float64) {
+	s.feeRate = math.Max(0, math.Min(rate, 1))
+}
+
+// transitionEscrow moves an escrow to status inside tx, stamping column, only
+// if it is still in one of the from statuses. The status check made before
+// the transaction can be stale: of two concurrent transitions out of the same
+// status only one updates the row, and the other fails here before it can
+// journal anything.
+func transitionEscrow(ctx context.Context, tx *sql.Tx, escrowID uuid.UUID, status EscrowStatus, column string, now time.Time, from ...EscrowStatus) error {
+	args := []interface{}{status, now, escrowID}
+	placeholders := make([]string, len(from))
+	for i, f := range from {
+		args = append(args, f)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE escrows
+		SET status = $1,
+		    %s = $2,
+		    updated_at = $2
+		WHERE id = $3 AND status IN (%s)
+	`, column, strings.Join(placeholders, ", "))
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n != 1 {
+		return ErrEscrowStateChanged
+	}
+	return nil
+}
+
+// journal posts the ledger entry for an escrow transition inside tx.
+// The idempotency key is derived from the escrow and action, so a transition
+// can never be journaled twice.
+func (s *EscrowService) journal(ctx context.Context, tx *sql.Tx, escrowID uuid.UUID, taskID, action, from, to string, amount float64) error {
+	return s.journalSplit(ctx, tx, escrowID, taskID, action, "", from, to, amount)
+}
+
+// journalSplit is journal for one payee of an escrow split between several;
+// split names the payee in the idempotency key
+func (s *EscrowService) journalSplit(ctx context.Context, tx *sql.Tx, escrowID uuid.UUID, taskID, action, split, from, to string, amount float64) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	var description string
+	switch action {
+	case "fund":
+		description = ledger.DescEscrowFund
+	case "release":
+		description = ledger.DescEscrowRelease
+	case "refund":
+		description = ledger.DescEscrowRefund
+	default:
+		return fmt.Errorf("unknown escrow action %q", action)
+	}
+
+	key := fmt.Sprintf("escrow:%s:%s", escrowID, action)
+	feeKey := fmt.Sprintf("escrow:%s:fee", escrowID)
+	if split != "" {
+		// One release per payee of a split escrow
+		key = fmt.Sprintf("escrow:%s:%s:%s", escrowID, action, split)
+		feeKey = fmt.Sprintf("escrow:%s:fee:%s", escrowID, split)
+	}
+
+	entry := ledger.NewTransfer(
+		key,
+		from, to,
+		ledger.FromFloat(amount),
+		description,
+		escrowID.String(),
+	)
+	entry.Metadata = map[string]string{ledger.MetadataTaskID: taskID}
+	if _, err := s.ledger.PostTx(ctx, tx, entry); err != nil {
+		return fmt.Errorf("failed to journal escrow %s: %w", action, err)
+	}
+
+	if action == "release" {
+		return s.journalFee(ctx, tx, feeKey, escrowID, taskID, to, amount)
+	}
+	return nil
+}
+
+// journalFee charges the payee's account the platform fee on a release
+func (s *EscrowService) journalFee(ctx context.Context, tx *sql.Tx, key string, escrowID uuid.UUID, taskID, payee string, released float64) error {
+	fee := ledger.FromFloat(released * s.feeRate)
+	if fee <= 0 {
+		return nil
+	}
+
+	entry := ledger.NewTransfer(
+		key,
+		payee, ledger.AccountFees,
+		fee,
+		ledger.DescFee,
+		escrowID.String(),
+	)
+	entry.Metadata = map[string]string{ledger.MetadataTaskID: taskID}
+	if _, err := s.ledger.PostTx(ctx, tx, entry); err != nil {
+		return fmt.Errorf("failed to journal escrow fee: %w", err)
+	}
+	return nil
+}
+
 // CompleteEscrow marks an escrow as completed (used when payment channel was used instead)
 func (s *EscrowService) CompleteEscrow(ctx context.Context, escrowID uuid.UUID) error {
 	query := `
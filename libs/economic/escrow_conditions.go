@@ -0,0 +1,440 @@
+package economic
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/validation"
+	"github.com/google/uuid"
+)
+
+// ConditionType identifies a release condition in the escrow condition language
+type ConditionType string
+
+const (
+	ConditionResultHash        ConditionType = "result_hash"        // Task result hash must equal expected hash
+	ConditionValidatorApproval ConditionType = "validator_approval" // Validator agent(s) must approve the result
+	ConditionSignatures        ConditionType = "signatures"         // N-of-M participant signatures
+	ConditionJSONSchema        ConditionType = "json_schema"        // Task result must pass a JSON schema
+	ConditionDeadline          ConditionType = "deadline"           // Result must be delivered before a deadline
+	ConditionMilestones        ConditionType = "milestones"         // Named milestones must be reached
+)
+
+// ConditionMode controls how individual condition results are combined
+type ConditionMode string
+
+const (
+	ConditionModeAll ConditionMode = "all" // Every condition must be satisfied (default)
+	ConditionModeAny ConditionMode = "any" // At least one condition must be satisfied
+)
+
+// ConditionStatus is the evaluated state of a single condition
+type ConditionStatus string
+
+const (
+	ConditionStatusSatisfied   ConditionStatus = "satisfied"   // Condition holds
+	ConditionStatusUnsatisfied ConditionStatus = "unsatisfied" // Condition may still become satisfied
+	ConditionStatusViolated    ConditionStatus = "violated"    // Condition can never be satisfied
+)
+
+// ConditionOutcome is the action an evaluation recommends for an escrow
+type ConditionOutcome string
+
+const (
+	ConditionOutcomeRelease ConditionOutcome = "release" // Release funds to payee
+	ConditionOutcomeRefund  ConditionOutcome = "refund"  // Refund funds to payer
+	ConditionOutcomePending ConditionOutcome = "pending" // Wait for more facts
+)
+
+// Fact types recorded against an escrow and fed into condition evaluation
+const (
+	FactTypeResult    = "result"
+	FactTypeApproval  = "approval"
+	FactTypeSignature = "signature"
+	FactTypeMilestone = "milestone"
+)
+
+var (
+	// ErrInvalidConditions indicates the escrow conditions document is malformed
+	ErrInvalidConditions = errors.New("invalid escrow conditions")
+
+	// ErrUnknownSigner indicates a signature was submitted by a non-participant
+	ErrUnknownSigner = errors.New("signer is not a participant in escrow conditions")
+)
+
+// ConditionSigner is a participant allowed to sign an escrow release.
+// PublicKey is the signer's hex-encoded ed25519 key; signatures must verify
+// against ReleaseSigningMessage for the escrow.
+type ConditionSigner struct {
+	ID        string `json:"id"`
+	PublicKey string `json:"public_key"`
+}
+
+// ReleaseCondition is a single clause of the escrow condition language
+type ReleaseCondition struct {
+	Type ConditionType `json:"type"`
+
+	// result_hash
+	ExpectedHash string `json:"expected_hash,omitempty"`
+
+	// validator_approval
+	Validators   []string `json:"validators,omitempty"`
+	MinApprovals int      `json:"min_approvals,omitempty"`
+
+	// signatures
+	Signers   []ConditionSigner `json:"signers,omitempty"`
+	Threshold int               `json:"threshold,omitempty"`
+
+	// json_schema
+	Schema map[string]interface{} `json:"schema,omitempty"`
+
+	// deadline
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	// milestones
+	Milestones []string `json:"milestones,omitempty"`
+}
+
+// ReleaseConditions is the parsed form of Escrow.Conditions
+type ReleaseConditions struct {
+	Mode       ConditionMode      `json:"mode,omitempty"`
+	Conditions []ReleaseCondition `json:"conditions"`
+}
+
+// ConditionFacts holds everything known about an escrow at evaluation time
+type ConditionFacts struct {
+	ResultHash string
+	Result     json.RawMessage
+	ResultAt   *time.Time
+	Approvals  map[string]bool   // approver ID -> approved
+	Signatures map[string]string // signer ID -> hex signature
+	Milestones map[string]bool   // milestone name -> reached
+}
+
+// ConditionResult is the evaluated state of one condition
+type ConditionResult struct {
+	Type   ConditionType   `json:"type"`
+	Status ConditionStatus `json:"status"`
+	Detail string          `json:"detail"`
+}
+
+// ConditionEvaluation is an auditable record of a condition evaluation
+type ConditionEvaluation struct {
+	ID          uuid.UUID         `json:"id"`
+	EscrowID    uuid.UUID         `json:"escrow_id"`
+	Trigger     string            `json:"trigger"`
+	Outcome     ConditionOutcome  `json:"outcome"`
+	Results     []ConditionResult `json:"results"`
+	Applied     bool              `json:"applied"`
+	EvaluatedAt time.Time         `json:"evaluated_at"`
+}
+
+// ParseReleaseConditions parses an escrow conditions document.
+// An empty document yields nil, meaning the escrow has no release conditions.
+func ParseReleaseConditions(raw string) (*ReleaseConditions, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var rc ReleaseConditions
+	if err := json.Unmarshal([]byte(raw), &rc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConditions, err)
+	}
+
+	if len(rc.Conditions) == 0 {
+		return nil, nil
+	}
+
+	if rc.Mode == "" {
+		rc.Mode = ConditionModeAll
+	}
+	if rc.Mode != ConditionModeAll && rc.Mode != ConditionModeAny {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidConditions, rc.Mode)
+	}
+
+	for i, c := range rc.Conditions {
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("%w: condition %d: %v", ErrInvalidConditions, i, err)
+		}
+	}
+
+	return &rc, nil
+}
+
+// validate checks that a condition carries the fields its type requires
+func (c *ReleaseCondition) validate() error {
+	switch c.Type {
+	case ConditionResultHash:
+		if c.ExpectedHash == "" {
+			return errors.New("result_hash requires expected_hash")
+		}
+	case ConditionValidatorApproval:
+		if len(c.Validators) == 0 {
+			return errors.New("validator_approval requires validators")
+		}
+		if c.MinApprovals > len(c.Validators) {
+			return errors.New("min_approvals exceeds number of validators")
+		}
+	case ConditionSignatures:
+		if len(c.Signers) == 0 {
+			return errors.New("signatures requires signers")
+		}
+		if c.Threshold <= 0 || c.Threshold > len(c.Signers) {
+			return fmt.Errorf("threshold must be between 1 and %d", len(c.Signers))
+		}
+		for _, s := range c.Signers {
+			if s.ID == "" {
+				return errors.New("signer id is required")
+			}
+			key, err := hex.DecodeString(s.PublicKey)
+			if err != nil || len(key) != ed25519.PublicKeySize {
+				return fmt.Errorf("signer %s requires a valid ed25519 public key", s.ID)
+			}
+		}
+	case ConditionJSONSchema:
+		if len(c.Schema) == 0 {
+			return errors.New("json_schema requires schema")
+		}
+	case ConditionDeadline:
+		if c.Deadline == nil {
+			return errors.New("deadline requires deadline timestamp")
+		}
+	case ConditionMilestones:
+		if len(c.Milestones) == 0 {
+			return errors.New("milestones requires at least one milestone")
+		}
+	default:
+		return fmt.Errorf("unknown condition type %q", c.Type)
+	}
+	return nil
+}
+
+// signer returns the signer with the given ID, if present
+func (c *ReleaseCondition) signer(id string) (ConditionSigner, bool) {
+	for _, s := range c.Signers {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return ConditionSigner{}, false
+}
+
+// hasDeadline reports whether any condition is a deadline, the only clause
+// whose state changes with time alone
+func (rc *ReleaseConditions) hasDeadline() bool {
+	if rc == nil {
+		return false
+	}
+	for _, c := range rc.Conditions {
+		if c.Type == ConditionDeadline {
+			return true
+		}
+	}
+	return false
+}
+
+// sameConditionState reports whether two evaluations reached the same outcome
+// with the same per-condition results
+func sameConditionState(a, b *ConditionEvaluation) bool {
+	if a.Outcome != b.Outcome || len(a.Results) != len(b.Results) {
+		return false
+	}
+	for i := range a.Results {
+		if a.Results[i] != b.Results[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ReleaseSigningMessage returns the message participants sign to approve release
+func ReleaseSigningMessage(escrowID uuid.UUID) []byte {
+	return []byte("zerostate-escrow-release:" + escrowID.String())
+}
+
+// HashTaskResult returns the canonical SHA-256 hash of a JSON task result.
+// The result is re-encoded so that key order and whitespace do not affect the hash.
+func HashTaskResult(result json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(result, &v); err != nil {
+		return "", fmt.Errorf("invalid result JSON: %w", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// EvaluateReleaseConditions evaluates conditions against facts and returns the
+// recommended outcome. It is pure: persistence and fund movement are left to
+// EscrowService.EvaluateConditions.
+func EvaluateReleaseConditions(escrowID uuid.UUID, rc *ReleaseConditions, facts *ConditionFacts, now time.Time) *ConditionEvaluation {
+	eval := &ConditionEvaluation{
+		ID:          uuid.New(),
+		EscrowID:    escrowID,
+		Outcome:     ConditionOutcomePending,
+		EvaluatedAt: now,
+	}
+	if rc == nil || len(rc.Conditions) == 0 {
+		return eval
+	}
+	if facts == nil {
+		facts = &ConditionFacts{}
+	}
+
+	satisfied, violated := 0, 0
+	for i := range rc.Conditions {
+		res := evaluateCondition(escrowID, &rc.Conditions[i], facts, now)
+		eval.Results = append(eval.Results, res)
+		switch res.Status {
+		case ConditionStatusSatisfied:
+			satisfied++
+		case ConditionStatusViolated:
+			violated++
+		}
+	}
+
+	total := len(rc.Conditions)
+	switch rc.Mode {
+	case ConditionModeAny:
+		if satisfied > 0 {
+			eval.Outcome = ConditionOutcomeRelease
+		} else if violated == total {
+			eval.Outcome = ConditionOutcomeRefund
+		}
+	default:
+		if violated > 0 {
+			eval.Outcome = ConditionOutcomeRefund
+		} else if satisfied == total {
+			eval.Outcome = ConditionOutcomeRelease
+		}
+	}
+
+	return eval
+}
+
+// evaluateCondition evaluates a single condition
+func evaluateCondition(escrowID uuid.UUID, c *ReleaseCondition, facts *ConditionFacts, now time.Time) ConditionResult {
+	res := ConditionResult{Type: c.Type, Status: ConditionStatusUnsatisfied}
+
+	switch c.Type {
+	case ConditionResultHash:
+		switch {
+		case facts.ResultHash == "":
+			res.Detail = "no result submitted"
+		case strings.EqualFold(facts.ResultHash, c.ExpectedHash):
+			res.Status = ConditionStatusSatisfied
+			res.Detail = "result hash matches"
+		default:
+			res.Status = ConditionStatusViolated
+			res.Detail = fmt.Sprintf("result hash %s does not match expected %s", facts.ResultHash, c.ExpectedHash)
+		}
+
+	case ConditionValidatorApproval:
+		required := c.MinApprovals
+		if required <= 0 {
+			required = 1
+		}
+		approved, rejected := 0, 0
+		for _, v := range c.Validators {
+			if ok, voted := facts.Approvals[v]; voted {
+				if ok {
+					approved++
+				} else {
+					rejected++
+				}
+			}
+		}
+		switch {
+		case approved >= required:
+			res.Status = ConditionStatusSatisfied
+		case len(c.Validators)-rejected < required:
+			res.Status = ConditionStatusViolated
+		}
+		res.Detail = fmt.Sprintf("%d/%d validator approvals (%d rejections)", approved, required, rejected)
+
+	case ConditionSignatures:
+		valid := 0
+		for signerID, sig := range facts.Signatures {
+			signer, ok := c.signer(signerID)
+			if !ok {
+				continue
+			}
+			if !verifyReleaseSignature(escrowID, signer.PublicKey, sig) {
+				continue
+			}
+			valid++
+		}
+		if valid >= c.Threshold {
+			res.Status = ConditionStatusSatisfied
+		}
+		res.Detail = fmt.Sprintf("%d/%d valid signatures", valid, c.Threshold)
+
+	case ConditionJSONSchema:
+		if len(facts.Result) == 0 {
+			res.Detail = "no result submitted"
+			break
+		}
+		// A delivered result that fails the schema can't be fixed later
+		var v interface{}
+		if err := json.Unmarshal(facts.Result, &v); err != nil {
+			res.Status = ConditionStatusViolated
+			res.Detail = fmt.Sprintf("result is not valid JSON: %v", err)
+			break
+		}
+		if errs := validation.ValidateJSONSchema(c.Schema, v, "$"); len(errs) > 0 {
+			res.Status = ConditionStatusViolated
+			res.Detail = strings.Join(errs, "; ")
+			break
+		}
+		res.Status = ConditionStatusSatisfied
+		res.Detail = "result matches schema"
+
+	case ConditionDeadline:
+		switch {
+		case facts.ResultAt != nil && !facts.ResultAt.After(*c.Deadline):
+			res.Status = ConditionStatusSatisfied
+			res.Detail = "result delivered before deadline"
+		case now.After(*c.Deadline):
+			res.Status = ConditionStatusViolated
+			res.Detail = "deadline passed without a timely result"
+		default:
+			res.Detail = fmt.Sprintf("awaiting result before %s", c.Deadline.Format(time.RFC3339))
+		}
+
+	case ConditionMilestones:
+		reached := 0
+		for _, m := range c.Milestones {
+			if facts.Milestones[m] {
+				reached++
+			}
+		}
+		if reached == len(c.Milestones) {
+			res.Status = ConditionStatusSatisfied
+		}
+		res.Detail = fmt.Sprintf("%d/%d milestones reached", reached, len(c.Milestones))
+	}
+
+	return res
+}
+
+// verifyReleaseSignature verifies a hex ed25519 signature over the escrow release message
+func verifyReleaseSignature(escrowID uuid.UUID, publicKeyHex, signatureHex string) bool {
+	key, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(signatureHex)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(key), ReleaseSigningMessage(escrowID), sig)
+}
@@ -0,0 +1,164 @@
+package economic
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReleaseConditions(t *testing.T) {
+	rc, err := ParseReleaseConditions("")
+	require.NoError(t, err)
+	assert.Nil(t, rc)
+
+	rc, err = ParseReleaseConditions(`{"conditions":[{"type":"result_hash","expected_hash":"abc"}]}`)
+	require.NoError(t, err)
+	require.NotNil(t, rc)
+	assert.Equal(t, ConditionModeAll, rc.Mode)
+
+	_, err = ParseReleaseConditions(`{"conditions":[{"type":"telepathy"}]}`)
+	assert.ErrorIs(t, err, ErrInvalidConditions)
+
+	_, err = ParseReleaseConditions(`{"conditions":[{"type":"signatures","signers":[{"id":"a"}],"threshold":2}]}`)
+	assert.ErrorIs(t, err, ErrInvalidConditions)
+
+	_, err = ParseReleaseConditions(`{"conditions":[{"type":"signatures","signers":[{"id":"a"}],"threshold":1}]}`)
+	assert.ErrorIs(t, err, ErrInvalidConditions, "signers need a public key")
+
+	_, err = ParseReleaseConditions(`not json`)
+	assert.ErrorIs(t, err, ErrInvalidConditions)
+}
+
+func TestHashTaskResultIsCanonical(t *testing.T) {
+	a, err := HashTaskResult(json.RawMessage(`{"b":2,"a":1}`))
+	require.NoError(t, err)
+	b, err := HashTaskResult(json.RawMessage(`{ "a": 1, "b": 2 }`))
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestEvaluateResultHashAndSchema(t *testing.T) {
+	escrowID := uuid.New()
+	result := json.RawMessage(`{"sum":42}`)
+	hash, err := HashTaskResult(result)
+	require.NoError(t, err)
+
+	rc := &ReleaseConditions{
+		Mode: ConditionModeAll,
+		Conditions: []ReleaseCondition{
+			{Type: ConditionResultHash, ExpectedHash: hash},
+			{Type: ConditionJSONSchema, Schema: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"sum"},
+				"properties": map[string]interface{}{
+					"sum": map[string]interface{}{"type": "integer", "minimum": float64(0)},
+				},
+			}},
+		},
+	}
+
+	eval := EvaluateReleaseConditions(escrowID, rc, &ConditionFacts{}, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome)
+
+	eval = EvaluateReleaseConditions(escrowID, rc, &ConditionFacts{ResultHash: hash, Result: result}, time.Now())
+	assert.Equal(t, ConditionOutcomeRelease, eval.Outcome)
+
+	bad := json.RawMessage(`{"sum":-1}`)
+	badHash, _ := HashTaskResult(bad)
+	eval = EvaluateReleaseConditions(escrowID, rc, &ConditionFacts{ResultHash: badHash, Result: bad}, time.Now())
+	assert.Equal(t, ConditionOutcomeRefund, eval.Outcome)
+	assert.Equal(t, ConditionStatusViolated, eval.Results[0].Status)
+	assert.Equal(t, ConditionStatusViolated, eval.Results[1].Status)
+
+	// A wrong result fails the schema check on its own too
+	eval = EvaluateReleaseConditions(escrowID, &ReleaseConditions{Conditions: rc.Conditions[1:]}, &ConditionFacts{ResultHash: badHash, Result: bad}, time.Now())
+	assert.Equal(t, ConditionOutcomeRefund, eval.Outcome)
+}
+
+func TestEvaluateValidatorApproval(t *testing.T) {
+	rc := &ReleaseConditions{Conditions: []ReleaseCondition{
+		{Type: ConditionValidatorApproval, Validators: []string{"v1", "v2"}, MinApprovals: 2},
+	}}
+
+	eval := EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{Approvals: map[string]bool{"v1": true}}, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome)
+
+	eval = EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{Approvals: map[string]bool{"v1": true, "v2": true}}, time.Now())
+	assert.Equal(t, ConditionOutcomeRelease, eval.Outcome)
+
+	eval = EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{Approvals: map[string]bool{"v1": true, "v2": false}}, time.Now())
+	assert.Equal(t, ConditionOutcomeRefund, eval.Outcome)
+}
+
+func TestEvaluateSignaturesThreshold(t *testing.T) {
+	escrowID := uuid.New()
+	alicePub, alicePriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	bobPub, bobPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	carolPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sign := func(priv ed25519.PrivateKey) string {
+		return hex.EncodeToString(ed25519.Sign(priv, ReleaseSigningMessage(escrowID)))
+	}
+
+	rc := &ReleaseConditions{Conditions: []ReleaseCondition{{
+		Type:      ConditionSignatures,
+		Threshold: 2,
+		Signers: []ConditionSigner{
+			{ID: "alice", PublicKey: hex.EncodeToString(alicePub)},
+			{ID: "bob", PublicKey: hex.EncodeToString(bobPub)},
+			{ID: "carol", PublicKey: hex.EncodeToString(carolPub)},
+		},
+	}}}
+
+	facts := &ConditionFacts{Signatures: map[string]string{"alice": "deadbeef", "bob": sign(bobPriv)}}
+	eval := EvaluateReleaseConditions(escrowID, rc, facts, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome, "forged signature must not count")
+
+	facts.Signatures["alice"] = ""
+	eval = EvaluateReleaseConditions(escrowID, rc, facts, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome, "empty signature must not count")
+
+	facts.Signatures["alice"] = sign(alicePriv)
+	eval = EvaluateReleaseConditions(escrowID, rc, facts, time.Now())
+	assert.Equal(t, ConditionOutcomeRelease, eval.Outcome)
+}
+
+func TestEvaluateDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	rc := &ReleaseConditions{Conditions: []ReleaseCondition{{Type: ConditionDeadline, Deadline: &deadline}}}
+
+	eval := EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{}, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome)
+
+	onTime := deadline.Add(-time.Minute)
+	eval = EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{ResultAt: &onTime}, time.Now())
+	assert.Equal(t, ConditionOutcomeRelease, eval.Outcome)
+
+	eval = EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{}, deadline.Add(time.Minute))
+	assert.Equal(t, ConditionOutcomeRefund, eval.Outcome)
+}
+
+func TestEvaluateAnyMode(t *testing.T) {
+	rc := &ReleaseConditions{
+		Mode: ConditionModeAny,
+		Conditions: []ReleaseCondition{
+			{Type: ConditionMilestones, Milestones: []string{"draft", "final"}},
+			{Type: ConditionValidatorApproval, Validators: []string{"v1"}},
+		},
+	}
+
+	eval := EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{Milestones: map[string]bool{"draft": true}}, time.Now())
+	assert.Equal(t, ConditionOutcomePending, eval.Outcome)
+
+	eval = EvaluateReleaseConditions(uuid.New(), rc, &ConditionFacts{Approvals: map[string]bool{"v1": true}}, time.Now())
+	assert.Equal(t, ConditionOutcomeRelease, eval.Outcome)
+}
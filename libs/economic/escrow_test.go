@@ -0,0 +1,301 @@
+package economic
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// escrowTestSchema is the SQLite equivalent of the escrow, condition and
+// ledger migrations
+const escrowTestSchema = `
+CREATE TABLE escrows (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	payer_id TEXT NOT NULL,
+	payee_id TEXT NOT NULL,
+	amount REAL NOT NULL,
+	status TEXT,
+	funded_at TIMESTAMP,
+	released_at TIMESTAMP,
+	refunded_at TIMESTAMP,
+	dispute_id TEXT,
+	expires_at TIMESTAMP NOT NULL,
+	auto_release_at TIMESTAMP,
+	conditions TEXT,
+	created_at TIMESTAMP,
+	updated_at TIMESTAMP,
+	error TEXT
+);
+CREATE TABLE escrow_condition_facts (
+	id TEXT PRIMARY KEY,
+	escrow_id TEXT NOT NULL,
+	fact_type TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	value TEXT NOT NULL,
+	created_at TIMESTAMP
+);
+CREATE TABLE escrow_condition_evaluations (
+	id TEXT PRIMARY KEY,
+	escrow_id TEXT NOT NULL,
+	trigger TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	results BLOB NOT NULL,
+	applied BOOLEAN NOT NULL DEFAULT false,
+	evaluated_at TIMESTAMP
+);
+CREATE TABLE ledger_accounts (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE ledger_entries (
+	id TEXT PRIMARY KEY,
+	idempotency_key TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	reference TEXT NOT NULL DEFAULT '',
+	metadata BLOB,
+	reverses_id TEXT,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE ledger_postings (
+	entry_id TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	account_id TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (entry_id, line)
+);
+`
+
+func newTestEscrowDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(escrowTestSchema)
+	require.NoError(t, err)
+	return db
+}
+
+// insertFundedEscrow adds a funded escrow with the given conditions and
+// auto-release time
+func insertFundedEscrow(t *testing.T, db *sql.DB, conditions string, autoReleaseAt *time.Time) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO escrows (id, task_id, payer_id, payee_id, amount, status, funded_at, expires_at, auto_release_at, conditions, created_at, updated_at)
+		VALUES ($1, $2, 'payer', 'payee', 10, $3, $4, $5, $6, $7, $4, $4)
+	`, id, "task-"+id.String(), EscrowStatusFunded, now, now.Add(time.Hour), autoReleaseAt, conditions)
+	require.NoError(t, err)
+	return id
+}
+
+func escrowStatus(t *testing.T, db *sql.DB, id uuid.UUID) EscrowStatus {
+	t.Helper()
+
+	var status EscrowStatus
+	require.NoError(t, db.QueryRow("SELECT status FROM escrows WHERE id = $1", id).Scan(&status))
+	return status
+}
+
+func evaluationCount(t *testing.T, db *sql.DB, id uuid.UUID) int {
+	t.Helper()
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM escrow_condition_evaluations WHERE escrow_id = $1", id).Scan(&n))
+	return n
+}
+
+func TestProcessConditionDeadlines(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	svc := NewEscrowService(db, zap.NewNop())
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	pending := insertFundedEscrow(t, db, `{"conditions":[{"type":"deadline","deadline":"`+future+`"}]}`, nil)
+	expired := insertFundedEscrow(t, db, `{"conditions":[{"type":"deadline","deadline":"`+past+`"}]}`, nil)
+	untimed := insertFundedEscrow(t, db, `{"conditions":[{"type":"milestones","milestones":["final"]}]}`, nil)
+
+	applied, err := svc.ProcessConditionDeadlines(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, EscrowStatusRefunded, escrowStatus(t, db, expired))
+	assert.Equal(t, 1, evaluationCount(t, db, expired))
+
+	// Ticks that change nothing leave no trace in the audit log
+	for i := 0; i < 3; i++ {
+		_, err = svc.ProcessConditionDeadlines(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, EscrowStatusFunded, escrowStatus(t, db, pending))
+	assert.Equal(t, 0, evaluationCount(t, db, pending))
+	assert.Equal(t, 0, evaluationCount(t, db, untimed))
+	assert.Equal(t, 1, evaluationCount(t, db, expired))
+}
+
+func TestProcessAutoReleasesSkipsUnmetConditions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	svc := NewEscrowService(db, zap.NewNop())
+
+	due := time.Now().Add(-time.Minute)
+	plain := insertFundedEscrow(t, db, "", &due)
+	unmet := insertFundedEscrow(t, db, `{"conditions":[{"type":"milestones","milestones":["final"]}]}`, &due)
+	met := insertFundedEscrow(t, db, `{"conditions":[{"type":"milestones","milestones":["final"]}]}`, &due)
+	require.NoError(t, svc.recordFact(ctx, met, FactTypeMilestone, "final", "reached"))
+
+	released, err := svc.ProcessAutoReleases(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, released)
+	assert.Equal(t, EscrowStatusReleased, escrowStatus(t, db, plain))
+	assert.Equal(t, EscrowStatusReleased, escrowStatus(t, db, met))
+	assert.Equal(t, EscrowStatusFunded, escrowStatus(t, db, unmet))
+}
+
+func TestReleaseEscrowChargesPlatformFee(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	svc := NewEscrowService(db, zap.NewNop())
+	svc.SetPlatformFee(0.1)
+
+	id := insertFundedEscrow(t, db, "", nil)
+	require.NoError(t, svc.ReleaseEscrow(ctx, id, "payer"))
+
+	fees, err := svc.Ledger().Balance(ctx, ledger.AccountFees)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.FromFloat(1), fees)
+	payee, err := svc.Ledger().Balance(ctx, ledger.UserAccount("payee"))
+	require.NoError(t, err)
+	assert.Equal(t, ledger.FromFloat(9), payee)
+
+	entries, err := svc.Ledger().Entries(ctx, ledger.EntryFilter{AccountID: ledger.AccountFees})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ledger.DescFee, entries[0].Description)
+	assert.Equal(t, "task-"+id.String(), entries[0].Metadata[ledger.MetadataTaskID])
+}
+
+func TestEscrowTransitionFromStaleStatusFails(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	svc := NewEscrowService(db, zap.NewNop())
+
+	id := insertFundedEscrow(t, db, "", nil)
+	require.NoError(t, svc.ReleaseEscrow(ctx, id, "payer"))
+
+	// A refund that read the escrow as funded before the release committed
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	err = transitionEscrow(ctx, tx, id, EscrowStatusRefunded, "refunded_at", time.Now(), EscrowStatusFunded)
+	assert.ErrorIs(t, err, ErrEscrowStateChanged)
+	require.NoError(t, tx.Rollback())
+
+	assert.Equal(t, EscrowStatusReleased, escrowStatus(t, db, id))
+	assert.Error(t, svc.RefundEscrow(ctx, id, "system"))
+	payer, err := svc.Ledger().Balance(ctx, ledger.UserAccount("payer"))
+	require.NoError(t, err)
+	assert.Zero(t, payer, "the payer must not be refunded a released escrow")
+}
+
+func TestSQLLedgerRefusesOverdraft(t *testing.T) {
+	ctx := context.Background()
+	journal := NewEscrowService(newTestEscrowDB(t), zap.NewNop()).Ledger()
+	_, err := journal.Transfer(ctx, "deposit", ledger.AccountCash, ledger.UserAccount("payer"), ledger.FromFloat(5), ledger.DescDeposit, "")
+	require.NoError(t, err)
+
+	entry := ledger.NewTransfer("spend", ledger.UserAccount("payer"), ledger.UserAccount("payee"), ledger.FromFloat(6), "spend", "")
+	entry.NoOverdraft = []string{ledger.UserAccount("payer")}
+	_, err = journal.Post(ctx, entry)
+	assert.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+
+	payer, err := journal.Balance(ctx, ledger.UserAccount("payer"))
+	require.NoError(t, err)
+	assert.Equal(t, ledger.FromFloat(5), payer, "a refused entry must not be written")
+}
+
+func TestEscrowJournalsToPartyDIDs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE users (id TEXT PRIMARY KEY, did TEXT UNIQUE NOT NULL);
+		CREATE TABLE agents (id TEXT PRIMARY KEY, did TEXT UNIQUE NOT NULL);
+	`)
+	require.NoError(t, err)
+	userID, agentID := uuid.New().String(), uuid.New().String()
+	_, err = db.Exec(`INSERT INTO users (id, did) VALUES ($1, 'did:user:alice')`, userID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO agents (id, did) VALUES ($1, 'did:agent:bob')`, agentID)
+	require.NoError(t, err)
+
+	svc := NewEscrowService(db, zap.NewNop())
+	svc.SetAccountResolver(UserDIDResolver)
+	escrow, err := svc.CreateEscrow(ctx, "task-1", userID, agentID, 10, 60, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, svc.FundEscrow(ctx, escrow.ID, ""))
+	require.NoError(t, svc.ReleaseEscrow(ctx, escrow.ID, userID))
+
+	for account, expected := range map[string]float64{
+		ledger.UserAccount("did:user:alice"): -10,
+		ledger.UserAccount("did:agent:bob"):  10,
+		ledger.UserAccount(userID):           0,
+		ledger.UserAccount(agentID):          0,
+	} {
+		balance, err := svc.Ledger().Balance(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, ledger.FromFloat(expected), balance, account)
+	}
+
+	// Parties that are already DIDs or unknown keep their ID
+	for _, id := range []string{"did:user:carol", uuid.New().String()} {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		resolved, err := UserDIDResolver(ctx, tx, id)
+		require.NoError(t, tx.Rollback())
+		require.NoError(t, err)
+		assert.Equal(t, id, resolved)
+	}
+}
+
+func TestSplitEscrow(t *testing.T) {
+	ctx := context.Background()
+	db := newTestEscrowDB(t)
+	svc := NewEscrowService(db, zap.NewNop())
+	svc.SetPlatformFee(0.1)
+
+	id := insertFundedEscrow(t, db, "", nil)
+	shares := map[string]float64{"did:agent:a": 4, "did:agent:b": 3}
+	assert.Error(t, svc.SplitEscrow(ctx, id, shares, "payer"))
+	assert.Error(t, svc.SplitEscrow(ctx, id, map[string]float64{"did:agent:a": 8, "did:agent:b": 3}, "system"))
+	require.NoError(t, svc.SplitEscrow(ctx, id, shares, "system"))
+	assert.Equal(t, EscrowStatusReleased, escrowStatus(t, db, id))
+
+	for account, expected := range map[string]float64{
+		ledger.UserAccount("did:agent:a"): 3.6,
+		ledger.UserAccount("did:agent:b"): 2.7,
+		ledger.UserAccount("payer"):       3,
+		ledger.AccountFees:                0.7,
+	} {
+		balance, err := svc.Ledger().Balance(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, ledger.FromFloat(expected), balance, account)
+	}
+
+	assert.Error(t, svc.SplitEscrow(ctx, id, shares, "system"), "a split escrow can't be split again")
+}
@@ -4,8 +4,11 @@ go 1.24.10
 
 require (
 	github.com/aidenlippert/zerostate/libs/database v0.0.0
+	github.com/aidenlippert/zerostate/libs/ledger v0.0.0
 	github.com/aidenlippert/zerostate/libs/metrics v0.0.0
+	github.com/aidenlippert/zerostate/libs/validation v0.0.0
 	github.com/google/uuid v1.6.0
+	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/prometheus/client_golang v1.23.2
 	github.com/stretchr/testify v1.11.1
 	go.uber.org/zap v1.27.0
@@ -13,15 +16,18 @@ require (
 
 replace github.com/aidenlippert/zerostate/libs/metrics => ../metrics
 
+replace github.com/aidenlippert/zerostate/libs/validation => ../validation
+
 replace github.com/aidenlippert/zerostate/libs/database => ../database
 
+replace github.com/aidenlippert/zerostate/libs/ledger => ../ledger
+
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/lib/pq v1.10.9 // indirect
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/prometheus/client_model v0.6.2 // indirect
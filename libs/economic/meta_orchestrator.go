@@ -72,8 +72,9 @@ type Subtask struct {
 
 // MetaOrchestratorService handles task decomposition and multi-agent coordination
 type MetaOrchestratorService struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db      *sql.DB
+	budgets *BudgetService
+	logger  *zap.Logger
 }
 
 // NewMetaOrchestratorService creates a new meta-orchestrator service
@@ -82,8 +83,9 @@ func NewMetaOrchestratorService(db *sql.DB, logger *zap.Logger) *MetaOrchestrato
 		logger = zap.NewNop()
 	}
 	return &MetaOrchestratorService{
-		db:     db,
-		logger: logger,
+		db:      db,
+		budgets: NewBudgetService(db, logger),
+		logger:  logger,
 	}
 }
 
@@ -344,11 +346,13 @@ func (s *MetaOrchestratorService) UpdateDelegationStatus(ctx context.Context, de
 	var status DelegationStatus
 	completedCount := 0
 	failedCount := 0
+	var completedBudget float64
 
 	for _, st := range subtasks {
 		switch st.Status {
 		case SubtaskStatusCompleted:
 			completedCount++
+			completedBudget += st.BudgetShare
 		case SubtaskStatusFailed:
 			failedCount++
 		}
@@ -379,6 +383,18 @@ func (s *MetaOrchestratorService) UpdateDelegationStatus(ctx context.Context, de
 		return fmt.Errorf("failed to update delegation status: %w", err)
 	}
 
+	// A finished delegation is charged the shares of the subtasks that
+	// completed, which frees the rest of its budget reservation
+	if status == DelegationStatusCompleted || status == DelegationStatusFailed {
+		delegation, err := s.GetDelegation(ctx, delegationID)
+		if err != nil {
+			return err
+		}
+		if err := s.budgets.SettleTask(ctx, delegation.TaskID, completedBudget); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -117,6 +118,9 @@ type PaymentChannelService struct {
 	channels map[string]*PaymentChannel // channel_id -> channel
 	accounts map[string]*Account        // did -> account
 
+	// Double-entry journal of every balance change (invariant 5)
+	ledger *ledger.Ledger
+
 	// Metrics
 	metricsChannelsActive        prometheus.Gauge
 	metricsChannelsClosed        prometheus.Counter
@@ -143,6 +147,7 @@ func NewPaymentChannelService() *PaymentChannelService {
 	return &PaymentChannelService{
 		channels: make(map[string]*PaymentChannel),
 		accounts: make(map[string]*Account),
+		ledger:   ledger.New(ledger.NewMemoryStore(), nil),
 		ctx:      ctx,
 		cancel:   cancel,
 
@@ -195,9 +200,58 @@ func NewPaymentChannelService() *PaymentChannelService {
 	}
 }
 
+// SetLedger replaces the default in-memory journal, e.g. with a SQL-backed ledger.
+// Must be called before any balance changes.
+func (pcs *PaymentChannelService) SetLedger(l *ledger.Ledger) {
+	pcs.mu.Lock()
+	defer pcs.mu.Unlock()
+	pcs.ledger = l
+}
+
+// Ledger returns the journal balance changes are posted to
+func (pcs *PaymentChannelService) Ledger() *ledger.Ledger {
+	pcs.mu.RLock()
+	defer pcs.mu.RUnlock()
+	return pcs.ledger
+}
+
+// journal posts a transfer for a balance change. Called with pcs.mu held and
+// BEFORE mutating state, so a failed posting leaves balances untouched.
+// txID is the idempotency key; replayed reports whether the ledger already
+// held an entry for it, in which case the change must not be applied again.
+func (pcs *PaymentChannelService) journal(ctx context.Context, txID, from, to string, amount float64, description, reference string) (replayed bool, err error) {
+	if amount <= 0 {
+		return false, nil
+	}
+	entry := ledger.NewTransfer(txID, from, to, ledger.FromFloat(amount), description, reference)
+	stored, err := pcs.ledger.Post(ctx, entry)
+	if err != nil {
+		return false, fmt.Errorf("failed to journal %s: %w", description, err)
+	}
+	return stored.ID != entry.ID, nil
+}
+
+// channelTxID derives the idempotency key of a channel operation. seq is the
+// channel's sequence number before the operation, so a retry of the same
+// operation maps to the same key.
+func channelTxID(channelID string, seq uint64, operation string) string {
+	return fmt.Sprintf("channel:%s:%d:%s", channelID, seq, operation)
+}
+
+// channelEscrowAccount holds funds locked for a task inside a channel
+func channelEscrowAccount(channelID string) string {
+	return ledger.ChannelAccount(channelID) + ":escrow"
+}
+
 // Deposit adds funds to an account
 // SECURITY: Must be atomic and prevent negative balances
 func (pcs *PaymentChannelService) Deposit(ctx context.Context, did string, amount float64) error {
+	return pcs.DepositWithID(ctx, generateTxID(), did, amount)
+}
+
+// DepositWithID adds funds to an account once per operationID, e.g. the
+// payment provider's charge ID. Retrying with the same ID is a no-op.
+func (pcs *PaymentChannelService) DepositWithID(ctx context.Context, operationID, did string, amount float64) error {
 	if amount <= 0 {
 		return ErrInvalidAmount
 	}
@@ -205,6 +259,11 @@ func (pcs *PaymentChannelService) Deposit(ctx context.Context, did string, amoun
 	pcs.mu.Lock()
 	defer pcs.mu.Unlock()
 
+	replayed, err := pcs.journal(ctx, "deposit:"+operationID, ledger.AccountCash, ledger.UserAccount(did), amount, ledger.DescDeposit, did)
+	if err != nil || replayed {
+		return err
+	}
+
 	account, exists := pcs.accounts[did]
 	if !exists {
 		account = &Account{
@@ -233,6 +292,12 @@ func (pcs *PaymentChannelService) Deposit(ctx context.Context, did string, amoun
 // Withdraw removes funds from an account
 // SECURITY: Must check sufficient balance and be atomic
 func (pcs *PaymentChannelService) Withdraw(ctx context.Context, did string, amount float64) error {
+	return pcs.WithdrawWithID(ctx, generateTxID(), did, amount)
+}
+
+// WithdrawWithID removes funds from an account once per operationID, e.g.
+// the payout ID. Retrying with the same ID is a no-op.
+func (pcs *PaymentChannelService) WithdrawWithID(ctx context.Context, operationID, did string, amount float64) error {
 	if amount <= 0 {
 		return ErrInvalidAmount
 	}
@@ -250,6 +315,11 @@ func (pcs *PaymentChannelService) Withdraw(ctx context.Context, did string, amou
 		return ErrInsufficientBalance
 	}
 
+	replayed, err := pcs.journal(ctx, "withdrawal:"+operationID, ledger.UserAccount(did), ledger.AccountCash, amount, ledger.DescWithdrawal, did)
+	if err != nil || replayed {
+		return err
+	}
+
 	// Atomic update
 	account.Balance -= amount
 	account.TotalWithdrawn += amount
@@ -305,6 +375,11 @@ func (pcs *PaymentChannelService) CreateChannel(
 		return nil, ErrChannelAlreadyExists
 	}
 
+	depositTxID := channelTxID(channelID, 0, "open")
+	if _, err := pcs.journal(ctx, depositTxID, ledger.UserAccount(payerDID), ledger.ChannelAccount(channelID), depositAmount, ledger.DescChannelDeposit, channelID); err != nil {
+		return nil, err
+	}
+
 	// Create channel
 	now := time.Now()
 	channel := &PaymentChannel{
@@ -324,7 +399,7 @@ func (pcs *PaymentChannelService) CreateChannel(
 		EscrowReleased: false,
 		TransactionLog: []ChannelTransaction{
 			{
-				ID:        generateTxID(),
+				ID:        depositTxID,
 				Type:      "deposit",
 				Amount:    depositAmount,
 				Timestamp: now,
@@ -372,6 +447,11 @@ func (pcs *PaymentChannelService) LockEscrow(
 		return ErrInsufficientBalance
 	}
 
+	escrowTxID := channelTxID(channelID, channel.SequenceNumber, "escrow")
+	if _, err := pcs.journal(ctx, escrowTxID, ledger.ChannelAccount(channelID), channelEscrowAccount(channelID), amount, ledger.DescChannelEscrow, taskID); err != nil {
+		return err
+	}
+
 	// ATOMIC: Move from current balance to escrow
 	channel.CurrentBalance -= amount
 	channel.EscrowedAmount += amount
@@ -383,7 +463,7 @@ func (pcs *PaymentChannelService) LockEscrow(
 
 	// Log transaction
 	channel.TransactionLog = append(channel.TransactionLog, ChannelTransaction{
-		ID:        generateTxID(),
+		ID:        escrowTxID,
 		Type:      "escrow",
 		Amount:    amount,
 		Timestamp: time.Now(),
@@ -425,6 +505,17 @@ func (pcs *PaymentChannelService) ReleaseEscrow(
 
 	escrowAmount := channel.EscrowedAmount
 
+	// Journal first: a failed posting must leave the escrow unreleased
+	releaseTxID := channelTxID(channelID, channel.SequenceNumber, "release")
+	recipient, description := ledger.UserAccount(channel.PayeeDID), ledger.DescChannelRelease
+	if !success {
+		releaseTxID = channelTxID(channelID, channel.SequenceNumber, "refund")
+		recipient, description = ledger.ChannelAccount(channelID), ledger.DescChannelRefund
+	}
+	if _, err := pcs.journal(ctx, releaseTxID, channelEscrowAccount(channelID), recipient, escrowAmount, description, taskID); err != nil {
+		return err
+	}
+
 	if success {
 		// Task succeeded: pay agent
 		channel.TotalSettled += escrowAmount
@@ -445,7 +536,7 @@ func (pcs *PaymentChannelService) ReleaseEscrow(
 
 		// Log
 		channel.TransactionLog = append(channel.TransactionLog, ChannelTransaction{
-			ID:        generateTxID(),
+			ID:        releaseTxID,
 			Type:      "release",
 			Amount:    escrowAmount,
 			Timestamp: time.Now(),
@@ -463,7 +554,7 @@ func (pcs *PaymentChannelService) ReleaseEscrow(
 
 		// Log
 		channel.TransactionLog = append(channel.TransactionLog, ChannelTransaction{
-			ID:        generateTxID(),
+			ID:        releaseTxID,
 			Type:      "refund",
 			Amount:    escrowAmount,
 			Timestamp: time.Now(),
@@ -506,6 +597,11 @@ func (pcs *PaymentChannelService) CloseChannel(ctx context.Context, channelID st
 		return fmt.Errorf("cannot close channel with active escrow: %f locked", channel.EscrowedAmount)
 	}
 
+	closeTxID := channelTxID(channelID, channel.SequenceNumber, "close")
+	if _, err := pcs.journal(ctx, closeTxID, ledger.ChannelAccount(channelID), ledger.UserAccount(channel.PayerDID), channel.CurrentBalance, ledger.DescChannelClose, channelID); err != nil {
+		return err
+	}
+
 	// Return remaining balance to payer
 	if channel.CurrentBalance > 0 {
 		payerAccount, exists := pcs.accounts[channel.PayerDID]
@@ -533,7 +629,7 @@ func (pcs *PaymentChannelService) CloseChannel(ctx context.Context, channelID st
 
 	// Log
 	channel.TransactionLog = append(channel.TransactionLog, ChannelTransaction{
-		ID:        generateTxID(),
+		ID:        closeTxID,
 		Type:      "close",
 		Amount:    channel.PendingRefund,
 		Timestamp: now,
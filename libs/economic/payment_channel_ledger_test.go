@@ -0,0 +1,110 @@
+package economic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NOTE: NewPaymentChannelService registers global Prometheus metrics, so this
+// package may only construct one instance per test binary.
+func TestPaymentChannelLedgerMirrorsBalances(t *testing.T) {
+	ctx := context.Background()
+	pcs := NewPaymentChannelService()
+
+	require.NoError(t, pcs.Deposit(ctx, "did:user", 100))
+	channel, err := pcs.CreateChannel(ctx, "did:user", "did:agent", 30, "auction-1")
+	require.NoError(t, err)
+	require.NoError(t, pcs.LockEscrow(ctx, channel.ID, "task-1", 20))
+	require.NoError(t, pcs.ReleaseEscrow(ctx, channel.ID, "task-1", true))
+	require.NoError(t, pcs.CloseChannel(ctx, channel.ID))
+	require.NoError(t, pcs.Withdraw(ctx, "did:agent", 5))
+
+	// Retried operations post once
+	for i := 0; i < 2; i++ {
+		require.NoError(t, pcs.DepositWithID(ctx, "charge-1", "did:agent", 10))
+		require.NoError(t, pcs.WithdrawWithID(ctx, "payout-1", "did:agent", 10))
+	}
+
+	l := pcs.Ledger()
+	for _, did := range []string{"did:user", "did:agent"} {
+		expected, err := pcs.GetBalance(ctx, did)
+		require.NoError(t, err)
+		actual, err := l.Balance(ctx, ledger.UserAccount(did))
+		require.NoError(t, err)
+		assert.Equal(t, ledger.FromFloat(expected), actual, did)
+	}
+
+	channelBalance, err := l.Balance(ctx, ledger.ChannelAccount(channel.ID))
+	require.NoError(t, err)
+	assert.Equal(t, ledger.Amount(0), channelBalance)
+
+	cash, err := l.Balance(ctx, ledger.AccountCash)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.FromFloat(95), cash)
+
+	tb, err := l.Store().TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.True(t, tb.Balanced)
+}
+
+func TestEconomicServiceJournalsChannels(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.Conn().SetMaxOpenConns(1)
+	_, err = db.Conn().Exec(`
+		CREATE TABLE payment_channels (
+			id TEXT PRIMARY KEY,
+			payer_did TEXT NOT NULL,
+			payee_did TEXT NOT NULL,
+			auction_id TEXT,
+			total_deposit REAL NOT NULL,
+			current_balance REAL NOT NULL,
+			escrowed_amount REAL NOT NULL DEFAULT 0,
+			total_settled REAL NOT NULL DEFAULT 0,
+			pending_refund REAL NOT NULL DEFAULT 0,
+			state TEXT NOT NULL,
+			task_id TEXT,
+			escrow_released BOOLEAN NOT NULL DEFAULT false,
+			sequence_number INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			closed_at TIMESTAMP
+		)
+	`)
+	require.NoError(t, err)
+
+	journal := ledger.New(ledger.NewMemoryStore(), nil)
+	svc := NewEconomicService(db)
+	svc.SetLedger(journal)
+
+	channel, err := svc.OpenPaymentChannel(ctx, "did:user", "did:agent", 30, nil)
+	require.NoError(t, err)
+	channelAccount := ledger.ChannelAccount(channel.ID.String())
+	balance, err := journal.Balance(ctx, channelAccount)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.FromFloat(30), balance)
+
+	// Settling twice posts once
+	for i := 0; i < 2; i++ {
+		require.NoError(t, svc.journalSettlement(ctx, channel, 12))
+	}
+	for account, expected := range map[string]float64{
+		channelAccount:                  0,
+		ledger.UserAccount("did:agent"): 12,
+		ledger.UserAccount("did:user"):  -12,
+	} {
+		balance, err := journal.Balance(ctx, account)
+		require.NoError(t, err)
+		assert.Equal(t, ledger.FromFloat(expected), balance, account)
+	}
+
+	err = svc.SettlePaymentChannel(ctx, channel.ID, 31)
+	assert.ErrorContains(t, err, "exceeds channel deposit")
+}
@@ -0,0 +1,457 @@
+package economic
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Price index defaults
+const (
+	// DefaultPriceIndexWindow is how far back cleared prices are aggregated
+	DefaultPriceIndexWindow = 7 * 24 * time.Hour
+
+	// DefaultPriceIndexMinSamples is the fewest cleared prices a capability
+	// needs before its percentiles can be used as a reserve or floor
+	DefaultPriceIndexMinSamples = 5
+
+	// PriceIndexPercentileStep is the spacing of published percentiles;
+	// quotes carry p5, p10, ... p95
+	PriceIndexPercentileStep = 5
+)
+
+// Price index errors
+var (
+	// ErrInvalidPriceReference indicates a reference other than pNN on the published grid
+	ErrInvalidPriceReference = errors.New("invalid price reference")
+
+	// ErrNoPriceData indicates too few cleared prices to resolve a reference
+	ErrNoPriceData = errors.New("not enough price data")
+)
+
+// ParsePriceReference parses a declarative price reference such as "p25"
+// and returns its percentile. Only published percentiles are accepted.
+func ParsePriceReference(ref string) (int, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "median" {
+		return 50, nil
+	}
+	if !strings.HasPrefix(ref, "p") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidPriceReference, ref)
+	}
+
+	p, err := strconv.Atoi(ref[1:])
+	if err != nil || p <= 0 || p >= 100 || p%PriceIndexPercentileStep != 0 {
+		return 0, fmt.Errorf("%w: %q (use p%d..p%d in steps of %d)", ErrInvalidPriceReference, ref,
+			PriceIndexPercentileStep, 100-PriceIndexPercentileStep, PriceIndexPercentileStep)
+	}
+
+	return p, nil
+}
+
+func percentileKey(p int) string {
+	return "p" + strconv.Itoa(p)
+}
+
+// PriceSample is one cleared price
+type PriceSample struct {
+	ID         string // Source auction; samples with a known ID are ignored
+	Capability string
+	Price      float64
+	ClearedAt  time.Time
+}
+
+// PriceQuote summarizes cleared prices for one capability over the window
+type PriceQuote struct {
+	Capability  string             `json:"capability"`
+	Samples     int                `json:"samples"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean"`
+	Percentiles map[string]float64 `json:"percentiles"` // "p5" .. "p95"
+	WindowSec   int64              `json:"window_seconds"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// Resolve returns the price for a reference such as "p25"
+func (q *PriceQuote) Resolve(ref string) (float64, error) {
+	p, err := ParsePriceReference(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	price, ok := q.Percentiles[percentileKey(p)]
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrNoPriceData, q.Capability)
+	}
+
+	return price, nil
+}
+
+// PriceIndexSnapshot is the set of quotes published to the network
+type PriceIndexSnapshot struct {
+	Quotes      []*PriceQuote `json:"quotes"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
+
+// PriceIndex aggregates cleared prices per capability over a rolling window.
+// It is safe for concurrent use.
+type PriceIndex struct {
+	mu         sync.Mutex
+	window     time.Duration
+	minSamples int
+	samples    map[string][]PriceSample // Capability → samples
+	seen       map[string]time.Time     // Sample ID → cleared at
+	now        func() time.Time
+}
+
+// NewPriceIndex creates a price index over the given window; zero uses the default
+func NewPriceIndex(window time.Duration) *PriceIndex {
+	if window <= 0 {
+		window = DefaultPriceIndexWindow
+	}
+
+	return &PriceIndex{
+		window:     window,
+		minSamples: DefaultPriceIndexMinSamples,
+		samples:    make(map[string][]PriceSample),
+		seen:       make(map[string]time.Time),
+		now:        time.Now,
+	}
+}
+
+// SetMinSamples sets how many cleared prices a capability needs before its
+// quote has percentiles
+func (idx *PriceIndex) SetMinSamples(n int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	idx.minSamples = n
+}
+
+// Window returns the aggregation window
+func (idx *PriceIndex) Window() time.Duration {
+	return idx.window
+}
+
+// Record adds a cleared price. It reports whether the sample was new.
+func (idx *PriceIndex) Record(sample PriceSample) bool {
+	if sample.Capability == "" || sample.Price <= 0 || math.IsNaN(sample.Price) || math.IsInf(sample.Price, 0) {
+		return false
+	}
+	if sample.ClearedAt.IsZero() {
+		sample.ClearedAt = idx.now()
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	cutoff := idx.now().Add(-idx.window)
+	if sample.ClearedAt.Before(cutoff) {
+		return false
+	}
+
+	if sample.ID != "" {
+		key := sample.ID + "|" + sample.Capability
+		if _, ok := idx.seen[key]; ok {
+			return false
+		}
+		idx.seen[key] = sample.ClearedAt
+	}
+
+	idx.samples[sample.Capability] = append(idx.samples[sample.Capability], sample)
+	return true
+}
+
+// Quote returns the current quote for a capability, or nil without data
+func (idx *PriceIndex) Quote(capability string) *PriceQuote {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.pruneLocked()
+	return idx.quoteLocked(capability)
+}
+
+// Quotes returns the current quote of every capability, sorted by name
+func (idx *PriceIndex) Quotes() []*PriceQuote {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.pruneLocked()
+
+	capabilities := make([]string, 0, len(idx.samples))
+	for capability := range idx.samples {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+
+	quotes := make([]*PriceQuote, 0, len(capabilities))
+	for _, capability := range capabilities {
+		quotes = append(quotes, idx.quoteLocked(capability))
+	}
+	return quotes
+}
+
+// Snapshot returns every quote for publishing
+func (idx *PriceIndex) Snapshot() *PriceIndexSnapshot {
+	return &PriceIndexSnapshot{Quotes: idx.Quotes(), GeneratedAt: idx.now()}
+}
+
+// Resolve returns the price a reference such as "p25" stands for on a capability
+func (idx *PriceIndex) Resolve(capability, ref string) (float64, error) {
+	if _, err := ParsePriceReference(ref); err != nil {
+		return 0, err
+	}
+
+	quote := idx.Quote(capability)
+	if quote == nil {
+		return 0, fmt.Errorf("%w for %s", ErrNoPriceData, capability)
+	}
+
+	return quote.Resolve(ref)
+}
+
+// ResolveAny resolves a reference against several capabilities and returns
+// the highest result, so a reserve covers the most expensive capability a
+// task needs. Capabilities without data are skipped.
+func (idx *PriceIndex) ResolveAny(capabilities []string, ref string) (float64, error) {
+	if _, err := ParsePriceReference(ref); err != nil {
+		return 0, err
+	}
+
+	best, found := 0.0, false
+	for _, capability := range capabilities {
+		price, err := idx.Resolve(capability, ref)
+		if err != nil {
+			continue
+		}
+		best, found = math.Max(best, price), true
+	}
+	if !found {
+		return 0, fmt.Errorf("%w for %s", ErrNoPriceData, strings.Join(capabilities, ","))
+	}
+
+	return best, nil
+}
+
+func (idx *PriceIndex) pruneLocked() {
+	cutoff := idx.now().Add(-idx.window)
+
+	for capability, samples := range idx.samples {
+		kept := samples[:0]
+		for _, sample := range samples {
+			if !sample.ClearedAt.Before(cutoff) {
+				kept = append(kept, sample)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.samples, capability)
+		} else {
+			idx.samples[capability] = kept
+		}
+	}
+
+	for key, at := range idx.seen {
+		if at.Before(cutoff) {
+			delete(idx.seen, key)
+		}
+	}
+}
+
+func (idx *PriceIndex) quoteLocked(capability string) *PriceQuote {
+	samples := idx.samples[capability]
+	if len(samples) == 0 {
+		return nil
+	}
+
+	prices := make([]float64, len(samples))
+	updated := samples[0].ClearedAt
+	sum := 0.0
+	for i, sample := range samples {
+		prices[i] = sample.Price
+		sum += sample.Price
+		if sample.ClearedAt.After(updated) {
+			updated = sample.ClearedAt
+		}
+	}
+	sort.Float64s(prices)
+
+	quote := &PriceQuote{
+		Capability:  capability,
+		Samples:     len(prices),
+		Min:         prices[0],
+		Max:         prices[len(prices)-1],
+		Mean:        sum / float64(len(prices)),
+		Percentiles: make(map[string]float64),
+		WindowSec:   int64(idx.window / time.Second),
+		UpdatedAt:   updated,
+	}
+
+	// Percentiles of a handful of prices are noise; publish them only once
+	// there is enough data to set reserves and floors against
+	if len(prices) >= idx.minSamples {
+		for p := PriceIndexPercentileStep; p < 100; p += PriceIndexPercentileStep {
+			quote.Percentiles[percentileKey(p)] = percentile(prices, float64(p)/100)
+		}
+	}
+
+	return quote
+}
+
+// percentile linearly interpolates the q-th quantile of sorted values
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*frac
+}
+
+// PriceIndexPublisher distributes price index snapshots, e.g. over gossip
+type PriceIndexPublisher interface {
+	PublishPriceIndex(ctx context.Context, snapshot *PriceIndexSnapshot) error
+}
+
+// PriceIndexService keeps a PriceIndex fed with cleared prices of awarded
+// auctions and periodically publishes it
+type PriceIndexService struct {
+	db        *sql.DB
+	index     *PriceIndex
+	publisher PriceIndexPublisher
+	logger    *zap.Logger
+}
+
+// NewPriceIndexService creates a price index service. The publisher may be nil.
+func NewPriceIndexService(db *sql.DB, index *PriceIndex, publisher PriceIndexPublisher, logger *zap.Logger) *PriceIndexService {
+	if index == nil {
+		index = NewPriceIndex(0)
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PriceIndexService{
+		db:        db,
+		index:     index,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Index returns the underlying price index
+func (s *PriceIndexService) Index() *PriceIndex {
+	return s.index
+}
+
+// Refresh loads prices cleared within the window that the index has not
+// seen yet and returns how many were added. The cleared price is the
+// auction's final price, falling back to the winning bid.
+func (s *PriceIndexService) Refresh(ctx context.Context) (int, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	query := `
+		SELECT a.id, a.capabilities, COALESCE(a.final_price, b.price), a.updated_at
+		FROM auctions a
+		LEFT JOIN bids b ON b.id = a.winning_bid_id
+		WHERE a.status = $1
+		  AND a.updated_at >= $2
+		  AND COALESCE(a.final_price, b.price) IS NOT NULL
+		ORDER BY a.updated_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, AuctionStatusAwarded, time.Now().Add(-s.index.Window()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load cleared prices: %w", err)
+	}
+	defer rows.Close()
+
+	added := 0
+	for rows.Next() {
+		var (
+			id           string
+			capabilities []byte
+			price        float64
+			clearedAt    time.Time
+		)
+		if err := rows.Scan(&id, &capabilities, &price, &clearedAt); err != nil {
+			return added, fmt.Errorf("failed to scan cleared price: %w", err)
+		}
+
+		var caps []string
+		if len(capabilities) > 0 {
+			if err := json.Unmarshal(capabilities, &caps); err != nil {
+				s.logger.Warn("skipping auction with malformed capabilities",
+					zap.String("auction_id", id),
+					zap.Error(err),
+				)
+				continue
+			}
+		}
+
+		for _, capability := range caps {
+			if s.index.Record(PriceSample{ID: id, Capability: capability, Price: price, ClearedAt: clearedAt}) {
+				added++
+			}
+		}
+	}
+
+	return added, rows.Err()
+}
+
+// Publish sends the current snapshot through the publisher, if any
+func (s *PriceIndexService) Publish(ctx context.Context) error {
+	if s.publisher == nil {
+		return nil
+	}
+
+	snapshot := s.index.Snapshot()
+	if len(snapshot.Quotes) == 0 {
+		return nil
+	}
+
+	return s.publisher.PublishPriceIndex(ctx, snapshot)
+}
+
+// Run refreshes and publishes the index every interval until ctx is done
+func (s *PriceIndexService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		added, err := s.Refresh(ctx)
+		if err != nil {
+			s.logger.Warn("price index refresh failed", zap.Error(err))
+		} else if added > 0 {
+			s.logger.Debug("price index refreshed", zap.Int("new_prices", added))
+		}
+
+		if err := s.Publish(ctx); err != nil {
+			s.logger.Warn("price index publish failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package economic
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriceReference(t *testing.T) {
+	for ref, want := range map[string]int{"p25": 25, "P10": 10, " p95 ": 95, "median": 50} {
+		p, err := ParsePriceReference(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, p, ref)
+	}
+
+	for _, ref := range []string{"", "25", "p0", "p100", "p12", "pxx", "q25"} {
+		_, err := ParsePriceReference(ref)
+		assert.True(t, errors.Is(err, ErrInvalidPriceReference), ref)
+	}
+}
+
+func TestPriceIndexPercentiles(t *testing.T) {
+	now := time.Now()
+	idx := NewPriceIndex(time.Hour)
+	idx.now = func() time.Time { return now }
+
+	for i := 1; i <= 11; i++ {
+		assert.True(t, idx.Record(PriceSample{Capability: "math", Price: float64(i * 10), ClearedAt: now}))
+	}
+
+	quote := idx.Quote("math")
+	require.NotNil(t, quote)
+	assert.Equal(t, 11, quote.Samples)
+	assert.Equal(t, 10.0, quote.Min)
+	assert.Equal(t, 110.0, quote.Max)
+	assert.InDelta(t, 60.0, quote.Mean, 1e-9)
+	assert.InDelta(t, 60.0, quote.Percentiles["p50"], 1e-9)
+	assert.InDelta(t, 20.0, quote.Percentiles["p10"], 1e-9)
+	assert.InDelta(t, 35.0, quote.Percentiles["p25"], 1e-9)
+	assert.Len(t, quote.Percentiles, 19)
+
+	reserve, err := idx.Resolve("math", "p25")
+	require.NoError(t, err)
+	assert.InDelta(t, 35.0, reserve, 1e-9)
+
+	_, err = idx.Resolve("image", "p25")
+	assert.True(t, errors.Is(err, ErrNoPriceData))
+}
+
+func TestPriceIndexMinSamplesAndDedup(t *testing.T) {
+	now := time.Now()
+	idx := NewPriceIndex(time.Hour)
+	idx.now = func() time.Time { return now }
+
+	assert.True(t, idx.Record(PriceSample{ID: "a1", Capability: "math", Price: 10, ClearedAt: now}))
+	assert.False(t, idx.Record(PriceSample{ID: "a1", Capability: "math", Price: 10, ClearedAt: now}))
+	assert.False(t, idx.Record(PriceSample{Capability: "math", Price: -1, ClearedAt: now}))
+
+	// Too few samples publish summary stats but no percentiles
+	quote := idx.Quote("math")
+	require.NotNil(t, quote)
+	assert.Equal(t, 1, quote.Samples)
+	assert.Empty(t, quote.Percentiles)
+
+	_, err := idx.Resolve("math", "p50")
+	assert.True(t, errors.Is(err, ErrNoPriceData))
+
+	idx.SetMinSamples(1)
+	price, err := idx.Resolve("math", "p50")
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, price)
+}
+
+func TestPriceIndexWindow(t *testing.T) {
+	now := time.Now()
+	idx := NewPriceIndex(time.Hour)
+	idx.SetMinSamples(1)
+	idx.now = func() time.Time { return now }
+
+	assert.False(t, idx.Record(PriceSample{Capability: "math", Price: 10, ClearedAt: now.Add(-2 * time.Hour)}))
+	assert.True(t, idx.Record(PriceSample{Capability: "math", Price: 10, ClearedAt: now.Add(-30 * time.Minute)}))
+	assert.True(t, idx.Record(PriceSample{Capability: "json", Price: 40, ClearedAt: now}))
+	assert.Len(t, idx.Quotes(), 2)
+
+	// Samples age out as the clock moves
+	now = now.Add(45 * time.Minute)
+	quotes := idx.Quotes()
+	require.Len(t, quotes, 1)
+	assert.Equal(t, "json", quotes[0].Capability)
+}
+
+func TestPriceIndexResolveAny(t *testing.T) {
+	idx := NewPriceIndex(time.Hour)
+	idx.SetMinSamples(1)
+	idx.Record(PriceSample{Capability: "math", Price: 10})
+	idx.Record(PriceSample{Capability: "json", Price: 40})
+
+	price, err := idx.ResolveAny([]string{"math", "json", "image"}, "p50")
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, price)
+
+	_, err = idx.ResolveAny([]string{"image"}, "p50")
+	assert.True(t, errors.Is(err, ErrNoPriceData))
+}
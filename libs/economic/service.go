@@ -9,12 +9,24 @@ import (
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/database"
+	"github.com/aidenlippert/zerostate/libs/ledger"
 	"github.com/google/uuid"
 )
 
+// ErrBidderFlagged is returned when a bidder is excluded from auctions
+var ErrBidderFlagged = errors.New("bidder is flagged for suspected collusion")
+
+// BidderScreen decides which bidders may take part in auctions, e.g. to
+// exclude DIDs flagged by collusion detection
+type BidderScreen interface {
+	IsFlagged(did string) bool
+}
+
 // EconomicService provides real database-backed economic layer operations
 type EconomicService struct {
-	db *database.Database
+	db     *database.Database
+	screen BidderScreen
+	ledger *ledger.Ledger // Journal for channel deposits and settlements; built from db when nil
 }
 
 // NewEconomicService creates a new economic service with database persistence
@@ -22,6 +34,25 @@ func NewEconomicService(db *database.Database) *EconomicService {
 	return &EconomicService{db: db}
 }
 
+// SetBidderScreen excludes bidders the screen flags from submitting bids
+func (s *EconomicService) SetBidderScreen(screen BidderScreen) {
+	s.screen = screen
+}
+
+// SetLedger journals payment channel balance changes to l, normally the
+// node's shared SQL ledger
+func (s *EconomicService) SetLedger(l *ledger.Ledger) {
+	s.ledger = l
+}
+
+// journal returns the ledger channel balance changes are posted to
+func (s *EconomicService) journal() *ledger.Ledger {
+	if s.ledger == nil {
+		s.ledger = ledger.New(ledger.NewSQLStore(s.db.Conn()), nil)
+	}
+	return s.ledger
+}
+
 // ============================================================================
 // AUCTION SERVICE METHODS
 // ============================================================================
@@ -76,9 +107,17 @@ type Bid struct {
 	ReputationScore *float64  `json:"reputation_score,omitempty"`
 	QualityScore    *float64  `json:"quality_score,omitempty"`
 	CompositeScore  *float64  `json:"composite_score,omitempty"`
+	SubmittedBy     string    `json:"submitted_by,omitempty"`
+	SourceIP        string    `json:"source_ip,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
 }
 
+// BidOrigin records who submitted a bid and from where, for collusion analysis
+type BidOrigin struct {
+	SubmittedBy string // Account or API key behind the bid
+	SourceIP    string
+}
+
 // CreateAuction creates a new auction with database persistence
 func (s *EconomicService) CreateAuction(ctx context.Context, taskID, userID string, auctionType AuctionType, durationSec int, reservePrice, maxPrice, minReputation *float64, capabilities json.RawMessage) (*Auction, error) {
 	if durationSec <= 0 {
@@ -124,9 +163,17 @@ func (s *EconomicService) CreateAuction(ctx context.Context, taskID, userID stri
 
 // SubmitBid submits a bid on an auction with database persistence
 func (s *EconomicService) SubmitBid(ctx context.Context, auctionID uuid.UUID, agentDID string, price float64, estimatedTimeSec *int) (*Bid, error) {
+	return s.SubmitBidFrom(ctx, auctionID, agentDID, price, estimatedTimeSec, BidOrigin{})
+}
+
+// SubmitBidFrom submits a bid and records its origin
+func (s *EconomicService) SubmitBidFrom(ctx context.Context, auctionID uuid.UUID, agentDID string, price float64, estimatedTimeSec *int, origin BidOrigin) (*Bid, error) {
 	if price <= 0 {
 		return nil, errors.New("bid price must be positive")
 	}
+	if s.screen != nil && s.screen.IsFlagged(agentDID) {
+		return nil, ErrBidderFlagged
+	}
 
 	// Check if auction exists and is open
 	var auctionStatus AuctionStatus
@@ -172,18 +219,21 @@ func (s *EconomicService) SubmitBid(ctx context.Context, auctionID uuid.UUID, ag
 		EstimatedTimeSec: estimatedTimeSec,
 		ReputationScore: reputationScore,
 		CompositeScore:  &compositeScore,
+		SubmittedBy:     origin.SubmittedBy,
+		SourceIP:        origin.SourceIP,
 		CreatedAt:       time.Now(),
 	}
 
 	query := `
 		INSERT INTO bids (
 			id, auction_id, agent_did, price, estimated_time_seconds,
-			reputation_score, composite_score, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			reputation_score, composite_score, submitted_by, source_ip, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10)
 	`
 	_, err = s.db.Conn().ExecContext(ctx, query,
 		bid.ID, bid.AuctionID, bid.AgentDID, bid.Price,
-		bid.EstimatedTimeSec, bid.ReputationScore, bid.CompositeScore, bid.CreatedAt,
+		bid.EstimatedTimeSec, bid.ReputationScore, bid.CompositeScore,
+		bid.SubmittedBy, bid.SourceIP, bid.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to submit bid: %w", err)
@@ -283,6 +333,16 @@ func (s *EconomicService) OpenPaymentChannel(ctx context.Context, payerDID, paye
 		return nil, fmt.Errorf("failed to create payment channel: %w", err)
 	}
 
+	// Journal the deposit moving from the payer into the channel
+	channelID := channel.ID.String()
+	_, err = s.journal().Transfer(ctx, "channel-open:"+channelID,
+		ledger.UserAccount(payerDID), ledger.ChannelAccount(channelID),
+		ledger.FromFloat(initialDeposit), ledger.DescChannelDeposit, channelID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to journal channel deposit: %w", err)
+	}
+
 	return channel, nil
 }
 
@@ -301,6 +361,15 @@ func (s *EconomicService) SettlePaymentChannel(ctx context.Context, channelID uu
 	if channel.State == "closed" {
 		return errors.New("channel is already closed")
 	}
+	if finalAmount > channel.TotalDeposit {
+		return errors.New("final amount exceeds channel deposit")
+	}
+
+	// Journal first: the entries are idempotent, so a settlement retried
+	// after a failed update is not posted twice
+	if err := s.journalSettlement(ctx, channel, finalAmount); err != nil {
+		return err
+	}
 
 	// Update channel state to settling, then closed
 	query := `
@@ -336,6 +405,34 @@ func (s *EconomicService) SettlePaymentChannel(ctx context.Context, channelID uu
 	return nil
 }
 
+// journalSettlement pays the settled amount from the channel to the payee and
+// returns the rest of the deposit to the payer
+func (s *EconomicService) journalSettlement(ctx context.Context, channel *database.PaymentChannel, finalAmount float64) error {
+	channelID := channel.ID.String()
+	journal := s.journal()
+
+	if finalAmount > 0 {
+		_, err := journal.Transfer(ctx, "channel-settle:"+channelID,
+			ledger.ChannelAccount(channelID), ledger.UserAccount(channel.PayeeDID),
+			ledger.FromFloat(finalAmount), ledger.DescChannelRelease, channelID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to journal channel settlement: %w", err)
+		}
+	}
+
+	if refund := channel.TotalDeposit - finalAmount; refund > 0 {
+		_, err := journal.Transfer(ctx, "channel-close:"+channelID,
+			ledger.ChannelAccount(channelID), ledger.UserAccount(channel.PayerDID),
+			ledger.FromFloat(refund), ledger.DescChannelClose, channelID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to journal channel close: %w", err)
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // REPUTATION SERVICE METHODS
 // ============================================================================
@@ -0,0 +1,293 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aidenlippert/zerostate/libs/validation"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Typed calling convention (v1). Instead of numeric arguments, a typed
+// function takes one encoded payload in linear memory and leaves one encoded
+// payload behind. The guest exports:
+//
+//	alloc(size) -> ptr                  or alloc_memory
+//	free(ptr, size)                     or dealloc_memory; optional
+//	<function>(ptr, len) -> status      0 on success
+//	get_result_ptr() -> ptr
+//	get_result_len() -> len
+//
+// The host allocates and writes the input, calls the function, frees the
+// input and reads the result buffer, which the guest owns. A non-zero status
+// means the result holds an error payload, ideally {"error": "..."}.
+
+// Payload encodings for typed functions
+const (
+	EncodingJSON    = "json"
+	EncodingMsgPack = "msgpack"
+	EncodingString  = "string" // Raw UTF-8 text
+)
+
+var (
+	// ErrSchemaViolation is returned when a payload doesn't match the
+	// function's declared schema
+	ErrSchemaViolation = errors.New("payload violates function schema")
+
+	// ErrBadCallingConvention is returned when a module lacks the exports the
+	// typed calling convention needs
+	ErrBadCallingConvention = errors.New("module does not follow the typed calling convention")
+)
+
+// FunctionSpec declares a typed function: how its payloads are encoded and
+// the JSON Schemas they must satisfy. Agents publish these in their card.
+type FunctionSpec struct {
+	Name         string                 `json:"name"`
+	Encoding     string                 `json:"encoding,omitempty"` // json (default), msgpack or string
+	InputSchema  map[string]interface{} `json:"inputSchema,omitempty"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+}
+
+// FunctionError is a failure reported by the guest through a non-zero status
+type FunctionError struct {
+	Function string
+	Status   int32
+	Message  string
+}
+
+func (e *FunctionError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.Function, e.Status, e.Message)
+}
+
+// Validate checks that the spec is usable
+func (s FunctionSpec) Validate() error {
+	if s.Name == "" {
+		return errors.New("function name is required")
+	}
+	switch s.Encoding {
+	case "", EncodingJSON, EncodingMsgPack, EncodingString:
+		return nil
+	}
+	return fmt.Errorf("unsupported encoding %q", s.Encoding)
+}
+
+// callTyped calls spec.Name in module with input, a JSON document, and
+// returns the decoded, validated output
+func callTyped(ctx context.Context, module api.Module, spec FunctionSpec, input json.RawMessage) (interface{}, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &value); err != nil {
+			return nil, fmt.Errorf("invalid input JSON: %w", err)
+		}
+	}
+	if spec.InputSchema != nil {
+		if errs := validation.ValidateJSONSchema(spec.InputSchema, value, "$"); len(errs) > 0 {
+			return nil, fmt.Errorf("%w: input %s", ErrSchemaViolation, strings.Join(errs, "; "))
+		}
+	}
+
+	payload, err := encodePayload(spec.Encoding, input, value)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := module.ExportedFunction(spec.Name)
+	if fn == nil {
+		return nil, fmt.Errorf("function not found: %s", spec.Name)
+	}
+	def := fn.Definition()
+	if len(def.ParamTypes()) != 2 || len(def.ResultTypes()) != 1 {
+		return nil, fmt.Errorf("%w: %s must take (ptr, len) and return a status", ErrBadCallingConvention, spec.Name)
+	}
+
+	ptr, err := writeInput(ctx, module, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// A trapped guest isn't asked to free; its instance is discarded anyway
+	results, err := fn.Call(ctx, uint64(ptr), uint64(len(payload)))
+	if err != nil {
+		return nil, err
+	}
+	status := int32(results[0])
+
+	if free := exportedFunction(module, "free", "dealloc_memory"); free != nil && len(payload) > 0 {
+		if _, err := free.Call(ctx, uint64(ptr), uint64(len(payload))); err != nil {
+			return nil, fmt.Errorf("failed to free input: %w", err)
+		}
+	}
+
+	raw, err := readResult(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != 0 {
+		return nil, &FunctionError{
+			Function: spec.Name,
+			Status:   status,
+			Message:  errorMessage(spec.Encoding, raw),
+		}
+	}
+
+	output, err := decodePayload(spec.Encoding, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output from %s: %w", spec.Name, err)
+	}
+	if spec.OutputSchema != nil {
+		if errs := validation.ValidateJSONSchema(spec.OutputSchema, output, "$"); len(errs) > 0 {
+			return nil, fmt.Errorf("%w: output %s", ErrSchemaViolation, strings.Join(errs, "; "))
+		}
+	}
+	return output, nil
+}
+
+// exportedFunction returns the first of names the module exports
+func exportedFunction(module api.Module, names ...string) api.Function {
+	for _, name := range names {
+		if fn := module.ExportedFunction(name); fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
+
+// writeInput copies payload into memory allocated by the guest
+func writeInput(ctx context.Context, module api.Module, payload []byte) (uint32, error) {
+	if len(payload) == 0 {
+		return 0, nil
+	}
+
+	alloc := exportedFunction(module, "alloc", "alloc_memory")
+	if alloc == nil {
+		return 0, fmt.Errorf("%w: no alloc export", ErrBadCallingConvention)
+	}
+	results, err := alloc.Call(ctx, uint64(len(payload)))
+	if err != nil {
+		return 0, fmt.Errorf("guest allocation failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if ptr == 0 || module.Memory() == nil || !module.Memory().Write(ptr, payload) {
+		return 0, fmt.Errorf("guest allocation of %d bytes failed", len(payload))
+	}
+	return ptr, nil
+}
+
+// readResult copies the guest's result buffer
+func readResult(ctx context.Context, module api.Module) ([]byte, error) {
+	getPtr := module.ExportedFunction("get_result_ptr")
+	getLen := module.ExportedFunction("get_result_len")
+	if getPtr == nil || getLen == nil {
+		return nil, fmt.Errorf("%w: no get_result_ptr/get_result_len exports", ErrBadCallingConvention)
+	}
+
+	ptr, err := getPtr.Call(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result pointer: %w", err)
+	}
+	length, err := getLen.Call(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result length: %w", err)
+	}
+	if length[0] == 0 {
+		return nil, nil
+	}
+	if length[0] > maxHostPayload {
+		return nil, fmt.Errorf("result of %d bytes exceeds limit", length[0])
+	}
+
+	data, ok := module.Memory().Read(uint32(ptr[0]), uint32(length[0]))
+	if !ok {
+		return nil, errors.New("result buffer out of bounds")
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// encodePayload converts a JSON input to the function's encoding
+func encodePayload(encoding string, input json.RawMessage, value interface{}) ([]byte, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return input, nil
+	case EncodingMsgPack:
+		if len(input) == 0 {
+			return nil, nil
+		}
+		return msgpack.Marshal(value)
+	case EncodingString:
+		s, ok := value.(string)
+		if !ok && value != nil {
+			return nil, fmt.Errorf("%w: input must be a string", ErrSchemaViolation)
+		}
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", encoding)
+}
+
+// decodePayload converts a guest payload to JSON-compatible values
+func decodePayload(encoding string, raw []byte) (interface{}, error) {
+	if len(raw) == 0 {
+		if encoding == EncodingString {
+			return "", nil
+		}
+		return nil, nil
+	}
+
+	var value interface{}
+	switch encoding {
+	case "", EncodingJSON:
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case EncodingMsgPack:
+		if err := msgpack.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		// Normalize numbers and maps to what encoding/json produces so
+		// schemas and callers see one representation
+		normalized, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		value = nil
+		if err := json.Unmarshal(normalized, &value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case EncodingString:
+		if !utf8.Valid(raw) {
+			return nil, errors.New("output is not valid UTF-8")
+		}
+		return string(raw), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", encoding)
+}
+
+// errorMessage extracts a readable message from an error payload
+func errorMessage(encoding string, raw []byte) string {
+	value, err := decodePayload(encoding, raw)
+	if err != nil {
+		return string(raw)
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if msg, ok := v["error"].(string); ok {
+			return msg
+		}
+	case string:
+		return v
+	case nil:
+		return "no error detail"
+	}
+	return string(raw)
+}
@@ -0,0 +1,140 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// typedGuestWASM assembles a guest following the typed calling convention.
+// alloc always returns offset 1024, "echo" returns its input as the result
+// and "fail" returns it as an error payload with status 1.
+func typedGuestWASM() []byte {
+	types := []byte{
+		0x60, 0x01, 0x7f, 0x01, 0x7f, // 0: (i32) -> i32
+		0x60, 0x02, 0x7f, 0x7f, 0x00, // 1: (i32, i32) -> ()
+		0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, // 2: (i32, i32) -> i32
+		0x60, 0x00, 0x01, 0x7f, // 3: () -> i32
+	}
+	funcs := []byte{0x00, 0x01, 0x02, 0x02, 0x03, 0x03}
+
+	// Two mutable i32 globals holding the result pointer and length
+	globals := []byte{0x7f, 0x01, 0x41, 0x00, 0x0b, 0x7f, 0x01, 0x41, 0x00, 0x0b}
+
+	var exports []byte
+	exports = append(exports, appendName(nil, "memory")...)
+	exports = append(exports, 0x02, 0x00)
+	for i, name := range []string{"alloc", "free", "echo", "fail", "get_result_ptr", "get_result_len"} {
+		exports = append(exports, appendName(nil, name)...)
+		exports = append(exports, 0x00, byte(i))
+	}
+
+	bodies := [][]byte{
+		{0x00, 0x41, 0x80, 0x08, 0x0b}, // i32.const 1024
+		{0x00, 0x0b},
+		{0x00, 0x20, 0x00, 0x24, 0x00, 0x20, 0x01, 0x24, 0x01, 0x41, 0x00, 0x0b}, // store ptr/len, return 0
+		{0x00, 0x20, 0x00, 0x24, 0x00, 0x20, 0x01, 0x24, 0x01, 0x41, 0x01, 0x0b}, // store ptr/len, return 1
+		{0x00, 0x23, 0x00, 0x0b}, // global.get 0
+		{0x00, 0x23, 0x01, 0x0b}, // global.get 1
+	}
+	var code []byte
+	for _, body := range bodies {
+		code = append(code, appendU32(nil, uint32(len(body)))...)
+		code = append(code, body...)
+	}
+
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, encodeSection(0x01, 4, types)...)
+	wasm = append(wasm, encodeSection(0x03, len(funcs), funcs)...)
+	wasm = append(wasm, encodeSection(0x05, 1, []byte{0x00, 0x01})...)
+	wasm = append(wasm, encodeSection(0x06, 2, globals)...)
+	wasm = append(wasm, encodeSection(0x07, 7, exports)...)
+	wasm = append(wasm, encodeSection(0x0a, len(bodies), code)...)
+	return wasm
+}
+
+func instantiateTyped(t *testing.T, binary []byte) api.Module {
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	t.Cleanup(func() { rt.Close(ctx) })
+
+	mod, err := rt.InstantiateWithConfig(ctx, binary, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	return mod
+}
+
+func TestTypedCallEncodings(t *testing.T) {
+	ctx := context.Background()
+	mod := instantiateTyped(t, typedGuestWASM())
+
+	input := json.RawMessage(`{"name":"alice","tags":["a","b"],"age":30}`)
+	for _, encoding := range []string{"", EncodingJSON, EncodingMsgPack} {
+		output, err := callTyped(ctx, mod, FunctionSpec{Name: "echo", Encoding: encoding}, input)
+		require.NoError(t, err, encoding)
+
+		encoded, err := json.Marshal(output)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(input), string(encoded), encoding)
+	}
+
+	output, err := callTyped(ctx, mod, FunctionSpec{Name: "echo", Encoding: EncodingString}, json.RawMessage(`"héllo wörld"`))
+	require.NoError(t, err)
+	assert.Equal(t, "héllo wörld", output)
+
+	_, err = callTyped(ctx, mod, FunctionSpec{Name: "echo", Encoding: EncodingString}, json.RawMessage(`{"not":"a string"}`))
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+}
+
+func TestTypedCallValidatesSchemas(t *testing.T) {
+	ctx := context.Background()
+	mod := instantiateTyped(t, typedGuestWASM())
+
+	spec := FunctionSpec{
+		Name: "echo",
+		InputSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"n"},
+			"properties": map[string]interface{}{
+				"n": map[string]interface{}{"type": "integer", "minimum": float64(0)},
+			},
+		},
+	}
+
+	output, err := callTyped(ctx, mod, spec, json.RawMessage(`{"n":3}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"n": float64(3)}, output)
+
+	_, err = callTyped(ctx, mod, spec, json.RawMessage(`{"n":-1}`))
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+	assert.Contains(t, err.Error(), "$.n")
+
+	// Echo hands back an object where the declared output is a string
+	spec.OutputSchema = map[string]interface{}{"type": "string"}
+	_, err = callTyped(ctx, mod, spec, json.RawMessage(`{"n":3}`))
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+	assert.Contains(t, err.Error(), "output")
+}
+
+func TestTypedCallReportsGuestErrors(t *testing.T) {
+	ctx := context.Background()
+	mod := instantiateTyped(t, typedGuestWASM())
+
+	_, err := callTyped(ctx, mod, FunctionSpec{Name: "fail"}, json.RawMessage(`{"error":"boom"}`))
+	var fnErr *FunctionError
+	require.True(t, errors.As(err, &fnErr))
+	assert.Equal(t, int32(1), fnErr.Status)
+	assert.Equal(t, "boom", fnErr.Message)
+
+	// Numeric-only modules don't follow the convention
+	_, err = callTyped(ctx, instantiateTyped(t, spinWASM), FunctionSpec{Name: "spin"}, json.RawMessage(`{}`))
+	assert.True(t, errors.Is(err, ErrBadCallingConvention))
+
+	_, err = callTyped(ctx, mod, FunctionSpec{Name: "echo", Encoding: "xml"}, json.RawMessage(`{}`))
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 type S3BinaryStore struct {
 	storage S3Storage
 	db      AgentDatabase
+	cache   *BinaryCache // Optional: local copies of downloaded binaries
 }
 
 // S3Storage interface that S3Storage implements
@@ -35,6 +36,12 @@ func NewS3BinaryStore(storage S3Storage, db AgentDatabase) *S3BinaryStore {
 	}
 }
 
+// SetBinaryCache serves repeat downloads from a local cache. Keys embed the
+// binary hash, so a re-uploaded agent never hits a stale entry.
+func (s *S3BinaryStore) SetBinaryCache(cache *BinaryCache) {
+	s.cache = cache
+}
+
 // GetBinary implements BinaryStore interface
 func (s *S3BinaryStore) GetBinary(ctx context.Context, agentID string) ([]byte, error) {
 	// Look up agent to get binary URL/key
@@ -51,8 +58,14 @@ func (s *S3BinaryStore) GetBinary(ctx context.Context, agentID string) ([]byte,
 	// or agents/{agentID}/{hash}.wasm for S3 key
 	key := fmt.Sprintf("agents/%s/%s.wasm", agentID, agent.BinaryHash)
 
-	// Download from S3
-	binary, err := s.storage.Download(ctx, key)
+	// Download from S3 (or the local cache)
+	download := s.storage.Download
+	if s.cache != nil {
+		download = func(ctx context.Context, key string) ([]byte, error) {
+			return s.cache.Fetch(ctx, key, s.storage.Download)
+		}
+	}
+	binary, err := download(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download binary: %w", err)
 	}
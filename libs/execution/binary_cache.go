@@ -0,0 +1,241 @@
+package execution
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DefaultMaxCachedBinaryBytes bounds the in-memory binary cache (256MB)
+const DefaultMaxCachedBinaryBytes = 256 << 20
+
+// BinaryCacheConfig configures a BinaryCache
+type BinaryCacheConfig struct {
+	// MaxBytes bounds the binaries kept in memory
+	MaxBytes int64
+
+	// Dir keeps downloaded binaries on local disk. Empty disables it.
+	Dir string
+}
+
+// BinaryCacheStats reports cache effectiveness
+type BinaryCacheStats struct {
+	Hits      int64 `json:"hits"`
+	DiskHits  int64 `json:"disk_hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Binaries  int   `json:"binaries"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// BinaryCache keeps downloaded WASM binaries in front of R2/S3. Keys are
+// storage keys and must be immutable; agent binaries are stored under their
+// content hash, so a re-upload gets a new key. It is safe for concurrent use.
+type BinaryCache struct {
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List // Front is most recently used
+	bytes    int64
+	maxBytes int64
+	dir      string
+	inflight map[string]*binaryFetch
+	stats    BinaryCacheStats
+
+	logger *zap.Logger
+}
+
+type binaryEntry struct {
+	key    string
+	binary []byte
+}
+
+// binaryFetch is a download shared by concurrent misses on one key
+type binaryFetch struct {
+	done   chan struct{}
+	binary []byte
+	err    error
+}
+
+// NewBinaryCache creates a binary cache
+func NewBinaryCache(cfg BinaryCacheConfig, logger *zap.Logger) (*BinaryCache, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxCachedBinaryBytes
+	}
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create binary cache dir: %w", err)
+		}
+	}
+
+	return &BinaryCache{
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		maxBytes: cfg.MaxBytes,
+		dir:      cfg.Dir,
+		inflight: make(map[string]*binaryFetch),
+		logger:   logger,
+	}, nil
+}
+
+// Get returns a cached binary, checking memory and then disk
+func (c *BinaryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		c.stats.Hits++
+		binary := elem.Value.(*binaryEntry).binary
+		c.mu.Unlock()
+		return binary, true
+	}
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil, false
+	}
+	binary, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	c.stats.DiskHits++
+	c.putLocked(key, binary)
+	c.mu.Unlock()
+	return binary, true
+}
+
+// Put caches a binary under its storage key
+func (c *BinaryCache) Put(key string, binary []byte) {
+	c.mu.Lock()
+	c.putLocked(key, binary)
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return
+	}
+	if err := writeFileAtomic(c.path(key), binary); err != nil {
+		c.logger.Warn("failed to persist cached binary",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// Fetch returns the binary for key, calling fetch on a miss. Concurrent
+// misses on the same key share one download.
+func (c *BinaryCache) Fetch(ctx context.Context, key string, fetch func(ctx context.Context, key string) ([]byte, error)) ([]byte, error) {
+	if binary, ok := c.Get(key); ok {
+		return binary, nil
+	}
+
+	c.mu.Lock()
+	if f, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.binary, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &binaryFetch{done: make(chan struct{})}
+	c.inflight[key] = f
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	f.binary, f.err = fetch(ctx, key)
+	if f.err == nil {
+		c.Put(key, f.binary)
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(f.done)
+
+	return f.binary, f.err
+}
+
+// Invalidate drops a key from memory and disk
+func (c *BinaryCache) Invalidate(key string) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	c.mu.Unlock()
+
+	if c.dir != "" {
+		os.Remove(c.path(key))
+	}
+}
+
+// Stats returns cache counters
+func (c *BinaryCache) Stats() BinaryCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Binaries = c.lru.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// putLocked stores a binary in memory and evicts down to the byte limit.
+// Binaries larger than the limit are only kept on disk.
+func (c *BinaryCache) putLocked(key string, binary []byte) {
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	if int64(len(binary)) > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&binaryEntry{key: key, binary: binary})
+	c.bytes += int64(len(binary))
+
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *BinaryCache) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*binaryEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.binary))
+}
+
+// path maps a storage key to a flat file name under the cache dir
+func (c *BinaryCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".wasm")
+}
+
+// writeFileAtomic writes through a temp file so readers never see a partial binary
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -3,6 +3,7 @@ package execution
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/economic"
@@ -17,6 +18,8 @@ type EconomicExecutor struct {
 	binaryStore   BinaryStore
 	escrowService *economic.EscrowService
 	metrics       *EconomicTaskMetrics
+	fuelPerUnit   uint64        // Fuel bought by one unit of budget; 0 disables metering
+	artifacts     ArtifactStore // Optional: stages input and stores output artifacts
 	logger        *zap.Logger
 }
 
@@ -29,6 +32,7 @@ type EconomicExecutionRequest struct {
 	Budget          float64                        `json:"budget"`
 	EscrowID        uuid.UUID                      `json:"escrow_id"`
 	Timeout         time.Duration                  `json:"timeout"`
+	InputArtifacts  []InputArtifact                `json:"input_artifacts,omitempty"` // Mounted read-only under /input
 }
 
 // EconomicExecutionResult extends execution result with economic metadata
@@ -40,6 +44,7 @@ type EconomicExecutionResult struct {
 	Error           string                 `json:"error,omitempty"`
 	ExecutionTime   time.Duration          `json:"execution_time"`
 	ResourceUsage   *ResourceUsage         `json:"resource_usage"`
+	Artifacts       []Artifact             `json:"artifacts,omitempty"`
 
 	// Economic metadata
 	EscrowID        uuid.UUID              `json:"escrow_id"`
@@ -56,6 +61,7 @@ type ResourceUsage struct {
 	CPUTimeMs        uint64        `json:"cpu_time_ms"`
 	ExecutionTimeMs  uint64        `json:"execution_time_ms"`
 	StorageUsedKB    uint64        `json:"storage_used_kb"`
+	FuelUsed         uint64        `json:"fuel_used"`
 }
 
 // NewEconomicExecutor creates a new economic executor instance
@@ -77,10 +83,23 @@ func NewEconomicExecutor(
 		binaryStore:   binaryStore,
 		escrowService: escrowService,
 		metrics:       metrics,
+		fuelPerUnit:   DefaultFuelPerUnit,
 		logger:        logger,
 	}
 }
 
+// SetFuelPerUnit sets how much fuel one unit of budget buys. Metered tasks
+// pay for the fuel they use instead of their whole budget; 0 disables metering.
+func (e *EconomicExecutor) SetFuelPerUnit(fuelPerUnit uint64) {
+	e.fuelPerUnit = fuelPerUnit
+}
+
+// SetArtifactStore sets where input artifacts are read from and output
+// artifacts are uploaded to
+func (e *EconomicExecutor) SetArtifactStore(store ArtifactStore) {
+	e.artifacts = store
+}
+
 // ExecuteWithEconomics executes a task with full economic integration
 func (e *EconomicExecutor) ExecuteWithEconomics(ctx context.Context, req *EconomicExecutionRequest) (*EconomicExecutionResult, error) {
 	startTime := time.Now()
@@ -213,16 +232,36 @@ func (e *EconomicExecutor) executeTask(ctx context.Context, req *EconomicExecuti
 		return nil, fmt.Errorf("failed to get agent binary: %w", err)
 	}
 
+	// Give the run a private filesystem, wiped when it ends
+	taskFS, err := NewTaskFS(req.TaskID.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer taskFS.Close()
+	if err := taskFS.Stage(ctx, req.InputArtifacts, e.artifacts, nil); err != nil {
+		return nil, err
+	}
+
 	// Prepare execution context with timeout
-	execCtx, cancel := context.WithTimeout(ctx, req.Timeout)
+	execCtx, cancel := context.WithTimeout(WithTaskFS(ctx, taskFS), req.Timeout)
 	defer cancel()
 
-	// Execute WASM
-	result, err := e.wasmRunner.Execute(execCtx, wasmBinary, []byte(req.Input))
+	// Execute WASM, metered against the task budget
+	limits := ResourceLimits{
+		Timeout: req.Timeout,
+		MaxFuel: FuelBudget(req.Budget, e.fuelPerUnit),
+	}
+	result, err := e.wasmRunner.ExecuteWithLimits(execCtx, wasmBinary, []byte(req.Input), limits)
 	if err != nil {
 		return nil, fmt.Errorf("WASM execution failed: %w", err)
 	}
 
+	result.StorageUsed = taskFS.Used()
+	result.Artifacts, err = taskFS.Collect(ctx, e.artifacts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Store result in database
 	if e.resultStore != nil {
 		taskResult := &TaskResult{
@@ -232,6 +271,8 @@ func (e *EconomicExecutor) executeTask(ctx context.Context, req *EconomicExecuti
 			Stdout:   result.Stdout,
 			Stderr:   result.Stderr,
 			Duration: result.Duration,
+			FuelUsed: result.FuelConsumed,
+			Artifacts: result.Artifacts,
 			Error:    "",
 			CreatedAt: time.Now(),
 		}
@@ -279,8 +320,18 @@ func (e *EconomicExecutor) handleExecutionSuccess(
 	paymentMethod = "escrow"
 	amountPaid = actualCost
 
+	// Metered tasks pay for the fuel they used and get the rest back
+	settle := func() error {
+		return e.escrowService.ReleaseEscrow(ctx, req.EscrowID, req.UserID.String())
+	}
+	if resourceUsage.FuelUsed > 0 {
+		settle = func() error {
+			return e.escrowService.SettleEscrow(ctx, req.EscrowID, amountPaid, "system")
+		}
+	}
+
 	settlementStart := time.Now()
-	if err := e.escrowService.ReleaseEscrow(ctx, req.EscrowID, req.UserID.String()); err != nil {
+	if err := settle(); err != nil {
 		// Record failed settlement
 		if e.metrics != nil {
 			e.metrics.RecordSettlementError("release_failed")
@@ -315,6 +366,7 @@ func (e *EconomicExecutor) handleExecutionSuccess(
 		Output:          string(execResult.Stdout),
 		ExecutionTime:   time.Since(startTime),
 		ResourceUsage:   resourceUsage,
+		Artifacts:       execResult.Artifacts,
 		EscrowID:        req.EscrowID,
 		EscrowStatus:    escrowStatus,
 		AmountPaid:      amountPaid,
@@ -364,13 +416,19 @@ func (e *EconomicExecutor) calculateResourceUsage(result *WASMResult) *ResourceU
 		MemoryUsedMB:    0, // TODO: Extract from WASM runtime metrics
 		CPUTimeMs:       uint64(result.Duration.Milliseconds()),
 		ExecutionTimeMs: uint64(result.Duration.Milliseconds()),
-		StorageUsedKB:   0, // TODO: Calculate storage usage
+		StorageUsedKB:   uint64(result.StorageUsed) / 1024,
+		FuelUsed:        result.FuelConsumed,
 	}
 }
 
 // calculateActualCost calculates actual cost based on resource usage
 func (e *EconomicExecutor) calculateActualCost(budgetedCost float64, usage *ResourceUsage) float64 {
-	// For now, use budgeted cost
+	// Metered tasks are priced by fuel, never above budget
+	if usage.FuelUsed > 0 && e.fuelPerUnit > 0 {
+		return math.Min(budgetedCost, FuelCost(usage.FuelUsed, e.fuelPerUnit))
+	}
+
+	// Otherwise use budgeted cost
 	// TODO: Implement dynamic pricing based on actual resource consumption
 	// Formula: base_cost + (memory_cost * MB) + (cpu_cost * ms) + (storage_cost * KB)
 	return budgetedCost
@@ -419,6 +477,12 @@ func (e *EconomicExecutor) GetExecutionReceipt(ctx context.Context, taskID uuid.
 		"execution_time": result.Duration.String(),
 		"created_at":     result.CreatedAt,
 	}
+	if result.FuelUsed > 0 {
+		receipt["fuel_used"] = result.FuelUsed
+	}
+	if len(result.Artifacts) > 0 {
+		receipt["artifacts"] = result.Artifacts
+	}
 
 	// Add output or error
 	if result.ExitCode == 0 {
@@ -0,0 +1,723 @@
+package execution
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Fuel metering
+//
+// InstrumentFuel rewrites a module so every basic block charges its
+// instruction count against an exported i64 global before it runs, and traps
+// once the global drops below zero. The charge depends only on the code that
+// runs, never on the host, so the same task with the same input always
+// consumes the same fuel.
+const (
+	// FuelExport is the global an instrumented module exposes its remaining fuel through
+	FuelExport = "zerostate_fuel"
+
+	// DefaultFuelPerUnit is how much fuel one unit of task budget buys
+	DefaultFuelPerUnit = 10_000_000
+)
+
+// ErrOutOfFuel is returned when a module exhausts its fuel budget
+var ErrOutOfFuel = errors.New("out of fuel")
+
+// ErrInterrupted is returned when a call is stopped because its context was
+// canceled or timed out. The error also wraps the context's error.
+var ErrInterrupted = errors.New("execution interrupted")
+
+// FuelBudget converts a task budget into a fuel limit. A non-positive budget
+// leaves the task unmetered.
+func FuelBudget(budget float64, fuelPerUnit uint64) uint64 {
+	if budget <= 0 || fuelPerUnit == 0 {
+		return 0
+	}
+	fuel := budget * float64(fuelPerUnit)
+	if fuel >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return uint64(fuel)
+}
+
+// FuelCost converts consumed fuel back into budget units
+func FuelCost(fuelUsed, fuelPerUnit uint64) float64 {
+	if fuelPerUnit == 0 {
+		return 0
+	}
+	return float64(fuelUsed) / float64(fuelPerUnit)
+}
+
+// Section IDs touched by instrumentation
+const (
+	sectionCustom byte = 0
+	sectionImport byte = 2
+	sectionGlobal byte = 6
+	sectionExport byte = 7
+	sectionCode   byte = 10
+)
+
+type wasmSection struct {
+	id      byte
+	payload []byte
+}
+
+// InstrumentFuel returns a copy of a WASM binary with fuel metering added.
+// The fuel global starts at math.MaxInt64; set the budget after
+// instantiation with instantiateMetered.
+func InstrumentFuel(binary []byte) ([]byte, error) {
+	if len(binary) < 8 || !bytes.Equal(binary[:4], []byte("\x00asm")) {
+		return nil, fmt.Errorf("invalid WASM: bad magic number")
+	}
+
+	var sections []wasmSection
+	r := &wasmReader{buf: binary, pos: 8}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, wasmSection{id: id, payload: payload})
+	}
+
+	// The fuel global goes after every imported and defined global, so no
+	// existing index moves
+	var fuelGlobal uint32
+	for _, s := range sections {
+		switch s.id {
+		case sectionImport:
+			n, err := countImportedGlobals(s.payload)
+			if err != nil {
+				return nil, fmt.Errorf("invalid import section: %w", err)
+			}
+			fuelGlobal += n
+		case sectionGlobal:
+			n, err := (&wasmReader{buf: s.payload}).u32()
+			if err != nil {
+				return nil, fmt.Errorf("invalid global section: %w", err)
+			}
+			fuelGlobal += n
+		}
+	}
+
+	global := []byte{0x7e, 0x01, 0x42} // mut i64, init i64.const
+	global = appendS64(global, math.MaxInt64)
+	global = append(global, 0x0b)
+
+	export := appendName(nil, FuelExport)
+	export = append(export, 0x03) // global
+	export = appendU32(export, fuelGlobal)
+
+	var err error
+	if sections, err = mergeVecSection(sections, sectionGlobal, global, nil); err != nil {
+		return nil, err
+	}
+	if sections, err = mergeVecSection(sections, sectionExport, export, checkExportName); err != nil {
+		return nil, err
+	}
+
+	for i, s := range sections {
+		if s.id != sectionCode {
+			continue
+		}
+		payload, err := instrumentCode(s.payload, fuelGlobal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to instrument code: %w", err)
+		}
+		sections[i].payload = payload
+	}
+
+	out := append([]byte{}, binary[:8]...)
+	for _, s := range sections {
+		out = append(out, s.id)
+		out = appendU32(out, uint32(len(s.payload)))
+		out = append(out, s.payload...)
+	}
+	return out, nil
+}
+
+// sectionRank orders non-custom sections as the spec requires
+func sectionRank(id byte) int {
+	switch id {
+	case 12: // data count
+		return 95
+	case 13: // tag
+		return 55
+	default:
+		return int(id) * 10
+	}
+}
+
+// mergeVecSection appends one entry to a vector section, creating the
+// section in spec order if the module has none
+func mergeVecSection(sections []wasmSection, id byte, entry []byte, check func(payload []byte) error) ([]wasmSection, error) {
+	for i, s := range sections {
+		if s.id != id {
+			continue
+		}
+		if check != nil {
+			if err := check(s.payload); err != nil {
+				return nil, err
+			}
+		}
+		r := &wasmReader{buf: s.payload}
+		n, err := r.u32()
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %d: %w", id, err)
+		}
+		payload := appendU32(nil, n+1)
+		payload = append(payload, s.payload[r.pos:]...)
+		sections[i].payload = append(payload, entry...)
+		return sections, nil
+	}
+
+	section := wasmSection{id: id, payload: append(appendU32(nil, 1), entry...)}
+	for i, s := range sections {
+		if s.id != sectionCustom && sectionRank(s.id) > sectionRank(id) {
+			return append(sections[:i], append([]wasmSection{section}, sections[i:]...)...), nil
+		}
+	}
+	return append(sections, section), nil
+}
+
+func countImportedGlobals(payload []byte) (uint32, error) {
+	r := &wasmReader{buf: payload}
+	n, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+
+	var globals uint32
+	for i := uint32(0); i < n; i++ {
+		if err := r.skipName(); err != nil {
+			return 0, err
+		}
+		if err := r.skipName(); err != nil {
+			return 0, err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		switch kind {
+		case 0x00: // func
+			_, err = r.u32()
+		case 0x01: // table
+			if _, err = r.byte(); err == nil {
+				err = r.skipLimits()
+			}
+		case 0x02: // memory
+			err = r.skipLimits()
+		case 0x03: // global
+			globals++
+			_, err = r.bytes(2)
+		default:
+			err = fmt.Errorf("unknown import kind 0x%02x", kind)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return globals, nil
+}
+
+func checkExportName(payload []byte) error {
+	r := &wasmReader{buf: payload}
+	n, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		if name == FuelExport {
+			return fmt.Errorf("module already exports %q", FuelExport)
+		}
+		if _, err := r.byte(); err != nil {
+			return err
+		}
+		if _, err := r.u32(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func instrumentCode(payload []byte, fuelGlobal uint32) ([]byte, error) {
+	r := &wasmReader{buf: payload}
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+
+	out := appendU32(nil, n)
+	for i := uint32(0); i < n; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		instrumented, err := instrumentBody(body, fuelGlobal)
+		if err != nil {
+			return nil, fmt.Errorf("function %d: %w", i, err)
+		}
+		out = appendU32(out, uint32(len(instrumented)))
+		out = append(out, instrumented...)
+	}
+	return out, nil
+}
+
+// meterPoint is where a charge is inserted and how much it charges
+type meterPoint struct {
+	pos  int
+	cost int64
+}
+
+// instrumentBody charges each straight-line segment up front. A segment
+// starts at function entry and after every block, loop, if, else, br_if and
+// nested end, so every loop iteration and every branch pays for what it
+// runs. Instructions skipped by an early br are still charged, which keeps
+// the count static.
+func instrumentBody(body []byte, fuelGlobal uint32) ([]byte, error) {
+	r := &wasmReader{buf: body}
+
+	// Locals are copied verbatim
+	groups, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < groups; i++ {
+		if _, err := r.u32(); err != nil {
+			return nil, err
+		}
+		if _, err := r.byte(); err != nil {
+			return nil, err
+		}
+	}
+	codeStart := r.pos
+
+	points := []meterPoint{{pos: codeStart}}
+	depth := 1
+	for depth > 0 {
+		if r.done() {
+			return nil, fmt.Errorf("unexpected end of function body")
+		}
+		op, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		points[len(points)-1].cost++
+
+		split := false
+		switch op {
+		case 0x02, 0x03, 0x04: // block, loop, if
+			depth++
+			split = true
+		case 0x05, 0x0d: // else, br_if
+			split = true
+		case 0x0b: // end
+			depth--
+			split = depth > 0
+		}
+		if err := r.skipImmediates(op); err != nil {
+			return nil, err
+		}
+		if split {
+			points = append(points, meterPoint{pos: r.pos})
+		}
+	}
+	if !r.done() {
+		return nil, fmt.Errorf("trailing bytes after function body")
+	}
+
+	out := append([]byte{}, body[:codeStart]...)
+	prev := codeStart
+	for _, p := range points {
+		out = append(out, body[prev:p.pos]...)
+		if p.cost > 0 {
+			out = appendCharge(out, fuelGlobal, p.cost)
+		}
+		prev = p.pos
+	}
+	return append(out, body[prev:]...), nil
+}
+
+// appendCharge emits: fuel -= cost; if fuel < 0 { unreachable }
+func appendCharge(out []byte, fuelGlobal uint32, cost int64) []byte {
+	out = append(out, 0x23) // global.get
+	out = appendU32(out, fuelGlobal)
+	out = append(out, 0x42) // i64.const
+	out = appendS64(out, cost)
+	out = append(out, 0x7d, 0x24) // i64.sub, global.set
+	out = appendU32(out, fuelGlobal)
+	out = append(out, 0x23) // global.get
+	out = appendU32(out, fuelGlobal)
+	out = append(out, 0x42, 0x00, 0x53)        // i64.const 0, i64.lt_s
+	return append(out, 0x04, 0x40, 0x00, 0x0b) // if, unreachable, end
+}
+
+// fuelMeter tracks the fuel of an instrumented module instance
+type fuelMeter struct {
+	global api.MutableGlobal
+	budget uint64
+}
+
+// instantiateMetered instantiates an instrumented module with the given fuel
+// budget, then runs its start functions under that budget. Fuel spent by the
+// module's start section counts against the budget too.
+func instantiateMetered(ctx context.Context, rt wazero.Runtime, compiled wazero.CompiledModule, config wazero.ModuleConfig, budget uint64, startFunctions ...string) (api.Module, *fuelMeter, error) {
+	module, err := rt.InstantiateModule(ctx, compiled, config.WithStartFunctions())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	global, ok := module.ExportedGlobal(FuelExport).(api.MutableGlobal)
+	if !ok {
+		module.Close(ctx)
+		return nil, nil, fmt.Errorf("module is not instrumented for fuel")
+	}
+	if budget > math.MaxInt64 {
+		budget = math.MaxInt64
+	}
+	meter := &fuelMeter{global: global, budget: budget}
+
+	startUsed := uint64(math.MaxInt64 - int64(global.Get()))
+	if startUsed > budget {
+		global.Set(api.EncodeI64(-1))
+		module.Close(ctx)
+		return nil, meter, meter.err(nil)
+	}
+	global.Set(budget - startUsed)
+
+	for _, name := range startFunctions {
+		fn := module.ExportedFunction(name)
+		if fn == nil {
+			continue
+		}
+		if _, err := fn.Call(ctx); err != nil {
+			var exitErr *sys.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+				// proc_exit(0) is a normal return
+				break
+			}
+			module.Close(ctx)
+			return nil, meter, meter.err(err)
+		}
+	}
+	return module, meter, nil
+}
+
+// remaining returns the fuel left; negative once exhausted
+func (m *fuelMeter) remaining() int64 {
+	return int64(m.global.Get())
+}
+
+// Used returns the fuel consumed so far, capped at the budget
+func (m *fuelMeter) Used() uint64 {
+	if m == nil {
+		return 0
+	}
+	remaining := m.remaining()
+	if remaining < 0 {
+		return m.budget
+	}
+	return m.budget - uint64(remaining)
+}
+
+// err attributes a trap to fuel exhaustion when the budget ran out
+func (m *fuelMeter) err(trap error) error {
+	if m.remaining() >= 0 {
+		return trap
+	}
+	if trap == nil {
+		return fmt.Errorf("%w: budget of %d exhausted", ErrOutOfFuel, m.budget)
+	}
+	return fmt.Errorf("%w: budget of %d exhausted (%v)", ErrOutOfFuel, m.budget, trap)
+}
+
+// wasmReader decodes the parts of the binary format instrumentation needs
+type wasmReader struct {
+	buf []byte
+	pos int
+}
+
+var errTruncated = errors.New("unexpected end of WASM binary")
+
+func (r *wasmReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *wasmReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.buf) {
+		return nil, errTruncated
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *wasmReader) u32() (uint32, error) {
+	var result uint32
+	for shift := 0; shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid LEB128 at offset %d", r.pos)
+}
+
+// skipLEB skips a LEB128 number of any width
+func (r *wasmReader) skipLEB() error {
+	for i := 0; i < 10; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b&0x80 == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid LEB128 at offset %d", r.pos)
+}
+
+func (r *wasmReader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	return string(b), err
+}
+
+func (r *wasmReader) skipName() error {
+	_, err := r.name()
+	return err
+}
+
+func (r *wasmReader) skipLimits() error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if err := r.skipLEB(); err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		return r.skipLEB()
+	}
+	return nil
+}
+
+func (r *wasmReader) skipMemarg() error {
+	if err := r.skipLEB(); err != nil {
+		return err
+	}
+	return r.skipLEB()
+}
+
+// skipImmediates skips the immediates that follow an opcode
+func (r *wasmReader) skipImmediates(op byte) error {
+	switch {
+	case op == 0x02, op == 0x03, op == 0x04: // block type
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b == 0x40 || (b >= 0x6f && b <= 0x7f) {
+			return nil
+		}
+		r.pos--
+		return r.skipLEB() // type index as s33
+	case op == 0x0c, op == 0x0d, // br, br_if
+		op >= 0x20 && op <= 0x26, // local.*, global.*, table.get/set
+		op == 0x10, op == 0xd2:   // call, ref.func
+		return r.skipLEB()
+	case op == 0x0e: // br_table
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i <= n; i++ {
+			if err := r.skipLEB(); err != nil {
+				return err
+			}
+		}
+		return nil
+	case op == 0x11: // call_indirect
+		if err := r.skipLEB(); err != nil {
+			return err
+		}
+		return r.skipLEB()
+	case op == 0x1c: // select t*
+		n, err := r.u32()
+		if err != nil {
+			return err
+		}
+		_, err = r.bytes(int(n))
+		return err
+	case op >= 0x28 && op <= 0x3e: // loads and stores
+		return r.skipMemarg()
+	case op == 0x3f, op == 0x40, op == 0xd0: // memory.size, memory.grow, ref.null
+		_, err := r.byte()
+		return err
+	case op == 0x41, op == 0x42: // i32.const, i64.const
+		return r.skipLEB()
+	case op == 0x43:
+		_, err := r.bytes(4)
+		return err
+	case op == 0x44:
+		_, err := r.bytes(8)
+		return err
+	case op <= 0x01, op == 0x05, op == 0x0b, op == 0x0f, op == 0x1a, op == 0x1b,
+		op >= 0x45 && op <= 0xc4, op == 0xd1:
+		return nil
+	case op == 0xfc:
+		return r.skipMiscImmediates()
+	case op == 0xfd:
+		return r.skipVectorImmediates()
+	case op == 0xfe:
+		return r.skipAtomicImmediates()
+	}
+	return fmt.Errorf("unsupported opcode 0x%02x at offset %d", op, r.pos-1)
+}
+
+func (r *wasmReader) skipMiscImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub <= 7: // saturating truncation
+		return nil
+	case sub == 8: // memory.init
+		if err := r.skipLEB(); err != nil {
+			return err
+		}
+		_, err := r.byte()
+		return err
+	case sub == 9, sub == 13, sub == 15, sub == 16, sub == 17: // data.drop, elem.drop, table.grow/size/fill
+		return r.skipLEB()
+	case sub == 10: // memory.copy
+		_, err := r.bytes(2)
+		return err
+	case sub == 11: // memory.fill
+		_, err := r.byte()
+		return err
+	case sub == 12, sub == 14: // table.init, table.copy
+		if err := r.skipLEB(); err != nil {
+			return err
+		}
+		return r.skipLEB()
+	}
+	return fmt.Errorf("unsupported opcode 0xfc %d", sub)
+}
+
+func (r *wasmReader) skipVectorImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub <= 0x0b, sub == 0x5c, sub == 0x5d: // loads and stores
+		return r.skipMemarg()
+	case sub == 0x0c, sub == 0x0d: // v128.const, i8x16.shuffle
+		_, err := r.bytes(16)
+		return err
+	case sub >= 0x15 && sub <= 0x22: // lane access
+		_, err := r.byte()
+		return err
+	case sub >= 0x54 && sub <= 0x5b: // lane loads and stores
+		if err := r.skipMemarg(); err != nil {
+			return err
+		}
+		_, err := r.byte()
+		return err
+	case sub <= 0x113:
+		return nil
+	}
+	return fmt.Errorf("unsupported opcode 0xfd %d", sub)
+}
+
+func (r *wasmReader) skipAtomicImmediates() error {
+	sub, err := r.u32()
+	if err != nil {
+		return err
+	}
+	switch {
+	case sub == 0x03: // atomic.fence
+		_, err := r.byte()
+		return err
+	case sub <= 0x02, sub >= 0x10 && sub <= 0x4e:
+		return r.skipMemarg()
+	}
+	return fmt.Errorf("unsupported opcode 0xfe %d", sub)
+}
+
+func appendU32(out []byte, v uint32) []byte {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func appendS64(out []byte, v int64) []byte {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func appendName(out []byte, name string) []byte {
+	out = appendU32(out, uint32(len(name)))
+	return append(out, name...)
+}
+
+// interrupted attributes a trap to the context when it stopped the call
+func interrupted(ctx context.Context, trap error) error {
+	if ctx.Err() == nil || errors.Is(trap, ErrOutOfFuel) {
+		return trap
+	}
+	return fmt.Errorf("%w: %w", ErrInterrupted, ctx.Err())
+}
@@ -0,0 +1,110 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+)
+
+// spinWASM counts its argument down to zero:
+//
+//	(module
+//	  (func (export "spin") (param i32) (result i32)
+//	    (loop
+//	      local.get 0  i32.const 1  i32.sub  local.tee 0  br_if 0)
+//	    local.get 0))
+var spinWASM = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x06, 0x01, 0x60, 0x01, 0x7f, 0x01, 0x7f, // Type section: (i32) -> i32
+	0x03, 0x02, 0x01, 0x00, // Function section
+	0x07, 0x08, 0x01, 0x04, 0x73, 0x70, 0x69, 0x6e, 0x00, 0x00, // Export "spin"
+	0x0a, 0x12, 0x01, 0x10, 0x00, // Code section, no locals
+	0x03, 0x40, 0x20, 0x00, 0x41, 0x01, 0x6b, 0x22, 0x00, 0x0d, 0x00, 0x0b,
+	0x20, 0x00, 0x0b,
+}
+
+func runSpin(t *testing.T, budget uint64, n uint64) (uint64, error) {
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+
+	instrumented, err := InstrumentFuel(spinWASM)
+	require.NoError(t, err)
+	compiled, err := rt.CompileModule(ctx, instrumented)
+	require.NoError(t, err)
+
+	module, meter, err := instantiateMetered(ctx, rt, compiled, wazero.NewModuleConfig(), budget)
+	require.NoError(t, err)
+
+	results, err := module.ExportedFunction("spin").Call(ctx, n)
+	if err != nil {
+		return meter.Used(), meter.err(err)
+	}
+	assert.Equal(t, uint64(0), results[0])
+	return meter.Used(), nil
+}
+
+func TestFuelIsDeterministic(t *testing.T) {
+	// One for entering the loop, five per iteration, then the trailing end
+	// and local.get 0 / end after the loop
+	used, err := runSpin(t, 1_000_000, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1+10*5+1+2), used)
+
+	again, err := runSpin(t, 1_000_000, 10)
+	require.NoError(t, err)
+	assert.Equal(t, used, again)
+
+	more, err := runSpin(t, 1_000_000, 20)
+	require.NoError(t, err)
+	assert.Equal(t, used+50, more)
+}
+
+func TestFuelExhaustionTraps(t *testing.T) {
+	used, err := runSpin(t, 100, 1_000_000)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrOutOfFuel))
+	assert.Equal(t, uint64(100), used)
+}
+
+func TestInstrumentFuelRejectsExportClash(t *testing.T) {
+	instrumented, err := InstrumentFuel(spinWASM)
+	require.NoError(t, err)
+
+	_, err = InstrumentFuel(instrumented)
+	assert.Error(t, err)
+}
+
+func TestFuelBudgetConversion(t *testing.T) {
+	assert.Equal(t, uint64(0), FuelBudget(0, DefaultFuelPerUnit))
+	assert.Equal(t, uint64(25_000_000), FuelBudget(2.5, DefaultFuelPerUnit))
+	assert.InDelta(t, 2.5, FuelCost(25_000_000, DefaultFuelPerUnit), 1e-9)
+}
+
+func TestCanceledCallIsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	rt, release, err := openRuntime(context.Background(), nil, 0)
+	require.NoError(t, err)
+	defer release(false)
+
+	compiled, err := rt.CompileModule(ctx, spinWASM)
+	require.NoError(t, err)
+	module, err := rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	// Counting down from zero wraps around and spins for billions of iterations
+	start := time.Now()
+	_, err = module.ExportedFunction("spin").Call(ctx, 0)
+	require.Error(t, err)
+	err = interrupted(ctx, err)
+	assert.True(t, errors.Is(err, ErrInterrupted))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -4,11 +4,13 @@ go 1.24.10
 
 require (
 	github.com/aidenlippert/zerostate/libs/metrics v0.0.0
+	github.com/aidenlippert/zerostate/libs/validation v0.0.0
 	github.com/google/uuid v1.6.0
 	github.com/libp2p/go-libp2p v0.39.1
 	github.com/prometheus/client_golang v1.23.2
 	github.com/stretchr/testify v1.11.1
 	github.com/tetratelabs/wazero v1.9.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	go.uber.org/zap v1.27.0
 )
 
@@ -102,6 +104,7 @@ require (
 	github.com/quic-go/webtransport-go v0.8.1-0.20241018022711-4ac2c9250e66 // indirect
 	github.com/raulk/go-watchdog v1.3.0 // indirect
 	github.com/spaolacci/murmur3 v1.1.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	github.com/wlynxg/anet v0.0.5 // indirect
 	go.uber.org/dig v1.18.0 // indirect
 	go.uber.org/fx v1.23.0 // indirect
@@ -125,4 +128,6 @@ require (
 
 replace github.com/aidenlippert/zerostate/libs/metrics => ../metrics
 
+replace github.com/aidenlippert/zerostate/libs/validation => ../validation
+
 replace github.com/aidenlippert/zerostate/libs/telemetry => ../telemetry
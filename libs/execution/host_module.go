@@ -0,0 +1,612 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+)
+
+// HostModuleName is the import module guests link the host API against. The
+// version suffix changes whenever the ABI changes incompatibly, so old agents
+// keep linking against the functions they were built for.
+//
+// Every function takes and returns i32. Byte strings are passed as a pointer
+// and length into guest memory. Functions that return data write it to a
+// guest buffer (ptr, cap) and return its full length; when the length exceeds
+// cap nothing is written and the guest retries with a larger buffer. Negative
+// results are HostErr* codes.
+//
+//	log(level, msg_ptr, msg_len, fields_ptr, fields_len) -> status
+//	input_read(buf_ptr, buf_cap) -> len
+//	progress(percent, data_ptr, data_len) -> status
+//	kv_get(key_ptr, key_len, buf_ptr, buf_cap) -> len
+//	kv_set(key_ptr, key_len, val_ptr, val_len) -> status
+//	kv_delete(key_ptr, key_len) -> status
+//	content_get(cid_ptr, cid_len, buf_ptr, buf_cap) -> len
+//	call_agent(req_ptr, req_len, buf_ptr, buf_cap) -> len
+const HostModuleName = "zerostate_v1"
+
+// Host function status codes
+const (
+	HostOK          int32 = 0
+	HostErrDenied   int32 = -1 // Capability not granted to the agent
+	HostErrNotFound int32 = -2 // Key or content does not exist
+	HostErrInvalid  int32 = -3 // Bad pointer, length or argument
+	HostErrLimit    int32 = -4 // Size or count limit exceeded
+	HostErrFailed   int32 = -5 // The backing service failed
+)
+
+// HostCapability names a group of host functions an agent can be granted.
+// Agents declare them in their card policy; anything not granted is denied.
+type HostCapability string
+
+const (
+	HostCapLog      HostCapability = "log"      // log
+	HostCapInput    HostCapability = "input"    // input_read
+	HostCapProgress HostCapability = "progress" // progress
+	HostCapKV       HostCapability = "kv"       // kv_get, kv_set, kv_delete
+	HostCapContent  HostCapability = "content"  // content_get
+	HostCapAgents   HostCapability = "agents"   // call_agent
+)
+
+// Host API limits
+const (
+	DefaultKVLimitBytes = 1 << 20  // Scratch storage per task
+	maxHostPayload      = 16 << 20 // Largest value passed in either direction
+	maxKVKeyBytes       = 256
+	maxLogMessageBytes  = 4096
+	maxLogEntries       = 1000
+)
+
+// logLevelNames names the levels log accepts, by number
+var logLevelNames = [...]string{"debug", "info", "warn", "error"}
+
+// HostGrants is the set of capabilities granted to one agent
+type HostGrants map[HostCapability]bool
+
+// NewHostGrants builds a grant set from capability names, ignoring names this
+// version of the host doesn't know
+func NewHostGrants(names ...string) HostGrants {
+	grants := make(HostGrants, len(names))
+	for _, name := range names {
+		switch capability := HostCapability(name); capability {
+		case HostCapLog, HostCapInput, HostCapProgress, HostCapKV, HostCapContent, HostCapAgents:
+			grants[capability] = true
+		}
+	}
+	return grants
+}
+
+// ContentFetcher retrieves content by CID. p2p.ContentStore satisfies it.
+type ContentFetcher interface {
+	Get(ctx context.Context, cid string) ([]byte, error)
+}
+
+// AgentCall is the JSON request a guest passes to call_agent. Either AgentID
+// or Capabilities selects the callee.
+type AgentCall struct {
+	AgentID      string          `json:"agent_id,omitempty"`
+	Capabilities []string        `json:"capabilities,omitempty"`
+	Input        json.RawMessage `json:"input,omitempty"`
+	Budget       float64         `json:"budget"`
+	TimeoutMS    int64           `json:"timeout_ms,omitempty"`
+}
+
+// AgentCallResult is the JSON response call_agent writes back to the guest
+type AgentCallResult struct {
+	AgentID string          `json:"agent_id"`
+	Status  string          `json:"status"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Cost    float64         `json:"cost"`
+}
+
+// AgentCaller runs a task on another agent on behalf of a guest
+type AgentCaller interface {
+	CallAgent(ctx context.Context, call AgentCall) (*AgentCallResult, error)
+}
+
+// ProgressUpdate is a partial result emitted by a guest
+type ProgressUpdate struct {
+	TaskID    string          `json:"task_id"`
+	Percent   int             `json:"percent"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// HostEnv is the per-task state behind the host module: what the agent was
+// granted and the services those grants reach. Attach it to the execution
+// context with WithHostEnv; runs without one are denied every capability.
+type HostEnv struct {
+	taskID  string
+	agentID string
+	grants  HostGrants
+	input   []byte
+	logger  *zap.Logger
+
+	onProgress  func(ProgressUpdate)
+	onLog       func(level, message string)
+	content     ContentFetcher
+	agents      AgentCaller
+	agentBudget float64 // Total budget call_agent may hand out
+
+	mu          sync.Mutex
+	kv          map[string][]byte
+	kvBytes     int
+	kvLimit     int
+	logEntries  int
+	progress    []ProgressUpdate
+	agentSpend  float64
+	lastContent *hostReply
+	lastCall    *hostReply
+}
+
+// hostReply keeps a result the guest's buffer was too small for, so the
+// retry doesn't repeat the fetch or call
+type hostReply struct {
+	request string
+	data    []byte
+}
+
+// NewHostEnv creates the host state for one task run
+func NewHostEnv(taskID, agentID string, grants HostGrants, input []byte, logger *zap.Logger) *HostEnv {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if grants == nil {
+		grants = HostGrants{}
+	}
+
+	return &HostEnv{
+		taskID:  taskID,
+		agentID: agentID,
+		grants:  grants,
+		input:   input,
+		logger:  logger,
+		kv:      make(map[string][]byte),
+		kvLimit: DefaultKVLimitBytes,
+	}
+}
+
+// SetProgressSink receives every progress update as the guest emits it
+func (e *HostEnv) SetProgressSink(sink func(ProgressUpdate)) {
+	e.onProgress = sink
+}
+
+// SetLogSink receives every line the guest logs, after the entry limit
+func (e *HostEnv) SetLogSink(sink func(level, message string)) {
+	e.onLog = sink
+}
+
+// SetContentStore backs content_get
+func (e *HostEnv) SetContentStore(content ContentFetcher) {
+	e.content = content
+}
+
+// SetAgentCaller backs call_agent. budget caps the total the guest can hand
+// to the agents it calls.
+func (e *HostEnv) SetAgentCaller(agents AgentCaller, budget float64) {
+	e.agents = agents
+	e.agentBudget = budget
+}
+
+// SetKVLimit caps the bytes of scratch storage the guest may hold
+func (e *HostEnv) SetKVLimit(limit int) {
+	e.kvLimit = limit
+}
+
+// Allowed reports whether the agent was granted capability
+func (e *HostEnv) Allowed(capability HostCapability) bool {
+	return e != nil && e.grants[capability]
+}
+
+// Progress returns the updates the guest emitted, oldest first
+func (e *HostEnv) Progress() []ProgressUpdate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]ProgressUpdate(nil), e.progress...)
+}
+
+// AgentSpend returns the cost of the agent calls the guest made
+func (e *HostEnv) AgentSpend() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.agentSpend
+}
+
+type hostEnvKey struct{}
+
+// WithHostEnv attaches env to ctx for the host functions of guests run with it
+func WithHostEnv(ctx context.Context, env *HostEnv) context.Context {
+	return context.WithValue(ctx, hostEnvKey{}, env)
+}
+
+// hostEnvFrom returns the env attached to ctx, or nil
+func hostEnvFrom(ctx context.Context) *HostEnv {
+	env, _ := ctx.Value(hostEnvKey{}).(*HostEnv)
+	return env
+}
+
+// instantiateHostModule adds the zerostate host module to rt. It holds no
+// state of its own, so pooled runtimes can serve any task.
+func instantiateHostModule(ctx context.Context, rt wazero.Runtime) error {
+	_, err := rt.NewHostModuleBuilder(HostModuleName).
+		NewFunctionBuilder().WithFunc(hostLog).Export("log").
+		NewFunctionBuilder().WithFunc(hostInputRead).Export("input_read").
+		NewFunctionBuilder().WithFunc(hostProgress).Export("progress").
+		NewFunctionBuilder().WithFunc(hostKVGet).Export("kv_get").
+		NewFunctionBuilder().WithFunc(hostKVSet).Export("kv_set").
+		NewFunctionBuilder().WithFunc(hostKVDelete).Export("kv_delete").
+		NewFunctionBuilder().WithFunc(hostContentGet).Export("content_get").
+		NewFunctionBuilder().WithFunc(hostCallAgent).Export("call_agent").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate %s host module: %w", HostModuleName, err)
+	}
+	return nil
+}
+
+// granted returns the env for ctx if it grants capability
+func granted(ctx context.Context, capability HostCapability) (*HostEnv, bool) {
+	env := hostEnvFrom(ctx)
+	if !env.Allowed(capability) {
+		if env != nil {
+			env.logger.Debug("host capability denied",
+				zap.String("task_id", env.taskID),
+				zap.String("agent_id", env.agentID),
+				zap.String("capability", string(capability)),
+			)
+		}
+		return nil, false
+	}
+	return env, true
+}
+
+// readGuest copies length bytes at ptr out of guest memory
+func readGuest(m api.Module, ptr, length uint32) ([]byte, bool) {
+	if length > maxHostPayload {
+		return nil, false
+	}
+	if length == 0 {
+		return nil, true
+	}
+	mem := m.Memory()
+	if mem == nil {
+		return nil, false
+	}
+	view, ok := mem.Read(ptr, length)
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), view...), true
+}
+
+// writeGuest writes data to the guest buffer if it fits and returns the
+// length the guest should expect
+func writeGuest(m api.Module, data []byte, ptr, capacity uint32) int32 {
+	if len(data) > maxHostPayload || len(data) > math.MaxInt32 {
+		return HostErrLimit
+	}
+	if len(data) > 0 && uint32(len(data)) <= capacity {
+		mem := m.Memory()
+		if mem == nil || !mem.Write(ptr, data) {
+			return HostErrInvalid
+		}
+	}
+	return int32(len(data))
+}
+
+// fits reports whether a reply of n bytes was delivered to a buffer of capacity
+func fits(n int32, capacity uint32) bool {
+	return n >= 0 && uint32(n) <= capacity
+}
+
+func hostLog(ctx context.Context, m api.Module, level, msgPtr, msgLen, fieldsPtr, fieldsLen uint32) int32 {
+	env, ok := granted(ctx, HostCapLog)
+	if !ok {
+		return HostErrDenied
+	}
+
+	msg, ok := readGuest(m, msgPtr, msgLen)
+	if !ok {
+		return HostErrInvalid
+	}
+	if len(msg) > maxLogMessageBytes {
+		msg = msg[:maxLogMessageBytes]
+	}
+
+	fields := []zap.Field{
+		zap.String("task_id", env.taskID),
+		zap.String("agent_id", env.agentID),
+	}
+	if fieldsLen > 0 {
+		raw, ok := readGuest(m, fieldsPtr, fieldsLen)
+		if !ok {
+			return HostErrInvalid
+		}
+		var structured map[string]interface{}
+		if err := json.Unmarshal(raw, &structured); err != nil {
+			return HostErrInvalid
+		}
+		fields = append(fields, zap.Any("fields", structured))
+	}
+
+	env.mu.Lock()
+	env.logEntries++
+	over := env.logEntries > maxLogEntries
+	env.mu.Unlock()
+	if over {
+		return HostErrLimit
+	}
+
+	logger := env.logger.Named("guest")
+	switch level {
+	case 0:
+		logger.Debug(string(msg), fields...)
+	case 1:
+		logger.Info(string(msg), fields...)
+	case 2:
+		logger.Warn(string(msg), fields...)
+	case 3:
+		logger.Error(string(msg), fields...)
+	default:
+		return HostErrInvalid
+	}
+	if env.onLog != nil {
+		env.onLog(logLevelNames[level], string(msg))
+	}
+	return HostOK
+}
+
+func hostInputRead(ctx context.Context, m api.Module, bufPtr, bufCap uint32) int32 {
+	env, ok := granted(ctx, HostCapInput)
+	if !ok {
+		return HostErrDenied
+	}
+	return writeGuest(m, env.input, bufPtr, bufCap)
+}
+
+func hostProgress(ctx context.Context, m api.Module, percent, dataPtr, dataLen uint32) int32 {
+	env, ok := granted(ctx, HostCapProgress)
+	if !ok {
+		return HostErrDenied
+	}
+	if percent > 100 {
+		return HostErrInvalid
+	}
+
+	data, ok := readGuest(m, dataPtr, dataLen)
+	if !ok {
+		return HostErrInvalid
+	}
+	if len(data) > 0 && !json.Valid(data) {
+		return HostErrInvalid
+	}
+
+	update := ProgressUpdate{
+		TaskID:    env.taskID,
+		Percent:   int(percent),
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	env.mu.Lock()
+	env.progress = append(env.progress, update)
+	env.mu.Unlock()
+
+	if env.onProgress != nil {
+		env.onProgress(update)
+	}
+	return HostOK
+}
+
+func hostKVGet(ctx context.Context, m api.Module, keyPtr, keyLen, bufPtr, bufCap uint32) int32 {
+	env, ok := granted(ctx, HostCapKV)
+	if !ok {
+		return HostErrDenied
+	}
+	if keyLen > maxKVKeyBytes {
+		return HostErrInvalid
+	}
+	key, ok := readGuest(m, keyPtr, keyLen)
+	if !ok {
+		return HostErrInvalid
+	}
+
+	env.mu.Lock()
+	value, found := env.kv[string(key)]
+	env.mu.Unlock()
+	if !found {
+		return HostErrNotFound
+	}
+	return writeGuest(m, value, bufPtr, bufCap)
+}
+
+func hostKVSet(ctx context.Context, m api.Module, keyPtr, keyLen, valPtr, valLen uint32) int32 {
+	env, ok := granted(ctx, HostCapKV)
+	if !ok {
+		return HostErrDenied
+	}
+	if keyLen == 0 || keyLen > maxKVKeyBytes {
+		return HostErrInvalid
+	}
+	key, ok := readGuest(m, keyPtr, keyLen)
+	if !ok {
+		return HostErrInvalid
+	}
+	value, ok := readGuest(m, valPtr, valLen)
+	if !ok {
+		return HostErrInvalid
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	size := env.kvBytes + len(key) + len(value)
+	if old, found := env.kv[string(key)]; found {
+		size -= len(key) + len(old)
+	}
+	if size > env.kvLimit {
+		return HostErrLimit
+	}
+	env.kv[string(key)] = value
+	env.kvBytes = size
+	return HostOK
+}
+
+func hostKVDelete(ctx context.Context, m api.Module, keyPtr, keyLen uint32) int32 {
+	env, ok := granted(ctx, HostCapKV)
+	if !ok {
+		return HostErrDenied
+	}
+	if keyLen > maxKVKeyBytes {
+		return HostErrInvalid
+	}
+	key, ok := readGuest(m, keyPtr, keyLen)
+	if !ok {
+		return HostErrInvalid
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	old, found := env.kv[string(key)]
+	if !found {
+		return HostErrNotFound
+	}
+	delete(env.kv, string(key))
+	env.kvBytes -= len(key) + len(old)
+	return HostOK
+}
+
+func hostContentGet(ctx context.Context, m api.Module, cidPtr, cidLen, bufPtr, bufCap uint32) int32 {
+	env, ok := granted(ctx, HostCapContent)
+	if !ok || env.content == nil {
+		return HostErrDenied
+	}
+	cid, ok := readGuest(m, cidPtr, cidLen)
+	if !ok || len(cid) == 0 {
+		return HostErrInvalid
+	}
+
+	data, pending := env.takeReply(&env.lastContent, string(cid))
+	if !pending {
+		fetched, err := env.content.Get(ctx, string(cid))
+		if err != nil {
+			env.logger.Debug("guest content fetch failed",
+				zap.String("task_id", env.taskID),
+				zap.String("cid", string(cid)),
+				zap.Error(err),
+			)
+			return HostErrNotFound
+		}
+		data = fetched
+	}
+
+	n := writeGuest(m, data, bufPtr, bufCap)
+	if n >= 0 && !fits(n, bufCap) {
+		env.keepReply(&env.lastContent, string(cid), data)
+	}
+	return n
+}
+
+func hostCallAgent(ctx context.Context, m api.Module, reqPtr, reqLen, bufPtr, bufCap uint32) int32 {
+	env, ok := granted(ctx, HostCapAgents)
+	if !ok || env.agents == nil {
+		return HostErrDenied
+	}
+	raw, ok := readGuest(m, reqPtr, reqLen)
+	if !ok {
+		return HostErrInvalid
+	}
+
+	reply, pending := env.takeReply(&env.lastCall, string(raw))
+	if !pending {
+		var call AgentCall
+		if err := json.Unmarshal(raw, &call); err != nil {
+			return HostErrInvalid
+		}
+		if call.AgentID == "" && len(call.Capabilities) == 0 {
+			return HostErrInvalid
+		}
+		if call.Budget <= 0 {
+			return HostErrInvalid
+		}
+
+		// Reserve the callee's budget up front so concurrent calls can't
+		// overspend, then settle to what it actually cost
+		env.mu.Lock()
+		if env.agentSpend+call.Budget > env.agentBudget {
+			env.mu.Unlock()
+			return HostErrLimit
+		}
+		env.agentSpend += call.Budget
+		env.mu.Unlock()
+
+		callCtx := ctx
+		if call.TimeoutMS > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(ctx, time.Duration(call.TimeoutMS)*time.Millisecond)
+			defer cancel()
+		}
+
+		result, err := env.agents.CallAgent(callCtx, call)
+
+		env.mu.Lock()
+		env.agentSpend -= call.Budget
+		if result != nil {
+			env.agentSpend += math.Min(result.Cost, call.Budget)
+		}
+		env.mu.Unlock()
+
+		if err != nil {
+			env.logger.Warn("guest agent call failed",
+				zap.String("task_id", env.taskID),
+				zap.String("callee", call.AgentID),
+				zap.Error(err),
+			)
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				return HostErrLimit
+			}
+			return HostErrFailed
+		}
+
+		reply, err = json.Marshal(result)
+		if err != nil {
+			return HostErrFailed
+		}
+	}
+
+	n := writeGuest(m, reply, bufPtr, bufCap)
+	if n >= 0 && !fits(n, bufCap) {
+		env.keepReply(&env.lastCall, string(raw), reply)
+	}
+	return n
+}
+
+// takeReply returns and clears a reply kept for request
+func (e *HostEnv) takeReply(slot **hostReply, request string) ([]byte, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	kept := *slot
+	*slot = nil
+	if kept == nil || kept.request != request {
+		return nil, false
+	}
+	return kept.data, true
+}
+
+// keepReply holds a reply until the guest retries with a bigger buffer
+func (e *HostEnv) keepReply(slot **hostReply, request string, data []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	*slot = &hostReply{request: request, data: data}
+}
@@ -0,0 +1,252 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"go.uber.org/zap"
+)
+
+// encodeSection encodes a section holding count entries
+func encodeSection(id byte, count int, body []byte) []byte {
+	payload := append(appendU32(nil, uint32(count)), body...)
+	return append(appendU32([]byte{id}, uint32(len(payload))), payload...)
+}
+
+// hostImports are the host functions the test guest wraps, with their
+// number of i32 parameters
+var hostImports = []struct {
+	name   string
+	params int
+}{
+	{"log", 5},
+	{"input_read", 2},
+	{"progress", 3},
+	{"kv_get", 4},
+	{"kv_set", 4},
+	{"kv_delete", 2},
+	{"content_get", 4},
+	{"call_agent", 4},
+}
+
+// hostGuestWASM assembles a guest that exports one memory page and a wrapper
+// for each host import, so tests can drive the host API from Go
+func hostGuestWASM() []byte {
+	// One type per distinct arity: (i32 x n) -> i32
+	var types []byte
+	typeIndex := map[int]int{}
+	for _, imp := range hostImports {
+		if _, ok := typeIndex[imp.params]; ok {
+			continue
+		}
+		typeIndex[imp.params] = len(typeIndex)
+		types = append(types, 0x60)
+		types = append(types, appendU32(nil, uint32(imp.params))...)
+		for i := 0; i < imp.params; i++ {
+			types = append(types, 0x7f)
+		}
+		types = append(types, 0x01, 0x7f)
+	}
+
+	var imports, funcs, exports, code []byte
+	for i, imp := range hostImports {
+		imports = append(imports, appendName(nil, HostModuleName)...)
+		imports = append(imports, appendName(nil, imp.name)...)
+		imports = append(imports, 0x00)
+		imports = append(imports, appendU32(nil, uint32(typeIndex[imp.params]))...)
+
+		funcs = append(funcs, appendU32(nil, uint32(typeIndex[imp.params]))...)
+
+		exports = append(exports, appendName(nil, imp.name)...)
+		exports = append(exports, 0x00)
+		exports = append(exports, appendU32(nil, uint32(len(hostImports)+i))...)
+
+		body := []byte{0x00} // no locals
+		for p := 0; p < imp.params; p++ {
+			body = append(body, 0x20, byte(p)) // local.get p
+		}
+		body = append(body, 0x10, byte(i), 0x0b) // call import i, end
+		code = append(code, appendU32(nil, uint32(len(body)))...)
+		code = append(code, body...)
+	}
+	exports = append(exports, appendName(nil, "memory")...)
+	exports = append(exports, 0x02, 0x00)
+
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, encodeSection(0x01, len(typeIndex), types)...)
+	wasm = append(wasm, encodeSection(0x02, len(hostImports), imports)...)
+	wasm = append(wasm, encodeSection(0x03, len(hostImports), funcs)...)
+	wasm = append(wasm, encodeSection(0x05, 1, []byte{0x00, 0x01})...)
+	wasm = append(wasm, encodeSection(0x07, len(hostImports)+1, exports)...)
+	wasm = append(wasm, encodeSection(0x0a, len(hostImports), code)...)
+	return wasm
+}
+
+// hostGuest is an instantiated test guest
+type hostGuest struct {
+	t   *testing.T
+	ctx context.Context
+	mod api.Module
+}
+
+func newHostGuest(t *testing.T, env *HostEnv) *hostGuest {
+	ctx := context.Background()
+	rt, release, err := openRuntime(ctx, nil, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { release(false) })
+
+	compiled, err := rt.CompileModule(ctx, hostGuestWASM())
+	require.NoError(t, err)
+	mod, err := rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	if env != nil {
+		ctx = WithHostEnv(ctx, env)
+	}
+	return &hostGuest{t: t, ctx: ctx, mod: mod}
+}
+
+// put writes data into guest memory at ptr
+func (g *hostGuest) put(ptr uint32, data string) uint64 {
+	require.True(g.t, g.mod.Memory().Write(ptr, []byte(data)))
+	return uint64(len(data))
+}
+
+// get reads n bytes of guest memory at ptr
+func (g *hostGuest) get(ptr uint32, n int32) string {
+	data, ok := g.mod.Memory().Read(ptr, uint32(n))
+	require.True(g.t, ok)
+	return string(data)
+}
+
+func (g *hostGuest) call(fn string, args ...uint64) int32 {
+	results, err := g.mod.ExportedFunction(fn).Call(g.ctx, args...)
+	require.NoError(g.t, err)
+	return int32(results[0])
+}
+
+func TestHostCapabilitiesDeniedByDefault(t *testing.T) {
+	env := NewHostEnv("task-1", "did:key:agent", NewHostGrants("input", "teleport"), []byte(`{"n":1}`), zap.NewNop())
+	guest := newHostGuest(t, env)
+
+	assert.Equal(t, int32(7), guest.call("input_read", 0, 0))
+	assert.Equal(t, HostErrDenied, guest.call("kv_set", 0, guest.put(0, "k"), 0, 0))
+	assert.Equal(t, HostErrDenied, guest.call("log", 1, 0, guest.put(0, "hi"), 0, 0))
+
+	// Granted but no store configured
+	env = NewHostEnv("task-1", "did:key:agent", NewHostGrants("content"), nil, zap.NewNop())
+	guest = newHostGuest(t, env)
+	assert.Equal(t, HostErrDenied, guest.call("content_get", 0, guest.put(0, "bafy"), 64, 64))
+
+	// No env at all
+	guest = newHostGuest(t, nil)
+	assert.Equal(t, HostErrDenied, guest.call("input_read", 0, 64))
+}
+
+func TestHostInputAndProgress(t *testing.T) {
+	var updates []ProgressUpdate
+	env := NewHostEnv("task-1", "did:key:agent", NewHostGrants("input", "progress", "log"), []byte(`{"n":1}`), zap.NewNop())
+	env.SetProgressSink(func(u ProgressUpdate) { updates = append(updates, u) })
+	guest := newHostGuest(t, env)
+
+	// Too small a buffer reports the size without writing
+	assert.Equal(t, int32(7), guest.call("input_read", 100, 3))
+	n := guest.call("input_read", 100, 64)
+	assert.Equal(t, `{"n":1}`, guest.get(100, n))
+
+	assert.Equal(t, HostOK, guest.call("progress", 50, 0, guest.put(0, `{"done":5}`)))
+	assert.Equal(t, HostErrInvalid, guest.call("progress", 60, 0, guest.put(0, `not json`)))
+	assert.Equal(t, HostErrInvalid, guest.call("progress", 101, 0, 0))
+	require.Len(t, updates, 1)
+	assert.Equal(t, 50, updates[0].Percent)
+	assert.JSONEq(t, `{"done":5}`, string(updates[0].Data))
+	assert.Equal(t, updates, env.Progress())
+
+	fields := guest.put(200, `{"step":2}`)
+	assert.Equal(t, HostOK, guest.call("log", 1, 0, guest.put(0, "working"), 200, fields))
+	assert.Equal(t, HostErrInvalid, guest.call("log", 9, 0, guest.put(0, "working"), 0, 0))
+}
+
+func TestHostKVScratch(t *testing.T) {
+	env := NewHostEnv("task-1", "did:key:agent", NewHostGrants("kv"), nil, zap.NewNop())
+	env.SetKVLimit(16)
+	guest := newHostGuest(t, env)
+
+	key := guest.put(0, "key")
+	assert.Equal(t, HostErrNotFound, guest.call("kv_get", 0, key, 100, 64))
+	assert.Equal(t, HostOK, guest.call("kv_set", 0, key, 10, guest.put(10, "value")))
+
+	n := guest.call("kv_get", 0, key, 100, 64)
+	assert.Equal(t, "value", guest.get(100, n))
+
+	// Overwriting only counts the new value against the limit
+	assert.Equal(t, HostOK, guest.call("kv_set", 0, key, 10, guest.put(10, "0123456789ab")))
+	assert.Equal(t, HostErrLimit, guest.call("kv_set", 0, key, 10, guest.put(10, "0123456789abcdef")))
+
+	assert.Equal(t, HostOK, guest.call("kv_delete", 0, key))
+	assert.Equal(t, HostErrNotFound, guest.call("kv_delete", 0, key))
+}
+
+type fakeContent map[string][]byte
+
+func (f fakeContent) Get(ctx context.Context, cid string) ([]byte, error) {
+	data, ok := f[cid]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+type fakeAgents struct {
+	calls []AgentCall
+}
+
+func (f *fakeAgents) CallAgent(ctx context.Context, call AgentCall) (*AgentCallResult, error) {
+	f.calls = append(f.calls, call)
+	return &AgentCallResult{
+		AgentID: "did:key:callee",
+		Status:  "completed",
+		Result:  json.RawMessage(`{"sum":3}`),
+		Cost:    call.Budget / 2,
+	}, nil
+}
+
+func TestHostContentAndAgentCalls(t *testing.T) {
+	agents := &fakeAgents{}
+	env := NewHostEnv("task-1", "did:key:agent", NewHostGrants("content", "agents"), nil, zap.NewNop())
+	env.SetContentStore(fakeContent{"bafy1": []byte("hello")})
+	env.SetAgentCaller(agents, 1.0)
+	guest := newHostGuest(t, env)
+
+	cid := guest.put(0, "bafy1")
+	n := guest.call("content_get", 0, cid, 100, 64)
+	assert.Equal(t, "hello", guest.get(100, n))
+	assert.Equal(t, HostErrNotFound, guest.call("content_get", 0, guest.put(0, "bafy2"), 100, 64))
+
+	req := guest.put(0, `{"capabilities":["math"],"input":{"a":1,"b":2},"budget":0.8}`)
+
+	// The retry with a big enough buffer is served without calling again
+	size := guest.call("call_agent", 0, req, 1000, 4)
+	require.Greater(t, size, int32(4))
+	n = guest.call("call_agent", 0, req, 1000, 1024)
+	assert.Equal(t, size, n)
+	require.Len(t, agents.calls, 1)
+	assert.Equal(t, []string{"math"}, agents.calls[0].Capabilities)
+
+	var result AgentCallResult
+	require.NoError(t, json.Unmarshal([]byte(guest.get(1000, n)), &result))
+	assert.Equal(t, "completed", result.Status)
+	assert.JSONEq(t, `{"sum":3}`, string(result.Result))
+	assert.InDelta(t, 0.4, env.AgentSpend(), 1e-9)
+
+	// 0.4 spent leaves too little for another 0.8
+	assert.Equal(t, HostErrLimit, guest.call("call_agent", 0, req, 1000, 1024))
+	assert.Equal(t, HostErrInvalid, guest.call("call_agent", 0, guest.put(0, `{"budget":0.1}`), 1000, 1024))
+	assert.Len(t, agents.calls, 1)
+}
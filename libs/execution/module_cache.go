@@ -0,0 +1,366 @@
+package execution
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"go.uber.org/zap"
+)
+
+// Module cache defaults
+const (
+	DefaultMaxCachedModules = 64
+	DefaultMaxIdleRuntimes  = 4
+)
+
+// ModuleHash returns the content address of a WASM binary
+func ModuleHash(binary []byte) string {
+	sum := sha256.Sum256(binary)
+	return hex.EncodeToString(sum[:])
+}
+
+// ModuleCacheConfig configures a ModuleCache
+type ModuleCacheConfig struct {
+	// MaxModules bounds how many compiled modules stay resident
+	MaxModules int
+
+	// Dir persists compiled code across restarts. Empty keeps it in memory.
+	// The directory must be private to this process.
+	Dir string
+
+	// MaxIdleRuntimes bounds the idle runtimes pooled per memory limit
+	MaxIdleRuntimes int
+}
+
+// ModuleCacheStats reports cache effectiveness
+type ModuleCacheStats struct {
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	Evictions    int64 `json:"evictions"`
+	Modules      int   `json:"modules"`
+	IdleRuntimes int   `json:"idle_runtimes"`
+}
+
+// ModuleCache shares compiled WASM code between runtimes, keyed by the hash
+// of the binary. Compiled code lives in a wazero.CompilationCache; the cache
+// pins the most recently used modules in it and evicts the rest. It also
+// pools idle runtimes with WASI already instantiated, so a run only pays for
+// instantiating the guest. It is safe for concurrent use.
+type ModuleCache struct {
+	compilation wazero.CompilationCache
+	anchor      wazero.Runtime // Owns the pinned compiled modules
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Front is most recently used
+	max     int
+	stats   ModuleCacheStats
+
+	idle    map[uint32][]wazero.Runtime // Memory limit pages → idle runtimes
+	maxIdle int
+
+	logger *zap.Logger
+}
+
+// moduleEntry is a pinned compiled module. ready is closed once compilation
+// finished; err is set if it failed.
+type moduleEntry struct {
+	hash     string
+	compiled wazero.CompiledModule
+	ready    chan struct{}
+	err      error
+}
+
+// NewModuleCache creates a module cache
+func NewModuleCache(ctx context.Context, cfg ModuleCacheConfig, logger *zap.Logger) (*ModuleCache, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.MaxModules <= 0 {
+		cfg.MaxModules = DefaultMaxCachedModules
+	}
+	if cfg.MaxIdleRuntimes <= 0 {
+		cfg.MaxIdleRuntimes = DefaultMaxIdleRuntimes
+	}
+
+	compilation := wazero.NewCompilationCache()
+	if cfg.Dir != "" {
+		var err error
+		compilation, err = wazero.NewCompilationCacheWithDir(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open compilation cache: %w", err)
+		}
+	}
+
+	c := &ModuleCache{
+		compilation: compilation,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		max:         cfg.MaxModules,
+		idle:        make(map[uint32][]wazero.Runtime),
+		maxIdle:     cfg.MaxIdleRuntimes,
+		logger:      logger,
+	}
+	c.anchor = wazero.NewRuntimeWithConfig(ctx, c.RuntimeConfig())
+
+	logger.Info("WASM module cache initialized",
+		zap.Int("max_modules", cfg.MaxModules),
+		zap.String("dir", cfg.Dir),
+	)
+
+	return c, nil
+}
+
+// RuntimeConfig returns a runtime configuration that shares this cache's
+// compiled code. Calls are interrupted when their context is done.
+func (c *ModuleCache) RuntimeConfig() wazero.RuntimeConfig {
+	return wazero.NewRuntimeConfig().
+		WithCompilationCache(c.compilation).
+		WithCloseOnContextDone(true)
+}
+
+// AcquireRuntime returns a runtime with WASI and the host API instantiated
+// and the given memory limit (0 for wazero's default), reusing an idle one
+// when possible.
+// Hand it back with ReleaseRuntime once every guest module in it is closed.
+func (c *ModuleCache) AcquireRuntime(ctx context.Context, memoryLimitPages uint32) (wazero.Runtime, error) {
+	c.mu.Lock()
+	if pool := c.idle[memoryLimitPages]; len(pool) > 0 {
+		rt := pool[len(pool)-1]
+		c.idle[memoryLimitPages] = pool[:len(pool)-1]
+		c.mu.Unlock()
+		return rt, nil
+	}
+	c.mu.Unlock()
+
+	config := c.RuntimeConfig()
+	if memoryLimitPages > 0 {
+		config = config.WithMemoryLimitPages(memoryLimitPages)
+	}
+
+	rt := wazero.NewRuntimeWithConfig(ctx, config)
+	if err := instantiateImports(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	return rt, nil
+}
+
+// ReleaseRuntime returns a runtime to the pool. Runtimes whose run failed
+// should be closed instead so no guest state can leak into the next run.
+func (c *ModuleCache) ReleaseRuntime(ctx context.Context, memoryLimitPages uint32, rt wazero.Runtime) {
+	c.mu.Lock()
+	if len(c.idle[memoryLimitPages]) < c.maxIdle {
+		c.idle[memoryLimitPages] = append(c.idle[memoryLimitPages], rt)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	rt.Close(ctx)
+}
+
+// Compile compiles a binary in rt, reusing cached code when the same binary
+// was compiled before. rt must have been created from RuntimeConfig or
+// AcquireRuntime. Don't Close the returned module: that would drop the shared
+// code for every runtime. It stays cached until evicted.
+func (c *ModuleCache) Compile(ctx context.Context, rt wazero.Runtime, binary []byte) (wazero.CompiledModule, error) {
+	if err := c.pin(ctx, binary); err != nil {
+		return nil, err
+	}
+	return rt.CompileModule(ctx, binary)
+}
+
+// Precompile compiles and pins a binary ahead of its first run
+func (c *ModuleCache) Precompile(ctx context.Context, binary []byte) (string, error) {
+	if err := c.pin(ctx, binary); err != nil {
+		return "", err
+	}
+	return ModuleHash(binary), nil
+}
+
+// pin makes sure the binary's compiled code is in the cache, compiling it in
+// the anchor runtime on a miss. Concurrent misses on one binary compile once.
+func (c *ModuleCache) pin(ctx context.Context, binary []byte) error {
+	hash := ModuleHash(binary)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[hash]; ok {
+		c.lru.MoveToFront(elem)
+		c.stats.Hits++
+		entry := elem.Value.(*moduleEntry)
+		c.mu.Unlock()
+
+		<-entry.ready
+		return entry.err
+	}
+
+	entry := &moduleEntry{hash: hash, ready: make(chan struct{})}
+	c.entries[hash] = c.lru.PushFront(entry)
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	compiled, err := c.anchor.CompileModule(ctx, binary)
+
+	c.mu.Lock()
+	entry.compiled, entry.err = compiled, err
+	close(entry.ready)
+	if err != nil {
+		// Don't cache failures; a later attempt may have a live context
+		if elem, ok := c.entries[hash]; ok && elem.Value == entry {
+			c.lru.Remove(elem)
+			delete(c.entries, hash)
+		}
+	}
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	for _, e := range evicted {
+		// Closing the anchor's module drops its code from the shared cache
+		e.compiled.Close(ctx)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug("WASM module compiled and cached",
+		zap.String("module_hash", hash),
+		zap.Int("binary_size", len(binary)),
+	)
+	return nil
+}
+
+// evictLocked drops the least recently used compiled modules over the limit.
+// Callers must hold c.mu and close the returned modules.
+func (c *ModuleCache) evictLocked() []*moduleEntry {
+	var evicted []*moduleEntry
+	for elem := c.lru.Back(); elem != nil && c.lru.Len() > c.max; {
+		prev := elem.Prev()
+		entry := elem.Value.(*moduleEntry)
+		select {
+		case <-entry.ready:
+			if entry.err == nil {
+				c.lru.Remove(elem)
+				delete(c.entries, entry.hash)
+				evicted = append(evicted, entry)
+				c.stats.Evictions++
+			}
+		default:
+			// Still compiling
+		}
+		elem = prev
+	}
+	return evicted
+}
+
+// Contains reports whether a binary's compiled code is cached
+func (c *ModuleCache) Contains(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[hash]
+	return ok
+}
+
+// Stats returns cache counters
+func (c *ModuleCache) Stats() ModuleCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Modules = c.lru.Len()
+	for _, pool := range c.idle {
+		stats.IdleRuntimes += len(pool)
+	}
+	return stats
+}
+
+// Close releases pooled runtimes and all cached code
+func (c *ModuleCache) Close(ctx context.Context) error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = make(map[uint32][]wazero.Runtime)
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.mu.Unlock()
+
+	for _, pool := range idle {
+		for _, rt := range pool {
+			rt.Close(ctx)
+		}
+	}
+	if err := c.anchor.Close(ctx); err != nil {
+		return err
+	}
+	return c.compilation.Close(ctx)
+}
+
+// memoryLimitPages converts a megabyte limit to 64KiB WASM pages
+func memoryLimitPages(maxMemoryMB int) uint32 {
+	if maxMemoryMB <= 0 {
+		return 0
+	}
+	return uint32(maxMemoryMB * 16) // 16 pages per MB (1MB = 1024KB / 64KB)
+}
+
+// openRuntime returns a runtime with WASI and the host API for a single run
+// and a function that disposes of it. Pass reuse=true only if the run
+// succeeded and its guest module is closed. Without a cache every run gets a
+// fresh runtime.
+func openRuntime(ctx context.Context, cache *ModuleCache, pages uint32) (wazero.Runtime, func(reuse bool), error) {
+	if cache != nil {
+		rt, err := cache.AcquireRuntime(ctx, pages)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rt, func(reuse bool) {
+			if reuse {
+				cache.ReleaseRuntime(ctx, pages, rt)
+				return
+			}
+			rt.Close(ctx)
+		}, nil
+	}
+
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if pages > 0 {
+		config = config.WithMemoryLimitPages(pages)
+	}
+	rt := wazero.NewRuntimeWithConfig(ctx, config)
+	if err := instantiateImports(ctx, rt); err != nil {
+		rt.Close(ctx)
+		return nil, nil, err
+	}
+	return rt, func(bool) { rt.Close(ctx) }, nil
+}
+
+// instantiateImports adds the modules guests may import: WASI and the
+// zerostate host API
+func instantiateImports(ctx context.Context, rt wazero.Runtime) error {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt); err != nil {
+		return fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	return instantiateHostModule(ctx, rt)
+}
+
+// compileModule compiles through the cache when one is configured
+func compileModule(ctx context.Context, cache *ModuleCache, rt wazero.Runtime, binary []byte) (wazero.CompiledModule, error) {
+	if cache != nil {
+		return cache.Compile(ctx, rt, binary)
+	}
+	return rt.CompileModule(ctx, binary)
+}
+
+// closeCompiled releases a module from compileModule. Cached code is left to
+// the cache's eviction.
+func closeCompiled(ctx context.Context, cache *ModuleCache, compiled wazero.CompiledModule) {
+	if cache == nil {
+		compiled.Close(ctx)
+	}
+}
@@ -0,0 +1,101 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	"go.uber.org/zap"
+)
+
+func TestModuleCacheReusesCompiledModules(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewModuleCache(ctx, ModuleCacheConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	defer cache.Close(ctx)
+
+	for i := 0; i < 3; i++ {
+		rt, err := cache.AcquireRuntime(ctx, 16)
+		require.NoError(t, err)
+
+		compiled, err := cache.Compile(ctx, rt, simpleWASM)
+		require.NoError(t, err)
+
+		mod, err := rt.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+		require.NoError(t, err)
+		results, err := mod.ExportedFunction("add").Call(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), results[0])
+		require.NoError(t, mod.Close(ctx))
+
+		cache.ReleaseRuntime(ctx, 16, rt)
+	}
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, 1, stats.Modules)
+	assert.Equal(t, 1, stats.IdleRuntimes)
+	assert.True(t, cache.Contains(ModuleHash(simpleWASM)))
+}
+
+func TestModuleCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewModuleCache(ctx, ModuleCacheConfig{MaxModules: 1}, zap.NewNop())
+	require.NoError(t, err)
+	defer cache.Close(ctx)
+
+	// Returns 43 instead of 42: a distinct binary
+	other := append([]byte{}, simpleWASM...)
+	other[len(other)-2] = 0x2b
+
+	_, err = cache.Precompile(ctx, simpleWASM)
+	require.NoError(t, err)
+	_, err = cache.Precompile(ctx, other)
+	require.NoError(t, err)
+
+	assert.False(t, cache.Contains(ModuleHash(simpleWASM)))
+	assert.True(t, cache.Contains(ModuleHash(other)))
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+
+	_, err = cache.Precompile(ctx, []byte("not wasm"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, cache.Stats().Modules, "failed compilations must not be cached")
+}
+
+func TestBinaryCacheFetchesOnce(t *testing.T) {
+	cache, err := NewBinaryCache(BinaryCacheConfig{MaxBytes: 64, Dir: t.TempDir()}, zap.NewNop())
+	require.NoError(t, err)
+
+	var downloads int32
+	fetch := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&downloads, 1)
+		return simpleWASM, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		binary, err := cache.Fetch(context.Background(), "agents/a/hash.wasm", fetch)
+		require.NoError(t, err)
+		assert.Equal(t, simpleWASM, binary)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// A second key pushes the first out of memory, but it is still on disk
+	_, err = cache.Fetch(context.Background(), "agents/b/hash.wasm", fetch)
+	require.NoError(t, err)
+	binary, ok := cache.Get("agents/a/hash.wasm")
+	require.True(t, ok)
+	assert.Equal(t, simpleWASM, binary)
+	assert.Equal(t, int64(1), cache.Stats().DiskHits)
+
+	_, err = cache.Fetch(context.Background(), "agents/c/hash.wasm", func(context.Context, string) ([]byte, error) {
+		return nil, errors.New("not found")
+	})
+	assert.Error(t, err)
+	_, ok = cache.Get("agents/c/hash.wasm")
+	assert.False(t, ok, "failed downloads must not be cached")
+}
@@ -3,6 +3,7 @@ package execution
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -34,20 +35,29 @@ func (s *PostgresResultStore) StoreResult(ctx context.Context, result *TaskResul
 			stderr,
 			duration_ms,
 			error,
-			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			created_at,
+			fuel_used,
+			artifacts
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		ON CONFLICT (task_id) DO UPDATE SET
 			exit_code = EXCLUDED.exit_code,
 			stdout = EXCLUDED.stdout,
 			stderr = EXCLUDED.stderr,
 			duration_ms = EXCLUDED.duration_ms,
 			error = EXCLUDED.error,
-			created_at = EXCLUDED.created_at
+			created_at = EXCLUDED.created_at,
+			fuel_used = EXCLUDED.fuel_used,
+			artifacts = EXCLUDED.artifacts
 	`
 
 	durationMs := result.Duration.Milliseconds()
 
-	_, err := s.db.ExecContext(ctx, query,
+	artifacts, err := marshalArtifacts(result.Artifacts)
+	if err != nil {
+		return fmt.Errorf("failed to store result: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, query,
 		result.TaskID,
 		result.AgentID,
 		result.ExitCode,
@@ -56,6 +66,8 @@ func (s *PostgresResultStore) StoreResult(ctx context.Context, result *TaskResul
 		durationMs,
 		result.Error,
 		result.CreatedAt,
+		int64(result.FuelUsed),
+		artifacts,
 	)
 
 	if err != nil {
@@ -86,14 +98,17 @@ func (s *PostgresResultStore) GetResult(ctx context.Context, taskID string) (*Ta
 			stderr,
 			duration_ms,
 			error,
-			created_at
+			created_at,
+			COALESCE(fuel_used, 0),
+			artifacts
 		FROM task_results
 		WHERE task_id = $1
 	`
 
 	var result TaskResult
-	var durationMs int64
+	var durationMs, fuelUsed int64
 	var errorStr sql.NullString
+	var artifacts []byte
 
 	err := s.db.QueryRowContext(ctx, query, taskID).Scan(
 		&result.TaskID,
@@ -104,6 +119,8 @@ func (s *PostgresResultStore) GetResult(ctx context.Context, taskID string) (*Ta
 		&durationMs,
 		&errorStr,
 		&result.CreatedAt,
+		&fuelUsed,
+		&artifacts,
 	)
 
 	if err == sql.ErrNoRows {
@@ -118,6 +135,8 @@ func (s *PostgresResultStore) GetResult(ctx context.Context, taskID string) (*Ta
 	}
 
 	result.Duration = time.Duration(durationMs) * time.Millisecond
+	result.FuelUsed = uint64(fuelUsed)
+	result.Artifacts = unmarshalArtifacts(artifacts)
 	if errorStr.Valid {
 		result.Error = errorStr.String
 	}
@@ -140,7 +159,9 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 				stderr,
 				duration_ms,
 				error,
-				created_at
+				created_at,
+				COALESCE(fuel_used, 0),
+				artifacts
 			FROM task_results
 			WHERE agent_id = $1
 			ORDER BY created_at DESC
@@ -157,7 +178,9 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 				stderr,
 				duration_ms,
 				error,
-				created_at
+				created_at,
+				COALESCE(fuel_used, 0),
+				artifacts
 			FROM task_results
 			ORDER BY created_at DESC
 			LIMIT $1 OFFSET $2
@@ -175,8 +198,9 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 	var results []*TaskResult
 	for rows.Next() {
 		var result TaskResult
-		var durationMs int64
+		var durationMs, fuelUsed int64
 		var errorStr sql.NullString
+		var artifacts []byte
 
 		err := rows.Scan(
 			&result.TaskID,
@@ -187,6 +211,8 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 			&durationMs,
 			&errorStr,
 			&result.CreatedAt,
+			&fuelUsed,
+			&artifacts,
 		)
 		if err != nil {
 			s.logger.Error("failed to scan result row", zap.Error(err))
@@ -195,6 +221,8 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 
 		result.Duration = time.Duration(durationMs) * time.Millisecond
 		result.DurationMs = durationMs
+		result.FuelUsed = uint64(fuelUsed)
+		result.Artifacts = unmarshalArtifacts(artifacts)
 		if errorStr.Valid {
 			result.Error = errorStr.String
 		}
@@ -210,6 +238,30 @@ func (s *PostgresResultStore) ListResults(ctx context.Context, agentID string, l
 	return results, nil
 }
 
+// marshalArtifacts encodes artifacts for the JSONB column; none is NULL
+func marshalArtifacts(artifacts []Artifact) (interface{}, error) {
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(artifacts)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// unmarshalArtifacts decodes the JSONB column, ignoring malformed values
+func unmarshalArtifacts(data []byte) []Artifact {
+	if len(data) == 0 {
+		return nil
+	}
+	var artifacts []Artifact
+	if err := json.Unmarshal(data, &artifacts); err != nil {
+		return nil
+	}
+	return artifacts
+}
+
 // InitResultsTable creates the task_results table if it doesn't exist
 func (s *PostgresResultStore) InitResultsTable(ctx context.Context) error {
 	query := `
@@ -63,7 +63,9 @@ type TaskResult struct {
 	Stdout     []byte
 	Stderr     []byte
 	Duration   time.Duration
-	DurationMs int64  // Duration in milliseconds for database storage
+	DurationMs int64      // Duration in milliseconds for database storage
+	FuelUsed   uint64     // Metered instructions; 0 when unmetered
+	Artifacts  []Artifact // Output files collected from the task filesystem
 	Error      string
 	CreatedAt  time.Time
 }
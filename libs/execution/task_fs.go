@@ -0,0 +1,354 @@
+package execution
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+)
+
+// Guest paths of a task filesystem. Inputs are read-only; scratch and output
+// share one size cap, and only output is collected after the run.
+const (
+	InputMountPath   = "/input"
+	ScratchMountPath = "/scratch"
+	OutputMountPath  = "/output"
+)
+
+// DefaultScratchLimitBytes caps what a task may write to scratch and output
+const DefaultScratchLimitBytes = 64 << 20
+
+// InputArtifact is a file mounted read-only under /input, fetched from
+// storage by key or from the content store by CID
+type InputArtifact struct {
+	Path string `json:"path"` // Relative to /input
+	Key  string `json:"key,omitempty"`
+	CID  string `json:"cid,omitempty"`
+}
+
+// Validate checks that the artifact names one source and a relative path
+// that stays inside /input
+func (a InputArtifact) Validate() error {
+	if _, err := cleanArtifactPath(a.Path); err != nil {
+		return err
+	}
+	if (a.Key == "") == (a.CID == "") {
+		return fmt.Errorf("artifact %s must set exactly one of key or cid", a.Path)
+	}
+	return nil
+}
+
+// Artifact is an output file collected after a run
+type Artifact struct {
+	Path   string `json:"path"` // Relative to /output
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Key    string `json:"key,omitempty"` // Storage key, once uploaded
+	URL    string `json:"url,omitempty"`
+}
+
+// ArtifactStore is the part of storage.Storage task filesystems need
+type ArtifactStore interface {
+	Upload(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	Download(ctx context.Context, key string) ([]byte, error)
+}
+
+// TaskFS is the private filesystem of one task run. Each task gets its own
+// host directory, removed by Close, so concurrent tasks never share files.
+type TaskFS struct {
+	taskID string
+	root   string
+	quota  *fsQuota
+}
+
+// NewTaskFS creates an empty filesystem whose writable mounts may hold at
+// most limitBytes; 0 uses DefaultScratchLimitBytes
+func NewTaskFS(taskID string, limitBytes int64) (*TaskFS, error) {
+	if limitBytes <= 0 {
+		limitBytes = DefaultScratchLimitBytes
+	}
+
+	root, err := os.MkdirTemp("", "zerostate-task-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task filesystem: %w", err)
+	}
+	for _, dir := range []string{"input", "scratch", "output"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o700); err != nil {
+			os.RemoveAll(root)
+			return nil, fmt.Errorf("failed to create task filesystem: %w", err)
+		}
+	}
+
+	return &TaskFS{
+		taskID: taskID,
+		root:   root,
+		quota:  &fsQuota{limit: limitBytes},
+	}, nil
+}
+
+// Stage fetches inputs into /input. Keys are read from store and CIDs from
+// content; an artifact whose source isn't configured fails staging.
+func (f *TaskFS) Stage(ctx context.Context, inputs []InputArtifact, store ArtifactStore, content ContentFetcher) error {
+	for _, input := range inputs {
+		if err := input.Validate(); err != nil {
+			return err
+		}
+		rel, _ := cleanArtifactPath(input.Path)
+
+		var data []byte
+		var err error
+		switch {
+		case input.Key != "" && store != nil:
+			data, err = store.Download(ctx, input.Key)
+		case input.CID != "" && content != nil:
+			data, err = content.Get(ctx, input.CID)
+		default:
+			return fmt.Errorf("no store configured for artifact %s", input.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch artifact %s: %w", input.Path, err)
+		}
+
+		dest := filepath.Join(f.root, "input", filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+			return fmt.Errorf("failed to stage artifact %s: %w", input.Path, err)
+		}
+		if err := os.WriteFile(dest, data, 0o400); err != nil {
+			return fmt.Errorf("failed to stage artifact %s: %w", input.Path, err)
+		}
+	}
+	return nil
+}
+
+// Used returns the bytes the guest currently holds in scratch and output
+func (f *TaskFS) Used() int64 {
+	return f.quota.used()
+}
+
+// fsConfig mounts the task's directories for the guest
+func (f *TaskFS) fsConfig() wazero.FSConfig {
+	config := wazero.NewFSConfig().(sysfs.FSConfig).
+		WithSysFSMount(&sysfs.ReadFS{FS: sysfs.DirFS(filepath.Join(f.root, "input"))}, InputMountPath)
+	config = config.(sysfs.FSConfig).
+		WithSysFSMount(&cappedFS{FS: sysfs.DirFS(filepath.Join(f.root, "scratch")), quota: f.quota}, ScratchMountPath)
+	return config.(sysfs.FSConfig).
+		WithSysFSMount(&cappedFS{FS: sysfs.DirFS(filepath.Join(f.root, "output")), quota: f.quota}, OutputMountPath)
+}
+
+// Collect hashes every regular file under /output and, when store is set,
+// uploads it under tasks/{taskID}/artifacts/{path}
+func (f *TaskFS) Collect(ctx context.Context, store ArtifactStore) ([]Artifact, error) {
+	outputDir := filepath.Join(f.root, "output")
+	var artifacts []Artifact
+
+	err := filepath.WalkDir(outputDir, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, p)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		artifact := Artifact{
+			Path:   filepath.ToSlash(rel),
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		if store != nil {
+			artifact.Key = fmt.Sprintf("tasks/%s/artifacts/%s", f.taskID, artifact.Path)
+			artifact.URL, err = store.Upload(ctx, artifact.Key, data, "application/octet-stream")
+			if err != nil {
+				return fmt.Errorf("failed to upload artifact %s: %w", artifact.Path, err)
+			}
+		}
+		artifacts = append(artifacts, artifact)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+// Close wipes the filesystem from the host
+func (f *TaskFS) Close() error {
+	return os.RemoveAll(f.root)
+}
+
+type taskFSKey struct{}
+
+// WithTaskFS attaches a task filesystem to ctx; runners mount it for the guest
+func WithTaskFS(ctx context.Context, fs *TaskFS) context.Context {
+	return context.WithValue(ctx, taskFSKey{}, fs)
+}
+
+// withTaskFS mounts the filesystem carried by ctx, if any
+func withTaskFS(ctx context.Context, config wazero.ModuleConfig) wazero.ModuleConfig {
+	if fs, ok := ctx.Value(taskFSKey{}).(*TaskFS); ok && fs != nil {
+		return config.WithFSConfig(fs.fsConfig())
+	}
+	return config
+}
+
+// cleanArtifactPath normalizes a relative artifact path, refusing paths that
+// would leave their mount
+func cleanArtifactPath(p string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if p == "" || clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("invalid artifact path %q", p)
+	}
+	return clean, nil
+}
+
+// fsQuota tracks the bytes held by a task's writable mounts
+type fsQuota struct {
+	mu    sync.Mutex
+	limit int64
+	bytes int64
+}
+
+func (q *fsQuota) used() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes
+}
+
+// grow charges delta bytes, refusing growth past the limit
+func (q *fsQuota) grow(delta int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if delta > 0 && q.bytes+delta > q.limit {
+		return false
+	}
+	q.bytes += delta
+	return true
+}
+
+// errQuota is returned for writes past the cap. wazero has no ENOSPC.
+var errQuota = experimentalsys.EIO
+
+// cappedFS charges file growth to a quota. Links are refused: symlinks could
+// point outside the sandbox and hard links would let deleted bytes linger.
+type cappedFS struct {
+	experimentalsys.FS
+	quota *fsQuota
+}
+
+func (c *cappedFS) OpenFile(p string, flag experimentalsys.Oflag, perm iofs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	var before int64
+	if flag&experimentalsys.O_TRUNC != 0 {
+		before = c.size(p)
+	}
+	f, errno := c.FS.OpenFile(p, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	if before > 0 {
+		c.quota.grow(-before)
+	}
+	return &cappedFile{File: f, quota: c.quota}, 0
+}
+
+func (c *cappedFS) Unlink(p string) experimentalsys.Errno {
+	size := c.size(p)
+	if errno := c.FS.Unlink(p); errno != 0 {
+		return errno
+	}
+	c.quota.grow(-size)
+	return 0
+}
+
+func (c *cappedFS) Rename(from, to string) experimentalsys.Errno {
+	replaced := c.size(to)
+	if errno := c.FS.Rename(from, to); errno != 0 {
+		return errno
+	}
+	c.quota.grow(-replaced)
+	return 0
+}
+
+func (c *cappedFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	return experimentalsys.EPERM
+}
+
+func (c *cappedFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	return experimentalsys.EPERM
+}
+
+// size returns the size of a regular file, or 0
+func (c *cappedFS) size(p string) int64 {
+	st, errno := c.FS.Lstat(p)
+	if errno != 0 || !st.Mode.IsRegular() {
+		return 0
+	}
+	return st.Size
+}
+
+// cappedFile charges what a write adds to the file's size. A write that
+// would pass the cap is undone and fails.
+type cappedFile struct {
+	experimentalsys.File
+	quota *fsQuota
+}
+
+func (f *cappedFile) Write(buf []byte) (int, experimentalsys.Errno) {
+	var written int
+	n, errno := f.charge(func() (int, experimentalsys.Errno) {
+		var errno experimentalsys.Errno
+		written, errno = f.File.Write(buf)
+		return written, errno
+	})
+	// Rewind over an undone write so the next one doesn't leave a hole
+	if errno == errQuota && written > 0 && !f.File.IsAppend() {
+		f.File.Seek(-int64(written), io.SeekCurrent)
+	}
+	return n, errno
+}
+
+func (f *cappedFile) Pwrite(buf []byte, off int64) (int, experimentalsys.Errno) {
+	return f.charge(func() (int, experimentalsys.Errno) { return f.File.Pwrite(buf, off) })
+}
+
+func (f *cappedFile) Truncate(size int64) experimentalsys.Errno {
+	_, errno := f.charge(func() (int, experimentalsys.Errno) { return 0, f.File.Truncate(size) })
+	return errno
+}
+
+func (f *cappedFile) charge(op func() (int, experimentalsys.Errno)) (int, experimentalsys.Errno) {
+	before, errno := f.File.Stat()
+	if errno != 0 {
+		return 0, errno
+	}
+	n, errno := op()
+	after, statErrno := f.File.Stat()
+	if statErrno != 0 {
+		return n, statErrno
+	}
+	if !f.quota.grow(after.Size - before.Size) {
+		f.File.Truncate(before.Size)
+		return 0, errQuota
+	}
+	return n, errno
+}
+
+var _ experimentalsys.FS = (*cappedFS)(nil)
@@ -0,0 +1,180 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"go.uber.org/zap"
+)
+
+// copyGuestWASM assembles a WASI command that copies up to 64 bytes of
+// /input/in.txt to /output/out.txt. Preopens are numbered from 3 in mount
+// order: /input, /scratch, /output.
+func copyGuestWASM() []byte {
+	i32 := func(v int64) []byte { return appendS64([]byte{0x41}, v) }
+	call := func(fn byte) []byte { return []byte{0x10, fn, 0x1a} } // call, drop the errno
+	store := func(addr, value []byte) []byte {
+		return append(append(addr, value...), 0x36, 0x02, 0x00)
+	}
+	load := func(addr int64) []byte { return append(i32(addr), 0x28, 0x02, 0x00) }
+	readRights := []byte{0x42, 0x02, 0x42, 0x00} // fd_read only; write rights open read-write
+	allRights := []byte{0x42, 0x7f, 0x42, 0x7f}  // i64.const -1, twice
+
+	var body []byte
+	add := func(parts ...[]byte) {
+		for _, p := range parts {
+			body = append(body, p...)
+		}
+	}
+
+	// path_open(3, 0, "in.txt", 0, fd_read, 0, 0, &fd)
+	add(i32(3), i32(0), i32(0), i32(6), i32(0), readRights, i32(0), i32(100), call(0))
+	// fd_read(fd, {1024, 64}, 1, &nread)
+	add(store(i32(200), i32(1024)), store(i32(204), i32(64)))
+	add(load(100), i32(200), i32(1), i32(300), call(1))
+	// path_open(5, 0, "out.txt", O_CREAT|O_TRUNC, rights, rights, 0, &fd)
+	add(i32(5), i32(0), i32(16), i32(7), i32(9), allRights, i32(0), i32(100), call(0))
+	// fd_write(fd, {1024, nread}, 1, &nwritten)
+	add(store(i32(400), i32(1024)), store(i32(404), load(300)))
+	add(load(100), i32(400), i32(1), i32(500), call(2))
+	body = append(append([]byte{0x00}, body...), 0x0b)
+
+	types := []byte{
+		0x60, 0x09, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7e, 0x7e, 0x7f, 0x7f, 0x01, 0x7f, // 0: path_open
+		0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, // 1: fd_read, fd_write
+		0x60, 0x00, 0x00, // 2: _start
+	}
+
+	var imports []byte
+	for _, imp := range []struct {
+		name string
+		typ  byte
+	}{{"path_open", 0}, {"fd_read", 1}, {"fd_write", 1}} {
+		imports = append(imports, appendName(nil, "wasi_snapshot_preview1")...)
+		imports = append(imports, appendName(nil, imp.name)...)
+		imports = append(imports, 0x00, imp.typ)
+	}
+
+	var exports []byte
+	exports = append(exports, appendName(nil, "memory")...)
+	exports = append(exports, 0x02, 0x00)
+	exports = append(exports, appendName(nil, "_start")...)
+	exports = append(exports, 0x00, 0x03)
+
+	// Paths at offsets 0 and 16
+	data := []byte{0x00, 0x41, 0x00, 0x0b}
+	data = append(data, appendName(nil, "in.txt\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00out.txt")...)
+
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	wasm = append(wasm, encodeSection(0x01, 3, types)...)
+	wasm = append(wasm, encodeSection(0x02, 3, imports)...)
+	wasm = append(wasm, encodeSection(0x03, 1, []byte{0x02})...)
+	wasm = append(wasm, encodeSection(0x05, 1, []byte{0x00, 0x01})...)
+	wasm = append(wasm, encodeSection(0x07, 2, exports)...)
+	wasm = append(wasm, encodeSection(0x0a, 1, append(appendU32(nil, uint32(len(body))), body...))...)
+	wasm = append(wasm, encodeSection(0x0b, 1, data)...)
+	return wasm
+}
+
+// memStore is an in-memory ArtifactStore
+type memStore map[string][]byte
+
+func (m memStore) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	m[key] = append([]byte(nil), data...)
+	return "mem://" + key, nil
+}
+
+func (m memStore) Download(ctx context.Context, key string) ([]byte, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func TestTaskFSRunsGuestAgainstArtifacts(t *testing.T) {
+	ctx := context.Background()
+	store := memStore{"uploads/doc.txt": []byte("hello artifacts")}
+
+	taskFS, err := NewTaskFS("task-1", 0)
+	require.NoError(t, err)
+	require.NoError(t, taskFS.Stage(ctx, []InputArtifact{{Path: "in.txt", Key: "uploads/doc.txt"}}, store, nil))
+
+	runner := NewWASMRunner(zap.NewNop(), 10*time.Second)
+	result, err := runner.Execute(WithTaskFS(ctx, taskFS), copyGuestWASM(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+
+	artifacts, err := taskFS.Collect(ctx, store)
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, "out.txt", artifacts[0].Path)
+	assert.Equal(t, int64(15), artifacts[0].Size)
+	assert.Equal(t, "tasks/task-1/artifacts/out.txt", artifacts[0].Key)
+	assert.Equal(t, "hello artifacts", string(store[artifacts[0].Key]))
+	assert.Len(t, artifacts[0].SHA256, 64)
+	assert.Equal(t, int64(15), taskFS.Used())
+
+	// Closing wipes the host directory
+	root := taskFS.root
+	require.NoError(t, taskFS.Close())
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTaskFSStagingRejectsEscapes(t *testing.T) {
+	taskFS, err := NewTaskFS("task-1", 0)
+	require.NoError(t, err)
+	defer taskFS.Close()
+
+	for _, path := range []string{"", "/etc/passwd", "../x", "a/../../x"} {
+		assert.Error(t, InputArtifact{Path: path, Key: "k"}.Validate(), path)
+	}
+	assert.Error(t, InputArtifact{Path: "a", Key: "k", CID: "c"}.Validate())
+
+	// A CID without a content store can't be staged
+	err = taskFS.Stage(context.Background(), []InputArtifact{{Path: "a", CID: "bafy"}}, memStore{}, nil)
+	assert.Error(t, err)
+}
+
+func TestTaskFSScratchQuota(t *testing.T) {
+	dir := t.TempDir()
+	quota := &fsQuota{limit: 10}
+	fs := &cappedFS{FS: sysfs.DirFS(dir), quota: quota}
+
+	f, errno := fs.OpenFile("a", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.Zero(t, errno)
+	n, errno := f.Write([]byte("123456"))
+	require.Zero(t, errno)
+	assert.Equal(t, 6, n)
+
+	// Overwriting in place doesn't grow the file
+	_, errno = f.Pwrite([]byte("abcdef"), 0)
+	assert.Zero(t, errno)
+	assert.Equal(t, int64(6), quota.used())
+
+	// Growth past the cap fails and is undone
+	_, errno = f.Write([]byte("78901"))
+	assert.Equal(t, errQuota, errno)
+	data, _ := os.ReadFile(filepath.Join(dir, "a"))
+	assert.Equal(t, "abcdef", string(data))
+	_, errno = f.Write([]byte("7"))
+	assert.Zero(t, errno)
+	data, _ = os.ReadFile(filepath.Join(dir, "a"))
+	assert.Equal(t, "abcdef7", string(data))
+	require.Zero(t, f.Close())
+
+	// Deleting frees space; links are refused
+	assert.Equal(t, experimentalsys.EPERM, fs.Symlink("/etc/passwd", "b"))
+	assert.Equal(t, experimentalsys.EPERM, fs.Link("a", "b"))
+	require.Zero(t, fs.Unlink("a"))
+	assert.Equal(t, int64(0), quota.used())
+}
@@ -0,0 +1,12 @@
+package execution
+
+// Minimal valid WASM module that exports a simple function
+// (module (func (export "add") (result i32) i32.const 42))
+var simpleWASM = []byte{
+	0x00, 0x61, 0x73, 0x6d, // WASM magic number
+	0x01, 0x00, 0x00, 0x00, // WASM version 1
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // Type section: function type () -> i32
+	0x03, 0x02, 0x01, 0x00, // Function section: 1 function of type 0
+	0x07, 0x07, 0x01, 0x03, 0x61, 0x64, 0x64, 0x00, 0x00, // Export section: export function 0 as "add"
+	0x0a, 0x06, 0x01, 0x04, 0x00, 0x41, 0x2a, 0x0b, // Code section: function returns i32.const 42
+}
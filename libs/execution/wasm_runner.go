@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/tetratelabs/wazero"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/api"
 	"go.uber.org/zap"
 )
 
@@ -15,15 +15,19 @@ import (
 type WASMRunner struct {
 	logger  *zap.Logger
 	timeout time.Duration
+	cache   *ModuleCache // Optional: reuses compiled modules and runtimes
 }
 
 // WASMResult contains the execution result
 type WASMResult struct {
-	ExitCode int
-	Stdout   []byte
-	Stderr   []byte
-	Duration time.Duration
-	Error    error
+	ExitCode     int
+	Stdout       []byte
+	Stderr       []byte
+	Duration     time.Duration
+	FuelConsumed uint64     // Instructions charged; 0 when unmetered
+	Artifacts    []Artifact // Output files, when the run had a task filesystem
+	StorageUsed  int64      // Bytes held in the task filesystem's writable mounts
+	Error        error
 }
 
 // NewWASMRunner creates a new WASM runner
@@ -34,6 +38,11 @@ func NewWASMRunner(logger *zap.Logger, timeout time.Duration) *WASMRunner {
 	}
 }
 
+// SetModuleCache shares compiled modules and idle runtimes across runs
+func (r *WASMRunner) SetModuleCache(cache *ModuleCache) {
+	r.cache = cache
+}
+
 // Execute runs a WASM binary with the given input
 func (r *WASMRunner) Execute(ctx context.Context, wasmBinary []byte, input []byte) (*WASMResult, error) {
 	startTime := time.Now()
@@ -47,12 +56,13 @@ func (r *WASMRunner) Execute(ctx context.Context, wasmBinary []byte, input []byt
 	execCtx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
-	// Create new runtime instance (sandboxed)
-	runtime := wazero.NewRuntime(execCtx)
-	defer runtime.Close(execCtx)
-
-	// Instantiate WASI (provides filesystem, env, etc.)
-	wasi_snapshot_preview1.MustInstantiate(execCtx, runtime)
+	// Create runtime instance with WASI (sandboxed, pooled when cached)
+	runtime, release, err := openRuntime(execCtx, r.cache, 0)
+	if err != nil {
+		return &WASMResult{ExitCode: -1, Error: err, Duration: time.Since(startTime)}, err
+	}
+	reuse := false
+	defer func() { release(reuse) }()
 
 	// Capture stdout and stderr
 	stdoutBuf := &captureWriter{}
@@ -64,9 +74,10 @@ func (r *WASMRunner) Execute(ctx context.Context, wasmBinary []byte, input []byt
 		WithStderr(stderrBuf).
 		WithStdin(nil). // No stdin for now
 		WithStartFunctions("_start")
+	config = withTaskFS(ctx, config)
 
 	// Compile and instantiate the WASM module
-	compiled, err := runtime.CompileModule(execCtx, wasmBinary)
+	compiled, err := compileModule(execCtx, r.cache, runtime, wasmBinary)
 	if err != nil {
 		r.logger.Error("failed to compile WASM module", zap.Error(err))
 		return &WASMResult{
@@ -75,11 +86,12 @@ func (r *WASMRunner) Execute(ctx context.Context, wasmBinary []byte, input []byt
 			Duration: time.Since(startTime),
 		}, err
 	}
-	defer compiled.Close(execCtx)
+	defer closeCompiled(execCtx, r.cache, compiled)
 
 	// Instantiate and run
 	module, err := runtime.InstantiateModule(execCtx, compiled, config)
 	if err != nil {
+		err = interrupted(execCtx, err)
 		r.logger.Error("failed to instantiate WASM module", zap.Error(err))
 		return &WASMResult{
 			ExitCode: -1,
@@ -89,7 +101,8 @@ func (r *WASMRunner) Execute(ctx context.Context, wasmBinary []byte, input []byt
 			Duration: time.Since(startTime),
 		}, err
 	}
-	defer module.Close(execCtx)
+	module.Close(execCtx)
+	reuse = true
 
 	duration := time.Since(startTime)
 
@@ -119,8 +132,23 @@ func (r *WASMRunner) ExecuteWithLimits(ctx context.Context, wasmBinary []byte, i
 		zap.Int("input_size", len(input)),
 		zap.Int("max_memory_mb", limits.MaxMemoryMB),
 		zap.Duration("timeout", limits.Timeout),
+		zap.Uint64("max_fuel", limits.MaxFuel),
 	)
 
+	// Meter instructions when a fuel budget is set
+	if limits.MaxFuel > 0 {
+		instrumented, err := InstrumentFuel(wasmBinary)
+		if err != nil {
+			r.logger.Error("failed to instrument WASM module for fuel", zap.Error(err))
+			return &WASMResult{
+				ExitCode: -1,
+				Error:    fmt.Errorf("compilation failed: %w", err),
+				Duration: time.Since(startTime),
+			}, err
+		}
+		wasmBinary = instrumented
+	}
+
 	// Create context with timeout from limits
 	timeout := limits.Timeout
 	if timeout == 0 {
@@ -129,19 +157,13 @@ func (r *WASMRunner) ExecuteWithLimits(ctx context.Context, wasmBinary []byte, i
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Create runtime with memory limits
-	runtimeConfig := wazero.NewRuntimeConfig()
-	if limits.MaxMemoryMB > 0 {
-		// Convert MB to pages (each page is 64KB)
-		maxMemoryPages := uint32(limits.MaxMemoryMB * 16) // 16 pages per MB (1MB = 1024KB / 64KB)
-		runtimeConfig = runtimeConfig.WithMemoryLimitPages(maxMemoryPages)
+	// Create runtime with memory limits and WASI (pooled when cached)
+	runtime, release, err := openRuntime(execCtx, r.cache, memoryLimitPages(limits.MaxMemoryMB))
+	if err != nil {
+		return &WASMResult{ExitCode: -1, Error: err, Duration: time.Since(startTime)}, err
 	}
-
-	runtime := wazero.NewRuntimeWithConfig(execCtx, runtimeConfig)
-	defer runtime.Close(execCtx)
-
-	// Instantiate WASI (provides filesystem, env, etc.)
-	wasi_snapshot_preview1.MustInstantiate(execCtx, runtime)
+	reuse := false
+	defer func() { release(reuse) }()
 
 	// Capture stdout and stderr
 	stdoutBuf := &captureWriter{}
@@ -153,9 +175,10 @@ func (r *WASMRunner) ExecuteWithLimits(ctx context.Context, wasmBinary []byte, i
 		WithStderr(stderrBuf).
 		WithStdin(nil). // No stdin for now
 		WithStartFunctions("_start")
+	config = withTaskFS(ctx, config)
 
 	// Compile and instantiate the WASM module
-	compiled, err := runtime.CompileModule(execCtx, wasmBinary)
+	compiled, err := compileModule(execCtx, r.cache, runtime, wasmBinary)
 	if err != nil {
 		r.logger.Error("failed to compile WASM module", zap.Error(err))
 		return &WASMResult{
@@ -164,29 +187,39 @@ func (r *WASMRunner) ExecuteWithLimits(ctx context.Context, wasmBinary []byte, i
 			Duration: time.Since(startTime),
 		}, err
 	}
-	defer compiled.Close(execCtx)
+	defer closeCompiled(execCtx, r.cache, compiled)
 
 	// Instantiate and run
-	module, err := runtime.InstantiateModule(execCtx, compiled, config)
+	var module api.Module
+	var meter *fuelMeter
+	if limits.MaxFuel > 0 {
+		module, meter, err = instantiateMetered(execCtx, runtime, compiled, config, limits.MaxFuel, "_start")
+	} else {
+		module, err = runtime.InstantiateModule(execCtx, compiled, config)
+	}
 	if err != nil {
+		err = interrupted(execCtx, err)
 		r.logger.Error("failed to instantiate WASM module", zap.Error(err))
 		return &WASMResult{
-			ExitCode: -1,
-			Stdout:   stdoutBuf.Bytes(),
-			Stderr:   stderrBuf.Bytes(),
-			Error:    fmt.Errorf("instantiation failed: %w", err),
-			Duration: time.Since(startTime),
+			ExitCode:     -1,
+			Stdout:       stdoutBuf.Bytes(),
+			Stderr:       stderrBuf.Bytes(),
+			FuelConsumed: meter.Used(),
+			Error:        fmt.Errorf("instantiation failed: %w", err),
+			Duration:     time.Since(startTime),
 		}, err
 	}
-	defer module.Close(execCtx)
+	module.Close(execCtx)
+	reuse = true
 
 	duration := time.Since(startTime)
 
 	result := &WASMResult{
-		ExitCode: 0, // If we got here, execution succeeded
-		Stdout:   stdoutBuf.Bytes(),
-		Stderr:   stderrBuf.Bytes(),
-		Duration: duration,
+		ExitCode:     0, // If we got here, execution succeeded
+		Stdout:       stdoutBuf.Bytes(),
+		Stderr:       stderrBuf.Bytes(),
+		Duration:     duration,
+		FuelConsumed: meter.Used(),
 	}
 
 	r.logger.Info("WASM execution completed with limits",
@@ -195,6 +228,7 @@ func (r *WASMRunner) ExecuteWithLimits(ctx context.Context, wasmBinary []byte, i
 		zap.Int("stderr_size", len(result.Stderr)),
 		zap.Duration("duration", duration),
 		zap.Int("max_memory_mb", limits.MaxMemoryMB),
+		zap.Uint64("fuel_consumed", result.FuelConsumed),
 	)
 
 	return result, nil
@@ -205,6 +239,7 @@ type ResourceLimits struct {
 	MaxMemoryMB int
 	MaxCPUCores int
 	Timeout     time.Duration
+	MaxFuel     uint64 // Instruction budget; 0 leaves execution unmetered
 }
 
 // captureWriter captures bytes written to it
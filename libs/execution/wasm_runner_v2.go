@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
 	"go.uber.org/zap"
 )
 
@@ -17,6 +18,8 @@ type WASMRunnerV2 struct {
 	r2Storage   R2StorageInterface
 	timeout     time.Duration
 	maxMemoryMB int
+	cache       *ModuleCache // Optional: reuses compiled modules and runtimes
+	binaries    *BinaryCache // Optional: local copies of R2 binaries
 }
 
 // R2StorageInterface defines the interface for R2 storage operations
@@ -31,6 +34,12 @@ type WASMExecutionRequest struct {
 	Args        []interface{} // Function arguments
 	Timeout     time.Duration // Execution timeout
 	MaxMemoryMB int           // Memory limit
+	MaxFuel     uint64        // Instruction budget; 0 leaves execution unmetered
+
+	// Typed calls: when Spec is set, Input is passed through linear memory
+	// using the typed calling convention instead of numeric Args
+	Spec  *FunctionSpec
+	Input json.RawMessage
 }
 
 // WASMExecutionResult contains the execution output
@@ -40,6 +49,7 @@ type WASMExecutionResult struct {
 	Error      string        `json:"error,omitempty"`
 	Duration   time.Duration `json:"duration"`
 	MemoryUsed int64         `json:"memory_used"`
+	FuelUsed   uint64        `json:"fuel_used,omitempty"`
 }
 
 // NewWASMRunnerV2 creates a new WASM runner with R2 integration
@@ -52,6 +62,24 @@ func NewWASMRunnerV2(logger *zap.Logger, r2Storage R2StorageInterface, timeout t
 	}
 }
 
+// SetModuleCache shares compiled modules and idle runtimes across runs
+func (r *WASMRunnerV2) SetModuleCache(cache *ModuleCache) {
+	r.cache = cache
+}
+
+// SetBinaryCache serves repeat downloads from a local cache instead of R2
+func (r *WASMRunnerV2) SetBinaryCache(cache *BinaryCache) {
+	r.binaries = cache
+}
+
+// download fetches a binary from R2, through the binary cache when configured
+func (r *WASMRunnerV2) download(ctx context.Context, key string) ([]byte, error) {
+	if r.binaries != nil {
+		return r.binaries.Fetch(ctx, key, r.r2Storage.DownloadWASM)
+	}
+	return r.r2Storage.DownloadWASM(ctx, key)
+}
+
 // Execute runs a WASM binary loaded from R2
 func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (*WASMExecutionResult, error) {
 	startTime := time.Now()
@@ -63,7 +91,7 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 	)
 
 	// Step 1: Download WASM binary from R2
-	wasmBinary, err := r.r2Storage.DownloadWASM(ctx, req.R2Key)
+	wasmBinary, err := r.download(ctx, req.R2Key)
 	if err != nil {
 		return &WASMExecutionResult{
 			Success:  false,
@@ -77,6 +105,17 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 		zap.Int("size_bytes", len(wasmBinary)),
 	)
 
+	// Meter instructions when a fuel budget is set
+	if req.MaxFuel > 0 {
+		if wasmBinary, err = InstrumentFuel(wasmBinary); err != nil {
+			return &WASMExecutionResult{
+				Success:  false,
+				Error:    fmt.Sprintf("failed to instrument WASM for fuel: %v", err),
+				Duration: time.Since(startTime),
+			}, err
+		}
+	}
+
 	// Step 2: Set execution timeout
 	timeout := req.Timeout
 	if timeout == 0 {
@@ -85,23 +124,24 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Step 3: Create wazero runtime with memory limits
-	runtimeConfig := wazero.NewRuntimeConfig()
+	// Step 3: Create wazero runtime with memory limits (pooled when cached)
 	maxMemory := req.MaxMemoryMB
 	if maxMemory == 0 {
 		maxMemory = r.maxMemoryMB
 	}
-	if maxMemory > 0 {
-		// Convert MB to pages (64KB per page)
-		maxPages := uint32(maxMemory * 16)
-		runtimeConfig = runtimeConfig.WithMemoryLimitPages(maxPages)
+	runtime, release, err := openRuntime(execCtx, r.cache, memoryLimitPages(maxMemory))
+	if err != nil {
+		return &WASMExecutionResult{
+			Success:  false,
+			Error:    fmt.Sprintf("failed to create WASM runtime: %v", err),
+			Duration: time.Since(startTime),
+		}, err
 	}
+	reuse := false
+	defer func() { release(reuse) }()
 
-	runtime := wazero.NewRuntimeWithConfig(execCtx, runtimeConfig)
-	defer runtime.Close(execCtx)
-
-	// Step 4: Compile WASM module
-	compiled, err := runtime.CompileModule(execCtx, wasmBinary)
+	// Step 4: Compile WASM module (cached by binary hash)
+	compiled, err := compileModule(execCtx, r.cache, runtime, wasmBinary)
 	if err != nil {
 		return &WASMExecutionResult{
 			Success:  false,
@@ -109,19 +149,68 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 			Duration: time.Since(startTime),
 		}, err
 	}
-	defer compiled.Close(execCtx)
+	defer closeCompiled(execCtx, r.cache, compiled)
 
 	// Step 5: Instantiate module
-	module, err := runtime.InstantiateModule(execCtx, compiled, wazero.NewModuleConfig())
+	var module api.Module
+	var meter *fuelMeter
+	if req.MaxFuel > 0 {
+		module, meter, err = instantiateMetered(execCtx, runtime, compiled, wazero.NewModuleConfig(), req.MaxFuel, "_start")
+	} else {
+		module, err = runtime.InstantiateModule(execCtx, compiled, wazero.NewModuleConfig())
+	}
 	if err != nil {
 		return &WASMExecutionResult{
 			Success:  false,
 			Error:    fmt.Sprintf("failed to instantiate WASM: %v", err),
 			Duration: time.Since(startTime),
+			FuelUsed: meter.Used(),
 		}, err
 	}
 	defer module.Close(execCtx)
 
+	// Typed functions take and return encoded payloads instead of numbers
+	if req.Spec != nil {
+		spec := *req.Spec
+		if spec.Name == "" {
+			spec.Name = req.Function
+		}
+
+		result, err := callTyped(execCtx, module, spec, req.Input)
+		if err != nil {
+			if meter != nil {
+				err = meter.err(err)
+			}
+			err = interrupted(execCtx, err)
+			return &WASMExecutionResult{
+				Success:  false,
+				Error:    fmt.Sprintf("WASM execution failed: %v", err),
+				Duration: time.Since(startTime),
+				FuelUsed: meter.Used(),
+			}, err
+		}
+
+		duration := time.Since(startTime)
+		memStats := module.Memory().Size()
+		module.Close(execCtx)
+		reuse = true
+
+		r.logger.Info("typed WASM execution successful",
+			zap.String("function", spec.Name),
+			zap.String("encoding", spec.Encoding),
+			zap.Duration("duration", duration),
+			zap.Uint64("fuel_used", meter.Used()),
+		)
+
+		return &WASMExecutionResult{
+			Success:    true,
+			Result:     result,
+			Duration:   duration,
+			MemoryUsed: int64(memStats * 65536),
+			FuelUsed:   meter.Used(),
+		}, nil
+	}
+
 	// Step 6: Get the function to call
 	fn := module.ExportedFunction(req.Function)
 	if fn == nil {
@@ -157,10 +246,15 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 	// Step 8: Execute the function
 	results, err := fn.Call(execCtx, params...)
 	if err != nil {
+		if meter != nil {
+			err = meter.err(err)
+		}
+		err = interrupted(execCtx, err)
 		return &WASMExecutionResult{
 			Success:  false,
 			Error:    fmt.Sprintf("WASM execution failed: %v", err),
 			Duration: time.Since(startTime),
+			FuelUsed: meter.Used(),
 		}, err
 	}
 
@@ -174,12 +268,15 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 
 	// Step 10: Get memory usage
 	memStats := module.Memory().Size()
+	module.Close(execCtx)
+	reuse = true
 
 	r.logger.Info("WASM execution successful",
 		zap.String("function", req.Function),
 		zap.Any("result", result),
 		zap.Duration("duration", duration),
 		zap.Uint32("memory_pages", memStats),
+		zap.Uint64("fuel_used", meter.Used()),
 	)
 
 	return &WASMExecutionResult{
@@ -187,26 +284,31 @@ func (r *WASMRunnerV2) Execute(ctx context.Context, req *WASMExecutionRequest) (
 		Result:     result,
 		Duration:   duration,
 		MemoryUsed: int64(memStats * 65536), // Convert pages to bytes
+		FuelUsed:   meter.Used(),
 	}, nil
 }
 
 // ExecuteMultiple calls multiple functions in sequence
 func (r *WASMRunnerV2) ExecuteMultiple(ctx context.Context, r2Key string, calls []FunctionCall) ([]interface{}, error) {
 	// Download WASM once
-	wasmBinary, err := r.r2Storage.DownloadWASM(ctx, r2Key)
+	wasmBinary, err := r.download(ctx, r2Key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download WASM: %w", err)
 	}
 
 	// Create runtime
-	runtime := wazero.NewRuntime(ctx)
-	defer runtime.Close(ctx)
+	runtime, release, err := openRuntime(ctx, r.cache, memoryLimitPages(r.maxMemoryMB))
+	if err != nil {
+		return nil, err
+	}
+	reuse := false
+	defer func() { release(reuse) }()
 
-	compiled, err := runtime.CompileModule(ctx, wasmBinary)
+	compiled, err := compileModule(ctx, r.cache, runtime, wasmBinary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile WASM: %w", err)
 	}
-	defer compiled.Close(ctx)
+	defer closeCompiled(ctx, r.cache, compiled)
 
 	module, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
 	if err != nil {
@@ -238,6 +340,9 @@ func (r *WASMRunnerV2) ExecuteMultiple(ctx context.Context, r2Key string, calls
 		}
 	}
 
+	module.Close(ctx)
+	reuse = true
+
 	return results, nil
 }
 
@@ -249,6 +354,14 @@ type FunctionCall struct {
 
 // ValidateWASM validates a WASM binary without executing it
 func (r *WASMRunnerV2) ValidateWASM(ctx context.Context, wasmBinary []byte) error {
+	if r.cache != nil {
+		// Validating compiles the module, so keep the result for the first run
+		if _, err := r.cache.Precompile(ctx, wasmBinary); err != nil {
+			return fmt.Errorf("invalid WASM: %w", err)
+		}
+		return nil
+	}
+
 	runtime := wazero.NewRuntime(ctx)
 	defer runtime.Close(ctx)
 
@@ -44,11 +44,20 @@ type Endpoints struct {
 
 // Capability describes an agent capability
 type Capability struct {
-	Name     string                 `json:"name"`
-	Version  string                 `json:"version"`
-	Cost     *Cost                  `json:"cost,omitempty"`
-	Limits   map[string]interface{} `json:"limits,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Name      string                 `json:"name"`
+	Version   string                 `json:"version"`
+	Cost      *Cost                  `json:"cost,omitempty"`
+	Limits    map[string]interface{} `json:"limits,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Functions []Function             `json:"functions,omitempty"`
+}
+
+// Function declares a WASM export called with typed payloads
+type Function struct {
+	Name         string                 `json:"name"`
+	Encoding     string                 `json:"encoding,omitempty"` // json (default), msgpack or string
+	InputSchema  map[string]interface{} `json:"inputSchema,omitempty"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
 }
 
 // Cost describes pricing
@@ -71,9 +80,10 @@ type Reputation struct {
 
 // Policy holds operational policy
 type Policy struct {
-	SLAClass     string `json:"slaClass,omitempty"`
-	EnergyBudget string `json:"energyBudget,omitempty"`
-	Privacy      string `json:"privacy,omitempty"`
+	SLAClass         string   `json:"slaClass,omitempty"`
+	EnergyBudget     string   `json:"energyBudget,omitempty"`
+	Privacy          string   `json:"privacy,omitempty"`
+	HostCapabilities []string `json:"hostCapabilities,omitempty"` // WASM host functions granted; none by default
 }
 
 // Proof holds the cryptographic proof
@@ -0,0 +1,419 @@
+// Package ledger implements a double-entry journal that every balance change
+// in the platform is posted to.
+//
+// INVARIANTS (CRITICAL):
+//  1. Every entry balances: total debits == total credits
+//  2. Entries and postings are immutable; corrections are reversing entries
+//  3. Every entry carries an idempotency key; replays return the original entry
+//  4. Amounts are fixed-point integers (no float drift in account balances)
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Ledger errors
+var (
+	// ErrUnbalancedEntry indicates debits and credits of an entry differ
+	ErrUnbalancedEntry = errors.New("entry debits and credits must be equal")
+
+	// ErrInvalidEntry indicates a structurally invalid entry
+	ErrInvalidEntry = errors.New("invalid journal entry")
+
+	// ErrMissingIdempotencyKey indicates an entry was posted without an idempotency key
+	ErrMissingIdempotencyKey = errors.New("idempotency key is required")
+
+	// ErrIdempotencyConflict indicates an idempotency key was reused for a different entry
+	ErrIdempotencyConflict = errors.New("idempotency key already used for a different entry")
+
+	// ErrEntryNotFound indicates the journal entry does not exist
+	ErrEntryNotFound = errors.New("journal entry not found")
+
+	// ErrInsufficientFunds indicates an entry would overdraw a guarded account
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Amount is a fixed-point monetary amount with 8 decimal places,
+// matching the DECIMAL(20, 8) columns used for balances
+type Amount int64
+
+// AmountScale is the number of Amount units per whole currency unit
+const AmountScale = 100_000_000
+
+// FromFloat converts a float balance to a fixed-point Amount
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * AmountScale))
+}
+
+// Float64 converts the amount back to a float for APIs that still use floats
+func (a Amount) Float64() float64 {
+	return float64(a) / AmountScale
+}
+
+// String formats the amount with full precision
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+		a = -a
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, int64(a)/AmountScale, int64(a)%AmountScale)
+}
+
+// AccountType classifies an account and determines its normal balance side
+type AccountType string
+
+const (
+	AccountTypeAsset     AccountType = "asset"     // Funds held by the platform
+	AccountTypeLiability AccountType = "liability" // Funds owed to users, escrows, channels
+	AccountTypeEquity    AccountType = "equity"    // Opening balances and adjustments
+	AccountTypeRevenue   AccountType = "revenue"   // Platform fees
+	AccountTypeExpense   AccountType = "expense"   // Write-offs
+)
+
+// DebitNormal reports whether the account type's balance increases with debits
+func (t AccountType) DebitNormal() bool {
+	return t == AccountTypeAsset || t == AccountTypeExpense
+}
+
+// Direction is the side of a posting
+type Direction string
+
+const (
+	Debit  Direction = "debit"
+	Credit Direction = "credit"
+)
+
+// Well-known platform accounts
+const (
+	AccountCash        = "platform:cash"        // Funds deposited into the platform
+	AccountFees        = "platform:fees"        // Fees earned by the platform
+	AccountAdjustments = "platform:adjustments" // Manual balance adjustments
+)
+
+// Entry descriptions posted by platform services. Statements classify
+// entries by description, so services must use these constants.
+const (
+	DescDeposit        = "deposit"
+	DescWithdrawal     = "withdrawal"
+	DescEscrowFund     = "escrow fund"
+	DescEscrowRelease  = "escrow release"
+	DescEscrowRefund   = "escrow refund"
+	DescChannelDeposit = "channel deposit"
+	DescChannelEscrow  = "channel escrow"
+	DescChannelRelease = "channel release"
+	DescChannelRefund  = "channel refund"
+	DescChannelClose   = "channel close"
+	DescFee            = "fee"
+	DescBondLock       = "bond lock"
+	DescBondRelease    = "bond release"
+	DescBondForfeit    = "bond forfeit"
+)
+
+// MetadataTaskID is the entry metadata key holding the task an entry pays for
+const MetadataTaskID = "task_id"
+
+// Account prefixes for per-entity accounts
+const (
+	prefixUser    = "user:"
+	prefixEscrow  = "escrow:"
+	prefixChannel = "channel:"
+	prefixBond    = "bond:"
+)
+
+// UserAccount returns the ledger account holding a user's or agent's available balance
+func UserAccount(did string) string { return prefixUser + did }
+
+// EscrowAccount returns the ledger account holding funds locked in an escrow
+func EscrowAccount(escrowID string) string { return prefixEscrow + escrowID }
+
+// ChannelAccount returns the ledger account holding funds deposited into a payment channel
+func ChannelAccount(channelID string) string { return prefixChannel + channelID }
+
+// BondAccount returns the ledger account holding the bond an agent posted for a sealed-bid auction
+func BondAccount(agentDID, cfpID string) string { return prefixBond + cfpID + ":" + agentDID }
+
+// AccountTypeOf infers the type of an account from its identifier
+func AccountTypeOf(accountID string) AccountType {
+	switch accountID {
+	case AccountCash:
+		return AccountTypeAsset
+	case AccountFees:
+		return AccountTypeRevenue
+	case AccountAdjustments:
+		return AccountTypeEquity
+	}
+	return AccountTypeLiability
+}
+
+// Posting is one immutable line of a journal entry
+type Posting struct {
+	AccountID string    `json:"account_id"`
+	Direction Direction `json:"direction"`
+	Amount    Amount    `json:"amount"` // Always positive
+}
+
+// Entry is an immutable, balanced journal entry
+type Entry struct {
+	ID             uuid.UUID         `json:"id"`
+	IdempotencyKey string            `json:"idempotency_key"`
+	Description    string            `json:"description"`
+	Reference      string            `json:"reference,omitempty"` // Escrow, channel or task this entry belongs to
+	Postings       []Posting         `json:"postings"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	ReversesID     *uuid.UUID        `json:"reverses_id,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+
+	// NoOverdraft lists accounts the entry must not leave with a negative
+	// balance. Stores check it atomically with the append; it isn't stored.
+	NoOverdraft []string `json:"-"`
+}
+
+// Validate checks the double-entry invariants of an entry
+func (e *Entry) Validate() error {
+	if strings.TrimSpace(e.IdempotencyKey) == "" {
+		return ErrMissingIdempotencyKey
+	}
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("%w: at least two postings are required", ErrInvalidEntry)
+	}
+
+	var debits, credits Amount
+	for _, p := range e.Postings {
+		if p.AccountID == "" {
+			return fmt.Errorf("%w: posting has no account", ErrInvalidEntry)
+		}
+		if p.Amount <= 0 {
+			return fmt.Errorf("%w: posting amount must be positive", ErrInvalidEntry)
+		}
+		switch p.Direction {
+		case Debit:
+			debits += p.Amount
+		case Credit:
+			credits += p.Amount
+		default:
+			return fmt.Errorf("%w: unknown direction %q", ErrInvalidEntry, p.Direction)
+		}
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits %s, credits %s", ErrUnbalancedEntry, debits, credits)
+	}
+	return nil
+}
+
+// samePostings reports whether two entries move the same money, used to
+// distinguish idempotent replays from key collisions
+func samePostings(a, b *Entry) bool {
+	if len(a.Postings) != len(b.Postings) {
+		return false
+	}
+	for i := range a.Postings {
+		if a.Postings[i] != b.Postings[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AccountBalance is the balance of an account on its normal side
+type AccountBalance struct {
+	AccountID string      `json:"account_id"`
+	Type      AccountType `json:"type"`
+	Debits    Amount      `json:"debits"`
+	Credits   Amount      `json:"credits"`
+	Balance   Amount      `json:"balance"`
+}
+
+// TrialBalance sums every posting in the ledger
+type TrialBalance struct {
+	Debits   Amount `json:"debits"`
+	Credits  Amount `json:"credits"`
+	Balanced bool   `json:"balanced"`
+}
+
+// EntryFilter selects journal entries
+type EntryFilter struct {
+	AccountID string
+	Reference string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+}
+
+// Store persists journal entries
+type Store interface {
+	// Append stores a validated entry. If the idempotency key already exists
+	// the stored entry is returned with existing=true and nothing is written.
+	Append(ctx context.Context, entry *Entry) (stored *Entry, existing bool, err error)
+
+	// GetEntry returns an entry by ID
+	GetEntry(ctx context.Context, id uuid.UUID) (*Entry, error)
+
+	// Entries returns entries matching the filter, oldest first
+	Entries(ctx context.Context, filter EntryFilter) ([]*Entry, error)
+
+	// Balances returns balances of all accounts whose ID starts with prefix
+	Balances(ctx context.Context, prefix string) ([]AccountBalance, error)
+
+	// TrialBalance sums all postings
+	TrialBalance(ctx context.Context) (*TrialBalance, error)
+}
+
+// Ledger posts balanced entries to a Store
+type Ledger struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// New creates a ledger backed by store
+func New(store Store, logger *zap.Logger) *Ledger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Ledger{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Store returns the underlying store
+func (l *Ledger) Store() Store {
+	return l.store
+}
+
+// Post validates and appends an entry. Posting the same idempotency key again
+// returns the original entry; reusing a key for different postings fails.
+func (l *Ledger) Post(ctx context.Context, entry *Entry) (*Entry, error) {
+	if err := l.prepare(entry); err != nil {
+		return nil, err
+	}
+
+	stored, existing, err := l.store.Append(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append journal entry: %w", err)
+	}
+	return l.finish(entry, stored, existing)
+}
+
+// Transfer posts a simple two-legged entry moving amount from one account to another
+func (l *Ledger) Transfer(ctx context.Context, idempotencyKey, from, to string, amount Amount, description, reference string) (*Entry, error) {
+	return l.Post(ctx, NewTransfer(idempotencyKey, from, to, amount, description, reference))
+}
+
+// Reverse posts an entry that exactly undoes entryID
+func (l *Ledger) Reverse(ctx context.Context, entryID uuid.UUID, idempotencyKey, reason string) (*Entry, error) {
+	original, err := l.store.GetEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	reversal := &Entry{
+		IdempotencyKey: idempotencyKey,
+		Description:    "reversal: " + reason,
+		Reference:      original.Reference,
+		ReversesID:     &original.ID,
+	}
+	for _, p := range original.Postings {
+		flipped := p
+		if p.Direction == Debit {
+			flipped.Direction = Credit
+		} else {
+			flipped.Direction = Debit
+		}
+		reversal.Postings = append(reversal.Postings, flipped)
+	}
+
+	return l.Post(ctx, reversal)
+}
+
+// Balance returns the normal-side balance of a single account
+func (l *Ledger) Balance(ctx context.Context, accountID string) (Amount, error) {
+	balances, err := l.store.Balances(ctx, accountID)
+	if err != nil {
+		return 0, err
+	}
+	for _, b := range balances {
+		if b.AccountID == accountID {
+			return b.Balance, nil
+		}
+	}
+	return 0, nil
+}
+
+// Entries returns journal entries matching filter
+func (l *Ledger) Entries(ctx context.Context, filter EntryFilter) ([]*Entry, error) {
+	return l.store.Entries(ctx, filter)
+}
+
+// NewTransfer builds a two-legged entry: debit from, credit to.
+// For liability accounts (users, escrows) a debit decreases the balance.
+func NewTransfer(idempotencyKey, from, to string, amount Amount, description, reference string) *Entry {
+	return &Entry{
+		IdempotencyKey: idempotencyKey,
+		Description:    description,
+		Reference:      reference,
+		Postings: []Posting{
+			{AccountID: from, Direction: Debit, Amount: amount},
+			{AccountID: to, Direction: Credit, Amount: amount},
+		},
+	}
+}
+
+// prepare validates an entry and fills in its ID and timestamp
+func (l *Ledger) prepare(entry *Entry) error {
+	if entry == nil {
+		return ErrInvalidEntry
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// finish resolves idempotent replays and logs newly posted entries
+func (l *Ledger) finish(entry, stored *Entry, existing bool) (*Entry, error) {
+	if existing {
+		if !samePostings(entry, stored) {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyConflict, entry.IdempotencyKey)
+		}
+		return stored, nil
+	}
+
+	l.logger.Debug("journal entry posted",
+		zap.String("entry_id", stored.ID.String()),
+		zap.String("idempotency_key", stored.IdempotencyKey),
+		zap.String("reference", stored.Reference),
+		zap.Int("postings", len(stored.Postings)),
+	)
+	return stored, nil
+}
+
+// balanceOf computes the normal-side balance for an account
+func balanceOf(accountID string, debits, credits Amount) AccountBalance {
+	t := AccountTypeOf(accountID)
+	balance := credits - debits
+	if t.DebitNormal() {
+		balance = debits - credits
+	}
+	return AccountBalance{
+		AccountID: accountID,
+		Type:      t,
+		Debits:    debits,
+		Credits:   credits,
+		Balance:   balance,
+	}
+}
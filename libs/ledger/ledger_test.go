@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountConversion(t *testing.T) {
+	assert.Equal(t, Amount(150_000_000), FromFloat(1.5))
+	assert.Equal(t, 0.3, (FromFloat(0.1) + FromFloat(0.2)).Float64())
+	assert.Equal(t, "-2.00000001", Amount(-200_000_001).String())
+}
+
+func TestEntryValidate(t *testing.T) {
+	entry := NewTransfer("k1", UserAccount("alice"), EscrowAccount("e1"), FromFloat(10), "fund", "e1")
+	require.NoError(t, entry.Validate())
+
+	entry.Postings[1].Amount = FromFloat(9)
+	assert.ErrorIs(t, entry.Validate(), ErrUnbalancedEntry)
+
+	missingKey := NewTransfer("", "a", "b", 1, "", "")
+	assert.ErrorIs(t, missingKey.Validate(), ErrMissingIdempotencyKey)
+
+	zero := NewTransfer("k2", "a", "b", 0, "", "")
+	assert.ErrorIs(t, zero.Validate(), ErrInvalidEntry)
+}
+
+func TestLedgerPostAndBalances(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+
+	_, err := l.Transfer(ctx, "deposit-1", AccountCash, UserAccount("alice"), FromFloat(100), "deposit", "")
+	require.NoError(t, err)
+	_, err = l.Transfer(ctx, "fund-e1", UserAccount("alice"), EscrowAccount("e1"), FromFloat(40), "fund escrow", "e1")
+	require.NoError(t, err)
+
+	cash, err := l.Balance(ctx, AccountCash)
+	require.NoError(t, err)
+	assert.Equal(t, FromFloat(100), cash, "asset accounts are debit-normal")
+
+	alice, err := l.Balance(ctx, UserAccount("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, FromFloat(60), alice)
+
+	escrow, err := l.Balance(ctx, EscrowAccount("e1"))
+	require.NoError(t, err)
+	assert.Equal(t, FromFloat(40), escrow)
+
+	tb, err := l.Store().TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.True(t, tb.Balanced)
+
+	entries, err := l.Entries(ctx, EntryFilter{AccountID: UserAccount("alice")})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestLedgerIdempotency(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+
+	first, err := l.Transfer(ctx, "release-e1", EscrowAccount("e1"), UserAccount("bob"), FromFloat(5), "release", "e1")
+	require.NoError(t, err)
+
+	replay, err := l.Transfer(ctx, "release-e1", EscrowAccount("e1"), UserAccount("bob"), FromFloat(5), "release", "e1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, replay.ID)
+
+	bob, err := l.Balance(ctx, UserAccount("bob"))
+	require.NoError(t, err)
+	assert.Equal(t, FromFloat(5), bob, "replays must not post twice")
+
+	_, err = l.Transfer(ctx, "release-e1", EscrowAccount("e1"), UserAccount("bob"), FromFloat(6), "release", "e1")
+	assert.ErrorIs(t, err, ErrIdempotencyConflict)
+}
+
+func TestLedgerNoOverdraft(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+	_, err := l.Transfer(ctx, "deposit", AccountCash, UserAccount("alice"), FromFloat(5), DescDeposit, "")
+	require.NoError(t, err)
+
+	spend := func(key string, amount float64) error {
+		entry := NewTransfer(key, UserAccount("alice"), UserAccount("bob"), FromFloat(amount), "spend", "")
+		entry.NoOverdraft = []string{UserAccount("alice")}
+		_, err := l.Post(ctx, entry)
+		return err
+	}
+
+	assert.ErrorIs(t, spend("spend-1", 6), ErrInsufficientFunds)
+	require.NoError(t, spend("spend-2", 5))
+	assert.ErrorIs(t, spend("spend-3", 1), ErrInsufficientFunds)
+
+	alice, err := l.Balance(ctx, UserAccount("alice"))
+	require.NoError(t, err)
+	assert.Zero(t, alice)
+}
+
+func TestLedgerReverse(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+
+	entry, err := l.Transfer(ctx, "k", AccountCash, UserAccount("carol"), FromFloat(7), "deposit", "")
+	require.NoError(t, err)
+
+	reversal, err := l.Reverse(ctx, entry.ID, "k-reversal", "duplicate deposit")
+	require.NoError(t, err)
+	require.NotNil(t, reversal.ReversesID)
+	assert.Equal(t, entry.ID, *reversal.ReversesID)
+
+	carol, err := l.Balance(ctx, UserAccount("carol"))
+	require.NoError(t, err)
+	assert.Equal(t, Amount(0), carol)
+
+	entries, err := l.Entries(ctx, EntryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "the original entry is kept for the audit trail")
+}
@@ -0,0 +1,191 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is an in-memory Store for in-process services and tests
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []*Entry
+	byID    map[uuid.UUID]*Entry
+	byKey   map[string]*Entry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:  make(map[uuid.UUID]*Entry),
+		byKey: make(map[string]*Entry),
+	}
+}
+
+// Append implements Store
+func (s *MemoryStore) Append(ctx context.Context, entry *Entry) (*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.byKey[entry.IdempotencyKey]; ok {
+		return copyEntry(existing), true, nil
+	}
+
+	for _, accountID := range entry.NoOverdraft {
+		if s.balanceLocked(accountID, entry) < 0 {
+			return nil, false, fmt.Errorf("%w in %s", ErrInsufficientFunds, accountID)
+		}
+	}
+
+	stored := copyEntry(entry)
+	s.entries = append(s.entries, stored)
+	s.byID[stored.ID] = stored
+	s.byKey[stored.IdempotencyKey] = stored
+
+	return copyEntry(stored), false, nil
+}
+
+// GetEntry implements Store
+func (s *MemoryStore) GetEntry(ctx context.Context, id uuid.UUID) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.byID[id]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return copyEntry(entry), nil
+}
+
+// Entries implements Store
+func (s *MemoryStore) Entries(ctx context.Context, filter EntryFilter) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*Entry
+	for _, entry := range s.entries {
+		if !matchesFilter(entry, filter) {
+			continue
+		}
+		result = append(result, copyEntry(entry))
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Balances implements Store
+func (s *MemoryStore) Balances(ctx context.Context, prefix string) ([]AccountBalance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	debits := make(map[string]Amount)
+	credits := make(map[string]Amount)
+	for _, entry := range s.entries {
+		for _, p := range entry.Postings {
+			if !strings.HasPrefix(p.AccountID, prefix) {
+				continue
+			}
+			if p.Direction == Debit {
+				debits[p.AccountID] += p.Amount
+			} else {
+				credits[p.AccountID] += p.Amount
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(debits)+len(credits))
+	for id := range debits {
+		ids = append(ids, id)
+	}
+	for id := range credits {
+		if _, ok := debits[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	balances := make([]AccountBalance, 0, len(ids))
+	for _, id := range ids {
+		balances = append(balances, balanceOf(id, debits[id], credits[id]))
+	}
+	return balances, nil
+}
+
+// balanceLocked returns an account's balance with pending posted. Callers
+// must hold s.mu.
+func (s *MemoryStore) balanceLocked(accountID string, pending *Entry) Amount {
+	var debits, credits Amount
+	for _, entry := range append(s.entries, pending) {
+		for _, p := range entry.Postings {
+			if p.AccountID != accountID {
+				continue
+			}
+			if p.Direction == Debit {
+				debits += p.Amount
+			} else {
+				credits += p.Amount
+			}
+		}
+	}
+	return balanceOf(accountID, debits, credits).Balance
+}
+
+// TrialBalance implements Store
+func (s *MemoryStore) TrialBalance(ctx context.Context) (*TrialBalance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tb := &TrialBalance{}
+	for _, entry := range s.entries {
+		for _, p := range entry.Postings {
+			if p.Direction == Debit {
+				tb.Debits += p.Amount
+			} else {
+				tb.Credits += p.Amount
+			}
+		}
+	}
+	tb.Balanced = tb.Debits == tb.Credits
+	return tb, nil
+}
+
+// matchesFilter reports whether an entry is selected by filter
+func matchesFilter(entry *Entry, filter EntryFilter) bool {
+	if filter.Reference != "" && entry.Reference != filter.Reference {
+		return false
+	}
+	if filter.Since != nil && entry.CreatedAt.Before(*filter.Since) {
+		return false
+	}
+	if filter.Until != nil && !entry.CreatedAt.Before(*filter.Until) {
+		return false
+	}
+	if filter.AccountID != "" {
+		for _, p := range entry.Postings {
+			if p.AccountID == filter.AccountID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyEntry returns a deep copy so stored entries stay immutable
+func copyEntry(entry *Entry) *Entry {
+	c := *entry
+	c.Postings = append([]Posting(nil), entry.Postings...)
+	if entry.Metadata != nil {
+		c.Metadata = make(map[string]string, len(entry.Metadata))
+		for k, v := range entry.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,306 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Escrow statuses as stored in the escrows table
+const (
+	escrowStatusFunded   = "funded"
+	escrowStatusDisputed = "disputed"
+	escrowStatusReleased = "released"
+	escrowStatusRefunded = "refunded"
+)
+
+// DiscrepancyKind classifies a reconciliation finding
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyLedgerBalance: the escrow's ledger account does not hold what the escrow table says
+	DiscrepancyLedgerBalance DiscrepancyKind = "ledger_balance_mismatch"
+	// DiscrepancyOrphanBalance: a ledger escrow account holds funds but has no escrow row
+	DiscrepancyOrphanBalance DiscrepancyKind = "orphan_ledger_balance"
+	// DiscrepancyChainMissing: a funded escrow has no on-chain counterpart
+	DiscrepancyChainMissing DiscrepancyKind = "chain_escrow_missing"
+	// DiscrepancyChainState: on-chain escrow state disagrees with the escrow table
+	DiscrepancyChainState DiscrepancyKind = "chain_state_mismatch"
+	// DiscrepancyChainAmount: on-chain escrow amount disagrees with the escrow table
+	DiscrepancyChainAmount DiscrepancyKind = "chain_amount_mismatch"
+	// DiscrepancyTrialBalance: total debits and credits differ (should be impossible)
+	DiscrepancyTrialBalance DiscrepancyKind = "trial_balance_unbalanced"
+)
+
+// EscrowRecord is an escrow as recorded in the escrows table
+type EscrowRecord struct {
+	ID     string
+	TaskID string
+	Amount float64
+	Status string
+}
+
+// EscrowRecordSource lists escrows from the escrow tables
+type EscrowRecordSource interface {
+	ListEscrowRecords(ctx context.Context) ([]EscrowRecord, error)
+}
+
+// ChainEscrow is the on-chain view of an escrow
+type ChainEscrow struct {
+	State  string  // On-chain state name (Pending, Accepted, Completed, Refunded, Disputed)
+	Amount float64 // Amount in currency units
+}
+
+// ChainEscrowSource reads on-chain escrow state by task ID.
+// It returns (nil, nil) when the chain has no escrow for the task.
+type ChainEscrowSource interface {
+	ChainEscrow(ctx context.Context, taskID string) (*ChainEscrow, error)
+}
+
+// Discrepancy is a single reconciliation finding
+type Discrepancy struct {
+	Kind     DiscrepancyKind `json:"kind"`
+	EscrowID string          `json:"escrow_id,omitempty"`
+	TaskID   string          `json:"task_id,omitempty"`
+	Expected string          `json:"expected"`
+	Actual   string          `json:"actual"`
+}
+
+// ReconciliationReport compares the ledger, escrow tables and chain state
+type ReconciliationReport struct {
+	GeneratedAt    time.Time     `json:"generated_at"`
+	TrialBalance   *TrialBalance `json:"trial_balance"`
+	EscrowsChecked int           `json:"escrows_checked"`
+	ChainChecked   bool          `json:"chain_checked"`
+	Discrepancies  []Discrepancy `json:"discrepancies"`
+}
+
+// Clean reports whether reconciliation found no discrepancies
+func (r *ReconciliationReport) Clean() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// Reconciler produces reconciliation reports
+type Reconciler struct {
+	ledger  *Ledger
+	escrows EscrowRecordSource
+	chain   ChainEscrowSource // optional
+	logger  *zap.Logger
+}
+
+// NewReconciler creates a reconciler. chain may be nil when the blockchain is disabled.
+func NewReconciler(ledger *Ledger, escrows EscrowRecordSource, chain ChainEscrowSource, logger *zap.Logger) *Reconciler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Reconciler{
+		ledger:  ledger,
+		escrows: escrows,
+		chain:   chain,
+		logger:  logger,
+	}
+}
+
+// Reconcile runs all checks and returns the report
+// CHECKS: trial balance → ledger vs escrow table → orphan escrow balances → escrow table vs chain
+func (r *Reconciler) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{
+		GeneratedAt:   time.Now().UTC(),
+		ChainChecked:  r.chain != nil,
+		Discrepancies: []Discrepancy{},
+	}
+
+	tb, err := r.ledger.store.TrialBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.TrialBalance = tb
+	if !tb.Balanced {
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Kind:     DiscrepancyTrialBalance,
+			Expected: tb.Debits.String(),
+			Actual:   tb.Credits.String(),
+		})
+	}
+
+	records, err := r.escrows.ListEscrowRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escrows: %w", err)
+	}
+	report.EscrowsChecked = len(records)
+
+	balances, err := r.ledger.store.Balances(ctx, prefixEscrow)
+	if err != nil {
+		return nil, err
+	}
+	ledgerBalances := make(map[string]Amount, len(balances))
+	for _, b := range balances {
+		ledgerBalances[strings.TrimPrefix(b.AccountID, prefixEscrow)] = b.Balance
+	}
+
+	known := make(map[string]bool, len(records))
+	for _, rec := range records {
+		known[rec.ID] = true
+
+		expected := Amount(0)
+		if rec.Status == escrowStatusFunded || rec.Status == escrowStatusDisputed {
+			expected = FromFloat(rec.Amount)
+		}
+		if actual := ledgerBalances[rec.ID]; actual != expected {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:     DiscrepancyLedgerBalance,
+				EscrowID: rec.ID,
+				TaskID:   rec.TaskID,
+				Expected: expected.String(),
+				Actual:   actual.String(),
+			})
+		}
+
+		if r.chain != nil {
+			found, err := r.reconcileChain(ctx, rec)
+			if err != nil {
+				return nil, err
+			}
+			report.Discrepancies = append(report.Discrepancies, found...)
+		}
+	}
+
+	for escrowID, balance := range ledgerBalances {
+		if !known[escrowID] && balance != 0 {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:     DiscrepancyOrphanBalance,
+				EscrowID: escrowID,
+				Expected: Amount(0).String(),
+				Actual:   balance.String(),
+			})
+		}
+	}
+
+	r.logger.Info("ledger reconciliation completed",
+		zap.Int("escrows_checked", report.EscrowsChecked),
+		zap.Int("discrepancies", len(report.Discrepancies)),
+		zap.Bool("chain_checked", report.ChainChecked),
+	)
+
+	return report, nil
+}
+
+// ReconciliationJob reconciles on a schedule and keeps the latest report, so
+// serving a report never waits on one chain read per escrow
+type ReconciliationJob struct {
+	reconciler *Reconciler
+	logger     *zap.Logger
+
+	mu      sync.RWMutex
+	latest  *ReconciliationReport
+	lastErr error
+}
+
+// NewReconciliationJob creates a job running reconciler
+func NewReconciliationJob(reconciler *Reconciler, logger *zap.Logger) *ReconciliationJob {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ReconciliationJob{reconciler: reconciler, logger: logger}
+}
+
+// RunOnce reconciles and stores the report. A failed run keeps the previous report.
+func (j *ReconciliationJob) RunOnce(ctx context.Context) (*ReconciliationReport, error) {
+	report, err := j.reconciler.Reconcile(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.lastErr = err
+	if err != nil {
+		return nil, err
+	}
+	j.latest = report
+	return report, nil
+}
+
+// Latest returns the most recent report, nil before the first successful
+// run, and the error of the most recent run
+func (j *ReconciliationJob) Latest() (*ReconciliationReport, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.latest, j.lastErr
+}
+
+// Run reconciles every interval until ctx is done
+func (j *ReconciliationJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil {
+			j.logger.Warn("ledger reconciliation failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileChain compares one escrow row with its on-chain counterpart
+func (r *Reconciler) reconcileChain(ctx context.Context, rec EscrowRecord) ([]Discrepancy, error) {
+	onChain, err := r.chain.ChainEscrow(ctx, rec.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain escrow for task %s: %w", rec.TaskID, err)
+	}
+
+	if onChain == nil {
+		// Only escrows holding funds must exist on-chain
+		if rec.Status == escrowStatusFunded || rec.Status == escrowStatusDisputed {
+			return []Discrepancy{{
+				Kind:     DiscrepancyChainMissing,
+				EscrowID: rec.ID,
+				TaskID:   rec.TaskID,
+				Expected: rec.Status,
+				Actual:   "missing",
+			}}, nil
+		}
+		return nil, nil
+	}
+
+	var found []Discrepancy
+	if !chainStateMatches(rec.Status, onChain.State) {
+		found = append(found, Discrepancy{
+			Kind:     DiscrepancyChainState,
+			EscrowID: rec.ID,
+			TaskID:   rec.TaskID,
+			Expected: rec.Status,
+			Actual:   onChain.State,
+		})
+	}
+	if FromFloat(onChain.Amount) != FromFloat(rec.Amount) {
+		found = append(found, Discrepancy{
+			Kind:     DiscrepancyChainAmount,
+			EscrowID: rec.ID,
+			TaskID:   rec.TaskID,
+			Expected: FromFloat(rec.Amount).String(),
+			Actual:   FromFloat(onChain.Amount).String(),
+		})
+	}
+	return found, nil
+}
+
+// chainStateMatches maps escrow table statuses onto pallet-escrow states
+func chainStateMatches(status, chainState string) bool {
+	switch status {
+	case escrowStatusReleased:
+		return chainState == "Completed"
+	case escrowStatusRefunded:
+		return chainState == "Refunded"
+	case escrowStatusDisputed:
+		return chainState == "Disputed"
+	default:
+		return chainState == "Pending" || chainState == "Accepted"
+	}
+}
@@ -0,0 +1,120 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticEscrows []EscrowRecord
+
+func (s staticEscrows) ListEscrowRecords(ctx context.Context) ([]EscrowRecord, error) {
+	return s, nil
+}
+
+type staticChain map[string]*ChainEscrow
+
+func (s staticChain) ChainEscrow(ctx context.Context, taskID string) (*ChainEscrow, error) {
+	return s[taskID], nil
+}
+
+func TestReconcileClean(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+
+	_, err := l.Transfer(ctx, "fund-e1", UserAccount("alice"), EscrowAccount("e1"), FromFloat(10), "fund", "e1")
+	require.NoError(t, err)
+	_, err = l.Transfer(ctx, "fund-e2", UserAccount("alice"), EscrowAccount("e2"), FromFloat(3), "fund", "e2")
+	require.NoError(t, err)
+	_, err = l.Transfer(ctx, "release-e2", EscrowAccount("e2"), UserAccount("bob"), FromFloat(3), "release", "e2")
+	require.NoError(t, err)
+
+	escrows := staticEscrows{
+		{ID: "e1", TaskID: "t1", Amount: 10, Status: "funded"},
+		{ID: "e2", TaskID: "t2", Amount: 3, Status: "released"},
+	}
+	chain := staticChain{
+		"t1": {State: "Pending", Amount: 10},
+		"t2": {State: "Completed", Amount: 3},
+	}
+
+	report, err := NewReconciler(l, escrows, chain, nil).Reconcile(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Clean(), "%+v", report.Discrepancies)
+	assert.True(t, report.ChainChecked)
+	assert.Equal(t, 2, report.EscrowsChecked)
+}
+
+func TestReconcileFindsDiscrepancies(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+
+	// e1 funded in the table but never journaled; e9 journaled but unknown
+	_, err := l.Transfer(ctx, "fund-e9", UserAccount("alice"), EscrowAccount("e9"), FromFloat(2), "fund", "e9")
+	require.NoError(t, err)
+
+	escrows := staticEscrows{
+		{ID: "e1", TaskID: "t1", Amount: 10, Status: "funded"},
+		{ID: "e2", TaskID: "t2", Amount: 5, Status: "refunded"},
+		{ID: "e3", TaskID: "t3", Amount: 1, Status: "funded"},
+	}
+	chain := staticChain{
+		"t1": {State: "Pending", Amount: 9},
+		"t2": {State: "Completed", Amount: 5},
+	}
+
+	report, err := NewReconciler(l, escrows, chain, nil).Reconcile(ctx)
+	require.NoError(t, err)
+
+	kinds := make(map[DiscrepancyKind]int)
+	for _, d := range report.Discrepancies {
+		kinds[d.Kind]++
+	}
+	assert.Equal(t, 2, kinds[DiscrepancyLedgerBalance]) // e1, e3
+	assert.Equal(t, 1, kinds[DiscrepancyOrphanBalance]) // e9
+	assert.Equal(t, 1, kinds[DiscrepancyChainAmount])   // t1
+	assert.Equal(t, 1, kinds[DiscrepancyChainState])    // t2
+	assert.Equal(t, 1, kinds[DiscrepancyChainMissing])  // t3
+}
+
+func TestReconcileWithoutChain(t *testing.T) {
+	l := New(NewMemoryStore(), nil)
+	report, err := NewReconciler(l, staticEscrows{{ID: "e1", TaskID: "t1", Amount: 1, Status: "created"}}, nil, nil).Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Clean())
+	assert.False(t, report.ChainChecked)
+}
+
+type failingChain struct{}
+
+func (failingChain) ChainEscrow(ctx context.Context, taskID string) (*ChainEscrow, error) {
+	return nil, errors.New("chain unavailable")
+}
+
+func TestReconciliationJobKeepsLatestReport(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryStore(), nil)
+	escrows := staticEscrows{{ID: "e1", TaskID: "t1", Amount: 0, Status: "released"}}
+
+	job := NewReconciliationJob(NewReconciler(l, escrows, nil, nil), nil)
+	report, err := job.Latest()
+	assert.Nil(t, report)
+	assert.NoError(t, err)
+
+	first, err := job.RunOnce(ctx)
+	require.NoError(t, err)
+	report, err = job.Latest()
+	require.NoError(t, err)
+	assert.Same(t, first, report)
+
+	// A failed run reports its error but keeps serving the last good report
+	job.reconciler = NewReconciler(l, escrows, failingChain{}, nil)
+	_, err = job.RunOnce(ctx)
+	require.Error(t, err)
+	report, err = job.Latest()
+	assert.Error(t, err)
+	assert.Same(t, first, report)
+}
@@ -0,0 +1,355 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SQLStore persists the journal in PostgreSQL (see migration 008)
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a store backed by the ledger tables in db
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// TxAppender is implemented by stores that can append inside a caller's
+// transaction, so a balance change and its journal entry commit together
+type TxAppender interface {
+	AppendTx(ctx context.Context, tx *sql.Tx, entry *Entry) (stored *Entry, existing bool, err error)
+}
+
+// PostTx validates and appends an entry inside tx. The ledger's store must
+// implement TxAppender.
+func (l *Ledger) PostTx(ctx context.Context, tx *sql.Tx, entry *Entry) (*Entry, error) {
+	appender, ok := l.store.(TxAppender)
+	if !ok {
+		return nil, fmt.Errorf("ledger store %T does not support transactions", l.store)
+	}
+	if err := l.prepare(entry); err != nil {
+		return nil, err
+	}
+
+	stored, existing, err := appender.AppendTx(ctx, tx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append journal entry: %w", err)
+	}
+	return l.finish(entry, stored, existing)
+}
+
+// Append implements Store
+func (s *SQLStore) Append(ctx context.Context, entry *Entry) (*Entry, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, existing, err := s.AppendTx(ctx, tx, entry)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stored, existing, nil
+}
+
+// AppendTx implements TxAppender
+func (s *SQLStore) AppendTx(ctx context.Context, tx *sql.Tx, entry *Entry) (*Entry, bool, error) {
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode entry metadata: %w", err)
+	}
+
+	// ON CONFLICT makes concurrent replays of the same key safe
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_entries (
+			id, idempotency_key, description, reference, metadata, reverses_id, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING id
+	`,
+		entry.ID, entry.IdempotencyKey, entry.Description, entry.Reference,
+		metadata, entry.ReversesID, entry.CreatedAt,
+	).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		existing, err := s.getEntry(ctx, tx, "idempotency_key", entry.IdempotencyKey)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	for i, p := range entry.Postings {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_accounts (id, type)
+			VALUES ($1, $2)
+			ON CONFLICT (id) DO NOTHING
+		`, p.AccountID, AccountTypeOf(p.AccountID))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to register ledger account: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_postings (entry_id, line, account_id, direction, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, entry.ID, i, p.AccountID, p.Direction, int64(p.Amount), entry.CreatedAt)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to insert posting: %w", err)
+		}
+	}
+
+	for _, accountID := range entry.NoOverdraft {
+		if err := s.checkOverdraft(ctx, tx, accountID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return copyEntry(entry), false, nil
+}
+
+// checkOverdraft fails if accountID's balance inside tx is negative. Updating
+// the account row first holds its lock until tx ends, so concurrent guarded
+// postings to the same account are checked one after another.
+func (s *SQLStore) checkOverdraft(ctx context.Context, tx *sql.Tx, accountID string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE ledger_accounts SET type = type WHERE id = $1`, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to lock ledger account: %w", err)
+	}
+
+	var debits, credits int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'debit' THEN amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE 0 END), 0)
+		FROM ledger_postings
+		WHERE account_id = $1
+	`, accountID).Scan(&debits, &credits)
+	if err != nil {
+		return fmt.Errorf("failed to query balance: %w", err)
+	}
+	if balanceOf(accountID, Amount(debits), Amount(credits)).Balance < 0 {
+		return fmt.Errorf("%w in %s", ErrInsufficientFunds, accountID)
+	}
+	return nil
+}
+
+// GetEntry implements Store
+func (s *SQLStore) GetEntry(ctx context.Context, id uuid.UUID) (*Entry, error) {
+	return s.getEntry(ctx, s.db, "id", id)
+}
+
+// Entries implements Store
+func (s *SQLStore) Entries(ctx context.Context, filter EntryFilter) ([]*Entry, error) {
+	var conditions []string
+	var args []interface{}
+
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.AccountID != "" {
+		conditions = append(conditions,
+			"id IN (SELECT entry_id FROM ledger_postings WHERE account_id = "+addArg(filter.AccountID)+")")
+	}
+	if filter.Reference != "" {
+		conditions = append(conditions, "reference = "+addArg(filter.Reference))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < "+addArg(*filter.Until))
+	}
+
+	query := `
+		SELECT id, idempotency_key, description, reference, metadata, reverses_id, created_at
+		FROM ledger_entries
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at, id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + addArg(filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal entries: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := s.loadPostings(ctx, s.db, entry); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Balances implements Store
+func (s *SQLStore) Balances(ctx context.Context, prefix string) ([]AccountBalance, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account_id,
+		       COALESCE(SUM(CASE WHEN direction = 'debit' THEN amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE 0 END), 0)
+		FROM ledger_postings
+		WHERE account_id LIKE $1
+		GROUP BY account_id
+		ORDER BY account_id
+	`, escapeLike(prefix)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []AccountBalance
+	for rows.Next() {
+		var accountID string
+		var debits, credits int64
+		if err := rows.Scan(&accountID, &debits, &credits); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances = append(balances, balanceOf(accountID, Amount(debits), Amount(credits)))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balances: %w", err)
+	}
+	return balances, nil
+}
+
+// TrialBalance implements Store
+func (s *SQLStore) TrialBalance(ctx context.Context) (*TrialBalance, error) {
+	var debits, credits int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN direction = 'debit' THEN amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE 0 END), 0)
+		FROM ledger_postings
+	`).Scan(&debits, &credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute trial balance: %w", err)
+	}
+
+	return &TrialBalance{
+		Debits:   Amount(debits),
+		Credits:  Amount(credits),
+		Balanced: debits == credits,
+	}, nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *SQLStore) getEntry(ctx context.Context, q queryer, column string, value interface{}) (*Entry, error) {
+	row := q.QueryRowContext(ctx, `
+		SELECT id, idempotency_key, description, reference, metadata, reverses_id, created_at
+		FROM ledger_entries
+		WHERE `+column+` = $1
+	`, value)
+
+	entry, err := scanEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadPostings(ctx, q, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *SQLStore) loadPostings(ctx context.Context, q queryer, entry *Entry) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT account_id, direction, amount
+		FROM ledger_postings
+		WHERE entry_id = $1
+		ORDER BY line
+	`, entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query postings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Posting
+		var amount int64
+		if err := rows.Scan(&p.AccountID, &p.Direction, &amount); err != nil {
+			return fmt.Errorf("failed to scan posting: %w", err)
+		}
+		p.Amount = Amount(amount)
+		entry.Postings = append(entry.Postings, p)
+	}
+	return rows.Err()
+}
+
+func scanEntry(row rowScanner) (*Entry, error) {
+	var entry Entry
+	var metadata []byte
+	var reversesID uuid.NullUUID
+
+	err := row.Scan(
+		&entry.ID, &entry.IdempotencyKey, &entry.Description, &entry.Reference,
+		&metadata, &reversesID, &entry.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+	}
+
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &entry.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode entry metadata: %w", err)
+		}
+	}
+	if reversesID.Valid {
+		entry.ReversesID = &reversesID.UUID
+	}
+	return &entry, nil
+}
+
+// escapeLike escapes LIKE wildcards in an account prefix
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
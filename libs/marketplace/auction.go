@@ -6,12 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/economic"
-	"github.com/aidenlippert/zerostate/libs/identity"
 	"github.com/aidenlippert/zerostate/libs/p2p"
+	"github.com/aidenlippert/zerostate/libs/scoring"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -25,6 +26,10 @@ var (
 	ErrInvalidBid         = errors.New("invalid bid")
 	ErrNoBids             = errors.New("no bids received")
 	ErrInsufficientScore  = errors.New("agent reputation score too low")
+
+	// ErrUnsupportedAuctionType is returned for auction types CloseAuction
+	// can't settle. Dutch auctions run in the orchestrator's Auctioneer.
+	ErrUnsupportedAuctionType = errors.New("unsupported auction type")
 )
 
 // AuctionType represents the type of auction mechanism
@@ -33,7 +38,7 @@ type AuctionType string
 const (
 	AuctionTypeFirstPrice  AuctionType = "first_price"  // Winner pays their bid
 	AuctionTypeSecondPrice AuctionType = "second_price" // Winner pays second-highest bid (Vickrey)
-	AuctionTypeDutch       AuctionType = "dutch"        // Price decreases over time
+	AuctionTypeDutch       AuctionType = "dutch"        // Price decreases over time; run by orchestration.Auctioneer, not this service
 	AuctionTypeReserve     AuctionType = "reserve"      // Minimum price threshold
 )
 
@@ -63,9 +68,13 @@ type TaskAuction struct {
 	Duration       time.Duration `json:"duration"`
 	ExpiresAt      time.Time     `json:"expires_at"`
 	ReservePrice   float64       `json:"reserve_price,omitempty"`   // Minimum acceptable price
+	ReservePriceRef string       `json:"reserve_price_ref,omitempty"` // Price index reference such as "p25"; resolved into ReservePrice on creation
 	MaxPrice       float64       `json:"max_price"`                 // User's budget
 	MinReputation  float64       `json:"min_reputation,omitempty"`  // Minimum reputation score
 
+	// Scoring rule published with the announcement; nil uses scoring.DefaultRule
+	ScoringRule *scoring.Rule `json:"scoring_rule,omitempty"`
+
 	// Task Requirements
 	Capabilities []string               `json:"capabilities"`
 	Requirements map[string]string      `json:"requirements"`
@@ -95,6 +104,10 @@ type Bid struct {
 	EstimatedTime    time.Duration     `json:"estimated_time"`     // Estimated completion time
 	ReputationScore  float64           `json:"reputation_score"`   // Agent's reputation
 	QualityScore     float64           `json:"quality_score"`      // Past quality metrics
+	Region           string            `json:"region,omitempty"`    // Agent region
+	Privacy          string            `json:"privacy,omitempty"`   // identity.Policy privacy level
+	SLAClass         string            `json:"sla_class,omitempty"` // identity.Policy SLA class
+	Load             float64           `json:"load,omitempty"`      // Capacity utilization, 0-1
 	Metadata         map[string]string `json:"metadata"`
 
 	// Composite Score (calculated)
@@ -105,7 +118,7 @@ type Bid struct {
 type AuctionService struct {
 	mu              sync.RWMutex
 	messageBus      *p2p.MessageBus
-	reputationSvc   *economic.ReputationService
+	reputationSvc   ReputationService
 	logger          *zap.Logger
 
 	// Active auctions
@@ -124,12 +137,18 @@ type AuctionService struct {
 	metricsAuctionDuration   prometheus.Histogram
 	metricsBidsPerAuction    prometheus.Histogram
 	metricsWinningBidPrice   prometheus.Histogram
+
+	// Cleared-price index fed by awarded auctions
+	priceIndex *economic.PriceIndex
+
+	// Excludes bidders flagged for collusion
+	screen economic.BidderScreen
 }
 
 // NewAuctionService creates a new auction service
 func NewAuctionService(
 	messageBus *p2p.MessageBus,
-	reputationSvc *economic.ReputationService,
+	reputationSvc ReputationService,
 	logger *zap.Logger,
 ) *AuctionService {
 	as := &AuctionService{
@@ -201,8 +220,22 @@ func (as *AuctionService) CreateAuction(ctx context.Context, config *TaskAuction
 	}
 	config.ExpiresAt = now.Add(config.Duration)
 
-	if config.Type == "" {
+	switch config.Type {
+	case "":
 		config.Type = AuctionTypeSecondPrice // Default to Vickrey auction
+	case AuctionTypeFirstPrice, AuctionTypeSecondPrice, AuctionTypeReserve:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAuctionType, config.Type)
+	}
+
+	if err := as.resolveReservePriceLocked(config); err != nil {
+		return nil, err
+	}
+
+	if config.ScoringRule != nil {
+		if err := config.ScoringRule.Validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	config.Bids = make([]*Bid, 0)
@@ -229,6 +262,15 @@ func (as *AuctionService) CreateAuction(ctx context.Context, config *TaskAuction
 	return config, nil
 }
 
+// SetBidderScreen rejects bids from bidders the screen flags, e.g. DIDs
+// suspected of collusion
+func (as *AuctionService) SetBidderScreen(screen economic.BidderScreen) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.screen = screen
+}
+
 // SubmitBid submits a bid for an auction
 func (as *AuctionService) SubmitBid(ctx context.Context, auctionID string, bid *Bid) error {
 	as.mu.Lock()
@@ -240,6 +282,10 @@ func (as *AuctionService) SubmitBid(ctx context.Context, auctionID string, bid *
 		return ErrAuctionNotFound
 	}
 
+	if as.screen != nil && as.screen.IsFlagged(bid.AgentDID) {
+		return fmt.Errorf("%w: %s", ErrInvalidBid, economic.ErrBidderFlagged)
+	}
+
 	// Check auction status
 	if auction.Status != AuctionStatusOpen {
 		return ErrAuctionClosed
@@ -273,8 +319,12 @@ func (as *AuctionService) SubmitBid(ctx context.Context, auctionID string, bid *
 	bid.AuctionID = auctionID
 	bid.CreatedAt = time.Now()
 
-	// Calculate composite score
-	bid.CompositeScore = as.calculateCompositeScore(bid, auction)
+	// Enforce the scoring rule's hard constraints
+	scored := auctionScoringRule(auction).Score(bidOffer(bid))
+	if !scored.Eligible {
+		return fmt.Errorf("%w: %s", ErrInvalidBid, strings.Join(scored.Violations, "; "))
+	}
+	bid.CompositeScore = scored.Score
 
 	// Add bid
 	auction.Bids = append(auction.Bids, bid)
@@ -336,6 +386,7 @@ func (as *AuctionService) CloseAuction(ctx context.Context, auctionID string) (*
 	as.metricsAuctionDuration.Observe(duration.Seconds())
 	as.metricsBidsPerAuction.Observe(float64(len(auction.Bids)))
 	as.metricsWinningBidPrice.Observe(finalPrice)
+	as.recordClearedPriceLocked(auction)
 
 	as.logger.Info("auction awarded",
 		zap.String("auction_id", auctionID),
@@ -390,53 +441,28 @@ func (as *AuctionService) selectWinner(auction *TaskAuction) (*Bid, float64) {
 
 // calculateCompositeScore calculates overall ranking score for a bid
 func (as *AuctionService) calculateCompositeScore(bid *Bid, auction *TaskAuction) float64 {
-	// Composite score formula:
-	// Score = w1*(1/normalized_price) + w2*reputation + w3*quality + w4*(1/normalized_time)
-
-	// Weights (sum to 1.0)
-	const (
-		weightPrice      = 0.40  // 40% - Price is important
-		weightReputation = 0.30  // 30% - Reputation matters
-		weightQuality    = 0.20  // 20% - Past quality
-		weightTime       = 0.10  // 10% - Speed
-	)
-
-	// Normalize price (lower is better, so invert)
-	// Score ranges from 0 to 1, where 1 is best
-	priceScore := 0.0
-	if auction.MaxPrice > 0 {
-		// Invert: low price = high score
-		priceScore = 1.0 - (bid.Price / auction.MaxPrice)
-	}
+	return auctionScoringRule(auction).Score(bidOffer(bid)).Score
+}
 
-	// Reputation score (0 to 1, where 1 is perfect reputation)
-	reputationScore := bid.ReputationScore / 100.0
-	if reputationScore > 1.0 {
-		reputationScore = 1.0
-	}
+// auctionScoringRule returns the auction's scoring rule with the budget and
+// timeout filling in anything it leaves open
+func auctionScoringRule(auction *TaskAuction) *scoring.Rule {
+	return auction.ScoringRule.WithDefaults(auction.MaxPrice, auction.Timeout.Milliseconds())
+}
 
-	// Quality score (0 to 1)
-	qualityScore := bid.QualityScore / 100.0
-	if qualityScore > 1.0 {
-		qualityScore = 1.0
+// bidOffer converts a bid to a scoring offer. Reputation and quality are
+// reported on a 0-100 scale.
+func bidOffer(bid *Bid) scoring.Offer {
+	return scoring.Offer{
+		Price:      bid.Price,
+		LatencyMS:  bid.EstimatedTime.Milliseconds(),
+		Reputation: bid.ReputationScore / 100.0,
+		Quality:    bid.QualityScore / 100.0,
+		Region:     bid.Region,
+		Privacy:    bid.Privacy,
+		SLAClass:   bid.SLAClass,
+		Load:       bid.Load,
 	}
-
-	// Time score (faster is better, so invert)
-	timeScore := 0.0
-	if auction.Timeout > 0 && bid.EstimatedTime > 0 {
-		timeScore = 1.0 - float64(bid.EstimatedTime)/float64(auction.Timeout)
-		if timeScore < 0 {
-			timeScore = 0
-		}
-	}
-
-	// Calculate weighted composite score
-	compositeScore := (weightPrice * priceScore) +
-		(weightReputation * reputationScore) +
-		(weightQuality * qualityScore) +
-		(weightTime * timeScore)
-
-	return compositeScore
 }
 
 // GetAuction retrieves an auction by ID
@@ -484,6 +510,10 @@ func (as *AuctionService) CancelAuction(auctionID string) error {
 
 // broadcastAuction broadcasts auction to the P2P network
 func (as *AuctionService) broadcastAuction(ctx context.Context, auction *TaskAuction) {
+	if as.messageBus == nil {
+		return
+	}
+
 	// Create auction announcement
 	announcement := map[string]interface{}{
 		"auction_id":    auction.ID,
@@ -497,6 +527,7 @@ func (as *AuctionService) broadcastAuction(ctx context.Context, auction *TaskAuc
 		"expires_at":    auction.ExpiresAt.Unix(),
 		"timeout":       auction.Timeout.Seconds(),
 	}
+	announcement["scoring_rule"] = auctionScoringRule(auction)
 
 	payload, err := json.Marshal(announcement)
 	if err != nil {
@@ -549,6 +580,9 @@ type AuctionStats struct {
 	TotalBids        int     `json:"total_bids"`
 	AvgBidsPerAuction float64 `json:"avg_bids_per_auction"`
 	AvgWinningPrice  float64 `json:"avg_winning_price"`
+
+	// Rolling cleared-price percentiles per capability, when a price index is attached
+	PriceQuotes []*economic.PriceQuote `json:"price_quotes,omitempty"`
 }
 
 // GetStats returns auction statistics
@@ -586,6 +620,9 @@ func (as *AuctionService) GetStats() *AuctionStats {
 	if awardedCount > 0 {
 		stats.AvgWinningPrice = totalWinningPrice / float64(awardedCount)
 	}
+	if as.priceIndex != nil {
+		stats.PriceQuotes = as.priceIndex.Quotes()
+	}
 
 	return stats
 }
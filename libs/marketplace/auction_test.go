@@ -0,0 +1,124 @@
+package marketplace
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/aidenlippert/zerostate/libs/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	testAuctionsOnce sync.Once
+	testAuctions     *AuctionService
+)
+
+// newTestAuctionService returns the package's shared auction service. Its
+// metrics register with the default registry, so there can only be one.
+func newTestAuctionService(t *testing.T) *AuctionService {
+	t.Helper()
+	testAuctionsOnce.Do(func() {
+		testAuctions = NewAuctionService(nil, nil, zap.NewNop())
+	})
+	testAuctions.SetPriceIndex(nil)
+	testAuctions.SetBidderScreen(nil)
+	return testAuctions
+}
+
+func TestSubmitBidScoresWithSharedRule(t *testing.T) {
+	ctx := context.Background()
+	as := newTestAuctionService(t)
+
+	rule := scoring.DefaultRule(100, 10000)
+	rule.Constraints.Regions = []string{"eu"}
+	auction, err := as.CreateAuction(ctx, &TaskAuction{
+		TaskID:      "task-scored",
+		Type:        AuctionTypeFirstPrice,
+		MaxPrice:    100,
+		Timeout:     10 * time.Second,
+		ScoringRule: rule,
+	})
+	require.NoError(t, err)
+
+	cheap := &Bid{AgentDID: "did:key:cheap", Price: 20, EstimatedTime: 8 * time.Second, ReputationScore: 40, QualityScore: 50, Region: "eu"}
+	fast := &Bid{AgentDID: "did:key:fast", Price: 60, EstimatedTime: time.Second, ReputationScore: 90, QualityScore: 95, Region: "eu"}
+	require.NoError(t, as.SubmitBid(ctx, auction.ID, cheap))
+	require.NoError(t, as.SubmitBid(ctx, auction.ID, fast))
+
+	// The marketplace ranks bids exactly as the orchestrator's scored
+	// selection would, using the rule published with the auction
+	for _, bid := range []*Bid{cheap, fast} {
+		want := rule.Score(scoring.Offer{
+			Price:      bid.Price,
+			LatencyMS:  bid.EstimatedTime.Milliseconds(),
+			Reputation: bid.ReputationScore / 100.0,
+			Quality:    bid.QualityScore / 100.0,
+			Region:     bid.Region,
+		})
+		assert.InDelta(t, want.Score, bid.CompositeScore, 1e-9, bid.AgentDID)
+	}
+
+	winner, err := as.CloseAuction(ctx, auction.ID)
+	require.NoError(t, err)
+	if cheap.CompositeScore > fast.CompositeScore {
+		assert.Equal(t, cheap.AgentDID, winner.AgentDID)
+	} else {
+		assert.Equal(t, fast.AgentDID, winner.AgentDID)
+	}
+}
+
+func TestSubmitBidRejectsIneligibleBid(t *testing.T) {
+	ctx := context.Background()
+	as := newTestAuctionService(t)
+
+	rule := scoring.DefaultRule(100, 10000)
+	rule.Constraints.Regions = []string{"eu"}
+	auction, err := as.CreateAuction(ctx, &TaskAuction{
+		TaskID:      "task-ineligible",
+		MaxPrice:    100,
+		Timeout:     10 * time.Second,
+		ScoringRule: rule,
+	})
+	require.NoError(t, err)
+
+	err = as.SubmitBid(ctx, auction.ID, &Bid{AgentDID: "did:key:us", Price: 10, Region: "us"})
+	assert.ErrorIs(t, err, ErrInvalidBid)
+
+	_, err = as.CreateAuction(ctx, &TaskAuction{
+		TaskID:      "task-bad-rule",
+		ScoringRule: &scoring.Rule{},
+	})
+	assert.ErrorIs(t, err, scoring.ErrInvalidRule)
+}
+
+type flaggedBidders map[string]bool
+
+func (f flaggedBidders) IsFlagged(did string) bool { return f[did] }
+
+func TestSubmitBidRejectsFlaggedBidder(t *testing.T) {
+	ctx := context.Background()
+	as := newTestAuctionService(t)
+	as.SetBidderScreen(flaggedBidders{"did:key:shill": true})
+
+	auction, err := as.CreateAuction(ctx, &TaskAuction{
+		TaskID:   "task-screened",
+		MaxPrice: 100,
+		Timeout:  10 * time.Second,
+	})
+	require.NoError(t, err)
+
+	err = as.SubmitBid(ctx, auction.ID, &Bid{AgentDID: "did:key:shill", Price: 10})
+	assert.ErrorIs(t, err, ErrInvalidBid)
+	assert.ErrorContains(t, err, economic.ErrBidderFlagged.Error())
+	require.NoError(t, as.SubmitBid(ctx, auction.ID, &Bid{AgentDID: "did:key:honest", Price: 10}))
+
+	stored, err := as.GetAuction(auction.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Bids, 1)
+	assert.Equal(t, "did:key:honest", stored.Bids[0].AgentDID)
+}
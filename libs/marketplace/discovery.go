@@ -13,7 +13,6 @@ import (
 
 	"github.com/aidenlippert/zerostate/libs/identity"
 	"github.com/aidenlippert/zerostate/libs/p2p"
-	"github.com/aidenlippert/zerostate/libs/reputation"
 )
 
 var (
@@ -106,10 +105,10 @@ type DiscoveryService struct {
 	index *CapabilityIndex
 
 	// P2P integration for distributed discovery
-	messageBus p2p.MessageBus
+	messageBus *p2p.MessageBus
 
 	// Reputation integration
-	reputationService *reputation.ReputationService
+	reputationService ReputationService
 
 	// Health checking
 	healthCheckInterval    time.Duration
@@ -154,10 +153,10 @@ func (idx *CapabilityIndex) AddAgent(record *AgentRecord) {
 
 	// Update inverted index
 	for _, capability := range record.AgentCard.Capabilities {
-		if idx.capabilityToAgents[capability] == nil {
-			idx.capabilityToAgents[capability] = make(map[string]bool)
+		if idx.capabilityToAgents[capability.Name] == nil {
+			idx.capabilityToAgents[capability.Name] = make(map[string]bool)
 		}
-		idx.capabilityToAgents[capability][agentDID] = true
+		idx.capabilityToAgents[capability.Name][agentDID] = true
 	}
 }
 
@@ -173,9 +172,9 @@ func (idx *CapabilityIndex) RemoveAgent(agentDID string) {
 
 	// Remove from inverted index
 	for _, capability := range record.AgentCard.Capabilities {
-		delete(idx.capabilityToAgents[capability], agentDID)
-		if len(idx.capabilityToAgents[capability]) == 0 {
-			delete(idx.capabilityToAgents, capability)
+		delete(idx.capabilityToAgents[capability.Name], agentDID)
+		if len(idx.capabilityToAgents[capability.Name]) == 0 {
+			delete(idx.capabilityToAgents, capability.Name)
 		}
 	}
 
@@ -264,10 +263,10 @@ func (idx *CapabilityIndex) UpdateAgent(record *AgentRecord) error {
 	} else {
 		oldCaps := make(map[string]bool)
 		for _, cap := range oldRecord.AgentCard.Capabilities {
-			oldCaps[cap] = true
+			oldCaps[cap.Name] = true
 		}
 		for _, cap := range record.AgentCard.Capabilities {
-			if !oldCaps[cap] {
+			if !oldCaps[cap.Name] {
 				capabilitiesChanged = true
 				break
 			}
@@ -278,18 +277,18 @@ func (idx *CapabilityIndex) UpdateAgent(record *AgentRecord) error {
 	if capabilitiesChanged {
 		// Remove old capability mappings
 		for _, capability := range oldRecord.AgentCard.Capabilities {
-			delete(idx.capabilityToAgents[capability], agentDID)
-			if len(idx.capabilityToAgents[capability]) == 0 {
-				delete(idx.capabilityToAgents, capability)
+			delete(idx.capabilityToAgents[capability.Name], agentDID)
+			if len(idx.capabilityToAgents[capability.Name]) == 0 {
+				delete(idx.capabilityToAgents, capability.Name)
 			}
 		}
 
 		// Add new capability mappings
 		for _, capability := range record.AgentCard.Capabilities {
-			if idx.capabilityToAgents[capability] == nil {
-				idx.capabilityToAgents[capability] = make(map[string]bool)
+			if idx.capabilityToAgents[capability.Name] == nil {
+				idx.capabilityToAgents[capability.Name] = make(map[string]bool)
 			}
-			idx.capabilityToAgents[capability][agentDID] = true
+			idx.capabilityToAgents[capability.Name][agentDID] = true
 		}
 	}
 
@@ -300,8 +299,8 @@ func (idx *CapabilityIndex) UpdateAgent(record *AgentRecord) error {
 
 // NewDiscoveryService creates a new discovery service
 func NewDiscoveryService(
-	messageBus p2p.MessageBus,
-	reputationService *reputation.ReputationService,
+	messageBus *p2p.MessageBus,
+	reputationService ReputationService,
 ) *DiscoveryService {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -359,7 +358,7 @@ func (ds *DiscoveryService) RegisterAgent(ctx context.Context, agentCard *identi
 	if ds.reputationService != nil {
 		score, err := ds.reputationService.GetScore(ctx, agentCard.DID)
 		if err == nil {
-			reputationScore = score.OverallScore
+			reputationScore = score
 		}
 	}
 
@@ -615,8 +614,8 @@ func (ds *DiscoveryService) checkAgentHealth(record *AgentRecord) {
 
 	// Send health check request via P2P
 	req := &p2p.TaskRequest{
-		TaskID: fmt.Sprintf("health-check-%d", time.Now().Unix()),
-		Type:   "health-check",
+		TaskID:       fmt.Sprintf("health-check-%d", time.Now().Unix()),
+		Requirements: map[string]string{"type": "health-check"},
 	}
 
 	startTime := time.Now()
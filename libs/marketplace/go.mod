@@ -1,3 +1,23 @@
 module github.com/aidenlippert/zerostate/libs/marketplace
 
-go 1.23
+go 1.24.10
+
+require (
+	github.com/aidenlippert/zerostate/libs/economic v0.0.0
+	github.com/aidenlippert/zerostate/libs/identity v0.0.0
+	github.com/aidenlippert/zerostate/libs/orchestration v0.0.0
+	github.com/aidenlippert/zerostate/libs/p2p v0.0.0
+	github.com/aidenlippert/zerostate/libs/scoring v0.0.0
+	github.com/google/uuid v1.6.0
+	github.com/prometheus/client_golang v1.23.2
+	github.com/stretchr/testify v1.11.1
+	go.uber.org/zap v1.27.0
+)
+
+replace (
+	github.com/aidenlippert/zerostate/libs/economic => ../economic
+	github.com/aidenlippert/zerostate/libs/identity => ../identity
+	github.com/aidenlippert/zerostate/libs/orchestration => ../orchestration
+	github.com/aidenlippert/zerostate/libs/p2p => ../p2p
+	github.com/aidenlippert/zerostate/libs/scoring => ../scoring
+)
@@ -2,6 +2,7 @@ package marketplace
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -10,7 +11,6 @@ import (
 	"github.com/aidenlippert/zerostate/libs/identity"
 	"github.com/aidenlippert/zerostate/libs/orchestration"
 	"github.com/aidenlippert/zerostate/libs/p2p"
-	"github.com/aidenlippert/zerostate/libs/reputation"
 )
 
 var (
@@ -28,8 +28,8 @@ type MarketplaceService struct {
 	// Core services
 	discoveryService  *DiscoveryService
 	auctionService    *AuctionService
-	messageBus        p2p.MessageBus
-	reputationService *reputation.ReputationService
+	messageBus        *p2p.MessageBus
+	reputationService ReputationService
 
 	// Configuration
 	minAgentsForAuction int
@@ -56,6 +56,7 @@ type AuctionRequest struct {
 	AuctionType     AuctionType   `json:"auction_type,omitempty"`
 	AuctionDuration time.Duration `json:"auction_duration,omitempty"`
 	ReservePrice    float64       `json:"reserve_price,omitempty"`
+	ReservePriceRef string        `json:"reserve_price_ref,omitempty"` // e.g. "p25"; overrides ReservePrice
 	MinReputation   float64       `json:"min_reputation,omitempty"`
 
 	// Discovery configuration
@@ -79,8 +80,8 @@ type AllocationResult struct {
 func NewMarketplaceService(
 	discoveryService *DiscoveryService,
 	auctionService *AuctionService,
-	messageBus p2p.MessageBus,
-	reputationService *reputation.ReputationService,
+	messageBus *p2p.MessageBus,
+	reputationService ReputationService,
 ) *MarketplaceService {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -185,18 +186,19 @@ func (ms *MarketplaceService) createAuctionFromRequest(
 	}
 
 	auction := &TaskAuction{
-		ID:            fmt.Sprintf("auction-%s-%d", req.TaskID, time.Now().Unix()),
-		TaskID:        req.TaskID,
-		UserID:        req.UserID,
-		Type:          auctionType,
-		Status:        AuctionStatusOpen,
-		Duration:      duration,
-		ExpiresAt:     time.Now().Add(duration),
-		ReservePrice:  req.ReservePrice,
-		MaxPrice:      req.MaxPrice,
-		MinReputation: req.MinReputation,
-		Capabilities:  req.Capabilities,
-		Bids:          make([]*Bid, 0),
+		ID:              fmt.Sprintf("auction-%s-%d", req.TaskID, time.Now().Unix()),
+		TaskID:          req.TaskID,
+		UserID:          req.UserID,
+		Type:            auctionType,
+		Status:          AuctionStatusOpen,
+		Duration:        duration,
+		ExpiresAt:       time.Now().Add(duration),
+		ReservePrice:    req.ReservePrice,
+		ReservePriceRef: req.ReservePriceRef,
+		MaxPrice:        req.MaxPrice,
+		MinReputation:   req.MinReputation,
+		Capabilities:    req.Capabilities,
+		Bids:            make([]*Bid, 0),
 	}
 
 	return ms.auctionService.CreateAuction(context.Background(), auction)
@@ -246,14 +248,19 @@ func (ms *MarketplaceService) sendAuctionInvitation(
 	agentDID string,
 	invitation *AuctionInvitation,
 ) error {
+	input, err := json.Marshal(invitation)
+	if err != nil {
+		return fmt.Errorf("failed to encode auction invitation: %w", err)
+	}
+
 	// Broadcast invitation via P2P
 	msg := &p2p.TaskRequest{
-		TaskID: invitation.AuctionID,
-		Type:   "auction-invitation",
-		Input:  invitation,
+		TaskID:       invitation.AuctionID,
+		Input:        input,
+		Requirements: map[string]string{"type": "auction-invitation"},
 	}
 
-	_, err := ms.messageBus.SendRequest(ctx, agentDID, msg, ms.invitationTimeout)
+	_, err = ms.messageBus.SendRequest(ctx, agentDID, msg, ms.invitationTimeout)
 	return err
 }
 
@@ -276,9 +283,9 @@ func (ms *MarketplaceService) waitForAuctionCompletion(
 		case <-timeout:
 			return nil, errors.New("auction timeout")
 		case <-ticker.C:
-			auction, exists := ms.auctionService.GetAuction(auctionID)
-			if !exists {
-				return nil, fmt.Errorf("auction %s not found", auctionID)
+			auction, err := ms.auctionService.GetAuction(auctionID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get auction %s: %w", auctionID, err)
 			}
 
 			if auction.Status == AuctionStatusAwarded {
@@ -317,7 +324,7 @@ func (ms *MarketplaceService) SubmitBidForAgent(
 		QualityScore:    record.QualityScore,
 	}
 
-	return ms.auctionService.SubmitBid(ctx, bid)
+	return ms.auctionService.SubmitBid(ctx, auctionID, bid)
 }
 
 // HandleTaskCompletion updates agent state after task completion
@@ -373,13 +380,13 @@ type AuctionInvitation struct {
 // MarketplaceOrchestrator integrates marketplace with task execution
 type MarketplaceOrchestrator struct {
 	marketplace *MarketplaceService
-	messageBus  p2p.MessageBus
+	messageBus  *p2p.MessageBus
 }
 
 // NewMarketplaceOrchestrator creates a new orchestrator
 func NewMarketplaceOrchestrator(
 	marketplace *MarketplaceService,
-	messageBus p2p.MessageBus,
+	messageBus *p2p.MessageBus,
 ) *MarketplaceOrchestrator {
 	return &MarketplaceOrchestrator{
 		marketplace: marketplace,
@@ -398,11 +405,16 @@ func (mo *MarketplaceOrchestrator) ExecuteTask(
 		return nil, fmt.Errorf("task allocation failed: %w", err)
 	}
 
+	input, err := json.Marshal(req.Input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task input: %w", err)
+	}
+
 	// Execute task with winning agent
 	taskReq := &p2p.TaskRequest{
-		TaskID: req.TaskID,
-		Type:   req.TaskType,
-		Input:  req.Input,
+		TaskID:       req.TaskID,
+		Input:        input,
+		Requirements: map[string]string{"type": req.TaskType},
 	}
 
 	response, err := mo.messageBus.SendRequest(ctx, allocation.WinnerDID, taskReq, req.Timeout)
@@ -415,13 +427,20 @@ func (mo *MarketplaceOrchestrator) ExecuteTask(
 	// Task execution succeeded
 	mo.marketplace.HandleTaskCompletion(ctx, allocation.WinnerDID, req.TaskID, true)
 
+	var output map[string]interface{}
+	if len(response.Result) > 0 {
+		if err := json.Unmarshal(response.Result, &output); err != nil {
+			return nil, fmt.Errorf("failed to decode task result: %w", err)
+		}
+	}
+
 	return &orchestration.TaskResult{
-		TaskID:        req.TaskID,
-		Status:        orchestration.TaskStatusCompleted,
-		Output:        response.Output,
-		AgentDID:      allocation.WinnerDID,
-		StartedAt:     allocation.StartedAt,
-		CompletedAt:   allocation.CompletedAt,
-		ExecutionTime: allocation.Duration,
+		TaskID:      req.TaskID,
+		Status:      orchestration.TaskStatusCompleted,
+		Result:      output,
+		AgentDID:    allocation.WinnerDID,
+		ExecutionMS: response.Duration,
+		Timestamp:   time.Now(),
+		Cost:        allocation.FinalPrice,
 	}, nil
 }
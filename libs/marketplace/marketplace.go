@@ -1,5 +1,7 @@
 package marketplace
 
+import "context"
+
 // Stub marketplace package for testing
 // TODO: Implement full marketplace functionality
 
@@ -8,3 +10,13 @@ type Marketplace struct{}
 func NewMarketplace() *Marketplace {
 	return &Marketplace{}
 }
+
+// ReputationService tracks agent reputation by DID
+type ReputationService interface {
+	// GetScore returns the agent's overall reputation score
+	GetScore(ctx context.Context, agentDID string) (float64, error)
+	// RecordSuccess records a successfully completed task
+	RecordSuccess(ctx context.Context, agentDID, taskID string) error
+	// RecordFailure records a failed task
+	RecordFailure(ctx context.Context, agentDID, taskID string) error
+}
@@ -7,7 +7,6 @@ import (
 	"sync"
 
 	"github.com/aidenlippert/zerostate/libs/economic"
-	"github.com/aidenlippert/zerostate/libs/reputation"
 )
 
 // Payment integration errors
@@ -30,7 +29,7 @@ type PaymentMarketplaceService struct {
 	// Core services
 	marketplaceService *MarketplaceService
 	paymentService     *economic.PaymentChannelService
-	reputationService  *reputation.ReputationService
+	reputationService  ReputationService
 
 	// Payment channel tracking
 	auctionToChannel map[string]string // auction_id -> channel_id
@@ -41,7 +40,7 @@ type PaymentMarketplaceService struct {
 func NewPaymentMarketplaceService(
 	marketplaceService *MarketplaceService,
 	paymentService *economic.PaymentChannelService,
-	reputationService *reputation.ReputationService,
+	reputationService ReputationService,
 ) *PaymentMarketplaceService {
 	return &PaymentMarketplaceService{
 		marketplaceService: marketplaceService,
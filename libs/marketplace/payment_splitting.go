@@ -8,7 +8,6 @@ import (
 
 	"github.com/aidenlippert/zerostate/libs/economic"
 	"github.com/aidenlippert/zerostate/libs/orchestration"
-	"github.com/aidenlippert/zerostate/libs/reputation"
 )
 
 // SECURITY INVARIANTS FOR PAYMENT SPLITTING (CRITICAL):
@@ -67,7 +66,7 @@ type PaymentSplittingService struct {
 
 	// Core services
 	paymentService    *economic.PaymentChannelService
-	reputationService *reputation.ReputationService
+	reputationService ReputationService
 
 	// DAG payment tracking
 	workflowPayments map[string]*DAGPaymentResult // workflow_id -> result
@@ -76,7 +75,7 @@ type PaymentSplittingService struct {
 // NewPaymentSplittingService creates payment splitting service
 func NewPaymentSplittingService(
 	paymentService *economic.PaymentChannelService,
-	reputationService *reputation.ReputationService,
+	reputationService ReputationService,
 ) *PaymentSplittingService {
 	return &PaymentSplittingService{
 		paymentService:    paymentService,
@@ -85,30 +84,38 @@ func NewPaymentSplittingService(
 	}
 }
 
-// CalculateSplitsFromDAG calculates payment splits from DAG execution result
+// CalculateSplitsFromDAG calculates payment splits from an executed DAG workflow
 // ALGORITHM: Proportional payment based on task complexity and execution time
+//
+// Deprecated: the DAG executor settles workflows itself with the workflow's
+// split policy; see orchestration.PlanDAGPayment.
 func (pss *PaymentSplittingService) CalculateSplitsFromDAG(
 	ctx context.Context,
 	workflow *orchestration.DAGWorkflow,
-	result *orchestration.WorkflowResult,
 	totalPayment float64,
 ) ([]PaymentSplit, error) {
-	if len(result.TaskResults) == 0 {
+	nodes := make([]*orchestration.DAGNode, 0, len(workflow.Nodes))
+	for _, node := range workflow.Nodes {
+		if node.AssignedTo != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
 		return nil, ErrNoAgentsInDAG
 	}
 
 	// Strategy 1: Equal split (simple, fair for similar tasks)
 	// Future: Could weight by execution time, complexity, or task dependencies
-	ratio := 1.0 / float64(len(result.TaskResults))
+	ratio := 1.0 / float64(len(nodes))
 
-	splits := make([]PaymentSplit, 0, len(result.TaskResults))
-	for taskID, taskResult := range result.TaskResults {
+	splits := make([]PaymentSplit, 0, len(nodes))
+	for _, node := range nodes {
 		split := PaymentSplit{
-			AgentDID: taskResult.AgentDID,
+			AgentDID: node.AssignedTo,
 			Ratio:    ratio,
 			Amount:   totalPayment * ratio,
-			TaskID:   taskID,
-			Success:  taskResult.Success,
+			TaskID:   node.ID,
+			Success:  node.Status == orchestration.DAGNodeStatusCompleted,
 		}
 		splits = append(splits, split)
 	}
@@ -134,7 +141,7 @@ func (pss *PaymentSplittingService) ExecuteDAGPayment(
 	}
 
 	if userBalance < req.TotalPayment {
-		return nil, economic.ErrInsufficientFunds
+		return nil, ErrInsufficientFunds
 	}
 
 	// Step 3: Create payment channels for each agent
@@ -255,7 +262,7 @@ func (pss *PaymentSplittingService) ExecuteAtomicDAGPayment(
 	}
 
 	if userBalance < req.TotalPayment {
-		return nil, economic.ErrInsufficientFunds
+		return nil, ErrInsufficientFunds
 	}
 
 	// Step 3: Check ALL tasks succeeded
@@ -0,0 +1,64 @@
+package marketplace
+
+import (
+	"fmt"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"go.uber.org/zap"
+)
+
+// SetPriceIndex attaches a price index. Awarded auctions feed their cleared
+// price into it and new auctions may set their reserve as a percentile of it.
+func (as *AuctionService) SetPriceIndex(index *economic.PriceIndex) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.priceIndex = index
+}
+
+// resolveReservePriceLocked turns a declarative reserve such as "p25" into
+// ReservePrice, using the most expensive of the auction's capabilities.
+// Callers must hold as.mu.
+func (as *AuctionService) resolveReservePriceLocked(auction *TaskAuction) error {
+	if auction.ReservePriceRef == "" {
+		return nil
+	}
+	if as.priceIndex == nil {
+		return fmt.Errorf("%w: no price index to resolve reserve %q", economic.ErrNoPriceData, auction.ReservePriceRef)
+	}
+
+	reserve, err := as.priceIndex.ResolveAny(auction.Capabilities, auction.ReservePriceRef)
+	if err != nil {
+		return err
+	}
+	if auction.MaxPrice > 0 && reserve > auction.MaxPrice {
+		return fmt.Errorf("reserve %s resolves to %.4f, above max price %.4f",
+			auction.ReservePriceRef, reserve, auction.MaxPrice)
+	}
+
+	auction.ReservePrice = reserve
+	as.logger.Debug("reserve price resolved from index",
+		zap.String("auction_id", auction.ID),
+		zap.String("reserve_price_ref", auction.ReservePriceRef),
+		zap.Float64("reserve_price", reserve),
+	)
+
+	return nil
+}
+
+// recordClearedPriceLocked feeds an awarded auction's final price into the
+// price index under each of its capabilities. Callers must hold as.mu.
+func (as *AuctionService) recordClearedPriceLocked(auction *TaskAuction) {
+	if as.priceIndex == nil {
+		return
+	}
+
+	for _, capability := range auction.Capabilities {
+		as.priceIndex.Record(economic.PriceSample{
+			ID:         auction.ID,
+			Capability: capability,
+			Price:      auction.FinalPrice,
+			ClearedAt:  auction.UpdatedAt,
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package marketplace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuctionReserveFromPriceIndex(t *testing.T) {
+	ctx := context.Background()
+	as := newTestAuctionService(t)
+
+	_, err := as.CreateAuction(ctx, &TaskAuction{
+		TaskID:          "task-no-index",
+		MaxPrice:        200,
+		ReservePriceRef: "p25",
+		Capabilities:    []string{"math"},
+	})
+	assert.ErrorIs(t, err, economic.ErrNoPriceData)
+
+	index := economic.NewPriceIndex(time.Hour)
+	index.SetMinSamples(1)
+	for i := 1; i <= 11; i++ {
+		index.Record(economic.PriceSample{Capability: "math", Price: float64(i * 10)})
+	}
+	index.Record(economic.PriceSample{Capability: "image", Price: 5})
+	as.SetPriceIndex(index)
+
+	// The most expensive capability sets the reserve
+	auction, err := as.CreateAuction(ctx, &TaskAuction{
+		TaskID:          "task-indexed",
+		Type:            AuctionTypeFirstPrice,
+		MaxPrice:        200,
+		Timeout:         10 * time.Second,
+		ReservePriceRef: "p25",
+		Capabilities:    []string{"image", "math"},
+	})
+	require.NoError(t, err)
+	assert.InDelta(t, 35.0, auction.ReservePrice, 1e-9)
+
+	err = as.SubmitBid(ctx, auction.ID, &Bid{AgentDID: "did:key:low", Price: 30})
+	assert.ErrorIs(t, err, ErrInvalidBid)
+	require.NoError(t, as.SubmitBid(ctx, auction.ID, &Bid{AgentDID: "did:key:ok", Price: 40}))
+
+	_, err = as.CreateAuction(ctx, &TaskAuction{
+		TaskID:          "task-over-max",
+		MaxPrice:        20,
+		ReservePriceRef: "p25",
+		Capabilities:    []string{"math"},
+	})
+	assert.Error(t, err)
+
+	// Awarded auctions feed their cleared price back into the index
+	_, err = as.CloseAuction(ctx, auction.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, index.Quote("image").Samples)
+	assert.Equal(t, 12, index.Quote("math").Samples)
+	assert.Equal(t, 40.0, index.Quote("image").Max)
+}
@@ -154,10 +154,15 @@ func (a *AACLAdapter) FormatAACLResponse(
 			durationMs = uint64(result.ExecutionMS)
 		}
 
+		gasUsed := result.FuelUsed
+		if gasUsed == 0 {
+			gasUsed = calculateGasUsed(task)
+		}
+
 		// Build execution metadata
 		metadata := &aacl.ExecutionMetadata{
 			DurationMs:      durationMs,
-			GasUsed:         calculateGasUsed(task),
+			GasUsed:         gasUsed,
 			CostUainur:      uint64(task.ActualCost),
 			AgentVersion:    "1.0.0",
 			AgentTrustScore: 50.0, // Default, will be updated with real reputation
@@ -245,8 +250,13 @@ func (a *AACLAdapter) FormatAACLResponseJSON(
 	return msg.ToJSON()
 }
 
-// calculateGasUsed estimates gas used for task execution
+// calculateGasUsed reports gas used for task execution. Metered fuel is
+// exact; otherwise it is estimated.
 func calculateGasUsed(task *Task) uint64 {
+	if task.FuelUsed > 0 {
+		return task.FuelUsed
+	}
+
 	// Simple heuristic: base cost + per-capability cost
 	baseCost := uint64(100)
 	capabilityCost := uint64(len(task.Capabilities)) * 50
@@ -201,6 +201,33 @@ func TestAACLAdapter_FormatResponse(t *testing.T) {
 		msg.ID, payload.Status, payload.ExecutionMetadata.AgentTrustScore)
 }
 
+func TestAACLAdapter_GasUsedReportsMeteredFuel(t *testing.T) {
+	adapter := NewAACLAdapter(zap.NewNop())
+
+	task := NewTask("did:ainur:user:alice", "compute", []string{"math.add"}, nil)
+	now := time.Now()
+	task.StartedAt = &now
+	completed := now.Add(5 * time.Second) // A slow host must not change the gas
+	task.CompletedAt = &completed
+
+	result := &TaskResult{
+		TaskID:   task.ID,
+		Status:   TaskStatusCompleted,
+		Result:   map[string]interface{}{"sum": 12},
+		FuelUsed: 123456,
+	}
+
+	msg, err := adapter.FormatAACLResponse(task, result, agentcard.NewAgentDID("math-001"), agentcard.NewUserDID("alice"), nil)
+	if err != nil {
+		t.Fatalf("Failed to format AACL response: %v", err)
+	}
+
+	payload := msg.Payload.(aacl.ResponsePayload)
+	if payload.ExecutionMetadata.GasUsed != 123456 {
+		t.Errorf("Gas mismatch: expected metered fuel 123456, got %d", payload.ExecutionMetadata.GasUsed)
+	}
+}
+
 func TestAACLAdapter_InferCapabilities(t *testing.T) {
 	adapter := NewAACLAdapter(zap.NewNop())
 
@@ -0,0 +1,106 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aidenlippert/zerostate/libs/execution"
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"go.uber.org/zap"
+)
+
+// maxSubtaskDepth bounds how deeply agents can call one another
+const maxSubtaskDepth = 4
+
+// ErrSubtaskDepth is returned when an agent call would nest too deeply
+var ErrSubtaskDepth = errors.New("agent call nesting too deep")
+
+type subtaskDepthKey struct{}
+
+// SubtaskCaller runs the agent calls WASM guests make through the host API.
+// The callee is picked by DID or by capabilities and runs synchronously on
+// the executor, inside the caller's deadline. Its cost is charged to the
+// calling task rather than escrowed separately.
+type SubtaskCaller struct {
+	selector AgentSelector
+	executor TaskExecutor
+	logger   *zap.Logger
+}
+
+// NewSubtaskCaller creates an agent caller backed by the orchestrator's
+// selector and executor
+func NewSubtaskCaller(selector AgentSelector, executor TaskExecutor, logger *zap.Logger) *SubtaskCaller {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &SubtaskCaller{
+		selector: selector,
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// CallAgent runs call as a subtask and returns its result
+func (c *SubtaskCaller) CallAgent(ctx context.Context, call execution.AgentCall) (*execution.AgentCallResult, error) {
+	depth, _ := ctx.Value(subtaskDepthKey{}).(int)
+	if depth >= maxSubtaskDepth {
+		return nil, ErrSubtaskDepth
+	}
+
+	// Object inputs are passed through; anything else is wrapped
+	input := map[string]interface{}{}
+	if len(call.Input) > 0 {
+		if err := json.Unmarshal(call.Input, &input); err != nil {
+			input = map[string]interface{}{"input": call.Input}
+		}
+	}
+
+	task := NewTask("", "agent-call", call.Capabilities, input)
+	task.Budget = call.Budget
+	task.MaxRetries = 0
+
+	// A named callee gets a bare card, as in chain steps: it runs without
+	// host capabilities of its own
+	var agent *identity.AgentCard
+	if call.AgentID != "" {
+		agent = &identity.AgentCard{DID: call.AgentID}
+	} else {
+		selected, err := c.selector.SelectAgent(ctx, task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select agent: %w", err)
+		}
+		agent = selected
+	}
+	task.AssignedTo = agent.DID
+
+	c.logger.Info("running agent call",
+		zap.String("task_id", task.ID),
+		zap.String("agent_id", agent.DID),
+		zap.Int("depth", depth+1),
+		zap.Float64("budget", call.Budget),
+	)
+
+	result, err := c.executor.ExecuteTask(context.WithValue(ctx, subtaskDepthKey{}, depth+1), task, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	var output json.RawMessage
+	if result.Result != nil {
+		output, err = json.Marshal(result.Result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode agent result: %w", err)
+		}
+	}
+
+	return &execution.AgentCallResult{
+		AgentID: agent.DID,
+		Status:  string(result.Status),
+		Result:  output,
+		Error:   result.Error,
+		Cost:    result.Cost,
+	}, nil
+}
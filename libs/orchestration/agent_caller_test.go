@@ -0,0 +1,88 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/execution"
+	"github.com/aidenlippert/zerostate/libs/identity"
+)
+
+type fixedSelector struct {
+	agent *identity.AgentCard
+}
+
+func (s *fixedSelector) SelectAgent(ctx context.Context, task *Task) (*identity.AgentCard, error) {
+	return s.agent, nil
+}
+
+// recursingExecutor calls back into the caller from every task it runs, like
+// an agent that calls itself
+type recursingExecutor struct {
+	caller *SubtaskCaller
+	tasks  []*Task
+}
+
+func (e *recursingExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identity.AgentCard) (*TaskResult, error) {
+	e.tasks = append(e.tasks, task)
+	if _, err := e.caller.CallAgent(ctx, execution.AgentCall{AgentID: agent.DID, Budget: task.Budget}); err != nil {
+		return &TaskResult{TaskID: task.ID, Status: TaskStatusFailed, Error: err.Error()}, nil
+	}
+	return &TaskResult{
+		TaskID: task.ID,
+		Status: TaskStatusCompleted,
+		Result: map[string]interface{}{"echo": task.Input["a"]},
+		Cost:   task.Budget / 2,
+	}, nil
+}
+
+func TestSubtaskCallerSelectsAndRunsCallee(t *testing.T) {
+	selector := &fixedSelector{agent: &identity.AgentCard{DID: "did:key:callee"}}
+	executor := NewMockTaskExecutor(nil)
+	caller := NewSubtaskCaller(selector, executor, nil)
+
+	result, err := caller.CallAgent(context.Background(), execution.AgentCall{
+		Capabilities: []string{"math.add"},
+		Input:        json.RawMessage(`{"a":1}`),
+		Budget:       2,
+	})
+	if err != nil {
+		t.Fatalf("agent call failed: %v", err)
+	}
+	if result.AgentID != "did:key:callee" {
+		t.Errorf("expected selected callee, got %s", result.AgentID)
+	}
+	if result.Status != string(TaskStatusCompleted) {
+		t.Errorf("expected completed call, got %s (%s)", result.Status, result.Error)
+	}
+}
+
+func TestSubtaskCallerBoundsNesting(t *testing.T) {
+	executor := &recursingExecutor{}
+	executor.caller = NewSubtaskCaller(&fixedSelector{}, executor, nil)
+
+	result, err := executor.caller.CallAgent(context.Background(), execution.AgentCall{
+		AgentID: "did:key:self",
+		Input:   json.RawMessage(`{"a":1}`),
+		Budget:  1,
+	})
+	if err != nil {
+		t.Fatalf("agent call failed: %v", err)
+	}
+	if len(executor.tasks) != maxSubtaskDepth {
+		t.Errorf("expected %d nested tasks, got %d", maxSubtaskDepth, len(executor.tasks))
+	}
+	if result.Status != string(TaskStatusCompleted) {
+		t.Errorf("expected outermost call to complete, got %s", result.Status)
+	}
+
+	outermost := executor.tasks[0]
+	if _, err := executor.caller.CallAgent(context.WithValue(context.Background(), subtaskDepthKey{}, maxSubtaskDepth), execution.AgentCall{AgentID: "did:key:self", Budget: 1}); !errors.Is(err, ErrSubtaskDepth) {
+		t.Errorf("expected ErrSubtaskDepth, got %v", err)
+	}
+	if outermost.Input["a"] != float64(1) {
+		t.Errorf("expected object input to pass through, got %v", outermost.Input)
+	}
+}
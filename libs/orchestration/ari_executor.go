@@ -13,6 +13,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// ariCancelGrace is how long a runtime has to end a canceled task's stream
+// before the stream is torn down
+const ariCancelGrace = 5 * time.Second
+
 // ARIExecutor executes tasks using ARI-v1 protocol via gRPC
 type ARIExecutor struct {
 	runtimeAddr string
@@ -111,8 +115,15 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 		zap.String("input", string(inputJSON)),
 	)
 
+	// The stream outlives ctx so a canceled task can still report how far it
+	// got: the runtime is told to cancel and ends the stream itself
+	streamCtx, stopStream := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopStream()
+	stopWatching := e.cancelOnDone(ctx, task.ID, stopStream)
+	defer stopWatching()
+
 	// Create streaming request
-	stream, err := e.taskClient.Execute(ctx, &ariv1.TaskExecuteRequest{
+	stream, err := e.taskClient.Execute(streamCtx, &ariv1.TaskExecuteRequest{
 		TaskId:    task.ID,
 		Input:     string(inputJSON),
 		TimeoutMs: int32(task.Timeout.Milliseconds()),
@@ -123,12 +134,24 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 
 	// Collect streaming responses
 	var finalResponse *ariv1.TaskExecuteResponse
+	var progress float32
 	for {
 		resp, err := stream.Recv()
 		if err != nil {
 			if err.Error() == "EOF" {
 				break
 			}
+			if ctx.Err() != nil {
+				// Torn down after the cancel; settle on the progress seen
+				finalResponse = &ariv1.TaskExecuteResponse{
+					TaskId:      task.ID,
+					Status:      ariv1.TaskStatus_TASK_STATUS_CANCELED,
+					Error:       "task canceled",
+					Progress:    progress,
+					ExecutionMs: time.Since(startTime).Milliseconds(),
+				}
+				break
+			}
 			return nil, fmt.Errorf("stream error: %w", err)
 		}
 
@@ -139,12 +162,17 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 		)
 
 		finalResponse = resp
+		if resp.Progress > progress {
+			progress = resp.Progress
+		}
 
-		// Break if completed or failed
+		// Break if completed, failed or canceled
 		if resp.Status == ariv1.TaskStatus_TASK_STATUS_COMPLETED ||
-			resp.Status == ariv1.TaskStatus_TASK_STATUS_FAILED {
+			resp.Status == ariv1.TaskStatus_TASK_STATUS_FAILED ||
+			resp.Status == ariv1.TaskStatus_TASK_STATUS_CANCELED {
 			break
 		}
+		ReportProgress(ctx, ariProgress(resp))
 	}
 
 	if finalResponse == nil {
@@ -158,6 +186,8 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 		status = TaskStatusCompleted
 	case ariv1.TaskStatus_TASK_STATUS_FAILED:
 		status = TaskStatusFailed
+	case ariv1.TaskStatus_TASK_STATUS_CANCELED:
+		status = TaskStatusCanceled
 	default:
 		status = TaskStatusRunning
 	}
@@ -180,14 +210,25 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 		agentDID = agent.DID
 	}
 
+	// Native runtimes measure usage; the peak is the larger of the biggest
+	// process and the task's cgroup
+	usage := finalResponse.GetUsage()
+	memoryBytes := max(usage.GetMaxRssBytes(), usage.GetPeakMemoryBytes())
+
 	executionTime := time.Since(startTime)
 	e.logger.Info("Task execution completed via ARI-v1",
 		zap.String("task_id", task.ID),
 		zap.String("status", string(status)),
 		zap.Duration("execution_time", executionTime),
+		zap.Int64("cpu_ms", usage.GetCpuMs()),
+		zap.Int64("memory_bytes", memoryBytes),
 	)
 
 	actualCost := extractActualCost(task.Budget, result)
+	if status == TaskStatusCanceled {
+		// Canceled tasks are paid for the share they got through
+		actualCost = proRatedCost(task.Budget, float64(max(progress, finalResponse.Progress)))
+	}
 
 	return &TaskResult{
 		TaskID:      task.ID,
@@ -195,12 +236,77 @@ func (e *ARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identi
 		Result:      result,
 		Error:       finalResponse.Error,
 		ExecutionMS: finalResponse.ExecutionMs,
+		CPUTimeMS:   usage.GetCpuMs(),
+		MemoryBytes: memoryBytes,
 		AgentDID:    agentDID,
 		Timestamp:   time.Now(),
 		Cost:        actualCost,
 	}, nil
 }
 
+// cancelOnDone tells the runtime to cancel the task once ctx is done. The
+// stream is stopped instead when the runtime can't cancel it, or hasn't
+// ended it within ariCancelGrace. The returned func stops watching.
+func (e *ARIExecutor) cancelOnDone(ctx context.Context, taskID string, stopStream context.CancelFunc) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		cancelCtx, cancel := context.WithTimeout(context.Background(), ariCancelGrace)
+		defer cancel()
+		resp, err := e.taskClient.Cancel(cancelCtx, &ariv1.TaskCancelRequest{
+			TaskId: taskID,
+			Reason: context.Cause(ctx).Error(),
+		})
+		if err != nil || !resp.Canceled {
+			e.logger.Warn("runtime did not cancel task, closing stream",
+				zap.String("task_id", taskID),
+				zap.Error(err),
+			)
+			stopStream()
+			return
+		}
+
+		e.logger.Info("Task canceled on runtime",
+			zap.String("task_id", taskID),
+			zap.Float32("progress", resp.Progress),
+		)
+		select {
+		case <-done:
+		case <-cancelCtx.Done():
+			stopStream()
+		}
+	}()
+	return func() { close(done) }
+}
+
+// ariProgress converts an in-flight ARI response to a progress update.
+// Partial results that aren't JSON are passed on as a JSON string.
+func ariProgress(resp *ariv1.TaskExecuteResponse) TaskProgress {
+	p := TaskProgress{
+		TaskID:  resp.TaskId,
+		Status:  TaskStatusRunning,
+		Percent: float64(resp.Progress) * 100,
+		Message: resp.ProgressMessage,
+		Logs:    resp.Logs,
+	}
+	if resp.Status == ariv1.TaskStatus_TASK_STATUS_PENDING {
+		p.Status = TaskStatusPending
+	}
+	if resp.PartialResult != "" {
+		if json.Valid([]byte(resp.PartialResult)) {
+			p.Partial = json.RawMessage(resp.PartialResult)
+		} else {
+			p.Partial, _ = json.Marshal(resp.PartialResult)
+		}
+	}
+	return p
+}
+
 // Close closes the gRPC connection
 func (e *ARIExecutor) Close() error {
 	if e.conn != nil {
@@ -0,0 +1,58 @@
+package orchestration
+
+import (
+	"net"
+	"testing"
+
+	ariv1 "github.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// meteredRuntime completes every task and reports what it consumed
+type meteredRuntime struct {
+	ariv1.UnimplementedTaskServer
+}
+
+func (r *meteredRuntime) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_ExecuteServer) error {
+	return stream.Send(&ariv1.TaskExecuteResponse{
+		TaskId:      req.TaskId,
+		Status:      ariv1.TaskStatus_TASK_STATUS_COMPLETED,
+		Result:      `{"answer": 42}`,
+		ExecutionMs: 30,
+		Progress:    1,
+		Usage:       &ariv1.TaskUsage{CpuMs: 25, MaxRssBytes: 4 << 20, PeakMemoryBytes: 6 << 20},
+	})
+}
+
+func TestARIExecutorReportsRuntimeUsage(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	ariv1.RegisterTaskServer(server, &meteredRuntime{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	executor, err := NewARIExecutor(lis.Addr().String(), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer executor.Close()
+
+	task := NewTask("did:user:1", "add", []string{"math"}, map[string]interface{}{"function": "add"})
+	result, err := executor.ExecuteTask(t.Context(), task, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != TaskStatusCompleted || result.ExecutionMS != 30 {
+		t.Fatalf("expected a completed task after 30ms, got %s after %dms", result.Status, result.ExecutionMS)
+	}
+	if result.CPUTimeMS != 25 {
+		t.Errorf("expected 25ms of CPU time, got %d", result.CPUTimeMS)
+	}
+	if result.MemoryBytes != 6<<20 {
+		t.Errorf("expected the cgroup peak as memory, got %d", result.MemoryBytes)
+	}
+}
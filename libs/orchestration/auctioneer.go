@@ -11,6 +11,7 @@ import (
 
 	"github.com/aidenlippert/zerostate/libs/agentcard-go"
 	"github.com/aidenlippert/zerostate/libs/p2p"
+	"github.com/aidenlippert/zerostate/libs/scoring"
 	"github.com/multiformats/go-multibase"
 	"go.uber.org/zap"
 )
@@ -23,6 +24,7 @@ const (
 	SelectionModeFastest        SelectionMode = "fastest"
 	SelectionModeBestReputation SelectionMode = "best_reputation"
 	SelectionModeCustom         SelectionMode = "custom"
+	SelectionModeScored         SelectionMode = "scored" // Rank by a scoring.Rule
 )
 
 // SelectionLogic defines how the winner is chosen among bids.
@@ -31,6 +33,9 @@ type SelectionLogic struct {
 	PriceWeight      float64
 	SpeedWeight      float64
 	ReputationWeight float64
+
+	// Rule scores bids in SelectionModeScored and is published in the CFP
+	Rule *scoring.Rule
 }
 
 // BidSummary captures the key comparable attributes of a bid.
@@ -41,6 +46,10 @@ type BidSummary struct {
 	ETAms      int64
 	Reputation float64
 
+	// Attributes carries the remaining scoring attributes of the bid
+	Attributes scoring.Offer
+	Score      float64 // Set when ranked by a scoring rule
+
 	RawMessage interface{} // will be *aacl.AACLMessage when wired
 }
 
@@ -50,6 +59,9 @@ type AuctionResult struct {
 	Winner   *BidSummary
 	AllBids  []BidSummary
 	TimedOut bool
+
+	// Violations lists sealed-bid commitments that were forfeited
+	Violations []BidViolation
 }
 
 // Auctioneer coordinates CFP broadcasts and bid collection via p2p.
@@ -57,6 +69,48 @@ type Auctioneer struct {
 	bus    *p2p.MessageBus
 	gossip *p2p.GossipService
 	logger *zap.Logger
+
+	mu     sync.RWMutex
+	sealed *SealedBidConfig // nil for open (cleartext) bidding
+	dutch  *DutchSchedule   // nil runs no price clock for urgent tasks
+	screen BidderScreen     // nil admits every verified bidder
+}
+
+// BidderScreen decides which bidders may take part in auctions, e.g. to
+// exclude DIDs flagged by collusion detection
+type BidderScreen interface {
+	IsFlagged(did string) bool
+}
+
+// SetBidderScreen drops bids from bidders the screen flags before a winner
+// is selected
+func (a *Auctioneer) SetBidderScreen(screen BidderScreen) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.screen = screen
+}
+
+// screenBids removes bids from flagged bidders
+func (a *Auctioneer) screenBids(cfpID string, bids []BidSummary) []BidSummary {
+	a.mu.RLock()
+	screen := a.screen
+	a.mu.RUnlock()
+	if screen == nil {
+		return bids
+	}
+
+	admitted := bids[:0:0]
+	for _, bid := range bids {
+		if screen.IsFlagged(string(bid.AgentDID)) {
+			a.logger.Warn("dropping bid from flagged bidder",
+				zap.String("cfp_id", cfpID),
+				zap.String("agent_did", string(bid.AgentDID)),
+			)
+			continue
+		}
+		admitted = append(admitted, bid)
+	}
+	return admitted
 }
 
 // NewAuctioneer creates a new Auctioneer instance.
@@ -130,6 +184,10 @@ func (a *Auctioneer) StartAuction(
 		},
 		"topic": cfpTopic,
 	}
+	if logic.Mode == SelectionModeScored {
+		logic.Rule = logic.Rule.WithDefaults(task.Budget, task.Timeout.Milliseconds())
+		payload["scoring_rule"] = logic.Rule
+	}
 
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -140,6 +198,10 @@ func (a *Auctioneer) StartAuction(
 		return &AuctionResult{CFPID: task.ID}, nil
 	}
 
+	if sealed := a.sealedConfig(); sealed != nil {
+		return a.startSealedAuction(ctx, task, logic, window, *sealed, cfpTopic, payload)
+	}
+
 	// Subscribe to bid topic to collect responses
 	var mu sync.Mutex
 	bids := []BidSummary{}
@@ -164,22 +226,7 @@ func (a *Auctioneer) StartAuction(
 			return nil // Don't return error, just skip this bid
 		}
 
-		// Extract key fields
-		bidID, _ := bid["bid_id"].(string)
-		fromDID, _ := bid["from"].(string)
-		intent, _ := bid["intent"].(map[string]interface{})
-		priceMap, _ := intent["price"].(map[string]interface{})
-		priceAmount, _ := priceMap["amount"].(float64)
-		etaMS, _ := intent["estimated_duration_ms"].(float64)
-
-		summary := BidSummary{
-			BidID:      bidID,
-			AgentDID:   agentcard.DID(fromDID),
-			Price:      priceAmount,
-			ETAms:      int64(etaMS),
-			Reputation: 0.0, // TODO: lookup from registry
-			RawMessage: bid,
-		}
+		summary := bidSummaryFromMessage(bid)
 
 		mu.Lock()
 		bids = append(bids, summary)
@@ -187,9 +234,9 @@ func (a *Auctioneer) StartAuction(
 
 		a.logger.Info("bid received and verified",
 			zap.String("cfp_id", task.ID),
-			zap.String("bid_id", bidID),
-			zap.String("from", fromDID),
-			zap.Float64("price", priceAmount),
+			zap.String("bid_id", summary.BidID),
+			zap.String("from", string(summary.AgentDID)),
+			zap.Float64("price", summary.Price),
 		)
 
 		return nil
@@ -231,32 +278,83 @@ func (a *Auctioneer) StartAuction(
 	copy(allBids, bids)
 	mu.Unlock()
 
+	return a.awardAuction(ctx, task.ID, allBids, logic), nil
+}
+
+// bidSummaryFromMessage extracts the comparable fields of a signed bid or reveal.
+func bidSummaryFromMessage(bid map[string]interface{}) BidSummary {
+	bidID, _ := bid["bid_id"].(string)
+	fromDID, _ := bid["from"].(string)
+	intent, _ := bid["intent"].(map[string]interface{})
+	priceMap, _ := intent["price"].(map[string]interface{})
+	priceAmount, _ := priceMap["amount"].(float64)
+	etaMS, _ := intent["estimated_duration_ms"].(float64)
+
+	// Optional attributes for scoring rules
+	quality, _ := intent["quality"].(float64)
+	region, _ := intent["region"].(string)
+	privacy, _ := intent["privacy"].(string)
+	slaClass, _ := intent["sla_class"].(string)
+	capacity, _ := intent["capacity"].(map[string]interface{})
+	load, _ := capacity["load"].(float64)
+
+	return BidSummary{
+		BidID:      bidID,
+		AgentDID:   agentcard.DID(fromDID),
+		Price:      priceAmount,
+		ETAms:      int64(etaMS),
+		Reputation: 0.0, // TODO: lookup from registry
+		Attributes: scoring.Offer{
+			Quality:  quality,
+			Region:   region,
+			Privacy:  privacy,
+			SLAClass: slaClass,
+			Load:     load,
+		},
+		RawMessage: bid,
+	}
+}
+
+// awardAuction selects the winner among the collected bids and notifies
+// winner and losers.
+func (a *Auctioneer) awardAuction(ctx context.Context, cfpID string, allBids []BidSummary, logic SelectionLogic) *AuctionResult {
+	allBids = a.screenBids(cfpID, allBids)
 	if len(allBids) == 0 {
 		a.logger.Warn("auction complete: no bids received",
-			zap.String("cfp_id", task.ID),
+			zap.String("cfp_id", cfpID),
 		)
 		return &AuctionResult{
-			CFPID:    task.ID,
+			CFPID:    cfpID,
 			Winner:   nil,
 			AllBids:  allBids,
 			TimedOut: true,
-		}, nil
+		}
 	}
 
 	// Select winner based on selection logic
 	winner := a.selectWinner(allBids, logic)
+	if winner == nil {
+		a.logger.Warn("auction complete: no bid satisfies the scoring rule",
+			zap.String("cfp_id", cfpID),
+			zap.Int("total_bids", len(allBids)),
+		)
+		return &AuctionResult{
+			CFPID:   cfpID,
+			AllBids: allBids,
+		}
+	}
 
 	a.logger.Info("auction complete: winner selected",
-		zap.String("cfp_id", task.ID),
+		zap.String("cfp_id", cfpID),
 		zap.Int("total_bids", len(allBids)),
 		zap.String("winner_did", string(winner.AgentDID)),
 		zap.Float64("winning_price", winner.Price),
 	)
 
 	// Send acceptance to winner
-	if err := a.sendAcceptProposal(ctx, task.ID, winner); err != nil {
+	if err := a.sendAcceptProposal(ctx, cfpID, winner); err != nil {
 		a.logger.Error("failed to send accept proposal to winner",
-			zap.String("cfp_id", task.ID),
+			zap.String("cfp_id", cfpID),
 			zap.String("winner_did", string(winner.AgentDID)),
 			zap.Error(err),
 		)
@@ -265,9 +363,9 @@ func (a *Auctioneer) StartAuction(
 	// Send rejections to losers
 	for i := range allBids {
 		if allBids[i].BidID != winner.BidID {
-			if err := a.sendRejectProposal(ctx, task.ID, &allBids[i], winner); err != nil {
+			if err := a.sendRejectProposal(ctx, cfpID, &allBids[i], winner); err != nil {
 				a.logger.Error("failed to send reject proposal",
-					zap.String("cfp_id", task.ID),
+					zap.String("cfp_id", cfpID),
 					zap.String("loser_did", string(allBids[i].AgentDID)),
 					zap.Error(err),
 				)
@@ -276,10 +374,10 @@ func (a *Auctioneer) StartAuction(
 	}
 
 	return &AuctionResult{
-		CFPID:   task.ID,
+		CFPID:   cfpID,
 		Winner:  winner,
 		AllBids: allBids,
-	}, nil
+	}
 }
 
 // selectWinner applies the selection logic to pick the winning bid.
@@ -309,6 +407,9 @@ func (a *Auctioneer) selectWinner(bids []BidSummary, logic SelectionLogic) *BidS
 		}
 		return winner
 
+	case SelectionModeScored:
+		return a.selectScored(bids, logic.Rule)
+
 	case SelectionModeBestReputation:
 		// Find highest reputation
 		winner := &bids[0]
@@ -331,6 +432,36 @@ func (a *Auctioneer) selectWinner(bids []BidSummary, logic SelectionLogic) *BidS
 	}
 }
 
+// selectScored ranks bids by a scoring rule and returns the best eligible
+// bid, or nil if every bid violates a hard constraint. Each bid's Score is set.
+func (a *Auctioneer) selectScored(bids []BidSummary, rule *scoring.Rule) *BidSummary {
+	if rule == nil {
+		rule = scoring.DefaultRule(0, 0)
+	}
+
+	var winner *BidSummary
+	for i := range bids {
+		offer := bids[i].Attributes
+		offer.Price = bids[i].Price
+		offer.LatencyMS = bids[i].ETAms
+		offer.Reputation = bids[i].Reputation / 1000.0 // Registry reputation is 0-1000
+
+		result := rule.Score(offer)
+		bids[i].Score = result.Score
+		if !result.Eligible {
+			a.logger.Debug("bid violates scoring constraints",
+				zap.String("bid_id", bids[i].BidID),
+				zap.Strings("violations", result.Violations),
+			)
+			continue
+		}
+		if winner == nil || bids[i].Score > winner.Score {
+			winner = &bids[i]
+		}
+	}
+	return winner
+}
+
 // sendAcceptProposal sends an AACL-Accept-Proposal-v1 message to the winning agent.
 func (a *Auctioneer) sendAcceptProposal(ctx context.Context, cfpID string, winner *BidSummary) error {
 	acceptMsg := map[string]interface{}{
@@ -432,15 +563,30 @@ func (a *Auctioneer) sendRejectProposal(ctx context.Context, cfpID string, loser
 
 // verifyBidSignature verifies the cryptographic signature on a bid.
 func (a *Auctioneer) verifyBidSignature(bid map[string]interface{}) error {
+	if err := verifyMessageSignature(bid); err != nil {
+		return err
+	}
+
+	a.logger.Debug("bid signature verified",
+		zap.Any("from", bid["from"]),
+	)
+
+	return nil
+}
+
+// verifyMessageSignature verifies the Ed25519 proof on a signed AACL message
+// against the did:key in its "from" field. The signature covers the message
+// without its proof.
+func verifyMessageSignature(msg map[string]interface{}) error {
 	// Extract proof
-	proof, ok := bid["proof"].(map[string]interface{})
+	proof, ok := msg["proof"].(map[string]interface{})
 	if !ok {
-		return fmt.Errorf("bid missing proof field")
+		return fmt.Errorf("message missing proof field")
 	}
 
 	proofValue, ok := proof["proof_value"].(string)
 	if !ok {
-		return fmt.Errorf("bid proof missing proof_value")
+		return fmt.Errorf("message proof missing proof_value")
 	}
 
 	// Decode signature
@@ -450,9 +596,9 @@ func (a *Auctioneer) verifyBidSignature(bid map[string]interface{}) error {
 	}
 
 	// Extract agent DID
-	fromDID, ok := bid["from"].(string)
+	fromDID, ok := msg["from"].(string)
 	if !ok {
-		return fmt.Errorf("bid missing from field")
+		return fmt.Errorf("message missing from field")
 	}
 
 	// Extract public key from DID
@@ -461,17 +607,17 @@ func (a *Auctioneer) verifyBidSignature(bid map[string]interface{}) error {
 		return fmt.Errorf("failed to extract public key from DID: %w", err)
 	}
 
-	// Create canonical bid (without proof) for verification
-	bidCopy := make(map[string]interface{})
-	for k, v := range bid {
+	// Create canonical message (without proof) for verification
+	msgCopy := make(map[string]interface{})
+	for k, v := range msg {
 		if k != "proof" {
-			bidCopy[k] = v
+			msgCopy[k] = v
 		}
 	}
 
-	canonical, err := json.Marshal(bidCopy)
+	canonical, err := json.Marshal(msgCopy)
 	if err != nil {
-		return fmt.Errorf("failed to marshal canonical bid: %w", err)
+		return fmt.Errorf("failed to marshal canonical message: %w", err)
 	}
 
 	// Verify signature
@@ -479,10 +625,6 @@ func (a *Auctioneer) verifyBidSignature(bid map[string]interface{}) error {
 		return fmt.Errorf("signature verification failed")
 	}
 
-	a.logger.Debug("bid signature verified",
-		zap.String("from", fromDID),
-	)
-
 	return nil
 }
 
@@ -0,0 +1,59 @@
+package orchestration
+
+import (
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/scoring"
+)
+
+func TestSelectWinnerScored(t *testing.T) {
+	a := NewAuctioneer(nil, nil)
+	rule := (&scoring.Rule{
+		Weights:     scoring.Weights{Price: 0.3, Quality: 0.7},
+		Constraints: scoring.Constraints{Regions: []string{"eu-west"}, MinQuality: 0.6},
+	}).WithDefaults(100, 0)
+
+	bids := []BidSummary{
+		{BidID: "cheapest", Price: 10, Attributes: scoring.Offer{Quality: 0.9, Region: "us-east"}},
+		{BidID: "sloppy", Price: 20, Attributes: scoring.Offer{Quality: 0.5, Region: "eu-west"}},
+		{BidID: "solid", Price: 60, Attributes: scoring.Offer{Quality: 0.95, Region: "eu-west"}},
+		{BidID: "decent", Price: 30, Attributes: scoring.Offer{Quality: 0.7, Region: "eu-west"}},
+	}
+
+	winner := a.selectWinner(bids, SelectionLogic{Mode: SelectionModeScored, Rule: rule})
+	if winner == nil || winner.BidID != "solid" {
+		t.Fatalf("expected the high-quality bid to win, got %+v", winner)
+	}
+	if bids[0].Score != 0 || bids[1].Score != 0 {
+		t.Error("expected ineligible bids to score 0")
+	}
+
+	winner = a.selectWinner(bids[:2], SelectionLogic{Mode: SelectionModeScored, Rule: rule})
+	if winner != nil {
+		t.Errorf("expected no winner when every bid is ineligible, got %s", winner.BidID)
+	}
+}
+
+type flaggedSet map[string]bool
+
+func (f flaggedSet) IsFlagged(did string) bool { return f[did] }
+
+func TestScreenBidsDropsFlaggedBidders(t *testing.T) {
+	a := NewAuctioneer(nil, nil)
+	a.SetBidderScreen(flaggedSet{"did:key:shill": true})
+
+	bids := []BidSummary{
+		{BidID: "shill", AgentDID: "did:key:shill", Price: 1},
+		{BidID: "honest", AgentDID: "did:key:honest", Price: 5},
+	}
+
+	admitted := a.screenBids("cfp-1", bids)
+	if len(admitted) != 1 || admitted[0].BidID != "honest" {
+		t.Fatalf("expected only the honest bid to be admitted, got %+v", admitted)
+	}
+
+	winner := a.selectWinner(admitted, SelectionLogic{Mode: SelectionModeCheapest})
+	if winner == nil || winner.BidID != "honest" {
+		t.Fatalf("expected the honest bid to win despite the cheaper shill, got %+v", winner)
+	}
+}
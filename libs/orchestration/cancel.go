@@ -0,0 +1,121 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTaskCanceled is the cancellation cause of a task canceled while a
+	// worker was processing it
+	ErrTaskCanceled = errors.New("task canceled")
+
+	// ErrTaskFinished is returned when canceling a task that already ended
+	ErrTaskFinished = errors.New("task already finished")
+)
+
+// CancelTask cancels a task. A queued task is dropped from the queue. A
+// running task has its execution interrupted, which tells the runtime
+// executing it to stop; its worker then settles the escrow by how far the
+// task got.
+func (o *Orchestrator) CancelTask(taskID string) error {
+	task, err := o.queue.Get(taskID)
+	if err != nil {
+		return err
+	}
+	if task.IsTerminal() {
+		return ErrTaskFinished
+	}
+
+	o.runningMu.Lock()
+	cancel, running := o.running[taskID]
+	o.runningMu.Unlock()
+
+	if err := o.queue.Cancel(taskID); err != nil {
+		return err
+	}
+	if running {
+		cancel(ErrTaskCanceled)
+		o.logger.Info("interrupting canceled task", zap.String("task_id", taskID))
+		return nil
+	}
+
+	o.closeUnstarted(task)
+	return nil
+}
+
+// closeUnstarted closes out a task canceled before it started. There is no
+// work to pay for and no escrow yet.
+func (o *Orchestrator) closeUnstarted(task *Task) {
+	o.notifyResultObservers(task, &TaskResult{
+		TaskID:    task.ID,
+		Status:    TaskStatusCanceled,
+		Timestamp: time.Now(),
+	})
+	o.publishProgress(task, FinalProgress(task))
+}
+
+// trackRunning makes a task being processed cancelable through CancelTask.
+// The returned func stops tracking it.
+func (o *Orchestrator) trackRunning(taskID string, cancel context.CancelCauseFunc) func() {
+	o.runningMu.Lock()
+	defer o.runningMu.Unlock()
+	o.running[taskID] = cancel
+
+	return func() {
+		o.runningMu.Lock()
+		defer o.runningMu.Unlock()
+		delete(o.running, taskID)
+	}
+}
+
+// finishCanceled closes out a task canceled while its worker had it. result
+// is what the executor returned, if it got that far; its cost is what the
+// agent earned before the cancel.
+func (w *worker) finishCanceled(task *Task, agent *identity.AgentCard, result *TaskResult, executionTime time.Duration) {
+	if result == nil {
+		result = &TaskResult{TaskID: task.ID, Timestamp: time.Now()}
+	}
+	result.Status = TaskStatusCanceled
+
+	task.Result = result.Result
+	task.ActualCost = result.Cost
+	task.FuelUsed = result.FuelUsed
+	task.CPUTimeMS = result.CPUTimeMS
+	task.MemoryBytes = result.MemoryBytes
+	task.UpdateStatus(TaskStatusCanceled)
+
+	if err := w.orchestrator.queue.Update(task); err != nil {
+		w.logger.Error("failed to update canceled task", zap.Error(err))
+	}
+	w.orchestrator.publishProgress(task, FinalProgress(task))
+
+	w.orchestrator.updateMetrics(result, executionTime)
+	w.orchestrator.notifyResultObservers(task, result)
+
+	if w.orchestrator.paymentManager != nil {
+		w.handlePaymentLifecycle(task, agent, TaskStatusCanceled)
+	}
+
+	w.logger.Info("task canceled",
+		zap.String("task_id", task.ID),
+		zap.Float64("earned", task.ActualCost),
+		zap.Duration("execution_time", executionTime),
+	)
+}
+
+// settleCanceledPayment pays the agent of a canceled task for the work it
+// did and refunds the rest of the escrow
+func (w *worker) settleCanceledPayment(task *Task, agentID string) {
+	if w.orchestrator.escrowClient != nil {
+		// The escrow pallet can't split an escrow, so the user gets it back
+		w.refundPaymentWithEscrow(task, fmt.Sprintf("task canceled after earning %.4f", task.ActualCost))
+		return
+	}
+	w.orchestrator.paymentManager.SettleCanceledPaymentAsync(w.orchestrator.ctx, task.ID, agentID, task.ActualCost)
+}
@@ -0,0 +1,232 @@
+package orchestration
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+	ariv1 "github.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// blockingExecutor runs until its task is interrupted, having earned cost
+type blockingExecutor struct {
+	started chan struct{}
+	cost    float64
+}
+
+func (e *blockingExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identity.AgentCard) (*TaskResult, error) {
+	close(e.started)
+	<-ctx.Done()
+	return &TaskResult{TaskID: task.ID, Status: TaskStatusFailed, Error: "interrupted", Cost: e.cost}, nil
+}
+
+// splittingChain is a blockchain that can split escrows
+type splittingChain struct {
+	partial chan float64
+	refunds chan string
+}
+
+func (c *splittingChain) ReleasePayment(ctx context.Context, taskID string) (string, error) {
+	return "0xrelease", nil
+}
+
+func (c *splittingChain) RefundEscrow(ctx context.Context, taskID string) (string, error) {
+	c.refunds <- taskID
+	return "0xrefund", nil
+}
+
+func (c *splittingChain) DisputeEscrow(ctx context.Context, taskID string, reason string) (string, error) {
+	return "0xdispute", nil
+}
+
+func (c *splittingChain) IsEnabled() bool { return true }
+
+func (c *splittingChain) GetEscrowStatus(ctx context.Context, taskID string) (PaymentStatus, error) {
+	return PaymentStatusAccepted, nil
+}
+
+func (c *splittingChain) ReleasePartialPayment(ctx context.Context, taskID string, amount float64) (string, error) {
+	c.partial <- amount
+	return "0xpartial", nil
+}
+
+type resultRecorder struct {
+	results chan *TaskResult
+}
+
+func (r *resultRecorder) OnTaskResult(ctx context.Context, task *Task, result *TaskResult) {
+	r.results <- result
+}
+
+func TestCancelRunningTaskProRatesPayment(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	queue := NewTaskQueue(ctx, 10, logger)
+	executor := &blockingExecutor{started: make(chan struct{}), cost: 4}
+	chain := &splittingChain{partial: make(chan float64, 1), refunds: make(chan string, 1)}
+
+	o := NewOrchestrator(ctx, queue, &fixedSelector{agent: &identity.AgentCard{DID: "did:agent:1"}}, executor, &OrchestratorConfig{NumWorkers: 1}, logger)
+	o.paymentManager = NewPaymentLifecycleManager(chain, DefaultPaymentConfig(), logger)
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	task := NewTask("did:user:1", "test", []string{"test"}, nil)
+	task.Budget = 10
+	if err := queue.Enqueue(task); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-executor.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never started")
+	}
+	updates, unsubscribe := o.SubscribeProgress(task.ID)
+	defer unsubscribe()
+
+	if err := o.CancelTask(task.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+
+	var last TaskProgress
+	for p := range updates {
+		last = p
+	}
+	if last.Status != TaskStatusCanceled {
+		t.Errorf("expected the stream to end canceled, got %+v", last)
+	}
+
+	select {
+	case amount := <-chain.partial:
+		if amount != 4 {
+			t.Errorf("expected the agent to be paid the 4 it earned, got %v", amount)
+		}
+	case <-chain.refunds:
+		t.Error("expected a partial release, got a full refund")
+	case <-time.After(5 * time.Second):
+		t.Fatal("payment was never settled")
+	}
+
+	got, _ := queue.Get(task.ID)
+	if got.Status != TaskStatusCanceled || got.ActualCost != 4 {
+		t.Errorf("unexpected canceled task status %s cost %v", got.Status, got.ActualCost)
+	}
+	if err := o.CancelTask(task.ID); err != ErrTaskFinished {
+		t.Errorf("expected canceling again to fail with ErrTaskFinished, got %v", err)
+	}
+}
+
+func TestCancelQueuedTask(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	queue := NewTaskQueue(ctx, 10, logger)
+	o := NewOrchestrator(ctx, queue, &fixedSelector{agent: &identity.AgentCard{DID: "did:agent:1"}}, NewMockTaskExecutor(logger), nil, logger)
+	recorder := &resultRecorder{results: make(chan *TaskResult, 1)}
+	o.AddResultObserver(recorder)
+
+	task := NewTask("did:user:1", "test", []string{"test"}, nil)
+	task.Budget = 10
+	if err := queue.Enqueue(task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.CancelTask(task.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if queue.Size() != 0 {
+		t.Error("expected the task to leave the queue")
+	}
+
+	// Nothing ran, so nothing is charged
+	result := <-recorder.results
+	if result.Status != TaskStatusCanceled || result.Cost != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if err := o.CancelTask("missing"); err != ErrTaskNotFound {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+// cancelableRuntime streams a task until Cancel is called for it
+type cancelableRuntime struct {
+	ariv1.UnimplementedTaskServer
+	canceled chan string
+}
+
+func (r *cancelableRuntime) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_ExecuteServer) error {
+	if err := stream.Send(&ariv1.TaskExecuteResponse{
+		TaskId:   req.TaskId,
+		Status:   ariv1.TaskStatus_TASK_STATUS_RUNNING,
+		Progress: 0.25,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case <-r.canceled:
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+	return stream.Send(&ariv1.TaskExecuteResponse{
+		TaskId:   req.TaskId,
+		Status:   ariv1.TaskStatus_TASK_STATUS_CANCELED,
+		Error:    "task canceled",
+		Progress: 0.5,
+	})
+}
+
+func (r *cancelableRuntime) Cancel(ctx context.Context, req *ariv1.TaskCancelRequest) (*ariv1.TaskCancelResponse, error) {
+	r.canceled <- req.TaskId
+	return &ariv1.TaskCancelResponse{TaskId: req.TaskId, Canceled: true, Progress: 0.5}, nil
+}
+
+func TestARIExecutorCancelsRuntimeTask(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	ariv1.RegisterTaskServer(server, &cancelableRuntime{canceled: make(chan string, 1)})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	executor, err := NewARIExecutor(lis.Addr().String(), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer executor.Close()
+
+	task := NewTask("did:user:1", "add", []string{"math"}, map[string]interface{}{"function": "add"})
+	task.Budget = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	progressed := make(chan struct{}, 1)
+	ctx = WithProgress(ctx, func(TaskProgress) {
+		select {
+		case progressed <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		<-progressed
+		cancel()
+	}()
+
+	result, err := executor.ExecuteTask(ctx, task, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != TaskStatusCanceled {
+		t.Errorf("expected canceled, got %s", result.Status)
+	}
+	if result.Cost != 5 {
+		t.Errorf("expected half the budget, got %v", result.Cost)
+	}
+}
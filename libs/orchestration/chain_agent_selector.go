@@ -82,6 +82,9 @@ func (s *ChainAgentSelector) SelectAgent(ctx context.Context, task *Task) (*iden
 	// Step 2: Fetch full AgentCards from chain
 	var agentCards []*identity.AgentCard
 	for did := range candidateDIDs {
+		if IsAgentExcluded(ctx, string(did)) {
+			continue
+		}
 		chainCard, err := s.client.GetAgentCard(ctx, did)
 		if err != nil {
 			s.logger.Warn("failed to fetch agent card from chain",
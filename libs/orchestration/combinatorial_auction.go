@@ -0,0 +1,683 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"go.uber.org/zap"
+)
+
+// Combinatorial auction errors
+var (
+	ErrInvalidBundleBid = errors.New("invalid bundle bid")
+)
+
+// BidLanguage defines how an agent's bundle bids combine
+type BidLanguage string
+
+const (
+	BidLanguageOR  BidLanguage = "OR"  // Any set of disjoint bundles may win, priced additively
+	BidLanguageXOR BidLanguage = "XOR" // At most one bundle may win
+)
+
+const (
+	// DefaultExactMaxBundles is the largest number of bundles solved exactly
+	DefaultExactMaxBundles = 20
+
+	// MessageTypeBundleBid is the message type of a combinatorial bid
+	MessageTypeBundleBid = "AACL-Bundle-Bid-v1"
+)
+
+// Node owners in a winner-determination solution besides agent indices
+const (
+	undecidedOwner = -2
+	fallbackOwner  = -1
+)
+
+// BundleBid is an agent's asking price for executing a set of DAG nodes
+type BundleBid struct {
+	BidID   string   `json:"bid_id"`
+	NodeIDs []string `json:"node_ids"`
+	Price   float64  `json:"price"`
+}
+
+// CombinatorialBid holds all bundle bids of one agent
+type CombinatorialBid struct {
+	AgentDID agentcard.DID `json:"agent_did"`
+	Language BidLanguage   `json:"language"`
+	Bundles  []BundleBid   `json:"bundles"`
+}
+
+// CombinatorialConfig configures winner determination
+type CombinatorialConfig struct {
+	ExactMaxBundles     int     // Above this many bundles the greedy solver is used
+	TransferCostPerEdge float64 // Cost of moving data along an edge between different agents
+}
+
+// BundleAllocation is the set of nodes awarded to one agent
+type BundleAllocation struct {
+	AgentDID agentcard.DID
+	BidIDs   []string
+	NodeIDs  []string
+	Price    float64 // Sum of the agent's winning bundle prices
+	Payment  float64 // VCG payment, never below Price
+}
+
+// CombinatorialAuctionResult is the outcome of a combinatorial auction.
+// Nodes in Unallocated fall back to per-node selection at their Budget.
+type CombinatorialAuctionResult struct {
+	WorkflowID      string
+	Allocations     []*BundleAllocation
+	Unallocated     []string
+	TotalCost       float64 // Winning prices + fallback budgets + transfer cost
+	TotalPayment    float64 // VCG payments + fallback budgets
+	TransferCost    float64
+	CrossAgentEdges int
+	Exact           bool // False when the greedy approximation was used
+	AllBids         []CombinatorialBid
+}
+
+// candidateBundle is a bundle bid resolved to node indices
+type candidateBundle struct {
+	agent int
+	bidID string
+	nodes []int
+	price float64
+	xor   bool
+}
+
+// wdProblem is a winner-determination instance. Nodes not covered by a
+// winning bundle cost their fallback budget; each dependency edge whose
+// endpoints run on different agents costs transfer.
+type wdProblem struct {
+	nodeIDs  []string
+	fallback []float64
+	adj      [][]int
+	bundles  []candidateBundle
+	byFirst  [][]int // node index -> bundles whose lowest node index it is
+	agents   []agentcard.DID
+	transfer float64
+}
+
+// wdSolution assigns every node an owner: an agent index or -1 for fallback
+type wdSolution struct {
+	chosen []int
+	owner  []int
+	cost   float64
+}
+
+// SolveCombinatorialAuction determines the cheapest allocation of workflow
+// nodes to bundle bids and computes VCG payments for the winners.
+func SolveCombinatorialAuction(workflow *DAGWorkflow, bids []CombinatorialBid, cfg CombinatorialConfig) (*CombinatorialAuctionResult, error) {
+	if cfg.ExactMaxBundles <= 0 {
+		cfg.ExactMaxBundles = DefaultExactMaxBundles
+	}
+
+	problem, err := newWDProblem(workflow, bids, cfg.TransferCostPerEdge)
+	if err != nil {
+		return nil, err
+	}
+	exact := len(problem.bundles) <= cfg.ExactMaxBundles
+
+	best := problem.solve(-1, exact)
+
+	result := &CombinatorialAuctionResult{
+		WorkflowID: workflow.ID,
+		TotalCost:  best.cost,
+		Exact:      exact,
+		AllBids:    bids,
+	}
+
+	byAgent := make(map[int]*BundleAllocation)
+	var order []int
+	for _, b := range best.chosen {
+		bundle := problem.bundles[b]
+		alloc, exists := byAgent[bundle.agent]
+		if !exists {
+			alloc = &BundleAllocation{AgentDID: problem.agents[bundle.agent]}
+			byAgent[bundle.agent] = alloc
+			order = append(order, bundle.agent)
+		}
+		alloc.BidIDs = append(alloc.BidIDs, bundle.bidID)
+		alloc.Price += bundle.price
+		for _, n := range bundle.nodes {
+			alloc.NodeIDs = append(alloc.NodeIDs, problem.nodeIDs[n])
+		}
+	}
+
+	// VCG: each winner is paid the cost it saves the rest of the market,
+	// i.e. C(without agent) - (C(all) - agent's price)
+	for _, agent := range order {
+		alloc := byAgent[agent]
+		without := problem.solve(agent, exact)
+		alloc.Payment = math.Max(alloc.Price, without.cost-(best.cost-alloc.Price))
+		sort.Strings(alloc.NodeIDs)
+		result.Allocations = append(result.Allocations, alloc)
+		result.TotalPayment += alloc.Payment
+	}
+
+	for i, owner := range best.owner {
+		if owner == fallbackOwner {
+			result.Unallocated = append(result.Unallocated, problem.nodeIDs[i])
+			result.TotalPayment += problem.fallback[i]
+		}
+	}
+	result.CrossAgentEdges = problem.crossEdges(best.owner)
+	result.TransferCost = float64(result.CrossAgentEdges) * problem.transfer
+
+	return result, nil
+}
+
+// ApplyAllocation pins each allocated node to its winning agent and sets the
+// node budget to its share of the agent's payment, split by original budget.
+func ApplyAllocation(workflow *DAGWorkflow, result *CombinatorialAuctionResult) {
+	for _, alloc := range result.Allocations {
+		var budgetSum float64
+		for _, nodeID := range alloc.NodeIDs {
+			budgetSum += workflow.Nodes[nodeID].Budget
+		}
+
+		for _, nodeID := range alloc.NodeIDs {
+			node := workflow.Nodes[nodeID]
+			node.AgentID = string(alloc.AgentDID)
+			if budgetSum > 0 {
+				node.Budget = alloc.Payment * node.Budget / budgetSum
+			} else {
+				node.Budget = alloc.Payment / float64(len(alloc.NodeIDs))
+			}
+		}
+	}
+
+	if workflow.Metadata == nil {
+		workflow.Metadata = make(map[string]interface{})
+	}
+	workflow.Metadata["combinatorial_auction"] = map[string]interface{}{
+		"allocations":       len(result.Allocations),
+		"unallocated":       result.Unallocated,
+		"total_cost":        result.TotalCost,
+		"total_payment":     result.TotalPayment,
+		"cross_agent_edges": result.CrossAgentEdges,
+		"exact":             result.Exact,
+	}
+	workflow.UpdatedAt = time.Now()
+}
+
+// newWDProblem validates the bids against the workflow and indexes them
+func newWDProblem(workflow *DAGWorkflow, bids []CombinatorialBid, transfer float64) (*wdProblem, error) {
+	p := &wdProblem{transfer: transfer}
+
+	for id := range workflow.Nodes {
+		p.nodeIDs = append(p.nodeIDs, id)
+	}
+	sort.Strings(p.nodeIDs)
+
+	index := make(map[string]int, len(p.nodeIDs))
+	for i, id := range p.nodeIDs {
+		index[id] = i
+		p.fallback = append(p.fallback, workflow.Nodes[id].Budget)
+	}
+
+	p.adj = make([][]int, len(p.nodeIDs))
+	for i, id := range p.nodeIDs {
+		for _, dep := range workflow.Nodes[id].Dependencies {
+			j, exists := index[dep]
+			if !exists {
+				return nil, fmt.Errorf("%w: node %s depends on unknown node %s", ErrDAGInvalidNode, id, dep)
+			}
+			p.adj[i] = append(p.adj[i], j)
+			p.adj[j] = append(p.adj[j], i)
+		}
+	}
+
+	p.byFirst = make([][]int, len(p.nodeIDs))
+	seenAgents := make(map[agentcard.DID]bool)
+	for _, bid := range bids {
+		if seenAgents[bid.AgentDID] {
+			return nil, fmt.Errorf("%w: multiple bids from %s", ErrInvalidBundleBid, bid.AgentDID)
+		}
+		seenAgents[bid.AgentDID] = true
+
+		language := bid.Language
+		if language == "" {
+			language = BidLanguageOR
+		}
+		if language != BidLanguageOR && language != BidLanguageXOR {
+			return nil, fmt.Errorf("%w: unknown bid language %q", ErrInvalidBundleBid, bid.Language)
+		}
+
+		agent := len(p.agents)
+		p.agents = append(p.agents, bid.AgentDID)
+
+		for _, bundle := range bid.Bundles {
+			if len(bundle.NodeIDs) == 0 || bundle.Price < 0 {
+				return nil, fmt.Errorf("%w: bundle %s must name nodes and a non-negative price", ErrInvalidBundleBid, bundle.BidID)
+			}
+
+			nodes := make([]int, 0, len(bundle.NodeIDs))
+			inBundle := make(map[int]bool, len(bundle.NodeIDs))
+			for _, nodeID := range bundle.NodeIDs {
+				n, exists := index[nodeID]
+				if !exists {
+					return nil, fmt.Errorf("%w: bundle %s names unknown node %s", ErrInvalidBundleBid, bundle.BidID, nodeID)
+				}
+				if inBundle[n] {
+					return nil, fmt.Errorf("%w: bundle %s names node %s twice", ErrInvalidBundleBid, bundle.BidID, nodeID)
+				}
+				inBundle[n] = true
+				nodes = append(nodes, n)
+			}
+			sort.Ints(nodes)
+
+			p.byFirst[nodes[0]] = append(p.byFirst[nodes[0]], len(p.bundles))
+			p.bundles = append(p.bundles, candidateBundle{
+				agent: agent,
+				bidID: bundle.BidID,
+				nodes: nodes,
+				price: bundle.Price,
+				xor:   language == BidLanguageXOR,
+			})
+		}
+	}
+
+	return p, nil
+}
+
+// solve returns the cheapest allocation that ignores the bids of exclude
+// (-1 for none), exactly or with the greedy approximation
+func (p *wdProblem) solve(exclude int, exact bool) *wdSolution {
+	if exact {
+		return p.solveExact(exclude)
+	}
+	return p.solveGreedy(exclude)
+}
+
+// solveExact enumerates allocations with branch and bound. The lowest
+// undecided node is either left to fallback or covered by a bundle that
+// starts at it, so every allocation is visited once.
+func (p *wdProblem) solveExact(exclude int) *wdSolution {
+	n := len(p.nodeIDs)
+	owner := make([]int, n)
+	for i := range owner {
+		owner[i] = undecidedOwner
+	}
+	usedXOR := make(map[int]bool)
+	var chosen []int
+
+	best := &wdSolution{cost: math.Inf(1)}
+
+	var search func(pos int, cost float64)
+	search = func(pos int, cost float64) {
+		if cost >= best.cost {
+			return
+		}
+		for pos < n && owner[pos] != undecidedOwner {
+			pos++
+		}
+		if pos == n {
+			best.cost = cost
+			best.chosen = append([]int(nil), chosen...)
+			best.owner = append([]int(nil), owner...)
+			return
+		}
+
+		// Leave the node to per-node fallback
+		owner[pos] = fallbackOwner
+		search(pos+1, cost+p.fallback[pos]+p.transferDelta(owner, pos))
+		owner[pos] = undecidedOwner
+
+		for _, b := range p.byFirst[pos] {
+			bundle := p.bundles[b]
+			if bundle.agent == exclude || (bundle.xor && usedXOR[bundle.agent]) || !p.available(owner, bundle) {
+				continue
+			}
+
+			delta := bundle.price
+			for _, node := range bundle.nodes {
+				owner[node] = bundle.agent
+				delta += p.transferDelta(owner, node)
+			}
+			if bundle.xor {
+				usedXOR[bundle.agent] = true
+			}
+			chosen = append(chosen, b)
+
+			search(pos+1, cost+delta)
+
+			chosen = chosen[:len(chosen)-1]
+			if bundle.xor {
+				usedXOR[bundle.agent] = false
+			}
+			for _, node := range bundle.nodes {
+				owner[node] = undecidedOwner
+			}
+		}
+	}
+	search(0, 0)
+
+	return best
+}
+
+// solveGreedy accepts bundles in order of price per sqrt(size) whenever
+// they are cheaper than leaving their nodes to fallback
+func (p *wdProblem) solveGreedy(exclude int) *wdSolution {
+	order := make([]int, 0, len(p.bundles))
+	for b, bundle := range p.bundles {
+		if bundle.agent != exclude {
+			order = append(order, b)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		bi, bj := p.bundles[order[i]], p.bundles[order[j]]
+		return bi.price/math.Sqrt(float64(len(bi.nodes))) < bj.price/math.Sqrt(float64(len(bj.nodes)))
+	})
+
+	owner := make([]int, len(p.nodeIDs))
+	for i := range owner {
+		owner[i] = undecidedOwner
+	}
+	usedXOR := make(map[int]bool)
+	solution := &wdSolution{}
+
+	for _, b := range order {
+		bundle := p.bundles[b]
+		if (bundle.xor && usedXOR[bundle.agent]) || !p.available(owner, bundle) {
+			continue
+		}
+
+		// Worth it if cheaper than fallback plus the transfer kept in-house
+		inBundle := make(map[int]bool, len(bundle.nodes))
+		for _, node := range bundle.nodes {
+			inBundle[node] = true
+		}
+		var alternative float64
+		for _, node := range bundle.nodes {
+			alternative += p.fallback[node]
+			for _, neighbor := range p.adj[node] {
+				if inBundle[neighbor] && neighbor > node {
+					alternative += p.transfer
+				}
+			}
+		}
+		if bundle.price > alternative {
+			continue
+		}
+
+		for _, node := range bundle.nodes {
+			owner[node] = bundle.agent
+		}
+		if bundle.xor {
+			usedXOR[bundle.agent] = true
+		}
+		solution.chosen = append(solution.chosen, b)
+		solution.cost += bundle.price
+	}
+
+	for i := range owner {
+		if owner[i] == undecidedOwner {
+			owner[i] = fallbackOwner
+			solution.cost += p.fallback[i]
+		}
+	}
+	solution.owner = owner
+	solution.cost += float64(p.crossEdges(owner)) * p.transfer
+
+	return solution
+}
+
+// available reports whether none of the bundle's nodes are decided yet
+func (p *wdProblem) available(owner []int, bundle candidateBundle) bool {
+	for _, node := range bundle.nodes {
+		if owner[node] != undecidedOwner {
+			return false
+		}
+	}
+	return true
+}
+
+// transferDelta is the transfer cost of edges between node and its already
+// decided neighbors
+func (p *wdProblem) transferDelta(owner []int, node int) float64 {
+	var cost float64
+	for _, neighbor := range p.adj[node] {
+		if neighbor == node || owner[neighbor] == undecidedOwner {
+			continue
+		}
+		if crosses(owner[node], owner[neighbor]) {
+			cost += p.transfer
+		}
+	}
+	return cost
+}
+
+// crossEdges counts dependency edges whose endpoints run on different agents
+func (p *wdProblem) crossEdges(owner []int) int {
+	count := 0
+	for node, neighbors := range p.adj {
+		for _, neighbor := range neighbors {
+			if neighbor > node && crosses(owner[node], owner[neighbor]) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// crosses reports whether two owners are different agents. Fallback nodes
+// are selected independently, so they never share an agent.
+func crosses(a, b int) bool {
+	return a == fallbackOwner || b == fallbackOwner || a != b
+}
+
+// StartCombinatorialAuction broadcasts a CFP for a whole workflow, collects
+// signed bundle bids for the window and runs winner determination with VCG
+// payments. Winners are not applied to the workflow; see ApplyAllocation.
+func (v *VCGAuctioneer) StartCombinatorialAuction(
+	ctx context.Context,
+	workflow *DAGWorkflow,
+	window time.Duration,
+	cfg CombinatorialConfig,
+) (*CombinatorialAuctionResult, error) {
+	a := v.auctioneer
+	if a == nil || a.gossip == nil {
+		return SolveCombinatorialAuction(workflow, nil, cfg)
+	}
+
+	bidTopic := fmt.Sprintf("ainur/v1/market/bundle-bid/%s", workflow.ID)
+
+	nodes := make([]map[string]interface{}, 0, len(workflow.Nodes))
+	capabilities := make(map[string]bool)
+	for _, node := range workflow.Nodes {
+		nodes = append(nodes, map[string]interface{}{
+			"id":           node.ID,
+			"task_type":    node.TaskType,
+			"capabilities": node.Capabilities,
+			"dependencies": node.Dependencies,
+			"budget":       node.Budget,
+			"timeout_ms":   int64(node.Timeout / time.Millisecond),
+		})
+		for _, capability := range node.Capabilities {
+			capabilities[capability] = true
+		}
+	}
+
+	payload := map[string]interface{}{
+		"cfp_type":          "AACL-Combinatorial-CFP-v1",
+		"cfp_id":            workflow.ID,
+		"from":              "orchestrator",
+		"to":                "*",
+		"created_at":        time.Now().UTC().Format(time.RFC3339Nano),
+		"auction_window_ms": int64(window / time.Millisecond),
+		"bid_topic":         bidTopic,
+		"bid_languages":     []BidLanguage{BidLanguageOR, BidLanguageXOR},
+		"transfer_cost":     cfg.TransferCostPerEdge,
+		"nodes":             nodes,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal combinatorial CFP: %w", err)
+	}
+
+	var mu sync.Mutex
+	var bids []CombinatorialBid
+	seen := make(map[agentcard.DID]bool)
+
+	bidHandler := func(ctx context.Context, msg *p2p.GossipMessage) error {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &raw); err != nil {
+			return nil
+		}
+		if msgType, _ := raw["message_type"].(string); msgType != MessageTypeBundleBid {
+			return nil
+		}
+		if cfpID, _ := raw["cfp_id"].(string); cfpID != workflow.ID {
+			return nil
+		}
+		if err := a.verifyBidSignature(raw); err != nil {
+			v.logger.Warn("bundle bid signature verification failed, rejecting bid",
+				zap.String("workflow_id", workflow.ID),
+				zap.Error(err),
+			)
+			return nil
+		}
+
+		var bid struct {
+			From     string      `json:"from"`
+			Language BidLanguage `json:"language"`
+			Bundles  []BundleBid `json:"bundles"`
+		}
+		if err := json.Unmarshal(msg.Payload, &bid); err != nil {
+			return nil
+		}
+
+		// Validate the bid on its own so one bad bid cannot spoil the auction
+		combinatorialBid := CombinatorialBid{
+			AgentDID: agentcard.DID(bid.From),
+			Language: bid.Language,
+			Bundles:  bid.Bundles,
+		}
+		if _, err := newWDProblem(workflow, []CombinatorialBid{combinatorialBid}, 0); err != nil {
+			v.logger.Warn("bundle bid rejected",
+				zap.String("workflow_id", workflow.ID),
+				zap.String("from", bid.From),
+				zap.Error(err),
+			)
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[combinatorialBid.AgentDID] {
+			return nil
+		}
+		seen[combinatorialBid.AgentDID] = true
+		bids = append(bids, combinatorialBid)
+		return nil
+	}
+
+	if err := a.gossip.Subscribe(bidTopic, bidHandler); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to bundle bid topic: %w", err)
+	}
+	defer a.gossip.Unsubscribe(bidTopic)
+
+	for capability := range capabilities {
+		cfpMsg := &p2p.GossipMessage{
+			Type:      "AACL-Combinatorial-CFP-v1",
+			Payload:   data,
+			Timestamp: time.Now().Unix(),
+			PeerID:    "orchestrator",
+		}
+		if err := a.gossip.Publish("ainur/v1/market/cfp/"+capability, cfpMsg); err != nil {
+			return nil, fmt.Errorf("failed to broadcast combinatorial CFP: %w", err)
+		}
+	}
+
+	v.logger.Info("combinatorial auction started",
+		zap.String("workflow_id", workflow.ID),
+		zap.Int("nodes", len(workflow.Nodes)),
+		zap.Duration("window", window),
+	)
+	if err := waitWindow(ctx, window); err != nil {
+		return nil, fmt.Errorf("combinatorial auction interrupted: %w", err)
+	}
+
+	mu.Lock()
+	collected := append([]CombinatorialBid(nil), bids...)
+	mu.Unlock()
+
+	result, err := SolveCombinatorialAuction(workflow, collected, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	v.logger.Info("combinatorial auction completed",
+		zap.String("workflow_id", workflow.ID),
+		zap.Int("bidders", len(collected)),
+		zap.Int("allocations", len(result.Allocations)),
+		zap.Int("unallocated", len(result.Unallocated)),
+		zap.Float64("total_payment", result.TotalPayment),
+		zap.Int("cross_agent_edges", result.CrossAgentEdges),
+		zap.Bool("exact", result.Exact),
+	)
+
+	return result, nil
+}
+
+// BundleAuction allocates a whole workflow before it runs
+type BundleAuction interface {
+	StartCombinatorialAuction(ctx context.Context, workflow *DAGWorkflow, window time.Duration, cfg CombinatorialConfig) (*CombinatorialAuctionResult, error)
+}
+
+// dagBundleAuction is the combinatorial auction a DAGExecutor runs on submission
+type dagBundleAuction struct {
+	auction BundleAuction
+	window  time.Duration
+	cfg     CombinatorialConfig
+}
+
+// SetCombinatorialAuction makes ExecuteDAG auction each workflow as bundles
+// before running it. Winning agents are pinned to their nodes; unallocated
+// nodes fall back to per-node selection.
+func (de *DAGExecutor) SetCombinatorialAuction(auction BundleAuction, window time.Duration, cfg CombinatorialConfig) {
+	de.mu.Lock()
+	defer de.mu.Unlock()
+	if auction == nil {
+		de.bundles = nil
+		return
+	}
+	de.bundles = &dagBundleAuction{auction: auction, window: window, cfg: cfg}
+}
+
+// allocateBundles runs the combinatorial auction for workflow, if enabled.
+// Workflows with nodes the caller already pinned skip the auction, and a
+// failed auction leaves every node to per-node selection.
+func (de *DAGExecutor) allocateBundles(ctx context.Context, workflow *DAGWorkflow) {
+	de.mu.RLock()
+	bundles := de.bundles
+	de.mu.RUnlock()
+	if bundles == nil {
+		return
+	}
+	for _, node := range workflow.Nodes {
+		if node.AgentID != "" {
+			return
+		}
+	}
+
+	result, err := bundles.auction.StartCombinatorialAuction(ctx, workflow, bundles.window, bundles.cfg)
+	if err != nil {
+		de.logger.Warn("combinatorial auction failed, selecting agents per node",
+			zap.String("workflow_id", workflow.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	ApplyAllocation(workflow, result)
+}
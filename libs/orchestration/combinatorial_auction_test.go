@@ -0,0 +1,219 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ocrTranslateWorkflow is a two-node pipeline: translate depends on ocr
+func ocrTranslateWorkflow(t *testing.T) *DAGWorkflow {
+	t.Helper()
+	workflow := NewDAGWorkflow("user-1", "ocr-translate")
+	if err := workflow.AddNode(&DAGNode{ID: "ocr", Capabilities: []string{"ocr"}, Budget: 10}); err != nil {
+		t.Fatalf("failed to add node: %v", err)
+	}
+	if err := workflow.AddNode(&DAGNode{ID: "translate", Capabilities: []string{"translate"}, Budget: 10, Dependencies: []string{"ocr"}}); err != nil {
+		t.Fatalf("failed to add node: %v", err)
+	}
+	return workflow
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCombinatorialAuctionPrefersBundle(t *testing.T) {
+	workflow := ocrTranslateWorkflow(t)
+	bids := []CombinatorialBid{
+		{
+			AgentDID: "did:key:zBundler",
+			Language: BidLanguageXOR,
+			Bundles: []BundleBid{
+				{BidID: "a-ocr", NodeIDs: []string{"ocr"}, Price: 6},
+				{BidID: "a-translate", NodeIDs: []string{"translate"}, Price: 6},
+				{BidID: "a-both", NodeIDs: []string{"ocr", "translate"}, Price: 9},
+			},
+		},
+		{
+			AgentDID: "did:key:zSingles",
+			Language: BidLanguageOR,
+			Bundles: []BundleBid{
+				{BidID: "b-ocr", NodeIDs: []string{"ocr"}, Price: 5},
+				{BidID: "b-translate", NodeIDs: []string{"translate"}, Price: 7},
+			},
+		},
+	}
+
+	result, err := SolveCombinatorialAuction(workflow, bids, CombinatorialConfig{TransferCostPerEdge: 1})
+	if err != nil {
+		t.Fatalf("SolveCombinatorialAuction failed: %v", err)
+	}
+
+	if !result.Exact {
+		t.Error("expected the exact solver for a small instance")
+	}
+	if len(result.Allocations) != 1 || result.Allocations[0].AgentDID != "did:key:zBundler" {
+		t.Fatalf("expected the bundler to win everything, got %+v", result.Allocations)
+	}
+	if !approxEqual(result.TotalCost, 9) || result.CrossAgentEdges != 0 {
+		t.Errorf("expected cost 9 with no transfer, got %.2f with %d cross edges", result.TotalCost, result.CrossAgentEdges)
+	}
+
+	// Without the bundler the singles agent runs both nodes for 12
+	if payment := result.Allocations[0].Payment; !approxEqual(payment, 12) {
+		t.Errorf("expected VCG payment 12, got %.2f", payment)
+	}
+
+	ApplyAllocation(workflow, result)
+	for _, node := range workflow.Nodes {
+		if node.AgentID != "did:key:zBundler" {
+			t.Errorf("expected node %s pinned to the bundler, got %q", node.ID, node.AgentID)
+		}
+		if !approxEqual(node.Budget, 6) {
+			t.Errorf("expected node %s budget 6, got %.2f", node.ID, node.Budget)
+		}
+	}
+}
+
+func TestCombinatorialAuctionXORLimitsWins(t *testing.T) {
+	workflow := ocrTranslateWorkflow(t)
+	bids := []CombinatorialBid{
+		{
+			AgentDID: "did:key:zCheap",
+			Language: BidLanguageXOR,
+			Bundles: []BundleBid{
+				{BidID: "a-ocr", NodeIDs: []string{"ocr"}, Price: 1},
+				{BidID: "a-translate", NodeIDs: []string{"translate"}, Price: 2},
+			},
+		},
+		{
+			AgentDID: "did:key:zOther",
+			Bundles: []BundleBid{
+				{BidID: "b-ocr", NodeIDs: []string{"ocr"}, Price: 5},
+				{BidID: "b-translate", NodeIDs: []string{"translate"}, Price: 5},
+			},
+		},
+	}
+
+	result, err := SolveCombinatorialAuction(workflow, bids, CombinatorialConfig{})
+	if err != nil {
+		t.Fatalf("SolveCombinatorialAuction failed: %v", err)
+	}
+
+	if len(result.Allocations) != 2 {
+		t.Fatalf("expected two winners, got %+v", result.Allocations)
+	}
+	for _, alloc := range result.Allocations {
+		if alloc.AgentDID == "did:key:zCheap" && len(alloc.NodeIDs) != 1 {
+			t.Errorf("expected the XOR bidder to win one node, got %v", alloc.NodeIDs)
+		}
+	}
+	if !approxEqual(result.TotalCost, 6) {
+		t.Errorf("expected cost 6, got %.2f", result.TotalCost)
+	}
+}
+
+func TestCombinatorialAuctionFallbackAndGreedy(t *testing.T) {
+	workflow := ocrTranslateWorkflow(t)
+	bids := []CombinatorialBid{
+		{
+			AgentDID: "did:key:zBundler",
+			Language: BidLanguageXOR,
+			Bundles: []BundleBid{
+				{BidID: "a-ocr", NodeIDs: []string{"ocr"}, Price: 6},
+				{BidID: "a-both", NodeIDs: []string{"ocr", "translate"}, Price: 9},
+			},
+		},
+		{
+			AgentDID: "did:key:zSingles",
+			Bundles: []BundleBid{
+				{BidID: "b-ocr", NodeIDs: []string{"ocr"}, Price: 5},
+			},
+		},
+	}
+
+	result, err := SolveCombinatorialAuction(workflow, bids, CombinatorialConfig{ExactMaxBundles: 1, TransferCostPerEdge: 1})
+	if err != nil {
+		t.Fatalf("SolveCombinatorialAuction failed: %v", err)
+	}
+
+	if result.Exact {
+		t.Error("expected the greedy solver above the bundle threshold")
+	}
+	// Greedy takes the cheapest single first and leaves translate to fallback
+	if len(result.Unallocated) != 1 || result.Unallocated[0] != "translate" {
+		t.Errorf("expected translate to fall back, got %v", result.Unallocated)
+	}
+	if !approxEqual(result.TotalCost, 16) {
+		t.Errorf("expected cost 16, got %.2f", result.TotalCost)
+	}
+	for _, alloc := range result.Allocations {
+		if alloc.Payment < alloc.Price {
+			t.Errorf("payment %.2f below price %.2f", alloc.Payment, alloc.Price)
+		}
+	}
+}
+
+func TestCombinatorialAuctionRejectsUnknownNode(t *testing.T) {
+	workflow := ocrTranslateWorkflow(t)
+	bids := []CombinatorialBid{{
+		AgentDID: "did:key:zAgent",
+		Bundles:  []BundleBid{{BidID: "x", NodeIDs: []string{"summarize"}, Price: 1}},
+	}}
+
+	if _, err := SolveCombinatorialAuction(workflow, bids, CombinatorialConfig{}); !errors.Is(err, ErrInvalidBundleBid) {
+		t.Errorf("expected ErrInvalidBundleBid, got %v", err)
+	}
+}
+
+// fixedBundleAuction solves every workflow against the same bids
+type fixedBundleAuction struct {
+	bids  []CombinatorialBid
+	calls int
+}
+
+func (f *fixedBundleAuction) StartCombinatorialAuction(ctx context.Context, workflow *DAGWorkflow, window time.Duration, cfg CombinatorialConfig) (*CombinatorialAuctionResult, error) {
+	f.calls++
+	return SolveCombinatorialAuction(workflow, f.bids, cfg)
+}
+
+func TestDAGExecutorAllocatesBundlesOnSubmission(t *testing.T) {
+	auction := &fixedBundleAuction{bids: []CombinatorialBid{{
+		AgentDID: "did:key:zBundler",
+		Bundles:  []BundleBid{{BidID: "both", NodeIDs: []string{"ocr", "translate"}, Price: 8}},
+	}}}
+	de := &DAGExecutor{logger: zap.NewNop()}
+	de.SetCombinatorialAuction(auction, time.Millisecond, CombinatorialConfig{})
+
+	// A workflow decoded from a request has no metadata map
+	workflow := &DAGWorkflow{
+		ID: "wf-1",
+		Nodes: map[string]*DAGNode{
+			"ocr":       {ID: "ocr", Capabilities: []string{"ocr"}, Budget: 10},
+			"translate": {ID: "translate", Capabilities: []string{"translate"}, Budget: 10, Dependencies: []string{"ocr"}},
+		},
+	}
+	de.allocateBundles(context.Background(), workflow)
+
+	for _, node := range workflow.Nodes {
+		if node.AgentID != "did:key:zBundler" {
+			t.Errorf("expected node %s pinned to the bundler, got %q", node.ID, node.AgentID)
+		}
+	}
+	if _, ok := workflow.Metadata["combinatorial_auction"]; !ok {
+		t.Error("expected the auction outcome in the workflow metadata")
+	}
+
+	// Nodes the caller pinned are left alone
+	workflow = ocrTranslateWorkflow(t)
+	workflow.Nodes["ocr"].AgentID = "did:key:zChosen"
+	de.allocateBundles(context.Background(), workflow)
+	if auction.calls != 1 || workflow.Nodes["translate"].AgentID != "" {
+		t.Errorf("expected no auction for a pinned workflow, got %d calls", auction.calls)
+	}
+}
@@ -2,6 +2,7 @@ package orchestration
 
 import (
 	"encoding/json"
+	"math"
 	"strconv"
 )
 
@@ -19,6 +20,12 @@ func extractActualCost(defaultCost float64, result map[string]interface{}) float
 	return defaultCost
 }
 
+// proRatedCost is the share of budget earned by a task stopped at progress
+// (0.0 - 1.0)
+func proRatedCost(budget float64, progress float64) float64 {
+	return budget * math.Max(0, math.Min(progress, 1))
+}
+
 func parseFloat(value interface{}) (float64, bool) {
 	switch v := value.(type) {
 	case float64:
@@ -70,6 +70,7 @@ type DAGNode struct {
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	ExecutionMS int64                  `json:"execution_ms,omitempty"`
+	ActualCost  float64                `json:"actual_cost,omitempty"` // Price charged by the executing agent
 }
 
 // DAGWorkflow represents a DAG-based multi-agent workflow
@@ -94,8 +95,9 @@ type DAGWorkflow struct {
 	Error       string      `json:"error,omitempty"`
 
 	// Execution Configuration
-	MaxParallelism int           `json:"max_parallelism"` // Max concurrent nodes (0 = unlimited)
-	Timeout        time.Duration `json:"timeout"`         // Total workflow timeout
+	MaxParallelism int           `json:"max_parallelism"`        // Max concurrent nodes (0 = unlimited)
+	Timeout        time.Duration `json:"timeout"`                // Total workflow timeout
+	SplitPolicy    string        `json:"split_policy,omitempty"` // How TotalBudget is split between agents (default equal)
 }
 
 // DAGExecutor executes DAG-based workflows with parallel execution
@@ -108,6 +110,12 @@ type DAGExecutor struct {
 	// Active workflows
 	activeWorkflows map[string]*dagExecution
 
+	// Optional combinatorial auction run before execution
+	bundles *dagBundleAuction
+
+	// Optional payment settlement of TotalBudget across the workflow's agents
+	paymentManager *PaymentLifecycleManager
+
 	// Metrics
 	metricsDAGTotal       prometheus.Counter
 	metricsDAGSuccess     prometheus.Counter
@@ -194,6 +202,16 @@ func (de *DAGExecutor) ExecuteDAG(ctx context.Context, workflow *DAGWorkflow) er
 		return fmt.Errorf("%w: %v", ErrDAGInvalidNode, err)
 	}
 
+	policy, err := SplitPolicyByName(workflow.SplitPolicy)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDAGInvalidNode, err)
+	}
+
+	// Auction the workflow as bundles so one agent can win dependent nodes
+	de.allocateBundles(ctx, workflow)
+	de.openPayment(workflow)
+	defer de.settlePayment(ctx, workflow, policy)
+
 	// Create execution context with timeout
 	execCtx := ctx
 	if workflow.Timeout > 0 {
@@ -547,6 +565,7 @@ func (de *DAGExecutor) executeNode(execution *dagExecution, node *DAGNode) error
 		execution.nodeResults[node.ID] = result
 		execution.workflow.TotalCost += resp.Price
 		execution.mu.Unlock()
+		node.ActualCost = resp.Price
 
 		nodeCompleted := time.Now()
 		node.CompletedAt = &nodeCompleted
@@ -0,0 +1,533 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// CONTRIBUTION-WEIGHTED PAYMENT SPLITTING:
+// An equal split pays a cheap formatting helper as much as the agent that did
+// the heavy lifting. Split policies weight each node's share by what it
+// actually contributed to the workflow. Every policy produces a DAGSplitPlan
+// whose splits sum EXACTLY to the payable amount.
+
+// Split policy errors
+var (
+	// ErrInvalidSplitShares indicates a policy returned negative or over-allocated shares
+	ErrInvalidSplitShares = errors.New("split shares must be non-negative and sum to at most 1.0")
+
+	// ErrNoExecutedNodes indicates no node of the workflow ran on an agent
+	ErrNoExecutedNodes = errors.New("DAG workflow has no executed nodes")
+
+	// ErrUnknownSplitPolicy indicates a workflow named a policy that doesn't exist
+	ErrUnknownSplitPolicy = errors.New("unknown split policy")
+)
+
+// SplitPolicy decides what share of a workflow's payment each node earns
+type SplitPolicy interface {
+	// Name identifies the policy in plans and audit logs
+	Name() string
+
+	// Shares returns each node's share of the total payment, keyed by node ID.
+	// Shares must be non-negative and sum to at most 1.0; any remainder is
+	// returned to the payer (clawback).
+	Shares(workflow *DAGWorkflow) (map[string]float64, error)
+}
+
+// SplitPolicyByName returns the policy a workflow selects by name. An empty
+// name selects the equal split; "clawback" wraps cost_proportional.
+func SplitPolicyByName(name string) (SplitPolicy, error) {
+	switch name {
+	case "", "equal":
+		return EqualSplitPolicy{}, nil
+	case "cost_proportional":
+		return CostProportionalSplitPolicy{}, nil
+	case "shapley":
+		return ShapleySplitPolicy{}, nil
+	case "critical_path":
+		return CriticalPathSplitPolicy{}, nil
+	case "clawback":
+		return ClawbackSplitPolicy{Base: CostProportionalSplitPolicy{}}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownSplitPolicy, name)
+}
+
+// DAGSplit is one node's cut of a workflow payment
+type DAGSplit struct {
+	NodeID   string  `json:"node_id"`
+	AgentDID string  `json:"agent_did"`
+	Ratio    float64 `json:"ratio"`  // Share of Payable
+	Amount   float64 `json:"amount"` // Payable * Ratio
+	Success  bool    `json:"success"`
+}
+
+// DAGSplitPlan is the outcome of applying a SplitPolicy to a finished workflow
+type DAGSplitPlan struct {
+	Policy       string     `json:"policy"`        // Policy that produced the plan
+	TotalPayment float64    `json:"total_payment"` // Payment offered for the workflow
+	Payable      float64    `json:"payable"`       // Amount owed to agents
+	ClawedBack   float64    `json:"clawed_back"`   // Amount returned to the payer
+	Splits       []DAGSplit `json:"splits"`        // Ratios sum to 1.0
+}
+
+// AgentShares sums the amounts owed to each agent whose node succeeded.
+// Failed nodes' amounts are left out, so they are refunded with the clawback.
+func (p *DAGSplitPlan) AgentShares() map[string]float64 {
+	shares := make(map[string]float64)
+	for _, split := range p.Splits {
+		if split.Success && split.Amount > 0 {
+			shares[split.AgentDID] += split.Amount
+		}
+	}
+	return shares
+}
+
+// PlanDAGPayment applies a split policy to an executed workflow
+// ALGORITHM: shares → drop zero-share nodes → normalize ratios → exact amounts
+func PlanDAGPayment(workflow *DAGWorkflow, totalPayment float64, policy SplitPolicy) (*DAGSplitPlan, error) {
+	if policy == nil {
+		policy = EqualSplitPolicy{}
+	}
+	if totalPayment <= 0 {
+		return nil, fmt.Errorf("total payment must be positive: %f", totalPayment)
+	}
+
+	shares, err := policy.Shares(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate %s shares: %w", policy.Name(), err)
+	}
+
+	nodeIDs := make([]string, 0, len(shares))
+	shareSum := 0.0
+	for nodeID, share := range shares {
+		if share < 0 || math.IsNaN(share) {
+			return nil, ErrInvalidSplitShares
+		}
+		node, ok := workflow.Nodes[nodeID]
+		if !ok || node.AssignedTo == "" || share == 0 {
+			continue
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+		shareSum += share
+	}
+	if shareSum > 1.0+1e-4 {
+		return nil, ErrInvalidSplitShares
+	}
+
+	plan := &DAGSplitPlan{
+		Policy:       policy.Name(),
+		TotalPayment: totalPayment,
+	}
+	if len(nodeIDs) == 0 {
+		// Every node was clawed back - nothing is owed
+		plan.ClawedBack = totalPayment
+		return plan, nil
+	}
+	sort.Strings(nodeIDs) // deterministic settlement order
+
+	plan.Payable = totalPayment
+	if shareSum < 1.0-1e-9 {
+		plan.Payable = totalPayment * shareSum
+	}
+	plan.ClawedBack = totalPayment - plan.Payable
+
+	// Amounts are derived from ratios; the rounding residue goes to the largest
+	// split so the splits sum exactly to Payable
+	allocated := 0.0
+	largest := 0
+	for i, nodeID := range nodeIDs {
+		node := workflow.Nodes[nodeID]
+		ratio := shares[nodeID] / shareSum
+		split := DAGSplit{
+			NodeID:   nodeID,
+			AgentDID: node.AssignedTo,
+			Ratio:    ratio,
+			Amount:   plan.Payable * ratio,
+			Success:  node.Status == DAGNodeStatusCompleted,
+		}
+		allocated += split.Amount
+		if i > 0 && split.Amount > plan.Splits[largest].Amount {
+			largest = i
+		}
+		plan.Splits = append(plan.Splits, split)
+	}
+	plan.Splits[largest].Amount += plan.Payable - allocated
+
+	return plan, nil
+}
+
+// SetPaymentManager makes ExecuteDAG escrow each workflow's TotalBudget and
+// split it between the agents with the workflow's SplitPolicy when it ends
+func (de *DAGExecutor) SetPaymentManager(pm *PaymentLifecycleManager) {
+	de.mu.Lock()
+	defer de.mu.Unlock()
+	de.paymentManager = pm
+}
+
+// payments returns the payment manager, or nil when workflows aren't paid
+func (de *DAGExecutor) payments(workflow *DAGWorkflow) *PaymentLifecycleManager {
+	de.mu.RLock()
+	defer de.mu.RUnlock()
+	if workflow.TotalBudget <= 0 {
+		return nil
+	}
+	return de.paymentManager
+}
+
+// openPayment records the workflow's payment before any node runs
+func (de *DAGExecutor) openPayment(workflow *DAGWorkflow) {
+	pm := de.payments(workflow)
+	if pm == nil {
+		return
+	}
+	pm.CreatePayment(workflow.ID, workflow.UserID, workflow.TotalBudget)
+	if err := pm.UpdatePaymentStatus(workflow.ID, PaymentStatusAccepted, "workflow started", ""); err != nil {
+		de.logger.Error("failed to accept workflow payment",
+			zap.String("workflow_id", workflow.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// settlePayment pays each agent its policy share of the nodes it completed
+// and refunds the rest
+func (de *DAGExecutor) settlePayment(ctx context.Context, workflow *DAGWorkflow, policy SplitPolicy) {
+	pm := de.payments(workflow)
+	if pm == nil {
+		return
+	}
+
+	var shares map[string]float64
+	plan, err := PlanDAGPayment(workflow, workflow.TotalBudget, policy)
+	if err == nil {
+		shares = plan.AgentShares()
+		if workflow.Metadata == nil {
+			workflow.Metadata = make(map[string]interface{})
+		}
+		workflow.Metadata["payment_split"] = plan
+	} else if !errors.Is(err, ErrNoExecutedNodes) {
+		de.logger.Error("failed to plan workflow payment",
+			zap.String("workflow_id", workflow.ID),
+			zap.Error(err),
+		)
+	}
+
+	if len(shares) == 0 {
+		err = pm.RefundPayment(ctx, workflow.ID, "no workflow node earned a share")
+	} else {
+		err = pm.ReleaseSharedPayment(ctx, workflow.ID, shares)
+	}
+	if err != nil {
+		de.logger.Error("failed to settle workflow payment",
+			zap.String("workflow_id", workflow.ID),
+			zap.String("split_policy", policy.Name()),
+			zap.Error(err),
+		)
+	}
+}
+
+// EqualSplitPolicy pays every executed node the same share
+type EqualSplitPolicy struct{}
+
+// Name implements SplitPolicy
+func (EqualSplitPolicy) Name() string { return "equal" }
+
+// Shares implements SplitPolicy
+func (EqualSplitPolicy) Shares(workflow *DAGWorkflow) (map[string]float64, error) {
+	nodes := executedNodes(workflow)
+	if len(nodes) == 0 {
+		return nil, ErrNoExecutedNodes
+	}
+
+	shares := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		shares[node.ID] = 1.0 / float64(len(nodes))
+	}
+	return shares, nil
+}
+
+// CostProportionalSplitPolicy pays each node in proportion to the price its agent charged
+type CostProportionalSplitPolicy struct{}
+
+// Name implements SplitPolicy
+func (CostProportionalSplitPolicy) Name() string { return "cost_proportional" }
+
+// Shares implements SplitPolicy
+func (CostProportionalSplitPolicy) Shares(workflow *DAGWorkflow) (map[string]float64, error) {
+	nodes := executedNodes(workflow)
+	if len(nodes) == 0 {
+		return nil, ErrNoExecutedNodes
+	}
+
+	weights := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		weights[node.ID] = nodeValue(node)
+	}
+	return normalizeShares(weights), nil
+}
+
+// ShapleySplitPolicy pays each node its Shapley value over the DAG
+// ALGORITHM: A node's value (its cost) is only realized when the node AND all of
+// its transitive dependencies are in the coalition. The game is therefore a sum
+// of unanimity games, whose Shapley value is exact and polynomial: each node's
+// value is shared equally among itself and its ancestors. Upstream helpers earn
+// a cut of everything they enable, core nodes keep most of their own value.
+type ShapleySplitPolicy struct{}
+
+// Name implements SplitPolicy
+func (ShapleySplitPolicy) Name() string { return "shapley" }
+
+// Shares implements SplitPolicy
+func (ShapleySplitPolicy) Shares(workflow *DAGWorkflow) (map[string]float64, error) {
+	nodes := executedNodes(workflow)
+	if len(nodes) == 0 {
+		return nil, ErrNoExecutedNodes
+	}
+
+	executed := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		executed[node.ID] = true
+	}
+
+	weights := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		// The unanimity coalition: the node plus every executed ancestor.
+		// A failed node keeps its own value as a singleton so its split is
+		// still reported (Success=false) and settlement can refund or abort.
+		members := []string{node.ID}
+		if node.Status == DAGNodeStatusCompleted {
+			for ancestorID := range ancestors(workflow, node.ID) {
+				if executed[ancestorID] {
+					members = append(members, ancestorID)
+				}
+			}
+		}
+
+		portion := nodeValue(node) / float64(len(members))
+		for _, memberID := range members {
+			weights[memberID] += portion
+		}
+	}
+
+	return normalizeShares(weights), nil
+}
+
+// CriticalPathSplitPolicy boosts nodes on the workflow's critical path
+// ALGORITHM: Longest path by ExecutionMS through the DAG; nodes on it have their
+// cost weighted by Multiplier since they determined end-to-end latency.
+type CriticalPathSplitPolicy struct {
+	Multiplier float64 // Weight applied to critical-path nodes (default 2.0)
+}
+
+// Name implements SplitPolicy
+func (CriticalPathSplitPolicy) Name() string { return "critical_path" }
+
+// Shares implements SplitPolicy
+func (p CriticalPathSplitPolicy) Shares(workflow *DAGWorkflow) (map[string]float64, error) {
+	nodes := executedNodes(workflow)
+	if len(nodes) == 0 {
+		return nil, ErrNoExecutedNodes
+	}
+
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2.0
+	}
+
+	critical := criticalPath(workflow)
+	weights := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		weight := nodeValue(node)
+		if critical[node.ID] {
+			weight *= multiplier
+		}
+		weights[node.ID] = weight
+	}
+	return normalizeShares(weights), nil
+}
+
+// ClawbackSplitPolicy wraps another policy and claws back the share of nodes
+// that failed or whose output was discarded (no completed node consumed it).
+// SECURITY: Clawed-back shares are NOT redistributed - they return to the payer,
+// so agents cannot inflate their payout by padding the DAG with dead branches.
+type ClawbackSplitPolicy struct {
+	Base SplitPolicy // Policy deciding shares before clawback (default equal)
+}
+
+// Name implements SplitPolicy
+func (p ClawbackSplitPolicy) Name() string {
+	return "clawback(" + p.base().Name() + ")"
+}
+
+// Shares implements SplitPolicy
+func (p ClawbackSplitPolicy) Shares(workflow *DAGWorkflow) (map[string]float64, error) {
+	shares, err := p.base().Shares(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	for nodeID := range shares {
+		node, ok := workflow.Nodes[nodeID]
+		if !ok || node.Status != DAGNodeStatusCompleted || outputDiscarded(workflow, nodeID) {
+			shares[nodeID] = 0
+		}
+	}
+	return shares, nil
+}
+
+func (p ClawbackSplitPolicy) base() SplitPolicy {
+	if p.Base == nil {
+		return EqualSplitPolicy{}
+	}
+	return p.Base
+}
+
+// executedNodes returns nodes that were assigned to an agent and finished, in ID order
+func executedNodes(workflow *DAGWorkflow) []*DAGNode {
+	if workflow == nil {
+		return nil
+	}
+
+	nodes := make([]*DAGNode, 0, len(workflow.Nodes))
+	for _, node := range workflow.Nodes {
+		if node.AssignedTo == "" {
+			continue
+		}
+		if node.Status != DAGNodeStatusCompleted && node.Status != DAGNodeStatusFailed {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// nodeValue is the contribution weight of a node: actual cost, then budget, then 1
+func nodeValue(node *DAGNode) float64 {
+	if node.ActualCost > 0 {
+		return node.ActualCost
+	}
+	if node.Budget > 0 {
+		return node.Budget
+	}
+	return 1.0
+}
+
+// normalizeShares scales weights so they sum to 1.0
+func normalizeShares(weights map[string]float64) map[string]float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+
+	shares := make(map[string]float64, len(weights))
+	for id, w := range weights {
+		if total > 0 {
+			shares[id] = w / total
+		} else {
+			shares[id] = 1.0 / float64(len(weights))
+		}
+	}
+	return shares
+}
+
+// ancestors returns the transitive dependencies of a node
+func ancestors(workflow *DAGWorkflow, nodeID string) map[string]bool {
+	seen := make(map[string]bool)
+	stack := []string{nodeID}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		node, ok := workflow.Nodes[current]
+		if !ok {
+			continue
+		}
+		for _, depID := range node.Dependencies {
+			if !seen[depID] {
+				seen[depID] = true
+				stack = append(stack, depID)
+			}
+		}
+	}
+	return seen
+}
+
+// outputDiscarded reports whether a non-sink node's output never reached a completed dependent
+func outputDiscarded(workflow *DAGWorkflow, nodeID string) bool {
+	hasDependents := false
+	for _, node := range workflow.Nodes {
+		for _, depID := range node.Dependencies {
+			if depID != nodeID {
+				continue
+			}
+			hasDependents = true
+			if node.Status == DAGNodeStatusCompleted {
+				return false
+			}
+		}
+	}
+	// Sink nodes produce the workflow output itself
+	return hasDependents
+}
+
+// criticalPath returns the nodes on the longest ExecutionMS path through the DAG
+func criticalPath(workflow *DAGWorkflow) map[string]bool {
+	finish := make(map[string]int64, len(workflow.Nodes))
+	prev := make(map[string]string, len(workflow.Nodes))
+
+	var visit func(id string, visiting map[string]bool) int64
+	visit = func(id string, visiting map[string]bool) int64 {
+		if f, ok := finish[id]; ok {
+			return f
+		}
+		node, ok := workflow.Nodes[id]
+		if !ok || visiting[id] {
+			return 0 // unknown node or cycle - DAG validation rejects both upstream
+		}
+		visiting[id] = true
+
+		var best int64
+		deps := append([]string(nil), node.Dependencies...)
+		sort.Strings(deps)
+		for _, depID := range deps {
+			if f := visit(depID, visiting); prev[id] == "" || f > best {
+				best = f
+				prev[id] = depID
+			}
+		}
+
+		delete(visiting, id)
+		finish[id] = best + node.ExecutionMS
+		return finish[id]
+	}
+
+	ids := make([]string, 0, len(workflow.Nodes))
+	for id := range workflow.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	end := ""
+	var longest int64 = -1
+	for _, id := range ids {
+		if f := visit(id, make(map[string]bool)); f > longest {
+			longest = f
+			end = id
+		}
+	}
+
+	path := make(map[string]bool)
+	for id := end; id != ""; id = prev[id] {
+		if path[id] {
+			break
+		}
+		path[id] = true
+	}
+	return path
+}
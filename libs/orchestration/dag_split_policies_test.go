@@ -0,0 +1,167 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// pipelineWorkflow is fetch → analyze → format next to lint → publish, where
+// publish failed and so discarded lint's output. Costs sum to 14.
+func pipelineWorkflow() *DAGWorkflow {
+	node := func(id, agent string, cost float64, ms int64, status DAGNodeStatus, deps ...string) *DAGNode {
+		return &DAGNode{ID: id, AssignedTo: agent, ActualCost: cost, ExecutionMS: ms, Status: status, Dependencies: deps}
+	}
+	return &DAGWorkflow{
+		ID:     "wf-split",
+		UserID: "user-1",
+		Nodes: map[string]*DAGNode{
+			"fetch":   node("fetch", "did:agent:a", 2, 100, DAGNodeStatusCompleted),
+			"analyze": node("analyze", "did:agent:b", 6, 300, DAGNodeStatusCompleted, "fetch"),
+			"format":  node("format", "did:agent:c", 2, 50, DAGNodeStatusCompleted, "analyze"),
+			"lint":    node("lint", "did:agent:d", 2, 20, DAGNodeStatusCompleted),
+			"publish": node("publish", "did:agent:e", 2, 10, DAGNodeStatusFailed, "lint"),
+		},
+	}
+}
+
+func TestSplitPolicyShares(t *testing.T) {
+	tests := []struct {
+		policy SplitPolicy
+		want   map[string]float64
+	}{
+		{EqualSplitPolicy{}, map[string]float64{
+			"fetch": 0.2, "analyze": 0.2, "format": 0.2, "lint": 0.2, "publish": 0.2,
+		}},
+		{CostProportionalSplitPolicy{}, map[string]float64{
+			"fetch": 2.0 / 14, "analyze": 6.0 / 14, "format": 2.0 / 14, "lint": 2.0 / 14, "publish": 2.0 / 14,
+		}},
+		// analyze shares its value with fetch, format with both ancestors
+		{ShapleySplitPolicy{}, map[string]float64{
+			"fetch": (2 + 3 + 2.0/3) / 14, "analyze": (3 + 2.0/3) / 14, "format": (2.0 / 3) / 14, "lint": 2.0 / 14, "publish": 2.0 / 14,
+		}},
+		// fetch → analyze → format is the longest path and counts double
+		{CriticalPathSplitPolicy{}, map[string]float64{
+			"fetch": 4.0 / 24, "analyze": 12.0 / 24, "format": 4.0 / 24, "lint": 2.0 / 24, "publish": 2.0 / 24,
+		}},
+		// publish failed and lint's output went nowhere
+		{ClawbackSplitPolicy{Base: CostProportionalSplitPolicy{}}, map[string]float64{
+			"fetch": 2.0 / 14, "analyze": 6.0 / 14, "format": 2.0 / 14, "lint": 0, "publish": 0,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.Name(), func(t *testing.T) {
+			shares, err := tt.policy.Shares(pipelineWorkflow())
+			if err != nil {
+				t.Fatalf("Shares failed: %v", err)
+			}
+			if len(shares) != len(tt.want) {
+				t.Fatalf("expected %d shares, got %v", len(tt.want), shares)
+			}
+			for nodeID, want := range tt.want {
+				if !approxEqual(shares[nodeID], want) {
+					t.Errorf("%s: expected share %.4f, got %.4f", nodeID, want, shares[nodeID])
+				}
+			}
+		})
+	}
+
+	if _, err := (EqualSplitPolicy{}).Shares(&DAGWorkflow{}); !errors.Is(err, ErrNoExecutedNodes) {
+		t.Errorf("expected ErrNoExecutedNodes, got %v", err)
+	}
+}
+
+func TestPlanDAGPaymentClawsBack(t *testing.T) {
+	plan, err := PlanDAGPayment(pipelineWorkflow(), 140, ClawbackSplitPolicy{Base: CostProportionalSplitPolicy{}})
+	if err != nil {
+		t.Fatalf("PlanDAGPayment failed: %v", err)
+	}
+
+	if !approxEqual(plan.Payable, 100) || !approxEqual(plan.ClawedBack, 40) {
+		t.Errorf("expected 100 payable and 40 clawed back, got %.2f and %.2f", plan.Payable, plan.ClawedBack)
+	}
+	sum := 0.0
+	for _, split := range plan.Splits {
+		sum += split.Amount
+	}
+	if sum != plan.Payable {
+		t.Errorf("splits sum to %v, expected exactly %v", sum, plan.Payable)
+	}
+
+	want := map[string]float64{"did:agent:a": 20, "did:agent:b": 60, "did:agent:c": 20}
+	shares := plan.AgentShares()
+	if len(shares) != len(want) {
+		t.Fatalf("expected shares for %d agents, got %v", len(want), shares)
+	}
+	for agent, amount := range want {
+		if !approxEqual(shares[agent], amount) {
+			t.Errorf("%s: expected %.2f, got %.2f", agent, amount, shares[agent])
+		}
+	}
+}
+
+func TestSplitPolicyByName(t *testing.T) {
+	for name, want := range map[string]string{
+		"":                  "equal",
+		"cost_proportional": "cost_proportional",
+		"shapley":           "shapley",
+		"critical_path":     "critical_path",
+		"clawback":          "clawback(cost_proportional)",
+	} {
+		policy, err := SplitPolicyByName(name)
+		if err != nil {
+			t.Fatalf("SplitPolicyByName(%q) failed: %v", name, err)
+		}
+		if policy.Name() != want {
+			t.Errorf("SplitPolicyByName(%q) = %s, expected %s", name, policy.Name(), want)
+		}
+	}
+
+	if _, err := SplitPolicyByName("lottery"); !errors.Is(err, ErrUnknownSplitPolicy) {
+		t.Errorf("expected ErrUnknownSplitPolicy, got %v", err)
+	}
+}
+
+func TestDAGExecutorSettlesWithWorkflowPolicy(t *testing.T) {
+	ctx := context.Background()
+	chain := &sharingChain{
+		splittingChain: &splittingChain{partial: make(chan float64, 1), refunds: make(chan string, 1)},
+		shares:         make(chan map[string]float64, 1),
+	}
+	de := &DAGExecutor{logger: zap.NewNop()}
+	de.SetPaymentManager(NewPaymentLifecycleManager(chain, DefaultPaymentConfig(), zap.NewNop()))
+
+	workflow := pipelineWorkflow()
+	workflow.TotalBudget = 140
+	workflow.SplitPolicy = "clawback"
+	policy, err := SplitPolicyByName(workflow.SplitPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	de.openPayment(workflow)
+	de.settlePayment(ctx, workflow, policy)
+
+	shares := <-chain.shares
+	if len(shares) != 3 || !approxEqual(shares["did:agent:b"], 60) {
+		t.Errorf("expected the clawback plan's shares, got %v", shares)
+	}
+	if _, ok := workflow.Metadata["payment_split"].(*DAGSplitPlan); !ok {
+		t.Error("expected the plan in the workflow metadata")
+	}
+
+	// A workflow where nothing succeeded is refunded
+	failed := pipelineWorkflow()
+	failed.ID = "wf-failed"
+	failed.TotalBudget = 10
+	for _, node := range failed.Nodes {
+		node.Status = DAGNodeStatusFailed
+	}
+	de.openPayment(failed)
+	de.settlePayment(ctx, failed, EqualSplitPolicy{})
+	if refunded := <-chain.refunds; refunded != "wf-failed" {
+		t.Errorf("expected wf-failed refunded, got %s", refunded)
+	}
+}
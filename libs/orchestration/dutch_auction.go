@@ -0,0 +1,385 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"go.uber.org/zap"
+)
+
+// Dutch auction errors
+var (
+	ErrInvalidDutchConfig  = errors.New("invalid dutch auction configuration")
+	ErrAuctionAlreadyTaken = errors.New("auction already claimed by another agent")
+	ErrDutchAuctionExpired = errors.New("dutch auction expired")
+	ErrAskAboveClock       = errors.New("ask above current clock price")
+)
+
+// DutchDecay selects how the Dutch price clock falls between ticks
+type DutchDecay string
+
+const (
+	DutchDecayLinear      DutchDecay = "linear"      // Reaches the reserve exactly at expiry
+	DutchDecayExponential DutchDecay = "exponential" // Remaining spread shrinks by DecayRate per tick
+	DutchDecayStep        DutchDecay = "step"        // Falls by StepSize per tick
+)
+
+const (
+	// defaultDutchTicks is the number of ticks a clock uses when no interval is set
+	defaultDutchTicks = 10
+
+	// minDutchTickInterval bounds how often price ticks are broadcast
+	minDutchTickInterval = 10 * time.Millisecond
+
+	// defaultDutchReserveFraction is the share of the budget the clock stops at
+	defaultDutchReserveFraction = 0.5
+)
+
+// DutchSchedule configures the price clock of a Dutch auction. The clock
+// starts at the task budget and decays toward the reserve, never below it.
+// The first agent to accept the current price wins the task at that price, so
+// urgent tasks are allocated in one round-trip instead of a full bid window.
+type DutchSchedule struct {
+	Duration        time.Duration `json:"duration"`                   // How long the clock runs
+	Decay           DutchDecay    `json:"decay"`                      // Defaults to linear
+	TickInterval    time.Duration `json:"tick_interval"`              // Defaults to Duration / 10
+	DecayRate       float64       `json:"decay_rate,omitempty"`       // Exponential: fraction of the spread removed per tick
+	StepSize        float64       `json:"step_size,omitempty"`        // Step: price drop per tick
+	ReserveFraction float64       `json:"reserve_fraction,omitempty"` // Share of the budget the clock stops at when the task sets no reserve price (default 0.5)
+}
+
+// withDefaults validates the schedule and fills in defaults
+func (s DutchSchedule) withDefaults() (DutchSchedule, error) {
+	if s.Duration <= 0 {
+		return s, fmt.Errorf("%w: duration must be positive", ErrInvalidDutchConfig)
+	}
+	if s.Decay == "" {
+		s.Decay = DutchDecayLinear
+	}
+	if s.TickInterval == 0 {
+		s.TickInterval = s.Duration / defaultDutchTicks
+	}
+	if s.TickInterval < minDutchTickInterval {
+		s.TickInterval = minDutchTickInterval
+	}
+	if s.ReserveFraction == 0 {
+		s.ReserveFraction = defaultDutchReserveFraction
+	}
+	if s.ReserveFraction < 0 || s.ReserveFraction > 1 {
+		return s, fmt.Errorf("%w: reserve fraction must be in [0, 1]", ErrInvalidDutchConfig)
+	}
+
+	switch s.Decay {
+	case DutchDecayLinear:
+	case DutchDecayExponential:
+		if s.DecayRate <= 0 || s.DecayRate >= 1 {
+			return s, fmt.Errorf("%w: exponential decay rate must be in (0, 1)", ErrInvalidDutchConfig)
+		}
+	case DutchDecayStep:
+		if s.StepSize <= 0 {
+			return s, fmt.Errorf("%w: step size must be positive", ErrInvalidDutchConfig)
+		}
+	default:
+		return s, fmt.Errorf("%w: unknown decay %q", ErrInvalidDutchConfig, s.Decay)
+	}
+	return s, nil
+}
+
+// DutchAuction is the price clock of one task. Accept is safe for concurrent
+// use; the first acceptance claims the task.
+type DutchAuction struct {
+	CFPID        string
+	MaxPrice     float64
+	ReservePrice float64
+	Schedule     DutchSchedule
+	OpenedAt     time.Time
+	ExpiresAt    time.Time
+
+	mu     sync.Mutex
+	winner *BidSummary
+	closed bool
+}
+
+// NewDutchAuction opens a price clock at maxPrice
+func NewDutchAuction(cfpID string, maxPrice float64, schedule DutchSchedule, now time.Time) (*DutchAuction, error) {
+	if maxPrice <= 0 {
+		return nil, fmt.Errorf("%w: max price must be positive", ErrInvalidDutchConfig)
+	}
+	schedule, err := schedule.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DutchAuction{
+		CFPID:        cfpID,
+		MaxPrice:     maxPrice,
+		ReservePrice: maxPrice * schedule.ReserveFraction,
+		Schedule:     schedule,
+		OpenedAt:     now,
+		ExpiresAt:    now.Add(schedule.Duration),
+	}, nil
+}
+
+// PriceAt returns the clock price at time t. Prices only change on tick
+// boundaries so every agent sees the same price between broadcasts.
+func (d *DutchAuction) PriceAt(t time.Time) float64 {
+	if !t.After(d.OpenedAt) {
+		return d.MaxPrice
+	}
+
+	schedule := d.Schedule
+	ticks := math.Floor(float64(t.Sub(d.OpenedAt)) / float64(schedule.TickInterval))
+	spread := d.MaxPrice - d.ReservePrice
+
+	var price float64
+	switch schedule.Decay {
+	case DutchDecayExponential:
+		price = d.ReservePrice + spread*math.Pow(1-schedule.DecayRate, ticks)
+	case DutchDecayStep:
+		price = d.MaxPrice - ticks*schedule.StepSize
+	default:
+		progress := ticks * float64(schedule.TickInterval) / float64(schedule.Duration)
+		price = d.MaxPrice - spread*math.Min(1, progress)
+	}
+
+	return math.Max(price, d.ReservePrice)
+}
+
+// Accept claims the auction for bid at the clock price at now. bid.Price is
+// the agent's minimum: the claim is rejected while the clock is below it.
+// The first acceptance wins; later ones get ErrAuctionAlreadyTaken.
+func (d *DutchAuction) Accept(bid BidSummary, now time.Time) (*BidSummary, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.winner != nil {
+		return nil, ErrAuctionAlreadyTaken
+	}
+	if d.closed || now.After(d.ExpiresAt) {
+		return nil, ErrDutchAuctionExpired
+	}
+
+	price := d.PriceAt(now)
+	if bid.Price > price {
+		return nil, fmt.Errorf("%w: ask %.4f, clock %.4f", ErrAskAboveClock, bid.Price, price)
+	}
+
+	bid.Price = price
+	d.winner = &bid
+	return d.winner, nil
+}
+
+// Winner returns the accepted bid, or nil while the clock is running
+func (d *DutchAuction) Winner() *BidSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.winner
+}
+
+// Close stops the clock and returns the accepted bid, or nil. Once it is
+// closed every acceptance is rejected, so a claim either wins before Close
+// or not at all.
+func (d *DutchAuction) Close() *BidSummary {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return d.winner
+}
+
+// EnableDutchAuctions allocates high and critical priority tasks with a
+// Dutch price clock before falling back to the regular auction
+func (a *Auctioneer) EnableDutchAuctions(schedule DutchSchedule) error {
+	schedule, err := schedule.withDefaults()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.dutch = &schedule
+	return nil
+}
+
+// dutchSchedule returns the schedule for task, or nil if it isn't urgent or
+// Dutch auctions are disabled
+func (a *Auctioneer) dutchSchedule(task *Task) *DutchSchedule {
+	if task.Priority < PriorityHigh || task.Budget <= 0 {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.dutch
+}
+
+// StartDutchAuction broadcasts a falling price for task to the agents of
+// every capability it needs and awards it to the first agent that accepts.
+// The clock runs from the budget down to the task's reserve price. Without an
+// acceptance before the clock expires the result has no winner and TimedOut
+// set.
+func (a *Auctioneer) StartDutchAuction(ctx context.Context, task *Task, schedule DutchSchedule) (*AuctionResult, error) {
+	if a == nil || a.gossip == nil || len(task.Capabilities) == 0 {
+		return &AuctionResult{}, nil
+	}
+
+	clock, err := NewDutchAuction(task.ID, task.Budget, schedule, time.Now())
+	if err != nil {
+		return &AuctionResult{CFPID: task.ID}, err
+	}
+	if task.ReservePrice > 0 {
+		if task.ReservePrice > task.Budget {
+			return &AuctionResult{CFPID: task.ID}, fmt.Errorf("%w: reserve price %.4f above budget %.4f", ErrInvalidDutchConfig, task.ReservePrice, task.Budget)
+		}
+		clock.ReservePrice = task.ReservePrice
+	}
+
+	priceTopic := fmt.Sprintf("ainur/v1/market/dutch/%s/price", task.ID)
+	acceptTopic := fmt.Sprintf("ainur/v1/market/dutch/%s/accept", task.ID)
+
+	claimed := make(chan struct{})
+	var once sync.Once
+	acceptHandler := func(ctx context.Context, msg *p2p.GossipMessage) error {
+		var accept map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &accept); err != nil {
+			return nil
+		}
+		if err := a.verifyBidSignature(accept); err != nil {
+			a.logger.Warn("dutch acceptance signature verification failed",
+				zap.String("cfp_id", task.ID),
+				zap.Error(err),
+			)
+			return nil
+		}
+
+		summary := bidSummaryFromMessage(accept)
+		if len(a.screenBids(task.ID, []BidSummary{summary})) == 0 {
+			return nil
+		}
+		if _, err := clock.Accept(summary, time.Now()); err != nil {
+			a.logger.Debug("dutch acceptance rejected",
+				zap.String("cfp_id", task.ID),
+				zap.String("from", string(summary.AgentDID)),
+				zap.Error(err),
+			)
+			return nil
+		}
+		once.Do(func() { close(claimed) })
+		return nil
+	}
+
+	if err := a.gossip.Subscribe(acceptTopic, acceptHandler); err != nil {
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to subscribe to dutch accept topic: %w", err)
+	}
+	defer a.gossip.Unsubscribe(acceptTopic)
+
+	payload := map[string]interface{}{
+		"cfp_type":         "AACL-Dutch-CFP-v1",
+		"cfp_id":           task.ID,
+		"from":             "orchestrator",
+		"to":               "*",
+		"created_at":       clock.OpenedAt.UTC().Format(time.RFC3339Nano),
+		"expires_at":       clock.ExpiresAt.UTC().Format(time.RFC3339Nano),
+		"current_price":    clock.MaxPrice,
+		"reserve_price":    clock.ReservePrice,
+		"dutch_schedule":   clock.Schedule,
+		"price_topic":      priceTopic,
+		"accept_topic":     acceptTopic,
+		"capabilities":     task.Capabilities,
+		"task_type":        task.Type,
+		"timeout_ms":       task.Timeout.Milliseconds(),
+		"tick_interval_ms": clock.Schedule.TickInterval.Milliseconds(),
+	}
+	published := make(map[string]bool, len(task.Capabilities))
+	for _, capability := range task.Capabilities {
+		if published[capability] {
+			continue
+		}
+		published[capability] = true
+		if err := a.publishJSON("ainur/v1/market/cfp/"+capability, "AACL-Dutch-CFP-v1", payload); err != nil {
+			return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to broadcast dutch CFP: %w", err)
+		}
+	}
+
+	a.logger.Info("dutch auction started",
+		zap.String("task_id", task.ID),
+		zap.Float64("max_price", clock.MaxPrice),
+		zap.Float64("reserve_price", clock.ReservePrice),
+		zap.Duration("duration", clock.Schedule.Duration),
+	)
+
+	ticker := time.NewTicker(clock.Schedule.TickInterval)
+	defer ticker.Stop()
+	expired := time.NewTimer(time.Until(clock.ExpiresAt))
+	defer expired.Stop()
+
+	// award notifies the agent whose claim won the clock
+	award := func(winner *BidSummary) (*AuctionResult, error) {
+		if err := a.sendAcceptProposal(ctx, task.ID, winner); err != nil {
+			a.logger.Warn("failed to send accept proposal to dutch winner",
+				zap.String("cfp_id", task.ID),
+				zap.Error(err),
+			)
+		}
+		a.logger.Info("dutch auction claimed",
+			zap.String("task_id", task.ID),
+			zap.String("winner_did", string(winner.AgentDID)),
+			zap.Float64("price", winner.Price),
+		)
+		return &AuctionResult{CFPID: task.ID, Winner: winner, AllBids: []BidSummary{*winner}}, nil
+	}
+
+	price := clock.MaxPrice
+	for {
+		select {
+		case <-claimed:
+			return award(clock.Winner())
+
+		case now := <-ticker.C:
+			next := clock.PriceAt(now)
+			if next == price {
+				continue
+			}
+			price = next
+			tick := map[string]interface{}{
+				"cfp_id":        task.ID,
+				"price":         price,
+				"reserve_price": clock.ReservePrice,
+				"expires_at":    clock.ExpiresAt.UTC().Format(time.RFC3339Nano),
+			}
+			if err := a.publishJSON(priceTopic, "AACL-Dutch-Price-v1", tick); err != nil {
+				a.logger.Warn("failed to broadcast dutch price tick", zap.Error(err))
+			}
+
+		case <-expired.C:
+			// A claim accepted just before expiry stands
+			if winner := clock.Close(); winner != nil {
+				return award(winner)
+			}
+			a.logger.Info("dutch auction expired unclaimed", zap.String("task_id", task.ID))
+			return &AuctionResult{CFPID: task.ID, TimedOut: true}, nil
+
+		case <-ctx.Done():
+			clock.Close()
+			return &AuctionResult{CFPID: task.ID}, ctx.Err()
+		}
+	}
+}
+
+// publishJSON publishes payload on topic as a gossip message of msgType
+func (a *Auctioneer) publishJSON(topic, msgType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return a.gossip.Publish(topic, &p2p.GossipMessage{
+		Type:      msgType,
+		Payload:   data,
+		Timestamp: time.Now().Unix(),
+		PeerID:    "orchestrator",
+	})
+}
@@ -0,0 +1,157 @@
+package orchestration
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestDutchAuction(t *testing.T, schedule DutchSchedule) (*DutchAuction, time.Time) {
+	t.Helper()
+	opened := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock, err := NewDutchAuction("task-1", 100, schedule, opened)
+	if err != nil {
+		t.Fatalf("NewDutchAuction failed: %v", err)
+	}
+	return clock, opened
+}
+
+func TestDutchAuctionPriceDecay(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule DutchSchedule
+		want     map[time.Duration]float64 // elapsed -> price
+	}{
+		{
+			name:     "linear",
+			schedule: DutchSchedule{Duration: 10 * time.Second},
+			want:     map[time.Duration]float64{0: 100, 999 * time.Millisecond: 100, time.Second: 95, 5 * time.Second: 75, 10 * time.Second: 50},
+		},
+		{
+			name:     "exponential",
+			schedule: DutchSchedule{Duration: 10 * time.Second, Decay: DutchDecayExponential, DecayRate: 0.5},
+			want:     map[time.Duration]float64{0: 100, time.Second: 75, 2 * time.Second: 62.5},
+		},
+		{
+			name:     "step",
+			schedule: DutchSchedule{Duration: 10 * time.Second, Decay: DutchDecayStep, StepSize: 20, ReserveFraction: 0.3},
+			want:     map[time.Duration]float64{time.Second: 80, 3 * time.Second: 40, 4 * time.Second: 30, 9 * time.Second: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, opened := newTestDutchAuction(t, tt.schedule)
+			for elapsed, want := range tt.want {
+				if got := clock.PriceAt(opened.Add(elapsed)); !approxEqual(got, want) {
+					t.Errorf("price after %s: expected %.4f, got %.4f", elapsed, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDutchAuctionReserveFloor(t *testing.T) {
+	clock, opened := newTestDutchAuction(t, DutchSchedule{Duration: time.Second, Decay: DutchDecayExponential, DecayRate: 0.9})
+	if clock.ReservePrice != 50 {
+		t.Fatalf("expected the default reserve at half the budget, got %.2f", clock.ReservePrice)
+	}
+
+	for elapsed := time.Duration(0); elapsed <= time.Hour; elapsed += time.Minute {
+		if price := clock.PriceAt(opened.Add(elapsed)); price < clock.ReservePrice {
+			t.Fatalf("price %.4f fell below the reserve after %s", price, elapsed)
+		}
+	}
+
+	// An agent asking more than the clock offers is turned away
+	_, err := clock.Accept(BidSummary{AgentDID: "did:agent:greedy", Price: 90}, opened.Add(500*time.Millisecond))
+	if !errors.Is(err, ErrAskAboveClock) {
+		t.Errorf("expected ErrAskAboveClock, got %v", err)
+	}
+}
+
+func TestDutchAuctionFirstAcceptanceWins(t *testing.T) {
+	clock, opened := newTestDutchAuction(t, DutchSchedule{Duration: 10 * time.Second})
+	at := opened.Add(2 * time.Second)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners, taken := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := clock.Accept(BidSummary{AgentDID: "did:agent:racer"}, at)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				winners++
+			case errors.Is(err, ErrAuctionAlreadyTaken):
+				taken++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners != 1 || taken != 19 {
+		t.Fatalf("expected exactly one winner, got %d winners and %d rejections", winners, taken)
+	}
+	if winner := clock.Winner(); winner == nil || !approxEqual(winner.Price, 90) {
+		t.Errorf("expected the winner to pay the clock price 90, got %+v", winner)
+	}
+}
+
+func TestDutchAuctionExpiry(t *testing.T) {
+	clock, opened := newTestDutchAuction(t, DutchSchedule{Duration: time.Second})
+
+	_, err := clock.Accept(BidSummary{AgentDID: "did:agent:late"}, opened.Add(time.Second+time.Millisecond))
+	if !errors.Is(err, ErrDutchAuctionExpired) {
+		t.Errorf("expected ErrDutchAuctionExpired, got %v", err)
+	}
+	if clock.Winner() != nil {
+		t.Error("expected no winner after expiry")
+	}
+
+	// A claim made before the clock closes wins; later ones are refused
+	clock, opened = newTestDutchAuction(t, DutchSchedule{Duration: time.Second})
+	if _, err := clock.Accept(BidSummary{AgentDID: "did:agent:1", Price: 10}, opened.Add(time.Second)); err != nil {
+		t.Fatalf("claim at expiry rejected: %v", err)
+	}
+	if winner := clock.Close(); winner == nil || winner.AgentDID != "did:agent:1" {
+		t.Errorf("expected Close to return the claim, got %+v", winner)
+	}
+	clock, opened = newTestDutchAuction(t, DutchSchedule{Duration: time.Second})
+	clock.Close()
+	if _, err := clock.Accept(BidSummary{AgentDID: "did:agent:2"}, opened); !errors.Is(err, ErrDutchAuctionExpired) {
+		t.Errorf("expected a closed clock to refuse claims, got %v", err)
+	}
+
+	if _, err := NewDutchAuction("task-2", 100, DutchSchedule{}, opened); !errors.Is(err, ErrInvalidDutchConfig) {
+		t.Errorf("expected ErrInvalidDutchConfig without a duration, got %v", err)
+	}
+}
+
+func TestDutchScheduleOnlyForUrgentTasks(t *testing.T) {
+	a := NewAuctioneer(nil, nil)
+	task := NewTask("did:user:1", "test", []string{"test"}, nil)
+	task.Budget = 10
+	task.Priority = PriorityCritical
+	if a.dutchSchedule(task) != nil {
+		t.Fatal("expected no schedule before Dutch auctions are enabled")
+	}
+
+	if err := a.EnableDutchAuctions(DutchSchedule{Duration: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if a.dutchSchedule(task) == nil {
+		t.Error("expected a schedule for a critical task")
+	}
+	task.Priority = PriorityNormal
+	if a.dutchSchedule(task) != nil {
+		t.Error("expected normal priority tasks to use the regular auction")
+	}
+}
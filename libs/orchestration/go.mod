@@ -6,6 +6,7 @@ toolchain go1.24.10
 
 require (
 	github.com/aidenlippert/zerostate/libs/identity v0.0.0
+	github.com/aidenlippert/zerostate/libs/scoring v0.0.0
 	github.com/aidenlippert/zerostate/libs/search v0.0.0
 	github.com/google/uuid v1.6.0
 	github.com/libp2p/go-libp2p v0.39.1
@@ -133,6 +134,7 @@ require (
 
 replace (
 	github.com/aidenlippert/zerostate/libs/identity => ../identity
+	github.com/aidenlippert/zerostate/libs/scoring => ../scoring
 	github.com/aidenlippert/zerostate/libs/search => ../search
 	github.com/ainur-project/zerostate/libs/aacl-go => ../aacl-go
 	github.com/ainur-project/zerostate/libs/agentcard-go => ../agentcard-go
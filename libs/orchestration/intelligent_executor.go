@@ -2,6 +2,7 @@ package orchestration
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -121,7 +122,7 @@ func (e *IntelligentTaskExecutor) ExecuteTask(
 		}
 
 		// Handle WASM agent steps
-		result, err := e.executeWASMStep(ctx, step, execContext, agentDID)
+		result, err := e.executeWASMStep(ctx, step, execContext, agent)
 		if err != nil {
 			return e.failedResult(task, agentDID, err, startTime), err
 		}
@@ -165,7 +166,7 @@ func (e *IntelligentTaskExecutor) executeWASMStep(
 	ctx context.Context,
 	step llm.TaskStep,
 	execContext *llm.ExecutionContext,
-	agentDID string,
+	agent *identity.AgentCard,
 ) (interface{}, error) {
 	// Convert args, replacing placeholders with previous step results
 	convertedArgs, err := e.convertArgs(step.Args, execContext)
@@ -179,13 +180,30 @@ func (e *IntelligentTaskExecutor) executeWASMStep(
 	// If agent has endpoints with HTTP URL, use that as the binary URL
 	// (For now, we assume the agent binary is at agents/{agent-name}.wasm in R2)
 
-	// Execute WASM function
-	result, err := e.wasmRunner.Execute(ctx, &execution.WASMExecutionRequest{
+	req := &execution.WASMExecutionRequest{
 		R2Key:    r2Key,
 		Function: step.Function,
 		Args:     convertedArgs,
 		Timeout:  30 * time.Second,
-	})
+	}
+
+	// Functions the agent declares as typed take one payload: the sole
+	// argument, or all of them as an array
+	if spec := functionSpec(agent, step.Function); spec != nil {
+		var payload interface{} = convertedArgs
+		if len(convertedArgs) == 1 {
+			payload = convertedArgs[0]
+		}
+		input, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode input: %w", err)
+		}
+		req.Spec = spec
+		req.Input = input
+	}
+
+	// Execute WASM function
+	result, err := e.wasmRunner.Execute(ctx, req)
 
 	if err != nil {
 		return nil, fmt.Errorf("WASM execution failed: %w", err)
@@ -198,6 +216,27 @@ func (e *IntelligentTaskExecutor) executeWASMStep(
 	return result.Result, nil
 }
 
+// functionSpec returns the typed signature the agent's card declares for
+// function, or nil for plain numeric functions
+func functionSpec(agent *identity.AgentCard, function string) *execution.FunctionSpec {
+	if agent == nil {
+		return nil
+	}
+	for _, capability := range agent.Capabilities {
+		for _, fn := range capability.Functions {
+			if fn.Name == function {
+				return &execution.FunctionSpec{
+					Name:         fn.Name,
+					Encoding:     fn.Encoding,
+					InputSchema:  fn.InputSchema,
+					OutputSchema: fn.OutputSchema,
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // executeLLMStep executes a text generation step
 func (e *IntelligentTaskExecutor) executeLLMStep(
 	ctx context.Context,
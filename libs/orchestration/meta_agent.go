@@ -145,6 +145,7 @@ func (m *MetaAgent) SelectAgent(ctx context.Context, task *Task) (*database.Agen
 	if err != nil {
 		return nil, fmt.Errorf("failed to find eligible agents: %w", err)
 	}
+	agents = withoutExcludedAgents(ctx, agents)
 
 	if len(agents) == 0 {
 		return nil, ErrNoAgentsAvailable
@@ -189,6 +190,25 @@ func (m *MetaAgent) SelectAgent(ctx context.Context, task *Task) (*database.Agen
 	return bestBid.Agent, nil
 }
 
+// withoutExcludedAgents drops the agents ctx excludes from selection. Agents
+// without a DID are known by their ID.
+func withoutExcludedAgents(ctx context.Context, agents []*database.Agent) []*database.Agent {
+	if excludedAgentCount(ctx) == 0 {
+		return agents
+	}
+	kept := make([]*database.Agent, 0, len(agents))
+	for _, agent := range agents {
+		did := agent.DID
+		if did == "" {
+			did = agent.ID.String()
+		}
+		if !IsAgentExcluded(ctx, did) {
+			kept = append(kept, agent)
+		}
+	}
+	return kept
+}
+
 // findEligibleAgents finds agents that meet task requirements using HNSW semantic search
 func (m *MetaAgent) findEligibleAgents(ctx context.Context, task *Task) ([]*database.Agent, error) {
 	var agentCards []*search.AgentCard
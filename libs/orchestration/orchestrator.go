@@ -9,7 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
 	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/reputation"
 	"github.com/aidenlippert/zerostate/libs/search"
 	"github.com/aidenlippert/zerostate/libs/substrate"
 	"github.com/google/uuid"
@@ -33,15 +35,32 @@ type TaskExecutor interface {
 	ExecuteTask(ctx context.Context, task *Task, agent *identity.AgentCard) (*TaskResult, error)
 }
 
+// TaskResultObserver is notified after a task execution produces a result.
+// Observers run synchronously on the worker goroutine and should not block.
+type TaskResultObserver interface {
+	OnTaskResult(ctx context.Context, task *Task, result *TaskResult)
+}
+
+// SpendGuard authorizes task budgets against a user's spending policies
+// before any funds are escrowed. AuthorizeSpend must accept or reject the
+// tasks as a whole; ReleaseSpend returns the budget of tasks that were
+// authorized but never queued.
+type SpendGuard interface {
+	AuthorizeSpend(ctx context.Context, userID string, tasks []*Task) error
+	ReleaseSpend(ctx context.Context, taskIDs []string)
+}
+
 // Orchestrator manages task routing and execution
 type Orchestrator struct {
 	// Core components
-	queue      *TaskQueue
-	selector   AgentSelector
-	executor   TaskExecutor
-	auctioneer *Auctioneer
-	cqRouter   *CQRouter // Confidence-based Q-Routing for intelligent agent discovery
-	logger     *zap.Logger
+	queue        *TaskQueue
+	selector     AgentSelector
+	executor     TaskExecutor
+	auctioneer   *Auctioneer
+	orderBook    *OrderBook       // Standing asks matched before any CFP auction
+	reservations *ReservationBook // Booked capacity honored before the spot market
+	cqRouter     *CQRouter        // Confidence-based Q-Routing for intelligent agent discovery
+	logger       *zap.Logger
 
 	// Worker pool
 	numWorkers int
@@ -65,6 +84,23 @@ type Orchestrator struct {
 
 	// Extended escrow functionality
 	escrowClient *substrate.EscrowClient
+
+	// Result observers (e.g. escrow condition evaluation)
+	resultObservers []TaskResultObserver
+
+	// Spending policy enforcement for batch task creation
+	spendGuard SpendGuard
+
+	// Live progress of running tasks, for streaming to clients
+	progress *progressHub
+
+	// Cancellation of the tasks workers are processing, by task ID
+	running   map[string]context.CancelCauseFunc
+	runningMu sync.Mutex
+
+	// Result comparison and local reputation for replicated tasks
+	similarity ResultSimilarity
+	reputation *reputation.ReputationManager
 }
 
 // SetAuctioneer attaches an Auctioneer to the orchestrator after construction.
@@ -76,6 +112,68 @@ func (o *Orchestrator) SetAuctioneer(a *Auctioneer) {
 	o.auctioneer = a
 }
 
+// Auctioneer returns the attached auctioneer, or nil
+func (o *Orchestrator) Auctioneer() *Auctioneer {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.auctioneer
+}
+
+// SetOrderBook attaches a spot order book. Tasks are matched against its
+// standing asks first and only go to a CFP auction when nothing matches.
+func (o *Orchestrator) SetOrderBook(ob *OrderBook) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.orderBook = ob
+}
+
+// OrderBook returns the attached spot order book, or nil
+func (o *Orchestrator) OrderBook() *OrderBook {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.orderBook
+}
+
+// SetReservations attaches a reservation book. Tasks covered by one of their
+// owner's capacity reservations go to the reserved agent before the spot market.
+func (o *Orchestrator) SetReservations(rb *ReservationBook) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reservations = rb
+}
+
+// Reservations returns the attached reservation book, or nil
+func (o *Orchestrator) Reservations() *ReservationBook {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.reservations
+}
+
+// AddResultObserver registers an observer that is notified of every task result.
+func (o *Orchestrator) AddResultObserver(obs TaskResultObserver) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.resultObservers = append(o.resultObservers, obs)
+}
+
+// SetSpendGuard attaches the guard that authorizes batch task budgets.
+func (o *Orchestrator) SetSpendGuard(g SpendGuard) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.spendGuard = g
+}
+
+// notifyResultObservers delivers a task result to all registered observers
+func (o *Orchestrator) notifyResultObservers(task *Task, result *TaskResult) {
+	o.mu.RLock()
+	observers := append([]TaskResultObserver(nil), o.resultObservers...)
+	o.mu.RUnlock()
+
+	for _, obs := range observers {
+		obs.OnTaskResult(o.ctx, task, result)
+	}
+}
+
 // OrchestratorMetrics tracks orchestration statistics
 type OrchestratorMetrics struct {
 	TasksProcessed   int64
@@ -86,6 +184,8 @@ type OrchestratorMetrics struct {
 	AuctionSuccesses int64 // Auctions that received bids and selected winner
 	AuctionFailures  int64 // Auctions that failed to run
 	AuctionNoBids    int64 // Auctions that completed but received no bids
+	SpotFills        int64 // Tasks matched instantly against standing asks
+	ReservedFills    int64 // Tasks allocated from capacity reservations
 	DBFallbacks      int64 // Times DB selector was used as fallback
 	AvgExecutionTime time.Duration
 	ActiveWorkers    int
@@ -191,6 +291,8 @@ func NewOrchestratorWithBlockchain(
 		blockchain:     blockchain,
 		paymentManager: paymentManager,
 		escrowClient:   escrowClient,
+		progress:       newProgressHub(logger),
+		running:        make(map[string]context.CancelCauseFunc),
 	}
 }
 
@@ -353,10 +455,16 @@ func (w *worker) processTask(task *Task) {
 		zap.Int("priority", int(task.Priority)),
 	)
 
-	// Update task status to assigned
-	task.UpdateStatus(TaskStatusAssigned)
-	if err := w.orchestrator.queue.Update(task); err != nil {
-		w.logger.Error("failed to update task status", zap.Error(err))
+	// Let CancelTask interrupt the task from here on
+	taskCtx, cancelTask := context.WithCancelCause(w.orchestrator.ctx)
+	defer cancelTask(nil)
+	defer w.orchestrator.trackRunning(task.ID, cancelTask)()
+	if !w.orchestrator.queue.Assign(task) {
+		if errors.Is(context.Cause(taskCtx), ErrTaskCanceled) {
+			// Canceled as it was dequeued; nothing has run yet
+			w.orchestrator.closeUnstarted(task)
+		}
+		return
 	}
 
 	// Initialize payment if payment manager is available
@@ -392,10 +500,32 @@ func (w *worker) processTask(task *Task) {
 		}
 		agent = nil // No agent needed
 	} else {
+		// Reserved capacity first, then the spot market: either allocates the
+		// task without waiting out an auction window
+		if reservations := w.orchestrator.Reservations(); reservations != nil {
+			if auctionResult = w.claimReservation(reservations, task); auctionResult != nil {
+				defer func() {
+					reservations.RecordOutcome(w.orchestrator.ctx, task.ID, task.Status == TaskStatusCompleted)
+				}()
+				agent = marketAgentCard(task, auctionResult.Winner.AgentDID)
+				task.AssignedTo = agent.DID
+			}
+		}
+		if orderBook := w.orchestrator.OrderBook(); agent == nil && orderBook != nil && len(task.Capabilities) > 0 {
+			if auctionResult = w.matchSpotOrder(orderBook, task); auctionResult != nil {
+				defer orderBook.Release(task.ID)
+				agent = marketAgentCard(task, auctionResult.Winner.AgentDID)
+				task.AssignedTo = agent.DID
+			}
+		}
+
 		// Market-based selection: run auction first, only fall back to DB if no bids
 		// Always try auction if auctioneer is available and task has capabilities
-		if w.orchestrator.auctioneer != nil && len(task.Capabilities) > 0 {
+		if agent == nil && w.orchestrator.auctioneer != nil && len(task.Capabilities) > 0 {
 			logic := SelectionLogic{Mode: SelectionModeCheapest}
+			if task.ScoringRule != nil {
+				logic = SelectionLogic{Mode: SelectionModeScored, Rule: task.ScoringRule}
+			}
 			window := 500 * time.Millisecond
 
 			w.logger.Info("starting market auction",
@@ -409,7 +539,19 @@ func (w *worker) processTask(task *Task) {
 			w.orchestrator.metrics.AuctionsStarted++
 			w.orchestrator.mu.Unlock()
 
-			auctionResult, err = w.orchestrator.auctioneer.StartAuction(w.orchestrator.ctx, task, logic, window)
+			// Urgent tasks go to the first agent that accepts a falling price
+			if schedule := w.orchestrator.auctioneer.dutchSchedule(task); schedule != nil {
+				auctionResult, err = w.orchestrator.auctioneer.StartDutchAuction(taskCtx, task, *schedule)
+				if err != nil {
+					w.logger.Warn("dutch auction failed; running the regular auction",
+						zap.String("task_id", task.ID),
+						zap.Error(err),
+					)
+				}
+			}
+			if auctionResult == nil || auctionResult.Winner == nil {
+				auctionResult, err = w.orchestrator.auctioneer.StartAuction(taskCtx, task, logic, window)
+			}
 			if err != nil {
 				w.logger.Warn("auction failed; falling back to DB selection",
 					zap.String("task_id", task.ID),
@@ -434,18 +576,7 @@ func (w *worker) processTask(task *Task) {
 				w.orchestrator.metrics.AuctionSuccesses++
 				w.orchestrator.mu.Unlock()
 
-				// Create a minimal AgentCard from the winner for execution
-				// TODO: fetch full AgentCard from registry or on-chain DID document
-				caps := make([]identity.Capability, len(task.Capabilities))
-				for i, cap := range task.Capabilities {
-					caps[i] = identity.Capability{Name: cap, Version: "v1"}
-				}
-				agent = &identity.AgentCard{
-					DID:          string(auctionResult.Winner.AgentDID),
-					Endpoints:    &identity.Endpoints{HTTP: []string{}},
-					Capabilities: caps,
-					Proof:        &identity.Proof{},
-				}
+				agent = marketAgentCard(task, auctionResult.Winner.AgentDID)
 				task.AssignedTo = agent.DID
 			} else {
 				// No bids received - this is the only case we fall back to DB
@@ -458,7 +589,7 @@ func (w *worker) processTask(task *Task) {
 				w.orchestrator.metrics.AuctionNoBids++
 				w.orchestrator.mu.Unlock()
 			}
-		} else {
+		} else if agent == nil {
 			// No auctioneer configured - this is expected for non-market mode
 			if len(task.Capabilities) > 0 {
 				w.logger.Debug("auctioneer not configured, using DB selection",
@@ -467,8 +598,9 @@ func (w *worker) processTask(task *Task) {
 			}
 		}
 
-		// Fall back to DB selector only if auction didn't produce a winner
-		if agent == nil {
+		// Fall back to DB selector only if auction didn't produce a winner,
+		// unless the task was canceled during the auction
+		if agent == nil && taskCtx.Err() == nil {
 			w.logger.Info("using database agent selector (fallback)",
 				zap.String("task_id", task.ID),
 			)
@@ -509,18 +641,41 @@ func (w *worker) processTask(task *Task) {
 		}
 	}
 
+	// Canceled while an agent was being found
+	if errors.Is(context.Cause(taskCtx), ErrTaskCanceled) {
+		w.finishCanceled(task, agent, nil, time.Since(startTime))
+		return
+	}
+
 	task.UpdateStatus(TaskStatusRunning)
 	if err := w.orchestrator.queue.Update(task); err != nil {
 		w.logger.Error("failed to update task with agent assignment", zap.Error(err))
 	}
+	w.orchestrator.publishProgress(task, TaskProgress{Status: TaskStatusRunning, Message: "task started"})
 
-	// Execute task with timeout
-	execCtx, cancel := context.WithTimeout(w.orchestrator.ctx, task.Timeout)
+	// Execute task with timeout, streaming the executor's progress
+	execCtx, cancel := context.WithTimeout(taskCtx, task.Timeout)
 	defer cancel()
+	execCtx = WithProgress(execCtx, w.orchestrator.progressReporter(task))
 
-	result, err := w.orchestrator.executor.ExecuteTask(execCtx, task, agent)
+	var result *TaskResult
+	if task.Replication.replicas() > 1 && agent != nil {
+		result, agent, err = w.executeReplicated(execCtx, task, agent)
+	} else {
+		result, err = w.orchestrator.executor.ExecuteTask(execCtx, task, agent)
+	}
 	executionTime := time.Since(startTime)
 
+	// A result that completed anyway stands; anything else was cut short
+	if errors.Is(context.Cause(taskCtx), ErrTaskCanceled) &&
+		(err != nil || result.Status != TaskStatusCompleted) {
+		if err != nil {
+			result = nil
+		}
+		w.finishCanceled(task, agent, result, executionTime)
+		return
+	}
+
 	if err != nil {
 		w.logger.Error("task execution failed",
 			zap.String("task_id", task.ID),
@@ -535,6 +690,10 @@ func (w *worker) processTask(task *Task) {
 	// Update task with result
 	task.Result = result.Result
 	task.ActualCost = result.Cost
+	task.FuelUsed = result.FuelUsed
+	task.CPUTimeMS = result.CPUTimeMS
+	task.MemoryBytes = result.MemoryBytes
+	task.Artifacts = result.Artifacts
 	if task.ActualCost == 0 {
 		if auctionResult != nil && auctionResult.Winner != nil {
 			task.ActualCost = auctionResult.Winner.Price
@@ -551,17 +710,27 @@ func (w *worker) processTask(task *Task) {
 	if err := w.orchestrator.queue.Update(task); err != nil {
 		w.logger.Error("failed to update task with result", zap.Error(err))
 	}
+	if task.IsTerminal() {
+		w.orchestrator.publishProgress(task, FinalProgress(task))
+	}
 
 	// Update metrics
 	w.orchestrator.updateMetrics(result, executionTime)
 
+	// Notify result observers (escrow conditions, etc.)
+	w.orchestrator.notifyResultObservers(task, result)
+
 	// Handle payment lifecycle based on task outcome
 	if w.orchestrator.paymentManager != nil {
 		w.handlePaymentLifecycle(task, agent, result.Status)
 	}
 
 	// Report reputation to blockchain (async, don't fail task if reputation fails)
-	go w.reportReputationAsync(task, agent, result.Status == TaskStatusCompleted)
+	if len(task.Replicas) > 0 {
+		go w.reportReplicaReputation(task)
+	} else {
+		go w.reportReputationAsync(task, agent, result.Status == TaskStatusCompleted)
+	}
 
 	w.logger.Info("task completed",
 		zap.String("task_id", task.ID),
@@ -570,6 +739,98 @@ func (w *worker) processTask(task *Task) {
 	)
 }
 
+// matchSpotOrder matches a task against the order book as a limit order at
+// its budget, or a market order when it has none. It returns the fill as a
+// single-bid auction result, or nil when no standing ask matches.
+func (w *worker) matchSpotOrder(orderBook *OrderBook, task *Task) *AuctionResult {
+	order := SpotOrder{TaskID: task.ID, Capability: task.Capabilities[0], Type: OrderTypeMarket}
+	if task.Budget > 0 {
+		order.Type = OrderTypeLimit
+		order.LimitPrice = task.Budget
+	}
+
+	fill, err := orderBook.Match(order)
+	if err != nil {
+		if !errors.Is(err, ErrNoMatch) {
+			w.logger.Warn("spot order rejected", zap.String("task_id", task.ID), zap.Error(err))
+		}
+		return nil
+	}
+
+	winner := fill.BidSummary()
+	w.logger.Info("task matched against standing ask",
+		zap.String("task_id", task.ID),
+		zap.String("ask_id", fill.AskID),
+		zap.String("agent_did", string(fill.AgentDID)),
+		zap.Float64("price", fill.Price),
+	)
+
+	w.orchestrator.mu.Lock()
+	w.orchestrator.metrics.SpotFills++
+	w.orchestrator.mu.Unlock()
+
+	// Tell the runtime its ask was taken, as with an auction award
+	if auctioneer := w.orchestrator.auctioneer; auctioneer != nil && auctioneer.gossip != nil {
+		if err := auctioneer.sendAcceptProposal(w.orchestrator.ctx, task.ID, &winner); err != nil {
+			w.logger.Warn("failed to notify standing ask owner",
+				zap.String("task_id", task.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return &AuctionResult{CFPID: task.ID, Winner: &winner, AllBids: []BidSummary{winner}}
+}
+
+// claimReservation allocates a task to one of its owner's reservations at the
+// reserved price. It returns the reservation as a single-bid auction result,
+// or nil when none applies.
+func (w *worker) claimReservation(reservations *ReservationBook, task *Task) *AuctionResult {
+	r := reservations.Claim(task)
+	if r == nil {
+		return nil
+	}
+
+	winner := BidSummary{BidID: r.ID, AgentDID: r.AgentDID, Price: r.PricePerSlot}
+	w.logger.Info("task allocated from capacity reservation",
+		zap.String("task_id", task.ID),
+		zap.String("reservation_id", r.ID),
+		zap.String("agent_did", string(r.AgentDID)),
+		zap.Int("claimed", r.Claimed),
+		zap.Int("slots", r.Slots),
+	)
+
+	w.orchestrator.mu.Lock()
+	w.orchestrator.metrics.ReservedFills++
+	w.orchestrator.mu.Unlock()
+
+	if auctioneer := w.orchestrator.auctioneer; auctioneer != nil && auctioneer.gossip != nil {
+		if err := auctioneer.sendAcceptProposal(w.orchestrator.ctx, task.ID, &winner); err != nil {
+			w.logger.Warn("failed to notify reserved agent",
+				zap.String("task_id", task.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return &AuctionResult{CFPID: task.ID, Winner: &winner, AllBids: []BidSummary{winner}}
+}
+
+// marketAgentCard builds a minimal AgentCard for a market winner
+// TODO: fetch full AgentCard from registry or on-chain DID document
+func marketAgentCard(task *Task, did agentcard.DID) *identity.AgentCard {
+	caps := make([]identity.Capability, len(task.Capabilities))
+	for i, cap := range task.Capabilities {
+		caps[i] = identity.Capability{Name: cap, Version: "v1"}
+	}
+	return &identity.AgentCard{
+		DID:          string(did),
+		Endpoints:    &identity.Endpoints{HTTP: []string{}},
+		Capabilities: caps,
+		Proof:        &identity.Proof{},
+	}
+}
+
 // handlePaymentLifecycle handles payment release/refund based on task outcome
 func (w *worker) handlePaymentLifecycle(task *Task, agent *identity.AgentCard, taskStatus TaskStatus) {
 	agentID := ""
@@ -592,8 +853,19 @@ func (w *worker) handlePaymentLifecycle(task *Task, agent *identity.AgentCard, t
 	switch taskStatus {
 	case TaskStatusCompleted:
 		// Release payment to agent on successful completion
-		if w.orchestrator.escrowClient != nil {
+		shares := ReplicaShares(task)
+		if w.orchestrator.escrowClient != nil && len(shares) > 1 {
+			// The escrow pallet pays a single agent; paying it all to one of
+			// the agreeing agents would stiff the others
+			w.logger.Error("escrow pallet cannot split a replicated task's payment, leaving the escrow for manual settlement",
+				zap.String("task_id", task.ID),
+				zap.Any("shares", shares),
+			)
+		} else if w.orchestrator.escrowClient != nil {
 			w.releasePaymentWithEscrow(task, agentID)
+		} else if len(shares) > 1 {
+			// Split between the agents that agreed on a replicated result
+			w.orchestrator.paymentManager.ReleaseSharedPaymentAsync(w.orchestrator.ctx, task.ID, shares)
 		} else {
 			w.orchestrator.paymentManager.ReleasePaymentAsync(w.orchestrator.ctx, task.ID, agentID)
 		}
@@ -603,8 +875,17 @@ func (w *worker) handlePaymentLifecycle(task *Task, agent *identity.AgentCard, t
 		w.orchestrator.metrics.PaymentsReleased++
 		w.orchestrator.mu.Unlock()
 
-	case TaskStatusFailed, TaskStatusCanceled:
-		// Refund payment on failure or cancellation
+	case TaskStatusCanceled:
+		// Pay for the work done before the cancel, refund the rest
+		w.settleCanceledPayment(task, agentID)
+
+		// Update payment metrics
+		w.orchestrator.mu.Lock()
+		w.orchestrator.metrics.PaymentsRefunded++
+		w.orchestrator.mu.Unlock()
+
+	case TaskStatusFailed:
+		// Refund payment on failure
 		reason := fmt.Sprintf("task %s", taskStatus)
 		if task.Error != "" {
 			reason = fmt.Sprintf("task failed: %s", task.Error)
@@ -796,6 +1077,10 @@ func (w *worker) handleTaskFailure(task *Task, err error) {
 				zap.String("task_id", task.ID),
 				zap.Int("retry_count", task.RetryCount),
 			)
+			w.orchestrator.publishProgress(task, TaskProgress{
+				Status:  TaskStatusPending,
+				Message: fmt.Sprintf("retrying after error: %s", task.Error),
+			})
 			return
 		}
 	} else {
@@ -805,6 +1090,16 @@ func (w *worker) handleTaskFailure(task *Task, err error) {
 	if err := w.orchestrator.queue.Update(task); err != nil {
 		w.logger.Error("failed to update failed task", zap.Error(err))
 	}
+	w.orchestrator.publishProgress(task, FinalProgress(task))
+
+	// Observers settle what they reserved for the task
+	w.orchestrator.notifyResultObservers(task, &TaskResult{
+		TaskID:    task.ID,
+		Status:    TaskStatusFailed,
+		Error:     task.Error,
+		AgentDID:  task.AssignedTo,
+		Timestamp: time.Now(),
+	})
 }
 
 // HNSWAgentSelector uses HNSW semantic search to find best agent
@@ -835,8 +1130,14 @@ func (s *HNSWAgentSelector) SelectAgent(ctx context.Context, task *Task) (*ident
 	embeddingGen := search.NewEmbedding(128)
 	taskVector := embeddingGen.EncodeCapabilities(task.Capabilities, nil)
 
-	// Search for similar agents (k=5)
-	results := s.hnsw.Search(taskVector, 5)
+	// Search for similar agents (k=5, plus any excluded ones to skip)
+	results := s.hnsw.Search(taskVector, 5+excludedAgentCount(ctx))
+	for len(results) > 0 {
+		if card, ok := results[0].Payload.(*identity.AgentCard); !ok || !IsAgentExcluded(ctx, card.DID) {
+			break
+		}
+		results = results[1:]
+	}
 
 	if len(results) == 0 {
 		s.logger.Warn("no agents found for task",
@@ -1254,6 +1555,20 @@ func (o *Orchestrator) CreateBatchTasks(
 		}
 	}
 
+	// Authorize the whole batch against the user's budget before escrowing anything
+	o.mu.RLock()
+	guard := o.spendGuard
+	o.mu.RUnlock()
+	if guard != nil {
+		if err := guard.AuthorizeSpend(ctx, userID, createdTasks); err != nil {
+			o.logger.Warn("batch rejected by spend guard",
+				zap.String("batch_id", batchID),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+	}
+
 	// Create batch escrow on blockchain if available
 	if o.escrowClient != nil && len(escrowRequests) > 0 {
 		batchResult, err := o.escrowClient.BatchCreateEscrow(ctx, escrowRequests)
@@ -1287,6 +1602,23 @@ func (o *Orchestrator) CreateBatchTasks(
 		}
 	}
 
+	// Return the budget of tasks that never made it into the queue
+	if guard != nil && len(result.FailedTasks) > 0 {
+		queued := make(map[string]bool, len(result.SuccessfulTasks))
+		for _, task := range result.SuccessfulTasks {
+			queued[task.ID] = true
+		}
+		var unqueued []string
+		for _, failed := range result.FailedTasks {
+			if !queued[failed.TaskID] {
+				unqueued = append(unqueued, failed.TaskID)
+			}
+		}
+		if len(unqueued) > 0 {
+			guard.ReleaseSpend(ctx, unqueued)
+		}
+	}
+
 	result.TotalSucceeded = len(result.SuccessfulTasks)
 	result.TotalFailed = len(result.FailedTasks)
 
@@ -0,0 +1,375 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Standing ask message types and topic
+const (
+	MessageTypeStandingAsk = "AACL-Standing-Ask-v1"
+	MessageTypeCancelAsk   = "AACL-Cancel-Ask-v1"
+
+	// StandingAskTopic carries standing asks and cancellations from runtimes
+	StandingAskTopic = "ainur/v1/market/asks"
+)
+
+// Order book errors
+var (
+	ErrInvalidAsk   = errors.New("invalid standing ask")
+	ErrInvalidOrder = errors.New("invalid spot order")
+	ErrAskNotFound  = errors.New("standing ask not found")
+	ErrNoMatch      = errors.New("no standing ask matches the order")
+)
+
+// StandingAsk is an agent's resting offer to execute tasks of one capability
+// at a fixed price. Capacity bounds how many matched tasks the ask may hold
+// at once; slots free up as those tasks finish.
+type StandingAsk struct {
+	ID         string        `json:"id"`
+	AgentDID   agentcard.DID `json:"agent_did"`
+	Capability string        `json:"capability"`
+	Price      float64       `json:"price"`
+	Capacity   int           `json:"capacity"`
+	ETAms      int64         `json:"eta_ms,omitempty"`
+	ValidUntil time.Time     `json:"valid_until"`
+	PostedAt   time.Time     `json:"posted_at"`
+	InFlight   int           `json:"in_flight"`
+
+	seq uint64 // Time priority; amended asks go to the back of their price level
+}
+
+// OrderType selects how a spot order is matched
+type OrderType string
+
+const (
+	OrderTypeMarket OrderType = "market" // Best available ask at any price
+	OrderTypeLimit  OrderType = "limit"  // Best ask at or below LimitPrice
+)
+
+// SpotOrder is a requester's immediate-or-cancel order for one task. Orders
+// that do not match are not rested in the book; the caller falls back to a
+// CFP auction instead.
+type SpotOrder struct {
+	TaskID     string    `json:"task_id"`
+	Capability string    `json:"capability"`
+	Type       OrderType `json:"type"`
+	LimitPrice float64   `json:"limit_price,omitempty"`
+}
+
+// Fill records a spot order matched against a standing ask. The task pays
+// the ask's price.
+type Fill struct {
+	TaskID     string        `json:"task_id"`
+	AskID      string        `json:"ask_id"`
+	AgentDID   agentcard.DID `json:"agent_did"`
+	Capability string        `json:"capability"`
+	Price      float64       `json:"price"`
+	ETAms      int64         `json:"eta_ms,omitempty"`
+	FilledAt   time.Time     `json:"filled_at"`
+}
+
+// BidSummary returns the fill as the winning bid of a degenerate auction, so
+// spot and auctioned tasks settle the same way
+func (f *Fill) BidSummary() BidSummary {
+	return BidSummary{
+		BidID:    f.AskID,
+		AgentDID: f.AgentDID,
+		Price:    f.Price,
+		ETAms:    f.ETAms,
+	}
+}
+
+// OrderBook holds standing asks per capability and matches spot orders
+// against them with price-time priority: the cheapest ask wins and, among
+// equally priced asks, the one posted first. It is safe for concurrent use.
+type OrderBook struct {
+	mu     sync.Mutex
+	asks   map[string]*StandingAsk   // Ask ID → ask
+	levels map[string][]*StandingAsk // Capability → asks in priority order
+	fills  map[string]*Fill          // Task ID → fill holding a capacity slot
+	seq    uint64
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+// NewOrderBook creates an empty order book
+func NewOrderBook(logger *zap.Logger) *OrderBook {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &OrderBook{
+		asks:   make(map[string]*StandingAsk),
+		levels: make(map[string][]*StandingAsk),
+		fills:  make(map[string]*Fill),
+		now:    time.Now,
+		logger: logger,
+	}
+}
+
+// PostAsk adds a standing ask, or amends the agent's ask with the same ID.
+// Amending keeps the slots already in flight but loses time priority.
+func (ob *OrderBook) PostAsk(ask StandingAsk) (*StandingAsk, error) {
+	now := ob.now()
+
+	switch {
+	case ask.AgentDID == "":
+		return nil, fmt.Errorf("%w: agent DID is required", ErrInvalidAsk)
+	case ask.Capability == "":
+		return nil, fmt.Errorf("%w: capability is required", ErrInvalidAsk)
+	case ask.Price <= 0 || math.IsNaN(ask.Price) || math.IsInf(ask.Price, 0):
+		return nil, fmt.Errorf("%w: price must be positive", ErrInvalidAsk)
+	case ask.Capacity <= 0:
+		return nil, fmt.Errorf("%w: capacity must be positive", ErrInvalidAsk)
+	case !ask.ValidUntil.After(now):
+		return nil, fmt.Errorf("%w: ask has already expired", ErrInvalidAsk)
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if ask.ID == "" {
+		ask.ID = uuid.New().String()
+	}
+
+	if existing, ok := ob.asks[ask.ID]; ok {
+		if existing.AgentDID != ask.AgentDID {
+			return nil, fmt.Errorf("%w: ask %s belongs to another agent", ErrInvalidAsk, ask.ID)
+		}
+		ask.InFlight = existing.InFlight
+		ob.removeLocked(existing)
+	} else {
+		ask.InFlight = 0
+	}
+
+	ob.seq++
+	ask.seq = ob.seq
+	ask.PostedAt = now
+
+	stored := ask
+	ob.asks[stored.ID] = &stored
+	ob.insertLocked(&stored)
+
+	ob.logger.Debug("standing ask posted",
+		zap.String("ask_id", stored.ID),
+		zap.String("agent_did", string(stored.AgentDID)),
+		zap.String("capability", stored.Capability),
+		zap.Float64("price", stored.Price),
+		zap.Int("capacity", stored.Capacity),
+	)
+
+	result := stored
+	return &result, nil
+}
+
+// CancelAsk withdraws an agent's standing ask. Tasks already matched to it
+// keep running.
+func (ob *OrderBook) CancelAsk(askID string, agentDID agentcard.DID) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ask, ok := ob.asks[askID]
+	if !ok || ask.AgentDID != agentDID {
+		return ErrAskNotFound
+	}
+
+	ob.removeLocked(ask)
+	return nil
+}
+
+// Depth returns the live asks of a capability in priority order
+func (ob *OrderBook) Depth(capability string) []StandingAsk {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	ob.pruneLocked(capability)
+
+	asks := make([]StandingAsk, 0, len(ob.levels[capability]))
+	for _, ask := range ob.levels[capability] {
+		asks = append(asks, *ask)
+	}
+	return asks
+}
+
+// Match fills a spot order against the best ask with free capacity. The
+// matched ask holds a slot until Release is called for the task.
+func (ob *OrderBook) Match(order SpotOrder) (*Fill, error) {
+	if order.TaskID == "" || order.Capability == "" {
+		return nil, fmt.Errorf("%w: task ID and capability are required", ErrInvalidOrder)
+	}
+	switch order.Type {
+	case "":
+		order.Type = OrderTypeMarket
+	case OrderTypeMarket:
+	case OrderTypeLimit:
+		if order.LimitPrice <= 0 {
+			return nil, fmt.Errorf("%w: limit orders need a positive limit price", ErrInvalidOrder)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown order type %q", ErrInvalidOrder, order.Type)
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if fill, ok := ob.fills[order.TaskID]; ok {
+		copied := *fill
+		return &copied, nil
+	}
+
+	ob.pruneLocked(order.Capability)
+
+	for _, ask := range ob.levels[order.Capability] {
+		if order.Type == OrderTypeLimit && ask.Price > order.LimitPrice {
+			break // Remaining asks are no cheaper
+		}
+		if ask.InFlight >= ask.Capacity {
+			continue
+		}
+
+		ask.InFlight++
+		fill := &Fill{
+			TaskID:     order.TaskID,
+			AskID:      ask.ID,
+			AgentDID:   ask.AgentDID,
+			Capability: ask.Capability,
+			Price:      ask.Price,
+			ETAms:      ask.ETAms,
+			FilledAt:   ob.now(),
+		}
+		ob.fills[order.TaskID] = fill
+
+		copied := *fill
+		return &copied, nil
+	}
+
+	return nil, ErrNoMatch
+}
+
+// Release frees the capacity slot held by a task's fill, if any
+func (ob *OrderBook) Release(taskID string) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	fill, ok := ob.fills[taskID]
+	if !ok {
+		return
+	}
+	delete(ob.fills, taskID)
+
+	if ask, ok := ob.asks[fill.AskID]; ok && ask.InFlight > 0 {
+		ask.InFlight--
+	}
+}
+
+// insertLocked places an ask at its price-time position. Callers must hold ob.mu.
+func (ob *OrderBook) insertLocked(ask *StandingAsk) {
+	level := ob.levels[ask.Capability]
+	i := sort.Search(len(level), func(i int) bool {
+		if level[i].Price != ask.Price {
+			return level[i].Price > ask.Price
+		}
+		return level[i].seq > ask.seq
+	})
+
+	level = append(level, nil)
+	copy(level[i+1:], level[i:])
+	level[i] = ask
+	ob.levels[ask.Capability] = level
+}
+
+// removeLocked drops an ask from the book. Callers must hold ob.mu.
+func (ob *OrderBook) removeLocked(ask *StandingAsk) {
+	delete(ob.asks, ask.ID)
+
+	level := ob.levels[ask.Capability]
+	for i, candidate := range level {
+		if candidate == ask {
+			level = append(level[:i], level[i+1:]...)
+			break
+		}
+	}
+	if len(level) == 0 {
+		delete(ob.levels, ask.Capability)
+	} else {
+		ob.levels[ask.Capability] = level
+	}
+}
+
+// pruneLocked drops expired asks of a capability. Callers must hold ob.mu.
+func (ob *OrderBook) pruneLocked(capability string) {
+	now := ob.now()
+	for _, ask := range append([]*StandingAsk(nil), ob.levels[capability]...) {
+		if !ask.ValidUntil.After(now) {
+			ob.removeLocked(ask)
+		}
+	}
+}
+
+// SubscribeAsks accepts signed standing asks and cancellations published by
+// runtimes on StandingAskTopic
+func (ob *OrderBook) SubscribeAsks(gossip *p2p.GossipService) error {
+	return gossip.Subscribe(StandingAskTopic, func(ctx context.Context, msg *p2p.GossipMessage) error {
+		return ob.handleAskMessage(msg)
+	})
+}
+
+// handleAskMessage applies one signed ask or cancellation
+func (ob *OrderBook) handleAskMessage(msg *p2p.GossipMessage) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(msg.Payload, &raw); err != nil {
+		return fmt.Errorf("failed to parse ask message: %w", err)
+	}
+	if err := verifyMessageSignature(raw); err != nil {
+		ob.logger.Warn("rejected unsigned standing ask", zap.Error(err))
+		return err
+	}
+
+	var ask struct {
+		MessageType string  `json:"message_type"`
+		AskID       string  `json:"ask_id"`
+		From        string  `json:"from"`
+		Capability  string  `json:"capability"`
+		Price       float64 `json:"price"`
+		Capacity    int     `json:"capacity"`
+		ETAms       int64   `json:"eta_ms"`
+		ValidUntil  string  `json:"valid_until"`
+	}
+	if err := json.Unmarshal(msg.Payload, &ask); err != nil {
+		return fmt.Errorf("failed to parse ask message: %w", err)
+	}
+
+	switch ask.MessageType {
+	case MessageTypeCancelAsk:
+		return ob.CancelAsk(ask.AskID, agentcard.DID(ask.From))
+	case MessageTypeStandingAsk:
+		validUntil, err := time.Parse(time.RFC3339, ask.ValidUntil)
+		if err != nil {
+			return fmt.Errorf("%w: invalid valid_until: %v", ErrInvalidAsk, err)
+		}
+		_, err = ob.PostAsk(StandingAsk{
+			ID:         ask.AskID,
+			AgentDID:   agentcard.DID(ask.From),
+			Capability: ask.Capability,
+			Price:      ask.Price,
+			Capacity:   ask.Capacity,
+			ETAms:      ask.ETAms,
+			ValidUntil: validUntil,
+		})
+		return err
+	default:
+		return fmt.Errorf("%w: unexpected message type %q", ErrInvalidAsk, ask.MessageType)
+	}
+}
@@ -0,0 +1,163 @@
+package orchestration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+)
+
+func postAsk(t *testing.T, ob *OrderBook, did string, price float64, capacity int) *StandingAsk {
+	t.Helper()
+	ask, err := ob.PostAsk(StandingAsk{
+		AgentDID:   agentcard.DID(did),
+		Capability: "math.add",
+		Price:      price,
+		Capacity:   capacity,
+		ValidUntil: ob.now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to post ask: %v", err)
+	}
+	return ask
+}
+
+func TestOrderBookPriceTimePriority(t *testing.T) {
+	ob := NewOrderBook(nil)
+	postAsk(t, ob, "did:key:early", 5, 1)
+	postAsk(t, ob, "did:key:expensive", 8, 1)
+	late := postAsk(t, ob, "did:key:late", 5, 1)
+
+	// Equal prices fill in posting order
+	fill, err := ob.Match(SpotOrder{TaskID: "t1", Capability: "math.add"})
+	if err != nil {
+		t.Fatalf("expected a fill: %v", err)
+	}
+	if fill.AgentDID != "did:key:early" || fill.Price != 5 {
+		t.Fatalf("expected the earliest cheapest ask, got %s at %v", fill.AgentDID, fill.Price)
+	}
+
+	fill, err = ob.Match(SpotOrder{TaskID: "t2", Capability: "math.add"})
+	if err != nil || fill.AskID != late.ID {
+		t.Fatalf("expected second fill from the next ask at the same price, got %+v (%v)", fill, err)
+	}
+
+	// A limit order below the remaining ask does not match
+	if _, err := ob.Match(SpotOrder{TaskID: "t3", Capability: "math.add", Type: OrderTypeLimit, LimitPrice: 7}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+
+	fill, err = ob.Match(SpotOrder{TaskID: "t3", Capability: "math.add", Type: OrderTypeLimit, LimitPrice: 8})
+	if err != nil || fill.Price != 8 {
+		t.Fatalf("expected fill at 8, got %+v (%v)", fill, err)
+	}
+}
+
+func TestOrderBookCapacityAndRelease(t *testing.T) {
+	ob := NewOrderBook(nil)
+	ask := postAsk(t, ob, "did:key:a", 3, 1)
+
+	if _, err := ob.Match(SpotOrder{TaskID: "t1", Capability: "math.add"}); err != nil {
+		t.Fatalf("expected a fill: %v", err)
+	}
+	if _, err := ob.Match(SpotOrder{TaskID: "t2", Capability: "math.add"}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected the full ask to be skipped, got %v", err)
+	}
+
+	// Re-matching the same task returns its existing fill
+	fill, err := ob.Match(SpotOrder{TaskID: "t1", Capability: "math.add"})
+	if err != nil || fill.AskID != ask.ID {
+		t.Fatalf("expected the existing fill, got %+v (%v)", fill, err)
+	}
+
+	ob.Release("t1")
+	if _, err := ob.Match(SpotOrder{TaskID: "t2", Capability: "math.add"}); err != nil {
+		t.Fatalf("expected a fill after release: %v", err)
+	}
+}
+
+func TestOrderBookExpiryAndCancel(t *testing.T) {
+	now := time.Now()
+	ob := NewOrderBook(nil)
+	ob.now = func() time.Time { return now }
+
+	ask := postAsk(t, ob, "did:key:a", 3, 2)
+	if err := ob.CancelAsk(ask.ID, "did:key:other"); !errors.Is(err, ErrAskNotFound) {
+		t.Fatalf("expected another agent's cancel to fail, got %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if depth := ob.Depth("math.add"); len(depth) != 0 {
+		t.Fatalf("expected the expired ask to be pruned, got %d", len(depth))
+	}
+
+	ask = postAsk(t, ob, "did:key:a", 3, 2)
+	if err := ob.CancelAsk(ask.ID, "did:key:a"); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+	if _, err := ob.Match(SpotOrder{TaskID: "t1", Capability: "math.add"}); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected an empty book, got %v", err)
+	}
+}
+
+func TestOrderBookRejectsInvalidInput(t *testing.T) {
+	ob := NewOrderBook(nil)
+
+	if _, err := ob.PostAsk(StandingAsk{AgentDID: "did:key:a", Capability: "math.add", Price: 1, Capacity: 1}); !errors.Is(err, ErrInvalidAsk) {
+		t.Fatalf("expected an ask without validity to be rejected, got %v", err)
+	}
+	if _, err := ob.Match(SpotOrder{TaskID: "t1", Capability: "math.add", Type: OrderTypeLimit}); !errors.Is(err, ErrInvalidOrder) {
+		t.Fatalf("expected a limit order without a price to be rejected, got %v", err)
+	}
+}
+
+func TestOrderBookSignedAskMessages(t *testing.T) {
+	ob := NewOrderBook(nil)
+	agent := newTestBidder(t)
+
+	post := agent.sign(t, map[string]interface{}{
+		"message_type": MessageTypeStandingAsk,
+		"ask_id":       "ask-1",
+		"from":         agent.did,
+		"capability":   "math.add",
+		"price":        2.5,
+		"capacity":     4,
+		"valid_until":  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+	if err := ob.handleAskMessage(gossipMessage(t, post)); err != nil {
+		t.Fatalf("failed to apply signed ask: %v", err)
+	}
+	if depth := ob.Depth("math.add"); len(depth) != 1 || depth[0].AgentDID != agentcard.DID(agent.did) {
+		t.Fatalf("expected the signed ask in the book, got %+v", depth)
+	}
+
+	// Tampering with the price breaks the signature
+	post["price"] = 0.5
+	if err := ob.handleAskMessage(gossipMessage(t, post)); err == nil {
+		t.Fatal("expected a tampered ask to be rejected")
+	}
+
+	cancel := agent.sign(t, map[string]interface{}{
+		"message_type": MessageTypeCancelAsk,
+		"ask_id":       "ask-1",
+		"from":         agent.did,
+	})
+	if err := ob.handleAskMessage(gossipMessage(t, cancel)); err != nil {
+		t.Fatalf("failed to apply signed cancel: %v", err)
+	}
+	if depth := ob.Depth("math.add"); len(depth) != 0 {
+		t.Fatalf("expected the ask to be cancelled, got %+v", depth)
+	}
+}
+
+func gossipMessage(t *testing.T, payload map[string]interface{}) *p2p.GossipMessage {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+	return &p2p.GossipMessage{Type: payload["message_type"].(string), Payload: data}
+}
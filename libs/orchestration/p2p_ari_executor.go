@@ -67,7 +67,17 @@ func (e *P2PARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *ide
 	)
 
 	// Wait a moment for runtime discovery (in case we just started)
-	time.Sleep(2 * time.Second)
+	select {
+	case <-time.After(2 * time.Second):
+	case <-ctx.Done():
+		return &TaskResult{
+			TaskID:      task.ID,
+			Status:      TaskStatusCanceled,
+			Error:       fmt.Sprintf("canceled before a runtime was selected: %v", context.Cause(ctx)),
+			ExecutionMS: time.Since(startTime).Milliseconds(),
+			Timestamp:   time.Now(),
+		}, nil
+	}
 
 	// Find runtimes with required capabilities
 	runtimes := e.registry.GetRuntimeByCapabilities(task.Capabilities)
@@ -107,6 +117,11 @@ func (e *P2PARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *ide
 		zap.String("grpc_address", selectedRuntime.GRPCAddress),
 	)
 
+	ReportProgress(ctx, TaskProgress{
+		Message: fmt.Sprintf("running on runtime %s", selectedRuntime.Name),
+		Logs:    []string{fmt.Sprintf("selected runtime %s at %s", selectedRuntime.DID, selectedRuntime.GRPCAddress)},
+	})
+
 	// Create direct ARI executor for this runtime
 	ariExecutor, err := NewARIExecutor(selectedRuntime.GRPCAddress, e.logger)
 	if err != nil {
@@ -120,7 +135,8 @@ func (e *P2PARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *ide
 	}
 	defer ariExecutor.Close()
 
-	// Execute task on selected runtime (pass nil for agent since runtime doesn't use it)
+	// Execute task on selected runtime (pass nil for agent since runtime doesn't use it).
+	// Canceling ctx sends the runtime a Cancel for the task.
 	ariResult, err := ariExecutor.ExecuteTask(ctx, task, nil)
 	if err != nil {
 		return &TaskResult{
@@ -144,8 +160,6 @@ func (e *P2PARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *ide
 		resultData = ariResult.Result
 	}
 
-	actualCost := extractActualCost(task.Budget, resultData)
-
 	return &TaskResult{
 		TaskID:      task.ID,
 		Status:      ariResult.Status,
@@ -154,7 +168,7 @@ func (e *P2PARIExecutor) ExecuteTask(ctx context.Context, task *Task, agent *ide
 		ExecutionMS: time.Since(startTime).Milliseconds(),
 		AgentDID:    selectedRuntime.DID,
 		Timestamp:   time.Now(),
-		Cost:        actualCost,
+		Cost:        ariResult.Cost, // Pro-rated by the ARI executor when canceled
 	}, nil
 }
 
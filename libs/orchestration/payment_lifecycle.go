@@ -23,6 +23,7 @@ var (
 	ErrInsufficientFunds         = errors.New("insufficient funds for payment")
 	ErrPaymentTimeout           = errors.New("payment operation timeout")
 	ErrCircuitBreakerOpen       = errors.New("payment circuit breaker is open")
+	ErrSplitUnsupported         = errors.New("blockchain cannot split an escrow between agents")
 )
 
 // PaymentEvent represents a payment lifecycle event
@@ -43,6 +44,8 @@ type PaymentInfo struct {
 	UserID       string        `json:"user_id"`
 	AgentID      string        `json:"agent_id,omitempty"`
 	Amount       float64       `json:"amount"`
+	AgentAmount  float64       `json:"agent_amount,omitempty"` // Paid to the agent when less than Amount
+	Shares       map[string]float64 `json:"shares,omitempty"`   // Per-agent amounts of a replicated task
 	Status       PaymentStatus `json:"status"`
 	EscrowTxHash string        `json:"escrow_tx_hash,omitempty"`
 	PaymentTxHash string       `json:"payment_tx_hash,omitempty"`
@@ -134,6 +137,20 @@ type BlockchainInterface interface {
 	GetEscrowStatus(ctx context.Context, taskID string) (PaymentStatus, error)
 }
 
+// PartialReleaser is implemented by blockchains that can split an escrow
+// between the agent and the user
+type PartialReleaser interface {
+	// ReleasePartialPayment pays amount to the agent and refunds the rest
+	ReleasePartialPayment(ctx context.Context, taskID string, amount float64) (txHash string, err error)
+}
+
+// SplitReleaser is implemented by blockchains that can pay an escrow out to
+// several agents
+type SplitReleaser interface {
+	// ReleaseSplitPayment pays each agent its share and refunds the rest
+	ReleaseSplitPayment(ctx context.Context, taskID string, shares map[string]float64) (txHash string, err error)
+}
+
 // PaymentLifecycleManager manages the complete payment lifecycle
 type PaymentLifecycleManager struct {
 	blockchain   BlockchainInterface
@@ -397,6 +414,153 @@ func (pm *PaymentLifecycleManager) RefundPayment(ctx context.Context, taskID, re
 	return nil
 }
 
+// SettleCanceledPaymentAsync settles a canceled task's payment asynchronously
+func (pm *PaymentLifecycleManager) SettleCanceledPaymentAsync(ctx context.Context, taskID, agentID string, earned float64) {
+	go func() {
+		pm.logger.Info("starting async canceled payment settlement",
+			zap.String("task_id", taskID),
+			zap.Float64("earned", earned),
+		)
+
+		err := pm.SettleCanceledPayment(ctx, taskID, agentID, earned)
+		if err != nil {
+			pm.logger.Error("failed to settle canceled payment asynchronously",
+				zap.String("task_id", taskID),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// SettleCanceledPayment settles the payment of a task canceled while it ran,
+// by what the agent earned before it stopped. Nothing earned is refunded in
+// full and the whole amount released in full. In between, the agent is paid
+// its share and the user refunded the rest when the blockchain can split an
+// escrow; otherwise the user is refunded in full, so a canceled task never
+// pays out for work it didn't do.
+func (pm *PaymentLifecycleManager) SettleCanceledPayment(ctx context.Context, taskID, agentID string, earned float64) error {
+	payment, err := pm.GetPaymentInfo(taskID)
+	if err != nil {
+		return err
+	}
+
+	if earned <= 0 {
+		return pm.RefundPayment(ctx, taskID, "task canceled before doing any work")
+	}
+	if earned >= payment.Amount {
+		return pm.ReleasePayment(ctx, taskID, agentID)
+	}
+
+	splitter, ok := pm.blockchain.(PartialReleaser)
+	if !ok {
+		return pm.RefundPayment(ctx, taskID,
+			fmt.Sprintf("task canceled after earning %.4f of %.4f; escrow cannot be split", earned, payment.Amount))
+	}
+
+	if payment.Status != PaymentStatusAccepted {
+		return fmt.Errorf("%w: payment status is %s, expected %s",
+			ErrInvalidPaymentStatus, payment.Status, PaymentStatusAccepted)
+	}
+
+	pm.mu.Lock()
+	if p, exists := pm.payments[taskID]; exists {
+		p.AgentID = agentID
+		p.AgentAmount = earned
+	}
+	pm.mu.Unlock()
+
+	reason := fmt.Sprintf("task canceled, released %.4f of %.4f", earned, payment.Amount)
+	err = pm.executePaymentWithRetry(ctx, taskID, func() error {
+		return pm.circuitBreaker.Call(func() error {
+			txHash, err := splitter.ReleasePartialPayment(ctx, taskID, earned)
+			if err != nil {
+				return err
+			}
+			return pm.UpdatePaymentStatus(taskID, PaymentStatusReleased, reason, txHash)
+		})
+	})
+
+	if err != nil {
+		pm.UpdatePaymentStatus(taskID, PaymentStatusFailure, fmt.Sprintf("partial release failed: %v", err), "")
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseSharedPaymentAsync releases a replicated task's payment asynchronously
+func (pm *PaymentLifecycleManager) ReleaseSharedPaymentAsync(ctx context.Context, taskID string, shares map[string]float64) {
+	go func() {
+		pm.logger.Info("starting async shared payment release",
+			zap.String("task_id", taskID),
+			zap.Int("agents", len(shares)),
+		)
+
+		err := pm.ReleaseSharedPayment(ctx, taskID, shares)
+		if err != nil {
+			pm.logger.Error("failed to release shared payment asynchronously",
+				zap.String("task_id", taskID),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// ReleaseSharedPayment pays the agents that agreed on a replicated task's
+// result their shares. It fails with ErrSplitUnsupported, leaving the escrow
+// untouched, when several agents are owed and the blockchain can't split an
+// escrow.
+func (pm *PaymentLifecycleManager) ReleaseSharedPayment(ctx context.Context, taskID string, shares map[string]float64) error {
+	if len(shares) == 0 {
+		return fmt.Errorf("no agents to pay for task %s", taskID)
+	}
+
+	payment, err := pm.GetPaymentInfo(taskID)
+	if err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	if p, exists := pm.payments[taskID]; exists {
+		p.Shares = shares
+	}
+	pm.mu.Unlock()
+
+	if len(shares) == 1 {
+		for agentID := range shares {
+			return pm.ReleasePayment(ctx, taskID, agentID)
+		}
+	}
+	splitter, ok := pm.blockchain.(SplitReleaser)
+	if !ok {
+		pm.UpdatePaymentStatus(taskID, PaymentStatusFailure, "split release failed: blockchain cannot split an escrow", "")
+		return fmt.Errorf("%w: task %s owes %d agents", ErrSplitUnsupported, taskID, len(shares))
+	}
+
+	if payment.Status != PaymentStatusAccepted {
+		return fmt.Errorf("%w: payment status is %s, expected %s",
+			ErrInvalidPaymentStatus, payment.Status, PaymentStatusAccepted)
+	}
+
+	reason := fmt.Sprintf("task completed, split between %d agents", len(shares))
+	err = pm.executePaymentWithRetry(ctx, taskID, func() error {
+		return pm.circuitBreaker.Call(func() error {
+			txHash, err := splitter.ReleaseSplitPayment(ctx, taskID, shares)
+			if err != nil {
+				return err
+			}
+			return pm.UpdatePaymentStatus(taskID, PaymentStatusReleased, reason, txHash)
+		})
+	})
+
+	if err != nil {
+		pm.UpdatePaymentStatus(taskID, PaymentStatusFailure, fmt.Sprintf("split release failed: %v", err), "")
+		return err
+	}
+
+	return nil
+}
+
 // DisputePayment initiates a payment dispute
 func (pm *PaymentLifecycleManager) DisputePayment(ctx context.Context, taskID, reason, initiator string) error {
 	pm.logger.Info("disputing payment",
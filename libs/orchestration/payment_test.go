@@ -135,6 +135,69 @@ func TestPaymentLifecycleManager_RefundPayment(t *testing.T) {
 	mockBlockchain.AssertExpectations(t)
 }
 
+func TestPaymentLifecycleManager_SettleCanceledPayment(t *testing.T) {
+	mockBlockchain := &MockBlockchain{}
+	logger := zaptest.NewLogger(t)
+	pm := NewPaymentLifecycleManager(mockBlockchain, DefaultPaymentConfig(), logger)
+	ctx := context.Background()
+
+	// Nothing earned: full refund
+	pm.CreatePayment("task-idle", "user-456", 10)
+	mockBlockchain.On("RefundEscrow", mock.Anything, "task-idle").Return("0xidle", nil)
+	assert.NoError(t, pm.SettleCanceledPayment(ctx, "task-idle", "agent-789", 0))
+
+	// Part earned, but this blockchain can't split an escrow: full refund
+	pm.CreatePayment("task-partial", "user-456", 10)
+	assert.NoError(t, pm.UpdatePaymentStatus("task-partial", PaymentStatusAccepted, "agent selected", ""))
+	mockBlockchain.On("RefundEscrow", mock.Anything, "task-partial").Return("0xpartial", nil)
+	assert.NoError(t, pm.SettleCanceledPayment(ctx, "task-partial", "agent-789", 4))
+
+	payment, err := pm.GetPaymentInfo("task-partial")
+	assert.NoError(t, err)
+	assert.Equal(t, PaymentStatusRefunded, payment.Status)
+	assert.Zero(t, payment.AgentAmount)
+
+	// Everything earned: full release
+	pm.CreatePayment("task-done", "user-456", 10)
+	assert.NoError(t, pm.UpdatePaymentStatus("task-done", PaymentStatusAccepted, "agent selected", ""))
+	mockBlockchain.On("ReleasePayment", mock.Anything, "task-done").Return("0xdone", nil)
+	assert.NoError(t, pm.SettleCanceledPayment(ctx, "task-done", "agent-789", 10))
+
+	mockBlockchain.AssertExpectations(t)
+}
+
+func TestPaymentLifecycleManager_ReleaseSharedPayment(t *testing.T) {
+	mockBlockchain := &MockBlockchain{}
+	logger := zaptest.NewLogger(t)
+	pm := NewPaymentLifecycleManager(mockBlockchain, DefaultPaymentConfig(), logger)
+	ctx := context.Background()
+
+	// This blockchain can't split an escrow: nobody is paid
+	pm.CreatePayment("task-shared", "user-456", 10)
+	assert.NoError(t, pm.UpdatePaymentStatus("task-shared", PaymentStatusAccepted, "agent selected", ""))
+
+	shares := map[string]float64{"agent-b": 3, "agent-a": 5, "agent-c": 5}
+	assert.ErrorIs(t, pm.ReleaseSharedPayment(ctx, "task-shared", shares), ErrSplitUnsupported)
+
+	payment, err := pm.GetPaymentInfo("task-shared")
+	assert.NoError(t, err)
+	assert.Equal(t, PaymentStatusFailure, payment.Status)
+	assert.Equal(t, shares, payment.Shares)
+
+	// A single agent is paid the usual way
+	pm.CreatePayment("task-single", "user-456", 10)
+	assert.NoError(t, pm.UpdatePaymentStatus("task-single", PaymentStatusAccepted, "agent selected", ""))
+	mockBlockchain.On("ReleasePayment", mock.Anything, "task-single").Return("0xsingle", nil)
+	assert.NoError(t, pm.ReleaseSharedPayment(ctx, "task-single", map[string]float64{"agent-a": 5}))
+
+	payment, err = pm.GetPaymentInfo("task-single")
+	assert.NoError(t, err)
+	assert.Equal(t, PaymentStatusReleased, payment.Status)
+	assert.Equal(t, "agent-a", payment.AgentID)
+
+	mockBlockchain.AssertExpectations(t)
+}
+
 func TestPaymentLifecycleManager_DisputePayment(t *testing.T) {
 	mockBlockchain := &MockBlockchain{}
 	logger := zaptest.NewLogger(t)
@@ -0,0 +1,198 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// progressBufferSize is how many updates a slow subscriber may fall behind
+// before updates to it are dropped
+const progressBufferSize = 64
+
+// TaskProgress is an update from a running task: how far it got, what it
+// logged and what it has produced so far. The last update of a task has a
+// terminal status and carries the result.
+type TaskProgress struct {
+	TaskID    string                 `json:"task_id"`
+	Status    TaskStatus             `json:"status"`
+	Percent   float64                `json:"percent"` // 0-100
+	Message   string                 `json:"message,omitempty"`
+	Logs      []string               `json:"logs,omitempty"`    // Lines logged since the previous update
+	Partial   json.RawMessage        `json:"partial,omitempty"` // Partial output, superseded by Result
+	Result    map[string]interface{} `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Final reports whether this is the last update of the task
+func (p TaskProgress) Final() bool {
+	return p.Status == TaskStatusCompleted ||
+		p.Status == TaskStatusFailed ||
+		p.Status == TaskStatusCanceled
+}
+
+// FinalProgress is the closing update for a task in a terminal state
+func FinalProgress(task *Task) TaskProgress {
+	p := TaskProgress{
+		TaskID:    task.ID,
+		Status:    task.Status,
+		Error:     task.Error,
+		Timestamp: time.Now(),
+	}
+	if task.Status == TaskStatusCompleted {
+		p.Percent = 100
+		p.Result = task.Result
+	}
+	return p
+}
+
+// ProgressBroadcaster forwards task progress to another transport, such as
+// the WebSocket subscribers of the task. Broadcasts must not block.
+type ProgressBroadcaster interface {
+	BroadcastTaskProgress(userID string, progress TaskProgress)
+}
+
+type progressKey struct{}
+
+// WithProgress attaches a progress reporter to ctx. Executors report through
+// ReportProgress; the orchestrator attaches one to every execution.
+func WithProgress(ctx context.Context, report func(TaskProgress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// ReportProgress delivers an update to the reporter carried by ctx, if any
+func ReportProgress(ctx context.Context, progress TaskProgress) {
+	report, ok := ctx.Value(progressKey{}).(func(TaskProgress))
+	if !ok || report == nil {
+		return
+	}
+	if progress.Status == "" {
+		progress.Status = TaskStatusRunning
+	}
+	if progress.Timestamp.IsZero() {
+		progress.Timestamp = time.Now()
+	}
+	report(progress)
+}
+
+// progressHub fans task progress out to subscribers. New subscribers are
+// sent the latest update first so they don't start from nothing.
+type progressHub struct {
+	mu           sync.Mutex
+	subscribers  map[string]map[chan TaskProgress]struct{}
+	latest       map[string]TaskProgress
+	broadcasters []ProgressBroadcaster
+	logger       *zap.Logger
+}
+
+func newProgressHub(logger *zap.Logger) *progressHub {
+	return &progressHub{
+		subscribers: make(map[string]map[chan TaskProgress]struct{}),
+		latest:      make(map[string]TaskProgress),
+		logger:      logger,
+	}
+}
+
+// subscribe returns the task's updates and a func that ends the
+// subscription. The channel is closed after the final update.
+func (h *progressHub) subscribe(taskID string) (<-chan TaskProgress, func()) {
+	ch := make(chan TaskProgress, progressBufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if latest, ok := h.latest[taskID]; ok {
+		ch <- latest
+	}
+	if h.subscribers[taskID] == nil {
+		h.subscribers[taskID] = make(map[chan TaskProgress]struct{})
+	}
+	h.subscribers[taskID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[taskID][ch]; ok {
+			delete(h.subscribers[taskID], ch)
+			if len(h.subscribers[taskID]) == 0 {
+				delete(h.subscribers, taskID)
+			}
+			close(ch)
+		}
+	}
+}
+
+// publish delivers an update to the task's subscribers and broadcasters.
+// The final update closes every subscription of the task.
+func (h *progressHub) publish(userID string, p TaskProgress) {
+	h.mu.Lock()
+	for ch := range h.subscribers[p.TaskID] {
+		select {
+		case ch <- p:
+		default:
+			h.logger.Debug("progress subscriber is behind, dropping update",
+				zap.String("task_id", p.TaskID),
+			)
+		}
+	}
+	if p.Final() {
+		for ch := range h.subscribers[p.TaskID] {
+			close(ch)
+		}
+		delete(h.subscribers, p.TaskID)
+		delete(h.latest, p.TaskID)
+	} else {
+		h.latest[p.TaskID] = p
+	}
+	broadcasters := append([]ProgressBroadcaster(nil), h.broadcasters...)
+	h.mu.Unlock()
+
+	for _, b := range broadcasters {
+		b.BroadcastTaskProgress(userID, p)
+	}
+}
+
+// AddProgressBroadcaster forwards every task's progress to b
+func (o *Orchestrator) AddProgressBroadcaster(b ProgressBroadcaster) {
+	o.progress.mu.Lock()
+	defer o.progress.mu.Unlock()
+	o.progress.broadcasters = append(o.progress.broadcasters, b)
+}
+
+// SubscribeProgress streams a task's progress until its final update. Call
+// the returned func to stop early; the channel is closed either way.
+func (o *Orchestrator) SubscribeProgress(taskID string) (<-chan TaskProgress, func()) {
+	return o.progress.subscribe(taskID)
+}
+
+// publishProgress publishes an update for task
+func (o *Orchestrator) publishProgress(task *Task, p TaskProgress) {
+	p.TaskID = task.ID
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+	o.progress.publish(task.UserID, p)
+}
+
+// progressReporter is the reporter attached to a task's execution. It keeps
+// percent from going backwards when an update, such as a log line, omits it.
+// Only the orchestrator ends a stream, once the result is stored.
+func (o *Orchestrator) progressReporter(task *Task) func(TaskProgress) {
+	var mu sync.Mutex
+	var percent float64
+	return func(p TaskProgress) {
+		if p.Final() {
+			p.Status = TaskStatusRunning
+		}
+		mu.Lock()
+		if p.Percent < percent {
+			p.Percent = percent
+		}
+		percent = p.Percent
+		mu.Unlock()
+		o.publishProgress(task, p)
+	}
+}
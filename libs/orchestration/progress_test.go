@@ -0,0 +1,107 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	ariv1 "github.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1"
+	"go.uber.org/zap"
+)
+
+type recordingBroadcaster struct {
+	mu      sync.Mutex
+	userIDs []string
+	updates []TaskProgress
+}
+
+func (b *recordingBroadcaster) BroadcastTaskProgress(userID string, p TaskProgress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.userIDs = append(b.userIDs, userID)
+	b.updates = append(b.updates, p)
+}
+
+func drain(ch <-chan TaskProgress) []TaskProgress {
+	var out []TaskProgress
+	for p := range ch {
+		out = append(out, p)
+	}
+	return out
+}
+
+func TestProgressStreamsUntilFinal(t *testing.T) {
+	o := &Orchestrator{progress: newProgressHub(zap.NewNop())}
+	broadcaster := &recordingBroadcaster{}
+	o.AddProgressBroadcaster(broadcaster)
+
+	task := &Task{ID: "task-1", UserID: "did:user:1"}
+	updates, unsubscribe := o.SubscribeProgress(task.ID)
+	defer unsubscribe()
+
+	ctx := WithProgress(context.Background(), o.progressReporter(task))
+	ReportProgress(ctx, TaskProgress{Percent: 40, Message: "halfway"})
+	ReportProgress(ctx, TaskProgress{Logs: []string{"info: still going"}})
+	ReportProgress(ctx, TaskProgress{Status: TaskStatusCompleted}) // Executors can't end the stream
+
+	// A late subscriber starts from the latest update
+	late, unsubscribeLate := o.SubscribeProgress(task.ID)
+	defer unsubscribeLate()
+
+	task.Status = TaskStatusCompleted
+	task.Result = map[string]interface{}{"answer": 42}
+	o.publishProgress(task, FinalProgress(task))
+
+	got := drain(updates)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 updates, got %d: %+v", len(got), got)
+	}
+	if got[1].Percent != 40 || len(got[1].Logs) != 1 {
+		t.Errorf("expected log update to keep 40%%, got %+v", got[1])
+	}
+	if got[2].Status != TaskStatusRunning {
+		t.Errorf("expected executor's terminal status to be demoted, got %s", got[2].Status)
+	}
+	if last := got[3]; !last.Final() || last.Percent != 100 || last.Result["answer"] != 42 {
+		t.Errorf("unexpected final update %+v", last)
+	}
+
+	lateGot := drain(late)
+	if len(lateGot) != 2 || lateGot[0].Percent != 40 || !lateGot[1].Final() {
+		t.Errorf("unexpected late updates %+v", lateGot)
+	}
+
+	if len(broadcaster.updates) != 4 || broadcaster.userIDs[0] != "did:user:1" {
+		t.Errorf("expected every update broadcast for the task owner, got %d", len(broadcaster.updates))
+	}
+
+	// Finished tasks leave nothing behind
+	if len(o.progress.subscribers) != 0 || len(o.progress.latest) != 0 {
+		t.Error("expected final update to clear the task")
+	}
+}
+
+func TestReportProgressWithoutReporter(t *testing.T) {
+	// Executors report unconditionally; runs outside the orchestrator drop it
+	ReportProgress(context.Background(), TaskProgress{Percent: 10})
+}
+
+func TestARIProgress(t *testing.T) {
+	p := ariProgress(&ariv1.TaskExecuteResponse{
+		TaskId:          "task-1",
+		Status:          ariv1.TaskStatus_TASK_STATUS_RUNNING,
+		Progress:        0.25,
+		ProgressMessage: "tokenizing",
+		Logs:            []string{"loaded model"},
+		PartialResult:   `{"tokens":12}`,
+	})
+	if p.Percent != 25 || p.Message != "tokenizing" || len(p.Logs) != 1 || string(p.Partial) != `{"tokens":12}` {
+		t.Errorf("unexpected progress %+v", p)
+	}
+
+	// Plain-text partial output is carried as a JSON string
+	p = ariProgress(&ariv1.TaskExecuteResponse{PartialResult: "The answer"})
+	if string(p.Partial) != `"The answer"` {
+		t.Errorf("expected quoted partial, got %s", p.Partial)
+	}
+}
@@ -78,6 +78,9 @@ func (tq *TaskQueue) Enqueue(task *Task) error {
 		return ErrQueueFull
 	}
 
+	// Update status before the task is visible to workers
+	task.UpdateStatus(TaskStatusQueued)
+
 	// Add to priority queue
 	item := &queueItem{
 		task:     task,
@@ -93,9 +96,6 @@ func (tq *TaskQueue) Enqueue(task *Task) error {
 	tq.tasks[task.ID] = task
 	tq.tasksMu.Unlock()
 
-	// Update status
-	task.UpdateStatus(TaskStatusQueued)
-
 	tq.logger.Info("task enqueued",
 		zap.String("task_id", task.ID),
 		zap.String("type", task.Type),
@@ -196,6 +196,20 @@ func (tq *TaskQueue) Update(task *Task) error {
 	return nil
 }
 
+// Assign marks a dequeued task as assigned unless it was canceled first. The
+// check and the update happen under the same lock Cancel takes, so a worker
+// never reads a status Cancel is writing
+func (tq *TaskQueue) Assign(task *Task) bool {
+	tq.tasksMu.Lock()
+	defer tq.tasksMu.Unlock()
+
+	if task.Status == TaskStatusCanceled {
+		return false
+	}
+	task.UpdateStatus(TaskStatusAssigned)
+	return true
+}
+
 // Cancel removes a task from the queue
 func (tq *TaskQueue) Cancel(taskID string) error {
 	tq.tasksMu.Lock()
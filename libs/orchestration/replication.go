@@ -0,0 +1,353 @@
+package orchestration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/reputation"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/zap"
+)
+
+// MaxReplicationFactor caps how many agents a single task can run on
+const MaxReplicationFactor = 7
+
+var (
+	// ErrNoQuorum is returned when too few replicas of a task agree on a result
+	ErrNoQuorum = errors.New("replicas did not reach a quorum")
+)
+
+// ReplicationPolicy runs a task on several independently selected agents and
+// accepts the result a quorum of them agree on
+type ReplicationPolicy struct {
+	Factor        int     `json:"factor"`                   // Agents to run the task on
+	Quorum        int     `json:"quorum,omitempty"`         // Agreeing agents needed; a majority by default
+	MinSimilarity float64 `json:"min_similarity,omitempty"` // Score at which a ResultSimilarity counts as agreement; 1 by default
+}
+
+// Validate checks the policy is satisfiable
+func (p *ReplicationPolicy) Validate() error {
+	if p.Factor < 1 || p.Factor > MaxReplicationFactor {
+		return fmt.Errorf("replication factor must be between 1 and %d", MaxReplicationFactor)
+	}
+	if p.Quorum < 0 || p.Quorum > p.Factor {
+		return fmt.Errorf("quorum must be between 1 and the replication factor")
+	}
+	if p.MinSimilarity < 0 || p.MinSimilarity > 1 {
+		return fmt.Errorf("min_similarity must be between 0 and 1")
+	}
+	return nil
+}
+
+// replicas returns how many agents the task runs on
+func (p *ReplicationPolicy) replicas() int {
+	if p == nil || p.Factor < 1 {
+		return 1
+	}
+	return p.Factor
+}
+
+// quorum returns how many agents must agree on a result
+func (p *ReplicationPolicy) quorum() int {
+	if p != nil && p.Quorum > 0 {
+		return p.Quorum
+	}
+	return p.replicas()/2 + 1
+}
+
+// minSimilarity returns the similarity score that counts as agreement
+func (p *ReplicationPolicy) minSimilarity() float64 {
+	if p == nil || p.MinSimilarity == 0 {
+		return 1
+	}
+	return p.MinSimilarity
+}
+
+// ResultSimilarity scores how alike two task results are, from 0 (nothing
+// in common) to 1 (equivalent). Results with the same hash always agree.
+type ResultSimilarity func(a, b map[string]interface{}) float64
+
+// ReplicaResult is one agent's run of a replicated task
+type ReplicaResult struct {
+	AgentDID    string     `json:"agent_did"`
+	Status      TaskStatus `json:"status"`
+	ResultHash  string     `json:"result_hash,omitempty"`
+	Cost        float64    `json:"cost,omitempty"`
+	ExecutionMS int64      `json:"execution_ms,omitempty"`
+	Agreed      bool       `json:"agreed"`           // Part of the accepted majority
+	Payout      float64    `json:"payout,omitempty"` // Paid out of the escrow for agreeing
+	Error       string     `json:"error,omitempty"`
+}
+
+type excludedAgentsKey struct{}
+
+// WithExcludedAgents tells the agent selectors called with ctx not to pick
+// the given agents, so a task can be sent to agents other than those it
+// already has
+func WithExcludedAgents(ctx context.Context, dids ...string) context.Context {
+	excluded := make(map[string]bool)
+	if prev, ok := ctx.Value(excludedAgentsKey{}).(map[string]bool); ok {
+		for did := range prev {
+			excluded[did] = true
+		}
+	}
+	for _, did := range dids {
+		excluded[did] = true
+	}
+	return context.WithValue(ctx, excludedAgentsKey{}, excluded)
+}
+
+// IsAgentExcluded reports whether ctx excludes the agent from selection
+func IsAgentExcluded(ctx context.Context, did string) bool {
+	excluded, _ := ctx.Value(excludedAgentsKey{}).(map[string]bool)
+	return excluded[did]
+}
+
+// excludedAgentCount returns how many agents ctx excludes from selection
+func excludedAgentCount(ctx context.Context) int {
+	excluded, _ := ctx.Value(excludedAgentsKey{}).(map[string]bool)
+	return len(excluded)
+}
+
+// resultHash returns a digest of a result. encoding/json sorts map keys, so
+// equal results hash the same.
+func resultHash(result map[string]interface{}) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// voteReplicas groups the completed replicas by agreement, comparing each to
+// the first member of every group, and returns the indexes of the largest
+// group. It returns nil when no group reaches the quorum.
+func voteReplicas(results []*TaskResult, hashes []string, quorum int, similar ResultSimilarity, minSimilarity float64) []int {
+	var groups [][]int
+	for i, result := range results {
+		if result == nil || result.Status != TaskStatusCompleted {
+			continue
+		}
+
+		joined := false
+		for g, group := range groups {
+			first := group[0]
+			if (hashes[i] != "" && hashes[i] == hashes[first]) ||
+				(similar != nil && similar(result.Result, results[first].Result) >= minSimilarity) {
+				groups[g] = append(group, i)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			groups = append(groups, []int{i})
+		}
+	}
+
+	var majority []int
+	for _, group := range groups {
+		if len(group) > len(majority) {
+			majority = group
+		}
+	}
+	if len(majority) < quorum {
+		return nil
+	}
+	return majority
+}
+
+// SetResultSimilarity sets how replicated tasks compare results that don't
+// hash the same. Without one only identical results agree.
+func (o *Orchestrator) SetResultSimilarity(fn ResultSimilarity) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.similarity = fn
+}
+
+// SetReputationManager attaches the local reputation manager replicated
+// tasks report their agents to, alongside the on-chain reputation
+func (o *Orchestrator) SetReputationManager(rm *reputation.ReputationManager) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reputation = rm
+}
+
+// executeReplicated runs a task on the agent it was assigned and on more
+// agents picked by the selector, then accepts the result a quorum of them
+// agree on. The agreeing agents share the task's cost, capped at its budget.
+// It returns the agent whose result was accepted, or the assigned agent when
+// there was no quorum.
+func (w *worker) executeReplicated(ctx context.Context, task *Task, first *identity.AgentCard) (*TaskResult, *identity.AgentCard, error) {
+	policy := task.Replication
+	agents := []*identity.AgentCard{first}
+	picked := []string{first.DID}
+
+	for len(agents) < policy.replicas() {
+		selectCtx := WithExcludedAgents(ctx, picked...)
+		agent, err := w.orchestrator.selector.SelectAgent(selectCtx, task)
+		if err != nil || agent == nil || IsAgentExcluded(selectCtx, agent.DID) {
+			// Out of agents, or a selector that ignores exclusions
+			break
+		}
+		agents = append(agents, agent)
+		picked = append(picked, agent.DID)
+	}
+	if len(agents) < policy.quorum() {
+		return nil, first, fmt.Errorf("%w: found %d agents for a quorum of %d", ErrNoSuitableAgent, len(agents), policy.quorum())
+	}
+
+	w.logger.Info("running replicated task",
+		zap.String("task_id", task.ID),
+		zap.Strings("agents", picked),
+		zap.Int("quorum", policy.quorum()),
+	)
+
+	results := make([]*TaskResult, len(agents))
+	errs := make([]error, len(agents))
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		// Each replica runs on its own copy, so a cancel updating the task's
+		// status never races with the executors reading it
+		replica := *task
+		wg.Add(1)
+		go func(i int, agent *identity.AgentCard) {
+			defer wg.Done()
+			results[i], errs[i] = w.orchestrator.executor.ExecuteTask(ctx, &replica, agent)
+		}(i, agent)
+	}
+	wg.Wait()
+
+	replicas := make([]ReplicaResult, len(agents))
+	hashes := make([]string, len(agents))
+	for i, agent := range agents {
+		replicas[i] = ReplicaResult{AgentDID: agent.DID, Status: TaskStatusFailed}
+		if errs[i] != nil {
+			results[i] = nil
+			replicas[i].Error = errs[i].Error()
+			continue
+		}
+		replicas[i].Status = results[i].Status
+		replicas[i].Cost = results[i].Cost
+		replicas[i].ExecutionMS = results[i].ExecutionMS
+		replicas[i].Error = results[i].Error
+		if results[i].Status == TaskStatusCompleted {
+			hashes[i] = resultHash(results[i].Result)
+			replicas[i].ResultHash = hashes[i]
+		}
+	}
+
+	w.orchestrator.mu.RLock()
+	similar := w.orchestrator.similarity
+	w.orchestrator.mu.RUnlock()
+
+	majority := voteReplicas(results, hashes, policy.quorum(), similar, policy.minSimilarity())
+	task.Replicas = replicas
+	if majority == nil {
+		w.logger.Warn("replicas disagreed",
+			zap.String("task_id", task.ID),
+			zap.Int("replicas", len(agents)),
+			zap.Int("quorum", policy.quorum()),
+		)
+		return &TaskResult{
+			TaskID:    task.ID,
+			Status:    TaskStatusFailed,
+			Error:     fmt.Sprintf("%v: no %d of %d results agreed", ErrNoQuorum, policy.quorum(), len(agents)),
+			Timestamp: time.Now(),
+		}, first, nil
+	}
+
+	accepted := *results[majority[0]]
+	accepted.AgentDID = agents[majority[0]].DID
+	accepted.Cost = 0
+	for _, i := range majority {
+		replicas[i].Agreed = true
+		replicas[i].Payout = replicaPayout(task, results, majority, i)
+		accepted.Cost += replicas[i].Payout
+	}
+	task.AssignedTo = accepted.AgentDID
+
+	return &accepted, agents[majority[0]], nil
+}
+
+// replicaPayout is what agreeing replica i earns: its own cost, scaled down
+// when the agreeing replicas together cost more than the budget. Replicas
+// that report no cost split the budget evenly.
+func replicaPayout(task *Task, results []*TaskResult, majority []int, i int) float64 {
+	total := 0.0
+	for _, j := range majority {
+		total += results[j].Cost
+	}
+	if total == 0 {
+		return task.Budget / float64(len(majority))
+	}
+	if task.Budget > 0 && total > task.Budget {
+		return results[i].Cost * task.Budget / total
+	}
+	return results[i].Cost
+}
+
+// ReplicaShares returns the payouts of the agents that agreed on a
+// replicated task's result, by DID
+func ReplicaShares(task *Task) map[string]float64 {
+	shares := make(map[string]float64)
+	for _, r := range task.Replicas {
+		if r.Agreed {
+			shares[r.AgentDID] += r.Payout
+		}
+	}
+	return shares
+}
+
+// reportReplicaReputation reports every agent of a replicated task: agreeing
+// with the accepted result counts as a success and anything else as a
+// failure. Without a quorum there is no accepted result to disagree with, so
+// only the agents whose runs failed are reported.
+func (w *worker) reportReplicaReputation(task *Task) {
+	consensus := false
+	for _, r := range task.Replicas {
+		consensus = consensus || r.Agreed
+	}
+
+	w.orchestrator.mu.RLock()
+	rm := w.orchestrator.reputation
+	w.orchestrator.mu.RUnlock()
+
+	for _, r := range task.Replicas {
+		if !consensus && r.Status == TaskStatusCompleted {
+			continue
+		}
+
+		w.reportReputationAsync(task, &identity.AgentCard{DID: r.AgentDID}, r.Agreed)
+
+		if rm == nil {
+			continue
+		}
+		errMsg := r.Error
+		if !r.Agreed && errMsg == "" {
+			errMsg = "result disagreed with the majority"
+		}
+		if err := rm.RecordExecution(w.orchestrator.ctx, reputation.ExecutionOutcome{
+			TaskID:     task.ID,
+			ExecutorID: peer.ID(r.AgentDID),
+			Success:    r.Agreed,
+			Duration:   time.Duration(r.ExecutionMS) * time.Millisecond,
+			Cost:       r.Payout,
+			Timestamp:  time.Now(),
+			Error:      errMsg,
+		}); err != nil {
+			w.logger.Warn("failed to record replica reputation",
+				zap.String("task_id", task.ID),
+				zap.String("agent_did", r.AgentDID),
+				zap.Error(err),
+			)
+		}
+	}
+}
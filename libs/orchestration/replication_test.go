@@ -0,0 +1,207 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/aidenlippert/zerostate/libs/reputation"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/zap"
+)
+
+// poolSelector picks the first agent of its pool the context doesn't exclude
+type poolSelector struct {
+	agents []*identity.AgentCard
+}
+
+func (s *poolSelector) SelectAgent(ctx context.Context, task *Task) (*identity.AgentCard, error) {
+	for _, agent := range s.agents {
+		if !IsAgentExcluded(ctx, agent.DID) {
+			return agent, nil
+		}
+	}
+	return nil, ErrNoSuitableAgent
+}
+
+// answeringExecutor returns the answer it has for each agent
+type answeringExecutor struct {
+	answers map[string]interface{}
+}
+
+func (e *answeringExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identity.AgentCard) (*TaskResult, error) {
+	answer, ok := e.answers[agent.DID]
+	if !ok {
+		return nil, errors.New("agent unreachable")
+	}
+	return &TaskResult{
+		TaskID: task.ID,
+		Status: TaskStatusCompleted,
+		Result: map[string]interface{}{"answer": answer},
+		Cost:   4,
+	}, nil
+}
+
+// sharingChain is a blockchain that can pay an escrow out to several agents
+type sharingChain struct {
+	*splittingChain
+	shares chan map[string]float64
+}
+
+func (c *sharingChain) ReleaseSplitPayment(ctx context.Context, taskID string, shares map[string]float64) (string, error) {
+	c.shares <- shares
+	return "0xsplit", nil
+}
+
+func agentPool(dids ...string) *poolSelector {
+	s := &poolSelector{}
+	for _, did := range dids {
+		s.agents = append(s.agents, &identity.AgentCard{DID: did})
+	}
+	return s
+}
+
+func TestVoteReplicas(t *testing.T) {
+	completed := func(answer interface{}) *TaskResult {
+		return &TaskResult{Status: TaskStatusCompleted, Result: map[string]interface{}{"answer": answer}}
+	}
+	hashesOf := func(results []*TaskResult) []string {
+		hashes := make([]string, len(results))
+		for i, r := range results {
+			if r != nil && r.Status == TaskStatusCompleted {
+				hashes[i] = resultHash(r.Result)
+			}
+		}
+		return hashes
+	}
+
+	results := []*TaskResult{completed(1.0), completed(2.0), completed(2.0), nil}
+	if got := voteReplicas(results, hashesOf(results), 2, nil, 1); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("expected replicas 1 and 2 to win, got %v", got)
+	}
+	if got := voteReplicas(results, hashesOf(results), 3, nil, 1); got != nil {
+		t.Errorf("expected no quorum of 3, got %v", got)
+	}
+
+	// Close enough answers agree under a similarity function
+	results = []*TaskResult{completed(1.0), completed(1.01), completed(5.0)}
+	near := func(a, b map[string]interface{}) float64 {
+		diff := a["answer"].(float64) - b["answer"].(float64)
+		if diff < 0 {
+			diff = -diff
+		}
+		return 1 - diff
+	}
+	if got := voteReplicas(results, hashesOf(results), 2, near, 0.95); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Errorf("expected replicas 0 and 1 to agree, got %v", got)
+	}
+	if got := voteReplicas(results, hashesOf(results), 2, nil, 1); got != nil {
+		t.Errorf("expected no agreement without a similarity function, got %v", got)
+	}
+}
+
+func TestReplicatedTaskPaysAgreeingAgents(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	queue := NewTaskQueue(ctx, 10, logger)
+	executor := &answeringExecutor{answers: map[string]interface{}{
+		"did:agent:1": 42.0,
+		"did:agent:2": 42.0,
+		"did:agent:3": 41.0,
+	}}
+	chain := &sharingChain{
+		splittingChain: &splittingChain{partial: make(chan float64, 1), refunds: make(chan string, 1)},
+		shares:         make(chan map[string]float64, 1),
+	}
+	rm := reputation.NewReputationManager(nil, logger)
+
+	o := NewOrchestrator(ctx, queue, agentPool("did:agent:1", "did:agent:2", "did:agent:3"), executor, &OrchestratorConfig{NumWorkers: 1}, logger)
+	o.paymentManager = NewPaymentLifecycleManager(chain, DefaultPaymentConfig(), logger)
+	o.SetReputationManager(rm)
+	recorder := &resultRecorder{results: make(chan *TaskResult, 1)}
+	o.AddResultObserver(recorder)
+	if err := o.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer o.Stop()
+
+	task := NewTask("did:user:1", "test", []string{"test"}, nil)
+	task.Budget = 10
+	task.Replication = &ReplicationPolicy{Factor: 3}
+	if err := queue.Enqueue(task); err != nil {
+		t.Fatal(err)
+	}
+
+	var result *TaskResult
+	select {
+	case result = <-recorder.results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never finished")
+	}
+	if result.Status != TaskStatusCompleted || result.Result["answer"] != 42.0 {
+		t.Fatalf("expected the majority answer, got %+v", result)
+	}
+	if result.Cost != 8 {
+		t.Errorf("expected the two agreeing agents' costs, got %v", result.Cost)
+	}
+
+	select {
+	case shares := <-chain.shares:
+		if len(shares) != 2 || shares["did:agent:1"] != 4 || shares["did:agent:2"] != 4 {
+			t.Errorf("unexpected shares %v", shares)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("payment was never split")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		score, err := rm.GetScore(peer.ID("did:agent:3"))
+		if err == nil {
+			if score.TasksFailed != 1 {
+				t.Errorf("expected the dissenting agent to be marked failed, got %+v", score)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("dissenting agent was never reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if score, err := rm.GetScore(peer.ID("did:agent:1")); err != nil || score.TasksCompleted != 1 {
+		t.Errorf("expected the agreeing agent to be marked successful, got %+v, %v", score, err)
+	}
+}
+
+func TestReplicatedTaskWithoutQuorumFails(t *testing.T) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	executor := &answeringExecutor{answers: map[string]interface{}{
+		"did:agent:1": 1.0,
+		"did:agent:2": 2.0,
+	}}
+	o := NewOrchestrator(ctx, NewTaskQueue(ctx, 10, logger), agentPool("did:agent:1", "did:agent:2", "did:agent:3"), executor, nil, logger)
+	w := &worker{orchestrator: o, logger: logger}
+
+	task := NewTask("did:user:1", "test", []string{"test"}, nil)
+	task.Replication = &ReplicationPolicy{Factor: 3}
+
+	result, _, err := w.executeReplicated(ctx, task, &identity.AgentCard{DID: "did:agent:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != TaskStatusFailed || result.Cost != 0 {
+		t.Errorf("expected an unpaid failure, got %+v", result)
+	}
+	if len(task.Replicas) != 3 || task.Replicas[2].Error == "" {
+		t.Errorf("expected the unreachable replica to be recorded, got %+v", task.Replicas)
+	}
+
+	// Too few agents to ever reach the quorum
+	o.selector = agentPool("did:agent:1")
+	if _, _, err := w.executeReplicated(ctx, task, &identity.AgentCard{DID: "did:agent:1"}); !errors.Is(err, ErrNoSuitableAgent) {
+		t.Errorf("expected ErrNoSuitableAgent, got %v", err)
+	}
+}
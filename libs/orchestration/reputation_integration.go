@@ -253,3 +253,32 @@ func (o *Orchestrator) GetReputationCircuitBreakerStats() map[string]interface{}
 		"timeout":      ReputationTimeout.String(),
 	}
 }
+
+// ReportBidViolation implements BidViolationReporter by recording a failed
+// outcome against an agent that broke a sealed-bid commitment.
+func (o *Orchestrator) ReportBidViolation(ctx context.Context, violation BidViolation) {
+	if o.blockchain == nil {
+		return
+	}
+
+	w := &worker{orchestrator: o, logger: o.logger}
+	agentAccount, err := w.convertDIDToAccountID(string(violation.AgentDID))
+	if err != nil {
+		o.logger.Warn("failed to convert agent DID to account ID",
+			zap.String("cfp_id", violation.CFPID),
+			zap.String("agent_did", string(violation.AgentDID)),
+			zap.Error(err),
+		)
+		return
+	}
+
+	task := &Task{ID: violation.CFPID}
+	if err := o.ReportTaskOutcomeToBlockchain(ctx, o.blockchain, task, agentAccount, false); err != nil {
+		o.logger.Warn("failed to report bid violation",
+			zap.String("cfp_id", violation.CFPID),
+			zap.String("agent_did", string(violation.AgentDID)),
+			zap.String("kind", string(violation.Kind)),
+			zap.Error(err),
+		)
+	}
+}
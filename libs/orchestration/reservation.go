@@ -0,0 +1,500 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MessageTypeReservation notifies a runtime of a change to one of its reservations
+const MessageTypeReservation = "AACL-Reservation-v1"
+
+// DefaultNoShowFee is the share of an unused slot's price a requester pays the
+// agent that held the capacity
+const DefaultNoShowFee = 0.5
+
+// Reservation errors
+var (
+	ErrInvalidReservation    = errors.New("invalid reservation")
+	ErrReservationNotFound   = errors.New("reservation not found")
+	ErrReservationTransition = errors.New("invalid reservation state transition")
+)
+
+// ReservationStatus is the lifecycle state of a reservation
+type ReservationStatus string
+
+const (
+	ReservationStatusPending   ReservationStatus = "pending"   // Escrowed, awaiting the agent's confirmation
+	ReservationStatusConfirmed ReservationStatus = "confirmed" // Agent holds the capacity
+	ReservationStatusSettled   ReservationStatus = "settled"   // Window closed and escrow split
+	ReservationStatusCancelled ReservationStatus = "cancelled" // Withdrawn before the window, fully refunded
+	ReservationStatusExpired   ReservationStatus = "expired"   // Never confirmed, fully refunded
+)
+
+// Reservation books up to Slots tasks of a capability on one agent during
+// [WindowStart, WindowEnd) at a fixed price per slot, at most Concurrency at
+// a time. The full price is escrowed when booking.
+type Reservation struct {
+	ID           string            `json:"id"`
+	UserID       string            `json:"user_id"`
+	AgentDID     agentcard.DID     `json:"agent_did"`
+	Capability   string            `json:"capability"`
+	Slots        int               `json:"slots"`
+	Concurrency  int               `json:"concurrency"`
+	PricePerSlot float64           `json:"price_per_slot"`
+	WindowStart  time.Time         `json:"window_start"`
+	WindowEnd    time.Time         `json:"window_end"`
+	Status       ReservationStatus `json:"status"`
+	EscrowID     string            `json:"escrow_id,omitempty"`
+
+	Claimed      int `json:"claimed"`        // Tasks allocated from the reservation
+	InFlight     int `json:"in_flight"`      // Claimed tasks still executing
+	Completed    int `json:"completed"`      // Claimed tasks the agent delivered
+	AgentNoShows int `json:"agent_no_shows"` // Claimed tasks the agent failed
+
+	CreatedAt time.Time  `json:"created_at"`
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+}
+
+// Total returns the escrowed amount
+func (r *Reservation) Total() float64 {
+	return float64(r.Slots) * r.PricePerSlot
+}
+
+// ReservationSettlement splits a reservation's escrow once its window closes.
+// Delivered slots are paid in full, slots the requester never used pay the
+// no-show fee, and slots the agent failed are refunded.
+type ReservationSettlement struct {
+	ReservationID string        `json:"reservation_id"`
+	EscrowID      string        `json:"escrow_id"`
+	UserID        string        `json:"user_id"`
+	AgentDID      agentcard.DID `json:"agent_did"`
+	Total         float64       `json:"total"`
+	AgentAmount   float64       `json:"agent_amount"`
+	RefundAmount  float64       `json:"refund_amount"`
+	Completed     int           `json:"completed"`
+	Unused        int           `json:"unused"`
+	AgentNoShows  int           `json:"agent_no_shows"`
+}
+
+// ReservationEscrow backs reservations with escrowed funds
+type ReservationEscrow interface {
+	// LockReservation escrows the reservation's total and returns the escrow ID
+	LockReservation(ctx context.Context, r *Reservation) (string, error)
+
+	// SettleReservation pays out a settlement; a zero AgentAmount is a full refund
+	SettleReservation(ctx context.Context, s *ReservationSettlement) error
+}
+
+// ReservationPolicy tunes no-show penalties
+type ReservationPolicy struct {
+	// NoShowFee is the share of an unused slot's price paid to the agent.
+	// Zero uses DefaultNoShowFee; negative disables the fee.
+	NoShowFee float64
+
+	// SettleGrace delays settlement after the window closes so claimed tasks
+	// can finish
+	SettleGrace time.Duration
+}
+
+// ReservationBook holds capacity reservations and allocates tasks to them
+// ahead of the spot market. It is safe for concurrent use.
+type ReservationBook struct {
+	mu           sync.Mutex
+	reservations map[string]*Reservation
+	tasks        map[string]string // Task ID → reservation ID
+	escrow       ReservationEscrow
+	reporter     BidViolationReporter
+	gossip       *p2p.GossipService
+	policy       ReservationPolicy
+	now          func() time.Time
+	logger       *zap.Logger
+}
+
+// NewReservationBook creates a reservation book. The escrow is required; the
+// reporter, which penalizes agents for no-shows, may be nil.
+func NewReservationBook(escrow ReservationEscrow, reporter BidViolationReporter, policy ReservationPolicy, logger *zap.Logger) *ReservationBook {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if policy.NoShowFee == 0 {
+		policy.NoShowFee = DefaultNoShowFee
+	}
+	policy.NoShowFee = math.Max(0, math.Min(policy.NoShowFee, 1))
+
+	return &ReservationBook{
+		reservations: make(map[string]*Reservation),
+		tasks:        make(map[string]string),
+		escrow:       escrow,
+		reporter:     reporter,
+		policy:       policy,
+		now:          time.Now,
+		logger:       logger,
+	}
+}
+
+// SetGossip publishes reservation changes to the agents holding the capacity
+func (rb *ReservationBook) SetGossip(gossip *p2p.GossipService) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.gossip = gossip
+}
+
+// Book escrows and records a reservation request. The agent must confirm it
+// before the window opens.
+func (rb *ReservationBook) Book(ctx context.Context, r Reservation) (*Reservation, error) {
+	now := rb.now()
+	if r.Concurrency <= 0 {
+		r.Concurrency = 1
+	}
+
+	switch {
+	case r.UserID == "" || r.AgentDID == "" || r.Capability == "":
+		return nil, fmt.Errorf("%w: user, agent and capability are required", ErrInvalidReservation)
+	case r.Slots <= 0:
+		return nil, fmt.Errorf("%w: slots must be positive", ErrInvalidReservation)
+	case r.PricePerSlot <= 0 || math.IsNaN(r.PricePerSlot) || math.IsInf(r.PricePerSlot, 0):
+		return nil, fmt.Errorf("%w: price per slot must be positive", ErrInvalidReservation)
+	case !r.WindowStart.After(now):
+		return nil, fmt.Errorf("%w: window must start in the future", ErrInvalidReservation)
+	case !r.WindowEnd.After(r.WindowStart):
+		return nil, fmt.Errorf("%w: window must end after it starts", ErrInvalidReservation)
+	}
+
+	r.ID = uuid.New().String()
+	r.Status = ReservationStatusPending
+	r.CreatedAt = now
+	r.Claimed, r.InFlight, r.Completed, r.AgentNoShows = 0, 0, 0, 0
+	r.SettledAt = nil
+
+	escrowID, err := rb.escrow.LockReservation(ctx, &r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to escrow reservation: %w", err)
+	}
+	r.EscrowID = escrowID
+
+	rb.mu.Lock()
+	stored := r
+	rb.reservations[r.ID] = &stored
+	rb.mu.Unlock()
+	booked := r
+
+	rb.logger.Info("reservation booked",
+		zap.String("reservation_id", r.ID),
+		zap.String("user_id", r.UserID),
+		zap.String("agent_did", string(r.AgentDID)),
+		zap.String("capability", r.Capability),
+		zap.Int("slots", r.Slots),
+		zap.Float64("total", r.Total()),
+	)
+	rb.notify(&booked)
+
+	return &r, nil
+}
+
+// Confirm records the agent's acceptance of a pending reservation
+func (rb *ReservationBook) Confirm(id string, agentDID agentcard.DID) (*Reservation, error) {
+	rb.mu.Lock()
+	r, ok := rb.reservations[id]
+	if !ok || r.AgentDID != agentDID {
+		rb.mu.Unlock()
+		return nil, ErrReservationNotFound
+	}
+	if r.Status != ReservationStatusPending || !rb.now().Before(r.WindowStart) {
+		rb.mu.Unlock()
+		return nil, fmt.Errorf("%w: reservation is %s", ErrReservationTransition, r.Status)
+	}
+	r.Status = ReservationStatusConfirmed
+	confirmed := *r
+	rb.mu.Unlock()
+
+	rb.notify(&confirmed)
+	return &confirmed, nil
+}
+
+// Cancel withdraws a reservation before its window opens and refunds the
+// escrow in full. Once the window is open the reservation runs to settlement.
+func (rb *ReservationBook) Cancel(ctx context.Context, id, userID string) (*Reservation, error) {
+	rb.mu.Lock()
+	r, ok := rb.reservations[id]
+	if !ok || r.UserID != userID {
+		rb.mu.Unlock()
+		return nil, ErrReservationNotFound
+	}
+	if (r.Status != ReservationStatusPending && r.Status != ReservationStatusConfirmed) || !rb.now().Before(r.WindowStart) {
+		rb.mu.Unlock()
+		return nil, fmt.Errorf("%w: only open reservations can be cancelled before their window", ErrReservationTransition)
+	}
+	rb.mu.Unlock()
+
+	return rb.close(ctx, id, ReservationStatusCancelled)
+}
+
+// Get returns a copy of a reservation
+func (rb *ReservationBook) Get(id string) (*Reservation, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	r, ok := rb.reservations[id]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	copied := *r
+	return &copied, nil
+}
+
+// List returns copies of a user's or agent's reservations, newest first.
+// Empty filters match everything.
+func (rb *ReservationBook) List(userID string, agentDID agentcard.DID) []*Reservation {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	var list []*Reservation
+	for _, r := range rb.reservations {
+		if (userID == "" || r.UserID == userID) && (agentDID == "" || r.AgentDID == agentDID) {
+			copied := *r
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Claim allocates a task to a matching reservation of its owner: confirmed,
+// inside its window, for the task's primary capability, with slots and
+// concurrency to spare. It returns nil when no reservation applies.
+func (rb *ReservationBook) Claim(task *Task) *Reservation {
+	if len(task.Capabilities) == 0 || task.UserID == "" {
+		return nil
+	}
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if id, ok := rb.tasks[task.ID]; ok {
+		copied := *rb.reservations[id]
+		return &copied
+	}
+
+	now := rb.now()
+	var best *Reservation
+	for _, r := range rb.reservations {
+		if r.Status != ReservationStatusConfirmed || r.UserID != task.UserID || r.Capability != task.Capabilities[0] {
+			continue
+		}
+		if now.Before(r.WindowStart) || !now.Before(r.WindowEnd) {
+			continue
+		}
+		if r.Claimed >= r.Slots || r.InFlight >= r.Concurrency {
+			continue
+		}
+		// Use up the reservation that closes first
+		if best == nil || r.WindowEnd.Before(best.WindowEnd) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	best.Claimed++
+	best.InFlight++
+	rb.tasks[task.ID] = best.ID
+
+	copied := *best
+	return &copied
+}
+
+// RecordOutcome releases a claimed task's slot. Failed tasks count as agent
+// no-shows: they are refunded at settlement and reported to the reporter.
+func (rb *ReservationBook) RecordOutcome(ctx context.Context, taskID string, delivered bool) {
+	rb.mu.Lock()
+	id, ok := rb.tasks[taskID]
+	if !ok {
+		rb.mu.Unlock()
+		return
+	}
+	delete(rb.tasks, taskID)
+
+	r := rb.reservations[id]
+	if r.InFlight > 0 {
+		r.InFlight--
+	}
+	if delivered {
+		r.Completed++
+	} else {
+		r.AgentNoShows++
+	}
+	agentDID := r.AgentDID
+	reporter := rb.reporter
+	rb.mu.Unlock()
+
+	if !delivered && reporter != nil {
+		reporter.ReportBidViolation(ctx, BidViolation{
+			CFPID:    id,
+			AgentDID: agentDID,
+			BidID:    taskID,
+			Kind:     BidViolationNoShow,
+		})
+	}
+}
+
+// Sweep expires unconfirmed reservations whose window has opened and settles
+// confirmed ones whose window (plus grace) has closed
+func (rb *ReservationBook) Sweep(ctx context.Context) {
+	now := rb.now()
+
+	rb.mu.Lock()
+	var expire, settle []string
+	for id, r := range rb.reservations {
+		switch {
+		case r.Status == ReservationStatusPending && !now.Before(r.WindowStart):
+			expire = append(expire, id)
+		case r.Status == ReservationStatusConfirmed && !now.Before(r.WindowEnd.Add(rb.policy.SettleGrace)):
+			settle = append(settle, id)
+		}
+	}
+	rb.mu.Unlock()
+
+	for _, id := range expire {
+		if _, err := rb.close(ctx, id, ReservationStatusExpired); err != nil {
+			rb.logger.Warn("failed to expire reservation", zap.String("reservation_id", id), zap.Error(err))
+		}
+	}
+	for _, id := range settle {
+		if _, err := rb.close(ctx, id, ReservationStatusSettled); err != nil {
+			rb.logger.Warn("failed to settle reservation", zap.String("reservation_id", id), zap.Error(err))
+		}
+	}
+}
+
+// Run sweeps reservations every interval until ctx is done
+func (rb *ReservationBook) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rb.Sweep(ctx)
+		}
+	}
+}
+
+// Settlement computes how a reservation's escrow would be split now
+func (rb *ReservationBook) Settlement(r *Reservation) *ReservationSettlement {
+	s := &ReservationSettlement{
+		ReservationID: r.ID,
+		EscrowID:      r.EscrowID,
+		UserID:        r.UserID,
+		AgentDID:      r.AgentDID,
+		Total:         r.Total(),
+		Completed:     r.Completed,
+		AgentNoShows:  r.AgentNoShows,
+	}
+
+	// Tasks still running at settlement are paid as delivered
+	s.Completed += r.InFlight
+	s.Unused = r.Slots - r.Claimed
+	if s.Unused < 0 {
+		s.Unused = 0
+	}
+
+	s.AgentAmount = float64(s.Completed)*r.PricePerSlot + float64(s.Unused)*r.PricePerSlot*rb.policy.NoShowFee
+	s.AgentAmount = math.Min(s.AgentAmount, s.Total)
+	s.RefundAmount = s.Total - s.AgentAmount
+	return s
+}
+
+// close settles or refunds a reservation's escrow and moves it to status
+func (rb *ReservationBook) close(ctx context.Context, id string, status ReservationStatus) (*Reservation, error) {
+	rb.mu.Lock()
+	r, ok := rb.reservations[id]
+	if !ok {
+		rb.mu.Unlock()
+		return nil, ErrReservationNotFound
+	}
+	if r.Status != ReservationStatusPending && r.Status != ReservationStatusConfirmed {
+		rb.mu.Unlock()
+		return nil, fmt.Errorf("%w: reservation is %s", ErrReservationTransition, r.Status)
+	}
+
+	var settlement *ReservationSettlement
+	if status == ReservationStatusSettled {
+		settlement = rb.Settlement(r)
+	} else {
+		settlement = &ReservationSettlement{
+			ReservationID: r.ID,
+			EscrowID:      r.EscrowID,
+			UserID:        r.UserID,
+			AgentDID:      r.AgentDID,
+			Total:         r.Total(),
+			RefundAmount:  r.Total(),
+		}
+	}
+	rb.mu.Unlock()
+
+	if err := rb.escrow.SettleReservation(ctx, settlement); err != nil {
+		return nil, fmt.Errorf("failed to settle reservation escrow: %w", err)
+	}
+
+	rb.mu.Lock()
+	now := rb.now()
+	r.Status = status
+	r.SettledAt = &now
+	closed := *r
+	rb.mu.Unlock()
+
+	rb.logger.Info("reservation closed",
+		zap.String("reservation_id", id),
+		zap.String("status", string(status)),
+		zap.Float64("agent_amount", settlement.AgentAmount),
+		zap.Float64("refund_amount", settlement.RefundAmount),
+		zap.Int("unused", settlement.Unused),
+		zap.Int("agent_no_shows", settlement.AgentNoShows),
+	)
+	rb.notify(&closed)
+
+	return &closed, nil
+}
+
+// notify publishes a reservation change to its agent's reservation topic
+func (rb *ReservationBook) notify(r *Reservation) {
+	rb.mu.Lock()
+	gossip := rb.gossip
+	rb.mu.Unlock()
+	if gossip == nil {
+		return
+	}
+
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	topic := fmt.Sprintf("ainur/v1/market/reservations/%s", r.AgentDID)
+	msg := &p2p.GossipMessage{
+		Type:      MessageTypeReservation,
+		Payload:   payload,
+		Timestamp: rb.now().Unix(),
+		PeerID:    "orchestrator",
+	}
+	if err := gossip.Publish(topic, msg); err != nil {
+		rb.logger.Warn("failed to publish reservation update",
+			zap.String("reservation_id", r.ID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,163 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordingReservationEscrow struct {
+	locked      int
+	settlements []*ReservationSettlement
+}
+
+func (e *recordingReservationEscrow) LockReservation(ctx context.Context, r *Reservation) (string, error) {
+	e.locked++
+	return "escrow-" + r.ID, nil
+}
+
+func (e *recordingReservationEscrow) SettleReservation(ctx context.Context, s *ReservationSettlement) error {
+	e.settlements = append(e.settlements, s)
+	return nil
+}
+
+func newTestReservationBook(t *testing.T, now *time.Time) (*ReservationBook, *recordingReservationEscrow, *recordingReporter) {
+	t.Helper()
+	escrow := &recordingReservationEscrow{}
+	reporter := &recordingReporter{}
+	rb := NewReservationBook(escrow, reporter, ReservationPolicy{NoShowFee: 0.5}, nil)
+	rb.now = func() time.Time { return *now }
+	return rb, escrow, reporter
+}
+
+func bookTestReservation(t *testing.T, rb *ReservationBook, now time.Time, slots, concurrency int) *Reservation {
+	t.Helper()
+	r, err := rb.Book(context.Background(), Reservation{
+		UserID:       "user-1",
+		AgentDID:     "did:key:agent",
+		Capability:   "math.add",
+		Slots:        slots,
+		Concurrency:  concurrency,
+		PricePerSlot: 10,
+		WindowStart:  now.Add(time.Hour),
+		WindowEnd:    now.Add(2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to book reservation: %v", err)
+	}
+	return r
+}
+
+func reservedTask(id string) *Task {
+	return &Task{ID: id, UserID: "user-1", Capabilities: []string{"math.add"}}
+}
+
+func TestReservationClaimHonorsWindowAndConcurrency(t *testing.T) {
+	now := time.Now()
+	rb, escrow, _ := newTestReservationBook(t, &now)
+	r := bookTestReservation(t, rb, now, 2, 1)
+
+	if escrow.locked != 1 {
+		t.Fatalf("expected the reservation to be escrowed, got %d locks", escrow.locked)
+	}
+	if _, err := rb.Confirm(r.ID, "did:key:other"); !errors.Is(err, ErrReservationNotFound) {
+		t.Fatalf("expected another agent's confirm to fail, got %v", err)
+	}
+	if _, err := rb.Confirm(r.ID, "did:key:agent"); err != nil {
+		t.Fatalf("failed to confirm: %v", err)
+	}
+
+	if rb.Claim(reservedTask("t1")) != nil {
+		t.Fatal("expected no claim before the window opens")
+	}
+
+	now = now.Add(90 * time.Minute)
+	if claimed := rb.Claim(reservedTask("t1")); claimed == nil || claimed.ID != r.ID {
+		t.Fatalf("expected t1 to claim the reservation, got %+v", claimed)
+	}
+	if rb.Claim(reservedTask("t2")) != nil {
+		t.Fatal("expected concurrency to block a second in-flight claim")
+	}
+	if rb.Claim(&Task{ID: "t3", UserID: "user-2", Capabilities: []string{"math.add"}}) != nil {
+		t.Fatal("expected another user's task not to claim the reservation")
+	}
+
+	rb.RecordOutcome(context.Background(), "t1", true)
+	if rb.Claim(reservedTask("t2")) == nil {
+		t.Fatal("expected a claim once the first task finished")
+	}
+	rb.RecordOutcome(context.Background(), "t2", true)
+	if rb.Claim(reservedTask("t4")) != nil {
+		t.Fatal("expected no claim once every slot is used")
+	}
+}
+
+func TestReservationSettlementChargesNoShows(t *testing.T) {
+	now := time.Now()
+	rb, escrow, reporter := newTestReservationBook(t, &now)
+	r := bookTestReservation(t, rb, now, 4, 2)
+	if _, err := rb.Confirm(r.ID, "did:key:agent"); err != nil {
+		t.Fatalf("failed to confirm: %v", err)
+	}
+
+	now = now.Add(90 * time.Minute)
+	rb.Claim(reservedTask("t1"))
+	rb.Claim(reservedTask("t2"))
+	rb.RecordOutcome(context.Background(), "t1", true)
+	rb.RecordOutcome(context.Background(), "t2", false)
+
+	if len(reporter.violations) != 1 || reporter.violations[0].Kind != BidViolationNoShow {
+		t.Fatalf("expected one no-show violation, got %+v", reporter.violations)
+	}
+
+	now = now.Add(time.Hour)
+	rb.Sweep(context.Background())
+
+	if len(escrow.settlements) != 1 {
+		t.Fatalf("expected one settlement, got %d", len(escrow.settlements))
+	}
+	s := escrow.settlements[0]
+	// One delivered slot in full, two unused slots at half price, the failed slot refunded
+	if s.AgentAmount != 20 || s.RefundAmount != 20 {
+		t.Fatalf("expected a 20/20 split, got %v/%v", s.AgentAmount, s.RefundAmount)
+	}
+	if s.Unused != 2 || s.AgentNoShows != 1 {
+		t.Fatalf("expected 2 unused and 1 no-show, got %d and %d", s.Unused, s.AgentNoShows)
+	}
+
+	settled, _ := rb.Get(r.ID)
+	if settled.Status != ReservationStatusSettled {
+		t.Fatalf("expected settled status, got %s", settled.Status)
+	}
+}
+
+func TestReservationCancelAndExpiryRefund(t *testing.T) {
+	now := time.Now()
+	rb, escrow, _ := newTestReservationBook(t, &now)
+	cancelled := bookTestReservation(t, rb, now, 2, 1)
+	unconfirmed := bookTestReservation(t, rb, now, 2, 1)
+
+	if _, err := rb.Cancel(context.Background(), cancelled.ID, "user-2"); !errors.Is(err, ErrReservationNotFound) {
+		t.Fatalf("expected another user's cancel to fail, got %v", err)
+	}
+	if _, err := rb.Cancel(context.Background(), cancelled.ID, "user-1"); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	rb.Sweep(context.Background())
+
+	expired, _ := rb.Get(unconfirmed.ID)
+	if expired.Status != ReservationStatusExpired {
+		t.Fatalf("expected the unconfirmed reservation to expire, got %s", expired.Status)
+	}
+	if len(escrow.settlements) != 2 {
+		t.Fatalf("expected two refunds, got %d", len(escrow.settlements))
+	}
+	for _, s := range escrow.settlements {
+		if s.AgentAmount != 0 || s.RefundAmount != 20 {
+			t.Fatalf("expected a full refund, got %+v", s)
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package orchestration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/p2p"
+	"go.uber.org/zap"
+)
+
+// Sealed-bid message types
+const (
+	MessageTypeBidCommit  = "AACL-Bid-Commit-v1"
+	MessageTypeBidReveal  = "AACL-Bid-Reveal-v1"
+	MessageTypeRevealOpen = "AACL-Reveal-Open-v1"
+)
+
+// Sealed-bid errors
+var (
+	ErrInsufficientBond = errors.New("bond below minimum")
+	ErrInvalidCommit    = errors.New("invalid bid commitment")
+	ErrInvalidReveal    = errors.New("invalid bid reveal")
+)
+
+// BidViolationKind classifies how a bidder broke a commitment
+type BidViolationKind string
+
+const (
+	BidViolationUnrevealed BidViolationKind = "unrevealed" // Committed but never revealed
+	BidViolationMismatch   BidViolationKind = "mismatch"   // Reveal did not hash to the commitment
+	BidViolationNoShow     BidViolationKind = "no_show"    // Failed a task from a capacity reservation
+)
+
+// BidViolation records a forfeited commitment
+type BidViolation struct {
+	CFPID    string
+	AgentDID agentcard.DID
+	BidID    string
+	Kind     BidViolationKind
+	Bond     float64
+}
+
+// BondManager locks the bond attached to a bid commitment until the auction
+// settles. Honest bidders get their bond back; violators forfeit it.
+type BondManager interface {
+	LockBond(ctx context.Context, agentDID, cfpID string, amount float64) error
+	ReleaseBond(ctx context.Context, agentDID, cfpID string) error
+	ForfeitBond(ctx context.Context, agentDID, cfpID string) error
+}
+
+// BidViolationReporter receives forfeited commitments, e.g. to penalize reputation
+type BidViolationReporter interface {
+	ReportBidViolation(ctx context.Context, violation BidViolation)
+}
+
+// SealedBidConfig enables commit-reveal bidding on an Auctioneer. Bidders
+// publish a hash commitment with a bond during the auction window, then reveal
+// the bid during the reveal window. Only revealed bids that match their
+// commitment are considered, so no bidder can see a competitor's price before
+// committing to its own.
+type SealedBidConfig struct {
+	RevealWindow time.Duration        // Defaults to the auction (commit) window
+	MinBond      float64              // Minimum bond per commitment
+	Bonds        BondManager          // Optional; without it bonds are declared but not locked
+	Reporter     BidViolationReporter // Optional
+}
+
+// ComputeBidCommitment returns the commitment a bidder publishes for a sealed
+// bid: hex(sha256(cfpID|agentDID|price|etaMS|nonce)). The nonce must be random
+// and kept secret until the reveal.
+func ComputeBidCommitment(cfpID, agentDID string, price float64, etaMS int64, nonce string) string {
+	preimage := cfpID + "|" + agentDID + "|" +
+		strconv.FormatFloat(price, 'f', -1, 64) + "|" +
+		strconv.FormatInt(etaMS, 10) + "|" + nonce
+	sum := sha256.Sum256([]byte(preimage))
+	return hex.EncodeToString(sum[:])
+}
+
+// EnableSealedBids switches the auctioneer to commit-reveal bidding
+func (a *Auctioneer) EnableSealedBids(cfg SealedBidConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sealed = &cfg
+}
+
+// sealedConfig returns the sealed-bid configuration, or nil for open bidding
+func (a *Auctioneer) sealedConfig() *SealedBidConfig {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.sealed
+}
+
+// sealedCommitment is one bidder's commitment within a sealed round
+type sealedCommitment struct {
+	bidID      string
+	agentDID   string
+	commitment string
+	bond       float64
+	revealed   *BidSummary
+	violation  BidViolationKind
+}
+
+// sealedRound collects the commitments and reveals of one sealed auction
+type sealedRound struct {
+	auctioneer *Auctioneer
+	cfpID      string
+	cfg        SealedBidConfig
+
+	mu        sync.Mutex
+	revealing bool
+	commits   map[string]*sealedCommitment // agent DID -> commitment
+}
+
+func newSealedRound(a *Auctioneer, cfpID string, cfg SealedBidConfig) *sealedRound {
+	return &sealedRound{
+		auctioneer: a,
+		cfpID:      cfpID,
+		cfg:        cfg,
+		commits:    make(map[string]*sealedCommitment),
+	}
+}
+
+// addCommit validates a signed commitment and locks its bond
+func (r *sealedRound) addCommit(ctx context.Context, msg map[string]interface{}) error {
+	if msgType, _ := msg["message_type"].(string); msgType != MessageTypeBidCommit {
+		return fmt.Errorf("%w: unexpected message type %q", ErrInvalidCommit, msgType)
+	}
+	if cfpID, _ := msg["cfp_id"].(string); cfpID != r.cfpID {
+		return fmt.Errorf("%w: commitment for another CFP", ErrInvalidCommit)
+	}
+	if err := r.auctioneer.verifyBidSignature(msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCommit, err)
+	}
+
+	fromDID, _ := msg["from"].(string)
+	bidID, _ := msg["bid_id"].(string)
+	commitment, _ := msg["commitment"].(string)
+	if decoded, err := hex.DecodeString(commitment); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("%w: commitment must be a hex SHA-256 digest", ErrInvalidCommit)
+	}
+
+	bondMap, _ := msg["bond"].(map[string]interface{})
+	bond, _ := bondMap["amount"].(float64)
+	if bond < r.cfg.MinBond {
+		return fmt.Errorf("%w: %.4f < %.4f", ErrInsufficientBond, bond, r.cfg.MinBond)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.revealing {
+		return fmt.Errorf("%w: commit phase is over", ErrInvalidCommit)
+	}
+	if _, exists := r.commits[fromDID]; exists {
+		return fmt.Errorf("%w: bidder already committed", ErrInvalidCommit)
+	}
+
+	if r.cfg.Bonds != nil {
+		if err := r.cfg.Bonds.LockBond(ctx, fromDID, r.cfpID, bond); err != nil {
+			return fmt.Errorf("failed to lock bond: %w", err)
+		}
+	}
+
+	r.commits[fromDID] = &sealedCommitment{
+		bidID:      bidID,
+		agentDID:   fromDID,
+		commitment: commitment,
+		bond:       bond,
+	}
+	return nil
+}
+
+// startReveal closes the commit phase and returns the accepted bid IDs
+func (r *sealedRound) startReveal() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revealing = true
+	bidIDs := make([]string, 0, len(r.commits))
+	for _, c := range r.commits {
+		bidIDs = append(bidIDs, c.bidID)
+	}
+	return bidIDs
+}
+
+// addReveal checks a signed reveal against the bidder's commitment. The first
+// reveal is final: a mismatch forfeits the bond.
+func (r *sealedRound) addReveal(msg map[string]interface{}) error {
+	if msgType, _ := msg["message_type"].(string); msgType != MessageTypeBidReveal {
+		return fmt.Errorf("%w: unexpected message type %q", ErrInvalidReveal, msgType)
+	}
+	if err := r.auctioneer.verifyBidSignature(msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReveal, err)
+	}
+
+	summary := bidSummaryFromMessage(msg)
+	nonce, _ := msg["nonce"].(string)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.revealing {
+		return fmt.Errorf("%w: reveal phase has not started", ErrInvalidReveal)
+	}
+	c, exists := r.commits[string(summary.AgentDID)]
+	if !exists {
+		return fmt.Errorf("%w: no commitment from bidder", ErrInvalidReveal)
+	}
+	if c.revealed != nil || c.violation != "" {
+		return fmt.Errorf("%w: bidder already revealed", ErrInvalidReveal)
+	}
+
+	expected := ComputeBidCommitment(r.cfpID, c.agentDID, summary.Price, summary.ETAms, nonce)
+	if expected != c.commitment || summary.BidID != c.bidID {
+		c.violation = BidViolationMismatch
+		return fmt.Errorf("%w: reveal does not match commitment", ErrInvalidReveal)
+	}
+
+	c.revealed = &summary
+	return nil
+}
+
+// settle releases the bonds of honest bidders, forfeits the rest and returns
+// the valid bids together with the violations
+func (r *sealedRound) settle(ctx context.Context) ([]BidSummary, []BidViolation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var bids []BidSummary
+	var violations []BidViolation
+	logger := r.auctioneer.logger
+
+	for _, c := range r.commits {
+		if c.revealed != nil {
+			bids = append(bids, *c.revealed)
+			if r.cfg.Bonds != nil {
+				if err := r.cfg.Bonds.ReleaseBond(ctx, c.agentDID, r.cfpID); err != nil {
+					logger.Error("failed to release bid bond",
+						zap.String("cfp_id", r.cfpID),
+						zap.String("agent_did", c.agentDID),
+						zap.Error(err),
+					)
+				}
+			}
+			continue
+		}
+
+		kind := c.violation
+		if kind == "" {
+			kind = BidViolationUnrevealed
+		}
+		violation := BidViolation{
+			CFPID:    r.cfpID,
+			AgentDID: agentcard.DID(c.agentDID),
+			BidID:    c.bidID,
+			Kind:     kind,
+			Bond:     c.bond,
+		}
+		violations = append(violations, violation)
+
+		logger.Warn("sealed bid commitment forfeited",
+			zap.String("cfp_id", r.cfpID),
+			zap.String("agent_did", c.agentDID),
+			zap.String("kind", string(kind)),
+			zap.Float64("bond", c.bond),
+		)
+
+		if r.cfg.Bonds != nil {
+			if err := r.cfg.Bonds.ForfeitBond(ctx, c.agentDID, r.cfpID); err != nil {
+				logger.Error("failed to forfeit bid bond",
+					zap.String("cfp_id", r.cfpID),
+					zap.String("agent_did", c.agentDID),
+					zap.Error(err),
+				)
+			}
+		}
+		if r.cfg.Reporter != nil {
+			r.cfg.Reporter.ReportBidViolation(ctx, violation)
+		}
+	}
+
+	return bids, violations
+}
+
+// abort closes an interrupted round, returning every bond: bidders are not
+// penalized for a reveal phase that never finished
+func (r *sealedRound) abort(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revealing = true
+	if r.cfg.Bonds == nil {
+		return
+	}
+	// Bonds are returned even though ctx is done
+	ctx = context.WithoutCancel(ctx)
+	for _, c := range r.commits {
+		if err := r.cfg.Bonds.ReleaseBond(ctx, c.agentDID, r.cfpID); err != nil {
+			r.auctioneer.logger.Error("failed to release bid bond",
+				zap.String("cfp_id", r.cfpID),
+				zap.String("agent_did", c.agentDID),
+				zap.Error(err),
+			)
+		}
+	}
+	r.commits = make(map[string]*sealedCommitment)
+}
+
+// waitWindow waits out an auction phase, returning ctx's error if the task
+// is canceled or the orchestrator stops first
+func waitWindow(ctx context.Context, window time.Duration) error {
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startSealedAuction runs the commit and reveal phases for a CFP. The commit
+// phase lasts for window; the reveal phase for cfg.RevealWindow.
+func (a *Auctioneer) startSealedAuction(
+	ctx context.Context,
+	task *Task,
+	logic SelectionLogic,
+	window time.Duration,
+	cfg SealedBidConfig,
+	cfpTopic string,
+	payload map[string]interface{},
+) (*AuctionResult, error) {
+	if cfg.RevealWindow == 0 {
+		cfg.RevealWindow = window
+	}
+
+	commitTopic := fmt.Sprintf("ainur/v1/market/commit/%s", task.ID)
+	revealTopic := fmt.Sprintf("ainur/v1/market/reveal/%s", task.ID)
+
+	payload["sealed_bid"] = map[string]interface{}{
+		"commit_topic":     commitTopic,
+		"reveal_topic":     revealTopic,
+		"commit_window_ms": int64(window / time.Millisecond),
+		"reveal_window_ms": int64(cfg.RevealWindow / time.Millisecond),
+		"min_bond":         cfg.MinBond,
+		"commitment":       "sha256(cfp_id|from|price|estimated_duration_ms|nonce)",
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to marshal CFP payload: %w", err)
+	}
+
+	round := newSealedRound(a, task.ID, cfg)
+
+	commitHandler := func(ctx context.Context, msg *p2p.GossipMessage) error {
+		var commit map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &commit); err != nil {
+			return nil
+		}
+		if err := round.addCommit(ctx, commit); err != nil {
+			a.logger.Warn("bid commitment rejected",
+				zap.String("cfp_id", task.ID),
+				zap.Error(err),
+			)
+		}
+		return nil
+	}
+
+	if err := a.gossip.Subscribe(commitTopic, commitHandler); err != nil {
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to subscribe to commit topic: %w", err)
+	}
+
+	cfpMsg := &p2p.GossipMessage{
+		Type:      "AACL-CFP-v1",
+		Payload:   data,
+		Timestamp: time.Now().Unix(),
+		PeerID:    "orchestrator",
+	}
+	if err := a.gossip.Publish(cfpTopic, cfpMsg); err != nil {
+		a.gossip.Unsubscribe(commitTopic)
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to broadcast CFP: %w", err)
+	}
+
+	a.logger.Info("sealed auction: commit phase open",
+		zap.String("cfp_id", task.ID),
+		zap.Duration("commit_window", window),
+	)
+	err = waitWindow(ctx, window)
+	a.gossip.Unsubscribe(commitTopic)
+	if err != nil {
+		round.abort(ctx)
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("sealed auction interrupted: %w", err)
+	}
+
+	bidIDs := round.startReveal()
+	if len(bidIDs) == 0 {
+		a.logger.Warn("sealed auction complete: no commitments received",
+			zap.String("cfp_id", task.ID),
+		)
+		return &AuctionResult{CFPID: task.ID, TimedOut: true}, nil
+	}
+
+	revealHandler := func(ctx context.Context, msg *p2p.GossipMessage) error {
+		if msg.Type == MessageTypeRevealOpen {
+			return nil
+		}
+		var reveal map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &reveal); err != nil {
+			return nil
+		}
+		if err := round.addReveal(reveal); err != nil {
+			a.logger.Warn("bid reveal rejected",
+				zap.String("cfp_id", task.ID),
+				zap.Error(err),
+			)
+		}
+		return nil
+	}
+
+	if err := a.gossip.Subscribe(revealTopic, revealHandler); err != nil {
+		round.settle(ctx)
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("failed to subscribe to reveal topic: %w", err)
+	}
+
+	openData, _ := json.Marshal(map[string]interface{}{
+		"message_type":    MessageTypeRevealOpen,
+		"cfp_id":          task.ID,
+		"bid_ids":         bidIDs,
+		"reveal_deadline": time.Now().Add(cfg.RevealWindow).UTC().Format(time.RFC3339Nano),
+	})
+	if err := a.gossip.Publish(revealTopic, &p2p.GossipMessage{
+		Type:      MessageTypeRevealOpen,
+		Payload:   openData,
+		Timestamp: time.Now().Unix(),
+		PeerID:    "orchestrator",
+	}); err != nil {
+		a.logger.Warn("failed to announce reveal phase",
+			zap.String("cfp_id", task.ID),
+			zap.Error(err),
+		)
+	}
+
+	a.logger.Info("sealed auction: reveal phase open",
+		zap.String("cfp_id", task.ID),
+		zap.Int("commitments", len(bidIDs)),
+		zap.Duration("reveal_window", cfg.RevealWindow),
+	)
+	err = waitWindow(ctx, cfg.RevealWindow)
+	a.gossip.Unsubscribe(revealTopic)
+	if err != nil {
+		round.abort(ctx)
+		return &AuctionResult{CFPID: task.ID}, fmt.Errorf("sealed auction interrupted: %w", err)
+	}
+
+	bids, violations := round.settle(ctx)
+	result := a.awardAuction(ctx, task.ID, bids, logic)
+	result.Violations = violations
+	return result, nil
+}
+
+// InMemoryBondManager is a BondManager backed by in-memory deposits, for
+// single-node deployments and tests
+type InMemoryBondManager struct {
+	mu        sync.Mutex
+	deposits  map[string]float64 // agent DID -> available balance
+	locked    map[string]float64 // agent DID + "|" + CFP ID -> locked bond
+	forfeited float64
+}
+
+// NewInMemoryBondManager creates an empty bond manager
+func NewInMemoryBondManager() *InMemoryBondManager {
+	return &InMemoryBondManager{
+		deposits: make(map[string]float64),
+		locked:   make(map[string]float64),
+	}
+}
+
+// Deposit adds funds an agent can post as bonds
+func (m *InMemoryBondManager) Deposit(agentDID string, amount float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deposits[agentDID] += amount
+}
+
+// Available returns an agent's unlocked balance
+func (m *InMemoryBondManager) Available(agentDID string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deposits[agentDID]
+}
+
+// Forfeited returns the total of all forfeited bonds
+func (m *InMemoryBondManager) Forfeited() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.forfeited
+}
+
+// LockBond implements BondManager
+func (m *InMemoryBondManager) LockBond(ctx context.Context, agentDID, cfpID string, amount float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := agentDID + "|" + cfpID
+	if _, exists := m.locked[key]; exists {
+		return fmt.Errorf("bond already locked for %s", cfpID)
+	}
+	if m.deposits[agentDID] < amount {
+		return fmt.Errorf("%w: available %.4f, required %.4f", ErrInsufficientBond, m.deposits[agentDID], amount)
+	}
+
+	m.deposits[agentDID] -= amount
+	m.locked[key] = amount
+	return nil
+}
+
+// ReleaseBond implements BondManager
+func (m *InMemoryBondManager) ReleaseBond(ctx context.Context, agentDID, cfpID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := agentDID + "|" + cfpID
+	amount, exists := m.locked[key]
+	if !exists {
+		return fmt.Errorf("no bond locked for %s", cfpID)
+	}
+	delete(m.locked, key)
+	m.deposits[agentDID] += amount
+	return nil
+}
+
+// ForfeitBond implements BondManager
+func (m *InMemoryBondManager) ForfeitBond(ctx context.Context, agentDID, cfpID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := agentDID + "|" + cfpID
+	amount, exists := m.locked[key]
+	if !exists {
+		return fmt.Errorf("no bond locked for %s", cfpID)
+	}
+	delete(m.locked, key)
+	m.forfeited += amount
+	return nil
+}
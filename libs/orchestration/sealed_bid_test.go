@@ -0,0 +1,225 @@
+package orchestration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/multiformats/go-multibase"
+)
+
+// testBidder signs sealed-bid messages with a did:key identity
+type testBidder struct {
+	did  string
+	priv ed25519.PrivateKey
+}
+
+func newTestBidder(t *testing.T) *testBidder {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	encoded, err := multibase.Encode(multibase.Base58BTC, pub)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	return &testBidder{did: "did:key:" + encoded, priv: priv}
+}
+
+// sign round-trips msg through JSON, as it would arrive over gossip, and adds a proof
+func (b *testBidder) sign(t *testing.T, msg map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	canonical, _ := json.Marshal(decoded)
+	decoded["proof"] = map[string]interface{}{
+		"proof_value": base64.StdEncoding.EncodeToString(ed25519.Sign(b.priv, canonical)),
+	}
+	return decoded
+}
+
+func (b *testBidder) commit(t *testing.T, cfpID, bidID string, price float64, etaMS int64, nonce string, bond float64) map[string]interface{} {
+	return b.sign(t, map[string]interface{}{
+		"message_type": MessageTypeBidCommit,
+		"cfp_id":       cfpID,
+		"bid_id":       bidID,
+		"from":         b.did,
+		"commitment":   ComputeBidCommitment(cfpID, b.did, price, etaMS, nonce),
+		"bond":         map[string]interface{}{"amount": bond},
+	})
+}
+
+func (b *testBidder) reveal(t *testing.T, cfpID, bidID string, price float64, etaMS int64, nonce string) map[string]interface{} {
+	return b.sign(t, map[string]interface{}{
+		"message_type": MessageTypeBidReveal,
+		"cfp_id":       cfpID,
+		"bid_id":       bidID,
+		"from":         b.did,
+		"nonce":        nonce,
+		"intent": map[string]interface{}{
+			"price":                 map[string]interface{}{"amount": price},
+			"estimated_duration_ms": etaMS,
+		},
+	})
+}
+
+type recordingReporter struct {
+	violations []BidViolation
+}
+
+func (r *recordingReporter) ReportBidViolation(ctx context.Context, violation BidViolation) {
+	r.violations = append(r.violations, violation)
+}
+
+func TestComputeBidCommitment(t *testing.T) {
+	base := ComputeBidCommitment("cfp-1", "did:key:zabc", 12.5, 1000, "nonce")
+	if len(base) != 64 {
+		t.Fatalf("expected 64 hex characters, got %d", len(base))
+	}
+	if base != ComputeBidCommitment("cfp-1", "did:key:zabc", 12.5, 1000, "nonce") {
+		t.Error("expected commitment to be deterministic")
+	}
+	if base == ComputeBidCommitment("cfp-1", "did:key:zabc", 12.4, 1000, "nonce") {
+		t.Error("expected price to change the commitment")
+	}
+	if base == ComputeBidCommitment("cfp-1", "did:key:zabc", 12.5, 1000, "other") {
+		t.Error("expected nonce to change the commitment")
+	}
+}
+
+func TestSealedRoundSettlement(t *testing.T) {
+	ctx := context.Background()
+	bonds := NewInMemoryBondManager()
+	reporter := &recordingReporter{}
+	round := newSealedRound(NewAuctioneer(nil, nil), "cfp-1", SealedBidConfig{
+		MinBond:  1,
+		Bonds:    bonds,
+		Reporter: reporter,
+	})
+
+	honest, silent, cheater, poor := newTestBidder(t), newTestBidder(t), newTestBidder(t), newTestBidder(t)
+	for _, b := range []*testBidder{honest, silent, cheater} {
+		bonds.Deposit(b.did, 5)
+	}
+
+	if err := round.addCommit(ctx, honest.commit(t, "cfp-1", "bid-honest", 10, 500, "n1", 2)); err != nil {
+		t.Fatalf("honest commit rejected: %v", err)
+	}
+	if err := round.addCommit(ctx, silent.commit(t, "cfp-1", "bid-silent", 8, 500, "n2", 2)); err != nil {
+		t.Fatalf("silent commit rejected: %v", err)
+	}
+	if err := round.addCommit(ctx, cheater.commit(t, "cfp-1", "bid-cheater", 12, 500, "n3", 2)); err != nil {
+		t.Fatalf("cheater commit rejected: %v", err)
+	}
+	if err := round.addCommit(ctx, honest.commit(t, "cfp-1", "bid-honest-2", 9, 500, "n4", 2)); err == nil {
+		t.Error("expected duplicate commitment to be rejected")
+	}
+	if err := round.addCommit(ctx, poor.commit(t, "cfp-1", "bid-poor", 9, 500, "n5", 0.5)); !errors.Is(err, ErrInsufficientBond) {
+		t.Errorf("expected ErrInsufficientBond, got %v", err)
+	}
+
+	if err := round.addReveal(honest.reveal(t, "cfp-1", "bid-honest", 10, 500, "n1")); err == nil {
+		t.Error("expected reveal before the reveal phase to be rejected")
+	}
+
+	if ids := round.startReveal(); len(ids) != 3 {
+		t.Fatalf("expected 3 commitments, got %d", len(ids))
+	}
+	if err := round.addCommit(ctx, poor.commit(t, "cfp-1", "bid-late", 9, 500, "n6", 2)); err == nil {
+		t.Error("expected commitment after the commit phase to be rejected")
+	}
+
+	if err := round.addReveal(honest.reveal(t, "cfp-1", "bid-honest", 10, 500, "n1")); err != nil {
+		t.Fatalf("honest reveal rejected: %v", err)
+	}
+	// The cheater saw the honest price and tries to undercut it
+	if err := round.addReveal(cheater.reveal(t, "cfp-1", "bid-cheater", 9, 500, "n3")); !errors.Is(err, ErrInvalidReveal) {
+		t.Errorf("expected mismatched reveal to be rejected, got %v", err)
+	}
+	if err := round.addReveal(cheater.reveal(t, "cfp-1", "bid-cheater", 12, 500, "n3")); err == nil {
+		t.Error("expected a second reveal to be rejected")
+	}
+
+	bids, violations := round.settle(ctx)
+
+	if len(bids) != 1 || bids[0].BidID != "bid-honest" || bids[0].Price != 10 {
+		t.Fatalf("expected only the honest bid, got %+v", bids)
+	}
+	if len(violations) != 2 || len(reporter.violations) != 2 {
+		t.Fatalf("expected 2 violations, got %d (reported %d)", len(violations), len(reporter.violations))
+	}
+
+	kinds := map[string]BidViolationKind{}
+	for _, v := range violations {
+		kinds[string(v.AgentDID)] = v.Kind
+	}
+	if kinds[silent.did] != BidViolationUnrevealed {
+		t.Errorf("expected silent bidder to be unrevealed, got %q", kinds[silent.did])
+	}
+	if kinds[cheater.did] != BidViolationMismatch {
+		t.Errorf("expected cheater to be a mismatch, got %q", kinds[cheater.did])
+	}
+
+	if got := bonds.Available(honest.did); got != 5 {
+		t.Errorf("expected honest bond to be released, available %.2f", got)
+	}
+	if got := bonds.Available(silent.did); got != 3 {
+		t.Errorf("expected silent bond to be forfeited, available %.2f", got)
+	}
+	if got := bonds.Forfeited(); got != 4 {
+		t.Errorf("expected 4 forfeited, got %.2f", got)
+	}
+}
+
+func TestSealedRoundRejectsForgedCommit(t *testing.T) {
+	round := newSealedRound(NewAuctioneer(nil, nil), "cfp-1", SealedBidConfig{})
+	bidder, impostor := newTestBidder(t), newTestBidder(t)
+
+	msg := impostor.commit(t, "cfp-1", "bid-1", 10, 500, "n", 0)
+	msg["from"] = bidder.did
+
+	if err := round.addCommit(context.Background(), msg); !errors.Is(err, ErrInvalidCommit) {
+		t.Errorf("expected forged commitment to be rejected, got %v", err)
+	}
+}
+
+func TestSealedRoundAbortReturnsBonds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bonds := NewInMemoryBondManager()
+	round := newSealedRound(NewAuctioneer(nil, nil), "cfp-1", SealedBidConfig{MinBond: 1, Bonds: bonds})
+
+	bidder := newTestBidder(t)
+	bonds.Deposit(bidder.did, 5)
+	if err := round.addCommit(ctx, bidder.commit(t, "cfp-1", "bid-1", 10, 500, "n", 2)); err != nil {
+		t.Fatalf("commit rejected: %v", err)
+	}
+
+	// The task is canceled before the reveal phase ends
+	cancel()
+	if err := waitWindow(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the window to end with the context, got %v", err)
+	}
+	round.abort(ctx)
+
+	if got := bonds.Available(bidder.did); got != 5 {
+		t.Errorf("expected the bond returned, available %.2f", got)
+	}
+	if got := bonds.Forfeited(); got != 0 {
+		t.Errorf("expected nothing forfeited, got %.2f", got)
+	}
+	if err := round.addCommit(context.Background(), bidder.commit(t, "cfp-1", "bid-2", 10, 500, "n", 2)); err == nil {
+		t.Error("expected an aborted round to reject commitments")
+	}
+}
@@ -3,6 +3,8 @@ package orchestration
 import (
 	"time"
 
+	"github.com/aidenlippert/zerostate/libs/execution"
+	"github.com/aidenlippert/zerostate/libs/scoring"
 	"github.com/google/uuid"
 )
 
@@ -81,11 +83,15 @@ type Task struct {
 	Input        map[string]interface{} `json:"input"`        // Task input data
 	Metadata     map[string]interface{} `json:"metadata"`     // Additional metadata
 
+	// Files mounted read-only under /input for WASM agents
+	InputArtifacts []execution.InputArtifact `json:"input_artifacts,omitempty"`
+
 	// Execution
 	Priority    TaskPriority           `json:"priority"`
 	Status      TaskStatus             `json:"status"`
 	AssignedTo  string                 `json:"assigned_to,omitempty"` // Agent DID
 	Result      map[string]interface{} `json:"result,omitempty"`
+	Artifacts   []execution.Artifact   `json:"artifacts,omitempty"` // Output files written by the agent
 	Error       string                 `json:"error,omitempty"`
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
@@ -99,7 +105,11 @@ type Task struct {
 
 	// Payment & Economics
 	Budget       float64 `json:"budget"`                  // Maximum price user will pay
+	ReservePrice float64 `json:"reserve_price,omitempty"` // Lowest price a Dutch auction may fall to
 	ActualCost   float64 `json:"actual_cost,omitempty"`   // Actual cost charged
+	FuelUsed     uint64  `json:"fuel_used,omitempty"`     // Metered WASM instructions
+	CPUTimeMS    int64   `json:"cpu_time_ms,omitempty"`   // CPU time, when the runtime measures it
+	MemoryBytes  int64   `json:"memory_bytes,omitempty"`  // Peak memory, when the runtime measures it
 	PaymentToken string  `json:"payment_token,omitempty"` // Payment reference
 
 	// Payment Lifecycle
@@ -126,6 +136,13 @@ type Task struct {
 
 	// Refund Policy
 	RefundPolicyType string `json:"refund_policy_type,omitempty"` // linear, exponential, stepwise, etc.
+
+	// Auction Scoring
+	ScoringRule *scoring.Rule `json:"scoring_rule,omitempty"` // Published in the CFP; bids are ranked by it
+
+	// Redundant Execution
+	Replication *ReplicationPolicy `json:"replication,omitempty"` // Run on several agents and vote on the result
+	Replicas    []ReplicaResult    `json:"replicas,omitempty"`    // Each agent's run, once voted on
 }
 
 // NewTask creates a new task with default values
@@ -186,6 +203,10 @@ type TaskResult struct {
 	AgentDID    string                 `json:"agent_did"`
 	Timestamp   time.Time              `json:"timestamp"`
 	Cost        float64                `json:"cost,omitempty"`
+	FuelUsed    uint64                 `json:"fuel_used,omitempty"`    // Metered instructions, when the executor meters
+	CPUTimeMS   int64                  `json:"cpu_time_ms,omitempty"`  // CPU time, when the runtime measures it
+	MemoryBytes int64                  `json:"memory_bytes,omitempty"` // Peak memory, when the runtime measures it
+	Artifacts   []execution.Artifact   `json:"artifacts,omitempty"`    // Output files, hashed and uploaded
 }
 
 // TaskFilter represents filtering criteria for task queries
@@ -19,6 +19,7 @@ type VCGAuctionResult struct {
 	AllBids        []*BidSummary
 	SocialWelfare  float64      // Total utility to society
 	AuctionType    string       // "VCG" or "FirstPrice" for comparison
+	Violations     []BidViolation // Forfeited sealed-bid commitments
 }
 
 // VCGAuctioneer implements Vickrey-Clarke-Groves second-price sealed-bid auctions
@@ -39,7 +40,9 @@ func NewVCGAuctioneer(auctioneer *Auctioneer, logger *zap.Logger) *VCGAuctioneer
 	}
 }
 
-// StartVCGAuction runs a VCG auction for the given task
+// StartVCGAuction runs a VCG auction for the given task. Truthful bidding is
+// only dominant if bidders cannot see each other's bids, so on an open gossip
+// network the auctioneer should run with EnableSealedBids.
 func (v *VCGAuctioneer) StartVCGAuction(
 	ctx context.Context,
 	task *Task,
@@ -68,11 +71,13 @@ func (v *VCGAuctioneer) StartVCGAuction(
 		return &VCGAuctionResult{
 			CFPID:       auctionResult.CFPID,
 			AuctionType: "VCG",
+			Violations:  auctionResult.Violations,
 		}, nil
 	}
 
 	// Run VCG mechanism on collected bids
 	vcgResult := v.runVCGMechanism(auctionResult, task)
+	vcgResult.Violations = auctionResult.Violations
 
 	v.logger.Info("VCG auction completed",
 		zap.String("task_id", task.ID),
@@ -269,43 +274,60 @@ func (v *VCGAuctioneer) CompareAuctionMechanisms(
 		return nil, fmt.Errorf("failed to collect bids for comparison: %w", err)
 	}
 
-	if auctionResult == nil || len(auctionResult.AllBids) == 0 {
+	if auctionResult == nil {
+		return v.CompareBids(task.ID, "", nil), nil
+	}
+
+	comparison := v.CompareBids(task.ID, auctionResult.CFPID, auctionResult.AllBids)
+	if comparison.VCGResult.Winner == nil {
+		return comparison, nil
+	}
+
+	v.logger.Info("auction mechanism comparison completed",
+		zap.String("task_id", task.ID),
+		zap.Float64("vcg_payment", comparison.VCGResult.SecondPrice),
+		zap.Float64("first_price_payment", comparison.FirstPriceResult.FirstPrice),
+		zap.Float64("cost_savings", comparison.CostSavings),
+		zap.Float64("efficiency_gain", comparison.EfficiencyGain),
+	)
+
+	return comparison, nil
+}
+
+// CompareBids clears an already collected bid set under both the VCG and the
+// first-price rules. This is the mechanism half of CompareAuctionMechanisms;
+// market simulators call it directly to exercise the same clearing code
+// without a gossip network.
+func (v *VCGAuctioneer) CompareBids(taskID, cfpID string, bids []BidSummary) *VCGComparisonResult {
+	if len(bids) == 0 {
 		return &VCGComparisonResult{
-			TaskID: task.ID,
+			TaskID: taskID,
 			VCGResult: &VCGAuctionResult{
-				CFPID:       auctionResult.CFPID,
+				CFPID:       cfpID,
 				AuctionType: "VCG",
 			},
 			FirstPriceResult: &VCGAuctionResult{
-				CFPID:       auctionResult.CFPID,
+				CFPID:       cfpID,
 				AuctionType: "FirstPrice",
 			},
-		}, nil
+		}
 	}
 
+	auctionResult := &AuctionResult{CFPID: cfpID, AllBids: bids}
+
 	// Run VCG mechanism
-	vcgResult := v.runVCGMechanism(auctionResult, task)
+	vcgResult := v.runVCGMechanism(auctionResult, nil)
 
 	// Simulate first-price auction on same bids
 	firstPriceResult := v.simulateFirstPriceAuction(auctionResult)
 
-	comparison := &VCGComparisonResult{
-		TaskID:           task.ID,
+	return &VCGComparisonResult{
+		TaskID:           taskID,
 		VCGResult:        vcgResult,
 		FirstPriceResult: firstPriceResult,
 		CostSavings:      firstPriceResult.FirstPrice - vcgResult.SecondPrice,
 		EfficiencyGain:   vcgResult.Efficiency,
 	}
-
-	v.logger.Info("auction mechanism comparison completed",
-		zap.String("task_id", task.ID),
-		zap.Float64("vcg_payment", vcgResult.SecondPrice),
-		zap.Float64("first_price_payment", firstPriceResult.FirstPrice),
-		zap.Float64("cost_savings", comparison.CostSavings),
-		zap.Float64("efficiency_gain", comparison.EfficiencyGain),
-	)
-
-	return comparison, nil
 }
 
 // simulateFirstPriceAuction simulates a first-price auction on existing bids
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/aidenlippert/zerostate/libs/execution"
@@ -15,6 +16,12 @@ import (
 type WASMTaskExecutor struct {
 	wasmRunner  *execution.WASMRunner
 	binaryStore execution.BinaryStore
+	fuelPerUnit uint64 // Fuel bought by one unit of task budget; 0 disables metering
+	content     execution.ContentFetcher
+	agents      execution.AgentCaller
+	onProgress  func(execution.ProgressUpdate)
+	artifacts   execution.ArtifactStore
+	scratchMax  int64 // Cap on bytes a task writes to its filesystem; 0 uses the default
 	logger      *zap.Logger
 }
 
@@ -27,10 +34,80 @@ func NewWASMTaskExecutor(wasmRunner *execution.WASMRunner, binaryStore execution
 	return &WASMTaskExecutor{
 		wasmRunner:  wasmRunner,
 		binaryStore: binaryStore,
+		fuelPerUnit: execution.DefaultFuelPerUnit,
 		logger:      logger,
 	}
 }
 
+// SetFuelPerUnit sets how much fuel one unit of task budget buys. Tasks are
+// metered and charged by fuel; 0 falls back to wall-clock timeouts only.
+func (e *WASMTaskExecutor) SetFuelPerUnit(fuelPerUnit uint64) {
+	e.fuelPerUnit = fuelPerUnit
+}
+
+// SetContentStore lets agents granted the content capability fetch by CID
+func (e *WASMTaskExecutor) SetContentStore(content execution.ContentFetcher) {
+	e.content = content
+}
+
+// SetAgentCaller lets agents granted the agents capability call other agents
+func (e *WASMTaskExecutor) SetAgentCaller(agents execution.AgentCaller) {
+	e.agents = agents
+}
+
+// SetProgressSink receives the partial results agents emit while running
+func (e *WASMTaskExecutor) SetProgressSink(sink func(execution.ProgressUpdate)) {
+	e.onProgress = sink
+}
+
+// SetArtifactStore sets where task input artifacts are fetched from by key
+// and where output artifacts are uploaded
+func (e *WASMTaskExecutor) SetArtifactStore(store execution.ArtifactStore) {
+	e.artifacts = store
+}
+
+// SetScratchLimit caps the bytes a task may write to /scratch and /output
+func (e *WASMTaskExecutor) SetScratchLimit(limitBytes int64) {
+	e.scratchMax = limitBytes
+}
+
+// hostEnv builds the host API state for a run, granting only what the agent's
+// card asks for. What the guest reports and logs is streamed through ctx.
+func (e *WASMTaskExecutor) hostEnv(ctx context.Context, task *Task, agent *identity.AgentCard, input []byte) *execution.HostEnv {
+	var grants execution.HostGrants
+	if agent.Policy != nil {
+		grants = execution.NewHostGrants(agent.Policy.HostCapabilities...)
+	}
+
+	env := execution.NewHostEnv(task.ID, agent.DID, grants, input, e.logger)
+	env.SetProgressSink(func(update execution.ProgressUpdate) {
+		ReportProgress(ctx, TaskProgress{Percent: float64(update.Percent), Partial: update.Data})
+		if e.onProgress != nil {
+			e.onProgress(update)
+		}
+	})
+	env.SetLogSink(func(level, message string) {
+		ReportProgress(ctx, TaskProgress{Logs: []string{level + ": " + message}})
+	})
+	if e.content != nil {
+		env.SetContentStore(e.content)
+	}
+	if e.agents != nil {
+		env.SetAgentCaller(e.agents, task.Budget)
+	}
+	return env
+}
+
+// runCost is what a run charges: metered fuel plus the agents it called,
+// never more than the task budget
+func (e *WASMTaskExecutor) runCost(task *Task, fuelUsed uint64, env *execution.HostEnv) float64 {
+	cost := execution.FuelCost(fuelUsed, e.fuelPerUnit) + env.AgentSpend()
+	if task.Budget > 0 {
+		cost = math.Min(cost, task.Budget)
+	}
+	return cost
+}
+
 // ExecuteTask executes a task using real WASM execution
 func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *identity.AgentCard) (*TaskResult, error) {
 	e.logger.Info("executing task with WASM",
@@ -78,8 +155,25 @@ func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *i
 		timeout = time.Duration(task.Timeout) * time.Second
 	}
 
-	// Create context with timeout
-	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	// Give the run a private filesystem with its input artifacts, wiped when
+	// the task ends
+	taskFS, err := execution.NewTaskFS(task.ID, e.scratchMax)
+	if err != nil {
+		return nil, err
+	}
+	defer taskFS.Close()
+	if err := taskFS.Stage(ctx, task.InputArtifacts, e.artifacts, e.content); err != nil {
+		return &TaskResult{
+			TaskID:      task.ID,
+			Status:      TaskStatusFailed,
+			Error:       fmt.Sprintf("failed to stage input artifacts: %v", err),
+			ExecutionMS: time.Since(start).Milliseconds(),
+		}, nil
+	}
+
+	// Create context with timeout, carrying the agent's host capabilities
+	env := e.hostEnv(ctx, task, agent, inputBytes)
+	execCtx, cancel := context.WithTimeout(execution.WithTaskFS(execution.WithHostEnv(ctx, env), taskFS), timeout)
 	defer cancel()
 
 	// Execute WASM with resource limits
@@ -87,6 +181,7 @@ func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *i
 		MaxMemoryMB: 512, // Default 512MB limit
 		MaxCPUCores: 1,   // Single core
 		Timeout:     timeout,
+		MaxFuel:     execution.FuelBudget(task.Budget, e.fuelPerUnit),
 	}
 
 	wasmResult, err := e.wasmRunner.ExecuteWithLimits(execCtx, wasmBinary, inputBytes, limits)
@@ -95,12 +190,26 @@ func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *i
 			zap.String("task_id", task.ID),
 			zap.Error(err),
 		)
-		return &TaskResult{
+		failed := &TaskResult{
 			TaskID:      task.ID,
 			Status:      TaskStatusFailed,
 			Error:       fmt.Sprintf("execution failed: %v", err),
 			ExecutionMS: time.Since(start).Milliseconds(),
-		}, nil
+		}
+		if wasmResult != nil {
+			failed.FuelUsed = wasmResult.FuelConsumed
+		}
+		failed.Cost = e.runCost(task, failed.FuelUsed, env)
+
+		// Keep the last partial result the agent reported
+		if progress := env.Progress(); len(progress) > 0 {
+			last := progress[len(progress)-1]
+			failed.Result = map[string]interface{}{
+				"partial":  last.Data,
+				"progress": last.Percent,
+			}
+		}
+		return failed, nil
 	}
 
 	// Parse output
@@ -130,11 +239,23 @@ func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *i
 		result["exit_code"] = wasmResult.ExitCode
 	}
 
+	// Hash and upload what the agent left in /output
+	artifacts, err := taskFS.Collect(ctx, e.artifacts)
+	if err != nil {
+		e.logger.Error("failed to collect output artifacts",
+			zap.String("task_id", task.ID),
+			zap.Error(err),
+		)
+		status = TaskStatusFailed
+		result["artifact_error"] = err.Error()
+	}
+
 	executionTime := time.Since(start).Milliseconds()
 	e.logger.Info("task execution complete",
 		zap.String("task_id", task.ID),
 		zap.String("status", string(status)),
 		zap.Int64("execution_ms", executionTime),
+		zap.Uint64("fuel_used", wasmResult.FuelConsumed),
 	)
 
 	return &TaskResult{
@@ -142,5 +263,8 @@ func (e *WASMTaskExecutor) ExecuteTask(ctx context.Context, task *Task, agent *i
 		Status:      status,
 		Result:      result,
 		ExecutionMS: executionTime,
+		FuelUsed:    wasmResult.FuelConsumed,
+		Cost:        e.runCost(task, wasmResult.FuelConsumed, env),
+		Artifacts:   artifacts,
 	}, nil
 }
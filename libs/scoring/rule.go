@@ -0,0 +1,316 @@
+// Package scoring implements multi-attribute scoring rules for task auctions.
+// A Rule is declared per task and published with the call for proposals, so
+// bidders can optimize against it; the orchestrator and the marketplace score
+// bids with the same Rule.
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+)
+
+// ErrInvalidRule is returned for a malformed scoring rule
+var ErrInvalidRule = errors.New("invalid scoring rule")
+
+// CurveKind selects the shape of a utility curve
+type CurveKind string
+
+const (
+	CurveLinear      CurveKind = "linear"      // Falls linearly from Best to Worst
+	CurvePower       CurveKind = "power"       // Linear utility raised to Exponent (>1 punishes, <1 forgives)
+	CurveExponential CurveKind = "exponential" // exp(-Exponent * distance), 0 at Worst
+	CurveStep        CurveKind = "step"        // Utility of the first step whose Max covers the value
+)
+
+// Step is one threshold of a step curve
+type Step struct {
+	Max     float64 `json:"max"`
+	Utility float64 `json:"utility"`
+}
+
+// Curve maps a cost-like attribute (lower is better) to a utility in [0, 1].
+// Values at or below Best score 1, values at or above Worst score 0.
+type Curve struct {
+	Kind     CurveKind `json:"kind"`
+	Best     float64   `json:"best"`
+	Worst    float64   `json:"worst"`
+	Exponent float64   `json:"exponent,omitempty"`
+	Steps    []Step    `json:"steps,omitempty"`
+}
+
+// Utility evaluates the curve at x
+func (c *Curve) Utility(x float64) float64 {
+	if c.Kind == CurveStep {
+		for _, step := range c.Steps {
+			if x <= step.Max {
+				return clamp(step.Utility)
+			}
+		}
+		return 0
+	}
+
+	if x <= c.Best {
+		return 1
+	}
+	if x >= c.Worst {
+		return 0
+	}
+	linear := (c.Worst - x) / (c.Worst - c.Best)
+
+	switch c.Kind {
+	case CurvePower:
+		return math.Pow(linear, c.Exponent)
+	case CurveExponential:
+		// Rescaled so utility reaches exactly 0 at Worst
+		floor := math.Exp(-c.Exponent)
+		return (math.Exp(-c.Exponent*(1-linear)) - floor) / (1 - floor)
+	default:
+		return linear
+	}
+}
+
+// Validate checks the curve's parameters
+func (c *Curve) Validate() error {
+	switch c.Kind {
+	case CurveStep:
+		if len(c.Steps) == 0 {
+			return fmt.Errorf("%w: step curve needs steps", ErrInvalidRule)
+		}
+		for i, step := range c.Steps {
+			if step.Utility < 0 || step.Utility > 1 {
+				return fmt.Errorf("%w: step utility must be in [0, 1]", ErrInvalidRule)
+			}
+			if i > 0 && step.Max <= c.Steps[i-1].Max {
+				return fmt.Errorf("%w: step thresholds must increase", ErrInvalidRule)
+			}
+		}
+		return nil
+	case CurveLinear, CurvePower, CurveExponential:
+	default:
+		return fmt.Errorf("%w: unknown curve kind %q", ErrInvalidRule, c.Kind)
+	}
+
+	if c.Worst <= c.Best {
+		return fmt.Errorf("%w: curve worst must exceed best", ErrInvalidRule)
+	}
+	if c.Kind != CurveLinear && c.Exponent <= 0 {
+		return fmt.Errorf("%w: %s curve needs a positive exponent", ErrInvalidRule, c.Kind)
+	}
+	return nil
+}
+
+// Constraints are hard requirements; an offer that violates any is ineligible
+type Constraints struct {
+	Regions       []string `json:"regions,omitempty"`     // Allowed agent regions
+	Privacy       []string `json:"privacy,omitempty"`     // Allowed identity.Policy privacy levels
+	SLAClasses    []string `json:"sla_classes,omitempty"` // Allowed identity.Policy SLA classes
+	MaxPrice      float64  `json:"max_price,omitempty"`
+	MaxLatencyMS  int64    `json:"max_latency_ms,omitempty"`
+	MinReputation float64  `json:"min_reputation,omitempty"` // Normalized, 0-1
+	MinQuality    float64  `json:"min_quality,omitempty"`    // Normalized, 0-1
+}
+
+// Weights weight each attribute's utility; they are normalized by their sum
+type Weights struct {
+	Price      float64 `json:"price"`
+	Latency    float64 `json:"latency"`
+	Reputation float64 `json:"reputation"`
+	Quality    float64 `json:"quality"`
+}
+
+// Rule scores offers for one task
+type Rule struct {
+	Weights     Weights     `json:"weights"`
+	Price       *Curve      `json:"price_curve,omitempty"`   // Defaults to linear over [0, MaxPrice]
+	Latency     *Curve      `json:"latency_curve,omitempty"` // Milliseconds; defaults to linear over [0, MaxLatencyMS]
+	Constraints Constraints `json:"constraints"`
+
+	// CapacityPenalty is subtracted from the score scaled by the offer's load,
+	// favoring agents that are not already saturated
+	CapacityPenalty float64 `json:"capacity_penalty,omitempty"`
+}
+
+// Offer is the comparable view of a bid
+type Offer struct {
+	Price      float64 `json:"price"`
+	LatencyMS  int64   `json:"latency_ms"`
+	Reputation float64 `json:"reputation"` // Normalized, 0-1
+	Quality    float64 `json:"quality"`    // Normalized, 0-1
+	Region     string  `json:"region,omitempty"`
+	Privacy    string  `json:"privacy,omitempty"`
+	SLAClass   string  `json:"sla_class,omitempty"`
+	Load       float64 `json:"load,omitempty"` // Capacity utilization, 0-1
+}
+
+// ApplyCard fills the offer's region and policy attributes from an agent card
+func (o *Offer) ApplyCard(card *identity.AgentCard) {
+	if card == nil {
+		return
+	}
+	if card.Endpoints != nil {
+		o.Region = card.Endpoints.Region
+	}
+	if card.Policy != nil {
+		o.Privacy = card.Policy.Privacy
+		o.SLAClass = card.Policy.SLAClass
+	}
+}
+
+// Result is the outcome of scoring one offer
+type Result struct {
+	Score      float64            `json:"score"`
+	Eligible   bool               `json:"eligible"`
+	Violations []string           `json:"violations,omitempty"`
+	Utilities  map[string]float64 `json:"utilities"`
+}
+
+// Default weights match the marketplace's historical composite score
+var defaultWeights = Weights{Price: 0.4, Reputation: 0.3, Quality: 0.2, Latency: 0.1}
+
+// DefaultRule is the rule used when a task declares none: linear price and
+// latency utilities over the task's budget and timeout
+func DefaultRule(maxPrice float64, timeoutMS int64) *Rule {
+	return &Rule{
+		Weights: defaultWeights,
+		Constraints: Constraints{
+			MaxPrice: maxPrice,
+		},
+		Latency: latencyCurve(timeoutMS),
+	}
+}
+
+// WithDefaults returns a copy of the rule with the task's budget and timeout
+// filling in a missing price limit and latency curve. A nil rule yields
+// DefaultRule. Every auction scores through this so the orchestrator and the
+// marketplace agree on the same bid.
+func (r *Rule) WithDefaults(maxPrice float64, timeoutMS int64) *Rule {
+	if r == nil {
+		return DefaultRule(maxPrice, timeoutMS)
+	}
+
+	rule := *r
+	if rule.Constraints.MaxPrice == 0 {
+		rule.Constraints.MaxPrice = maxPrice
+	}
+	if rule.Latency == nil && rule.Constraints.MaxLatencyMS == 0 {
+		rule.Latency = latencyCurve(timeoutMS)
+	}
+	return &rule
+}
+
+func latencyCurve(timeoutMS int64) *Curve {
+	if timeoutMS <= 0 {
+		return nil
+	}
+	return &Curve{Kind: CurveLinear, Best: 0, Worst: float64(timeoutMS)}
+}
+
+// Validate checks the rule's weights, curves and constraints
+func (r *Rule) Validate() error {
+	w := r.Weights
+	if w.Price < 0 || w.Latency < 0 || w.Reputation < 0 || w.Quality < 0 {
+		return fmt.Errorf("%w: weights must be non-negative", ErrInvalidRule)
+	}
+	if w.Price+w.Latency+w.Reputation+w.Quality == 0 {
+		return fmt.Errorf("%w: at least one weight must be positive", ErrInvalidRule)
+	}
+	for _, curve := range []*Curve{r.Price, r.Latency} {
+		if curve == nil {
+			continue
+		}
+		if err := curve.Validate(); err != nil {
+			return err
+		}
+	}
+	c := r.Constraints
+	if c.MaxPrice < 0 || c.MaxLatencyMS < 0 {
+		return fmt.Errorf("%w: limits must be non-negative", ErrInvalidRule)
+	}
+	if c.MinReputation < 0 || c.MinReputation > 1 || c.MinQuality < 0 || c.MinQuality > 1 {
+		return fmt.Errorf("%w: quality thresholds must be in [0, 1]", ErrInvalidRule)
+	}
+	if r.CapacityPenalty < 0 {
+		return fmt.Errorf("%w: capacity penalty must be non-negative", ErrInvalidRule)
+	}
+	return nil
+}
+
+// Score evaluates an offer. Ineligible offers score 0 and list every
+// violated constraint.
+func (r *Rule) Score(o Offer) Result {
+	result := Result{Utilities: make(map[string]float64, 4)}
+	c := r.Constraints
+
+	if len(c.Regions) > 0 && !containsFold(c.Regions, o.Region) {
+		result.Violations = append(result.Violations, fmt.Sprintf("region %q not allowed", o.Region))
+	}
+	if len(c.Privacy) > 0 && !containsFold(c.Privacy, o.Privacy) {
+		result.Violations = append(result.Violations, fmt.Sprintf("privacy %q not allowed", o.Privacy))
+	}
+	if len(c.SLAClasses) > 0 && !containsFold(c.SLAClasses, o.SLAClass) {
+		result.Violations = append(result.Violations, fmt.Sprintf("sla class %q not allowed", o.SLAClass))
+	}
+	if c.MaxPrice > 0 && o.Price > c.MaxPrice {
+		result.Violations = append(result.Violations, fmt.Sprintf("price %.4f above %.4f", o.Price, c.MaxPrice))
+	}
+	if c.MaxLatencyMS > 0 && o.LatencyMS > c.MaxLatencyMS {
+		result.Violations = append(result.Violations, fmt.Sprintf("latency %dms above %dms", o.LatencyMS, c.MaxLatencyMS))
+	}
+	if o.Reputation < c.MinReputation {
+		result.Violations = append(result.Violations, fmt.Sprintf("reputation %.2f below %.2f", o.Reputation, c.MinReputation))
+	}
+	if o.Quality < c.MinQuality {
+		result.Violations = append(result.Violations, fmt.Sprintf("quality %.2f below %.2f", o.Quality, c.MinQuality))
+	}
+	if len(result.Violations) > 0 {
+		return result
+	}
+	result.Eligible = true
+
+	price := r.Price
+	if price == nil && c.MaxPrice > 0 {
+		price = &Curve{Kind: CurveLinear, Best: 0, Worst: c.MaxPrice}
+	}
+	latency := r.Latency
+	if latency == nil {
+		latency = latencyCurve(c.MaxLatencyMS)
+	}
+
+	// Without a curve an attribute has no reference point and scores 0,
+	// matching the marketplace's behavior for auctions without a budget
+	if price != nil {
+		result.Utilities["price"] = price.Utility(o.Price)
+	}
+	if latency != nil && o.LatencyMS > 0 {
+		result.Utilities["latency"] = latency.Utility(float64(o.LatencyMS))
+	}
+	result.Utilities["reputation"] = clamp(o.Reputation)
+	result.Utilities["quality"] = clamp(o.Quality)
+
+	w := r.Weights
+	total := w.Price + w.Latency + w.Reputation + w.Quality
+	score := (w.Price*result.Utilities["price"] +
+		w.Latency*result.Utilities["latency"] +
+		w.Reputation*result.Utilities["reputation"] +
+		w.Quality*result.Utilities["quality"]) / total
+
+	result.Score = score - r.CapacityPenalty*clamp(o.Load)
+	return result
+}
+
+func containsFold(values []string, v string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
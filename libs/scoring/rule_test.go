@@ -0,0 +1,109 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/aidenlippert/zerostate/libs/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurveUtility(t *testing.T) {
+	linear := &Curve{Kind: CurveLinear, Best: 10, Worst: 20}
+	assert.Equal(t, 1.0, linear.Utility(5))
+	assert.InDelta(t, 0.5, linear.Utility(15), 1e-9)
+	assert.Equal(t, 0.0, linear.Utility(25))
+
+	power := &Curve{Kind: CurvePower, Best: 0, Worst: 100, Exponent: 2}
+	assert.InDelta(t, 0.25, power.Utility(50), 1e-9)
+
+	exponential := &Curve{Kind: CurveExponential, Best: 0, Worst: 100, Exponent: 3}
+	assert.InDelta(t, 1.0, exponential.Utility(0), 1e-9)
+	assert.InDelta(t, 0.0, exponential.Utility(100), 1e-9)
+	assert.Less(t, exponential.Utility(50), 0.5, "exponential decay should fall faster than linear")
+
+	step := &Curve{Kind: CurveStep, Steps: []Step{{Max: 100, Utility: 1}, {Max: 500, Utility: 0.5}}}
+	assert.Equal(t, 1.0, step.Utility(80))
+	assert.Equal(t, 0.5, step.Utility(300))
+	assert.Equal(t, 0.0, step.Utility(900))
+}
+
+func TestRuleValidate(t *testing.T) {
+	require.NoError(t, DefaultRule(100, 5000).Validate())
+
+	assert.ErrorIs(t, (&Rule{}).Validate(), ErrInvalidRule)
+	assert.ErrorIs(t, (&Rule{
+		Weights: Weights{Price: 1},
+		Price:   &Curve{Kind: CurvePower, Best: 0, Worst: 10},
+	}).Validate(), ErrInvalidRule)
+	assert.ErrorIs(t, (&Rule{
+		Weights:     Weights{Price: 1},
+		Constraints: Constraints{MinQuality: 80},
+	}).Validate(), ErrInvalidRule)
+}
+
+func TestRuleScoreConstraints(t *testing.T) {
+	rule := &Rule{
+		Weights: Weights{Price: 1},
+		Constraints: Constraints{
+			MaxPrice:   10,
+			Regions:    []string{"eu-west"},
+			Privacy:    []string{"strict"},
+			MinQuality: 0.8,
+		},
+	}
+
+	offer := Offer{Price: 5, Quality: 0.9}
+	offer.ApplyCard(&identity.AgentCard{
+		Endpoints: &identity.Endpoints{Region: "EU-WEST"},
+		Policy:    &identity.Policy{Privacy: "strict"},
+	})
+
+	result := rule.Score(offer)
+	require.True(t, result.Eligible, "violations: %v", result.Violations)
+	assert.InDelta(t, 0.5, result.Score, 1e-9)
+
+	offer.Quality = 0.5
+	offer.Region = "us-east"
+	result = rule.Score(offer)
+	assert.False(t, result.Eligible)
+	assert.Len(t, result.Violations, 2)
+	assert.Equal(t, 0.0, result.Score)
+}
+
+func TestRuleScoreQualityBeatsPrice(t *testing.T) {
+	// Procurement: past a quality threshold, quality outweighs small savings
+	rule := &Rule{
+		Weights:         Weights{Price: 0.3, Quality: 0.7},
+		Price:           &Curve{Kind: CurvePower, Best: 0, Worst: 100, Exponent: 0.5},
+		Constraints:     Constraints{MinQuality: 0.6},
+		CapacityPenalty: 0.2,
+	}
+
+	cheap := rule.Score(Offer{Price: 20, Quality: 0.65})
+	good := rule.Score(Offer{Price: 40, Quality: 0.95})
+	busy := rule.Score(Offer{Price: 40, Quality: 0.95, Load: 1})
+
+	assert.Greater(t, good.Score, cheap.Score)
+	assert.InDelta(t, good.Score-0.2, busy.Score, 1e-9)
+}
+
+func TestDefaultRuleMatchesCompositeScore(t *testing.T) {
+	rule := DefaultRule(100, 10000)
+	result := rule.Score(Offer{Price: 40, LatencyMS: 2000, Reputation: 0.9, Quality: 0.8})
+
+	// 0.4*(1-40/100) + 0.3*0.9 + 0.2*0.8 + 0.1*(1-2000/10000)
+	assert.InDelta(t, 0.4*0.6+0.3*0.9+0.2*0.8+0.1*0.8, result.Score, 1e-9)
+}
+
+func TestRuleWithDefaults(t *testing.T) {
+	var missing *Rule
+	assert.Equal(t, DefaultRule(50, 1000), missing.WithDefaults(50, 1000))
+
+	rule := &Rule{Weights: Weights{Price: 1, Latency: 1}}
+	filled := rule.WithDefaults(50, 1000)
+	assert.Equal(t, 50.0, filled.Constraints.MaxPrice)
+	require.NotNil(t, filled.Latency)
+	assert.Equal(t, 1000.0, filled.Latency.Worst)
+	assert.Nil(t, rule.Latency, "the declared rule must not be modified")
+}
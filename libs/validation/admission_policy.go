@@ -0,0 +1,231 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Violation rules reported by admission
+const (
+	RuleFormat          = "format"
+	RuleDangerousImport = "dangerous_import"
+	RuleResourceLimit   = "resource_limit"
+	RuleImportNamespace = "import_namespace"
+	RuleInitialMemory   = "memory_initial"
+	RuleMaximumMemory   = "memory_maximum"
+	RuleFunctionCount   = "function_count"
+	RuleBinarySize      = "binary_size"
+	RuleRequiredExport  = "required_export"
+	RuleThreads         = "threads"
+	RuleSIMD            = "simd"
+)
+
+// AdmissionPolicy decides which agent binaries may be uploaded. Zero limits
+// are not enforced.
+type AdmissionPolicy struct {
+	// AllowedImportModules lists import namespaces as path.Match patterns
+	AllowedImportModules []string `json:"allowed_import_modules"`
+
+	MaxInitialPages uint32 `json:"max_initial_pages,omitempty"`
+	MaxMaximumPages uint32 `json:"max_maximum_pages,omitempty"`
+	MaxFunctions    int    `json:"max_functions,omitempty"`
+	MaxBinaryBytes  int    `json:"max_binary_bytes,omitempty"`
+
+	// RequireMemoryMaximum rejects memories that may grow without a bound
+	// declared in the binary. The runtime caps memory either way.
+	RequireMemoryMaximum bool `json:"require_memory_maximum,omitempty"`
+
+	// BaseExports are required of every agent; RequiredExports adds exports
+	// per declared capability
+	BaseExports     []string            `json:"base_exports,omitempty"`
+	RequiredExports map[string][]string `json:"required_exports,omitempty"`
+
+	AllowThreads bool `json:"allow_threads"`
+	AllowSIMD    bool `json:"allow_simd"`
+}
+
+// Violation is one reason a binary was refused
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Subject string `json:"subject,omitempty"` // Offending import, export or capability
+}
+
+// PolicyReport is the outcome of admitting one binary. It is stored with the
+// agent version it was produced for.
+type PolicyReport struct {
+	Admitted   bool              `json:"admitted"`
+	Violations []Violation       `json:"violations"`
+	Module     *ValidationResult `json:"module,omitempty"`
+	Policy     *AdmissionPolicy  `json:"policy"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// DefaultAdmissionPolicy allows WASI and the zerostate host module, up to
+// 128MB of initial memory and no threads
+func DefaultAdmissionPolicy() *AdmissionPolicy {
+	return &AdmissionPolicy{
+		AllowedImportModules: []string{"wasi_snapshot_preview1", "zerostate_v*"},
+		MaxInitialPages:      2048,  // 128MB
+		MaxMaximumPages:      16384, // 1GB
+		MaxFunctions:         10000,
+		MaxBinaryBytes:       50 * 1024 * 1024,
+		BaseExports:          []string{"memory"},
+		AllowSIMD:            true,
+	}
+}
+
+// Admit validates binary and evaluates it against policy for an agent
+// declaring capabilities. Structural failures from the validator are
+// reported as violations rather than errors.
+func (v *WASMValidator) Admit(binary []byte, policy *AdmissionPolicy, capabilities []string) *PolicyReport {
+	if policy == nil {
+		policy = DefaultAdmissionPolicy()
+	}
+
+	result, err := v.Validate(bytes.NewReader(binary))
+	report := &PolicyReport{
+		Violations: []Violation{},
+		Module:     result,
+		Policy:     policy,
+		CheckedAt:  time.Now(),
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrMaliciousCode):
+		report.Violations = append(report.Violations, Violation{Rule: RuleDangerousImport, Message: err.Error()})
+	case errors.Is(err, ErrResourceLimit):
+		report.Violations = append(report.Violations, Violation{Rule: RuleResourceLimit, Message: err.Error()})
+	default:
+		// Nothing past the header can be trusted
+		report.Violations = append(report.Violations, Violation{Rule: RuleFormat, Message: err.Error()})
+		return report
+	}
+
+	report.Violations = append(report.Violations, policy.Evaluate(result, capabilities)...)
+	report.Admitted = len(report.Violations) == 0
+
+	v.logger.Info("WASM admission evaluated",
+		zap.Bool("admitted", report.Admitted),
+		zap.Int("violations", len(report.Violations)),
+	)
+	return report
+}
+
+// Evaluate checks a parsed module against the policy
+func (p *AdmissionPolicy) Evaluate(result *ValidationResult, capabilities []string) []Violation {
+	var violations []Violation
+
+	if p.MaxBinaryBytes > 0 && result.BinarySize > p.MaxBinaryBytes {
+		violations = append(violations, Violation{
+			Rule:    RuleBinarySize,
+			Message: fmt.Sprintf("binary is %d bytes, limit is %d", result.BinarySize, p.MaxBinaryBytes),
+		})
+	}
+
+	for _, module := range result.ImportedModules {
+		if !p.importAllowed(module) {
+			violations = append(violations, Violation{
+				Rule:    RuleImportNamespace,
+				Message: fmt.Sprintf("imports from %q are not allowed", module),
+				Subject: module,
+			})
+		}
+	}
+
+	if p.MaxInitialPages > 0 && result.MemorySize > p.MaxInitialPages {
+		violations = append(violations, Violation{
+			Rule:    RuleInitialMemory,
+			Message: fmt.Sprintf("initial memory is %d pages, limit is %d", result.MemorySize, p.MaxInitialPages),
+		})
+	}
+	switch {
+	case result.HasMemoryMaximum && p.MaxMaximumPages > 0 && result.MemoryMaximum > p.MaxMaximumPages:
+		violations = append(violations, Violation{
+			Rule:    RuleMaximumMemory,
+			Message: fmt.Sprintf("maximum memory is %d pages, limit is %d", result.MemoryMaximum, p.MaxMaximumPages),
+		})
+	case !result.HasMemoryMaximum && p.RequireMemoryMaximum && result.MemorySize > 0:
+		violations = append(violations, Violation{
+			Rule:    RuleMaximumMemory,
+			Message: "memory must declare a maximum",
+		})
+	}
+
+	if p.MaxFunctions > 0 && result.FunctionsCount > p.MaxFunctions {
+		violations = append(violations, Violation{
+			Rule:    RuleFunctionCount,
+			Message: fmt.Sprintf("module has %d functions, limit is %d", result.FunctionsCount, p.MaxFunctions),
+		})
+	}
+
+	if result.SharedMemory && !p.AllowThreads {
+		violations = append(violations, Violation{
+			Rule:    RuleThreads,
+			Message: "shared memory requires threads, which are not allowed",
+		})
+	}
+	if result.UsesSIMD && !p.AllowSIMD {
+		violations = append(violations, Violation{
+			Rule:    RuleSIMD,
+			Message: "module uses SIMD, which is not allowed",
+		})
+	}
+
+	exports := make(map[string]bool, len(result.Exports))
+	for _, exp := range result.Exports {
+		exports[exp.Name] = true
+	}
+	for _, name := range p.BaseExports {
+		if !exports[name] {
+			violations = append(violations, Violation{
+				Rule:    RuleRequiredExport,
+				Message: fmt.Sprintf("missing required export %q", name),
+				Subject: name,
+			})
+		}
+	}
+	for _, capability := range sortedUnique(capabilities) {
+		for _, name := range p.RequiredExports[capability] {
+			if !exports[name] {
+				violations = append(violations, Violation{
+					Rule:    RuleRequiredExport,
+					Message: fmt.Sprintf("capability %q requires export %q", capability, name),
+					Subject: capability,
+				})
+			}
+		}
+	}
+
+	return violations
+}
+
+// importAllowed reports whether module matches an allowed namespace
+func (p *AdmissionPolicy) importAllowed(module string) bool {
+	for _, pattern := range p.AllowedImportModules {
+		if ok, err := path.Match(pattern, module); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
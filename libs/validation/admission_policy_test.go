@@ -0,0 +1,199 @@
+package validation
+
+import (
+	"bytes"
+	"testing"
+)
+
+// moduleSpec describes a test module to assemble
+type moduleSpec struct {
+	imports   [][2]string // module, function name
+	functions int
+	memory    []byte // limits: flags, initial[, maximum]
+	exports   []string
+	simd      bool
+}
+
+func leb(v uint32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			out = append(out, b|0x80)
+			continue
+		}
+		return append(out, b)
+	}
+}
+
+func name(s string) []byte {
+	return append(leb(uint32(len(s))), s...)
+}
+
+func section(id byte, count int, body []byte) []byte {
+	payload := append(leb(uint32(count)), body...)
+	return append(append([]byte{id}, leb(uint32(len(payload)))...), payload...)
+}
+
+// build assembles a module whose functions all have type () -> ()
+func (m moduleSpec) build() []byte {
+	wasm := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+	types, typeCount := []byte{0x60, 0x00, 0x00}, 1
+	if m.simd {
+		types, typeCount = append(types, 0x60, 0x01, 0x7b, 0x00), 2 // (v128) -> ()
+	}
+	wasm = append(wasm, section(1, typeCount, types)...)
+
+	if len(m.imports) > 0 {
+		var body []byte
+		for _, imp := range m.imports {
+			body = append(body, name(imp[0])...)
+			body = append(body, name(imp[1])...)
+			body = append(body, 0x00, 0x00)
+		}
+		wasm = append(wasm, section(2, len(m.imports), body)...)
+	}
+
+	if m.functions > 0 {
+		wasm = append(wasm, section(3, m.functions, bytes.Repeat([]byte{0x00}, m.functions))...)
+	}
+	if m.memory != nil {
+		wasm = append(wasm, section(5, 1, m.memory)...)
+	}
+
+	var exports []byte
+	for _, exp := range m.exports {
+		exports = append(exports, name(exp)...)
+		if exp == "memory" {
+			exports = append(exports, 0x02, 0x00)
+		} else {
+			exports = append(exports, 0x00, byte(len(m.imports)))
+		}
+	}
+	wasm = append(wasm, section(7, len(m.exports), exports)...)
+
+	if m.functions > 0 {
+		wasm = append(wasm, section(10, m.functions, bytes.Repeat([]byte{0x02, 0x00, 0x0b}, m.functions))...)
+	}
+	return wasm
+}
+
+func rules(report *PolicyReport) map[string]int {
+	out := map[string]int{}
+	for _, v := range report.Violations {
+		out[v.Rule]++
+	}
+	return out
+}
+
+func TestValidateParsesModule(t *testing.T) {
+	binary := moduleSpec{
+		imports:   [][2]string{{"wasi_snapshot_preview1", "fd_write"}, {"zerostate_v1", "log"}},
+		functions: 200, // Count needs a multi-byte LEB128
+		memory:    []byte{0x01, 0x11, 0x80, 0x02},
+		exports:   []string{"memory", "execute"},
+	}.build()
+
+	result, err := NewWASMValidator(nil).Validate(bytes.NewReader(binary))
+	if err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+	if result.FunctionsCount != 202 {
+		t.Errorf("expected 202 functions, got %d", result.FunctionsCount)
+	}
+	if len(result.ImportedModules) != 2 || result.ImportedModules[1] != "zerostate_v1" {
+		t.Errorf("unexpected imported modules %v", result.ImportedModules)
+	}
+	if result.MemorySize != 17 || !result.HasMemoryMaximum || result.MemoryMaximum != 256 {
+		t.Errorf("unexpected memory %d/%d", result.MemorySize, result.MemoryMaximum)
+	}
+	if len(result.ExportedFunctions) != 1 || result.ExportedFunctions[0] != "execute" {
+		t.Errorf("unexpected exported functions %v", result.ExportedFunctions)
+	}
+
+	// Truncated sections are rejected instead of skipped
+	if _, err := NewWASMValidator(nil).Validate(bytes.NewReader(binary[:len(binary)-5])); err == nil {
+		t.Error("expected truncated module to be rejected")
+	}
+}
+
+func TestAdmitDefaultPolicy(t *testing.T) {
+	binary := moduleSpec{
+		imports:   [][2]string{{"wasi_snapshot_preview1", "fd_write"}, {"zerostate_v1", "log"}},
+		functions: 1,
+		memory:    []byte{0x00, 0x01},
+		exports:   []string{"memory", "execute"},
+	}.build()
+
+	report := NewWASMValidator(nil).Admit(binary, nil, []string{"math"})
+	if !report.Admitted {
+		t.Fatalf("expected module to be admitted, got %+v", report.Violations)
+	}
+	if report.Module == nil || report.Policy == nil {
+		t.Error("expected report to carry module facts and policy")
+	}
+}
+
+func TestAdmitReportsEveryViolation(t *testing.T) {
+	binary := moduleSpec{
+		imports:   [][2]string{{"env", "abort"}, {"wasi_snapshot_preview1", "fd_write"}},
+		functions: 3,
+		memory:    []byte{0x03, 0x20, 0x40}, // Shared, 32 initial, 64 maximum
+		exports:   []string{"run"},
+		simd:      true,
+	}.build()
+
+	policy := DefaultAdmissionPolicy()
+	policy.MaxInitialPages = 16
+	policy.MaxMaximumPages = 32
+	policy.MaxFunctions = 2
+	policy.MaxBinaryBytes = 32
+	policy.AllowSIMD = false
+	policy.RequiredExports = map[string][]string{"math": {"execute", "get_result_ptr"}}
+
+	report := NewWASMValidator(nil).Admit(binary, policy, []string{"math", "math", "text"})
+	if report.Admitted {
+		t.Fatal("expected module to be refused")
+	}
+
+	got := rules(report)
+	want := map[string]int{
+		RuleImportNamespace: 1,
+		RuleInitialMemory:   1,
+		RuleMaximumMemory:   1,
+		RuleFunctionCount:   1,
+		RuleBinarySize:      1,
+		RuleThreads:         1,
+		RuleSIMD:            1,
+		RuleRequiredExport:  3, // memory, and two for math
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("expected %d %s violations, got %d (%+v)", n, rule, got[rule], report.Violations)
+		}
+	}
+	for _, v := range report.Violations {
+		if v.Rule == RuleImportNamespace && v.Subject != "env" {
+			t.Errorf("expected env to be the refused namespace, got %q", v.Subject)
+		}
+	}
+}
+
+func TestAdmitRejectsMalformedBinary(t *testing.T) {
+	report := NewWASMValidator(nil).Admit([]byte("not wasm at all"), nil, nil)
+	if report.Admitted {
+		t.Fatal("expected malformed binary to be refused")
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Rule != RuleFormat {
+		t.Errorf("expected a single format violation, got %+v", report.Violations)
+	}
+
+	// A dangerous namespace is reported alongside policy violations
+	binary := moduleSpec{imports: [][2]string{{"process", "spawn"}}, memory: []byte{0x00, 0x01}, exports: []string{"memory"}}.build()
+	got := rules(NewWASMValidator(nil).Admit(binary, nil, nil))
+	if got[RuleDangerousImport] != 1 || got[RuleImportNamespace] != 1 {
+		t.Errorf("expected dangerous import and namespace violations, got %v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ValidateJSONSchema validates a decoded JSON value against the supported
+// subset of JSON Schema: type, enum, required, properties,
+// additionalProperties (bool), items, minimum, maximum, minLength, maxLength,
+// minItems and maxItems. It returns one message per violation, prefixed with
+// the path to the value.
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var errs []string
+
+	if t, ok := schema["type"].(string); ok && !jsonTypeMatches(t, value) {
+		return append(errs, fmt.Sprintf("%s: expected %s", path, t))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: value not in enum", path))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := v[name]; !present {
+						errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, child := range v {
+			if propSchema, ok := props[name].(map[string]interface{}); ok {
+				errs = append(errs, ValidateJSONSchema(propSchema, child, path+"."+name)...)
+			} else if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+				errs = append(errs, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+		}
+
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			errs = append(errs, fmt.Sprintf("%s: fewer than %v items", path, min))
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			errs = append(errs, fmt.Sprintf("%s: more than %v items", path, max))
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, ValidateJSONSchema(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case string:
+		if min, ok := schema["minLength"].(float64); ok && float64(len(v)) < min {
+			errs = append(errs, fmt.Sprintf("%s: shorter than %v", path, min))
+		}
+		if max, ok := schema["maxLength"].(float64); ok && float64(len(v)) > max {
+			errs = append(errs, fmt.Sprintf("%s: longer than %v", path, max))
+		}
+
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			errs = append(errs, fmt.Sprintf("%s: %v is below minimum %v", path, v, min))
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			errs = append(errs, fmt.Sprintf("%s: %v exceeds maximum %v", path, v, max))
+		}
+	}
+
+	return errs
+}
+
+// jsonTypeMatches reports whether a decoded JSON value has the given schema type
+func jsonTypeMatches(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ab) == string(bb)
+}
@@ -0,0 +1,46 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["name", "count"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 2},
+			"count": {"type": "integer", "minimum": 0, "maximum": 10},
+			"mode": {"enum": ["fast", "slow"]},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+		}
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"valid", `{"name": "ok", "count": 3, "mode": "fast", "tags": ["a"]}`, 0},
+		{"wrong type", `[]`, 1},
+		{"missing required", `{"name": "ok"}`, 1},
+		{"unexpected property", `{"name": "ok", "count": 1, "extra": true}`, 1},
+		{"nested violations", `{"name": "x", "count": 1.5, "mode": "warp", "tags": ["a", 2, "c"]}`, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if errs := ValidateJSONSchema(schema, value, "$"); len(errs) != tt.want {
+				t.Errorf("expected %d violations, got %v", tt.want, errs)
+			}
+		})
+	}
+}
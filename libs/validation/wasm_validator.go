@@ -10,28 +10,59 @@ import (
 )
 
 var (
-	ErrInvalidWASM       = errors.New("invalid WASM binary")
+	ErrInvalidWASM        = errors.New("invalid WASM binary")
 	ErrUnsupportedVersion = errors.New("unsupported WASM version")
-	ErrMaliciousCode     = errors.New("potentially malicious code detected")
-	ErrResourceLimit     = errors.New("resource limits exceeded")
+	ErrMaliciousCode      = errors.New("potentially malicious code detected")
+	ErrResourceLimit      = errors.New("resource limits exceeded")
 )
 
+// External kinds used by import and export entries
+const (
+	KindFunction = "function"
+	KindTable    = "table"
+	KindMemory   = "memory"
+	KindGlobal   = "global"
+)
+
+// valTypeV128 is the SIMD vector value type
+const valTypeV128 = 0x7b
+
 // WASMValidator validates WASM binaries
 type WASMValidator struct {
 	logger *zap.Logger
 }
 
+// Import is one entry of the import section
+type Import struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+}
+
+// Export is one entry of the export section
+type Export struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
 // ValidationResult contains validation results
 type ValidationResult struct {
-	IsValid           bool     `json:"is_valid"`
-	ErrorMessage      string   `json:"error_message,omitempty"`
-	Version           uint32   `json:"version,omitempty"`
-	ImportedModules   []string `json:"imported_modules,omitempty"`
-	ExportedFunctions []string `json:"exported_functions,omitempty"`
-	MemorySize        uint32   `json:"memory_size,omitempty"`    // Pages
-	TableSize         uint32   `json:"table_size,omitempty"`      // Elements
-	GlobalsCount      int      `json:"globals_count,omitempty"`
-	FunctionsCount    int      `json:"functions_count,omitempty"`
+	IsValid           bool                   `json:"is_valid"`
+	ErrorMessage      string                 `json:"error_message,omitempty"`
+	Version           uint32                 `json:"version,omitempty"`
+	BinarySize        int                    `json:"binary_size,omitempty"`
+	ImportedModules   []string               `json:"imported_modules,omitempty"`
+	ExportedFunctions []string               `json:"exported_functions,omitempty"`
+	Imports           []Import               `json:"imports,omitempty"`
+	Exports           []Export               `json:"exports,omitempty"`
+	MemorySize        uint32                 `json:"memory_size,omitempty"`    // Initial pages
+	MemoryMaximum     uint32                 `json:"memory_maximum,omitempty"` // Maximum pages, when declared
+	HasMemoryMaximum  bool                   `json:"has_memory_maximum,omitempty"`
+	SharedMemory      bool                   `json:"shared_memory,omitempty"` // Requires threads
+	UsesSIMD          bool                   `json:"uses_simd,omitempty"`     // v128 in a signature, global or local
+	TableSize         uint32                 `json:"table_size,omitempty"`    // Elements
+	GlobalsCount      int                    `json:"globals_count,omitempty"`
+	FunctionsCount    int                    `json:"functions_count,omitempty"` // Defined and imported
 	Details           map[string]interface{} `json:"details,omitempty"`
 }
 
@@ -56,6 +87,7 @@ func (v *WASMValidator) Validate(reader io.Reader) (*ValidationResult, error) {
 
 	result := &ValidationResult{
 		IsValid:           false,
+		BinarySize:        len(data),
 		ImportedModules:   []string{},
 		ExportedFunctions: []string{},
 		Details:           make(map[string]interface{}),
@@ -89,10 +121,9 @@ func (v *WASMValidator) Validate(reader io.Reader) (*ValidationResult, error) {
 	)
 
 	// Parse WASM sections to extract metadata
-	// This is a simplified parser - for production, use a full WASM parser library
 	if err := v.parseSections(data[8:], result); err != nil {
 		result.ErrorMessage = err.Error()
-		return result, err
+		return result, fmt.Errorf("%w: %v", ErrInvalidWASM, err)
 	}
 
 	// Security checks
@@ -110,8 +141,8 @@ func (v *WASMValidator) Validate(reader io.Reader) (*ValidationResult, error) {
 	result.IsValid = true
 
 	v.logger.Info("WASM validation passed",
-		zap.Int("imports", len(result.ImportedModules)),
-		zap.Int("exports", len(result.ExportedFunctions)),
+		zap.Int("imports", len(result.Imports)),
+		zap.Int("exports", len(result.Exports)),
 		zap.Uint32("memory_pages", result.MemorySize),
 		zap.Int("functions", result.FunctionsCount),
 	)
@@ -119,119 +150,429 @@ func (v *WASMValidator) Validate(reader io.Reader) (*ValidationResult, error) {
 	return result, nil
 }
 
-// parseSections parses WASM sections (simplified)
+// parseSections walks the module's sections. Only the structure needed for
+// admission is decoded; instruction bodies are skipped.
 func (v *WASMValidator) parseSections(data []byte, result *ValidationResult) error {
-	offset := 0
-	functionsCount := 0
-	globalsCount := 0
+	r := &byteReader{data: data}
+	modules := map[string]bool{}
 
-	for offset < len(data) {
-		if offset+1 >= len(data) {
-			break
+	for !r.done() {
+		sectionID, err := r.byte()
+		if err != nil {
+			return err
 		}
-
-		sectionID := data[offset]
-		offset++
-
-		// Read section size (LEB128 encoding - simplified)
-		sectionSize, bytesRead := v.readVarUint32(data[offset:])
-		if bytesRead == 0 {
-			break
+		sectionSize, err := r.u32()
+		if err != nil {
+			return fmt.Errorf("section %d: %w", sectionID, err)
 		}
-		offset += bytesRead
-
-		if offset+int(sectionSize) > len(data) {
-			break
+		body, err := r.bytes(int(sectionSize))
+		if err != nil {
+			return fmt.Errorf("section %d: %w", sectionID, err)
 		}
-
-		sectionData := data[offset : offset+int(sectionSize)]
+		section := &byteReader{data: body}
 
 		switch sectionID {
 		case 1: // Type section
-			// Parse function signatures
+			err = v.parseTypeSection(section, result)
 		case 2: // Import section
-			imports := v.parseImportSection(sectionData)
-			result.ImportedModules = append(result.ImportedModules, imports...)
+			err = v.parseImportSection(section, result)
+			for _, imp := range result.Imports {
+				if !modules[imp.Module] {
+					modules[imp.Module] = true
+					result.ImportedModules = append(result.ImportedModules, imp.Module)
+				}
+			}
 		case 3: // Function section
-			functionsCount = v.countFunctions(sectionData)
-			result.FunctionsCount = functionsCount
+			var count uint32
+			count, err = section.u32()
+			result.FunctionsCount += int(count)
+		case 4: // Table section
+			err = v.parseTableSection(section, result)
 		case 5: // Memory section
-			result.MemorySize = v.parseMemorySection(sectionData)
+			err = v.parseMemorySection(section, result)
 		case 6: // Global section
-			globalsCount = v.countGlobals(sectionData)
-			result.GlobalsCount = globalsCount
+			err = v.parseGlobalSection(section, result)
 		case 7: // Export section
-			exports := v.parseExportSection(sectionData)
-			result.ExportedFunctions = append(result.ExportedFunctions, exports...)
-		case 4: // Table section
-			result.TableSize = v.parseTableSection(sectionData)
+			err = v.parseExportSection(section, result)
+		case 10: // Code section
+			err = v.parseCodeSection(section, result)
+		}
+		if err != nil {
+			return fmt.Errorf("section %d: %w", sectionID, err)
 		}
-
-		offset += int(sectionSize)
 	}
 
 	return nil
 }
 
-// readVarUint32 reads a LEB128-encoded unsigned integer (simplified)
-func (v *WASMValidator) readVarUint32(data []byte) (uint32, int) {
-	if len(data) == 0 {
-		return 0, 0
+// parseTypeSection flags SIMD when a signature uses v128
+func (v *WASMValidator) parseTypeSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return fmt.Errorf("unsupported type form 0x%x", form)
+		}
+		// Params then results
+		for j := 0; j < 2; j++ {
+			n, err := r.u32()
+			if err != nil {
+				return err
+			}
+			types, err := r.bytes(int(n))
+			if err != nil {
+				return err
+			}
+			if bytes.IndexByte(types, valTypeV128) >= 0 {
+				result.UsesSIMD = true
+			}
+		}
 	}
+	return nil
+}
 
-	// Simplified: just read first byte for small values
-	// Real implementation would handle multi-byte LEB128
-	value := uint32(data[0] & 0x7F)
-	if data[0]&0x80 == 0 {
-		return value, 1
+// parseImportSection records every import entry
+func (v *WASMValidator) parseImportSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
 	}
+	for i := uint32(0); i < count; i++ {
+		module, err := r.name()
+		if err != nil {
+			return err
+		}
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
 
-	// For larger values, approximate
-	return value, 1
+		imp := Import{Module: module, Name: name}
+		switch kind {
+		case 0x00:
+			imp.Kind = KindFunction
+			_, err = r.u32()
+			result.FunctionsCount++
+		case 0x01:
+			imp.Kind = KindTable
+			var size uint32
+			size, err = readTable(r)
+			result.TableSize += size
+		case 0x02:
+			imp.Kind = KindMemory
+			err = readMemory(r, result)
+		case 0x03:
+			imp.Kind = KindGlobal
+			var valType byte
+			if valType, err = r.byte(); err == nil {
+				_, err = r.byte() // Mutability
+			}
+			if valType == valTypeV128 {
+				result.UsesSIMD = true
+			}
+			result.GlobalsCount++
+		default:
+			return fmt.Errorf("unknown import kind 0x%x", kind)
+		}
+		if err != nil {
+			return err
+		}
+		result.Imports = append(result.Imports, imp)
+	}
+	return nil
 }
 
-// parseImportSection extracts imported modules (simplified)
-func (v *WASMValidator) parseImportSection(data []byte) []string {
-	// Simplified: return placeholder
-	// Real implementation would parse import entries
-	return []string{"env", "wasi_snapshot_preview1"}
-}
+// parseExportSection records every export entry
+func (v *WASMValidator) parseExportSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if _, err := r.u32(); err != nil {
+			return err
+		}
 
-// parseExportSection extracts exported functions (simplified)
-func (v *WASMValidator) parseExportSection(data []byte) []string {
-	// Simplified: return common exports
-	// Real implementation would parse export entries
-	return []string{"_start", "memory"}
+		exp := Export{Name: name}
+		switch kind {
+		case 0x00:
+			exp.Kind = KindFunction
+			result.ExportedFunctions = append(result.ExportedFunctions, name)
+		case 0x01:
+			exp.Kind = KindTable
+		case 0x02:
+			exp.Kind = KindMemory
+		case 0x03:
+			exp.Kind = KindGlobal
+		default:
+			return fmt.Errorf("unknown export kind 0x%x", kind)
+		}
+		result.Exports = append(result.Exports, exp)
+	}
+	return nil
 }
 
 // parseMemorySection extracts memory configuration
-func (v *WASMValidator) parseMemorySection(data []byte) uint32 {
-	if len(data) < 2 {
-		return 0
+func (v *WASMValidator) parseMemorySection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if err := readMemory(r, result); err != nil {
+			return err
+		}
 	}
-	// Simplified: return default
-	return 16 // 16 pages = 1MB
+	return nil
 }
 
 // parseTableSection extracts table configuration
-func (v *WASMValidator) parseTableSection(data []byte) uint32 {
-	// Simplified: return default
-	return 0
+func (v *WASMValidator) parseTableSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		size, err := readTable(r)
+		if err != nil {
+			return err
+		}
+		result.TableSize += size
+	}
+	return nil
 }
 
-// countFunctions counts function definitions
-func (v *WASMValidator) countFunctions(data []byte) int {
-	if len(data) == 0 {
-		return 0
+// parseGlobalSection counts globals. Initializers are constant expressions
+// and are skipped up to their end opcode.
+func (v *WASMValidator) parseGlobalSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
 	}
-	// Simplified: return approximate count
-	return 10
+	for i := uint32(0); i < count; i++ {
+		valType, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if valType == valTypeV128 {
+			result.UsesSIMD = true
+		}
+		if _, err := r.byte(); err != nil { // Mutability
+			return err
+		}
+		if err := skipConstExpr(r); err != nil {
+			return err
+		}
+		result.GlobalsCount++
+	}
+	return nil
 }
 
-// countGlobals counts global variables
-func (v *WASMValidator) countGlobals(data []byte) int {
-	// Simplified
-	return 0
+// parseCodeSection flags SIMD when a function declares v128 locals
+func (v *WASMValidator) parseCodeSection(r *byteReader, result *ValidationResult) error {
+	count, err := r.u32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		body, err := r.bytes(int(size))
+		if err != nil {
+			return err
+		}
+		locals := &byteReader{data: body}
+		groups, err := locals.u32()
+		if err != nil {
+			return err
+		}
+		for j := uint32(0); j < groups; j++ {
+			if _, err := locals.u32(); err != nil {
+				return err
+			}
+			valType, err := locals.byte()
+			if err != nil {
+				return err
+			}
+			if valType == valTypeV128 {
+				result.UsesSIMD = true
+			}
+		}
+	}
+	return nil
+}
+
+// readMemory reads memory limits. Flag bit 0 means a maximum follows and
+// bit 1 marks shared memory from the threads proposal.
+func readMemory(r *byteReader, result *ValidationResult) error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if flags > 0x03 {
+		return fmt.Errorf("unsupported memory flags 0x%x", flags)
+	}
+	initial, err := r.u32()
+	if err != nil {
+		return err
+	}
+	result.MemorySize += initial
+	if flags&0x01 != 0 {
+		maximum, err := r.u32()
+		if err != nil {
+			return err
+		}
+		result.MemoryMaximum += maximum
+		result.HasMemoryMaximum = true
+	}
+	if flags&0x02 != 0 {
+		result.SharedMemory = true
+	}
+	return nil
+}
+
+// readTable reads a table type and returns its initial size
+func readTable(r *byteReader) (uint32, error) {
+	if _, err := r.byte(); err != nil { // Element type
+		return 0, err
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	initial, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	if flags&0x01 != 0 {
+		if _, err := r.u32(); err != nil {
+			return 0, err
+		}
+	}
+	return initial, nil
+}
+
+// skipConstExpr skips a constant expression through its end opcode
+func skipConstExpr(r *byteReader) error {
+	for {
+		op, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case 0x0b: // end
+			return nil
+		case 0x41, 0x42, 0x23, 0xd2: // i32.const, i64.const, global.get, ref.func
+			err = r.skipLEB()
+		case 0x43: // f32.const
+			_, err = r.bytes(4)
+		case 0x44: // f64.const
+			_, err = r.bytes(8)
+		case 0xd0: // ref.null
+			_, err = r.byte()
+		case 0xfd: // v128.const
+			if _, err = r.u32(); err == nil {
+				_, err = r.bytes(16)
+			}
+		default:
+			return fmt.Errorf("unsupported constant expression opcode 0x%x", op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// byteReader decodes the primitive encodings used by the binary format
+type byteReader struct {
+	data []byte
+	pos  int
+}
+
+var errTruncated = errors.New("unexpected end of section")
+
+func (r *byteReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *byteReader) byte() (byte, error) {
+	if r.done() {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// u32 reads an unsigned LEB128 integer of at most five bytes
+func (r *byteReader) u32() (uint32, error) {
+	var value uint32
+	for shift := uint(0); shift < 35; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errors.New("LEB128 integer too long")
+}
+
+// skipLEB skips a signed or unsigned LEB128 integer
+func (r *byteReader) skipLEB() error {
+	for i := 0; i < 10; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b&0x80 == 0 {
+			return nil
+		}
+	}
+	return errors.New("LEB128 integer too long")
+}
+
+func (r *byteReader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
 }
 
 // performSecurityChecks checks for potentially malicious patterns
@@ -16,6 +16,7 @@ type Message struct {
 	Timestamp time.Time              `json:"timestamp"`  // Message timestamp
 	Data      map[string]interface{} `json:"data"`       // Message payload
 	UserID    string                 `json:"user_id,omitempty"` // Target user (for private messages)
+	TaskID    string                 `json:"task_id,omitempty"` // Target task (for task subscribers)
 }
 
 // Client represents a WebSocket client connection
@@ -28,6 +29,12 @@ type Client struct {
 	Logger   *zap.Logger         // Client logger
 	ctx      context.Context     // Client context
 	cancel   context.CancelFunc  // Cancel function
+
+	// AuthorizeTask decides whether the client may subscribe to a task's
+	// updates; when nil, every subscription is refused
+	AuthorizeTask func(taskID string) bool
+	tasks         map[string]bool // Subscribed task IDs
+	tasksMu       sync.RWMutex
 }
 
 // Hub maintains active WebSocket connections and broadcasts messages
@@ -41,6 +48,7 @@ type Hub struct {
 	// Message broadcasting
 	broadcast  chan *Message        // Broadcast to all clients
 	userMsg    chan *Message        // Send to specific user
+	taskMsg    chan *Message        // Send to subscribers of a task
 
 	// Configuration
 	logger     *zap.Logger          // Hub logger
@@ -67,6 +75,7 @@ func NewHub(ctx context.Context, logger *zap.Logger) *Hub {
 		unregister: make(chan *Client, 10),
 		broadcast:  make(chan *Message, 100),
 		userMsg:    make(chan *Message, 100),
+		taskMsg:    make(chan *Message, 100),
 		logger:     logger,
 		ctx:        hubCtx,
 		cancel:     cancel,
@@ -168,6 +177,25 @@ func (h *Hub) Start() {
 					)
 				}
 
+			case message := <-h.taskMsg:
+				// Send message to the task's subscribers
+				h.clientsMu.RLock()
+				for client := range h.clients {
+					if !client.Subscribed(message.TaskID) {
+						continue
+					}
+					select {
+					case client.Send <- message:
+						h.messagesSent++
+					default:
+						h.logger.Warn("failed to send task message, buffer full",
+							zap.String("client_id", client.ID),
+							zap.String("task_id", message.TaskID),
+						)
+					}
+				}
+				h.clientsMu.RUnlock()
+
 			case <-h.ctx.Done():
 				h.logger.Info("WebSocket hub shutting down")
 				return
@@ -242,6 +270,76 @@ func (h *Hub) SendToUser(userID string, msgType string, data map[string]interfac
 	}
 }
 
+// SendToTask sends a message to the clients subscribed to a task
+func (h *Hub) SendToTask(taskID string, msgType string, data map[string]interface{}) {
+	message := &Message{
+		Type:      msgType,
+		Timestamp: time.Now(),
+		Data:      data,
+		TaskID:    taskID,
+	}
+
+	select {
+	case h.taskMsg <- message:
+	default:
+		h.logger.Warn("task message channel full, dropping message",
+			zap.String("task_id", taskID),
+		)
+	}
+}
+
+// BroadcastTaskUpdate sends a task status change to the task's subscribers
+func (h *Hub) BroadcastTaskUpdate(taskID, status, message string) error {
+	h.SendToTask(taskID, "task_update", map[string]interface{}{
+		"task_id": taskID,
+		"status":  status,
+		"message": message,
+	})
+	return nil
+}
+
+// Subscribed reports whether the client receives a task's updates
+func (c *Client) Subscribed(taskID string) bool {
+	c.tasksMu.RLock()
+	defer c.tasksMu.RUnlock()
+	return c.tasks[taskID]
+}
+
+// handleTaskSubscription subscribes or unsubscribes the client from the task
+// named in msg, replying with the outcome
+func (c *Client) handleTaskSubscription(msg *Message, subscribe bool) {
+	taskID, _ := msg.Data["task_id"].(string)
+	reply := &Message{
+		Type:      "subscribed",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"task_id": taskID},
+		TaskID:    taskID,
+	}
+
+	switch {
+	case !subscribe:
+		c.tasksMu.Lock()
+		delete(c.tasks, taskID)
+		c.tasksMu.Unlock()
+		reply.Type = "unsubscribed"
+	case taskID == "" || c.AuthorizeTask == nil || !c.AuthorizeTask(taskID):
+		reply.Type = "error"
+		reply.Data["message"] = "not allowed to subscribe to task"
+	default:
+		c.tasksMu.Lock()
+		c.tasks[taskID] = true
+		c.tasksMu.Unlock()
+	}
+
+	select {
+	case c.Send <- reply:
+	default:
+		c.Logger.Warn("failed to reply to subscription, buffer full",
+			zap.String("client_id", c.ID),
+		)
+	}
+}
+
 // GetStats returns hub statistics
 func (h *Hub) GetStats() map[string]interface{} {
 	h.clientsMu.RLock()
@@ -305,6 +403,10 @@ func (c *Client) ReadPump() {
 						"received": msg.Timestamp,
 					},
 				}
+			case "subscribe_task":
+				c.handleTaskSubscription(&msg, true)
+			case "unsubscribe_task":
+				c.handleTaskSubscription(&msg, false)
 			default:
 				c.Logger.Debug("unhandled message type",
 					zap.String("type", msg.Type),
@@ -372,6 +474,7 @@ func NewClient(conn *websocket.Conn, userID string, hub *Hub, logger *zap.Logger
 		Logger: logger.With(zap.String("client_type", "websocket")),
 		ctx:    ctx,
 		cancel: cancel,
+		tasks:  make(map[string]bool),
 	}
 }
 
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aidenlippert/zerostate/reference-runtime-v1/internal/market"
+	"github.com/aidenlippert/zerostate/reference-runtime-v1/internal/sim"
+	"go.uber.org/zap"
+)
+
+// market-sim runs the built-in market scenario against every pricing
+// strategy and exits non-zero when the run misses the given thresholds, so
+// pricing regressions fail CI before they reach production runtimes
+func main() {
+	seed := flag.Int64("seed", 1, "Random seed for task arrivals and budgets")
+	duration := flag.Duration("duration", 6*time.Hour, "Simulated time horizon")
+	mechanism := flag.String("mechanism", string(sim.MechanismVCG), "Auction mechanism: vcg or first-price")
+	minEfficiency := flag.Float64("min-efficiency", 0, "Fail if allocative efficiency is below this")
+	minAllocation := flag.Float64("min-allocation", 0, "Fail if the share of allocated tasks is below this")
+	requireConvergence := flag.Bool("require-convergence", false, "Fail if clearing prices have not converged")
+	jsonOut := flag.String("json", "", "Write the full report as JSON to this path")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	simulator, err := sim.New(sim.Config{
+		Seed:      *seed,
+		Start:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration:  *duration,
+		Mechanism: sim.Mechanism(*mechanism),
+		Streams:   defaultStreams(),
+		Bidders:   defaultBidders(),
+	}, logger)
+	if err != nil {
+		logger.Fatal("Invalid simulation config", zap.Error(err))
+	}
+
+	report, err := simulator.Run(context.Background())
+	if err != nil {
+		logger.Fatal("Simulation failed", zap.Error(err))
+	}
+
+	for _, bidder := range report.Bidders {
+		logger.Info("Bidder result",
+			zap.String("id", bidder.ID),
+			zap.String("strategy", bidder.Strategy),
+			zap.Int("bids", bidder.Bids),
+			zap.Float64("win_rate", bidder.WinRate),
+			zap.Float64("mean_bid", bidder.MeanBid),
+			zap.Float64("profit", bidder.Profit),
+		)
+	}
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			logger.Fatal("Failed to encode report", zap.Error(err))
+		}
+		if err := os.WriteFile(*jsonOut, data, 0644); err != nil {
+			logger.Fatal("Failed to write report", zap.String("path", *jsonOut), zap.Error(err))
+		}
+	}
+
+	failures := report.Check(sim.Thresholds{
+		MinEfficiency:      *minEfficiency,
+		MinAllocationRate:  *minAllocation,
+		RequireConvergence: *requireConvergence,
+	})
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			logger.Error("Threshold missed", zap.String("reason", failure))
+		}
+		os.Exit(1)
+	}
+
+	logger.Info("Simulation passed",
+		zap.Int("tasks", report.Tasks),
+		zap.Float64("efficiency", report.Efficiency),
+		zap.Float64("revenue", report.Revenue),
+		zap.Float64("win_gini", report.WinGini),
+	)
+}
+
+func defaultStreams() []sim.TaskStream {
+	return []sim.TaskStream{
+		{Capability: "math", RatePerMinute: 20, BudgetMean: 20, BudgetStdDev: 4, MeanDuration: 15 * time.Second},
+		{Capability: "json", RatePerMinute: 5, BudgetMean: 60, BudgetStdDev: 10, MeanDuration: time.Minute},
+	}
+}
+
+// defaultBidders fields one runtime per strategy with differing private costs
+func defaultBidders() []sim.BidderSpec {
+	logger := zap.NewNop()
+	capabilities := []string{"math", "json"}
+
+	return []sim.BidderSpec{
+		{
+			ID:           "did:sim:static",
+			Capabilities: capabilities,
+			Strategy:     market.NewStaticFloorPricing(15, logger),
+			MaxTasks:     4,
+			Cost:         8,
+			Reputation:   600,
+			Reliability:  0.98,
+		},
+		{
+			ID:           "did:sim:load-aware",
+			Capabilities: capabilities,
+			Strategy:     market.NewLoadAwarePricing(12, 2, 2, logger),
+			MaxTasks:     4,
+			Cost:         7,
+			Reputation:   700,
+			Reliability:  0.97,
+		},
+		{
+			ID:           "did:sim:competitive",
+			Capabilities: capabilities,
+			Strategy:     market.NewCompetitivePricing(16, 0.3, 0.05, 0.1, logger),
+			MaxTasks:     4,
+			Cost:         9,
+			Reputation:   500,
+			Reliability:  0.95,
+		},
+		{
+			ID:           "did:sim:hybrid",
+			Capabilities: capabilities,
+			Strategy:     market.NewHybridPricing(16, 0.3, 0.05, 0.1, 2, logger),
+			MaxTasks:     4,
+			Cost:         6,
+			Reputation:   800,
+			Reliability:  0.99,
+		},
+		{
+			ID:           "did:sim:rl",
+			Capabilities: capabilities,
+			Strategy:     market.NewRLPricing(market.NewRLBidder("did:sim:rl", capabilities, logger), logger),
+			MaxTasks:     4,
+			Cost:         7,
+			Reputation:   650,
+			Reliability:  0.97,
+		},
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"time"
@@ -32,14 +33,23 @@ type RuntimeConfig struct {
 		Name    string `yaml:"name"`
 		Version string `yaml:"version"`
 		Runtime struct {
-			Type string `yaml:"type"`
-			Path string `yaml:"path"`
+			Type     string `yaml:"type"` // "wasm" (default) or "native"
+			Path     string `yaml:"path"`
+			CacheDir string `yaml:"cache_dir"` // Optional: persists compiled modules
+
+			// Native runtime: the command run for each task, sandboxed
+			Command      []string `yaml:"command"`
+			Env          []string `yaml:"env"`
+			Network      bool     `yaml:"network"`       // Optional: share the host network
+			CgroupParent string   `yaml:"cgroup_parent"` // Optional: delegated cgroup v2 for tasks
 		} `yaml:"runtime"`
 		Capabilities []string `yaml:"capabilities"`
 		Limits       struct {
 			MaxMemoryMB        int32 `yaml:"max_memory_mb"`
 			MaxExecutionTimeMS int32 `yaml:"max_execution_time_ms"`
 			MaxConcurrentTasks int32 `yaml:"max_concurrent_tasks"`
+			MaxProcesses       int32 `yaml:"max_processes"`  // Native runtime only
+			MaxOpenFiles       int32 `yaml:"max_open_files"` // Native runtime only
 		} `yaml:"limits"`
 	} `yaml:"agent"`
 
@@ -62,6 +72,11 @@ type RuntimeConfig struct {
 }
 
 func main() {
+	// Native tasks are sandboxed by re-executing this binary as a shim
+	if task.IsSandboxShim() {
+		task.RunSandboxShim()
+	}
+
 	// Parse flags
 	configPath := flag.String("agent-config", "testdata/math-agent.yaml", "Path to agent configuration file")
 	flag.Parse()
@@ -97,6 +112,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	native := config.Agent.Runtime.Type == "native"
+	runtimeMetadata := map[string]string{"wasm_path": config.Agent.Runtime.Path}
+	if native {
+		runtimeMetadata = map[string]string{"command": strings.Join(config.Agent.Runtime.Command, " ")}
+	}
+
 	// Initialize services
 	agentService := agent.NewService(&agent.Config{
 		DID:          config.Agent.DID,
@@ -104,11 +125,9 @@ func main() {
 		Version:      config.Agent.Version,
 		Capabilities: config.Agent.Capabilities,
 		RuntimeInfo: &agent.RuntimeInfo{
-			Type:    config.Agent.Runtime.Type,
-			Version: "1.0.0",
-			Metadata: map[string]string{
-				"wasm_path": config.Agent.Runtime.Path,
-			},
+			Type:     config.Agent.Runtime.Type,
+			Version:  "1.0.0",
+			Metadata: runtimeMetadata,
 		},
 		Limits: &agent.ResourceLimits{
 			MaxMemoryMB:        config.Agent.Limits.MaxMemoryMB,
@@ -119,11 +138,29 @@ func main() {
 
 	healthService := health.NewService(logger)
 
-	taskService, err := task.NewService(config.Agent.Runtime.Path, logger)
-	if err != nil {
-		logger.Fatal("Failed to create task service",
-			zap.Error(err),
-		)
+	var taskService *task.Service
+	if native {
+		executor, err := task.NewNativeExecutor(nativeConfig(config), logger)
+		if err != nil {
+			logger.Fatal("Failed to create native executor",
+				zap.Error(err),
+			)
+		}
+		taskService = task.NewServiceWithExecutor(executor, logger)
+	} else {
+		moduleCache, err := task.NewModuleCache(config.Agent.Runtime.CacheDir, logger)
+		if err != nil {
+			logger.Fatal("Failed to create module cache",
+				zap.Error(err),
+			)
+		}
+
+		taskService, err = task.NewService(config.Agent.Runtime.Path, moduleCache, logger)
+		if err != nil {
+			logger.Fatal("Failed to create task service",
+				zap.Error(err),
+			)
+		}
 	}
 
 	// Create gRPC server
@@ -189,17 +226,24 @@ func main() {
 			Interfaces: []string{"grpc", "p2p"},
 		}
 
+		wasmEngine, wasmVersion := "wasmtime", "14.0.0"
+		networkEnabled := true
+		if native {
+			wasmEngine, wasmVersion = "", ""
+			networkEnabled = config.Agent.Runtime.Network
+		}
+
 		runtime := agentcard.RuntimeInfo{
 			Protocol:       "ari-v1",
 			Implementation: "reference-runtime-v1",
 			Version:        config.Agent.Version,
-			WasmEngine:     "wasmtime",
-			WasmVersion:    "14.0.0",
-			ModuleHash:     fmt.Sprintf("file://%s", config.Agent.Runtime.Path),
+			WasmEngine:     wasmEngine,
+			WasmVersion:    wasmVersion,
+			ModuleHash:     "sha256:" + taskService.ModuleHash(),
 			ExecutionEnvironment: agentcard.ExecutionEnvironment{
 				MemoryLimitMB:     uint32(config.Agent.Limits.MaxMemoryMB),
 				CPUQuotaMs:        1000,
-				NetworkEnabled:    true,
+				NetworkEnabled:    networkEnabled,
 				FilesystemEnabled: false,
 			},
 			Endpoints: []agentcard.Endpoint{
@@ -372,6 +416,27 @@ func loadConfig(path string) (*RuntimeConfig, error) {
 	return &config, nil
 }
 
+// nativeConfig builds the native executor's configuration. The execution
+// time limit becomes a CPU time limit; wall time is bounded by the caller's
+// deadline.
+func nativeConfig(config *RuntimeConfig) task.NativeConfig {
+	runtimeConfig := config.Agent.Runtime
+	limits := config.Agent.Limits
+
+	return task.NativeConfig{
+		Command:      runtimeConfig.Command,
+		Env:          runtimeConfig.Env,
+		Network:      runtimeConfig.Network,
+		CgroupParent: runtimeConfig.CgroupParent,
+		Limits: task.NativeLimits{
+			MemoryMB:     int64(limits.MaxMemoryMB),
+			CPUSeconds:   uint64((limits.MaxExecutionTimeMS + 999) / 1000),
+			MaxProcesses: int64(limits.MaxProcesses),
+			MaxOpenFiles: uint64(limits.MaxOpenFiles),
+		},
+	}
+}
+
 // initLogger initializes the zap logger
 func initLogger() (*zap.Logger, error) {
 	config := zap.NewProductionConfig()
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aidenlippert/zerostate/reference-runtime-v1/internal/market"
+	"go.uber.org/zap"
+)
+
+// train-bidder pre-trains an RL bidder policy from recorded market logs and
+// writes a checkpoint the runtime can load before going live
+func main() {
+	logPaths := flag.String("log", "", "Comma-separated market log files (JSONL) to replay in order")
+	agentID := flag.String("agent-id", "", "DID of the agent the checkpoint is for")
+	capabilities := flag.String("capabilities", "math,string,json", "Comma-separated agent capabilities")
+	initPath := flag.String("init", "", "Optional checkpoint to continue training from")
+	outPath := flag.String("out", "bidder-checkpoint.json", "Path to write the trained checkpoint")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	if *logPaths == "" {
+		logger.Fatal("No market logs given; use -log")
+	}
+
+	bidder := market.NewRLBidder(*agentID, strings.Split(*capabilities, ","), logger)
+
+	if *initPath != "" {
+		cp, err := market.LoadCheckpoint(*initPath)
+		if err != nil {
+			logger.Fatal("Failed to load initial checkpoint", zap.String("path", *initPath), zap.Error(err))
+		}
+		if cp.AgentID == *agentID {
+			err = bidder.Restore(cp)
+		} else {
+			err = bidder.WarmStart(cp)
+		}
+		if err != nil {
+			logger.Fatal("Failed to apply initial checkpoint", zap.String("path", *initPath), zap.Error(err))
+		}
+	}
+
+	trainer := market.NewOfflineTrainer(bidder, nil, logger)
+	for _, path := range strings.Split(*logPaths, ",") {
+		report, err := trainer.TrainFile(context.Background(), path)
+		if err != nil {
+			logger.Fatal("Failed to replay market log", zap.String("path", path), zap.Error(err))
+		}
+
+		logger.Info("Replayed market log",
+			zap.String("path", path),
+			zap.Int("cfps", report.CFPs),
+			zap.Int("bids", report.Bids),
+			zap.Int("outcomes", report.Outcomes),
+			zap.Int("wins", report.Wins),
+			zap.Int("unsettled", report.Unsettled),
+			zap.Int("skipped", report.Skipped),
+		)
+	}
+
+	if err := market.SaveCheckpoint(*outPath, bidder.Checkpoint()); err != nil {
+		logger.Fatal("Failed to save checkpoint", zap.String("path", *outPath), zap.Error(err))
+	}
+
+	logger.Info("Checkpoint written", zap.String("path", *outPath))
+}
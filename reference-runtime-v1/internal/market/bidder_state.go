@@ -18,6 +18,9 @@ type BidderState struct {
 	// Per-capability statistics
 	CapabilityStats map[string]*CapabilityMetrics
 
+	// Capacity promised to requesters ahead of time, by reservation ID
+	Reservations map[string]*CapacityReservation
+
 	// Global metrics
 	TotalBidsSubmitted  int
 	TotalBidsAccepted   int
@@ -58,6 +61,39 @@ type CapabilityMetrics struct {
 	LastUpdated    time.Time
 }
 
+// CapacityReservation is capacity the runtime has agreed to hold for one
+// requester: up to Slots tasks of a capability during [WindowStart,
+// WindowEnd), at most Concurrency of them at a time. While the window is
+// open the unused part is withheld from spot bidding.
+type CapacityReservation struct {
+	ID           string
+	Capability   string
+	Slots        int
+	Concurrency  int
+	PricePerSlot float64
+	WindowStart  time.Time
+	WindowEnd    time.Time
+
+	Used     int // Reserved tasks started so far
+	InFlight int // Reserved tasks currently executing
+}
+
+// held returns how many concurrent task slots the reservation withholds at now
+func (r *CapacityReservation) held(now time.Time) int {
+	if now.Before(r.WindowStart) || !now.Before(r.WindowEnd) {
+		return 0
+	}
+
+	held := r.Concurrency - r.InFlight
+	if remaining := r.Slots - r.Used; remaining < held {
+		held = remaining
+	}
+	if held < 0 {
+		return 0
+	}
+	return held
+}
+
 // TaskOutcome represents the result of a completed task
 // Used as training signal for RL-based pricing strategies
 type TaskOutcome struct {
@@ -82,6 +118,7 @@ func NewBidderState(maxTasks int) *BidderState {
 		MaxTasks:        maxTasks,
 		LoadFactor:      0.0,
 		CapabilityStats: make(map[string]*CapabilityMetrics),
+		Reservations:    make(map[string]*CapacityReservation),
 		LastUpdated:     time.Now(),
 	}
 }
@@ -97,11 +134,91 @@ func (s *BidderState) GetLoadFactor() float64 {
 	return float64(s.ActiveTasks) / float64(s.MaxTasks)
 }
 
-// CanAcceptTask returns true if there's capacity for another task
+// CanAcceptTask returns true if there's capacity for another spot task,
+// after setting aside capacity held for open reservations
 func (s *BidderState) CanAcceptTask() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.ActiveTasks < s.MaxTasks
+	return s.ActiveTasks+s.reservedCapacityLocked(time.Now()) < s.MaxTasks
+}
+
+// ReservedCapacity returns the concurrent slots currently withheld for reservations
+func (s *BidderState) ReservedCapacity() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reservedCapacityLocked(time.Now())
+}
+
+func (s *BidderState) reservedCapacityLocked(now time.Time) int {
+	held := 0
+	for _, r := range s.Reservations {
+		held += r.held(now)
+	}
+	return held
+}
+
+// AddReservation records capacity the runtime has agreed to hold. Concurrency
+// defaults to one task at a time. Re-adding a known reservation updates its
+// terms and keeps its usage.
+func (s *BidderState) AddReservation(r CapacityReservation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Concurrency <= 0 {
+		r.Concurrency = 1
+	}
+	if s.Reservations == nil {
+		s.Reservations = make(map[string]*CapacityReservation)
+	}
+	if existing, ok := s.Reservations[r.ID]; ok {
+		r.Used, r.InFlight = existing.Used, existing.InFlight
+	}
+	s.Reservations[r.ID] = &r
+	s.LastUpdated = time.Now()
+}
+
+// RemoveReservation drops a reservation once it is settled or cancelled
+func (s *BidderState) RemoveReservation(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Reservations, id)
+	s.LastUpdated = time.Now()
+}
+
+// StartReservedTask takes one slot of a reservation for an arriving task. It
+// reports false if the reservation is unknown, outside its window or used up.
+func (s *BidderState) StartReservedTask(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.Reservations[id]
+	now := time.Now()
+	if !ok || now.Before(r.WindowStart) || !now.Before(r.WindowEnd) || r.Used >= r.Slots {
+		return false
+	}
+
+	r.Used++
+	r.InFlight++
+	s.ActiveTasks++
+	s.LoadFactor = float64(s.ActiveTasks) / float64(s.MaxTasks)
+	s.LastUpdated = now
+	return true
+}
+
+// FinishReservedTask releases the slot taken by StartReservedTask
+func (s *BidderState) FinishReservedTask(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.Reservations[id]; ok && r.InFlight > 0 {
+		r.InFlight--
+	}
+	if s.ActiveTasks > 0 {
+		s.ActiveTasks--
+	}
+	s.LoadFactor = float64(s.ActiveTasks) / float64(s.MaxTasks)
+	s.LastUpdated = time.Now()
 }
 
 // IncrementActiveTasks atomically increments active task count
@@ -0,0 +1,262 @@
+package market
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CheckpointVersion is the current bidder checkpoint format version.
+// Bump it whenever the state encoding or action space changes meaning.
+const CheckpointVersion = 1
+
+// WarmStartEpsilon is the minimum exploration rate after warm-starting from
+// another runtime's checkpoint; that runtime's market may differ from ours
+const WarmStartEpsilon = 0.1
+
+var (
+	// ErrCheckpointVersion is returned for checkpoints written by an incompatible format
+	ErrCheckpointVersion = errors.New("unsupported bidder checkpoint version")
+
+	// ErrCheckpointMismatch is returned when a checkpoint's action space or
+	// capabilities do not match the bidder restoring it
+	ErrCheckpointMismatch = errors.New("bidder checkpoint does not match bidder")
+)
+
+// QEntry is one serialized Q-table cell
+type QEntry struct {
+	State  string  `json:"state"`
+	Action int     `json:"action"`
+	Q      float64 `json:"q"`
+}
+
+// PricingCheckpoint captures the learned parameters of an adaptive pricing strategy
+type PricingCheckpoint struct {
+	Strategy     string  `json:"strategy"`
+	BasePrice    float64 `json:"base_price"`
+	CurrentPrice float64 `json:"current_price"`
+	Epsilon      float64 `json:"epsilon"`
+}
+
+// BidderCheckpoint is a versioned snapshot of an RLBidder's learned policy
+type BidderCheckpoint struct {
+	Version      int       `json:"version"`
+	AgentID      string    `json:"agent_id"`
+	Capabilities []string  `json:"capabilities"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Action space; a Q-table is only meaningful for the same discretization
+	NumActions  int     `json:"num_actions"`
+	MinBidRatio float64 `json:"min_bid_ratio"`
+	MaxBidRatio float64 `json:"max_bid_ratio"`
+
+	Epsilon      float64            `json:"epsilon"`
+	State        RLBidderState      `json:"state"`
+	QTable       []QEntry           `json:"q_table"`
+	ReplayBuffer []Experience       `json:"replay_buffer,omitempty"`
+	Pricing      *PricingCheckpoint `json:"pricing,omitempty"`
+}
+
+// Checkpoint snapshots the bidder's Q-table, replay buffer, exploration rate and state
+func (b *RLBidder) Checkpoint() *BidderCheckpoint {
+	cp := &BidderCheckpoint{
+		Version:      CheckpointVersion,
+		AgentID:      b.agentID,
+		Capabilities: append([]string(nil), b.capabilities...),
+		CreatedAt:    time.Now(),
+		NumActions:   b.numActions,
+		MinBidRatio:  b.minBidRatio,
+		MaxBidRatio:  b.maxBidRatio,
+		Epsilon:      b.epsilon,
+	}
+
+	b.stateMux.RLock()
+	cp.State = *b.state
+	b.stateMux.RUnlock()
+
+	b.qTableMux.RLock()
+	cp.QTable = make([]QEntry, 0, len(b.qTable))
+	for key, q := range b.qTable {
+		cp.QTable = append(cp.QTable, QEntry{State: key.State, Action: key.Action, Q: q})
+	}
+	b.qTableMux.RUnlock()
+
+	// Stable ordering keeps checkpoints diffable
+	sort.Slice(cp.QTable, func(i, j int) bool {
+		if cp.QTable[i].State != cp.QTable[j].State {
+			return cp.QTable[i].State < cp.QTable[j].State
+		}
+		return cp.QTable[i].Action < cp.QTable[j].Action
+	})
+
+	b.replayMux.Lock()
+	cp.ReplayBuffer = append([]Experience(nil), b.replayBuffer...)
+	b.replayMux.Unlock()
+
+	return cp
+}
+
+// Restore replaces the bidder's learned state with a checkpoint it wrote itself
+func (b *RLBidder) Restore(cp *BidderCheckpoint) error {
+	if err := b.checkCompatible(cp); err != nil {
+		return err
+	}
+
+	state := cp.State
+	b.stateMux.Lock()
+	b.state = &state
+	b.stateMux.Unlock()
+
+	b.loadQTable(cp.QTable)
+
+	buffer := cp.ReplayBuffer
+	if len(buffer) > b.bufferSize {
+		buffer = buffer[len(buffer)-b.bufferSize:]
+	}
+	b.replayMux.Lock()
+	b.replayBuffer = append(make([]Experience, 0, b.bufferSize), buffer...)
+	b.replayMux.Unlock()
+
+	b.epsilon = cp.Epsilon
+
+	b.logger.Info("restored RL bidder from checkpoint",
+		zap.String("agent", b.agentID),
+		zap.Time("created_at", cp.CreatedAt),
+		zap.Int("q_table_size", len(cp.QTable)),
+		zap.Int("experience_count", len(buffer)),
+		zap.Float64("epsilon", b.epsilon),
+	)
+
+	return nil
+}
+
+// WarmStart seeds the bidder's policy from a checkpoint exported by another
+// runtime with the same capabilities. Only the Q-table and market view are
+// imported; the other agent's win rate, revenue, capacity and experiences
+// describe that agent, not this one.
+func (b *RLBidder) WarmStart(cp *BidderCheckpoint) error {
+	if err := b.checkCompatible(cp); err != nil {
+		return err
+	}
+	if !sameCapabilities(b.capabilities, cp.Capabilities) {
+		return fmt.Errorf("%w: capabilities %v, checkpoint has %v", ErrCheckpointMismatch, b.capabilities, cp.Capabilities)
+	}
+
+	b.loadQTable(cp.QTable)
+
+	b.stateMux.Lock()
+	b.state.MarketDemand = cp.State.MarketDemand
+	b.state.NumCompetitors = cp.State.NumCompetitors
+	b.stateMux.Unlock()
+
+	b.epsilon = math.Max(cp.Epsilon, WarmStartEpsilon)
+
+	b.logger.Info("warm-started RL bidder from peer checkpoint",
+		zap.String("agent", b.agentID),
+		zap.String("source_agent", cp.AgentID),
+		zap.Int("q_table_size", len(cp.QTable)),
+		zap.Float64("epsilon", b.epsilon),
+	)
+
+	return nil
+}
+
+func (b *RLBidder) checkCompatible(cp *BidderCheckpoint) error {
+	if cp == nil {
+		return fmt.Errorf("%w: nil checkpoint", ErrCheckpointMismatch)
+	}
+	if cp.Version != CheckpointVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrCheckpointVersion, cp.Version, CheckpointVersion)
+	}
+	if cp.NumActions != b.numActions || cp.MinBidRatio != b.minBidRatio || cp.MaxBidRatio != b.maxBidRatio {
+		return fmt.Errorf("%w: action space differs", ErrCheckpointMismatch)
+	}
+	return nil
+}
+
+func (b *RLBidder) loadQTable(entries []QEntry) {
+	qTable := make(map[StateActionKey]float64, len(entries))
+	for _, entry := range entries {
+		if entry.Action < 0 || entry.Action >= b.numActions {
+			continue
+		}
+		qTable[StateActionKey{entry.State, entry.Action}] = entry.Q
+	}
+
+	b.qTableMux.Lock()
+	b.qTable = qTable
+	b.qTableMux.Unlock()
+}
+
+func sameCapabilities(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveCheckpoint writes a checkpoint as JSON. The file is replaced atomically
+// so a crash mid-write never leaves a truncated checkpoint behind.
+func SaveCheckpoint(path string, cp *BidderCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint reads a checkpoint written by SaveCheckpoint
+func LoadCheckpoint(path string) (*BidderCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp BidderCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrCheckpointVersion, cp.Version, CheckpointVersion)
+	}
+
+	return &cp, nil
+}
@@ -0,0 +1,144 @@
+package market
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// trainedBidder returns a bidder that has learned from a won and a lost auction
+func trainedBidder(agentID string) *RLBidder {
+	b := NewRLBidder(agentID, []string{"math", "json"}, nil)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfp := &CFP{ID: "cfp-1", Capability: "math", Budget: 50, Deadline: at.Add(time.Minute).Unix()}
+
+	for i, outcome := range []TaskOutcome{
+		{CFPId: "cfp-1", Won: true, Success: true, Profit: 20},
+		{CFPId: "cfp-1", Won: false},
+	} {
+		_, exp := b.decide(cfp, at.Add(time.Duration(i)*time.Second))
+		b.learnFrom(exp, outcome)
+	}
+	return b
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	original := trainedBidder("did:agent:a")
+	cp := original.Checkpoint()
+	if len(cp.QTable) == 0 || len(cp.ReplayBuffer) != 2 {
+		t.Fatalf("expected a learned Q-table and two experiences, got %d and %d", len(cp.QTable), len(cp.ReplayBuffer))
+	}
+
+	path := filepath.Join(t.TempDir(), "bidder.json")
+	if err := SaveCheckpoint(path, cp); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+
+	restored := NewRLBidder("did:agent:a", []string{"math", "json"}, nil)
+	if err := restored.Restore(loaded); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	got := restored.Checkpoint()
+	if !reflect.DeepEqual(got.QTable, cp.QTable) {
+		t.Errorf("Q-table changed in the round trip:\n got %v\nwant %v", got.QTable, cp.QTable)
+	}
+	if got.Epsilon != cp.Epsilon || got.State != cp.State {
+		t.Errorf("expected epsilon %v and state %+v, got %v and %+v", cp.Epsilon, cp.State, got.Epsilon, got.State)
+	}
+	if len(got.ReplayBuffer) != len(cp.ReplayBuffer) || got.ReplayBuffer[0].Action != cp.ReplayBuffer[0].Action {
+		t.Errorf("expected the replay buffer restored, got %+v", got.ReplayBuffer)
+	}
+	if !loaded.CreatedAt.Equal(cp.CreatedAt) {
+		t.Errorf("expected created_at %v, got %v", cp.CreatedAt, loaded.CreatedAt)
+	}
+
+	// Nothing but the checkpoint is left in the directory
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the checkpoint file, got %d entries", len(entries))
+	}
+}
+
+func TestCheckpointRejectsIncompatible(t *testing.T) {
+	cp := trainedBidder("did:agent:a").Checkpoint()
+
+	future := *cp
+	future.Version = CheckpointVersion + 1
+	path := filepath.Join(t.TempDir(), "future.json")
+	if err := SaveCheckpoint(path, &future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCheckpoint(path); !errors.Is(err, ErrCheckpointVersion) {
+		t.Errorf("expected ErrCheckpointVersion, got %v", err)
+	}
+
+	coarser := *cp
+	coarser.NumActions = 5
+	if err := NewRLBidder("did:agent:a", cp.Capabilities, nil).Restore(&coarser); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("expected ErrCheckpointMismatch for another action space, got %v", err)
+	}
+}
+
+func TestWarmStartFromPeer(t *testing.T) {
+	cp := trainedBidder("did:agent:peer").Checkpoint()
+	cp.Epsilon = 0.01
+
+	if err := NewRLBidder("did:agent:b", []string{"math"}, nil).WarmStart(cp); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("expected ErrCheckpointMismatch for other capabilities, got %v", err)
+	}
+
+	// Capability order doesn't matter
+	b := NewRLBidder("did:agent:b", []string{"json", "math"}, nil)
+	if err := b.WarmStart(cp); err != nil {
+		t.Fatalf("WarmStart failed: %v", err)
+	}
+	got := b.Checkpoint()
+	if !reflect.DeepEqual(got.QTable, cp.QTable) {
+		t.Error("expected the peer's Q-table")
+	}
+	if got.Epsilon != WarmStartEpsilon {
+		t.Errorf("expected exploration raised to %v, got %v", WarmStartEpsilon, got.Epsilon)
+	}
+	if len(got.ReplayBuffer) != 0 || got.State.AvgWinRate != 0 {
+		t.Errorf("expected the peer's own history left behind, got %d experiences and win rate %v", len(got.ReplayBuffer), got.State.AvgWinRate)
+	}
+}
+
+func TestCompetitivePricingCheckpoint(t *testing.T) {
+	s := NewCompetitivePricing(10, 0.3, 0.05, 0.1, nil)
+	s.CurrentPrice = 14
+	cp := s.Checkpoint()
+
+	restored := NewCompetitivePricing(10, 0.3, 0.05, 0.2, nil)
+	if err := restored.Restore(cp); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.CurrentPrice != 14 || restored.Epsilon != 0.1 {
+		t.Errorf("expected price 14 and epsilon 0.1, got %v and %v", restored.CurrentPrice, restored.Epsilon)
+	}
+
+	// A checkpointed price outside this strategy's bounds is clamped
+	cp.CurrentPrice = 100
+	if err := restored.Restore(cp); err != nil {
+		t.Fatal(err)
+	}
+	if restored.CurrentPrice != 30 {
+		t.Errorf("expected the price clamped to 30, got %v", restored.CurrentPrice)
+	}
+
+	cp.Strategy = "StaticFloorPricing"
+	if err := restored.Restore(cp); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("expected ErrCheckpointMismatch for another strategy, got %v", err)
+	}
+}
@@ -25,6 +25,9 @@ type IntelligentBidder struct {
 	// RL pricing engine
 	rlBidder *RLBidder
 
+	// Optional market activity log for offline training
+	marketLog *MarketLogWriter
+
 	// State tracking
 	activeCFPs map[string]*CFPContext // CFP ID → context
 	activeBids map[string]*BidContext // Bid ID → context
@@ -157,6 +160,8 @@ func (ib *IntelligentBidder) handleCFP(msg *Message) error {
 		zap.Int64("deadline", cfp.Deadline),
 	)
 
+	ib.recordMarketLog(MarketLogRecord{Type: LogRecordCFP, CFP: &cfp})
+
 	// Store CFP context
 	ib.activeCFPs[cfp.ID] = &CFPContext{
 		CFP:        &cfp,
@@ -215,6 +220,8 @@ func (ib *IntelligentBidder) decideBid(ctx context.Context, cfp *CFP) {
 		}
 	}
 
+	ib.recordMarketLog(MarketLogRecord{Type: LogRecordBid, Bid: bid})
+
 	// Update CFP context
 	if ctx, exists := ib.activeCFPs[cfp.ID]; exists {
 		ctx.BidSubmitted = true
@@ -305,6 +312,7 @@ func (ib *IntelligentBidder) simulateTaskExecution(bidCtx *BidContext) {
 
 	// Report to RL bidder for learning
 	ib.rlBidder.Learn(outcome)
+	ib.recordMarketLog(MarketLogRecord{Type: LogRecordOutcome, Outcome: &outcome})
 
 	ib.logger.Info("task execution complete",
 		zap.String("cfp_id", bidCtx.CFP.ID),
@@ -332,6 +340,7 @@ func (ib *IntelligentBidder) ReportTaskOutcome(cfpID string, completed bool, act
 			}
 
 			ib.rlBidder.Learn(outcome)
+			ib.recordMarketLog(MarketLogRecord{Type: LogRecordOutcome, Outcome: &outcome})
 
 			ib.logger.Info("reported task outcome to RL bidder",
 				zap.String("cfp_id", cfpID),
@@ -344,6 +353,40 @@ func (ib *IntelligentBidder) ReportTaskOutcome(cfpID string, completed bool, act
 	}
 }
 
+// SetMarketLog records CFPs, bids and outcomes for offline training
+func (ib *IntelligentBidder) SetMarketLog(w *MarketLogWriter) {
+	ib.marketLog = w
+}
+
+func (ib *IntelligentBidder) recordMarketLog(record MarketLogRecord) {
+	if ib.marketLog == nil {
+		return
+	}
+	if err := ib.marketLog.Write(record); err != nil {
+		ib.logger.Warn("failed to write market log", zap.String("type", record.Type), zap.Error(err))
+	}
+}
+
+// SaveCheckpoint persists the RL bidder's learned policy to path
+func (ib *IntelligentBidder) SaveCheckpoint(path string) error {
+	return SaveCheckpoint(path, ib.rlBidder.Checkpoint())
+}
+
+// LoadCheckpoint resumes from a checkpoint at path. A checkpoint written by
+// this agent is restored in full; one exported by another runtime is used to
+// warm-start the policy.
+func (ib *IntelligentBidder) LoadCheckpoint(path string) error {
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+
+	if cp.AgentID == agentDID(ib.agentCard) {
+		return ib.rlBidder.Restore(cp)
+	}
+	return ib.rlBidder.WarmStart(cp)
+}
+
 // GetStats returns bidder statistics
 func (ib *IntelligentBidder) GetStats() map[string]interface{} {
 	rlStats := ib.rlBidder.GetStats()
@@ -0,0 +1,102 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"go.uber.org/zap"
+)
+
+// PriceIndexTopic carries the orchestrator's rolling cleared-price percentiles
+const PriceIndexTopic = "ainur/v1/market/prices"
+
+// PriceIndexCache keeps the latest price index snapshot received from the
+// market so strategies can resolve floor references such as "p10" locally
+type PriceIndexCache struct {
+	mu        sync.RWMutex
+	quotes    map[string]*economic.PriceQuote
+	updatedAt time.Time
+	logger    *zap.Logger
+}
+
+// NewPriceIndexCache creates an empty cache
+func NewPriceIndexCache(logger *zap.Logger) *PriceIndexCache {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PriceIndexCache{
+		quotes: make(map[string]*economic.PriceQuote),
+		logger: logger,
+	}
+}
+
+// Subscribe keeps the cache current from price index broadcasts on the bus
+func (c *PriceIndexCache) Subscribe(bus MessageBus) error {
+	return bus.Subscribe(PriceIndexTopic, func(ctx context.Context, msg *Message) error {
+		return c.handleSnapshot(msg)
+	})
+}
+
+// handleSnapshot accepts either a bare snapshot or one wrapped in a gossip envelope
+func (c *PriceIndexCache) handleSnapshot(msg *Message) error {
+	var envelope struct {
+		Payload json.RawMessage `json:"payload"`
+	}
+	data := msg.Data
+	if err := json.Unmarshal(data, &envelope); err == nil && len(envelope.Payload) > 0 {
+		data = envelope.Payload
+	}
+
+	var snapshot economic.PriceIndexSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		c.logger.Warn("failed to parse price index", zap.Error(err))
+		return err
+	}
+
+	c.Update(&snapshot)
+	return nil
+}
+
+// Update replaces the cached quotes with a snapshot. Older snapshots are ignored.
+func (c *PriceIndexCache) Update(snapshot *economic.PriceIndexSnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if snapshot.GeneratedAt.Before(c.updatedAt) {
+		return
+	}
+
+	c.quotes = make(map[string]*economic.PriceQuote, len(snapshot.Quotes))
+	for _, quote := range snapshot.Quotes {
+		c.quotes[quote.Capability] = quote
+	}
+	c.updatedAt = snapshot.GeneratedAt
+
+	c.logger.Debug("price index updated", zap.Int("capabilities", len(snapshot.Quotes)))
+}
+
+// Quote returns the cached quote of a capability, or nil
+func (c *PriceIndexCache) Quote(capability string) *economic.PriceQuote {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.quotes[capability]
+}
+
+// Resolve returns the price a reference such as "p10" stands for on a capability
+func (c *PriceIndexCache) Resolve(capability, ref string) (float64, error) {
+	quote := c.Quote(capability)
+	if quote == nil {
+		if _, err := economic.ParsePriceReference(ref); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w for %s", economic.ErrNoPriceData, capability)
+	}
+
+	return quote.Resolve(ref)
+}
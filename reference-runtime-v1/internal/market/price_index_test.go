@@ -0,0 +1,124 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/economic"
+	"go.uber.org/zap"
+)
+
+// localBus delivers published messages straight to the topic's handlers
+type localBus struct {
+	handlers map[string][]MessageHandler
+}
+
+func newLocalBus() *localBus {
+	return &localBus{handlers: make(map[string][]MessageHandler)}
+}
+
+func (b *localBus) Subscribe(topic string, handler MessageHandler) error {
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	return nil
+}
+
+func (b *localBus) Publish(topic string, data []byte) error {
+	for _, handler := range b.handlers[topic] {
+		if err := handler(context.Background(), &Message{Data: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mathSnapshot(at time.Time, p10 float64) *economic.PriceIndexSnapshot {
+	return &economic.PriceIndexSnapshot{
+		GeneratedAt: at,
+		Quotes: []*economic.PriceQuote{{
+			Capability:  "math",
+			Samples:     20,
+			Percentiles: map[string]float64{"p10": p10, "p25": p10 + 2, "p50": p10 + 5},
+		}},
+	}
+}
+
+func TestPriceIndexCacheFollowsBroadcasts(t *testing.T) {
+	bus := newLocalBus()
+	cache := NewPriceIndexCache(nil)
+	if err := cache.Subscribe(bus); err != nil {
+		t.Fatal(err)
+	}
+	generated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// A bare snapshot
+	data, _ := json.Marshal(mathSnapshot(generated, 8))
+	if err := bus.Publish(PriceIndexTopic, data); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if price, err := cache.Resolve("math", "p10"); err != nil || price != 8 {
+		t.Errorf("expected p10 of 8, got %v, %v", price, err)
+	}
+
+	// One in a gossip envelope
+	payload, _ := json.Marshal(mathSnapshot(generated.Add(time.Minute), 9))
+	data, _ = json.Marshal(map[string]interface{}{"type": "price_index", "payload": json.RawMessage(payload)})
+	if err := bus.Publish(PriceIndexTopic, data); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if price, _ := cache.Resolve("math", "p25"); price != 11 {
+		t.Errorf("expected p25 of 11 from the newer snapshot, got %v", price)
+	}
+
+	// A snapshot delivered late doesn't roll the cache back
+	cache.Update(mathSnapshot(generated, 1))
+	if price, _ := cache.Resolve("math", "median"); price != 14 {
+		t.Errorf("expected the newer median of 14 kept, got %v", price)
+	}
+
+	if err := bus.Publish(PriceIndexTopic, []byte("{not json")); err == nil {
+		t.Error("expected a malformed snapshot to be rejected")
+	}
+	if _, err := cache.Resolve("json", "p10"); !errors.Is(err, economic.ErrNoPriceData) {
+		t.Errorf("expected ErrNoPriceData for an unquoted capability, got %v", err)
+	}
+	if _, err := cache.Resolve("json", "p12"); !errors.Is(err, economic.ErrInvalidPriceReference) {
+		t.Errorf("expected ErrInvalidPriceReference, got %v", err)
+	}
+}
+
+func TestIndexedFloorPricing(t *testing.T) {
+	cache := NewPriceIndexCache(nil)
+	if _, err := NewIndexedFloorPricing("cheap", cache, 5, zap.NewNop()); !errors.Is(err, economic.ErrInvalidPriceReference) {
+		t.Errorf("expected ErrInvalidPriceReference, got %v", err)
+	}
+	if _, err := NewIndexedFloorPricing("p10", nil, 5, zap.NewNop()); err == nil {
+		t.Error("expected a floor reference without a price index to fail")
+	}
+
+	pricing, err := NewIndexedFloorPricing("p10", cache, 5, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewIndexedFloorPricing failed: %v", err)
+	}
+	ctx := context.Background()
+	state := NewBidderState(1)
+	cfp := &CFPMessage{CFPID: "cfp-1", Capability: "math", Budget: 7}
+
+	// The fixed floor applies until the index has data
+	if price := pricing.CalculatePrice(ctx, cfp, state); price != 5 {
+		t.Errorf("expected the fallback floor 5, got %v", price)
+	}
+	if !pricing.ShouldBid(ctx, cfp, state) {
+		t.Error("expected a bid at the fallback floor")
+	}
+
+	cache.Update(mathSnapshot(time.Now(), 8))
+	if price := pricing.CalculatePrice(ctx, cfp, state); price != 8 {
+		t.Errorf("expected the p10 floor 8, got %v", price)
+	}
+	if pricing.ShouldBid(ctx, cfp, state) {
+		t.Error("expected no bid when the budget is under the indexed floor")
+	}
+}
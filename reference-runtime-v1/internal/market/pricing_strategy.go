@@ -2,9 +2,13 @@ package market
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"math/rand"
+	"sync"
+	"time"
 
+	"github.com/aidenlippert/zerostate/libs/economic"
 	"go.uber.org/zap"
 )
 
@@ -29,6 +33,14 @@ type PricingStrategy interface {
 	Name() string
 }
 
+// CheckpointablePricing is implemented by strategies whose learned
+// parameters can be persisted alongside the RL bidder's checkpoint
+type CheckpointablePricing interface {
+	PricingStrategy
+	Checkpoint() *PricingCheckpoint
+	Restore(cp *PricingCheckpoint) error
+}
+
 // CFPMessage wraps the parsed CFP for easier access
 type CFPMessage struct {
 	CFPID           string
@@ -47,7 +59,14 @@ type CFPMessage struct {
 // This is the simplest strategy and serves as a baseline
 type StaticFloorPricing struct {
 	FloorPrice float64
-	logger     *zap.Logger
+
+	// FloorRef declares the floor as a percentile of recently cleared prices
+	// for the CFP's capability, e.g. "p10". FloorPrice is used until the
+	// index has data for that capability.
+	FloorRef string
+	Prices   *PriceIndexCache
+
+	logger *zap.Logger
 }
 
 func NewStaticFloorPricing(floorPrice float64, logger *zap.Logger) *StaticFloorPricing {
@@ -57,18 +76,55 @@ func NewStaticFloorPricing(floorPrice float64, logger *zap.Logger) *StaticFloorP
 	}
 }
 
+// NewIndexedFloorPricing bids at a percentile of the market price index,
+// falling back to fallbackPrice for capabilities without price data
+func NewIndexedFloorPricing(floorRef string, prices *PriceIndexCache, fallbackPrice float64, logger *zap.Logger) (*StaticFloorPricing, error) {
+	if _, err := economic.ParsePriceReference(floorRef); err != nil {
+		return nil, err
+	}
+	if prices == nil {
+		return nil, fmt.Errorf("floor reference %q needs a price index", floorRef)
+	}
+
+	return &StaticFloorPricing{
+		FloorPrice: fallbackPrice,
+		FloorRef:   floorRef,
+		Prices:     prices,
+		logger:     logger,
+	}, nil
+}
+
+// floor returns the floor for a CFP's capability
+func (s *StaticFloorPricing) floor(cfp *CFPMessage) float64 {
+	if s.FloorRef == "" || s.Prices == nil {
+		return s.FloorPrice
+	}
+
+	price, err := s.Prices.Resolve(cfp.Capability, s.FloorRef)
+	if err != nil {
+		s.logger.Debug("floor reference unresolved, using fixed floor",
+			zap.String("capability", cfp.Capability),
+			zap.String("floor_ref", s.FloorRef),
+			zap.Error(err),
+		)
+		return s.FloorPrice
+	}
+
+	return price
+}
+
 func (s *StaticFloorPricing) Name() string {
 	return "StaticFloorPricing"
 }
 
 func (s *StaticFloorPricing) ShouldBid(ctx context.Context, cfp *CFPMessage, state *BidderState) bool {
 	// Bid if budget meets floor AND we have capacity
-	return cfp.Budget >= s.FloorPrice && state.CanAcceptTask()
+	return cfp.Budget >= s.floor(cfp) && state.CanAcceptTask()
 }
 
 func (s *StaticFloorPricing) CalculatePrice(ctx context.Context, cfp *CFPMessage, state *BidderState) float64 {
 	// Always bid at floor price
-	return s.FloorPrice
+	return s.floor(cfp)
 }
 
 func (s *StaticFloorPricing) OnBidResult(ctx context.Context, accepted bool, cfp *CFPMessage, bidPrice float64, state *BidderState) {
@@ -252,6 +308,30 @@ func (s *CompetitivePricing) OnTaskOutcome(ctx context.Context, outcome TaskOutc
 	// If profit margins are high and win rate is low, decrease price
 }
 
+// Checkpoint captures the learned price so it survives restarts
+func (s *CompetitivePricing) Checkpoint() *PricingCheckpoint {
+	return &PricingCheckpoint{
+		Strategy:     s.Name(),
+		BasePrice:    s.BasePrice,
+		CurrentPrice: s.CurrentPrice,
+		Epsilon:      s.Epsilon,
+	}
+}
+
+// Restore resumes from a checkpointed price, clamped to this strategy's bounds
+func (s *CompetitivePricing) Restore(cp *PricingCheckpoint) error {
+	if cp == nil || cp.Strategy != s.Name() {
+		return fmt.Errorf("%w: expected %s pricing", ErrCheckpointMismatch, s.Name())
+	}
+
+	minPrice := s.BasePrice * 0.5
+	maxPrice := s.BasePrice * 3.0
+	s.CurrentPrice = math.Max(minPrice, math.Min(maxPrice, cp.CurrentPrice))
+	s.Epsilon = cp.Epsilon
+
+	return nil
+}
+
 // ============================================================================
 // Strategy 4: HybridPricing (Combines load-awareness and competition)
 // ============================================================================
@@ -303,44 +383,121 @@ func (s *HybridPricing) OnTaskOutcome(ctx context.Context, outcome TaskOutcome,
 	s.competitiveBase.OnTaskOutcome(ctx, outcome, state)
 }
 
+// Checkpoint captures the competitive component's learned price
+func (s *HybridPricing) Checkpoint() *PricingCheckpoint {
+	cp := s.competitiveBase.Checkpoint()
+	cp.Strategy = s.Name()
+	return cp
+}
+
+// Restore resumes the competitive component from a checkpoint
+func (s *HybridPricing) Restore(cp *PricingCheckpoint) error {
+	if cp == nil || cp.Strategy != s.Name() {
+		return fmt.Errorf("%w: expected %s pricing", ErrCheckpointMismatch, s.Name())
+	}
+
+	base := *cp
+	base.Strategy = s.competitiveBase.Name()
+	return s.competitiveBase.Restore(&base)
+}
+
 // ============================================================================
-// Future: RLPricing (Full reinforcement learning - placeholder)
+// Strategy 5: RLPricing (Q-learning via RLBidder)
 // ============================================================================
 
-// RLPricing is a placeholder for future deep RL-based pricing strategies
-// Could use Q-learning, Actor-Critic, or Policy Gradient methods
+// RLPricing adapts an RLBidder to the PricingStrategy interface so the
+// Q-learning policy can be compared against the other strategies. Decisions
+// are tracked per CFP, so outcomes may arrive in any order.
 type RLPricing struct {
-	// model *ReinforcementLearningModel  // To be implemented
-	logger *zap.Logger
+	bidder *RLBidder
+
+	// Now returns the current time; simulators replace it with their clock
+	Now func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]Experience // CFP ID → decision awaiting its outcome
+	logger  *zap.Logger
 }
 
-func NewRLPricing(logger *zap.Logger) *RLPricing {
+func NewRLPricing(bidder *RLBidder, logger *zap.Logger) *RLPricing {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	return &RLPricing{
-		logger: logger,
+		bidder:  bidder,
+		Now:     time.Now,
+		pending: make(map[string]Experience),
+		logger:  logger,
 	}
 }
 
 func (s *RLPricing) Name() string {
-	return "RLPricing (not implemented)"
+	return "RLPricing"
+}
+
+// Bidder returns the underlying RL bidder, e.g. for checkpointing
+func (s *RLPricing) Bidder() *RLBidder {
+	return s.bidder
 }
 
 func (s *RLPricing) ShouldBid(ctx context.Context, cfp *CFPMessage, state *BidderState) bool {
-	// TODO: Implement using RL model
-	s.logger.Warn("RLPricing.ShouldBid called but not implemented")
-	return false
+	// The action space is relative to the budget, so any funded CFP is biddable
+	return cfp.Budget > 0 && state.CanAcceptTask()
 }
 
 func (s *RLPricing) CalculatePrice(ctx context.Context, cfp *CFPMessage, state *BidderState) float64 {
-	// TODO: Implement using RL model
-	s.logger.Warn("RLPricing.CalculatePrice called but not implemented")
-	return 0.0
+	now := s.Now()
+	price, exp := s.bidder.decide(&CFP{
+		ID:         cfp.CFPID,
+		Capability: cfp.Capability,
+		Budget:     cfp.Budget,
+		Deadline:   parseDeadline(cfp.Deadline, now),
+	}, now)
+
+	s.mu.Lock()
+	s.pending[cfp.CFPID] = exp
+	s.mu.Unlock()
+
+	return price
 }
 
 func (s *RLPricing) OnBidResult(ctx context.Context, accepted bool, cfp *CFPMessage, bidPrice float64, state *BidderState) {
-	// TODO: Update RL model
+	if accepted {
+		// Learn once the task outcome is known
+		return
+	}
+
+	s.learn(TaskOutcome{
+		CFPId:      cfp.CFPID,
+		Capability: cfp.Capability,
+		BidPrice:   bidPrice,
+		Won:        false,
+		Timestamp:  s.Now(),
+	})
 }
 
 func (s *RLPricing) OnTaskOutcome(ctx context.Context, outcome TaskOutcome, state *BidderState) {
-	// TODO: Primary learning signal for RL
-	// This is where you'd update Q-values, policy gradients, etc.
+	s.learn(outcome)
+}
+
+func (s *RLPricing) learn(outcome TaskOutcome) {
+	s.mu.Lock()
+	exp, ok := s.pending[outcome.CFPId]
+	delete(s.pending, outcome.CFPId)
+	s.mu.Unlock()
+
+	if !ok {
+		s.logger.Debug("no RL decision recorded for outcome", zap.String("cfp_id", outcome.CFPId))
+		return
+	}
+	s.bidder.learnFrom(exp, outcome)
+}
+
+// parseDeadline reads an RFC 3339 CFP deadline, defaulting to an hour from now
+func parseDeadline(deadline string, now time.Time) int64 {
+	if t, err := time.Parse(time.RFC3339, deadline); err == nil {
+		return t.Unix()
+	}
+	return now.Add(time.Hour).Unix()
 }
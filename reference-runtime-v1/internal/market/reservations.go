@@ -0,0 +1,100 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReservationTopic returns the topic the orchestrator uses to notify an agent
+// of changes to its capacity reservations
+func ReservationTopic(agentDID string) string {
+	return fmt.Sprintf("ainur/v1/market/reservations/%s", agentDID)
+}
+
+// reservationNotice mirrors the reservation the orchestrator publishes
+type reservationNotice struct {
+	ID           string    `json:"id"`
+	AgentDID     string    `json:"agent_did"`
+	Capability   string    `json:"capability"`
+	Slots        int       `json:"slots"`
+	Concurrency  int       `json:"concurrency"`
+	PricePerSlot float64   `json:"price_per_slot"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
+	Status       string    `json:"status"`
+}
+
+// ReservationWatcher keeps a BidderState's reserved capacity in step with the
+// reservations the orchestrator holds for this agent, so spot bidding never
+// sells capacity that has already been booked
+type ReservationWatcher struct {
+	agentDID string
+	state    *BidderState
+	logger   *zap.Logger
+}
+
+// NewReservationWatcher creates a watcher for the given agent's reservations
+func NewReservationWatcher(agentDID string, state *BidderState, logger *zap.Logger) *ReservationWatcher {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ReservationWatcher{
+		agentDID: agentDID,
+		state:    state,
+		logger:   logger,
+	}
+}
+
+// Subscribe applies reservation notices from the bus to the bidder state
+func (w *ReservationWatcher) Subscribe(bus MessageBus) error {
+	return bus.Subscribe(ReservationTopic(w.agentDID), func(ctx context.Context, msg *Message) error {
+		return w.handleNotice(msg)
+	})
+}
+
+// handleNotice accepts either a bare reservation or one wrapped in a gossip envelope
+func (w *ReservationWatcher) handleNotice(msg *Message) error {
+	var envelope struct {
+		Payload json.RawMessage `json:"payload"`
+	}
+	data := msg.Data
+	if err := json.Unmarshal(data, &envelope); err == nil && len(envelope.Payload) > 0 {
+		data = envelope.Payload
+	}
+
+	var notice reservationNotice
+	if err := json.Unmarshal(data, &notice); err != nil {
+		w.logger.Warn("failed to parse reservation notice", zap.Error(err))
+		return err
+	}
+	if notice.AgentDID != w.agentDID {
+		return nil
+	}
+
+	switch notice.Status {
+	case "confirmed":
+		// Capacity is only withheld once the agent has committed to it
+		w.state.AddReservation(CapacityReservation{
+			ID:           notice.ID,
+			Capability:   notice.Capability,
+			Slots:        notice.Slots,
+			Concurrency:  notice.Concurrency,
+			PricePerSlot: notice.PricePerSlot,
+			WindowStart:  notice.WindowStart,
+			WindowEnd:    notice.WindowEnd,
+		})
+	case "settled", "cancelled", "expired":
+		w.state.RemoveReservation(notice.ID)
+	}
+
+	w.logger.Debug("reservation notice applied",
+		zap.String("reservation_id", notice.ID),
+		zap.String("status", notice.Status),
+	)
+	return nil
+}
@@ -0,0 +1,147 @@
+package market
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func openReservation(id string, slots, concurrency int) CapacityReservation {
+	now := time.Now()
+	return CapacityReservation{
+		ID:          id,
+		Capability:  "math",
+		Slots:       slots,
+		Concurrency: concurrency,
+		WindowStart: now.Add(-time.Minute),
+		WindowEnd:   now.Add(time.Hour),
+	}
+}
+
+func TestCapacityReservationHeld(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &CapacityReservation{Slots: 3, Concurrency: 2, WindowStart: start, WindowEnd: start.Add(time.Hour)}
+
+	tests := []struct {
+		name           string
+		at             time.Time
+		used, inFlight int
+		want           int
+	}{
+		{"before window", start.Add(-time.Second), 0, 0, 0},
+		{"window open", start, 0, 0, 2},
+		{"one in flight", start.Add(time.Minute), 1, 1, 1},
+		{"last slot", start.Add(time.Minute), 2, 0, 1},
+		{"used up", start.Add(time.Minute), 3, 1, 0},
+		{"window closed", start.Add(time.Hour), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.Used, r.InFlight = tt.used, tt.inFlight
+			if got := r.held(tt.at); got != tt.want {
+				t.Errorf("expected %d held, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBidderStateWithholdsReservedCapacity(t *testing.T) {
+	state := NewBidderState(3)
+	state.AddReservation(openReservation("res-1", 5, 2))
+	if got := state.ReservedCapacity(); got != 2 {
+		t.Fatalf("expected 2 slots held, got %d", got)
+	}
+
+	state.IncrementActiveTasks()
+	if state.CanAcceptTask() {
+		t.Error("expected no spot capacity with 1 spot task and 2 reserved slots")
+	}
+
+	// A reserved task uses its own held slot, not spot capacity
+	if !state.StartReservedTask("res-1") {
+		t.Fatal("expected the reserved task to start")
+	}
+	if state.ReservedCapacity() != 1 || state.ActiveTasks != 2 {
+		t.Errorf("expected 1 slot held and 2 active tasks, got %d and %d", state.ReservedCapacity(), state.ActiveTasks)
+	}
+
+	// New terms keep the usage so far
+	state.AddReservation(openReservation("res-1", 1, 2))
+	if state.ReservedCapacity() != 0 {
+		t.Errorf("expected the single slot used, got %d held", state.ReservedCapacity())
+	}
+	if state.StartReservedTask("res-1") {
+		t.Error("expected a used-up reservation to refuse tasks")
+	}
+	if state.StartReservedTask("res-unknown") {
+		t.Error("expected an unknown reservation to refuse tasks")
+	}
+
+	state.FinishReservedTask("res-1")
+	if state.ActiveTasks != 1 || !state.CanAcceptTask() {
+		t.Errorf("expected spot capacity back after the reserved task, got %d active", state.ActiveTasks)
+	}
+
+	state.AddReservation(openReservation("res-2", 10, 0))
+	if state.ReservedCapacity() != 1 {
+		t.Errorf("expected concurrency to default to one, got %d held", state.ReservedCapacity())
+	}
+	state.RemoveReservation("res-2")
+	if state.ReservedCapacity() != 0 {
+		t.Errorf("expected nothing held after removal, got %d", state.ReservedCapacity())
+	}
+}
+
+func TestReservationWatcherAppliesNotices(t *testing.T) {
+	bus := newLocalBus()
+	state := NewBidderState(4)
+	if err := NewReservationWatcher("did:agent:a", state, nil).Subscribe(bus); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(agentDID, status string, wrap bool) {
+		t.Helper()
+		r := openReservation("res-1", 4, 2)
+		data, _ := json.Marshal(reservationNotice{
+			ID:          r.ID,
+			AgentDID:    agentDID,
+			Capability:  r.Capability,
+			Slots:       r.Slots,
+			Concurrency: r.Concurrency,
+			WindowStart: r.WindowStart,
+			WindowEnd:   r.WindowEnd,
+			Status:      status,
+		})
+		if wrap {
+			data, _ = json.Marshal(map[string]interface{}{"type": "reservation", "payload": json.RawMessage(data)})
+		}
+		if err := bus.Publish(ReservationTopic("did:agent:a"), data); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	// Capacity isn't withheld until the agent has committed to it
+	publish("did:agent:a", "pending", false)
+	if state.ReservedCapacity() != 0 {
+		t.Error("expected a pending reservation to hold nothing")
+	}
+
+	publish("did:agent:a", "confirmed", true)
+	if state.ReservedCapacity() != 2 {
+		t.Errorf("expected 2 slots held once confirmed, got %d", state.ReservedCapacity())
+	}
+
+	publish("did:agent:b", "cancelled", false)
+	if state.ReservedCapacity() != 2 {
+		t.Error("expected another agent's notice to be ignored")
+	}
+
+	publish("did:agent:a", "cancelled", false)
+	if state.ReservedCapacity() != 0 {
+		t.Errorf("expected nothing held after cancellation, got %d", state.ReservedCapacity())
+	}
+
+	if err := bus.Publish(ReservationTopic("did:agent:a"), []byte("{not json")); err == nil {
+		t.Error("expected a malformed notice to be rejected")
+	}
+}
@@ -123,13 +123,22 @@ func NewRLBidder(agentID string, capabilities []string, logger *zap.Logger) *RLB
 
 // SelectBidPrice uses epsilon-greedy Q-learning to choose bid price
 func (b *RLBidder) SelectBidPrice(ctx context.Context, cfp *CFP) (float64, error) {
+	bidPrice, _ := b.decide(cfp, time.Now())
+	return bidPrice, nil
+}
+
+// decide picks a bid price for the CFP as of now and records the decision,
+// returning the experience to learn from once the outcome is known
+func (b *RLBidder) decide(cfp *CFP, now time.Time) (float64, Experience) {
 	// Update market state
-	b.updateMarketState(cfp)
+	b.updateMarketState(cfp, now)
 
-	// Get current state
+	// Snapshot current state so the stored experience is not mutated by
+	// later outcomes
 	b.stateMux.RLock()
-	state := b.state
+	snapshot := *b.state
 	b.stateMux.RUnlock()
+	state := &snapshot
 
 	// Encode state for Q-table lookup
 	stateKey := b.encodeState(state)
@@ -162,17 +171,14 @@ func (b *RLBidder) SelectBidPrice(ctx context.Context, cfp *CFP) (float64, error
 	bidPrice := b.actionToPrice(action, cfp.Budget)
 
 	// Store experience for learning later
-	b.storePreDecision(state, action, cfp)
+	exp := b.storePreDecision(state, action, now)
 
-	return bidPrice, nil
+	return bidPrice, exp
 }
 
 // Learn updates Q-table based on task outcome
 // This is the core Q-learning algorithm: Q(s,a) ← Q(s,a) + α·[R + γ·max_a' Q(s',a') - Q(s,a)]
 func (b *RLBidder) Learn(outcome TaskOutcome) {
-	// Calculate reward
-	reward := b.calculateReward(outcome)
-
 	// Get stored experience
 	b.replayMux.Lock()
 	if len(b.replayBuffer) == 0 {
@@ -183,9 +189,21 @@ func (b *RLBidder) Learn(outcome TaskOutcome) {
 	exp := b.replayBuffer[len(b.replayBuffer)-1]
 	b.replayMux.Unlock()
 
+	b.learnFrom(exp, outcome)
+}
+
+// learnFrom applies the Q-learning update for the experience that led to outcome
+func (b *RLBidder) learnFrom(exp Experience, outcome TaskOutcome) {
+	// Calculate reward
+	reward := b.calculateReward(outcome)
+
 	// Update experience with outcome
+	b.stateMux.RLock()
+	next := *b.state
+	b.stateMux.RUnlock()
+
 	exp.Reward = reward
-	exp.NextState = b.state
+	exp.NextState = &next
 	exp.Done = true
 
 	// Q-learning update
@@ -250,8 +268,9 @@ func (b *RLBidder) calculateReward(outcome TaskOutcome) float64 {
 	return reward
 }
 
-// updateMarketState updates market demand based on CFP characteristics
-func (b *RLBidder) updateMarketState(cfp *CFP) {
+// updateMarketState updates market demand based on CFP characteristics as
+// seen at the given time
+func (b *RLBidder) updateMarketState(cfp *CFP, now time.Time) {
 	// Simple heuristic: high budget + urgent deadline = high demand
 	demandScore := 0.5
 
@@ -261,7 +280,7 @@ func (b *RLBidder) updateMarketState(cfp *CFP) {
 	}
 
 	// Deadline factor: tight deadline indicates urgency
-	timeToDeadline := time.Until(time.Unix(cfp.Deadline, 0))
+	timeToDeadline := time.Unix(cfp.Deadline, 0).Sub(now)
 	if timeToDeadline < 1*time.Hour {
 		demandScore += 0.2
 	}
@@ -371,17 +390,35 @@ func (b *RLBidder) actionToPrice(action int, budget float64) float64 {
 	return budget * ratio
 }
 
+// priceToAction maps a bid price back to the nearest discrete action, used
+// when replaying recorded bids
+func (b *RLBidder) priceToAction(price, budget float64) int {
+	if budget <= 0 {
+		return 0
+	}
+	step := (b.maxBidRatio - b.minBidRatio) / float64(b.numActions-1)
+	action := int(math.Round((price/budget - b.minBidRatio) / step))
+
+	return clamp(action, 0, b.numActions-1)
+}
+
 // storePreDecision saves state and action before outcome is known
-func (b *RLBidder) storePreDecision(state *RLBidderState, action int, cfp *CFP) {
+func (b *RLBidder) storePreDecision(state *RLBidderState, action int, now time.Time) Experience {
 	exp := Experience{
 		State:     state,
 		Action:    action,
 		Reward:    0, // Will be filled in Learn()
 		NextState: nil,
 		Done:      false,
-		Timestamp: time.Now(),
+		Timestamp: now,
 	}
 
+	b.recordExperience(exp)
+	return exp
+}
+
+// recordExperience appends an experience to the bounded replay buffer
+func (b *RLBidder) recordExperience(exp Experience) {
 	b.replayMux.Lock()
 	defer b.replayMux.Unlock()
 
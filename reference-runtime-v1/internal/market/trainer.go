@@ -0,0 +1,234 @@
+package market
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Market log record types
+const (
+	LogRecordCFP     = "cfp"
+	LogRecordBid     = "bid"
+	LogRecordOutcome = "outcome"
+)
+
+// MarketLogRecord is one line of a recorded CFP/bid/outcome log (JSONL)
+type MarketLogRecord struct {
+	Type      string       `json:"type"`
+	Timestamp time.Time    `json:"timestamp"`
+	CFP       *CFP         `json:"cfp,omitempty"`
+	Bid       *BidMessage  `json:"bid,omitempty"`
+	Outcome   *TaskOutcome `json:"outcome,omitempty"`
+}
+
+// MarketLogWriter records market activity as JSONL for offline training
+type MarketLogWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewMarketLogWriter creates a log writer on w
+func NewMarketLogWriter(w io.Writer) *MarketLogWriter {
+	return &MarketLogWriter{enc: json.NewEncoder(w)}
+}
+
+// Write appends a record, stamping it with the current time if unset
+func (w *MarketLogWriter) Write(record MarketLogRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(record)
+}
+
+// TrainingReport summarizes an offline training run
+type TrainingReport struct {
+	Records   int     `json:"records"`
+	CFPs      int     `json:"cfps"`
+	Bids      int     `json:"bids"`
+	Outcomes  int     `json:"outcomes"`
+	Skipped   int     `json:"skipped"`   // Malformed or unmatched records
+	Unsettled int     `json:"unsettled"` // Bids without an outcome
+	Wins      int     `json:"wins"`
+	Profit    float64 `json:"profit"`
+}
+
+// OfflineTrainer pre-trains an RLBidder by replaying recorded market logs,
+// so a runtime can go live with a policy instead of exploring from scratch.
+// An optional pricing strategy is trained on the same bid results.
+type OfflineTrainer struct {
+	bidder  *RLBidder
+	pricing PricingStrategy
+	state   *BidderState
+	logger  *zap.Logger
+}
+
+// NewOfflineTrainer creates a trainer for the given bidder. pricing may be nil.
+func NewOfflineTrainer(bidder *RLBidder, pricing PricingStrategy, logger *zap.Logger) *OfflineTrainer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &OfflineTrainer{
+		bidder:  bidder,
+		pricing: pricing,
+		state:   NewBidderState(1),
+		logger:  logger,
+	}
+}
+
+// pendingBid is a replayed bid awaiting its outcome
+type pendingBid struct {
+	cfp   *CFP
+	price float64
+	exp   Experience
+}
+
+// Train replays a JSONL market log. Bids are mapped to the nearest discrete
+// action and learned from once their outcome is seen; records are expected
+// in the order they were logged.
+func (t *OfflineTrainer) Train(ctx context.Context, r io.Reader) (*TrainingReport, error) {
+	report := &TrainingReport{}
+	cfps := make(map[string]*CFP)
+	pending := make(map[string]*pendingBid) // CFP ID → our bid
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		report.Records++
+
+		var record MarketLogRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.logger.Debug("skipping malformed log record", zap.Int("line", report.Records), zap.Error(err))
+			report.Skipped++
+			continue
+		}
+
+		switch record.Type {
+		case LogRecordCFP:
+			if record.CFP == nil {
+				report.Skipped++
+				continue
+			}
+			cfps[record.CFP.ID] = record.CFP
+			report.CFPs++
+
+		case LogRecordBid:
+			if record.Bid == nil || cfps[record.Bid.CFPID] == nil {
+				report.Skipped++
+				continue
+			}
+			cfp := cfps[record.Bid.CFPID]
+			pending[cfp.ID] = &pendingBid{cfp: cfp, price: record.Bid.Price, exp: t.replayBid(cfp, record.Bid.Price, record.Timestamp)}
+			report.Bids++
+
+		case LogRecordOutcome:
+			if record.Outcome == nil || pending[record.Outcome.CFPId] == nil {
+				report.Skipped++
+				continue
+			}
+			bid := pending[record.Outcome.CFPId]
+			delete(pending, record.Outcome.CFPId)
+			t.replayOutcome(ctx, bid, *record.Outcome)
+
+			report.Outcomes++
+			if record.Outcome.Won {
+				report.Wins++
+				report.Profit += record.Outcome.Profit
+			}
+
+		default:
+			report.Skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read market log: %w", err)
+	}
+
+	report.Unsettled = len(pending)
+
+	t.logger.Info("offline training complete",
+		zap.String("agent", t.bidder.agentID),
+		zap.Int("records", report.Records),
+		zap.Int("outcomes", report.Outcomes),
+		zap.Int("skipped", report.Skipped),
+		zap.Float64("epsilon", t.bidder.epsilon),
+	)
+
+	return report, nil
+}
+
+// TrainFile replays the market log at path
+func (t *OfflineTrainer) TrainFile(ctx context.Context, path string) (*TrainingReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market log: %w", err)
+	}
+	defer f.Close()
+
+	return t.Train(ctx, f)
+}
+
+// replayBid reconstructs the decision the bidder would have recorded for a logged bid
+func (t *OfflineTrainer) replayBid(cfp *CFP, price float64, at time.Time) Experience {
+	b := t.bidder
+	b.updateMarketState(cfp, at)
+
+	b.stateMux.RLock()
+	state := *b.state
+	b.stateMux.RUnlock()
+
+	exp := Experience{
+		State:     &state,
+		Action:    b.priceToAction(price, cfp.Budget),
+		Timestamp: at,
+	}
+	b.recordExperience(exp)
+
+	if t.pricing != nil {
+		t.state.RecordBidSubmitted(cfp.Capability, price)
+	}
+
+	return exp
+}
+
+// replayOutcome applies the outcome to the bidder and the pricing strategy
+func (t *OfflineTrainer) replayOutcome(ctx context.Context, bid *pendingBid, outcome TaskOutcome) {
+	t.bidder.learnFrom(bid.exp, outcome)
+
+	if t.pricing == nil {
+		return
+	}
+
+	if outcome.Won {
+		t.state.RecordBidAccepted(bid.cfp.Capability)
+	} else {
+		t.state.RecordBidRejected(bid.cfp.Capability)
+	}
+
+	msg := &CFPMessage{CFPID: bid.cfp.ID, Capability: bid.cfp.Capability, Budget: bid.cfp.Budget}
+	t.pricing.OnBidResult(ctx, outcome.Won, msg, bid.price, t.state)
+	if outcome.Won {
+		t.state.RecordTaskOutcome(outcome)
+		t.pricing.OnTaskOutcome(ctx, outcome, t.state)
+	}
+}
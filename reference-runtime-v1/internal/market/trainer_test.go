@@ -0,0 +1,110 @@
+package market
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// marketLog is a fixed log of three CFPs: a bid that won, one that lost and
+// one still open, among records the trainer must skip
+func marketLog(t *testing.T) []byte {
+	t.Helper()
+	opened := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return opened.Add(time.Duration(minutes) * time.Minute) }
+	cfp := func(id string, budget float64, deadline time.Duration) *CFP {
+		return &CFP{ID: id, Capability: "math", Budget: budget, Deadline: opened.Add(deadline).Unix()}
+	}
+
+	var buf bytes.Buffer
+	w := NewMarketLogWriter(&buf)
+	for _, record := range []MarketLogRecord{
+		{Type: LogRecordCFP, Timestamp: at(0), CFP: cfp("cfp-1", 100, 30*time.Minute)},
+		{Type: LogRecordBid, Timestamp: at(0), Bid: &BidMessage{CFPID: "cfp-1", Price: 60}},
+		{Type: LogRecordOutcome, Timestamp: at(1), Outcome: &TaskOutcome{CFPId: "cfp-1", Won: true, Success: true, Profit: 20}},
+		{Type: LogRecordCFP, Timestamp: at(2), CFP: cfp("cfp-2", 200, 3*time.Hour)},
+		{Type: LogRecordBid, Timestamp: at(2), Bid: &BidMessage{CFPID: "cfp-2", Price: 60}},
+		{Type: LogRecordOutcome, Timestamp: at(3), Outcome: &TaskOutcome{CFPId: "cfp-2", Won: false}},
+		{Type: LogRecordCFP, Timestamp: at(4), CFP: cfp("cfp-3", 50, time.Hour)},
+		{Type: LogRecordBid, Timestamp: at(4), Bid: &BidMessage{CFPID: "cfp-3", Price: 40}},
+		{Type: LogRecordBid, Timestamp: at(5), Bid: &BidMessage{CFPID: "cfp-unknown", Price: 10}},
+		{Type: LogRecordOutcome, Timestamp: at(5), Outcome: &TaskOutcome{CFPId: "cfp-unknown", Won: true}},
+		{Type: "heartbeat", Timestamp: at(6)},
+	} {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString("{not json\n\n")
+	return buf.Bytes()
+}
+
+func TestOfflineTrainerReplaysLog(t *testing.T) {
+	bidder := NewRLBidder("did:agent:a", []string{"math"}, nil)
+	report, err := NewOfflineTrainer(bidder, nil, zap.NewNop()).Train(context.Background(), bytes.NewReader(marketLog(t)))
+	if err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+
+	want := TrainingReport{Records: 12, CFPs: 3, Bids: 3, Outcomes: 2, Skipped: 4, Unsettled: 1, Wins: 1, Profit: 20}
+	if *report != want {
+		t.Errorf("expected report %+v, got %+v", want, *report)
+	}
+
+	// Both bids were made in state c0w0d3r2: the win at 60% of the budget
+	// (action 3) earned 20 profit plus the completion bonus, and the loss at
+	// 30% (action 0) still looks ahead to that win
+	cp := bidder.Checkpoint()
+	wantQ := []QEntry{{State: "c0w0d3r2", Action: 0, Q: 0.1 * (-1 + 0.9*3)}, {State: "c0w0d3r2", Action: 3, Q: 0.1 * 30}}
+	if len(cp.QTable) != len(wantQ) {
+		t.Fatalf("expected Q-table %v, got %v", wantQ, cp.QTable)
+	}
+	for i, entry := range cp.QTable {
+		if entry.State != wantQ[i].State || entry.Action != wantQ[i].Action || math.Abs(entry.Q-wantQ[i].Q) > 1e-9 {
+			t.Errorf("expected %+v, got %+v", wantQ[i], entry)
+		}
+	}
+	if wantEpsilon := 0.5 * 0.995 * 0.995; math.Abs(cp.Epsilon-wantEpsilon) > 1e-9 {
+		t.Errorf("expected epsilon %v after two outcomes, got %v", wantEpsilon, cp.Epsilon)
+	}
+	if len(cp.ReplayBuffer) != 3 {
+		t.Errorf("expected an experience per bid, got %d", len(cp.ReplayBuffer))
+	}
+
+	// Replaying the same log gives the same policy
+	again := NewRLBidder("did:agent:a", []string{"math"}, nil)
+	if _, err := NewOfflineTrainer(again, nil, zap.NewNop()).Train(context.Background(), bytes.NewReader(marketLog(t))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Checkpoint().QTable, cp.QTable) {
+		t.Error("expected replaying the same log to be deterministic")
+	}
+}
+
+func TestOfflineTrainerTrainsPricing(t *testing.T) {
+	pricing := NewCompetitivePricing(10, 0.3, 0.05, 0.1, zap.NewNop())
+	trainer := NewOfflineTrainer(NewRLBidder("did:agent:a", []string{"math"}, nil), pricing, zap.NewNop())
+	if _, err := trainer.Train(context.Background(), bytes.NewReader(marketLog(t))); err != nil {
+		t.Fatalf("Train failed: %v", err)
+	}
+
+	// Winning one of two bids is above the 30% target, so the price rises
+	if pricing.CurrentPrice <= pricing.BasePrice {
+		t.Errorf("expected the price raised above %v, got %v", pricing.BasePrice, pricing.CurrentPrice)
+	}
+}
+
+func TestOfflineTrainerStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	trainer := NewOfflineTrainer(NewRLBidder("did:agent:a", []string{"math"}, nil), nil, zap.NewNop())
+	if _, err := trainer.Train(ctx, bytes.NewReader(marketLog(t))); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Report summarizes a simulation run
+type Report struct {
+	Mechanism Mechanism `json:"mechanism"`
+	Tasks     int       `json:"tasks"`
+	Allocated int       `json:"allocated"`
+	Completed int       `json:"completed"`
+	Failed    int       `json:"failed"`
+
+	// Welfare is the sum over allocated tasks of budget minus the winner's
+	// private cost; OptimalWelfare allocates every task to the cheapest
+	// capable runtime with free capacity. Efficiency is their ratio.
+	Welfare        float64 `json:"welfare"`
+	OptimalWelfare float64 `json:"optimal_welfare"`
+	Efficiency     float64 `json:"efficiency"`
+
+	// Revenue is what requesters paid runtimes for allocated tasks
+	Revenue float64 `json:"revenue"`
+
+	// WinGini is the Gini coefficient of wins across bidders: 0 when wins
+	// are spread evenly, approaching 1 when one bidder wins everything
+	WinGini float64        `json:"win_gini"`
+	Bidders []BidderReport `json:"bidders"`
+	Prices  []PriceSeries  `json:"prices"`
+}
+
+// BidderReport summarizes one bidder's run
+type BidderReport struct {
+	ID        string  `json:"id"`
+	Strategy  string  `json:"strategy"`
+	Bids      int     `json:"bids"`
+	Wins      int     `json:"wins"`
+	WinRate   float64 `json:"win_rate"`
+	MeanBid   float64 `json:"mean_bid"`
+	Revenue   float64 `json:"revenue"`
+	Profit    float64 `json:"profit"`
+	Completed int     `json:"completed"`
+	Failed    int     `json:"failed"`
+}
+
+// PriceSeries tracks clearing prices for one capability. Windows holds the
+// mean clearing price of each PriceWindow consecutive allocations.
+type PriceSeries struct {
+	Capability string    `json:"capability"`
+	Windows    []float64 `json:"windows"`
+	FinalDrift float64   `json:"final_drift"` // Relative change between the last two windows
+	Converged  bool      `json:"converged"`
+}
+
+// Thresholds are the pass criteria for a regression run
+type Thresholds struct {
+	MinEfficiency      float64
+	MinAllocationRate  float64
+	RequireConvergence bool
+}
+
+// Check returns one message per threshold the report misses
+func (r *Report) Check(t Thresholds) []string {
+	var failures []string
+	if r.Efficiency < t.MinEfficiency {
+		failures = append(failures, fmt.Sprintf("efficiency %.3f below %.3f", r.Efficiency, t.MinEfficiency))
+	}
+	if r.Tasks > 0 {
+		if rate := float64(r.Allocated) / float64(r.Tasks); rate < t.MinAllocationRate {
+			failures = append(failures, fmt.Sprintf("allocation rate %.3f below %.3f", rate, t.MinAllocationRate))
+		}
+	}
+	if t.RequireConvergence {
+		for _, series := range r.Prices {
+			if !series.Converged {
+				failures = append(failures, fmt.Sprintf("%s prices did not converge (drift %.3f)", series.Capability, series.FinalDrift))
+			}
+		}
+	}
+	return failures
+}
+
+// collector accumulates run statistics
+type collector struct {
+	cfg      Config
+	totals   Report
+	bidders  map[*simBidder]*BidderReport
+	bidTotal map[*simBidder]float64
+	order    []*simBidder
+	prices   map[string][]float64 // Capability → clearing prices in order
+}
+
+func newCollector(cfg Config, bidders []*simBidder) *collector {
+	c := &collector{
+		cfg:      cfg,
+		totals:   Report{Mechanism: cfg.Mechanism},
+		bidders:  make(map[*simBidder]*BidderReport, len(bidders)),
+		bidTotal: make(map[*simBidder]float64, len(bidders)),
+		order:    bidders,
+		prices:   make(map[string][]float64),
+	}
+	for _, bidder := range bidders {
+		c.bidders[bidder] = &BidderReport{ID: bidder.spec.ID, Strategy: bidder.spec.Strategy.Name()}
+	}
+	return c
+}
+
+func (c *collector) recordTask(capability string, budget, minCost float64) {
+	c.totals.Tasks++
+	if !math.IsInf(minCost, 1) && minCost < budget {
+		c.totals.OptimalWelfare += budget - minCost
+	}
+}
+
+func (c *collector) recordBid(bidder *simBidder, price float64, won bool) {
+	br := c.bidders[bidder]
+	br.Bids++
+	c.bidTotal[bidder] += price
+	if won {
+		br.Wins++
+	}
+}
+
+func (c *collector) recordAllocation(capability string, budget float64, winner *simBidder, payment float64) {
+	c.totals.Allocated++
+	c.totals.Welfare += budget - winner.spec.Cost
+	c.totals.Revenue += payment
+	c.prices[capability] = append(c.prices[capability], payment)
+}
+
+func (c *collector) recordCompletion(bidder *simBidder, payment, profit float64, success bool) {
+	br := c.bidders[bidder]
+	br.Profit += profit
+	if success {
+		c.totals.Completed++
+		br.Completed++
+		br.Revenue += payment
+	} else {
+		c.totals.Failed++
+		br.Failed++
+	}
+}
+
+func (c *collector) report() *Report {
+	r := c.totals
+	if r.OptimalWelfare > 0 {
+		r.Efficiency = r.Welfare / r.OptimalWelfare
+	}
+
+	wins := make([]float64, 0, len(c.order))
+	for _, bidder := range c.order {
+		br := *c.bidders[bidder]
+		if br.Bids > 0 {
+			br.WinRate = float64(br.Wins) / float64(br.Bids)
+			br.MeanBid = c.bidTotal[bidder] / float64(br.Bids)
+		}
+		r.Bidders = append(r.Bidders, br)
+		wins = append(wins, float64(br.Wins))
+	}
+	r.WinGini = gini(wins)
+
+	capabilities := make([]string, 0, len(c.prices))
+	for capability := range c.prices {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	for _, capability := range capabilities {
+		r.Prices = append(r.Prices, c.priceSeries(capability))
+	}
+
+	return &r
+}
+
+func (c *collector) priceSeries(capability string) PriceSeries {
+	series := PriceSeries{Capability: capability}
+	prices := c.prices[capability]
+
+	for start := 0; start+c.cfg.PriceWindow <= len(prices); start += c.cfg.PriceWindow {
+		sum := 0.0
+		for _, p := range prices[start : start+c.cfg.PriceWindow] {
+			sum += p
+		}
+		series.Windows = append(series.Windows, sum/float64(c.cfg.PriceWindow))
+	}
+
+	if n := len(series.Windows); n >= 2 && series.Windows[n-2] > 0 {
+		series.FinalDrift = math.Abs(series.Windows[n-1]-series.Windows[n-2]) / series.Windows[n-2]
+		series.Converged = series.FinalDrift <= c.cfg.ConvergenceTolerance
+	}
+
+	return series
+}
+
+// gini computes the Gini coefficient of non-negative values
+func gini(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum, weighted float64
+	for i, v := range sorted {
+		sum += v
+		weighted += float64(i+1) * v
+	}
+	if sum == 0 {
+		return 0
+	}
+
+	return (2*weighted)/(float64(n)*sum) - float64(n+1)/float64(n)
+}
@@ -0,0 +1,376 @@
+// Package sim is an in-process discrete-event market simulator. It drives a
+// population of runtime bidders, each with its own market.PricingStrategy,
+// against synthetic task arrival streams and clears every task through the
+// orchestrator's auction mechanisms, so pricing strategies and mechanisms
+// can be evaluated together and regression-checked in CI.
+package sim
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/aidenlippert/zerostate/libs/agentcard-go"
+	"github.com/aidenlippert/zerostate/libs/orchestration"
+	"github.com/aidenlippert/zerostate/reference-runtime-v1/internal/market"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidConfig is returned for a malformed simulation config
+var ErrInvalidConfig = errors.New("invalid simulation config")
+
+// Mechanism selects how the simulated auctioneer clears bids
+type Mechanism string
+
+const (
+	MechanismFirstPrice Mechanism = "first-price" // Winner is paid its own bid
+	MechanismVCG        Mechanism = "vcg"         // Winner is paid the second-lowest bid
+)
+
+// TaskStream is a Poisson arrival process of tasks for one capability
+type TaskStream struct {
+	Capability    string
+	RatePerMinute float64
+	BudgetMean    float64
+	BudgetStdDev  float64
+	MeanDuration  time.Duration // Exponentially distributed execution time
+}
+
+// BidderSpec describes one simulated runtime
+type BidderSpec struct {
+	ID           string
+	Capabilities []string
+	Strategy     market.PricingStrategy
+	MaxTasks     int
+	Cost         float64 // Private cost of executing one task
+	Reputation   float64 // 0-1000, as reported in bids
+	Reliability  float64 // Probability a won task succeeds; 0 means always
+}
+
+// Config configures a simulation run
+type Config struct {
+	// Seed drives arrivals, budgets, durations and failures. Strategies that
+	// keep their own randomness are not reseeded, so metrics vary slightly
+	// between runs; CI thresholds should leave headroom for that.
+	Seed      int64
+	Start     time.Time     // Simulated start time; defaults to now
+	Duration  time.Duration // Simulated horizon
+	Mechanism Mechanism
+	Streams   []TaskStream
+	Bidders   []BidderSpec
+
+	// Cleared tasks per price-convergence window (default 50) and the
+	// relative change between the last two windows that counts as converged
+	// (default 0.05)
+	PriceWindow          int
+	ConvergenceTolerance float64
+}
+
+// Simulator runs one simulation. It is not safe for concurrent use.
+type Simulator struct {
+	cfg     Config
+	rng     *rand.Rand
+	now     time.Time
+	events  eventQueue
+	seq     int
+	tasks   int
+	bidders []*simBidder
+	vcg     *orchestration.VCGAuctioneer
+	stats   *collector
+	logger  *zap.Logger
+}
+
+type simBidder struct {
+	spec  BidderSpec
+	state *market.BidderState
+	caps  map[string]bool
+}
+
+// New validates the config and builds a simulator
+func New(cfg Config, logger *zap.Logger) (*Simulator, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.Duration <= 0 {
+		return nil, fmt.Errorf("%w: duration must be positive", ErrInvalidConfig)
+	}
+	if len(cfg.Streams) == 0 || len(cfg.Bidders) == 0 {
+		return nil, fmt.Errorf("%w: need at least one task stream and one bidder", ErrInvalidConfig)
+	}
+	switch cfg.Mechanism {
+	case "":
+		cfg.Mechanism = MechanismVCG
+	case MechanismFirstPrice, MechanismVCG:
+	default:
+		return nil, fmt.Errorf("%w: unknown mechanism %q", ErrInvalidConfig, cfg.Mechanism)
+	}
+	for _, stream := range cfg.Streams {
+		if stream.RatePerMinute <= 0 || stream.BudgetMean <= 0 {
+			return nil, fmt.Errorf("%w: stream %q needs a positive rate and budget", ErrInvalidConfig, stream.Capability)
+		}
+	}
+	if cfg.Start.IsZero() {
+		cfg.Start = time.Now()
+	}
+	if cfg.PriceWindow <= 0 {
+		cfg.PriceWindow = 50
+	}
+	if cfg.ConvergenceTolerance <= 0 {
+		cfg.ConvergenceTolerance = 0.05
+	}
+
+	s := &Simulator{
+		cfg:    cfg,
+		rng:    rand.New(rand.NewSource(cfg.Seed)),
+		now:    cfg.Start,
+		vcg:    orchestration.NewVCGAuctioneer(nil, logger),
+		logger: logger,
+	}
+
+	seen := make(map[string]bool, len(cfg.Bidders))
+	for _, spec := range cfg.Bidders {
+		if spec.ID == "" || seen[spec.ID] {
+			return nil, fmt.Errorf("%w: bidder IDs must be unique and non-empty", ErrInvalidConfig)
+		}
+		if spec.Strategy == nil || spec.MaxTasks <= 0 {
+			return nil, fmt.Errorf("%w: bidder %s needs a strategy and capacity", ErrInvalidConfig, spec.ID)
+		}
+		seen[spec.ID] = true
+
+		// Learning strategies must see simulated time, not wall time
+		if rl, ok := spec.Strategy.(*market.RLPricing); ok {
+			rl.Now = s.Now
+		}
+
+		bidder := &simBidder{
+			spec:  spec,
+			state: market.NewBidderState(spec.MaxTasks),
+			caps:  make(map[string]bool, len(spec.Capabilities)),
+		}
+		for _, capability := range spec.Capabilities {
+			bidder.caps[capability] = true
+		}
+		s.bidders = append(s.bidders, bidder)
+	}
+
+	s.stats = newCollector(cfg, s.bidders)
+	return s, nil
+}
+
+// Now returns the simulated clock
+func (s *Simulator) Now() time.Time {
+	return s.now
+}
+
+// Run simulates the configured horizon and reports on the market
+func (s *Simulator) Run(ctx context.Context) (*Report, error) {
+	end := s.cfg.Start.Add(s.cfg.Duration)
+	for i := range s.cfg.Streams {
+		s.scheduleArrival(i)
+	}
+
+	for s.events.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ev := heap.Pop(&s.events).(*event)
+		if ev.at.After(end) {
+			break
+		}
+		s.now = ev.at
+
+		switch ev.kind {
+		case eventArrival:
+			s.runAuction(ctx, ev.stream)
+			s.scheduleArrival(ev.stream)
+		case eventCompletion:
+			s.complete(ctx, ev.task)
+		}
+	}
+
+	report := s.stats.report()
+	s.logger.Info("market simulation complete",
+		zap.String("mechanism", string(s.cfg.Mechanism)),
+		zap.Int("tasks", report.Tasks),
+		zap.Int("allocated", report.Allocated),
+		zap.Float64("efficiency", report.Efficiency),
+		zap.Float64("revenue", report.Revenue),
+	)
+
+	return report, nil
+}
+
+// simTask is a won task executing on a bidder
+type simTask struct {
+	id         string
+	capability string
+	bidder     *simBidder
+	bidPrice   float64
+	payment    float64
+}
+
+func (s *Simulator) scheduleArrival(stream int) {
+	rate := s.cfg.Streams[stream].RatePerMinute / float64(time.Minute)
+	s.push(&event{at: s.now.Add(time.Duration(s.rng.ExpFloat64() / rate)), kind: eventArrival, stream: stream})
+}
+
+func (s *Simulator) runAuction(ctx context.Context, streamIdx int) {
+	stream := s.cfg.Streams[streamIdx]
+	s.tasks++
+	taskID := fmt.Sprintf("sim-task-%d", s.tasks)
+
+	budget := stream.BudgetMean + s.rng.NormFloat64()*stream.BudgetStdDev
+	budget = math.Max(stream.BudgetMean*0.1, budget)
+
+	cfp := &market.CFPMessage{
+		CFPID:           "cfp-" + taskID,
+		OrchestratorDID: "did:sim:orchestrator",
+		Capability:      stream.Capability,
+		Budget:          budget,
+		Deadline:        s.now.Add(time.Hour).Format(time.RFC3339),
+	}
+
+	var (
+		bids    []orchestration.BidSummary
+		bidders = make(map[string]*simBidder)
+		minCost = math.Inf(1)
+	)
+	for _, bidder := range s.bidders {
+		if !bidder.caps[stream.Capability] || !bidder.state.CanAcceptTask() {
+			continue
+		}
+		minCost = math.Min(minCost, bidder.spec.Cost)
+
+		if !bidder.spec.Strategy.ShouldBid(ctx, cfp, bidder.state) {
+			continue
+		}
+		price := bidder.spec.Strategy.CalculatePrice(ctx, cfp, bidder.state)
+		if price <= 0 || price > budget {
+			continue
+		}
+
+		bidID := fmt.Sprintf("%s-%s", cfp.CFPID, bidder.spec.ID)
+		bidder.state.RecordBidSubmitted(stream.Capability, price)
+		bidders[bidID] = bidder
+		bids = append(bids, orchestration.BidSummary{
+			BidID:      bidID,
+			AgentDID:   agentcard.DID(bidder.spec.ID),
+			Price:      price,
+			Reputation: bidder.spec.Reputation,
+		})
+	}
+
+	s.stats.recordTask(stream.Capability, budget, minCost)
+
+	comparison := s.vcg.CompareBids(taskID, cfp.CFPID, bids)
+	result := comparison.VCGResult
+	if s.cfg.Mechanism == MechanismFirstPrice {
+		result = comparison.FirstPriceResult
+	}
+
+	for _, bid := range bids {
+		bidder := bidders[bid.BidID]
+		won := result.Winner != nil && result.Winner.BidID == bid.BidID
+		if won {
+			bidder.state.RecordBidAccepted(stream.Capability)
+		} else {
+			bidder.state.RecordBidRejected(stream.Capability)
+		}
+		bidder.spec.Strategy.OnBidResult(ctx, won, cfp, bid.Price, bidder.state)
+		s.stats.recordBid(bidder, bid.Price, won)
+	}
+
+	if result.Winner == nil {
+		return
+	}
+
+	winner := bidders[result.Winner.BidID]
+	winner.state.IncrementActiveTasks()
+	s.stats.recordAllocation(stream.Capability, budget, winner, result.SecondPrice)
+
+	duration := time.Duration(s.rng.ExpFloat64() * float64(stream.MeanDuration))
+	s.push(&event{
+		at:   s.now.Add(duration),
+		kind: eventCompletion,
+		task: &simTask{
+			id:         taskID,
+			capability: stream.Capability,
+			bidder:     winner,
+			bidPrice:   result.Winner.Price,
+			payment:    result.SecondPrice,
+		},
+	})
+}
+
+func (s *Simulator) complete(ctx context.Context, task *simTask) {
+	bidder := task.bidder
+	bidder.state.DecrementActiveTasks()
+
+	success := bidder.spec.Reliability <= 0 || s.rng.Float64() < bidder.spec.Reliability
+	profit := -bidder.spec.Cost
+	if success {
+		profit += task.payment
+	}
+
+	outcome := market.TaskOutcome{
+		TaskID:     task.id,
+		CFPId:      "cfp-" + task.id,
+		BidID:      fmt.Sprintf("cfp-%s-%s", task.id, bidder.spec.ID),
+		Capability: task.capability,
+		BidPrice:   task.bidPrice,
+		ActualCost: bidder.spec.Cost,
+		Profit:     profit,
+		Success:    success,
+		Won:        true,
+		Timestamp:  s.now,
+	}
+
+	bidder.state.RecordTaskOutcome(outcome)
+	bidder.spec.Strategy.OnTaskOutcome(ctx, outcome, bidder.state)
+	s.stats.recordCompletion(bidder, task.payment, profit, success)
+}
+
+func (s *Simulator) push(ev *event) {
+	s.seq++
+	ev.seq = s.seq
+	heap.Push(&s.events, ev)
+}
+
+type eventKind int
+
+const (
+	eventArrival eventKind = iota
+	eventCompletion
+)
+
+type event struct {
+	at     time.Time
+	seq    int // Breaks ties so runs are reproducible
+	kind   eventKind
+	stream int
+	task   *simTask
+}
+
+// eventQueue is a min-heap of events ordered by time
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at.Equal(q[j].at) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x any)   { *q = append(*q, x.(*event)) }
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
@@ -0,0 +1,143 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aidenlippert/zerostate/reference-runtime-v1/internal/market"
+	"go.uber.org/zap"
+)
+
+// seededConfig is a two-hour market of strategies without randomness of
+// their own, so a run depends on the seed alone. Exploration is off for the
+// learning strategies because they draw from their own sources.
+func seededConfig(seed int64, mechanism Mechanism) Config {
+	logger := zap.NewNop()
+	capabilities := []string{"math", "json"}
+	bidder := func(id string, strategy market.PricingStrategy, cost float64) BidderSpec {
+		return BidderSpec{ID: id, Capabilities: capabilities, Strategy: strategy, MaxTasks: 3, Cost: cost, Reputation: 500, Reliability: 0.9}
+	}
+
+	return Config{
+		Seed:      seed,
+		Start:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration:  2 * time.Hour,
+		Mechanism: mechanism,
+		Streams: []TaskStream{
+			{Capability: "math", RatePerMinute: 10, BudgetMean: 20, BudgetStdDev: 4, MeanDuration: 15 * time.Second},
+			{Capability: "json", RatePerMinute: 2, BudgetMean: 60, BudgetStdDev: 10, MeanDuration: time.Minute},
+		},
+		Bidders: []BidderSpec{
+			bidder("did:sim:static", market.NewStaticFloorPricing(15, logger), 8),
+			bidder("did:sim:load-aware", market.NewLoadAwarePricing(12, 2, 2, logger), 7),
+			bidder("did:sim:competitive", market.NewCompetitivePricing(16, 0.3, 0.05, 0, logger), 9),
+			bidder("did:sim:hybrid", market.NewHybridPricing(16, 0.3, 0.05, 0, 2, logger), 6),
+		},
+		PriceWindow: 100,
+	}
+}
+
+func runSeeded(t *testing.T, seed int64, mechanism Mechanism) *Report {
+	t.Helper()
+	simulator, err := New(seededConfig(seed, mechanism), nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	report, err := simulator.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return report
+}
+
+func TestSeededRunIsDeterministic(t *testing.T) {
+	for _, mechanism := range []Mechanism{MechanismVCG, MechanismFirstPrice} {
+		t.Run(string(mechanism), func(t *testing.T) {
+			report := runSeeded(t, 42, mechanism)
+			if again := runSeeded(t, 42, mechanism); !reflect.DeepEqual(report, again) {
+				t.Fatalf("same seed gave different reports:\n%+v\n%+v", report, again)
+			}
+
+			// Two hours at 12 tasks a minute
+			if report.Tasks < 1200 || report.Tasks > 1700 {
+				t.Errorf("expected about 1440 tasks, got %d", report.Tasks)
+			}
+			if report.Allocated == 0 || report.Allocated > report.Tasks {
+				t.Errorf("expected some of %d tasks allocated, got %d", report.Tasks, report.Allocated)
+			}
+			if report.Completed+report.Failed > report.Allocated {
+				t.Errorf("%d completed and %d failed of %d allocated", report.Completed, report.Failed, report.Allocated)
+			}
+			if report.Efficiency <= 0 || report.Efficiency > 1 {
+				t.Errorf("expected efficiency in (0, 1], got %v", report.Efficiency)
+			}
+			if report.WinGini < 0 || report.WinGini >= 1 {
+				t.Errorf("expected a Gini coefficient in [0, 1), got %v", report.WinGini)
+			}
+
+			wins, revenue := 0, 0.0
+			for _, bidder := range report.Bidders {
+				wins += bidder.Wins
+				revenue += bidder.Revenue
+			}
+			if wins != report.Allocated {
+				t.Errorf("bidders won %d tasks, %d were allocated", wins, report.Allocated)
+			}
+			if revenue > report.Revenue+1e-9 {
+				t.Errorf("bidders earned %v of %v revenue", revenue, report.Revenue)
+			}
+			if len(report.Prices) != 2 || report.Prices[0].Capability != "json" || len(report.Prices[1].Windows) == 0 {
+				t.Errorf("expected price series for json and math, got %+v", report.Prices)
+			}
+		})
+	}
+
+	// Pinned, so nondeterminism that differs between processes rather than
+	// between runs, like map iteration order, fails too
+	report := runSeeded(t, 42, MechanismVCG)
+	if report.Tasks != 1465 || report.Allocated != 1393 || report.Completed != 1244 || report.Failed != 145 {
+		t.Errorf("expected 1465 tasks, 1393 allocated, 1244 completed and 145 failed, got %d, %d, %d and %d",
+			report.Tasks, report.Allocated, report.Completed, report.Failed)
+	}
+	if other := runSeeded(t, 43, MechanismVCG); reflect.DeepEqual(other, report) {
+		t.Error("expected a different seed to give a different market")
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := map[string]func(*Config){
+		"no duration":        func(c *Config) { c.Duration = 0 },
+		"no streams":         func(c *Config) { c.Streams = nil },
+		"unknown mechanism":  func(c *Config) { c.Mechanism = "english" },
+		"zero rate":          func(c *Config) { c.Streams[0].RatePerMinute = 0 },
+		"duplicate bidder":   func(c *Config) { c.Bidders[1].ID = c.Bidders[0].ID },
+		"bidder without cap": func(c *Config) { c.Bidders[0].MaxTasks = 0 },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := seededConfig(1, MechanismVCG)
+			mutate(&cfg)
+			if _, err := New(cfg, nil); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	report := &Report{
+		Tasks:      100,
+		Allocated:  80,
+		Efficiency: 0.9,
+		Prices:     []PriceSeries{{Capability: "math", FinalDrift: 0.2}},
+	}
+	if failures := report.Check(Thresholds{MinEfficiency: 0.8, MinAllocationRate: 0.75}); len(failures) != 0 {
+		t.Errorf("expected the run to pass, got %v", failures)
+	}
+	if failures := report.Check(Thresholds{MinEfficiency: 0.95, MinAllocationRate: 0.9, RequireConvergence: true}); len(failures) != 3 {
+		t.Errorf("expected three failures, got %v", failures)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package task
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// taskCgroup is the cgroup v2 a single native task runs in
+type taskCgroup struct {
+	dir string
+	fd  int // Open directory, for clone3's CLONE_INTO_CGROUP
+}
+
+// newTaskCgroup creates a cgroup for one task under parent, or under the
+// runtime's own cgroup when parent is empty, and applies limits to it. It
+// fails when cgroup v2 isn't mounted, the parent isn't delegated to the
+// runtime, or the memory and pids controllers aren't enabled for it.
+func newTaskCgroup(parent string, limits NativeLimits) (*taskCgroup, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 is not available")
+	}
+	if parent == "" {
+		own, err := ownCgroup()
+		if err != nil {
+			return nil, err
+		}
+		parent = filepath.Join(cgroupRoot, own)
+	}
+
+	dir, err := os.MkdirTemp(parent, "ari-task-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	cg := &taskCgroup{dir: dir, fd: -1}
+
+	if limits.MemoryMB > 0 {
+		if err := cg.write("memory.max", strconv.FormatInt(limits.MemoryMB<<20, 10)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+		// Keep memory.max from being dodged by swapping
+		cg.write("memory.swap.max", "0")
+	}
+	if limits.MaxProcesses > 0 {
+		if err := cg.write("pids.max", strconv.FormatInt(limits.MaxProcesses, 10)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	if cg.fd, err = unix.Open(dir, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	return cg, nil
+}
+
+// ownCgroup returns the runtime's cgroup v2 path, relative to cgroupRoot
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("runtime is not in a cgroup v2")
+}
+
+func (cg *taskCgroup) write(file, value string) error {
+	if err := os.WriteFile(filepath.Join(cg.dir, file), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to set %s: %w", file, err)
+	}
+	return nil
+}
+
+// peakMemory returns the most memory the task used at once, if the kernel
+// reports it
+func (cg *taskCgroup) peakMemory() int64 {
+	data, err := os.ReadFile(filepath.Join(cg.dir, "memory.peak"))
+	if err != nil {
+		return 0
+	}
+	peak, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return peak
+}
+
+// kill kills every process in the cgroup
+func (cg *taskCgroup) kill() {
+	cg.write("cgroup.kill", "1")
+}
+
+// remove kills anything left in the cgroup and deletes it
+func (cg *taskCgroup) remove() {
+	if cg.fd >= 0 {
+		unix.Close(cg.fd)
+		cg.fd = -1
+	}
+	cg.kill()
+	os.Remove(cg.dir)
+}
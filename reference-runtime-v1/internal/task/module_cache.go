@@ -0,0 +1,259 @@
+package task
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bytecodealliance/wasmtime-go/v14"
+	"go.uber.org/zap"
+)
+
+// ModuleHash returns the content address of a WASM binary
+func ModuleHash(binary []byte) string {
+	sum := sha256.Sum256(binary)
+	return hex.EncodeToString(sum[:])
+}
+
+// DefaultMaxCachedModules bounds how many compiled modules a ModuleCache
+// keeps in memory
+const DefaultMaxCachedModules = 64
+
+// ModuleCacheStats reports cache effectiveness
+type ModuleCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Modules   int   `json:"modules"`
+}
+
+// ModuleCache holds compiled wasmtime modules keyed by the hash of their
+// binary, so a module is compiled once per engine however many executors
+// load it. The most recently used modules stay in memory and the rest are
+// evicted; executors keep the modules they already hold. With a directory,
+// compiled code is serialized there and deserialized on the next start
+// instead of recompiling. Deserializing trusts the file contents, so the
+// directory must be private to the runtime. It is safe for concurrent use.
+type ModuleCache struct {
+	engine *wasmtime.Engine
+	dir    string
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Front is most recently used
+	max     int
+	stats   ModuleCacheStats
+}
+
+// moduleEntry is a cached module. ready is closed once it is compiled or
+// deserialized; err is set if that failed.
+type moduleEntry struct {
+	hash   string
+	module *wasmtime.Module
+	ready  chan struct{}
+	err    error
+}
+
+// NewModuleCache creates a module cache. dir may be empty to keep compiled
+// modules in memory only.
+func NewModuleCache(dir string, logger *zap.Logger) (*ModuleCache, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create module cache dir: %w", err)
+		}
+	}
+
+	return &ModuleCache{
+		engine:  wasmtime.NewEngine(),
+		dir:     dir,
+		logger:  logger,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		max:     DefaultMaxCachedModules,
+	}, nil
+}
+
+// SetMaxModules bounds how many compiled modules stay in memory
+func (c *ModuleCache) SetMaxModules(max int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if max <= 0 {
+		max = DefaultMaxCachedModules
+	}
+	c.max = max
+	c.evictLocked()
+}
+
+// Engine returns the engine every cached module is compiled for
+func (c *ModuleCache) Engine() *wasmtime.Engine {
+	return c.engine
+}
+
+// Load returns the compiled module for a binary and its hash. Concurrent
+// loads of one binary compile it once.
+func (c *ModuleCache) Load(binary []byte) (*wasmtime.Module, string, error) {
+	hash := ModuleHash(binary)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[hash]; ok {
+		c.lru.MoveToFront(elem)
+		c.stats.Hits++
+		entry := elem.Value.(*moduleEntry)
+		c.mu.Unlock()
+
+		<-entry.ready
+		return entry.module, hash, entry.err
+	}
+
+	entry := &moduleEntry{hash: hash, ready: make(chan struct{})}
+	c.entries[hash] = c.lru.PushFront(entry)
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	var err error
+	module := c.loadSerialized(hash)
+	compiled := module == nil
+	if compiled {
+		module, err = wasmtime.NewModule(c.engine, binary)
+	}
+
+	c.mu.Lock()
+	entry.module, entry.err = module, err
+	close(entry.ready)
+	if err != nil {
+		// Don't cache failures
+		if elem, ok := c.entries[hash]; ok && elem.Value == entry {
+			c.lru.Remove(elem)
+			delete(c.entries, hash)
+		}
+	}
+	c.evictLocked()
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, "", err
+	}
+	if compiled {
+		c.storeSerialized(hash, module)
+		c.logger.Debug("WASM module compiled and cached", zap.String("module_hash", hash))
+	}
+
+	return module, hash, nil
+}
+
+// evictLocked drops the least recently used modules over the limit. Callers
+// must hold c.mu.
+func (c *ModuleCache) evictLocked() {
+	for elem := c.lru.Back(); elem != nil && c.lru.Len() > c.max; {
+		prev := elem.Prev()
+		entry := elem.Value.(*moduleEntry)
+		select {
+		case <-entry.ready:
+			c.lru.Remove(elem)
+			delete(c.entries, entry.hash)
+			c.stats.Evictions++
+		default:
+			// Still loading
+		}
+		elem = prev
+	}
+}
+
+// Contains reports whether a binary's compiled module is cached in memory
+func (c *ModuleCache) Contains(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[hash]
+	return ok
+}
+
+// Stats returns cache counters
+func (c *ModuleCache) Stats() ModuleCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Modules = c.lru.Len()
+	return stats
+}
+
+// LoadFile reads and loads a binary from disk
+func (c *ModuleCache) LoadFile(path string) (*wasmtime.Module, string, error) {
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.Load(binary)
+}
+
+// loadSerialized returns a previously serialized module, or nil. Artifacts
+// from another wasmtime version or engine config fail to deserialize and
+// are recompiled.
+func (c *ModuleCache) loadSerialized(hash string) *wasmtime.Module {
+	if c.dir == "" {
+		return nil
+	}
+	path := c.path(hash)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	module, err := wasmtime.NewModuleDeserializeFile(c.engine, path)
+	if err != nil {
+		c.logger.Warn("discarding unusable compiled module",
+			zap.String("module_hash", hash),
+			zap.Error(err),
+		)
+		os.Remove(path)
+		return nil
+	}
+	return module
+}
+
+func (c *ModuleCache) storeSerialized(hash string, module *wasmtime.Module) {
+	if c.dir == "" {
+		return
+	}
+
+	encoded, err := module.Serialize()
+	if err == nil {
+		err = writeFileAtomic(c.path(hash), encoded)
+	}
+	if err != nil {
+		c.logger.Warn("failed to persist compiled module",
+			zap.String("module_hash", hash),
+			zap.Error(err),
+		)
+	}
+}
+
+func (c *ModuleCache) path(hash string) string {
+	return filepath.Join(c.dir, hash+".cwasm")
+}
+
+// writeFileAtomic writes through a temp file so readers never see a partial artifact
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
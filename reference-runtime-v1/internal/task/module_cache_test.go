@@ -0,0 +1,178 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v14"
+	"go.uber.org/zap"
+)
+
+// answerWASM returns a module whose "answer" export returns n
+func answerWASM(t *testing.T, n int) []byte {
+	t.Helper()
+	binary, err := wasmtime.Wat2Wasm(fmt.Sprintf(`(module (func (export "answer") (result i32) i32.const %d))`, n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binary
+}
+
+func newTestModuleCache(t *testing.T, dir string) *ModuleCache {
+	t.Helper()
+	cache, err := NewModuleCache(dir, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewModuleCache failed: %v", err)
+	}
+	return cache
+}
+
+func TestModuleCacheKeysByContentHash(t *testing.T) {
+	cache := newTestModuleCache(t, "")
+	binary := answerWASM(t, 42)
+
+	module, hash, err := cache.Load(binary)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	sum := sha256.Sum256(binary)
+	if hash != hex.EncodeToString(sum[:]) || hash != ModuleHash(binary) {
+		t.Errorf("expected the SHA-256 of the binary, got %s", hash)
+	}
+
+	again, againHash, err := cache.Load(append([]byte(nil), binary...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != module || againHash != hash {
+		t.Error("expected the same binary to reuse the compiled module")
+	}
+
+	// Any change to the binary is a different module
+	_, otherHash, err := cache.Load(answerWASM(t, 43))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherHash == hash {
+		t.Error("expected a different binary to hash differently")
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Modules != 2 {
+		t.Errorf("expected 1 hit, 2 misses and 2 modules, got %+v", stats)
+	}
+
+	// Failures aren't cached
+	if _, _, err := cache.Load([]byte("not wasm")); err == nil {
+		t.Error("expected an invalid binary to fail")
+	}
+	if cache.Contains(ModuleHash([]byte("not wasm"))) {
+		t.Error("expected a failed compile not to be cached")
+	}
+}
+
+func TestModuleCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newTestModuleCache(t, "")
+	cache.SetMaxModules(2)
+	a, b, c := answerWASM(t, 1), answerWASM(t, 2), answerWASM(t, 3)
+
+	for _, binary := range [][]byte{a, b, a, c} {
+		if _, _, err := cache.Load(binary); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !cache.Contains(ModuleHash(a)) || !cache.Contains(ModuleHash(c)) {
+		t.Error("expected the recently used modules to stay cached")
+	}
+	if cache.Contains(ModuleHash(b)) {
+		t.Error("expected the least recently used module evicted")
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 || stats.Modules != 2 {
+		t.Errorf("expected 1 eviction and 2 modules, got %+v", stats)
+	}
+
+	// Lowering the bound evicts at once
+	cache.SetMaxModules(1)
+	if stats := cache.Stats(); stats.Modules != 1 || !cache.Contains(ModuleHash(c)) {
+		t.Errorf("expected only the most recent module left, got %+v", stats)
+	}
+}
+
+func TestModuleCacheConcurrentLoads(t *testing.T) {
+	cache := newTestModuleCache(t, "")
+	binary := answerWASM(t, 42)
+
+	const loaders = 16
+	modules := make([]*wasmtime.Module, loaders)
+	var wg sync.WaitGroup
+	for i := 0; i < loaders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			module, _, err := cache.Load(binary)
+			if err != nil {
+				t.Errorf("Load failed: %v", err)
+			}
+			modules[i] = module
+		}(i)
+	}
+	wg.Wait()
+
+	for _, module := range modules {
+		if module == nil || module != modules[0] {
+			t.Fatal("expected every loader to get the one compiled module")
+		}
+	}
+	if stats := cache.Stats(); stats.Misses != 1 || stats.Hits != loaders-1 {
+		t.Errorf("expected one compile for %d loads, got %+v", loaders, stats)
+	}
+}
+
+func TestModuleCachePersistsCompiledModules(t *testing.T) {
+	dir := t.TempDir()
+	binary := answerWASM(t, 42)
+
+	_, hash, err := newTestModuleCache(t, dir).Load(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact := filepath.Join(dir, hash+".cwasm")
+	serialized, err := os.ReadFile(artifact)
+	if err != nil {
+		t.Fatalf("expected the compiled module persisted: %v", err)
+	}
+
+	// A restarted runtime runs the persisted module
+	path := filepath.Join(t.TempDir(), "agent.wasm")
+	if err := os.WriteFile(path, binary, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	executor, err := NewWASMExecutor(path, newTestModuleCache(t, dir), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewWASMExecutor failed: %v", err)
+	}
+	if executor.ModuleHash() != hash {
+		t.Errorf("expected module hash %s, got %s", hash, executor.ModuleHash())
+	}
+	if result, err := executor.Execute(context.Background(), &TaskInput{Function: "answer"}); err != nil || result != int32(42) {
+		t.Errorf("expected 42, got %v, %v", result, err)
+	}
+
+	// An unusable artifact is discarded and recompiled
+	if err := os.WriteFile(artifact, []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := newTestModuleCache(t, dir).Load(binary); err != nil {
+		t.Fatalf("Load with a corrupt artifact failed: %v", err)
+	}
+	if rewritten, err := os.ReadFile(artifact); err != nil || !bytes.Equal(rewritten, serialized) {
+		t.Errorf("expected the artifact rewritten, got %d bytes, %v", len(rewritten), err)
+	}
+}
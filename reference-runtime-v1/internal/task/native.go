@@ -0,0 +1,257 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ariv1 "github.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxOutputBytes caps what a native task may write to stdout
+	defaultMaxOutputBytes = 16 << 20
+
+	// maxStderrBytes is how much of a native task's stderr is kept for errors
+	maxStderrBytes = 64 << 10
+)
+
+// NativeConfig declares the local command a NativeExecutor runs for each
+// task, and the sandbox it runs in
+type NativeConfig struct {
+	Command []string // Executable and arguments, e.g. ["python3", "agent.py"]
+	Dir     string   // Working directory; a fresh temporary directory per task when empty
+	Env     []string // The command's entire environment
+
+	// Network gives the command the runtime's network. By default it runs in
+	// a new network namespace with nothing but a loopback device.
+	Network bool
+
+	// CgroupParent is a cgroup v2 directory delegated to the runtime, under
+	// which each task gets its own cgroup. Empty uses the runtime's own
+	// cgroup. Without cgroup v2 or delegation only rlimits apply.
+	CgroupParent string
+
+	Limits NativeLimits
+}
+
+// NativeLimits bounds the resources of a native task. Zero means unlimited.
+type NativeLimits struct {
+	MemoryMB       int64  `json:"memory_mb"`        // RLIMIT_DATA, and memory.max in a cgroup
+	CPUSeconds     uint64 `json:"cpu_seconds"`      // RLIMIT_CPU
+	MaxProcesses   int64  `json:"max_processes"`    // RLIMIT_NPROC, and pids.max in a cgroup
+	MaxOpenFiles   uint64 `json:"max_open_files"`   // RLIMIT_NOFILE
+	MaxFileSizeMB  uint64 `json:"max_file_size_mb"` // RLIMIT_FSIZE
+	MaxOutputBytes int    `json:"max_output_bytes"` // Stdout; 16MiB by default
+}
+
+// nativeUsage is what a native task consumed
+type nativeUsage struct {
+	Duration   time.Duration
+	CPUTime    time.Duration // User plus system time of the command and its children
+	MaxRSSKB   int64         // Peak resident set size of the largest process
+	PeakMemory int64         // Peak memory of the task's cgroup; 0 without one
+	ExitCode   int
+}
+
+// taskUsage converts the usage to what the Task service reports
+func (u nativeUsage) taskUsage() *ariv1.TaskUsage {
+	return &ariv1.TaskUsage{
+		CpuMs:           u.CPUTime.Milliseconds(),
+		MaxRssBytes:     u.MaxRSSKB * 1024,
+		PeakMemoryBytes: u.PeakMemory,
+	}
+}
+
+// NativeExecutor runs tasks as a local command, for agents that can't be
+// compiled to WASM. It keeps the WASM contract: the command reads the task
+// input ({"function": ..., "args": [...]}) as JSON on stdin and writes its
+// result as JSON on stdout. Each run is sandboxed with rlimits, a seccomp
+// filter, a network namespace and, where available, a cgroup v2.
+type NativeExecutor struct {
+	config NativeConfig
+	path   string // Resolved executable
+	hash   string
+	logger *zap.Logger
+}
+
+// NewNativeExecutor creates a native executor for the configured command
+func NewNativeExecutor(config NativeConfig, logger *zap.Logger) (*NativeExecutor, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("native runtime needs a command")
+	}
+
+	path, err := exec.LookPath(config.Command[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to find command: %w", err)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, fmt.Errorf("failed to resolve command: %w", err)
+	}
+
+	hash, err := commandHash(path, config.Command[1:], config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash command: %w", err)
+	}
+
+	logger.Info("Native command loaded successfully",
+		zap.Strings("command", config.Command),
+		zap.String("module_hash", hash),
+		zap.Bool("network", config.Network),
+	)
+
+	return &NativeExecutor{
+		config: config,
+		path:   path,
+		hash:   hash,
+		logger: logger,
+	}, nil
+}
+
+// commandHash returns the content hash of a command: its executable and any
+// files named by its arguments, such as the script an interpreter runs
+func commandHash(path string, args []string, dir string) (string, error) {
+	h := sha256.New()
+	files := []string{path}
+	for _, arg := range args {
+		if !filepath.IsAbs(arg) && dir != "" {
+			arg = filepath.Join(dir, arg)
+		}
+		if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+			files = append(files, arg)
+		}
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ModuleHash returns the content hash of the command
+func (e *NativeExecutor) ModuleHash() string {
+	return e.hash
+}
+
+// Execute runs the command for one task call. ctx being done kills the
+// command and everything it started.
+func (e *NativeExecutor) Execute(ctx context.Context, input *TaskInput) (interface{}, error) {
+	result, _, err := e.ExecuteWithUsage(ctx, input)
+	return result, err
+}
+
+// ExecuteWithUsage runs the command like Execute and also reports what it
+// consumed. Usage is nil only when the command never started.
+func (e *NativeExecutor) ExecuteWithUsage(ctx context.Context, input *TaskInput) (interface{}, *ariv1.TaskUsage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode input: %w", err)
+	}
+
+	dir := e.config.Dir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "native-task-"); err != nil {
+			return nil, nil, fmt.Errorf("failed to create task directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	cmd, sandbox, err := e.sandboxedCommand(ctx, dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sandbox command: %w", err)
+	}
+	defer sandbox.release()
+
+	maxOutput := e.config.Limits.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutputBytes
+	}
+	stdout := &cappedBuffer{limit: maxOutput}
+	stderr := &cappedBuffer{limit: maxStderrBytes}
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	runErr := cmd.Run()
+	usage := sandbox.usage(cmd.ProcessState, time.Since(start))
+
+	e.logger.Debug("Native command executed",
+		zap.String("function", input.Function),
+		zap.Duration("duration", usage.Duration),
+		zap.Duration("cpu_time", usage.CPUTime),
+		zap.Int64("max_rss_kb", usage.MaxRSSKB),
+		zap.Int64("peak_memory", usage.PeakMemory),
+		zap.Int("exit_code", usage.ExitCode),
+	)
+
+	consumed := usage.taskUsage()
+	if err := ctx.Err(); err != nil {
+		return nil, consumed, err
+	}
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			return nil, consumed, fmt.Errorf("command failed: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, consumed, fmt.Errorf("failed to run command: %w", runErr)
+	}
+	if stdout.truncated {
+		return nil, consumed, fmt.Errorf("command output exceeds %d bytes", maxOutput)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, consumed, fmt.Errorf("command wrote invalid JSON: %w", err)
+	}
+	return result, consumed, nil
+}
+
+// Close closes the native executor
+func (e *NativeExecutor) Close() error {
+	return nil
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,27 @@
+package task
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain lets the test binary stand in for the runtime: NativeExecutor
+// re-executes its own binary as the sandbox shim
+func TestMain(m *testing.M) {
+	if IsSandboxShim() {
+		RunSandboxShim()
+	}
+	os.Exit(m.Run())
+}
+
+func TestCappedBufferDropsOverflow(t *testing.T) {
+	buf := &cappedBuffer{limit: 4}
+	for _, chunk := range []string{"ab", "cde", "f"} {
+		if n, err := buf.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if buf.String() != "abcd" || !buf.truncated {
+		t.Errorf("expected \"abcd\" and truncated, got %q, %v", buf.String(), buf.truncated)
+	}
+}
@@ -0,0 +1,328 @@
+//go:build linux
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the sandbox spec from the runtime to the shim it
+// re-executes itself as
+const sandboxEnv = "ARI_NATIVE_SANDBOX"
+
+// sandboxSpec is what the shim applies to itself before exec'ing the command.
+// rlimits and seccomp filters are inherited across exec, and neither can be
+// set on a child from outside, so the runtime starts itself as a shim in the
+// child's place.
+type sandboxSpec struct {
+	Path   string       `json:"path"`
+	Args   []string     `json:"args"`
+	Limits NativeLimits `json:"limits"`
+}
+
+// nativeSandbox is the per-run state of a sandboxed command
+type nativeSandbox struct {
+	cgroup *taskCgroup // nil without cgroup v2
+}
+
+// sandboxedCommand returns the command for one run, started through the
+// sandbox shim in its own process group and network namespace, and in a
+// cgroup of its own when cgroup v2 is available
+func (e *NativeExecutor) sandboxedCommand(ctx context.Context, dir string) (*exec.Cmd, *nativeSandbox, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find runtime executable: %w", err)
+	}
+	spec, err := json.Marshal(sandboxSpec{Path: e.path, Args: e.config.Command, Limits: e.config.Limits})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, self)
+	cmd.Args = []string{"ari-native-sandbox"}
+	cmd.Dir = dir
+	cmd.Env = append(append([]string{}, e.config.Env...), sandboxEnv+"="+string(spec))
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if !e.config.Network {
+		cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWNET
+		if uid, gid := os.Geteuid(), os.Getegid(); uid != 0 {
+			// Unprivileged, the network namespace needs a user namespace
+			cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+			cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+			cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		}
+	}
+
+	sandbox := &nativeSandbox{}
+	if cg, err := newTaskCgroup(e.config.CgroupParent, e.config.Limits); err != nil {
+		e.logger.Debug("Running native task without a cgroup", zap.Error(err))
+	} else {
+		sandbox.cgroup = cg
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = cg.fd
+	}
+
+	// Kill everything the command started, not just the shim's process
+	cmd.Cancel = func() error {
+		if sandbox.cgroup != nil {
+			sandbox.cgroup.kill()
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	return cmd, sandbox, nil
+}
+
+// usage reports what a finished run consumed
+func (s *nativeSandbox) usage(state *os.ProcessState, duration time.Duration) nativeUsage {
+	usage := nativeUsage{Duration: duration, ExitCode: -1}
+	if state != nil {
+		usage.ExitCode = state.ExitCode()
+		usage.CPUTime = state.UserTime() + state.SystemTime()
+		if ru, ok := state.SysUsage().(*syscall.Rusage); ok {
+			usage.MaxRSSKB = ru.Maxrss
+		}
+	}
+	if s.cgroup != nil {
+		usage.PeakMemory = s.cgroup.peakMemory()
+	}
+	return usage
+}
+
+// release kills anything left of the run and removes its cgroup
+func (s *nativeSandbox) release() {
+	if s.cgroup != nil {
+		s.cgroup.remove()
+	}
+}
+
+// IsSandboxShim reports whether this process was started by a
+// NativeExecutor to sandbox a task command. A runtime hosting a
+// NativeExecutor must check it first thing in main and call RunSandboxShim.
+func IsSandboxShim() bool {
+	return os.Getenv(sandboxEnv) != ""
+}
+
+// RunSandboxShim applies the sandbox the runtime asked for to this process,
+// then replaces it with the task command. It never returns; failing to
+// sandbox the command exits with status 126 without running it.
+func RunSandboxShim() {
+	// no_new_privs and the seccomp filter are per thread; exec must happen
+	// on the thread that has them
+	runtime.LockOSThread()
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxEnv)), &spec); err != nil {
+		shimFail("invalid sandbox spec", err)
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, sandboxEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	if err := applyRlimits(spec.Limits); err != nil {
+		shimFail("failed to set rlimits", err)
+	}
+	if err := installSeccompFilter(); err != nil {
+		shimFail("failed to install seccomp filter", err)
+	}
+
+	err := syscall.Exec(spec.Path, spec.Args, env)
+	shimFail("failed to exec command", err)
+}
+
+func shimFail(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "native sandbox: %s: %v\n", msg, err)
+	os.Exit(126)
+}
+
+// applyRlimits sets the task's limits on this process for the command to
+// inherit. syscall.Setrlimit is used so the runtime doesn't restore its own
+// RLIMIT_NOFILE on exec.
+func applyRlimits(limits NativeLimits) error {
+	set := func(resource int, value uint64) error {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("resource %d: %w", resource, err)
+		}
+		return nil
+	}
+
+	if err := set(unix.RLIMIT_CORE, 0); err != nil {
+		return err
+	}
+	if limits.MemoryMB > 0 {
+		// RLIMIT_DATA counts heap and private mappings but not reserved
+		// address space, which RLIMIT_AS would and many runtimes need
+		if err := set(unix.RLIMIT_DATA, uint64(limits.MemoryMB)<<20); err != nil {
+			return err
+		}
+	}
+	if limits.CPUSeconds > 0 {
+		if err := set(unix.RLIMIT_CPU, limits.CPUSeconds); err != nil {
+			return err
+		}
+	}
+	if limits.MaxProcesses > 0 {
+		if err := set(unix.RLIMIT_NPROC, uint64(limits.MaxProcesses)); err != nil {
+			return err
+		}
+	}
+	if limits.MaxOpenFiles > 0 {
+		if err := set(unix.RLIMIT_NOFILE, limits.MaxOpenFiles); err != nil {
+			return err
+		}
+	}
+	if limits.MaxFileSizeMB > 0 {
+		if err := set(unix.RLIMIT_FSIZE, limits.MaxFileSizeMB<<20); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditArches are the architectures the seccomp filter is written for
+var auditArches = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+// deniedSyscalls fail with EPERM under the seccomp filter: they administer
+// the host, escape or reshape the sandbox, or inspect other processes
+var deniedSyscalls = []uint32{
+	unix.SYS_ACCT,
+	unix.SYS_ADD_KEY,
+	unix.SYS_ADJTIMEX,
+	unix.SYS_BPF,
+	unix.SYS_CHROOT,
+	unix.SYS_CLOCK_SETTIME,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_FSMOUNT,
+	unix.SYS_FSOPEN,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_KEXEC_FILE_LOAD,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_KEYCTL,
+	unix.SYS_MOUNT,
+	unix.SYS_MOUNT_SETATTR,
+	unix.SYS_MOVE_MOUNT,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_OPEN_TREE,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_PTRACE,
+	unix.SYS_QUOTACTL,
+	unix.SYS_REBOOT,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_SETDOMAINNAME,
+	unix.SYS_SETHOSTNAME,
+	unix.SYS_SETNS,
+	unix.SYS_SETTIMEOFDAY,
+	unix.SYS_SWAPOFF,
+	unix.SYS_SWAPON,
+	unix.SYS_UMOUNT2,
+	unix.SYS_UNSHARE,
+	unix.SYS_USERFAULTFD,
+}
+
+// namespaceCloneFlags are the clone flags that create namespaces
+const namespaceCloneFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+	unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP | unix.CLONE_NEWTIME
+
+// seccompFilter builds the BPF program of the sandbox. It kills a process
+// making syscalls for another architecture, denies deniedSyscalls and
+// namespace-creating clones, and allows everything else. clone3 fails with
+// ENOSYS since its flags can't be inspected; libc falls back to clone.
+func seccompFilter() ([]unix.SockFilter, error) {
+	arch, ok := auditArches[runtime.GOARCH]
+	if !ok {
+		return nil, fmt.Errorf("no seccomp filter for %s", runtime.GOARCH)
+	}
+
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	const (
+		loadWord = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jumpEq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		ret      = unix.BPF_RET | unix.BPF_K
+
+		// Offsets into struct seccomp_data
+		offNr    = 0
+		offArch  = 4
+		offArg0  = 16 // Low 32 bits on little-endian arches
+		x32Flag  = 0x40000000
+		denyPerm = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+		denyNone = unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS)
+	)
+
+	filter := []unix.SockFilter{
+		stmt(loadWord, offArch),
+		jump(jumpEq, arch, 1, 0),
+		stmt(ret, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(loadWord, offNr),
+	}
+	if runtime.GOARCH == "amd64" {
+		// x32 syscall numbers would slip past the checks below
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32Flag, 0, 1),
+			stmt(ret, denyPerm),
+		)
+	}
+	filter = append(filter,
+		jump(jumpEq, unix.SYS_CLONE3, 0, 1),
+		stmt(ret, denyNone),
+		jump(jumpEq, unix.SYS_CLONE, 0, 4),
+		stmt(loadWord, offArg0),
+		jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, namespaceCloneFlags, 0, 1),
+		stmt(ret, denyPerm),
+		stmt(ret, unix.SECCOMP_RET_ALLOW),
+	)
+	for _, nr := range deniedSyscalls {
+		filter = append(filter,
+			jump(jumpEq, nr, 0, 1),
+			stmt(ret, denyPerm),
+		)
+	}
+	filter = append(filter, stmt(ret, unix.SECCOMP_RET_ALLOW))
+	return filter, nil
+}
+
+// installSeccompFilter confines the calling thread, and what it execs, to
+// the sandbox's seccomp filter
+func installSeccompFilter() error {
+	filter, err := seccompFilter()
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := unix.Syscall(unix.SYS_PRCTL, unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return errno
+	}
+	return nil
+}
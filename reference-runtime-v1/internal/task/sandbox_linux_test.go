@@ -0,0 +1,201 @@
+//go:build linux
+
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runShell runs script with sh in the sandbox and returns what it printed
+func runShell(t *testing.T, ctx context.Context, config NativeConfig, script string) (interface{}, error) {
+	t.Helper()
+	config.Command = []string{"sh", "-c", script}
+	executor, err := NewNativeExecutor(config, nil)
+	if err != nil {
+		t.Fatalf("NewNativeExecutor failed: %v", err)
+	}
+	return executor.Execute(ctx, &TaskInput{Function: "run"})
+}
+
+func TestNativeExecutorJSONContract(t *testing.T) {
+	// The script answers with the input it was given
+	result, err := runShell(t, context.Background(), NativeConfig{Network: true}, `read -r input; echo "{\"echo\": $input}"`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	echo, _ := result.(map[string]interface{})["echo"].(map[string]interface{})
+	if echo["function"] != "run" {
+		t.Errorf("expected the task input echoed back, got %v", result)
+	}
+
+	if _, err := runShell(t, context.Background(), NativeConfig{Network: true}, `echo not json`); err == nil {
+		t.Error("expected invalid output to fail")
+	}
+	if _, err := runShell(t, context.Background(), NativeConfig{Network: true}, `echo oops >&2; exit 3`); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("expected a failed command to report its stderr, got %v", err)
+	}
+}
+
+func TestNativeExecutorScrubsEnvironment(t *testing.T) {
+	t.Setenv("ARI_TEST_SECRET", "leaked")
+	config := NativeConfig{Env: []string{"ONLY=1"}, Network: true}
+
+	result, err := runShell(t, context.Background(), config,
+		`echo "{\"secret\": \"${ARI_TEST_SECRET-}\", \"sandbox\": \"${ARI_NATIVE_SANDBOX-}\", \"only\": \"${ONLY-}\"}"`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	env := result.(map[string]interface{})
+	if env["secret"] != "" {
+		t.Error("expected the runtime's environment not to reach the command")
+	}
+	if env["sandbox"] != "" {
+		t.Error("expected the shim to drop the sandbox spec before exec")
+	}
+	if env["only"] != "1" {
+		t.Errorf("expected the configured environment, got %v", env)
+	}
+}
+
+func TestNativeExecutorReportsUsage(t *testing.T) {
+	config := NativeConfig{Command: []string{"sh", "-c", `i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done; echo "$i"`}, Network: true}
+	executor, err := NewNativeExecutor(config, nil)
+	if err != nil {
+		t.Fatalf("NewNativeExecutor failed: %v", err)
+	}
+
+	result, usage, err := executor.ExecuteWithUsage(context.Background(), &TaskInput{Function: "run"})
+	if err != nil {
+		t.Fatalf("ExecuteWithUsage failed: %v", err)
+	}
+	if result != float64(20000) {
+		t.Errorf("expected 20000, got %v", result)
+	}
+	if usage == nil || usage.MaxRssBytes <= 0 || usage.CpuMs < 0 {
+		t.Errorf("expected the command's usage, got %v", usage)
+	}
+
+	// A failed command still used resources
+	config.Command = []string{"sh", "-c", "exit 3"}
+	if executor, err = NewNativeExecutor(config, nil); err != nil {
+		t.Fatalf("NewNativeExecutor failed: %v", err)
+	}
+	if _, usage, err = executor.ExecuteWithUsage(context.Background(), &TaskInput{Function: "run"}); err == nil || usage == nil {
+		t.Errorf("expected an error with usage, got %v, %v", usage, err)
+	}
+}
+
+func TestNativeExecutorKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	config := NativeConfig{Dir: dir, Env: []string{"PATH=" + os.Getenv("PATH")}, Network: true}
+
+	for name, cancel := range map[string]func(context.Context) (context.Context, context.CancelFunc){
+		"timeout": func(ctx context.Context) (context.Context, context.CancelFunc) {
+			return context.WithTimeout(ctx, 500*time.Millisecond)
+		},
+		"cancel": func(ctx context.Context) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(ctx)
+			time.AfterFunc(500*time.Millisecond, cancel)
+			return ctx, cancel
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pidFile := filepath.Join(dir, name+".pid")
+			ctx, stop := cancel(context.Background())
+			defer stop()
+
+			// The background sleep outlives sh unless its whole group is killed
+			_, err := runShell(t, ctx, config, `sleep 30 & echo $! > `+pidFile+`; wait`)
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected the context's error, got %v", err)
+			}
+
+			data, err := os.ReadFile(pidFile)
+			if err != nil {
+				t.Fatalf("command didn't record its child: %v", err)
+			}
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for deadline := time.Now().Add(5 * time.Second); processAlive(pid); time.Sleep(20 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatalf("child %d survived its task", pid)
+				}
+			}
+		})
+	}
+}
+
+// processAlive reports whether pid is running; a zombie waiting to be
+// reaped by init counts as dead
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestNativeExecutorNetworkOff(t *testing.T) {
+	config := NativeConfig{Env: []string{"PATH=" + os.Getenv("PATH")}}
+	// /proc/net lists the devices of the reader's network namespace
+	script := `echo "\"$(tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' ' | tr '\n' ' ')\""`
+
+	result, err := runShell(t, context.Background(), config, script)
+	if err != nil {
+		if strings.Contains(err.Error(), "operation not permitted") {
+			t.Skipf("no network namespaces here: %v", err)
+		}
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if devices := strings.Fields(result.(string)); len(devices) != 1 || devices[0] != "lo" {
+		t.Errorf("expected only a loopback device, got %v", devices)
+	}
+}
+
+func TestTaskCgroupAppliesLimits(t *testing.T) {
+	cg, err := newTaskCgroup("", NativeLimits{MemoryMB: 64, MaxProcesses: 16})
+	if err != nil {
+		t.Skipf("no delegated cgroup v2 here: %v", err)
+	}
+	dir := cg.dir
+	defer cg.remove()
+
+	for file, want := range map[string]string{
+		"memory.max": strconv.Itoa(64 << 20),
+		"pids.max":   "16",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(data)); got != want {
+			t.Errorf("%s: expected %s, got %s", file, want, got)
+		}
+	}
+
+	// Each task runs in a cgroup of its own under the parent
+	parent := filepath.Dir(dir)
+	config := NativeConfig{CgroupParent: parent, Env: []string{"PATH=" + os.Getenv("PATH")}, Network: true}
+	result, err := runShell(t, context.Background(), config, `echo "\"$(grep '^0::' /proc/self/cgroup | cut -d: -f3)\""`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if own := filepath.Join(cgroupRoot, result.(string)); !strings.HasPrefix(own, filepath.Join(parent, "ari-task-")) {
+		t.Errorf("expected the task in a cgroup under %s, got %s", parent, own)
+	}
+
+	cg.remove()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the cgroup removed, got %v", err)
+	}
+}
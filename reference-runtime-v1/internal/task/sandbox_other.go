@@ -0,0 +1,36 @@
+//go:build !linux
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// nativeSandbox is the per-run state of a sandboxed command
+type nativeSandbox struct{}
+
+// sandboxedCommand fails: the sandbox is built on Linux namespaces, rlimits
+// and seccomp, and native tasks don't run without it
+func (e *NativeExecutor) sandboxedCommand(ctx context.Context, dir string) (*exec.Cmd, *nativeSandbox, error) {
+	return nil, nil, fmt.Errorf("native tasks are not supported on %s", runtime.GOOS)
+}
+
+func (s *nativeSandbox) usage(state *os.ProcessState, duration time.Duration) nativeUsage {
+	return nativeUsage{Duration: duration}
+}
+
+func (s *nativeSandbox) release() {}
+
+// IsSandboxShim reports whether this process was started by a
+// NativeExecutor to sandbox a task command, which it never is here
+func IsSandboxShim() bool {
+	return false
+}
+
+// RunSandboxShim does nothing; there is no sandbox shim on this platform
+func RunSandboxShim() {}
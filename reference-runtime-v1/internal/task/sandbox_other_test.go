@@ -0,0 +1,23 @@
+//go:build !linux
+
+package task
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNativeExecutorRefusesWithoutSandbox(t *testing.T) {
+	executor, err := NewNativeExecutor(NativeConfig{Command: []string{os.Args[0]}}, nil)
+	if err != nil {
+		t.Fatalf("NewNativeExecutor failed: %v", err)
+	}
+	if _, err := executor.Execute(context.Background(), &TaskInput{Function: "run"}); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected native tasks to be refused, got %v", err)
+	}
+	if IsSandboxShim() {
+		t.Error("expected no sandbox shim")
+	}
+}
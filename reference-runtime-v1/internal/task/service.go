@@ -12,34 +12,66 @@ import (
 	"go.uber.org/zap"
 )
 
+// Executor runs the calls tasks make. WASMExecutor calls into a WASM module
+// and NativeExecutor runs a local command.
+type Executor interface {
+	Execute(ctx context.Context, input *TaskInput) (interface{}, error)
+	ModuleHash() string
+	Close() error
+}
+
+// UsageExecutor is an Executor that also measures what each call consumed.
+// The Task service reports the usage with the task's final response.
+type UsageExecutor interface {
+	Executor
+	ExecuteWithUsage(ctx context.Context, input *TaskInput) (interface{}, *ariv1.TaskUsage, error)
+}
+
 // Service implements the ARI v1 Task service
 type Service struct {
 	ariv1.UnimplementedTaskServer
 
-	executor *WASMExecutor
+	executor Executor
 	logger   *zap.Logger
 
 	// Task tracking
 	mu          sync.RWMutex
-	activeTasks map[string]context.CancelFunc
+	activeTasks map[string]*activeTask
+}
+
+// activeTask is a running task as seen by Cancel
+type activeTask struct {
+	cancel   context.CancelFunc
+	started  time.Time
+	progress float32 // Last progress sent on the stream
 }
 
-// NewService creates a new Task service
-func NewService(wasmPath string, logger *zap.Logger) (*Service, error) {
+// NewService creates a new Task service. cache may be nil, in which case
+// the module is compiled into a cache private to this service.
+func NewService(wasmPath string, cache *ModuleCache, logger *zap.Logger) (*Service, error) {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 
-	executor, err := NewWASMExecutor(wasmPath, logger)
+	executor, err := NewWASMExecutor(wasmPath, cache, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create WASM executor: %w", err)
 	}
 
+	return NewServiceWithExecutor(executor, logger), nil
+}
+
+// NewServiceWithExecutor creates a Task service running tasks on executor
+func NewServiceWithExecutor(executor Executor, logger *zap.Logger) *Service {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	return &Service{
 		executor:    executor,
 		logger:      logger,
-		activeTasks: make(map[string]context.CancelFunc),
-	}, nil
+		activeTasks: make(map[string]*activeTask),
+	}
 }
 
 // Execute executes a task and streams the response
@@ -57,7 +89,7 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 	defer cancel()
 
 	s.mu.Lock()
-	s.activeTasks[req.TaskId] = cancel
+	s.activeTasks[req.TaskId] = &activeTask{cancel: cancel, started: startTime}
 	s.mu.Unlock()
 
 	defer func() {
@@ -92,10 +124,40 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 		})
 	}
 
+	// Report the call before it runs; long calls would otherwise look stalled
+	s.setProgress(req.TaskId, 0.1)
+	if err := stream.Send(&ariv1.TaskExecuteResponse{
+		TaskId:          req.TaskId,
+		Status:          ariv1.TaskStatus_TASK_STATUS_RUNNING,
+		Progress:        0.1,
+		ProgressMessage: fmt.Sprintf("Calling %s", input.Function),
+		Logs:            []string{fmt.Sprintf("calling %s with %d args", input.Function, len(input.Args))},
+	}); err != nil {
+		return err
+	}
+
 	// Execute the task
-	result, err := s.executor.Execute(taskCtx, &input)
+	result, usage, err := s.run(taskCtx, &input)
 	executionTime := time.Since(startTime)
 
+	// Canceled through Cancel rather than by the caller going away
+	if taskCtx.Err() != nil && ctx.Err() == nil {
+		s.logger.Info("Task execution canceled",
+			zap.String("task_id", req.TaskId),
+			zap.Duration("execution_time", executionTime),
+		)
+
+		return stream.Send(&ariv1.TaskExecuteResponse{
+			TaskId:          req.TaskId,
+			Status:          ariv1.TaskStatus_TASK_STATUS_CANCELED,
+			Error:           "task canceled",
+			ExecutionMs:     executionTime.Milliseconds(),
+			Progress:        s.progressOf(req.TaskId),
+			ProgressMessage: "Task canceled",
+			Usage:           usage,
+		})
+	}
+
 	if err != nil {
 		s.logger.Error("Task execution failed",
 			zap.String("task_id", req.TaskId),
@@ -108,6 +170,7 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 			Status:      ariv1.TaskStatus_TASK_STATUS_FAILED,
 			Error:       err.Error(),
 			ExecutionMs: executionTime.Milliseconds(),
+			Usage:       usage,
 		})
 	}
 
@@ -119,6 +182,7 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 			Status:      ariv1.TaskStatus_TASK_STATUS_FAILED,
 			Error:       fmt.Sprintf("Failed to marshal result: %v", err),
 			ExecutionMs: executionTime.Milliseconds(),
+			Usage:       usage,
 		})
 	}
 
@@ -126,6 +190,9 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 	s.logger.Info("Task execution completed",
 		zap.String("task_id", req.TaskId),
 		zap.Duration("execution_time", executionTime),
+		zap.Int64("cpu_ms", usage.GetCpuMs()),
+		zap.Int64("max_rss_bytes", usage.GetMaxRssBytes()),
+		zap.Int64("peak_memory_bytes", usage.GetPeakMemoryBytes()),
 	)
 
 	return stream.Send(&ariv1.TaskExecuteResponse{
@@ -135,22 +202,81 @@ func (s *Service) Execute(req *ariv1.TaskExecuteRequest, stream ariv1.Task_Execu
 		ExecutionMs:     executionTime.Milliseconds(),
 		Progress:        1.0,
 		ProgressMessage: "Task completed successfully",
+		Usage:           usage,
 	})
 }
 
+// run executes a task call, with its usage when the executor measures it
+func (s *Service) run(ctx context.Context, input *TaskInput) (interface{}, *ariv1.TaskUsage, error) {
+	if executor, ok := s.executor.(UsageExecutor); ok {
+		return executor.ExecuteWithUsage(ctx, input)
+	}
+	result, err := s.executor.Execute(ctx, input)
+	return result, nil, err
+}
+
+// Cancel stops a running task. The task's Execute stream ends with status
+// CANCELED; the response reports how far it got.
+func (s *Service) Cancel(ctx context.Context, req *ariv1.TaskCancelRequest) (*ariv1.TaskCancelResponse, error) {
+	s.mu.RLock()
+	task, exists := s.activeTasks[req.TaskId]
+	var resp *ariv1.TaskCancelResponse
+	if exists {
+		resp = &ariv1.TaskCancelResponse{
+			TaskId:      req.TaskId,
+			Canceled:    true,
+			Progress:    task.progress,
+			ExecutionMs: time.Since(task.started).Milliseconds(),
+		}
+	}
+	s.mu.RUnlock()
+
+	if !exists {
+		return &ariv1.TaskCancelResponse{TaskId: req.TaskId}, nil
+	}
+
+	task.cancel()
+	s.logger.Info("Task cancelled",
+		zap.String("task_id", req.TaskId),
+		zap.String("reason", req.Reason),
+	)
+	return resp, nil
+}
+
 // CancelTask cancels a running task
 func (s *Service) CancelTask(taskID string) error {
+	resp, err := s.Cancel(context.Background(), &ariv1.TaskCancelRequest{TaskId: taskID})
+	if err != nil {
+		return err
+	}
+	if !resp.Canceled {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	return nil
+}
+
+// setProgress records the progress last sent for a task
+func (s *Service) setProgress(taskID string, progress float32) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if task, ok := s.activeTasks[taskID]; ok {
+		task.progress = progress
+	}
+}
 
-	cancel, exists := s.activeTasks[taskID]
-	if !exists {
-		return fmt.Errorf("task not found: %s", taskID)
+// progressOf returns the progress last sent for a task
+func (s *Service) progressOf(taskID string) float32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if task, ok := s.activeTasks[taskID]; ok {
+		return task.progress
 	}
+	return 0
+}
 
-	cancel()
-	s.logger.Info("Task cancelled", zap.String("task_id", taskID))
-	return nil
+// ModuleHash returns the content hash of the code the service runs
+func (s *Service) ModuleHash() string {
+	return s.executor.ModuleHash()
 }
 
 // TaskInput represents the input to a task
@@ -163,39 +289,63 @@ type TaskInput struct {
 type WASMExecutor struct {
 	engine *wasmtime.Engine
 	module *wasmtime.Module
+	hash   string
+	linker *wasmtime.Linker // WASI imports, shared by every store
 	logger *zap.Logger
 }
 
-// NewWASMExecutor creates a new WASM executor
-func NewWASMExecutor(wasmPath string, logger *zap.Logger) (*WASMExecutor, error) {
-	engine := wasmtime.NewEngine()
+// NewWASMExecutor creates a new WASM executor, reusing cached compiled code
+func NewWASMExecutor(wasmPath string, cache *ModuleCache, logger *zap.Logger) (*WASMExecutor, error) {
+	if cache == nil {
+		var err error
+		if cache, err = NewModuleCache("", logger); err != nil {
+			return nil, err
+		}
+	}
 
-	module, err := wasmtime.NewModuleFromFile(engine, wasmPath)
+	module, hash, err := cache.LoadFile(wasmPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load WASM module: %w", err)
 	}
 
-	logger.Info("WASM module loaded successfully", zap.String("path", wasmPath))
+	// Define WASI once; the linker is reused across stores
+	linker := wasmtime.NewLinker(cache.Engine())
+	if err := linker.DefineWasi(); err != nil {
+		return nil, fmt.Errorf("failed to define WASI: %w", err)
+	}
+
+	logger.Info("WASM module loaded successfully",
+		zap.String("path", wasmPath),
+		zap.String("module_hash", hash),
+	)
 
 	return &WASMExecutor{
-		engine: engine,
+		engine: cache.Engine(),
 		module: module,
+		hash:   hash,
+		linker: linker,
 		logger: logger,
 	}, nil
 }
 
-// Execute executes a function in the WASM module
-func (e *WASMExecutor) Execute(ctx context.Context, input *TaskInput) (interface{}, error) {
-	store := wasmtime.NewStore(e.engine)
-	linker := wasmtime.NewLinker(e.engine)
+// ModuleHash returns the content hash of the loaded module
+func (e *WASMExecutor) ModuleHash() string {
+	return e.hash
+}
 
-	// Define WASI (if needed)
-	if err := linker.DefineWasi(); err != nil {
-		return nil, fmt.Errorf("failed to define WASI: %w", err)
+// Execute executes a function in the WASM module. It returns ctx's error as
+// soon as ctx is done; epoch interruption is engine-wide in wasmtime, so a
+// call already running is abandoned to finish on its own store.
+func (e *WASMExecutor) Execute(ctx context.Context, input *TaskInput) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
+	store := wasmtime.NewStore(e.engine)
+	store.SetWasi(wasmtime.NewWasiConfig())
+
 	// Instantiate the module
-	instance, err := linker.Instantiate(store, e.module)
+	instance, err := e.linker.Instantiate(store, e.module)
 	if err != nil {
 		return nil, fmt.Errorf("failed to instantiate module: %w", err)
 	}
@@ -222,9 +372,25 @@ func (e *WASMExecutor) Execute(ctx context.Context, input *TaskInput) (interface
 	}
 
 	// Call the function
-	result, err := fn.Call(store, wasmArgs...)
-	if err != nil {
-		return nil, fmt.Errorf("function call failed: %w", err)
+	type callResult struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		value, err := fn.Call(store, wasmArgs...)
+		done <- callResult{value, err}
+	}()
+
+	var result interface{}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			return nil, fmt.Errorf("function call failed: %w", r.err)
+		}
+		result = r.value
 	}
 
 	e.logger.Debug("WASM function executed",
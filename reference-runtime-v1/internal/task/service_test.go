@@ -0,0 +1,79 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	ariv1 "github.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1"
+	"google.golang.org/grpc"
+)
+
+// recordingStream collects what the Task service sends
+type recordingStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*ariv1.TaskExecuteResponse
+}
+
+func (s *recordingStream) Context() context.Context { return s.ctx }
+
+func (s *recordingStream) Send(resp *ariv1.TaskExecuteResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+// meteredExecutor answers every call and reports fixed usage
+type meteredExecutor struct {
+	err error
+}
+
+func (e *meteredExecutor) Execute(ctx context.Context, input *TaskInput) (interface{}, error) {
+	result, _, err := e.ExecuteWithUsage(ctx, input)
+	return result, err
+}
+
+func (e *meteredExecutor) ExecuteWithUsage(ctx context.Context, input *TaskInput) (interface{}, *ariv1.TaskUsage, error) {
+	usage := &ariv1.TaskUsage{CpuMs: 12, MaxRssBytes: 4096, PeakMemoryBytes: 8192}
+	if e.err != nil {
+		return nil, usage, e.err
+	}
+	return 42, usage, nil
+}
+
+func (e *meteredExecutor) ModuleHash() string { return "metered" }
+func (e *meteredExecutor) Close() error       { return nil }
+
+func TestServiceReportsExecutorUsage(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status ariv1.TaskStatus
+	}{
+		{"completed", nil, ariv1.TaskStatus_TASK_STATUS_COMPLETED},
+		{"failed", context.DeadlineExceeded, ariv1.TaskStatus_TASK_STATUS_FAILED},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewServiceWithExecutor(&meteredExecutor{err: tc.err}, nil)
+			stream := &recordingStream{ctx: context.Background()}
+
+			err := service.Execute(&ariv1.TaskExecuteRequest{TaskId: "task-1", Input: `{"function": "run"}`}, stream)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			final := stream.responses[len(stream.responses)-1]
+			if final.Status != tc.status {
+				t.Fatalf("expected %v, got %v", tc.status, final.Status)
+			}
+			usage := final.GetUsage()
+			if usage.GetCpuMs() != 12 || usage.GetMaxRssBytes() != 4096 || usage.GetPeakMemoryBytes() != 8192 {
+				t.Errorf("expected the executor's usage on the final response, got %v", usage)
+			}
+			for _, resp := range stream.responses[:len(stream.responses)-1] {
+				if resp.Usage != nil {
+					t.Errorf("expected usage only on the final response, got it with %v", resp.Status)
+				}
+			}
+		})
+	}
+}
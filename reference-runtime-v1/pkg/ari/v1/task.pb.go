@@ -30,6 +30,7 @@ const (
 	TaskStatus_TASK_STATUS_RUNNING     TaskStatus = 2
 	TaskStatus_TASK_STATUS_COMPLETED   TaskStatus = 3
 	TaskStatus_TASK_STATUS_FAILED      TaskStatus = 4
+	TaskStatus_TASK_STATUS_CANCELED    TaskStatus = 5
 )
 
 // Enum value maps for TaskStatus.
@@ -40,6 +41,7 @@ var (
 		2: "TASK_STATUS_RUNNING",
 		3: "TASK_STATUS_COMPLETED",
 		4: "TASK_STATUS_FAILED",
+		5: "TASK_STATUS_CANCELED",
 	}
 	TaskStatus_value = map[string]int32{
 		"TASK_STATUS_UNSPECIFIED": 0,
@@ -47,6 +49,7 @@ var (
 		"TASK_STATUS_RUNNING":     2,
 		"TASK_STATUS_COMPLETED":   3,
 		"TASK_STATUS_FAILED":      4,
+		"TASK_STATUS_CANCELED":    5,
 	}
 )
 
@@ -167,8 +170,14 @@ type TaskExecuteResponse struct {
 	Progress float32 `protobuf:"fixed32,6,opt,name=progress,proto3" json:"progress,omitempty"`
 	// Progress message (optional)
 	ProgressMessage string `protobuf:"bytes,7,opt,name=progress_message,json=progressMessage,proto3" json:"progress_message,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// Log lines emitted since the previous response (optional)
+	Logs []string `protobuf:"bytes,8,rep,name=logs,proto3" json:"logs,omitempty"`
+	// Partial result so far (JSON-encoded, optional); superseded by result
+	PartialResult string `protobuf:"bytes,9,opt,name=partial_result,json=partialResult,proto3" json:"partial_result,omitempty"`
+	// Resources the task consumed (final response only, when the executor measures them)
+	Usage         *TaskUsage `protobuf:"bytes,10,opt,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TaskExecuteResponse) Reset() {
@@ -250,6 +259,219 @@ func (x *TaskExecuteResponse) GetProgressMessage() string {
 	return ""
 }
 
+func (x *TaskExecuteResponse) GetLogs() []string {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+func (x *TaskExecuteResponse) GetPartialResult() string {
+	if x != nil {
+		return x.PartialResult
+	}
+	return ""
+}
+
+func (x *TaskExecuteResponse) GetUsage() *TaskUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+// TaskUsage is what a task consumed while it ran
+type TaskUsage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// User plus system CPU time in milliseconds
+	CpuMs int64 `protobuf:"varint,1,opt,name=cpu_ms,json=cpuMs,proto3" json:"cpu_ms,omitempty"`
+	// Peak resident set size of the largest process, in bytes
+	MaxRssBytes int64 `protobuf:"varint,2,opt,name=max_rss_bytes,json=maxRssBytes,proto3" json:"max_rss_bytes,omitempty"`
+	// Peak memory of the task's cgroup in bytes (0 without one)
+	PeakMemoryBytes int64 `protobuf:"varint,3,opt,name=peak_memory_bytes,json=peakMemoryBytes,proto3" json:"peak_memory_bytes,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TaskUsage) Reset() {
+	*x = TaskUsage{}
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskUsage) ProtoMessage() {}
+
+func (x *TaskUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskUsage.ProtoReflect.Descriptor instead.
+func (*TaskUsage) Descriptor() ([]byte, []int) {
+	return file_pkg_ari_v1_task_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TaskUsage) GetCpuMs() int64 {
+	if x != nil {
+		return x.CpuMs
+	}
+	return 0
+}
+
+func (x *TaskUsage) GetMaxRssBytes() int64 {
+	if x != nil {
+		return x.MaxRssBytes
+	}
+	return 0
+}
+
+func (x *TaskUsage) GetPeakMemoryBytes() int64 {
+	if x != nil {
+		return x.PeakMemoryBytes
+	}
+	return 0
+}
+
+// TaskCancelRequest names the task to stop
+type TaskCancelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Task ID (as sent to Execute)
+	TaskId string `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	// Why the task is being canceled (optional)
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskCancelRequest) Reset() {
+	*x = TaskCancelRequest{}
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskCancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskCancelRequest) ProtoMessage() {}
+
+func (x *TaskCancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskCancelRequest.ProtoReflect.Descriptor instead.
+func (*TaskCancelRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_ari_v1_task_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TaskCancelRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskCancelRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// TaskCancelResponse reports how far the task got before it stopped
+type TaskCancelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Task ID (echoed from request)
+	TaskId string `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	// False when the task was not running, e.g. it had already finished
+	Canceled bool `protobuf:"varint,2,opt,name=canceled,proto3" json:"canceled,omitempty"`
+	// Progress when the task stopped (0.0 - 1.0)
+	Progress float32 `protobuf:"fixed32,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	// Execution time in milliseconds until the task stopped
+	ExecutionMs   int64 `protobuf:"varint,4,opt,name=execution_ms,json=executionMs,proto3" json:"execution_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskCancelResponse) Reset() {
+	*x = TaskCancelResponse{}
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskCancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskCancelResponse) ProtoMessage() {}
+
+func (x *TaskCancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_ari_v1_task_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskCancelResponse.ProtoReflect.Descriptor instead.
+func (*TaskCancelResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_ari_v1_task_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskCancelResponse) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskCancelResponse) GetCanceled() bool {
+	if x != nil {
+		return x.Canceled
+	}
+	return false
+}
+
+func (x *TaskCancelResponse) GetProgress() float32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *TaskCancelResponse) GetExecutionMs() int64 {
+	if x != nil {
+		return x.ExecutionMs
+	}
+	return 0
+}
+
 var File_pkg_ari_v1_task_proto protoreflect.FileDescriptor
 
 const file_pkg_ari_v1_task_proto_rawDesc = "" +
@@ -260,7 +482,7 @@ const file_pkg_ari_v1_task_proto_rawDesc = "" +
 	"\x05input\x18\x02 \x01(\tR\x05input\x12\x1d\n" +
 	"\n" +
 	"timeout_ms\x18\x03 \x01(\x05R\ttimeoutMs\x12\"\n" +
-	"\rmax_memory_mb\x18\x04 \x01(\x05R\vmaxMemoryMb\"\xf2\x01\n" +
+	"\rmax_memory_mb\x18\x04 \x01(\x05R\vmaxMemoryMb\"\xd6\x02\n" +
 	"\x13TaskExecuteResponse\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12*\n" +
 	"\x06status\x18\x02 \x01(\x0e2\x12.ari.v1.TaskStatusR\x06status\x12\x16\n" +
@@ -268,16 +490,34 @@ const file_pkg_ari_v1_task_proto_rawDesc = "" +
 	"\x05error\x18\x04 \x01(\tR\x05error\x12!\n" +
 	"\fexecution_ms\x18\x05 \x01(\x03R\vexecutionMs\x12\x1a\n" +
 	"\bprogress\x18\x06 \x01(\x02R\bprogress\x12)\n" +
-	"\x10progress_message\x18\a \x01(\tR\x0fprogressMessage*\x8e\x01\n" +
+	"\x10progress_message\x18\a \x01(\tR\x0fprogressMessage\x12\x12\n" +
+	"\x04logs\x18\b \x03(\tR\x04logs\x12%\n" +
+	"\x0epartial_result\x18\t \x01(\tR\rpartialResult\x12'\n" +
+	"\x05usage\x18\n" +
+	" \x01(\v2\x11.ari.v1.TaskUsageR\x05usage\"r\n" +
+	"\tTaskUsage\x12\x15\n" +
+	"\x06cpu_ms\x18\x01 \x01(\x03R\x05cpuMs\x12\"\n" +
+	"\rmax_rss_bytes\x18\x02 \x01(\x03R\vmaxRssBytes\x12*\n" +
+	"\x11peak_memory_bytes\x18\x03 \x01(\x03R\x0fpeakMemoryBytes\"D\n" +
+	"\x11TaskCancelRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\x88\x01\n" +
+	"\x12TaskCancelResponse\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x1a\n" +
+	"\bcanceled\x18\x02 \x01(\bR\bcanceled\x12\x1a\n" +
+	"\bprogress\x18\x03 \x01(\x02R\bprogress\x12!\n" +
+	"\fexecution_ms\x18\x04 \x01(\x03R\vexecutionMs*\xa8\x01\n" +
 	"\n" +
 	"TaskStatus\x12\x1b\n" +
 	"\x17TASK_STATUS_UNSPECIFIED\x10\x00\x12\x17\n" +
 	"\x13TASK_STATUS_PENDING\x10\x01\x12\x17\n" +
 	"\x13TASK_STATUS_RUNNING\x10\x02\x12\x19\n" +
 	"\x15TASK_STATUS_COMPLETED\x10\x03\x12\x16\n" +
-	"\x12TASK_STATUS_FAILED\x10\x042L\n" +
+	"\x12TASK_STATUS_FAILED\x10\x04\x12\x18\n" +
+	"\x14TASK_STATUS_CANCELED\x10\x052\x8d\x01\n" +
 	"\x04Task\x12D\n" +
-	"\aExecute\x12\x1a.ari.v1.TaskExecuteRequest\x1a\x1b.ari.v1.TaskExecuteResponse0\x01BIZGgithub.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1;ariv1b\x06proto3"
+	"\aExecute\x12\x1a.ari.v1.TaskExecuteRequest\x1a\x1b.ari.v1.TaskExecuteResponse0\x01\x12?\n" +
+	"\x06Cancel\x12\x19.ari.v1.TaskCancelRequest\x1a\x1a.ari.v1.TaskCancelResponseBIZGgithub.com/aidenlippert/zerostate/reference-runtime-v1/pkg/ari/v1;ariv1b\x06proto3"
 
 var (
 	file_pkg_ari_v1_task_proto_rawDescOnce sync.Once
@@ -292,21 +532,27 @@ func file_pkg_ari_v1_task_proto_rawDescGZIP() []byte {
 }
 
 var file_pkg_ari_v1_task_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pkg_ari_v1_task_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_pkg_ari_v1_task_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_pkg_ari_v1_task_proto_goTypes = []any{
 	(TaskStatus)(0),             // 0: ari.v1.TaskStatus
 	(*TaskExecuteRequest)(nil),  // 1: ari.v1.TaskExecuteRequest
 	(*TaskExecuteResponse)(nil), // 2: ari.v1.TaskExecuteResponse
+	(*TaskUsage)(nil),           // 3: ari.v1.TaskUsage
+	(*TaskCancelRequest)(nil),   // 4: ari.v1.TaskCancelRequest
+	(*TaskCancelResponse)(nil),  // 5: ari.v1.TaskCancelResponse
 }
 var file_pkg_ari_v1_task_proto_depIdxs = []int32{
 	0, // 0: ari.v1.TaskExecuteResponse.status:type_name -> ari.v1.TaskStatus
-	1, // 1: ari.v1.Task.Execute:input_type -> ari.v1.TaskExecuteRequest
-	2, // 2: ari.v1.Task.Execute:output_type -> ari.v1.TaskExecuteResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3, // 1: ari.v1.TaskExecuteResponse.usage:type_name -> ari.v1.TaskUsage
+	1, // 2: ari.v1.Task.Execute:input_type -> ari.v1.TaskExecuteRequest
+	4, // 3: ari.v1.Task.Cancel:input_type -> ari.v1.TaskCancelRequest
+	2, // 4: ari.v1.Task.Execute:output_type -> ari.v1.TaskExecuteResponse
+	5, // 5: ari.v1.Task.Cancel:output_type -> ari.v1.TaskCancelResponse
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_pkg_ari_v1_task_proto_init() }
@@ -320,7 +566,7 @@ func file_pkg_ari_v1_task_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_ari_v1_task_proto_rawDesc), len(file_pkg_ari_v1_task_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   2,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Task_Execute_FullMethodName = "/ari.v1.Task/Execute"
+	Task_Cancel_FullMethodName  = "/ari.v1.Task/Cancel"
 )
 
 // TaskClient is the client API for Task service.
@@ -30,6 +31,8 @@ const (
 type TaskClient interface {
 	// Execute runs a task and returns the result (supports streaming for long-running tasks)
 	Execute(ctx context.Context, in *TaskExecuteRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TaskExecuteResponse], error)
+	// Cancel stops a running task; its Execute stream ends with status CANCELED
+	Cancel(ctx context.Context, in *TaskCancelRequest, opts ...grpc.CallOption) (*TaskCancelResponse, error)
 }
 
 type taskClient struct {
@@ -59,6 +62,16 @@ func (c *taskClient) Execute(ctx context.Context, in *TaskExecuteRequest, opts .
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Task_ExecuteClient = grpc.ServerStreamingClient[TaskExecuteResponse]
 
+func (c *taskClient) Cancel(ctx context.Context, in *TaskCancelRequest, opts ...grpc.CallOption) (*TaskCancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TaskCancelResponse)
+	err := c.cc.Invoke(ctx, Task_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TaskServer is the server API for Task service.
 // All implementations must embed UnimplementedTaskServer
 // for forward compatibility.
@@ -67,6 +80,8 @@ type Task_ExecuteClient = grpc.ServerStreamingClient[TaskExecuteResponse]
 type TaskServer interface {
 	// Execute runs a task and returns the result (supports streaming for long-running tasks)
 	Execute(*TaskExecuteRequest, grpc.ServerStreamingServer[TaskExecuteResponse]) error
+	// Cancel stops a running task; its Execute stream ends with status CANCELED
+	Cancel(context.Context, *TaskCancelRequest) (*TaskCancelResponse, error)
 	mustEmbedUnimplementedTaskServer()
 }
 
@@ -80,6 +95,9 @@ type UnimplementedTaskServer struct{}
 func (UnimplementedTaskServer) Execute(*TaskExecuteRequest, grpc.ServerStreamingServer[TaskExecuteResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Execute not implemented")
 }
+func (UnimplementedTaskServer) Cancel(context.Context, *TaskCancelRequest) (*TaskCancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
 func (UnimplementedTaskServer) mustEmbedUnimplementedTaskServer() {}
 func (UnimplementedTaskServer) testEmbeddedByValue()              {}
 
@@ -112,13 +130,36 @@ func _Task_Execute_Handler(srv interface{}, stream grpc.ServerStream) error {
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Task_ExecuteServer = grpc.ServerStreamingServer[TaskExecuteResponse]
 
+func _Task_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskCancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Task_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServer).Cancel(ctx, req.(*TaskCancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Task_ServiceDesc is the grpc.ServiceDesc for Task service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Task_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "ari.v1.Task",
 	HandlerType: (*TaskServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Cancel",
+			Handler:    _Task_Cancel_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Execute",